@@ -39,8 +39,8 @@ func (a *App) Run(args []string) int {
 	for _, r := range args {
 		// Hack.
 		switch r {
-		case "cli", "repl": // Sub commands for new-style interface.
-			// If an arg named "cli" or "repl" is passed, it is regarded as a sub-command of new-style.
+		case "cli", "repl", "health", "channelz", "schema", "mock", "fuzz", "test", "perf": // Sub commands for new-style interface.
+			// If an arg named one of the sub-commands is passed, it is regarded as a sub-command of new-style.
 			a.cmd.registerNewCommands()
 			a.cmd.RunE = nil
 		case "-h", "--help":
@@ -56,11 +56,14 @@ func (a *App) Run(args []string) int {
 	var e interface {
 		Code() usecase.ErrorCode
 		Message() string
+		Details() []map[string]interface{}
 	}
 	if errors.As(err, &e) {
-		a.cui.Error(
-			fmt.Sprintf("evans: code = %s, number = %d, message = %q", e.Code().String(), e.Code(), e.Message()),
-		)
+		msg := fmt.Sprintf("evans: code = %s, number = %d, message = %q", e.Code().String(), e.Code(), e.Message())
+		if len(e.Details()) != 0 {
+			msg = fmt.Sprintf("%s\n%s", msg, usecase.FormatStatusDetails(e.Details()))
+		}
+		a.cui.Error(msg)
 		return 1
 	}
 
@@ -93,14 +96,18 @@ type mergedConfig struct {
 	repl bool
 }
 
-func mergeConfig(fs *pflag.FlagSet, flags *flags, protos []string) (*mergedConfig, error) {
+func mergeConfig(fs *pflag.FlagSet, flags *flags, protos []string, specRequired bool) (*mergedConfig, error) {
 	cfg, err := config.Get(fs)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get config")
 	}
 	cfg.Default.ProtoFile = append(cfg.Default.ProtoFile, protos...)
 
-	if err := cfg.Validate(); err != nil {
+	validate := cfg.Validate
+	if !specRequired {
+		validate = cfg.ValidateWithoutSpec
+	}
+	if err := validate(); err != nil {
 		return nil, err
 	}
 
@@ -3,16 +3,26 @@ package app
 import (
 	"strings"
 
+	"github.com/ktr0731/evans/batch"
 	"github.com/ktr0731/evans/cui"
+	fmttable "github.com/ktr0731/evans/format/table"
 	"github.com/ktr0731/evans/mode"
+	"github.com/ktr0731/evans/perf"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 	var (
-		out    string
-		enrich bool
+		in                      string
+		out, tmpl, filter       string
+		rawRequest, rawResponse string
+		enrich, dryRun, stats   bool
+		columns                 []string
+		maxColumnWidth          int
+		expectCode              string
+		expectBodyContains      []string
+		allowDeprecated         bool
 	)
 	cmd := &cobra.Command{
 		Use:     "call [options ...] <method>",
@@ -24,6 +34,18 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 			"        $ evans -r cli call -f in.json api.Service.Unary  # call Unary method with an input file",
 			"",
 			"        $ evans -r cli call -f in.json --enrich --output json api.Service.Unary # enrich output with JSON format",
+			"        $ evans -r cli call -f in.json --output ndjson api.Service.ServerStreaming | jq . # newline-delimited JSON output",
+			"        $ evans -r cli call -f in.yaml --input yaml --output yaml api.Service.Unary       # YAML input and output",
+			"        $ evans -r cli call --output table --columns id,name api.Service.ListUsers # show id and name of each user as a table",
+			"        $ evans -r cli call --output csv api.Service.ListUsers > users.csv           # export users as CSV",
+			"        $ evans -r cli call --raw-request req.bin --raw-response res.bin api.Service.Unary # send and dump serialized messages",
+			"        $ evans -r cli call -f in.json --compact --sort-keys api.Service.Unary # single-line JSON with sorted keys",
+			"        $ evans -r cli call -f in.json --template '{{.user.id}} {{.user.name}}' api.Service.Unary # print only specific fields",
+			"        $ evans -r cli call -f in.json --filter '.items[].name' api.Service.Unary # extract values by a jq-style filter",
+			"        $ evans --proto api.proto cli call -f in.json --dry-run api.Service.Unary # validate the request without calling",
+			"        $ evans -r cli call -f in.json --expect-code NotFound api.Service.Unary # exit with a non-zero code unless the status is NotFound",
+			"        $ evans -r cli call -f in.json --stats api.Service.Unary # show the elapsed time and the message size to stderr",
+			"        $ evans -r cli call -f in.json --expect-body-contains '\"name\":\"foo\"' api.Service.Unary # exit with a non-zero code unless the response has the name",
 		}, "\n"),
 		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
 			if cfg.REPL.ColoredOutput {
@@ -34,7 +56,60 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 			if len(args) == 0 {
 				return errors.New("method is required")
 			}
-			invoker, err := mode.NewCallCLIInvoker(ui, args[0], cfg.file, cfg.Config.Request.Header, enrich, out)
+			if tmpl != "" && cmd.Flags().Changed("output") {
+				return errors.New("--template cannot be specified with --output")
+			}
+			if filter != "" && (tmpl != "" || cmd.Flags().Changed("output")) {
+				return errors.New("--filter cannot be specified with --output or --template")
+			}
+			if cmd.Flags().Changed("columns") && out != "table" && out != "csv" {
+				return errors.New("--columns can be specified only with --output table or csv")
+			}
+			if cmd.Flags().Changed("max-column-width") && out != "table" {
+				return errors.New("--max-column-width can be specified only with --output table")
+			}
+			file := cfg.file
+			if rawRequest != "" {
+				if file != "" {
+					return errors.New("--raw-request cannot be specified with --file")
+				}
+				file, in = rawRequest, "binary"
+			}
+			var expect *mode.Expectation
+			if stats && dryRun {
+				return errors.New("--stats cannot be specified with --dry-run")
+			}
+			if expectCode != "" || len(expectBodyContains) != 0 {
+				if dryRun {
+					return errors.New("--expect-code and --expect-body-contains cannot be specified with --dry-run")
+				}
+				expect = &mode.Expectation{Code: expectCode, BodyContains: expectBodyContains}
+			}
+			if dryRun {
+				invoker, err := mode.NewDryRunCLIInvoker(ui, args[0], file, in)
+				if err != nil {
+					return err
+				}
+				if err := mode.RunAsDryRunCLIMode(cfg.Config, invoker); err != nil {
+					return errors.Wrap(err, "failed to run CLI mode")
+				}
+				return nil
+			}
+			invoker, err := mode.NewCallCLIInvoker(ui, args[0], mode.CallCLIOptions{
+				FilePath:        file,
+				Headers:         cfg.Config.Request.Header,
+				Enrich:          enrich,
+				Stats:           stats,
+				DenyDeprecated:  cfg.Config.Request.DenyDeprecated && !allowDeprecated,
+				InputType:       in,
+				FormatType:      out,
+				OutputTemplate:  tmpl,
+				OutputFilter:    filter,
+				RawResponsePath: rawResponse,
+				Output:          cfg.Config.Output,
+				Table:           fmttable.Options{Columns: columns, MaxWidth: maxColumnWidth},
+				Expect:          expect,
+			})
 			if err != nil {
 				return err
 			}
@@ -50,7 +125,27 @@ func newCLICallCommand(flags *flags, ui cui.UI) *cobra.Command {
 	f := cmd.Flags()
 	initFlagSet(f, ui.Writer())
 	f.BoolVar(&enrich, "enrich", false, `enrich response output includes header, message, trailer and status`)
-	f.StringVarP(&out, "output", "o", "curl", `output format. one of "json" or "curl". "curl" is a curl-like format.`)
+	f.StringVar(&in, "input", "json", `input format. one of "json", "yaml" or "prototext". multiple requests are separated by "---" in YAML and prototext.`)
+	f.StringVarP(&out, "output", "o", "curl", `output format. one of "json", "ndjson", "yaml", "prototext", "table", "csv" or "curl". "curl" is a curl-like format. "ndjson" writes each message as a line of JSON as soon as it is received. "table" shows a column per field. "csv" writes top-level scalar fields as records.`)
+	f.StringSliceVar(&columns, "columns", nil, `comma-separated field names shown as columns of the table or CSV output. all fields (only scalar fields for CSV) are shown by default.`)
+	f.IntVar(&maxColumnWidth, "max-column-width", fmttable.DefaultMaxWidth, `the maximum width of each cell of the table output. longer values are truncated. 0 means no limit.`)
+	f.StringVar(&tmpl, "template", "", `format each message by the Go template such that '{{.user.id}} {{.user.name}}'. fields are referenced by their JSON names.`)
+	f.StringVar(&filter, "filter", "", `show values extracted from each message by the jq-style filter such that '.items[].name'. each value is shown as JSON.`)
+	f.StringVar(&rawRequest, "raw-request", "", `send the serialized message in the file as it is. for client streaming RPCs, each message is prefixed by its varint-encoded length.`)
+	f.BoolVar(&dryRun, "dry-run", false, `validate requests and show them with their wire sizes instead of calling the method. the server is never connected, so gRPC reflection is not available.`)
+	f.StringVar(&expectCode, "expect-code", "", `exit with a non-zero code unless the status code is the code such that "OK", "NotFound" or "5". a non-OK status is regarded as a success if it is expected.`)
+	f.StringArrayVar(&expectBodyContains, "expect-body-contains", nil, `exit with a non-zero code unless the response body contains the string. the body is compact JSON such that {"name":"foo"}. it can be specified multiple times.`)
+	f.BoolVar(&stats, "stats", false, `show the elapsed time, the number of sent and received messages and their size to stderr after the call`)
+	f.BoolVar(&allowDeprecated, "allow-deprecated", false, `call deprecated methods and send deprecated fields even if request.denyDeprecated is enabled in the config. they are warned to stderr regardless of it.`)
+	f.StringVar(&rawResponse, "raw-response", "", `write serialized responses to the file. for server streaming RPCs, each message is prefixed by its varint-encoded length.`)
+	// The following flags are bound to the output config, so that their values are not held here.
+	f.Bool("compact", false, `format JSON output in a single line`)
+	f.Int("indent", 2, `the number of spaces for each indentation level of JSON output`)
+	f.Bool("sort-keys", false, `order keys of messages alphabetically instead of the schema order`)
+	f.Bool("emit-defaults", false, `show fields that have the default value`)
+	f.Bool("proto-names", false, `use original field names in the proto file instead of lowerCamelCase JSON names`)
+	f.Bool("int64-as-number", false, `show 64-bit integers as JSON numbers instead of strings`)
+	f.String("bytes-encoding", "base64", `the encoding of bytes fields. one of "base64", "hex" or "utf8". "utf8" escapes invalid bytes as \xNN.`)
 
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), []string{"file"}))
 	return cmd
@@ -101,6 +196,9 @@ list lists method names belong to the service. If not, list lists all services.`
 }
 
 func newCLIDescribeCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		out string
+	)
 	cmd := &cobra.Command{
 		Use:     "desc [options ...] [symbol]",
 		Aliases: []string{"describe"},
@@ -111,6 +209,7 @@ The symbol should be a fully-qualified name. If no symbol is passed, desc shows
 			"        $ evans -r cli desc             # describe the descriptors of the loaded services",
 			`        $ evans -r cli desc api.Service # describe the service descriptor of "api.Service"`,
 			`        $ evans -r cli desc api.Request # describe the message descriptor of "api.Request"`,
+			`        $ evans -r cli desc -o json     # describe the descriptors of the loaded services with JSON format`,
 		}, "\n"),
 		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
 			if cfg.REPL.ColoredOutput {
@@ -122,7 +221,49 @@ The symbol should be a fully-qualified name. If no symbol is passed, desc shows
 			if len(args) > 0 {
 				fqn = args[0]
 			}
-			invoker := mode.NewDescribeCLIInvoker(ui, fqn)
+			invoker, err := mode.NewDescribeCLIInvoker(ui, fqn, out)
+			if err != nil {
+				return err
+			}
+			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+				return errors.Wrap(err, "failed to run CLI mode")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVarP(&out, "output", "o", "proto", `output format. one of "proto" or "json". "json" is the JSON representation of the descriptor proto.`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newCLIExportCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		format string
+		out    string
+	)
+	cmd := &cobra.Command{
+		Use:   "export [options ...] [fully-qualified service name ...]",
+		Short: "export the schema of services",
+		Long: `export writes out the schema of the given services and their dependencies.
+It is mainly used for saving the schema fetched by gRPC reflection. If no services are passed,
+export exports all loaded files. The schema is written as a protoset (FileDescriptorSet) or
+reconstructed proto files. Comments and formatting of reconstructed files may differ from the original.`,
+		Example: strings.Join([]string{
+			"        $ evans -r cli export > api.protoset                 # export all files as a protoset",
+			`        $ evans -r cli export --out api.protoset api.Service # export files which "api.Service" depends on`,
+			"        $ evans -r cli export --format proto --out protos    # export all files as proto files under protos",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			invoker, err := mode.NewExportCLIInvoker(ui, cmd.Flags().Args(), format, out)
+			if err != nil {
+				return err
+			}
 			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
 				return errors.Wrap(err, "failed to run CLI mode")
 			}
@@ -134,6 +275,67 @@ The symbol should be a fully-qualified name. If no symbol is passed, desc shows
 
 	f := cmd.Flags()
 	initFlagSet(f, ui.Writer())
+	f.StringVar(&format, "format", "protoset", `export format. one of "protoset" or "proto".`)
+	f.StringVar(&out, "out", "", `output file for "protoset" or output directory for "proto". "protoset" is written to stdout if it is empty.`)
+
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newCLIBatchCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		opts   batch.Options
+		stages []string
+	)
+	cmd := &cobra.Command{
+		Use:   "batch [options ...] <batch file>",
+		Short: "call methods listed in a batch file",
+		Long: `batch calls methods with the requests listed in the batch file, and writes the result of each request
+as a line of JSON in the same order as the requests. The batch file is newline-delimited JSON or a JSON array,
+and each entry has the fully-qualified method name, headers and the request body. The body of client streaming
+and bidi streaming RPCs may be an array of requests. batch exits with a non-zero code if one or more requests
+fail or return non-OK statuses. The rate of requests can be limited by --rps or --stage in the same way as perf.
+
+    {"method": "api.Service.Unary", "headers": {"authorization": "Bearer token"}, "body": {"name": "oumae"}}
+    {"method": "api.Service.ClientStreaming", "body": [{"name": "oumae"}, {"name": "kousaka"}]}`,
+		Example: strings.Join([]string{
+			"        $ evans -r cli batch requests.ndjson                   # call methods sequentially",
+			"        $ evans -r cli batch --parallel 4 requests.json        # call at most 4 methods concurrently",
+			"        $ evans -r cli batch --rps 10 requests.ndjson          # at most 10 calls per second",
+			"        $ evans -r cli batch --stage 1-10:1m requests.ndjson   # ramp up to 10 calls per second",
+			"        $ cat requests.ndjson | evans -r cli batch -           # read requests from stdin",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("batch file is required")
+			}
+			if len(stages) != 0 {
+				if cmd.Flags().Changed("rps") {
+					return errors.New("--stage cannot be used with --rps")
+				}
+				for _, s := range stages {
+					stage, err := perf.ParseStage(s)
+					if err != nil {
+						return err
+					}
+					opts.Stages = append(opts.Stages, stage)
+				}
+			}
+			if err := mode.RunBatch(cfg.Config, ui, args[0], opts); err != nil {
+				return errors.Wrap(err, "failed to run the batch")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.IntVar(&opts.Parallel, "parallel", 1, "the maximum number of requests run concurrently")
+	f.Float64Var(&opts.Rate, "rps", 0, "the maximum number of requests per second in total. 0 means unlimited")
+	f.StringArrayVar(&stages, "stage", nil, `a stage of the rate such that "10:30s" or "1-10:1m". it can be specified multiple times`)
 
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
 	return cmd
@@ -8,19 +8,26 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ktr0731/evans/cache"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/mode"
+	"github.com/ktr0731/evans/perf"
 	"github.com/ktr0731/evans/prompt"
+	"github.com/ktr0731/evans/report"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
 )
 
+// specNotRequiredAnnotation is the annotation of commands which don't load the spec.
+// Neither proto files nor gRPC reflection are required for such commands.
+const specNotRequiredAnnotation = "specNotRequired"
+
 type command struct {
 	*cobra.Command
 
@@ -33,6 +40,13 @@ func (c *command) registerNewCommands() {
 	c.AddCommand(
 		newCLICommand(c.flags, c.ui),
 		newREPLCommand(c.flags, c.ui),
+		newHealthCommand(c.flags, c.ui),
+		newChannelzCommand(c.flags, c.ui),
+		newSchemaCommand(c.flags, c.ui),
+		newMockCommand(c.flags, c.ui),
+		newFuzzCommand(c.flags, c.ui),
+		newTestCommand(c.flags, c.ui),
+		newPerfCommand(c.flags, c.ui),
 	)
 }
 
@@ -74,7 +88,8 @@ func runFunc(
 			protos = args
 		}
 		// Pass Flags instead of LocalFlags because the config is merged with common and local flags.
-		cfg, err := mergeConfig(cmd.Flags(), flags, protos)
+		_, specNotRequired := cmd.Annotations[specNotRequiredAnnotation]
+		cfg, err := mergeConfig(cmd.Flags(), flags, protos, !specNotRequired)
 		if err != nil {
 			if err, ok := err.(*config.ValidationError); ok {
 				printUsage(cmd)
@@ -113,7 +128,12 @@ func newOldCommand(flags *flags, ui cui.UI) *command {
 			if cfg.repl || !isCLIMode {
 				return runREPLCommand(cfg, ui)
 			}
-			invoker, err := mode.NewCallCLIInvoker(ui, cfg.call, cfg.file, cfg.Config.Request.Header, false, "")
+			invoker, err := mode.NewCallCLIInvoker(ui, cfg.call, mode.CallCLIOptions{
+				FilePath:       cfg.file,
+				Headers:        cfg.Config.Request.Header,
+				DenyDeprecated: cfg.Config.Request.DenyDeprecated,
+				Output:         cfg.Config.Output,
+			})
 			if err != nil {
 				return err
 			}
@@ -147,14 +167,27 @@ func bindFlags(f *pflag.FlagSet, flags *flags, w io.Writer) {
 	f.StringVar(&flags.common.pkg, "package", "", "default package")
 	f.StringVar(&flags.common.service, "service", "", "default service")
 	f.StringSliceVar(&flags.common.path, "path", nil, "comma-separated proto file paths")
-	f.StringSliceVar(&flags.common.proto, "proto", nil, "comma-separated proto file names")
-	f.StringVar(&flags.common.host, "host", "", "gRPC server host")
+	f.StringSliceVar(&flags.common.proto, "proto", nil, "comma-separated proto file names. glob patterns such that api/**/*.proto and directories are expanded")
+	f.BoolVar(&flags.common.autoPath, "auto-path", false, "resolve imports which are not found in --path from parent directories and vendor directories such that vendor/ or third_party/")
+	f.StringSliceVar(&flags.common.protoset, "protoset", nil, "comma-separated FileDescriptorSet files such that outputs of protoc --descriptor_set_out or buf build. they are used instead of proto files")
+	f.StringVar(&flags.common.bsr, "bsr", "", "Buf Schema Registry module such that buf.build/<owner>/<repository>:<reference>. it is used instead of proto files. $BUF_TOKEN is used as an API token")
+	f.Var(
+		newHostsValue("", &flags.common.host),
+		"host", `gRPC server host. comma-separated hosts or multiple --host flags are balanced by --lb-policy. "xds:///name" is resolved by xDS`)
 	f.StringVarP(&flags.common.port, "port", "p", "50051", "gRPC server port")
 	f.Var(
 		newStringToStringValue(nil, &flags.common.header),
 		"header", "default headers that set to each requests (example: foo=bar)")
+	f.StringVar(&flags.common.lbPolicy, "lb-policy", "pick_first", `the load balancing policy for multiple hosts. one of "pick_first" or "round_robin"`)
+	f.StringVar(&flags.common.xdsBootstrap, "xds-bootstrap", "", "the xDS bootstrap file for xds:/// hosts. if empty, GRPC_XDS_BOOTSTRAP is used")
 	f.BoolVar(&flags.common.web, "web", false, "use gRPC-Web protocol")
+	f.BoolVar(&flags.common.twirp, "twirp", false, "use Twirp protocol. streaming RPCs and gRPC reflection are not supported")
+	f.StringVar(&flags.common.twirpEnc, "twirp-encoding", "protobuf", `the encoding of Twirp request bodies. one of "protobuf" or "json"`)
+	f.BoolVar(&flags.common.transcode, "transcoding", false, "send RPCs as REST requests annotated by google.api.http options. streaming RPCs and gRPC reflection are not supported")
+	f.StringVar(&flags.common.proxy, "proxy", "", `proxy URL such that "http://host:port" or "socks5://host:port". if empty, HTTPS_PROXY and ALL_PROXY are used`)
+	f.BoolVar(&flags.common.h2c, "h2c", false, "use cleartext HTTP/2 with prior knowledge for Twirp or HTTP/JSON transcoding")
 	f.BoolVarP(&flags.common.reflection, "reflection", "r", false, "use gRPC reflection")
+	f.BoolVar(&flags.common.noCache, "no-cache", false, "neither read nor write the on-disk cache of descriptors fetched by gRPC reflection")
 	f.BoolVarP(&flags.common.tls, "tls", "t", false, "use a secure TLS connection")
 	f.StringVar(&flags.common.cacert, "cacert", "", "the CA certificate file for verifying the server")
 	f.StringVar(
@@ -166,10 +199,49 @@ func bindFlags(f *pflag.FlagSet, flags *flags, w io.Writer) {
 	f.StringVar(
 		&flags.common.serverName,
 		"servername", "", "override the server name used to verify the hostname (ignored if --tls is disabled)")
+	f.DurationVar(&flags.common.timeout, "timeout", 0, "the timeout for each RPC call such that 5s or 500ms (0 means no timeout)")
+	f.DurationVar(&flags.common.keepaliveTime, "keepalive-time", 0, "the interval of gRPC keepalive pings such that 30s (0 means no keepalive pings)")
+	f.DurationVar(&flags.common.keepaliveTimeout, "keepalive-timeout", 0, "the time to wait for the ack of a keepalive ping (0 means the gRPC default)")
+	f.BoolVar(&flags.common.keepaliveWithoutStream, "keepalive-permit-without-stream", false, "send keepalive pings even if there are no active streams")
+	f.Int32Var(&flags.common.initialWindowSize, "initial-window-size", 0, "the HTTP/2 initial window size of each stream (0 means the gRPC default)")
+	f.Int32Var(&flags.common.initialConnWindowSize, "initial-conn-window-size", 0, "the HTTP/2 initial window size of each connection (0 means the gRPC default)")
+	f.Uint32Var(&flags.common.maxHeaderListSize, "max-header-list-size", 0, "the HTTP/2 maximum header list size (0 means the gRPC default)")
+	f.IntVar(&flags.common.maxRecvMsgSize, "max-recv-msg-size", 0, "the maximum size in bytes of messages the client can receive (0 means the gRPC default, 4MB)")
+	f.IntVar(&flags.common.maxSendMsgSize, "max-send-msg-size", 0, "the maximum size in bytes of messages the client can send (0 means the gRPC default)")
+	f.StringVar(&flags.common.compression, "compression", "", `the compressor used to compress requests such that "gzip" (empty means no compression)`)
+	f.DurationVar(&flags.common.connectTimeout, "connect-timeout", 0, "the timeout to connect to the server. if set, Evans waits for the connection at startup (0 means lazy connection)")
+	f.BoolVar(&flags.common.waitForReady, "wait-for-ready", false, "make RPCs wait until the connection becomes ready instead of failing immediately")
+	f.StringVar(&flags.common.record, "record", "", "record every RPC to the cassette file")
+	f.StringVar(&flags.common.replay, "replay", "", "answer RPCs from the cassette file without connecting to the server")
+	f.IntVar(&flags.common.retry, "retry", 0, "the maximum number of retries of failed unary RPCs (0 means no retries)")
+	f.DurationVar(&flags.common.retryBackoff, "retry-backoff", 100*time.Millisecond, "the backoff before the first retry. it is doubled for each retry with jitter")
+	f.DurationVar(&flags.common.retryMaxBackoff, "retry-max-backoff", 5*time.Second, "the upper limit of retry backoffs (0 means no limit)")
+	f.BoolVar(&flags.common.tracePropagation, "trace-propagation", true, "send the W3C traceparent header with every RPC")
+	f.StringVar(&flags.common.traceparent, "traceparent", "", "the W3C traceparent header of an existing trace. RPCs are sent as its children")
+	f.StringVar(&flags.common.otlpEndpoint, "otlp-endpoint", "", `the OTLP/HTTP endpoint such that "http://localhost:4318" to export client spans to`)
+	f.StringVar(&flags.common.oauth2Flow, "oauth2-flow", "", `acquire access tokens by the OAuth 2.0 flow. one of "client_credentials" or "device_code"`)
+	f.StringVar(&flags.common.oauth2TokenURL, "oauth2-token-url", "", "the token endpoint of the OAuth 2.0 authorization server")
+	f.StringVar(&flags.common.oauth2DeviceAuthURL, "oauth2-device-auth-url", "", "the device authorization endpoint used by the device code flow")
+	f.StringVar(&flags.common.oauth2ClientID, "oauth2-client-id", "", "the OAuth 2.0 client ID")
+	f.StringVar(&flags.common.oauth2ClientSecret, "oauth2-client-secret", "", "the OAuth 2.0 client secret")
+	f.StringSliceVar(&flags.common.oauth2Scopes, "oauth2-scopes", nil, "comma-separated OAuth 2.0 scopes")
+	f.StringVar(&flags.common.jwtKey, "jwt-key", "", "sign a JWT for every RPC with the PEM encoded RSA or ECDSA private key, or the HMAC secret in the file")
+	f.StringVar(&flags.common.jwtAlg, "jwt-alg", "", `the JWT signing algorithm such that "RS256". if empty, it is inferred from the key`)
+	f.StringVar(&flags.common.jwtClaims, "jwt-claims", "", `JWT claims as a JSON object such that '{"sub":"alice"}'. it overrides --jwt-claims-file`)
+	f.StringVar(&flags.common.jwtClaimsFile, "jwt-claims-file", "", "the JSON file which has JWT claims")
+	f.StringVar(&flags.common.jwtHeader, "jwt-header", "authorization", `the header JWTs are sent as. JWTs are sent as "Bearer <token>" for authorization`)
+	f.DurationVar(&flags.common.jwtExpiry, "jwt-expiry", time.Hour, "the lifetime of JWTs used for iat and exp claims (0 means no expiry)")
+	f.StringVar(&flags.common.authProvider, "auth-provider", "", `the credential provider. one of "static", "basic", "oauth2", "jwt" or "exec"`)
+	f.StringVar(&flags.common.authHeader, "auth-header", "", "the header the static provider sends")
+	f.StringVar(&flags.common.authValue, "auth-value", "", "the header value the static provider sends such that an API key")
+	f.StringVar(&flags.common.authUsername, "auth-username", "", "the username of the basic provider")
+	f.StringVar(&flags.common.authPassword, "auth-password", "", "the password of the basic provider")
+	f.StringVar(&flags.common.authCommand, "auth-command", "", "the command the exec provider runs to get credentials")
+	f.StringSliceVar(&flags.common.retryCodes, "retry-codes", nil, "comma-separated status codes to be retried. UNAVAILABLE and RESOURCE_EXHAUSTED if empty")
 
 	f.BoolVarP(&flags.meta.edit, "edit", "e", false, "edit the project config file by using $EDITOR")
 	f.BoolVar(&flags.meta.editGlobal, "edit-global", false, "edit the global config file by using $EDITOR")
-	f.BoolVar(&flags.meta.verbose, "verbose", false, "verbose output")
+	f.BoolVar(&flags.meta.verbose, "verbose", false, "verbose output. DNS resolution, connections, TLS handshakes, headers, message sizes and trailers of gRPC are traced like curl -v")
 	f.BoolVarP(&flags.meta.version, "version", "v", false, "display version and exit")
 	f.BoolVarP(&flags.meta.help, "help", "h", false, "display help text and exit")
 
@@ -207,7 +279,12 @@ func newCLICommand(flags *flags, ui cui.UI) *cobra.Command {
 				}
 				call = args[0]
 			}
-			invoker, err := mode.NewCallCLIInvoker(ui, call, cfg.file, cfg.Config.Request.Header, false, "")
+			invoker, err := mode.NewCallCLIInvoker(ui, call, mode.CallCLIOptions{
+				FilePath:       cfg.file,
+				Headers:        cfg.Config.Request.Header,
+				DenyDeprecated: cfg.Config.Request.DenyDeprecated,
+				Output:         cfg.Config.Output,
+			})
 			if err != nil {
 				return err
 			}
@@ -226,11 +303,17 @@ func newCLICommand(flags *flags, ui cui.UI) *cobra.Command {
 		newCLICallCommand(flags, ui),
 		newCLIListCommand(flags, ui),
 		newCLIDescribeCommand(flags, ui),
+		newCLIExportCommand(flags, ui),
+		newCLIBatchCommand(flags, ui),
 	)
 	return cmd
 }
 
 func newREPLCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		script, reportFile string
+		allowDeprecated    bool
+	)
 	cmd := &cobra.Command{
 		Use:   "repl [options ...]",
 		Short: "REPL mode",
@@ -238,16 +321,354 @@ func newREPLCommand(flags *flags, ui cui.UI) *cobra.Command {
 			if cfg.REPL.ColoredOutput {
 				ui = cui.NewColored(ui)
 			}
+			if script != "" {
+				if allowDeprecated {
+					cfg.Config.Request.DenyDeprecated = false
+				}
+				return runScriptCommand(cfg, ui, script, reportFile)
+			}
+			if reportFile != "" {
+				return errors.New("--report must be used with --exec")
+			}
+			if allowDeprecated {
+				return errors.New("--allow-deprecated must be used with --exec")
+			}
 			return runREPLCommand(cfg, ui)
 		}),
 		SilenceErrors: true,
 		SilenceUsage:  true,
 	}
-	initFlagSet(cmd.Flags(), ui.Writer())
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVar(&script, "exec", "", `execute REPL commands in the script file non-interactively. "-" means stdin.`)
+	f.StringVar(&reportFile, "report", "", "write the result of each call command of the script to the file as JUnit XML (.xml) or JSON (.json)")
+	f.BoolVar(&allowDeprecated, "allow-deprecated", false, "use deprecated services and methods and send deprecated fields in the script even if request.denyDeprecated is enabled in the config")
+	f.BoolVar(&flags.repl.watch, "watch", false, "reload the spec when proto files are modified, or periodically if gRPC reflection or a BSR module is used")
 	cmd.SetHelpFunc(usageFunc(ui.Writer(), []string{"package", "service"}))
 	return cmd
 }
 
+func newHealthCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var watch bool
+	cmd := &cobra.Command{
+		Use:   "health [options ...] [service ...]",
+		Short: "check the serving status of the server or services",
+		Long: `health calls grpc.health.v1.Health/Check for each fully-qualified service name.
+If no services are passed, the overall health of the server is checked.
+It exits with a non-zero code if one or more services are not SERVING.`,
+		Example: strings.Join([]string{
+			"        $ evans health                         # check the overall health of the server",
+			"        $ evans health api.Service api.Service2 # check the health of each service",
+			"        $ evans health --watch api.Service      # watch changes of the serving status",
+		}, "\n"),
+		Annotations: map[string]string{specNotRequiredAnnotation: ""},
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			invoker := mode.NewHealthCLIInvoker(ui, cmd.Flags().Args(), watch)
+			if err := mode.RunAsCLIModeWithoutSpec(cfg.Config, invoker); err != nil {
+				return errors.Wrap(err, "failed to check the health")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.BoolVarP(&watch, "watch", "w", false, "watch changes of the serving status by grpc.health.v1.Health/Watch until interrupted")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newChannelzCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var out string
+	cmd := &cobra.Command{
+		Use:   "channelz [options ...] <channels | servers | sockets <server id>>",
+		Short: "inspect the server by channelz",
+		Long: `channelz shows the summary of channels, servers or sockets of the server by grpc.channelz.v1.Channelz.
+The server must register the channelz service.`,
+		Example: strings.Join([]string{
+			"        $ evans channelz channels  # list top channels the server has",
+			"        $ evans channelz servers   # list servers and their listen sockets",
+			"        $ evans channelz sockets 2 # list sockets accepted by the server whose ID is 2",
+		}, "\n"),
+		Annotations: map[string]string{specNotRequiredAnnotation: ""},
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("target is required")
+			}
+			invoker, err := mode.NewChannelzCLIInvoker(ui, args[0], args[1:], out)
+			if err != nil {
+				return err
+			}
+			if err := mode.RunAsCLIModeWithoutSpec(cfg.Config, invoker); err != nil {
+				return errors.Wrap(err, "failed to inspect the server")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVarP(&out, "output", "o", "table", `output format. one of "table" or "json".`)
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newSchemaCommand(flags *flags, ui cui.UI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "inspect schemas",
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	cmd.AddCommand(
+		newSchemaDiffCommand(flags, ui),
+	)
+	return cmd
+}
+
+func newSchemaDiffCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var oldSource, newSource string
+	cmd := &cobra.Command{
+		Use:   "diff [options ...] --old <source> --new <source>",
+		Short: "report differences between two schemas",
+		Long: `diff reports added, removed and changed services, methods, messages, fields and enums between two schemas.
+Each source is a server address which supports gRPC reflection, a protoset, a proto file, or a directory
+containing proto files. Breaking changes such that removed fields or changed field numbers are flagged,
+and diff exits with a non-zero code if one or more breaking changes are found.`,
+		Example: strings.Join([]string{
+			"        $ evans schema diff --old localhost:50051 --new localhost:50052 # compare two servers by gRPC reflection",
+			"        $ evans schema diff --old api.protoset --new proto              # compare a protoset with proto files under proto",
+		}, "\n"),
+		Annotations: map[string]string{specNotRequiredAnnotation: ""},
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if oldSource == "" || newSource == "" {
+				return errors.New("both of --old and --new are required")
+			}
+			return mode.RunSchemaDiff(cfg.Config, ui, oldSource, newSource)
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVar(&oldSource, "old", "", "the old schema. a server address, protoset, proto file or directory")
+	f.StringVar(&newSource, "new", "", "the new schema. a server address, protoset, proto file or directory")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newMockCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var responses string
+	cmd := &cobra.Command{
+		Use:   "mock [options ...]",
+		Short: "serve RPCs with fake responses",
+		Long: `mock launches a gRPC server which serves all RPCs of the loaded schema on --host and --port.
+Each RPC responds with a fake message generated from the response type, or canned responses in the file
+specified by --responses. The top-level keys of the file are fully-qualified method names, and each value
+is a response or a sequence of responses. It is useful for developing clients before the real server exists.`,
+		Example: strings.Join([]string{
+			"        $ evans --proto api.proto --port 50051 mock                   # serve all RPCs with fake responses",
+			"        $ evans --proto api.proto --port 50051 mock --responses res.yaml # respond with canned responses",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			if err := mode.RunAsMockServer(cfg.Config, ui, responses); err != nil {
+				return errors.Wrap(err, "failed to run the mock server")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.StringVar(&responses, "responses", "", "the YAML file which has canned responses keyed by fully-qualified method names")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newFuzzCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		iterations   int
+		seed         int64
+		showRequests bool
+	)
+	cmd := &cobra.Command{
+		Use:   "fuzz [options ...] <method>",
+		Short: "send requests filled with boundary values for robustness testing",
+		Long: `fuzz sends structurally valid but boundary-heavy requests to a method, such that the maximum integers,
+huge strings, empty repeated fields and absurd timestamps. Responses are discarded, and each request which
+fails with a non-OK status is reported with its seed. The request is reproduced by passing the seed with
+--iterations 1. fuzz exits with a non-zero code if one or more requests fail.`,
+		Example: strings.Join([]string{
+			"        $ evans -r fuzz --iterations 1000 api.Service.Unary                        # send 1000 requests",
+			"        $ evans -r fuzz --seed 42 --iterations 1 --show-requests api.Service.Unary # show the request of seed 42 and send it again",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("method is required")
+			}
+			if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			invoker, err := mode.NewFuzzCLIInvoker(ui, args[0], cfg.Config.Request.Header, iterations, seed, showRequests)
+			if err != nil {
+				return err
+			}
+			if err := mode.RunAsCLIMode(cfg.Config, invoker); err != nil {
+				return errors.Wrap(err, "failed to fuzz")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.IntVar(&iterations, "iterations", 100, "the number of requests")
+	f.Int64Var(&seed, "seed", 0, "the seed of the first request. following requests use seed+1, seed+2, and so on. a random seed is used by default")
+	f.BoolVar(&showRequests, "show-requests", false, "show failed requests as JSON")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newTestCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		parallel   int
+		reportFile string
+	)
+	cmd := &cobra.Command{
+		Use:   "test [options ...] <suite file>",
+		Short: "run test cases defined in a YAML file",
+		Long: `test runs test cases in the suite file, and reports the result of each case.
+Each case calls a method with a request and headers, and asserts the status code and responses.
+Only fields which are set in an expected response are compared. test exits with a non-zero code
+if one or more cases fail.
+
+    cases:
+      - name: say hello
+        method: api.Example.Unary
+        headers:
+          authorization: Bearer token
+        request:
+          name: oumae
+        expect:
+          code: OK
+          response:
+            message: hello, oumae`,
+		Example: strings.Join([]string{
+			"        $ evans -r test suite.yaml                      # run cases sequentially",
+			"        $ evans -r test --parallel 4 suite.yaml         # run at most 4 cases concurrently",
+			"        $ evans -r test --report junit.xml suite.yaml   # also write results as JUnit XML",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("suite file is required")
+			}
+			if reportFile != "" {
+				if err := report.ValidateFileName(reportFile); err != nil {
+					return err
+				}
+			}
+			if err := mode.RunTestSuite(cfg.Config, ui, args[0], parallel, reportFile); err != nil {
+				return errors.Wrap(err, "failed to run the test suite")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.IntVar(&parallel, "parallel", 1, "the maximum number of cases run concurrently")
+	f.StringVar(&reportFile, "report", "", "write results to the file as JUnit XML (.xml) or JSON (.json)")
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
+func newPerfCommand(flags *flags, ui cui.UI) *cobra.Command {
+	var (
+		opts     perf.Options
+		dataFile string
+		stages   []string
+	)
+	cmd := &cobra.Command{
+		Use:   "perf [options ...] <method>",
+		Short: "load test a method and report the throughput and latencies",
+		Long: `perf calls a method concurrently for a while with the same request, and reports the throughput,
+the error rate, latency percentiles (p50, p95 and p99) and the latency histogram.
+Headers, TLS and other settings are the same as other commands. Interrupting perf stops calling
+and reports the result so far.
+
+--rps limits the number of calls per second in total. --stage changes the limit over time instead of
+--duration and --rps. "RATE:DURATION" keeps the rate for the duration, and "FROM-TO:DURATION" changes
+the rate linearly over the duration. Stages are run in the order they are specified.`,
+		Example: strings.Join([]string{
+			"        $ evans -r perf api.Service.Unary                                                 # call with an empty request",
+			"        $ evans -r perf --concurrency 50 --duration 30s --data req.json api.Service.Unary # 50 workers for 30 seconds",
+			"        $ evans -r perf --rps 100 api.Service.Unary                                       # at most 100 calls per second",
+			"        $ evans -r perf --stage 10-100:1m --stage 100:5m api.Service.Unary                # ramp up, then keep 100 calls per second",
+		}, "\n"),
+		RunE: runFunc(flags, func(cmd *cobra.Command, cfg *mergedConfig) error {
+			if cfg.REPL.ColoredOutput {
+				ui = cui.NewColored(ui)
+			}
+			args := cmd.Flags().Args()
+			if len(args) == 0 {
+				return errors.New("method is required")
+			}
+			if len(stages) != 0 {
+				if cmd.Flags().Changed("duration") || cmd.Flags().Changed("rps") {
+					return errors.New("--stage cannot be used with --duration or --rps")
+				}
+				opts.Duration = 0
+				for _, s := range stages {
+					stage, err := perf.ParseStage(s)
+					if err != nil {
+						return err
+					}
+					opts.Stages = append(opts.Stages, stage)
+				}
+			}
+			if err := mode.RunPerf(cfg.Config, ui, args[0], dataFile, opts); err != nil {
+				return errors.Wrap(err, "failed to run the load test")
+			}
+			return nil
+		}),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	f := cmd.Flags()
+	initFlagSet(f, ui.Writer())
+	f.IntVarP(&opts.Concurrency, "concurrency", "c", 10, "the number of workers which call the method concurrently")
+	f.DurationVarP(&opts.Duration, "duration", "d", 10*time.Second, "how long to call the method")
+	f.StringVar(&dataFile, "data", "", `the JSON file of the request. "-" means stdin. an empty request is sent by default`)
+	f.Float64Var(&opts.Rate, "rps", 0, "the maximum number of calls per second in total. 0 means unlimited")
+	f.StringArrayVar(&stages, "stage", nil, `a stage of the rate such that "100:30s" or "10-100:1m". it can be specified multiple times`)
+	cmd.SetHelpFunc(usageFunc(ui.Writer(), nil))
+	return cmd
+}
+
 func runREPLCommand(cfg *mergedConfig, ui cui.UI) error {
 	cache, err := cache.Get()
 	if err != nil {
@@ -283,6 +704,29 @@ func runREPLCommand(cfg *mergedConfig, ui cui.UI) error {
 	return nil
 }
 
+// runScriptCommand executes the script file fname. Unlike runREPLCommand, it doesn't check application updates
+// because it is used non-interactively.
+func runScriptCommand(cfg *mergedConfig, ui cui.UI, fname, reportFile string) error {
+	if reportFile != "" {
+		if err := report.ValidateFileName(reportFile); err != nil {
+			return err
+		}
+	}
+	var in io.Reader = os.Stdin
+	if fname != "-" {
+		f, err := os.Open(fname)
+		if err != nil {
+			return errors.Wrap(err, "failed to open the script file")
+		}
+		defer f.Close()
+		in = f
+	}
+	if err := mode.RunAsScriptMode(cfg.Config, ui, in, reportFile); err != nil {
+		return errors.Wrap(err, "failed to run the script")
+	}
+	return nil
+}
+
 func initFlagSet(f *pflag.FlagSet, w io.Writer) {
 	f.SortFlags = false
 	f.SetOutput(w)
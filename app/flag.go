@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ktr0731/go-multierror"
 	"github.com/pkg/errors"
@@ -24,6 +25,7 @@ type flags struct {
 
 	repl struct {
 		silent bool
+		watch  bool
 	}
 
 	common struct {
@@ -31,16 +33,67 @@ type flags struct {
 		service    string
 		path       []string
 		proto      []string
+		autoPath   bool
+		protoset   []string
+		bsr        string
 		host       string
 		port       string
 		header     map[string][]string
 		web        bool
+		twirp      bool
+		twirpEnc   string
+		transcode  bool
+		proxy      string
+		h2c        bool
 		reflection bool
+		noCache    bool
 		tls        bool
 		cacert     string
 		cert       string
 		certKey    string
 		serverName string
+		timeout    time.Duration
+
+		keepaliveTime          time.Duration
+		keepaliveTimeout       time.Duration
+		keepaliveWithoutStream bool
+		initialWindowSize      int32
+		initialConnWindowSize  int32
+		maxHeaderListSize      uint32
+		maxRecvMsgSize         int
+		maxSendMsgSize         int
+		compression            string
+		retry                  int
+		retryBackoff           time.Duration
+		retryMaxBackoff        time.Duration
+		retryCodes             []string
+		connectTimeout         time.Duration
+		waitForReady           bool
+		record                 string
+		replay                 string
+		lbPolicy               string
+		xdsBootstrap           string
+		tracePropagation       bool
+		traceparent            string
+		otlpEndpoint           string
+		oauth2Flow             string
+		oauth2TokenURL         string
+		oauth2DeviceAuthURL    string
+		oauth2ClientID         string
+		oauth2ClientSecret     string
+		oauth2Scopes           []string
+		jwtKey                 string
+		jwtAlg                 string
+		jwtClaims              string
+		jwtClaimsFile          string
+		jwtHeader              string
+		jwtExpiry              time.Duration
+		authProvider           string
+		authHeader             string
+		authValue              string
+		authUsername           string
+		authPassword           string
+		authCommand            string
 	}
 
 	meta struct {
@@ -69,6 +122,37 @@ func (f *flags) validate() error {
 	return result
 }
 
+// -- hosts Value
+// hostsValue is a comma-separated list of hosts. If the flag is specified multiple times, hosts are appended.
+type hostsValue struct {
+	value   *string
+	changed bool
+}
+
+func newHostsValue(val string, p *string) *hostsValue {
+	*p = val
+	return &hostsValue{value: p}
+}
+
+func (h *hostsValue) Set(val string) error {
+	if h.changed {
+		*h.value += "," + val
+	} else {
+		*h.value = val
+	}
+	h.changed = true
+	return nil
+}
+
+// Type returns "string" so that the value is bound to the config as a string.
+func (h *hostsValue) Type() string {
+	return "string"
+}
+
+func (h *hostsValue) String() string {
+	return *h.value
+}
+
 // -- stringToString Value
 type stringToStringSliceValue struct {
 	value   *map[string][]string
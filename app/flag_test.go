@@ -40,3 +40,30 @@ func Test_stringToStringSliceValue(t *testing.T) {
 		})
 	}
 }
+
+func Test_hostsValue(t *testing.T) {
+	cases := []struct {
+		in       []string
+		expected string
+	}{
+		{in: nil, expected: "localhost"},
+		{in: []string{"10.0.0.1"}, expected: "10.0.0.1"},
+		{in: []string{"10.0.0.1,10.0.0.2"}, expected: "10.0.0.1,10.0.0.2"},
+		{in: []string{"10.0.0.1", "10.0.0.2:50052"}, expected: "10.0.0.1,10.0.0.2:50052"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.expected, func(t *testing.T) {
+			var s string
+			v := newHostsValue("localhost", &s)
+			for _, in := range c.in {
+				if err := v.Set(in); err != nil {
+					t.Fatalf("Set must not return an error, but got '%s'", err)
+				}
+			}
+			if actual := v.String(); c.expected != actual {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
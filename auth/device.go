@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// defaultPollInterval is the polling interval of the device code flow used if the server doesn't specify it.
+	defaultPollInterval = 5 * time.Second
+	// defaultDeviceCodeLifetime is the lifetime of device codes used if the server doesn't specify it.
+	defaultDeviceCodeLifetime = 5 * time.Minute
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+type deviceAuthResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURL is used instead of VerificationURI by some providers such that Google.
+	VerificationURL         string `json:"verification_url"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthError is the error response defined by RFC 6749.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// deviceCode runs the device authorization grant defined by RFC 8628. It shows the verification URI and the user code
+// to users, then polls the token endpoint until users authorize Evans.
+func (o *OAuth2) deviceCode(ctx context.Context) (*oauth2.Token, error) {
+	v := url.Values{"client_id": {o.cfg.ClientID}}
+	if len(o.cfg.Scopes) != 0 {
+		v.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	var da deviceAuthResponse
+	if err := postForm(ctx, o.cfg.DeviceAuthURL, v, &da); err != nil {
+		return nil, errors.Wrap(err, "failed to start the device authorization")
+	}
+	uri := da.VerificationURIComplete
+	if uri == "" {
+		uri = da.VerificationURI
+	}
+	if uri == "" {
+		uri = da.VerificationURL
+	}
+	fmt.Fprintf(o.w, "To authorize Evans, open %s and enter the code %s\n", uri, da.UserCode)
+
+	interval := defaultPollInterval
+	if da.Interval > 0 {
+		interval = time.Duration(da.Interval) * time.Second
+	}
+	lifetime := defaultDeviceCodeLifetime
+	if da.ExpiresIn > 0 {
+		lifetime = time.Duration(da.ExpiresIn) * time.Second
+	}
+	deadline := time.Now().Add(lifetime)
+
+	v = url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {da.DeviceCode},
+		"client_id":   {o.cfg.ClientID},
+	}
+	if o.cfg.ClientSecret != "" {
+		v.Set("client_secret", o.cfg.ClientSecret)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("the device code expired before the authorization")
+		}
+
+		var tr tokenResponse
+		err := postForm(ctx, o.cfg.TokenURL, v, &tr)
+		if oerr, ok := err.(*oauthError); ok {
+			switch oerr.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to retrieve a token")
+		}
+		t := &oauth2.Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, RefreshToken: tr.RefreshToken}
+		if tr.ExpiresIn > 0 {
+			t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+		}
+		return t, nil
+	}
+}
+
+// postForm posts v to endpoint and decodes the JSON response into out.
+// If the server returns an error response of OAuth 2.0, it returns an *oauthError.
+func postForm(ctx context.Context, endpoint string, v url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "failed to create a request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to send a request")
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return errors.Wrap(err, "failed to read the response")
+	}
+	if res.StatusCode/100 != 2 {
+		var oerr oauthError
+		if err := json.Unmarshal(b, &oerr); err == nil && oerr.Code != "" {
+			return &oerr
+		}
+		return errors.Errorf("the server returned %s: %s", res.Status, strings.TrimSpace(string(b)))
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return errors.Wrap(err, "failed to decode the response")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/go-shellstring"
+	"github.com/pkg/errors"
+)
+
+// execExpiryDelta is the margin to regard credentials as expired before their expiry.
+const execExpiryDelta = 10 * time.Second
+
+// execCredential is the JSON output of credential commands.
+type execCredential struct {
+	// Token is sent as "authorization: Bearer <token>".
+	Token string `json:"token"`
+	// Headers are sent as they are.
+	Headers map[string]string `json:"headers"`
+	// Expiry is the time the credentials expire. Zero means they don't expire.
+	Expiry time.Time `json:"expiry"`
+}
+
+// Exec is a CredentialProvider which runs a user command to get credentials like exec credential plugins of kubectl.
+// It allows users to get credentials from any sources such that secret stores and internal token vendors.
+type Exec struct {
+	args []string
+
+	mu     sync.Mutex
+	md     map[string]string
+	expiry time.Time
+
+	now func() time.Time
+}
+
+// NewExec returns a new Exec which runs command. command is split into arguments like shells.
+//
+// The command must write credentials to stdout. If the output is a JSON object, it is decoded as
+// {"token": "...", "headers": {"key": "value"}, "expiry": "2006-01-02T15:04:05Z"}, all keys of which are optional.
+// Otherwise, the whole output is used as the token. Credentials are cached until they expire or they are invalidated.
+func NewExec(command string) (*Exec, error) {
+	args, err := shellstring.Parse(command)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the command '%s'", command)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("the credential command is empty")
+	}
+	return &Exec{args: args, now: time.Now}, nil
+}
+
+// RequestMetadata returns the cached credentials, or runs the command if they are not cached or expired.
+func (e *Exec) RequestMetadata(ctx context.Context) (map[string]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.md != nil && (e.expiry.IsZero() || e.now().Add(execExpiryDelta).Before(e.expiry)) {
+		return e.md, nil
+	}
+	cred, err := e.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string]string, len(cred.Headers)+1)
+	for k, v := range cred.Headers {
+		md[strings.ToLower(k)] = v
+	}
+	if cred.Token != "" {
+		md["authorization"] = "Bearer " + cred.Token
+	}
+	if len(md) == 0 {
+		return nil, errors.Errorf("the credential command '%s' returned no credentials", e.args[0])
+	}
+	e.md, e.expiry = md, cred.Expiry
+	return md, nil
+}
+
+// Invalidate discards the cached credentials. The next RequestMetadata runs the command again.
+func (e *Exec) Invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.md = nil
+}
+
+func (e *Exec) run(ctx context.Context) (*execCredential, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.args[0], e.args[1:]...)
+	cmd.Stdout = &stdout
+	// Commands may show prompts or errors to users.
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "failed to run the credential command '%s'", e.args[0])
+	}
+	out := bytes.TrimSpace(stdout.Bytes())
+	if !bytes.HasPrefix(out, []byte("{")) {
+		return &execCredential{Token: string(out)}, nil
+	}
+	var cred execCredential
+	if err := json.Unmarshal(out, &cred); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the output of the credential command '%s'", e.args[0])
+	}
+	return &cred, nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// counterCommand returns a command which prints out. %d in out is replaced with the number of runs.
+func counterCommand(t *testing.T, out string) (string, func() int) {
+	dir := t.TempDir()
+	counter, script := filepath.Join(dir, "counter"), filepath.Join(dir, "cred.sh")
+	body := fmt.Sprintf("echo x >> %s\nn=$(wc -l < %s | tr -d ' ')\ncat <<EOF\n%s\nEOF\n", counter, counter, strings.Replace(out, "%d", "$n", -1))
+	if err := ioutil.WriteFile(script, []byte(body), 0600); err != nil {
+		t.Fatalf("failed to write the script: %s", err)
+	}
+	return "sh " + script, func() int {
+		b, _ := ioutil.ReadFile(counter)
+		return strings.Count(string(b), "\n")
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Run("token", func(t *testing.T) {
+		cmd, runs := counterCommand(t, "token%d")
+		e, err := NewExec(cmd)
+		if err != nil {
+			t.Fatalf("NewExec must not return an error, but got '%s'", err)
+		}
+		for i := 0; i < 2; i++ {
+			md, err := e.RequestMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(map[string]string{"authorization": "Bearer token1"}, md); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		}
+		if n := runs(); n != 1 {
+			t.Errorf("credentials without expiry must be cached, but the command ran %d times", n)
+		}
+
+		e.Invalidate()
+		md, err := e.RequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+		}
+		if v := md["authorization"]; v != "Bearer token2" {
+			t.Errorf("invalidated credentials must be discarded, but got '%s'", v)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		cmd, runs := counterCommand(t, `{"token": "token%d", "headers": {"X-Tenant": "acme"}, "expiry": "2020-01-01T00:01:00Z"}`)
+		e, err := NewExec(cmd)
+		if err != nil {
+			t.Fatalf("NewExec must not return an error, but got '%s'", err)
+		}
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		e.now = func() time.Time { return now }
+
+		md, err := e.RequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff(map[string]string{"authorization": "Bearer token1", "x-tenant": "acme"}, md); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+
+		now = now.Add(55 * time.Second)
+		if _, err := e.RequestMetadata(context.Background()); err != nil {
+			t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+		}
+		if n := runs(); n != 2 {
+			t.Errorf("credentials which are about to expire must be acquired again, but the command ran %d times", n)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		for _, cmd := range []string{"false", "echo", `echo '{"token": 1}'`} {
+			e, err := NewExec(cmd)
+			if err != nil {
+				t.Fatalf("NewExec must not return an error, but got '%s'", err)
+			}
+			if _, err := e.RequestMetadata(context.Background()); err == nil {
+				t.Errorf("RequestMetadata must return an error for '%s', but got nil", cmd)
+			}
+		}
+		if _, err := NewExec(""); err == nil {
+			t.Error("NewExec must return an error for an empty command, but got nil")
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWTConfig is the configuration of JWTs signed by Evans.
+type JWTConfig struct {
+	// KeyFile is a PEM encoded RSA or ECDSA private key, or a file which has an HMAC secret.
+	KeyFile string
+	// Algorithm is the signing algorithm such that "RS256". If it is empty, it is inferred from the key.
+	Algorithm string
+	// Claims is a JSON object of claims. It overrides claims in ClaimsFile.
+	Claims string
+	// ClaimsFile is a JSON file which has an object of claims.
+	ClaimsFile string
+	// Header is the metadata key the token is sent as. The token is sent as "Bearer <token>" if it is "authorization".
+	Header string
+	// Expiry is the lifetime of tokens. "iat" and "exp" claims are set unless the claims have them. Zero means tokens
+	// don't expire.
+	Expiry time.Duration
+}
+
+// JWT signs a JWT for every RPC. It is useful to test authentication of servers without identity providers.
+type JWT struct {
+	alg    string
+	key    interface{}
+	claims map[string]interface{}
+	header string
+	expiry time.Duration
+
+	now func() time.Time
+}
+
+// NewJWT loads the key and the claims specified by cfg, then returns a new JWT.
+func NewJWT(cfg JWTConfig) (*JWT, error) {
+	b, err := ioutil.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the key file")
+	}
+	key, err := parseKey(b)
+	if err != nil {
+		return nil, err
+	}
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = defaultAlgorithm(key)
+	}
+	if _, err := signingHash(alg, key); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if cfg.ClaimsFile != "" {
+		b, err := ioutil.ReadFile(cfg.ClaimsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the claims file")
+		}
+		if err := decodeClaims(b, claims); err != nil {
+			return nil, errors.Wrapf(err, "invalid claims in '%s'", cfg.ClaimsFile)
+		}
+	}
+	if cfg.Claims != "" {
+		if err := decodeClaims([]byte(cfg.Claims), claims); err != nil {
+			return nil, errors.Wrap(err, "invalid claims")
+		}
+	}
+
+	header := strings.ToLower(cfg.Header)
+	if header == "" {
+		header = "authorization"
+	}
+	return &JWT{alg: alg, key: key, claims: claims, header: header, expiry: cfg.Expiry, now: time.Now}, nil
+}
+
+// RequestMetadata returns the header which has a newly signed token.
+func (j *JWT) RequestMetadata(ctx context.Context) (map[string]string, error) {
+	tok, err := j.Sign()
+	if err != nil {
+		return nil, err
+	}
+	if j.header == "authorization" {
+		tok = "Bearer " + tok
+	}
+	return map[string]string{j.header: tok}, nil
+}
+
+// Sign returns a new token signed with the key.
+func (j *JWT) Sign() (string, error) {
+	claims := make(map[string]interface{}, len(j.claims)+2)
+	for k, v := range j.claims {
+		claims[k] = v
+	}
+	if j.expiry > 0 {
+		now := j.now()
+		if _, ok := claims["iat"]; !ok {
+			claims["iat"] = now.Unix()
+		}
+		if _, ok := claims["exp"]; !ok {
+			claims["exp"] = now.Add(j.expiry).Unix()
+		}
+	}
+	h, err := json.Marshal(map[string]string{"alg": j.alg, "typ": "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode the JWT header")
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode the claims")
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := sign(j.alg, j.key, []byte(input))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign the JWT")
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// decodeClaims decodes b as a JSON object into claims. Numbers are kept as they are.
+func decodeClaims(b []byte, claims map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return errors.Wrap(err, "claims must be a JSON object")
+	}
+	for k, v := range m {
+		claims[k] = v
+	}
+	return nil
+}
+
+// parseKey parses b as a PEM encoded private key. If b is not PEM encoded, it is regarded as an HMAC secret.
+func parseKey(b []byte) (interface{}, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		secret := bytes.TrimRight(b, "\r\n")
+		if len(secret) == 0 {
+			return nil, errors.New("the key file is empty")
+		}
+		return secret, nil
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		return k, errors.Wrap(err, "failed to parse the RSA private key")
+	case "EC PRIVATE KEY":
+		k, err := x509.ParseECPrivateKey(block.Bytes)
+		return k, errors.Wrap(err, "failed to parse the EC private key")
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the private key")
+		}
+		switch k.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return k, nil
+		}
+		return nil, errors.Errorf("unsupported private key type %T", k)
+	}
+	return nil, errors.Errorf("unsupported PEM block type '%s'", block.Type)
+}
+
+func defaultAlgorithm(key interface{}) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 384:
+			return "ES384"
+		case 521:
+			return "ES512"
+		}
+		return "ES256"
+	}
+	return "HS256"
+}
+
+// signingHash returns the hash function of alg. It returns an error if alg cannot be used with key.
+func signingHash(alg string, key interface{}) (crypto.Hash, error) {
+	if len(alg) != 5 {
+		return 0, errors.Errorf("unsupported algorithm '%s'", alg)
+	}
+	var h crypto.Hash
+	switch alg[2:] {
+	case "256":
+		h = crypto.SHA256
+	case "384":
+		h = crypto.SHA384
+	case "512":
+		h = crypto.SHA512
+	default:
+		return 0, errors.Errorf("unsupported algorithm '%s'", alg)
+	}
+	var ok bool
+	switch alg[:2] {
+	case "HS":
+		_, ok = key.([]byte)
+	case "RS":
+		_, ok = key.(*rsa.PrivateKey)
+	case "ES":
+		_, ok = key.(*ecdsa.PrivateKey)
+	default:
+		return 0, errors.Errorf("unsupported algorithm '%s'", alg)
+	}
+	if !ok {
+		return 0, errors.Errorf("algorithm '%s' cannot be used with the key", alg)
+	}
+	return h, nil
+}
+
+func sign(alg string, key interface{}, input []byte) ([]byte, error) {
+	h, err := signingHash(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	if secret, ok := key.([]byte); ok {
+		mac := hmac.New(h.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+	hasher := h.New()
+	hasher.Write(input)
+	digest := hasher.Sum(nil)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, k, h, digest)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses the fixed-length concatenation of r and s instead of ASN.1.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+		return sig, nil
+	}
+	return nil, errors.Errorf("unsupported key type %T", key)
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFile(t *testing.T, name string, b []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(p, b, 0600); err != nil {
+		t.Fatalf("failed to write '%s': %s", p, err)
+	}
+	return p
+}
+
+func decodeSegment(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("failed to decode '%s': %s", s, err)
+	}
+	return b
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate an RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate an EC key: %s", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("failed to encode the EC key: %s", err)
+	}
+
+	cases := map[string]struct {
+		key    []byte
+		alg    string
+		verify func(t *testing.T, input string, sig []byte)
+	}{
+		"HMAC": {
+			key: []byte("secret\n"),
+			alg: "HS256",
+			verify: func(t *testing.T, input string, sig []byte) {
+				mac := hmac.New(sha256.New, []byte("secret"))
+				mac.Write([]byte(input))
+				if !hmac.Equal(mac.Sum(nil), sig) {
+					t.Error("invalid HMAC signature")
+				}
+			},
+		},
+		"RSA": {
+			key: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			alg: "RS256",
+			verify: func(t *testing.T, input string, sig []byte) {
+				digest := sha256.Sum256([]byte(input))
+				if err := rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+					t.Errorf("invalid RSA signature: %s", err)
+				}
+			},
+		},
+		"ECDSA": {
+			key: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+			alg: "ES256",
+			verify: func(t *testing.T, input string, sig []byte) {
+				if len(sig) != 64 {
+					t.Fatalf("the signature must be 64 bytes, but got %d bytes", len(sig))
+				}
+				digest := sha256.Sum256([]byte(input))
+				r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+				if !ecdsa.Verify(&ecKey.PublicKey, digest[:], r, s) {
+					t.Error("invalid ECDSA signature")
+				}
+			},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			j, err := NewJWT(JWTConfig{
+				KeyFile:    writeFile(t, "key", c.key),
+				Claims:     `{"sub": "alice", "admin": true}`,
+				ClaimsFile: writeFile(t, "claims.json", []byte(`{"sub": "bob", "aud": "api", "n": 12345678901}`)),
+				Expiry:     time.Hour,
+			})
+			if err != nil {
+				t.Fatalf("NewJWT must not return an error, but got '%s'", err)
+			}
+			j.now = func() time.Time { return time.Unix(1600000000, 0) }
+
+			md, err := j.RequestMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+			}
+			tok := strings.TrimPrefix(md["authorization"], "Bearer ")
+			seg := strings.Split(tok, ".")
+			if len(seg) != 3 {
+				t.Fatalf("the token must have 3 segments, but got '%s'", tok)
+			}
+
+			var header map[string]string
+			if err := json.Unmarshal(decodeSegment(t, seg[0]), &header); err != nil {
+				t.Fatalf("failed to decode the header: %s", err)
+			}
+			if diff := cmp.Diff(map[string]string{"alg": c.alg, "typ": "JWT"}, header); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+
+			expectedClaims := `{"admin":true,"aud":"api","exp":1600003600,"iat":1600000000,"n":12345678901,"sub":"alice"}`
+			if claims := string(decodeSegment(t, seg[1])); claims != expectedClaims {
+				t.Errorf("expected claims %s, but got %s", expectedClaims, claims)
+			}
+
+			c.verify(t, seg[0]+"."+seg[1], decodeSegment(t, seg[2]))
+		})
+	}
+}
+
+func TestJWT_header(t *testing.T) {
+	j, err := NewJWT(JWTConfig{KeyFile: writeFile(t, "key", []byte("secret")), Header: "X-JWT-Assertion"})
+	if err != nil {
+		t.Fatalf("NewJWT must not return an error, but got '%s'", err)
+	}
+	md, err := j.RequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+	}
+	tok, ok := md["x-jwt-assertion"]
+	if !ok || strings.HasPrefix(tok, "Bearer ") {
+		t.Errorf("the token must be sent as the x-jwt-assertion header without the scheme, but got %v", md)
+	}
+	if claims := string(decodeSegment(t, strings.Split(tok, ".")[1])); claims != "{}" {
+		t.Errorf("tokens without expiry must not have iat and exp claims, but got %s", claims)
+	}
+}
+
+func TestNewJWT_error(t *testing.T) {
+	secret := writeFile(t, "secret", []byte("secret"))
+	cases := map[string]JWTConfig{
+		"no key file":                  {KeyFile: filepath.Join(t.TempDir(), "missing")},
+		"empty key":                    {KeyFile: writeFile(t, "empty", nil)},
+		"unsupported algorithm":        {KeyFile: secret, Algorithm: "none"},
+		"algorithm mismatch":           {KeyFile: secret, Algorithm: "RS256"},
+		"claims is not a JSON object":  {KeyFile: secret, Claims: `["sub"]`},
+		"unsupported PEM block":        {KeyFile: writeFile(t, "cert", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("foo")}))},
+		"claims file is not found":     {KeyFile: secret, ClaimsFile: filepath.Join(t.TempDir(), "missing.json")},
+		"broken RSA private key":       {KeyFile: writeFile(t, "rsa", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("foo")}))},
+		"claims file has invalid JSON": {KeyFile: secret, ClaimsFile: writeFile(t, "claims.json", []byte("{"))},
+	}
+	for name, cfg := range cases {
+		if _, err := NewJWT(cfg); err == nil {
+			t.Errorf("%s: NewJWT must return an error, but got nil", name)
+		}
+	}
+}
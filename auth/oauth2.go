@@ -0,0 +1,184 @@
+// Package auth provides credential providers which give credentials attached to every RPC such that OAuth 2.0
+// access tokens.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ktr0731/evans/cache"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth 2.0 flows supported by OAuth2.
+const (
+	FlowClientCredentials = "client_credentials"
+	FlowDeviceCode        = "device_code"
+)
+
+// OAuth2Config is the configuration of an OAuth 2.0 flow.
+type OAuth2Config struct {
+	// Flow is one of FlowClientCredentials or FlowDeviceCode.
+	Flow     string
+	TokenURL string
+	// DeviceAuthURL is the device authorization endpoint. It is required by FlowDeviceCode.
+	DeviceAuthURL string
+	ClientID      string
+	// ClientSecret is optional for FlowDeviceCode because public clients don't have secrets.
+	ClientSecret string
+	Scopes       []string
+}
+
+// OAuth2 acquires access tokens by an OAuth 2.0 flow. Acquired tokens are cached in memory and on disk,
+// and they are refreshed transparently when they expire.
+type OAuth2 struct {
+	cfg OAuth2Config
+	// w is the destination of instructions for users such that the user code of the device code flow.
+	w io.Writer
+
+	mu  sync.Mutex
+	ts  oauth2.TokenSource
+	src *tokenSource
+}
+
+// NewOAuth2 returns a new OAuth2. No tokens are acquired until RequestMetadata is called.
+func NewOAuth2(cfg OAuth2Config, w io.Writer) (*OAuth2, error) {
+	switch cfg.Flow {
+	case FlowClientCredentials:
+		if cfg.ClientSecret == "" {
+			return nil, errors.New("the client credentials flow requires the client secret")
+		}
+	case FlowDeviceCode:
+		if cfg.DeviceAuthURL == "" {
+			return nil, errors.New("the device code flow requires the device authorization URL")
+		}
+	default:
+		return nil, errors.Errorf(`unknown OAuth 2.0 flow '%s'. it must be one of "%s" or "%s"`, cfg.Flow, FlowClientCredentials, FlowDeviceCode)
+	}
+	if cfg.TokenURL == "" || cfg.ClientID == "" {
+		return nil, errors.New("OAuth 2.0 requires the token URL and the client ID")
+	}
+	o := &OAuth2{cfg: cfg, w: w}
+	cached := o.loadCache()
+	o.src = &tokenSource{o: o, last: cached}
+	o.ts = oauth2.ReuseTokenSource(cached, o.src)
+	return o, nil
+}
+
+// RequestMetadata returns the authorization header which has a valid access token.
+func (o *OAuth2) RequestMetadata(ctx context.Context) (map[string]string, error) {
+	o.mu.Lock()
+	ts := o.ts
+	o.mu.Unlock()
+	t, err := ts.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to acquire an OAuth 2.0 access token")
+	}
+	return map[string]string{"authorization": t.Type() + " " + t.AccessToken}, nil
+}
+
+// Invalidate discards the current access token even if it hasn't expired, such that it was revoked by the server.
+// The next RequestMetadata refreshes the token or runs the flow again.
+func (o *OAuth2) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ts = oauth2.ReuseTokenSource(nil, o.src)
+}
+
+// tokenSource acquires a new token when the current one expires. It refreshes the last token if it has a refresh
+// token, otherwise it runs the flow again.
+type tokenSource struct {
+	o *OAuth2
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := context.Background()
+	var t *oauth2.Token
+	if s.last != nil && s.last.RefreshToken != "" {
+		var err error
+		t, err = s.o.refresh(ctx, s.last)
+		if err != nil {
+			logger.Printf("failed to refresh the OAuth 2.0 token, acquire a new one: %s", err)
+		}
+	}
+	if t == nil {
+		var err error
+		t, err = s.o.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.last = t
+	s.o.saveCache(t)
+	return t, nil
+}
+
+func (o *OAuth2) acquire(ctx context.Context) (*oauth2.Token, error) {
+	if o.cfg.Flow == FlowDeviceCode {
+		return o.deviceCode(ctx)
+	}
+	cfg := &clientcredentials.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.cfg.ClientSecret,
+		TokenURL:     o.cfg.TokenURL,
+		Scopes:       o.cfg.Scopes,
+	}
+	return cfg.Token(ctx)
+}
+
+func (o *OAuth2) refresh(ctx context.Context, t *oauth2.Token) (*oauth2.Token, error) {
+	cfg := &oauth2.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.cfg.ClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: o.cfg.TokenURL},
+		Scopes:       o.cfg.Scopes,
+	}
+	// The access token is dropped to force refreshing.
+	return cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: t.RefreshToken}).Token()
+}
+
+// cacheKey identifies tokens issued for the configuration.
+func (o *OAuth2) cacheKey() string {
+	return strings.Join([]string{o.cfg.Flow, o.cfg.TokenURL, o.cfg.ClientID, strings.Join(o.cfg.Scopes, " ")}, "\n")
+}
+
+// loadCache returns the token cached on disk. It returns nil if there is no cache.
+func (o *OAuth2) loadCache() *oauth2.Token {
+	b, ok, err := cache.GetToken(o.cacheKey())
+	if err != nil {
+		logger.Printf("failed to get the cached OAuth 2.0 token: %s", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var t oauth2.Token
+	if err := json.Unmarshal(b, &t); err != nil {
+		logger.Printf("failed to decode the cached OAuth 2.0 token: %s", err)
+		return nil
+	}
+	return &t
+}
+
+// saveCache writes t to the disk. Errors are only logged because the token can be acquired again.
+func (o *OAuth2) saveCache(t *oauth2.Token) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		logger.Printf("failed to encode the OAuth 2.0 token: %s", err)
+		return
+	}
+	if err := cache.SaveToken(o.cacheKey(), b); err != nil {
+		logger.Printf("failed to cache the OAuth 2.0 token: %s", err)
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupCache isolates the token cache from the user's one.
+func setupCache(t *testing.T) {
+	old, ok := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func TestOAuth2_clientCredentials(t *testing.T) {
+	setupCache(t)
+
+	var issued int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse the form: %s", err)
+		}
+		if gt := r.PostForm.Get("grant_type"); gt != "client_credentials" {
+			t.Errorf("expected grant_type client_credentials, but got '%s'", gt)
+		}
+		if scope := r.PostForm.Get("scope"); scope != "read write" {
+			t.Errorf("expected scope 'read write', but got '%s'", scope)
+		}
+		n := atomic.AddInt32(&issued, 1)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": fmt.Sprintf("token%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer srv.Close()
+
+	cfg := OAuth2Config{
+		Flow:         FlowClientCredentials,
+		TokenURL:     srv.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+	o, err := NewOAuth2(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewOAuth2 must not return an error, but got '%s'", err)
+	}
+	for i := 0; i < 2; i++ {
+		md, err := o.RequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+		}
+		if v := md["authorization"]; v != "Bearer token1" {
+			t.Errorf("expected 'Bearer token1', but got '%s'", v)
+		}
+	}
+
+	// A new OAuth2 uses the token cached on disk.
+	o, err = NewOAuth2(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewOAuth2 must not return an error, but got '%s'", err)
+	}
+	if md, err := o.RequestMetadata(context.Background()); err != nil || md["authorization"] != "Bearer token1" {
+		t.Errorf("expected the cached token, but got '%v' (err: %v)", md, err)
+	}
+	if n := atomic.LoadInt32(&issued); n != 1 {
+		t.Errorf("the token must be issued only once, but issued %d times", n)
+	}
+
+	// An invalidated token is discarded even if it hasn't expired.
+	o.Invalidate()
+	if md, err := o.RequestMetadata(context.Background()); err != nil || md["authorization"] != "Bearer token2" {
+		t.Errorf("expected a new token, but got '%v' (err: %v)", md, err)
+	}
+}
+
+func TestOAuth2_deviceCode(t *testing.T) {
+	setupCache(t)
+	old := defaultPollInterval
+	defaultPollInterval = time.Millisecond
+	defer func() { defaultPollInterval = old }()
+
+	var polled int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       60,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse the form: %s", err)
+		}
+		switch r.PostForm.Get("grant_type") {
+		case deviceCodeGrantType:
+			if atomic.AddInt32(&polled, 1) < 3 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"access_token":  "token",
+				"token_type":    "Bearer",
+				"refresh_token": "refresh",
+				"expires_in":    1,
+			})
+		case "refresh_token":
+			if rt := r.PostForm.Get("refresh_token"); rt != "refresh" {
+				t.Errorf("expected refresh token 'refresh', but got '%s'", rt)
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"access_token": "refreshed",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		default:
+			t.Errorf("unexpected grant_type '%s'", r.PostForm.Get("grant_type"))
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	o, err := NewOAuth2(OAuth2Config{
+		Flow:          FlowDeviceCode,
+		TokenURL:      srv.URL + "/token",
+		DeviceAuthURL: srv.URL + "/device",
+		ClientID:      "id",
+	}, &buf)
+	if err != nil {
+		t.Fatalf("NewOAuth2 must not return an error, but got '%s'", err)
+	}
+	md, err := o.RequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+	}
+	if v := md["authorization"]; v != "Bearer token" {
+		t.Errorf("expected 'Bearer token', but got '%s'", v)
+	}
+	if !strings.Contains(buf.String(), "open https://example.com/device and enter the code ABCD-EFGH") {
+		t.Errorf("the instruction must be shown, but got '%s'", buf.String())
+	}
+
+	// The token expires within the expiry delta of oauth2, so it is refreshed by the refresh token.
+	md, err = o.RequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+	}
+	if v := md["authorization"]; v != "Bearer refreshed" {
+		t.Errorf("expected 'Bearer refreshed', but got '%s'", v)
+	}
+}
+
+func TestOAuth2_error(t *testing.T) {
+	setupCache(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "access_denied", "error_description": "denied"})
+	}))
+	defer srv.Close()
+
+	o, err := NewOAuth2(OAuth2Config{
+		Flow:          FlowDeviceCode,
+		TokenURL:      srv.URL,
+		DeviceAuthURL: srv.URL,
+		ClientID:      "id",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewOAuth2 must not return an error, but got '%s'", err)
+	}
+	_, err = o.RequestMetadata(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "access_denied: denied") {
+		t.Errorf("RequestMetadata must return the OAuth 2.0 error, but got '%v'", err)
+	}
+}
+
+func TestNewOAuth2(t *testing.T) {
+	cases := map[string]OAuth2Config{
+		"unknown flow":                  {Flow: "password", TokenURL: "http://localhost", ClientID: "id"},
+		"client credentials w/o secret": {Flow: FlowClientCredentials, TokenURL: "http://localhost", ClientID: "id"},
+		"device code w/o device URL":    {Flow: FlowDeviceCode, TokenURL: "http://localhost", ClientID: "id"},
+		"no client ID":                  {Flow: FlowDeviceCode, TokenURL: "http://localhost", DeviceAuthURL: "http://localhost"},
+	}
+	for name, cfg := range cases {
+		if _, err := NewOAuth2(cfg, nil); err == nil {
+			t.Errorf("%s: NewOAuth2 must return an error, but got nil", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+// Names of built-in credential providers.
+const (
+	ProviderStatic = "static"
+	ProviderBasic  = "basic"
+	ProviderOAuth2 = "oauth2"
+	ProviderJWT    = "jwt"
+	ProviderExec   = "exec"
+)
+
+// CredentialProvider provides metadata attached to every RPC such that the authorization header.
+// It is similar to credentials.PerRPCCredentials of gRPC, but it is available for all protocols Evans supports.
+type CredentialProvider interface {
+	// RequestMetadata returns metadata attached to an RPC. It may acquire new credentials if they expired.
+	RequestMetadata(ctx context.Context) (map[string]string, error)
+}
+
+// Invalidator is implemented by CredentialProviders which can discard cached credentials such that expired access
+// tokens. After Invalidate is called, RequestMetadata acquires new credentials.
+type Invalidator interface {
+	Invalidate()
+}
+
+type staticProvider map[string]string
+
+// NewStatic returns a CredentialProvider which always provides the header with value such that an API key.
+func NewStatic(header, value string) CredentialProvider {
+	return staticProvider{strings.ToLower(header): value}
+}
+
+// NewBasic returns a CredentialProvider which provides the authorization header of HTTP basic authentication.
+func NewBasic(username, password string) CredentialProvider {
+	v := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return staticProvider{"authorization": "Basic " + v}
+}
+
+func (p staticProvider) RequestMetadata(context.Context) (map[string]string, error) {
+	return p, nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStaticProviders(t *testing.T) {
+	cases := map[string]struct {
+		p        CredentialProvider
+		expected map[string]string
+	}{
+		"static": {
+			p:        NewStatic("X-API-Key", "secret"),
+			expected: map[string]string{"x-api-key": "secret"},
+		},
+		"basic": {
+			p:        NewBasic("alice", "p@ss"),
+			expected: map[string]string{"authorization": "Basic YWxpY2U6cEBzcw=="},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			md, err := c.p.RequestMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("RequestMetadata must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, md); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package batch provides a runner of batch files. A batch file is newline-delimited JSON or a JSON array,
+// and each entry is a request which names a method, headers and the request body.
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Request is an entry of a batch file.
+type Request struct {
+	// Method is the fully-qualified method name such that "api.Example.Unary".
+	Method string `json:"method"`
+	// Headers are sent with the request in addition to the default headers.
+	// References in the values are not expanded unlike the default headers.
+	Headers map[string]string `json:"headers"`
+	// Body is a request body, or an array of request bodies for client streaming and bidi streaming RPCs.
+	// If it is empty, an empty request is sent.
+	Body json.RawMessage `json:"body"`
+}
+
+// Load reads requests from r. The input is newline-delimited JSON, or a JSON array.
+func Load(r io.Reader) ([]*Request, error) {
+	br := bufio.NewReader(r)
+	isArray, err := startsWithArray(br)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(br)
+	dec.DisallowUnknownFields()
+
+	var reqs []*Request
+	if isArray {
+		if err := dec.Decode(&reqs); err != nil {
+			return nil, errors.Wrap(err, "failed to decode the batch file as a JSON array")
+		}
+	} else {
+		for {
+			var req Request
+			err := dec.Decode(&req)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode the request #%d", len(reqs)+1)
+			}
+			reqs = append(reqs, &req)
+		}
+	}
+	if len(reqs) == 0 {
+		return nil, errors.New("the batch file has no requests")
+	}
+	for i, req := range reqs {
+		if req == nil || req.Method == "" {
+			return nil, errors.Errorf("method of the request #%d is required", i+1)
+		}
+	}
+	return reqs, nil
+}
+
+// startsWithArray reports whether the first non-space character of r is '['. It doesn't consume the character.
+func startsWithArray(r *bufio.Reader) (bool, error) {
+	for {
+		c, _, err := r.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "failed to read the batch file")
+		}
+		if unicode.IsSpace(c) {
+			continue
+		}
+		if err := r.UnreadRune(); err != nil {
+			return false, errors.Wrap(err, "failed to read the batch file")
+		}
+		return c == '[', nil
+	}
+}
@@ -0,0 +1,62 @@
+package batch_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/batch"
+)
+
+func TestLoad(t *testing.T) {
+	expected := []*batch.Request{
+		{Method: "api.Example.Unary", Headers: map[string]string{"foo": "bar"}, Body: json.RawMessage(`{"name": "oumae"}`)},
+		{Method: "api.Example.ClientStreaming", Body: json.RawMessage(`[{"name": "oumae"}, {"name": "kousaka"}]`)},
+	}
+	cases := map[string]struct {
+		in       string
+		expected []*batch.Request
+		hasErr   bool
+	}{
+		"newline-delimited JSON": {
+			in: `{"method": "api.Example.Unary", "headers": {"foo": "bar"}, "body": {"name": "oumae"}}
+{"method": "api.Example.ClientStreaming", "body": [{"name": "oumae"}, {"name": "kousaka"}]}
+`,
+			expected: expected,
+		},
+		"JSON array": {
+			in: `
+  [
+    {"method": "api.Example.Unary", "headers": {"foo": "bar"}, "body": {"name": "oumae"}},
+    {"method": "api.Example.ClientStreaming", "body": [{"name": "oumae"}, {"name": "kousaka"}]}
+  ]`,
+			expected: expected,
+		},
+		"empty":          {in: "", hasErr: true},
+		"empty array":    {in: "[]", hasErr: true},
+		"no method":      {in: `{"body": {}}`, hasErr: true},
+		"null entry":     {in: `[null]`, hasErr: true},
+		"unknown key":    {in: `{"method": "api.Example.Unary", "foo": "bar"}`, hasErr: true},
+		"invalid JSON":   {in: `{"method": "api.Example.Unary"`, hasErr: true},
+		"invalid header": {in: `{"method": "api.Example.Unary", "headers": {"foo": 1}}`, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			reqs, err := batch.Load(strings.NewReader(c.in))
+			if c.hasErr {
+				if err == nil {
+					t.Fatalf("Load must return an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, reqs); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	idlproto "github.com/ktr0731/evans/idl/proto"
+	"github.com/ktr0731/evans/perf"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Runner runs requests by calling methods of the spec with the client.
+type Runner struct {
+	spec     idl.Spec
+	client   grpc.Client
+	headers  map[string][]string
+	scoped   grpc.ScopedHeaders
+	timeout  time.Duration
+	jsonOpts format.JSONOptions
+}
+
+// NewRunner returns a new runner. headers are sent with all requests in addition to headers of each request,
+// and scoped headers are sent with requests to methods in the scope.
+// If timeout is positive, each call is canceled after timeout. Responses are formatted as JSON by jsonOpts.
+func NewRunner(spec idl.Spec, client grpc.Client, headers map[string][]string, scoped grpc.ScopedHeaders, timeout time.Duration, jsonOpts format.JSONOptions) *Runner {
+	return &Runner{spec: spec, client: client, headers: headers, scoped: scoped, timeout: timeout, jsonOpts: jsonOpts}
+}
+
+// Result is the result of a request.
+type Result struct {
+	// Index is the 0-origin position of the request in the batch file.
+	Index  int    `json:"index"`
+	Method string `json:"method"`
+	// Status is the status returned from the server. It is nil if the request failed before or without
+	// receiving the status, such that the method is unknown or the request body is invalid.
+	Status    *Status           `json:"status,omitempty"`
+	Responses []json.RawMessage `json:"responses,omitempty"`
+	// Error is the reason why the request failed without the status.
+	Error string `json:"error,omitempty"`
+	// Time is the elapsed time in seconds.
+	Time float64 `json:"time"`
+}
+
+// Status is a gRPC status.
+type Status struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Failed reports whether the request failed or returned a non-OK status.
+func (r *Result) Failed() bool {
+	return r.Status == nil || r.Status.Code != codes.OK.String()
+}
+
+// Options is the settings of Run.
+type Options struct {
+	// Parallel is the maximum number of requests run concurrently. Requests are run sequentially if it is 1 or less.
+	Parallel int
+	// Rate is the maximum number of requests started per second. If it is zero, requests are not limited.
+	Rate float64
+	// Stages is the schedule of the rate. If it is not empty, Rate must be zero. The rate at the end of the last stage
+	// is kept after it.
+	Stages []perf.Stage
+}
+
+// Run runs reqs with opts. f is called with each result in the same order as reqs as soon as the result and
+// all preceding results are available.
+func (r *Runner) Run(ctx context.Context, reqs []*Request, opts Options, f func(*Result)) error {
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	limiterCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tokens, err := perf.Limit(limiterCtx, opts.Rate, opts.Stages)
+	if err != nil {
+		return err
+	}
+	// Default headers are expanded only once to not run commands for every request.
+	headers, err := grpc.ExpandHeaders(ctx, r.headers, nil)
+	if err != nil {
+		return err
+	}
+	scoped, err := r.scoped.Expand(ctx, nil)
+	if err != nil {
+		return err
+	}
+	results := make([]*Result, len(reqs))
+	done := make([]chan struct{}, len(reqs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sem := make(chan struct{}, parallel)
+		for i, req := range reqs {
+			sem <- struct{}{}
+			// The channel is closed only if ctx is canceled. Remaining requests fail immediately in that case.
+			if tokens != nil {
+				<-tokens
+			}
+			go func(i int, req *Request) {
+				defer func() { <-sem }()
+				results[i] = r.run(ctx, i, req, scoped.For(req.Method, headers))
+				close(done[i])
+			}(i, req)
+		}
+	}()
+	for i := range reqs {
+		<-done[i]
+		f(results[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// run runs req with headers in addition to headers of req.
+func (r *Runner) run(ctx context.Context, index int, req *Request, headers grpc.Headers) *Result {
+	start := time.Now()
+	res := &Result{Index: index, Method: req.Method}
+	defer func() {
+		res.Time = time.Since(start).Seconds()
+	}()
+
+	rpc, reqs, err := r.prepare(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	msgs, stat, err := grpc.CallWithOptions(ctx, r.client, rpc, reqs, grpc.CallOptions{
+		Headers:      headers,
+		ExtraHeaders: req.Headers,
+		Timeout:      r.timeout,
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = &Status{Code: stat.Code().String(), Message: stat.Message()}
+	for _, msg := range msgs {
+		m, ok := msg.(proto.Message)
+		if !ok {
+			res.Error = errors.Errorf("%T is not a Protocol Buffers message", msg).Error()
+			return res
+		}
+		b, err := r.jsonOpts.MarshalMessage(m)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.Responses = append(res.Responses, b)
+	}
+	return res
+}
+
+// prepare finds the RPC of req and decodes the request body.
+func (r *Runner) prepare(req *Request) (*grpc.RPC, []interface{}, error) {
+	rpc, err := idlproto.LookupRPC(r.spec, req.Method)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(req.Body) == 0 {
+		msg, err := rpc.RequestType.New()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to instantiate a request")
+		}
+		return rpc, []interface{}{msg}, nil
+	}
+
+	filler := fill.NewSilentFiller(bytes.NewReader(req.Body))
+	var msgs []interface{}
+	for {
+		msg, err := rpc.RequestType.New()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to instantiate a request")
+		}
+		err = filler.Fill(msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid request body")
+		}
+		msgs = append(msgs, msg)
+	}
+	return rpc, msgs, nil
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+const descriptorSetDirName = "descriptors"
+
+// GetDescriptorSet returns the serialized FileDescriptorSet cached for host.
+// hash identifies the schema the server provides. If the cached one was stored with another hash,
+// it is regarded as stale and GetDescriptorSet reports false as well as there is no cache for host.
+func GetDescriptorSet(host, hash string) ([]byte, bool, error) {
+	b, err := ioutil.ReadFile(resolveDescriptorSetPath(host, hash))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read the cached descriptor set")
+	}
+	return b, true, nil
+}
+
+// SaveDescriptorSet writes b to the cache as the serialized FileDescriptorSet for host and hash.
+// Stale caches stored for host with other hashes are removed.
+func SaveDescriptorSet(host, hash string, b []byte) error {
+	p := resolveDescriptorSetPath(host, hash)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return errors.Wrap(err, "failed to create the cache dir")
+	}
+	stale, err := filepath.Glob(filepath.Join(filepath.Dir(p), hostKey(host)+"-*.pb"))
+	if err != nil {
+		return errors.Wrap(err, "failed to list stale caches")
+	}
+	for _, s := range stale {
+		if err := os.Remove(s); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove the stale cache '%s'", s)
+		}
+	}
+	if err := ioutil.WriteFile(p, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write the descriptor set to the cache")
+	}
+	return nil
+}
+
+func resolveDescriptorSetPath(host, hash string) string {
+	return filepath.Join(xdgbasedir.CacheHome(), meta.AppName, descriptorSetDirName, hostKey(host)+"-"+hash+".pb")
+}
+
+// hostKey converts host to a string which can be used as a file name.
+func hostKey(host string) string {
+	sum := sha256.Sum256([]byte(host))
+	return hex.EncodeToString(sum[:])
+}
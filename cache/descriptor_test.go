@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDescriptorSet(t *testing.T) {
+	host := fmt.Sprintf("localhost:%d", time.Now().UnixNano())
+
+	if _, ok, err := GetDescriptorSet(host, "hash1"); err != nil || ok {
+		t.Fatalf("GetDescriptorSet must report no cache, but got ok=%t, err='%v'", ok, err)
+	}
+
+	if err := SaveDescriptorSet(host, "hash1", []byte("foo")); err != nil {
+		t.Fatalf("SaveDescriptorSet must not return an error, but got '%s'", err)
+	}
+	b, ok, err := GetDescriptorSet(host, "hash1")
+	if err != nil || !ok {
+		t.Fatalf("GetDescriptorSet must return the cache, but got ok=%t, err='%v'", ok, err)
+	}
+	if string(b) != "foo" {
+		t.Errorf("expected 'foo', but got '%s'", b)
+	}
+	fi, err := os.Stat(resolveDescriptorSetPath(host, "hash1"))
+	if err != nil {
+		t.Fatalf("Stat must not return an error, but got '%s'", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("the cache file must be readable only by the owner, but got %s", perm)
+	}
+
+	if _, ok, err := GetDescriptorSet(host, "hash2"); err != nil || ok {
+		t.Errorf("GetDescriptorSet must not return the cache stored with another hash, but got ok=%t, err='%v'", ok, err)
+	}
+	if _, ok, err := GetDescriptorSet("another"+host, "hash1"); err != nil || ok {
+		t.Errorf("GetDescriptorSet must not return the cache of another host, but got ok=%t, err='%v'", ok, err)
+	}
+
+	if err := SaveDescriptorSet(host, "hash2", []byte("bar")); err != nil {
+		t.Fatalf("SaveDescriptorSet must not return an error, but got '%s'", err)
+	}
+	if _, ok, err := GetDescriptorSet(host, "hash1"); err != nil || ok {
+		t.Errorf("the stale cache must be removed, but got ok=%t, err='%v'", ok, err)
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+const tokenDirName = "tokens"
+
+// GetToken returns the serialized token cached for key. It reports false if there is no cache for key.
+func GetToken(key string) ([]byte, bool, error) {
+	b, err := ioutil.ReadFile(resolveTokenPath(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read the cached token")
+	}
+	return b, true, nil
+}
+
+// SaveToken writes b to the cache as the serialized token for key.
+// Because tokens are credentials, the cache file is readable only by the owner.
+func SaveToken(key string, b []byte) error {
+	p := resolveTokenPath(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return errors.Wrap(err, "failed to create the cache dir")
+	}
+	if err := ioutil.WriteFile(p, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write the token to the cache")
+	}
+	return nil
+}
+
+func resolveTokenPath(key string) string {
+	return filepath.Join(xdgbasedir.CacheHome(), meta.AppName, tokenDirName, hostKey(key)+".json")
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	key := fmt.Sprintf("https://example.com/token:%d", time.Now().UnixNano())
+
+	if _, ok, err := GetToken(key); err != nil || ok {
+		t.Fatalf("GetToken must report no cache, but got ok=%t, err='%v'", ok, err)
+	}
+
+	if err := SaveToken(key, []byte("foo")); err != nil {
+		t.Fatalf("SaveToken must not return an error, but got '%s'", err)
+	}
+	defer os.Remove(resolveTokenPath(key))
+	b, ok, err := GetToken(key)
+	if err != nil || !ok {
+		t.Fatalf("GetToken must return the cache, but got ok=%t, err='%v'", ok, err)
+	}
+	if string(b) != "foo" {
+		t.Errorf("expected 'foo', but got '%s'", b)
+	}
+
+	fi, err := os.Stat(resolveTokenPath(key))
+	if err != nil {
+		t.Fatalf("Stat must not return an error, but got '%s'", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("the cache file must be readable only by the owner, but got %s", perm)
+	}
+}
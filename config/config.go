@@ -5,14 +5,18 @@ package config
 import (
 	"encoding/csv"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/k0kubun/pp"
+	"github.com/ktr0731/evans/auth"
 	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/meta"
+	"github.com/ktr0731/evans/tracing"
 	"github.com/ktr0731/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -26,34 +30,216 @@ var (
 )
 
 type Server struct {
+	// Host is the server host. Comma-separated hosts are balanced by LoadBalancingPolicy.
+	// Each host can have its own port such that "10.0.0.1:50051".
 	Host       string `toml:"host"`
 	Port       string `toml:"port"`
 	Reflection bool   `toml:"reflection"`
 	TLS        bool   `toml:"tls"`
 	Name       string `toml:"name"`
+	// NoCache disables the on-disk cache of descriptors fetched by gRPC reflection.
+	// The cache is keyed by the server address and the hash of services the server provides.
+	NoCache bool `toml:"noCache"`
+	// LoadBalancingPolicy is the policy to balance RPCs to the hosts, one of "pick_first" or "round_robin".
+	// If it is "round_robin" and Host is a single DNS name, all A records of it are used.
+	LoadBalancingPolicy string `toml:"loadBalancingPolicy"`
+	// XDSBootstrap is the path of the xDS bootstrap file. It is used if Host is an xDS target such that
+	// "xds:///service-name". If it is empty, the GRPC_XDS_BOOTSTRAP environment variable is used.
+	XDSBootstrap string `toml:"xdsBootstrap"`
 }
 
+// Header is request headers. Values can reference environment variables and commands such that ${API_KEY} and
+// $(cat token), which are expanded at call time.
 type Header map[string][]string
 
+// ScopedHeader is default headers which are sent only with RPCs in the scope.
+type ScopedHeader struct {
+	// Scope is a fully-qualified package, service or RPC name such that "api.Example".
+	Scope  string `toml:"scope"`
+	Header Header `toml:"header"`
+}
+
+// PropagatedHeader is a rule which copies values of a response header or trailer into a request header
+// such that a session token returned by the server is sent with subsequent requests.
+type PropagatedHeader struct {
+	// From is the key of the response header or trailer.
+	From string `toml:"from"`
+	// To is the key of the request header. If it is empty, From is used.
+	To string `toml:"to"`
+}
+
 type Request struct {
-	Header      Header `toml:"header"`
-	Web         bool   `toml:"web"`
-	CACertFile  string `toml:"caCertFile"`
-	CertFile    string `toml:"certFile"`
-	CertKeyFile string `toml:"certKeyFile"`
+	Header Header `toml:"header"`
+	// ScopedHeaders is default headers of packages, services and RPCs. Their values replace ones of the same key
+	// in Header and broader scopes.
+	ScopedHeaders []*ScopedHeader `toml:"scopedHeaders"`
+	// PropagatedHeaders is rules to copy response headers into headers of subsequent requests.
+	PropagatedHeaders []*PropagatedHeader `toml:"propagatedHeaders"`
+	Web               bool                `toml:"web"`
+	CACertFile        string              `toml:"caCertFile"`
+	CertFile          string              `toml:"certFile"`
+	CertKeyFile       string              `toml:"certKeyFile"`
+	// Timeout is the timeout for each RPC call. Zero means no timeout.
+	Timeout time.Duration `toml:"timeout"`
+	// Twirp sends requests with Twirp protocol instead of gRPC.
+	Twirp bool `toml:"twirp"`
+	// TwirpEncoding is the encoding of Twirp request bodies, one of "protobuf" or "json".
+	TwirpEncoding string `toml:"twirpEncoding"`
+	// Transcoding sends requests as REST requests annotated by google.api.http options instead of gRPC.
+	Transcoding bool `toml:"transcoding"`
+	// Proxy is the URL of the proxy used to connect to the server. Its scheme is one of "http", "socks5" or "socks5h".
+	// If it is empty, HTTPS_PROXY, ALL_PROXY and NO_PROXY environment variables are used.
+	Proxy string `toml:"proxy"`
+	// H2C sends Twirp or HTTP/JSON transcoding requests over cleartext HTTP/2 with prior knowledge.
+	H2C bool `toml:"h2c"`
+	// KeepaliveTime is the interval of gRPC keepalive pings. Zero disables keepalive pings.
+	KeepaliveTime time.Duration `toml:"keepaliveTime"`
+	// KeepaliveTimeout is the time to wait for the ack of a keepalive ping. Zero means the default of gRPC.
+	KeepaliveTimeout time.Duration `toml:"keepaliveTimeout"`
+	// KeepalivePermitWithoutStream sends keepalive pings even if there are no active streams.
+	KeepalivePermitWithoutStream bool `toml:"keepalivePermitWithoutStream"`
+	// InitialWindowSize is the HTTP/2 initial window size of each stream. Zero means the default of gRPC.
+	InitialWindowSize int32 `toml:"initialWindowSize"`
+	// InitialConnWindowSize is the HTTP/2 initial window size of each connection. Zero means the default of gRPC.
+	InitialConnWindowSize int32 `toml:"initialConnWindowSize"`
+	// MaxHeaderListSize is the HTTP/2 maximum header list size. Zero means the default of gRPC.
+	MaxHeaderListSize uint32 `toml:"maxHeaderListSize"`
+	// MaxRecvMsgSize is the maximum size in bytes of messages received from gRPC servers. Zero means 4MB, the default of gRPC.
+	MaxRecvMsgSize int `toml:"maxRecvMsgSize"`
+	// MaxSendMsgSize is the maximum size in bytes of messages sent to gRPC servers. Zero means the default of gRPC.
+	MaxSendMsgSize int `toml:"maxSendMsgSize"`
+	// Compression is the name of the compressor used to compress gRPC requests such that "gzip".
+	// Empty means no compression.
+	Compression string `toml:"compression"`
+	// Retry is the maximum number of retries of failed unary RPCs. Zero disables retries.
+	Retry int `toml:"retry"`
+	// RetryBackoff is the backoff before the first retry. It is doubled for each retry.
+	RetryBackoff time.Duration `toml:"retryBackoff"`
+	// RetryMaxBackoff is the upper limit of retry backoffs. Zero means no limit.
+	RetryMaxBackoff time.Duration `toml:"retryMaxBackoff"`
+	// RetryCodes is the status codes to be retried such that "UNAVAILABLE".
+	// If it is empty, UNAVAILABLE and RESOURCE_EXHAUSTED are retried.
+	RetryCodes []string `toml:"retryCodes"`
+	// ConnectTimeout is the timeout to connect to gRPC servers. If it is set, Evans waits for the connection at startup.
+	// Zero means the connection is established lazily. It is independent of Timeout.
+	ConnectTimeout time.Duration `toml:"connectTimeout"`
+	// WaitForReady makes RPCs wait until the connection becomes ready instead of failing immediately.
+	WaitForReady bool `toml:"waitForReady"`
+	// Record is the cassette file to record every RPC to. New interactions are appended if the file exists.
+	Record string `toml:"record"`
+	// Replay is the cassette file to answer RPCs from instead of connecting to the server.
+	Replay string `toml:"replay"`
+	// DenyDeprecated makes CLI mode and scripts fail to use deprecated services and RPCs or send requests which set
+	// deprecated fields unless --allow-deprecated is specified. Otherwise, they are only warned.
+	DenyDeprecated bool `toml:"denyDeprecated"`
+	// Trace writes the wire-level trace of gRPC connections and RPCs to stderr like curl -v.
+	// It is not supported by gRPC-Web, Twirp and HTTP/JSON transcoding.
+	Trace bool `toml:"trace"`
+	// TracePropagation sends the W3C traceparent header with every RPC. Each RPC starts a new trace unless
+	// Traceparent is set.
+	TracePropagation bool `toml:"tracePropagation"`
+	// Traceparent is the W3C traceparent header of an existing trace such that
+	// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". RPCs are sent as children of it.
+	Traceparent string `toml:"traceparent"`
+	// OTLPEndpoint is the OTLP/HTTP endpoint of an OpenTelemetry collector such that "http://localhost:4318".
+	// If it is set, client spans of RPCs are exported to it.
+	OTLPEndpoint string `toml:"otlpEndpoint"`
+	// OAuth2 acquires access tokens by an OAuth 2.0 flow and sends them as the authorization header.
+	OAuth2 OAuth2 `toml:"oauth2"`
+	// JWT signs JWTs from a local key and sends them with every RPC.
+	JWT JWT `toml:"jwt"`
+	// Auth selects the credential provider which gives credentials attached to every RPC.
+	Auth Auth `toml:"auth"`
+}
+
+// Auth is the configuration of the credential provider.
+type Auth struct {
+	// Provider is one of "static", "basic", "oauth2", "jwt" or "exec". If it is empty, OAuth 2.0 access tokens and JWTs
+	// are sent if request.oauth2 and request.jwt are configured.
+	Provider string `toml:"provider"`
+	// Header and Value are the header the static provider sends such that an API key.
+	Header string `toml:"header"`
+	Value  string `toml:"value"`
+	// Username and Password are used by the basic provider.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Command is the command the exec provider runs to get credentials.
+	Command string `toml:"command"`
+}
+
+// OAuth2 is the configuration of an OAuth 2.0 flow.
+type OAuth2 struct {
+	// Flow is one of "client_credentials" or "device_code". Empty disables OAuth 2.0.
+	Flow     string `toml:"flow"`
+	TokenURL string `toml:"tokenURL"`
+	// DeviceAuthURL is the device authorization endpoint used by the device code flow.
+	DeviceAuthURL string   `toml:"deviceAuthURL"`
+	ClientID      string   `toml:"clientID"`
+	ClientSecret  string   `toml:"clientSecret"`
+	Scopes        []string `toml:"scopes"`
 }
 
+// JWT is the configuration of JWTs signed by Evans for testing.
+type JWT struct {
+	// KeyFile is a PEM encoded RSA or ECDSA private key, or a file which has an HMAC secret. Empty disables JWTs.
+	KeyFile string `toml:"keyFile"`
+	// Algorithm is the signing algorithm such that "RS256". If it is empty, it is inferred from the key.
+	Algorithm string `toml:"algorithm"`
+	// Claims is a JSON object of claims. It overrides claims in ClaimsFile.
+	Claims     string `toml:"claims"`
+	ClaimsFile string `toml:"claimsFile"`
+	// Header is the header the token is sent as. The token is sent as "Bearer <token>" if it is "authorization".
+	Header string `toml:"header"`
+	// Expiry is the lifetime of tokens used for "iat" and "exp" claims. Zero means tokens don't expire.
+	Expiry time.Duration `toml:"expiry"`
+}
+
+// DefaultPromptFormat is the default value of repl.promptFormat. It shows the selected package and service,
+// and the server address such that "api.Example@127.0.0.1:50051> ".
+const DefaultPromptFormat = "{{with .DSN}}{{.}}@{{end}}{{.Host}}:{{.Port}}{{if .ShowConnection}} ({{.Connection}}){{end}}> "
+
 type REPL struct {
+	// PromptFormat is a Go template of the REPL prompt. See repl.promptData for the available fields.
+	// The color function such that {{color "red"}} changes the color of the prompt.
 	PromptFormat      string `toml:"promptFormat"`
 	InputPromptFormat string `toml:"inputPromptFormat"`
+	// PromptConnection shows the transport, whether TLS is enabled and the current profile in the prompt
+	// in addition to the server address.
+	PromptConnection bool `toml:"promptConnection"`
 
 	ColoredOutput bool `toml:"coloredOutput"`
 
 	Silent         bool   `toml:"silent"`
 	SplashTextPath string `toml:"splashTextPath"`
 
-	// TODO: Split history files between projects.
+	// HistorySize is the maximum number of commands kept in the command history.
 	HistorySize int `toml:"historySize"`
+	// HistoryPerHost splits the command history for each server address. If it is false, the history is shared.
+	HistoryPerHost bool `toml:"historyPerHost"`
+
+	// Watch reloads the spec when proto files are modified, or periodically if the spec is loaded from
+	// remote sources such that gRPC reflection.
+	Watch bool `toml:"watch"`
+}
+
+// Output is settings for formatting responses as JSON. Curl-like, YAML and newline-delimited JSON outputs
+// also respect SortKeys and EmitDefaults.
+type Output struct {
+	// Compact formats JSON in a single line.
+	Compact bool `toml:"compact"`
+	// Indent is the number of spaces for each indentation level. It is ignored if Compact is true.
+	Indent int `toml:"indent"`
+	// SortKeys orders keys alphabetically instead of the schema order.
+	SortKeys bool `toml:"sortKeys"`
+	// EmitDefaults emits fields that have the default value.
+	EmitDefaults bool `toml:"emitDefaults"`
+	// ProtoNames uses original field names in the proto file instead of lowerCamelCase JSON names.
+	// It is also applied to request bodies shown by the REPL such that call --edit.
+	ProtoNames bool `toml:"protoNames"`
+	// Int64AsNumber renders 64-bit integers as JSON numbers instead of strings.
+	Int64AsNumber bool `toml:"int64AsNumber"`
+	// BytesEncoding is the encoding of bytes fields, one of "base64", "hex" or "utf8".
+	BytesEncoding string `toml:"bytesEncoding"`
 }
 
 type Meta struct {
@@ -70,6 +256,37 @@ type Config struct {
 	Server  *Server  `toml:"server"`
 	Log     *Log     `toml:"log"`
 	Request *Request `toml:"request"`
+	Output  *Output  `toml:"output"`
+
+	// Profiles is named connection settings. Each profile is already merged with top-level settings.
+	// The key is a profile name.
+	Profiles map[string]*Profile `toml:"profiles"`
+}
+
+// Profile represents a named connection setting such that dev, stage and prod.
+// In config files, profiles are defined as [profiles.<name>] tables that have the same structure as the top-level
+// server, request and default tables. Keys in a profile override top-level ones.
+type Profile struct {
+	Server  *Server  `toml:"server"`
+	Request *Request `toml:"request"`
+	Default *Default `toml:"default"`
+}
+
+// ApplyProfile overwrites server, request and default settings of c with the profile named name.
+// The pointers of these settings are kept, so that holders of them can observe the changes.
+func (c *Config) ApplyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return errors.Errorf("unknown profile name '%s'", name)
+	}
+	*c.Server = *p.Server
+	*c.Default = *p.Default
+	*c.Request = *p.Request
+	c.Request.Header = make(Header, len(p.Request.Header))
+	for k, v := range p.Request.Header {
+		c.Request.Header[k] = append([]string(nil), v...)
+	}
+	return nil
 }
 
 // ValidationError contains errors that describes invalid config conditions.
@@ -86,34 +303,250 @@ func (e *ValidationError) Error() string {
 // For example, in the case of CLI mode, c must have package, service and call values.
 // Validate returns ValidationError if some conditions are invalid.
 func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// ValidateWithoutSpec validates c like Validate, but neither proto files nor gRPC reflection are required.
+// It is used by commands which don't load the spec such that health checking.
+func (c *Config) ValidateWithoutSpec() error {
+	return c.validate(false)
+}
+
+func (c *Config) validate(specRequired bool) error {
 	var result *multierror.Error
 	invalidCases := []struct {
 		name string
 		cond bool
 	}{
 		{"port must not be empty", len(c.Server.Port) == 0},
-		{"certFile config or --cert flag required", c.Request.CertFile == "" && c.Request.CertKeyFile != ""},
-		{"certKeyFile config or --certkey flag required", c.Request.CertFile != "" && c.Request.CertKeyFile == ""},
-		{"one or more proto files, or gRPC reflection required", len(c.Default.ProtoFile) == 0 && !c.Server.Reflection},
-		// TODO: support it.
-		{"currently, gRPC-Web with TLS communication is not supported", c.Request.Web && c.Server.TLS},
+		{
+			"one or more proto files, protosets, a BSR module, or gRPC reflection required",
+			specRequired && len(c.Default.ProtoFile) == 0 && len(c.Default.Protoset) == 0 && c.Default.BSR == "" && !c.Server.Reflection,
+		},
+		{
+			"only one of proto files, protosets, or a BSR module can be specified",
+			countTrue(len(c.Default.ProtoFile) != 0, len(c.Default.Protoset) != 0, c.Default.BSR != "") > 1,
+		},
+		{"output.indent config or --indent flag must not be negative", c.Output.Indent < 0},
+		{
+			`output.bytesEncoding config or --bytes-encoding flag must be one of "base64", "hex" or "utf8"`,
+			c.Output.BytesEncoding != "base64" && c.Output.BytesEncoding != "hex" && c.Output.BytesEncoding != "utf8",
+		},
 	}
 	for _, c := range invalidCases {
 		if c.cond {
 			result = multierror.Append(result, errors.New(c.name))
 		}
 	}
+	for _, err := range validateConnection(c.Server, c.Request) {
+		result = multierror.Append(result, err)
+	}
+	// Profiles are validated only connection settings because the others are inherited
+	// from the top-level settings.
+	for name, p := range c.Profiles {
+		for _, err := range validateConnection(p.Server, p.Request) {
+			result = multierror.Append(result, errors.Wrapf(err, "profile '%s'", name))
+		}
+	}
 	if result != nil {
 		return &ValidationError{Err: result}
 	}
 	return nil
 }
 
+// minWindowSize is the minimum HTTP/2 window size. Smaller sizes are ignored by gRPC.
+const minWindowSize = 65535
+
+// validateConnection validates server and TLS settings.
+func validateConnection(s *Server, r *Request) []error {
+	invalidCases := []struct {
+		name string
+		cond bool
+	}{
+		{"certFile config or --cert flag required", r.CertFile == "" && r.CertKeyFile != ""},
+		{"certKeyFile config or --certkey flag required", r.CertFile != "" && r.CertKeyFile == ""},
+		// TODO: support it.
+		{"currently, gRPC-Web with TLS communication is not supported", r.Web && s.TLS},
+		{"cannot use two or more of gRPC-Web, Twirp and HTTP/JSON transcoding", countTrue(r.Web, r.Twirp, r.Transcoding) > 1},
+		{"Twirp doesn't support gRPC reflection. specify proto files instead", r.Twirp && s.Reflection},
+		{"HTTP/JSON transcoding doesn't support gRPC reflection. specify proto files instead", r.Transcoding && s.Reflection},
+		{
+			`request.twirpEncoding config or --twirp-encoding flag must be one of "protobuf" or "json"`,
+			r.Twirp && r.TwirpEncoding != "protobuf" && r.TwirpEncoding != "json",
+		},
+		{"h2c cannot be used with TLS", r.H2C && s.TLS},
+		{"--h2c flag is only available for Twirp or HTTP/JSON transcoding", r.H2C && !r.Twirp && !r.Transcoding},
+		{"currently, gRPC-Web with request.proxy config or --proxy flag is not supported. use HTTPS_PROXY instead", r.Web && r.Proxy != ""},
+		{"request.keepaliveTime config or --keepalive-time flag must not be negative", r.KeepaliveTime < 0},
+		{"request.keepaliveTimeout config or --keepalive-timeout flag must not be negative", r.KeepaliveTimeout < 0},
+		{
+			"request.keepaliveTimeout config or --keepalive-timeout flag requires request.keepaliveTime config or --keepalive-time flag",
+			r.KeepaliveTimeout > 0 && r.KeepaliveTime == 0,
+		},
+		{
+			"request.initialWindowSize config or --initial-window-size flag must be 0 or 64KiB (65535) or more",
+			r.InitialWindowSize != 0 && r.InitialWindowSize < minWindowSize,
+		},
+		{
+			"request.initialConnWindowSize config or --initial-conn-window-size flag must be 0 or 64KiB (65535) or more",
+			r.InitialConnWindowSize != 0 && r.InitialConnWindowSize < minWindowSize,
+		},
+		{"request.maxRecvMsgSize config or --max-recv-msg-size flag must not be negative", r.MaxRecvMsgSize < 0},
+		{"request.maxSendMsgSize config or --max-send-msg-size flag must not be negative", r.MaxSendMsgSize < 0},
+		{"request.retry config or --retry flag must not be negative", r.Retry < 0},
+		{"request.retryBackoff config or --retry-backoff flag must not be negative", r.RetryBackoff < 0},
+		{"request.retryMaxBackoff config or --retry-max-backoff flag must not be negative", r.RetryMaxBackoff < 0},
+		{"currently, retries are supported only for gRPC", r.Retry > 0 && countTrue(r.Web, r.Twirp, r.Transcoding) > 0},
+		{"request.connectTimeout config or --connect-timeout flag must not be negative", r.ConnectTimeout < 0},
+		{"cannot record and replay at the same time", r.Record != "" && r.Replay != ""},
+		{"the replay mode doesn't support gRPC reflection. specify proto files instead", r.Replay != "" && s.Reflection},
+		{
+			`server.loadBalancingPolicy config or --lb-policy flag must be one of "pick_first" or "round_robin"`,
+			s.LoadBalancingPolicy != "" && s.LoadBalancingPolicy != "pick_first" && s.LoadBalancingPolicy != "round_robin",
+		},
+		{
+			"currently, xDS is supported only for gRPC",
+			strings.HasPrefix(s.Host, "xds:///") && countTrue(r.Web, r.Twirp, r.Transcoding) > 0,
+		},
+		{"xDS target cannot be used with other hosts", strings.Contains(s.Host, "xds:///") && strings.Contains(s.Host, ",")},
+		{
+			"currently, multiple hosts are supported only for gRPC",
+			strings.Contains(s.Host, ",") && countTrue(r.Web, r.Twirp, r.Transcoding) > 0,
+		},
+	}
+	var errs []error
+	for _, c := range invalidCases {
+		if c.cond {
+			errs = append(errs, errors.New(c.name))
+		}
+	}
+	if r.Proxy != "" {
+		u, err := url.Parse(r.Proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "socks5" && u.Scheme != "socks5h") {
+			errs = append(errs, errors.New(`request.proxy config or --proxy flag must be a URL such that "http://host:port", "socks5://host:port" or "socks5h://host:port"`))
+		}
+	}
+	if r.Traceparent != "" {
+		if _, err := tracing.ParseTraceparent(r.Traceparent); err != nil {
+			errs = append(errs, errors.Wrap(err, "request.traceparent config or --traceparent flag must be a W3C traceparent header"))
+		}
+	}
+	if r.OTLPEndpoint != "" {
+		if u, err := url.Parse(r.OTLPEndpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New(`request.otlpEndpoint config or --otlp-endpoint flag must be a URL such that "http://host:port"`))
+		}
+	}
+	for i, sh := range r.ScopedHeaders {
+		if sh == nil || sh.Scope == "" {
+			errs = append(errs, errors.Errorf("request.scopedHeaders[%d].scope config required", i))
+		}
+	}
+	for i, ph := range r.PropagatedHeaders {
+		if ph == nil || ph.From == "" {
+			errs = append(errs, errors.Errorf("request.propagatedHeaders[%d].from config required", i))
+		}
+	}
+	errs = append(errs, validateOAuth2(&r.OAuth2)...)
+	if r.JWT.KeyFile == "" && (r.JWT.Claims != "" || r.JWT.ClaimsFile != "") {
+		errs = append(errs, errors.New("request.jwt.keyFile config or --jwt-key flag required to sign JWTs"))
+	}
+	if r.JWT.Expiry < 0 {
+		errs = append(errs, errors.New("request.jwt.expiry config or --jwt-expiry flag must not be negative"))
+	}
+	errs = append(errs, validateAuth(r)...)
+	return errs
+}
+
+// validateOAuth2 validates the OAuth 2.0 flow. Nothing is validated if it is disabled.
+func validateOAuth2(o *OAuth2) []error {
+	if o.Flow == "" {
+		return nil
+	}
+	invalidCases := []struct {
+		name string
+		cond bool
+	}{
+		{
+			`request.oauth2.flow config or --oauth2-flow flag must be one of "client_credentials" or "device_code"`,
+			o.Flow != auth.FlowClientCredentials && o.Flow != auth.FlowDeviceCode,
+		},
+		{"request.oauth2.tokenURL config or --oauth2-token-url flag required", o.TokenURL == ""},
+		{"request.oauth2.clientID config or --oauth2-client-id flag required", o.ClientID == ""},
+		{
+			"the client credentials flow requires request.oauth2.clientSecret config or --oauth2-client-secret flag",
+			o.Flow == auth.FlowClientCredentials && o.ClientSecret == "",
+		},
+		{
+			"the device code flow requires request.oauth2.deviceAuthURL config or --oauth2-device-auth-url flag",
+			o.Flow == auth.FlowDeviceCode && o.DeviceAuthURL == "",
+		},
+	}
+	var errs []error
+	for _, c := range invalidCases {
+		if c.cond {
+			errs = append(errs, errors.New(c.name))
+		}
+	}
+	return errs
+}
+
+// validateAuth validates the credential provider and settings it requires.
+func validateAuth(r *Request) []error {
+	var (
+		msg  string
+		cond bool
+	)
+	switch a := r.Auth; a.Provider {
+	case "":
+		msg = "cannot send both of OAuth 2.0 access tokens and JWTs as the authorization header"
+		cond = r.OAuth2.Flow != "" && r.JWT.KeyFile != "" && strings.EqualFold(r.JWT.Header, "authorization")
+	case auth.ProviderStatic:
+		msg = "the static provider requires request.auth.header and request.auth.value config or --auth-header and --auth-value flags"
+		cond = a.Header == "" || a.Value == ""
+	case auth.ProviderBasic:
+		msg = "the basic provider requires request.auth.username config or --auth-username flag"
+		cond = a.Username == ""
+	case auth.ProviderOAuth2:
+		msg = "the oauth2 provider requires request.oauth2.flow config or --oauth2-flow flag"
+		cond = r.OAuth2.Flow == ""
+	case auth.ProviderJWT:
+		msg = "the jwt provider requires request.jwt.keyFile config or --jwt-key flag"
+		cond = r.JWT.KeyFile == ""
+	case auth.ProviderExec:
+		msg = "the exec provider requires request.auth.command config or --auth-command flag"
+		cond = a.Command == ""
+	default:
+		msg = `request.auth.provider config or --auth-provider flag must be one of "static", "basic", "oauth2", "jwt" or "exec"`
+		cond = true
+	}
+	if cond {
+		return []error{errors.New(msg)}
+	}
+	return nil
+}
+
+func countTrue(bs ...bool) int {
+	var n int
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
 type Default struct {
 	ProtoPath []string `toml:"protoPath"`
 	ProtoFile []string `toml:"protoFile"`
-	Package   string   `toml:"package"`
-	Service   string   `toml:"service"`
+	// AutoProtoPath enables import path discovery. Imports which are not found in ProtoPath are resolved from
+	// parent directories and well-known vendor directories.
+	AutoProtoPath bool `toml:"autoProtoPath"`
+	// Protoset is FileDescriptorSet files used instead of ProtoFile.
+	Protoset []string `toml:"protoset"`
+	// BSR is a Buf Schema Registry module such that "buf.build/acme/weather:v1" used instead of ProtoFile.
+	BSR     string `toml:"bsr"`
+	Package string `toml:"package"`
+	Service string `toml:"service"`
 }
 
 type Log struct {
@@ -139,6 +572,9 @@ func newDefaultViper() *viper.Viper {
 	v := viper.New()
 	v.SetDefault("default.protoPath", []string{""})
 	v.SetDefault("default.protoFile", []string{""})
+	v.SetDefault("default.autoProtoPath", false)
+	v.SetDefault("default.protoset", []string{""})
+	v.SetDefault("default.bsr", "")
 	v.SetDefault("default.package", "")
 	v.SetDefault("default.service", "")
 
@@ -147,26 +583,88 @@ func newDefaultViper() *viper.Viper {
 	v.SetDefault("meta.autoUpdate", false)
 	v.SetDefault("meta.updateLevel", "patch")
 
-	v.SetDefault("repl.promptFormat", "{package}.{service}@{addr}:{port}")
+	v.SetDefault("repl.promptFormat", DefaultPromptFormat)
 	v.SetDefault("repl.inputPromptFormat", "{ancestor}{name} ({type}) => ")
 	v.SetDefault("repl.coloredOutput", true)
 	v.SetDefault("repl.silent", false)
 	v.SetDefault("repl.splashTextPath", "")
 	v.SetDefault("repl.historySize", 100)
+	v.SetDefault("repl.historyPerHost", false)
+	v.SetDefault("repl.watch", false)
+	v.SetDefault("repl.promptConnection", false)
 
 	v.SetDefault("server.host", "127.0.0.1")
+	v.SetDefault("server.loadBalancingPolicy", "pick_first")
+	v.SetDefault("server.xdsBootstrap", "")
 	v.SetDefault("server.port", "50051")
 	v.SetDefault("server.reflection", false)
 	v.SetDefault("server.tls", false)
 	v.SetDefault("server.name", "")
+	v.SetDefault("server.noCache", false)
 
 	v.SetDefault("log.prefix", "evans: ")
 
 	v.SetDefault("request.header", Header{"grpc-client": []string{"evans"}})
+	v.SetDefault("request.scopedHeaders", []*ScopedHeader{})
+	v.SetDefault("request.propagatedHeaders", []*PropagatedHeader{})
 	v.SetDefault("request.cacertFile", "")
 	v.SetDefault("request.certFile", "")
 	v.SetDefault("request.certKeyFile", "")
 	v.SetDefault("request.web", false)
+	v.SetDefault("request.twirp", false)
+	v.SetDefault("request.twirpEncoding", "protobuf")
+	v.SetDefault("request.transcoding", false)
+	v.SetDefault("request.proxy", "")
+	v.SetDefault("request.h2c", false)
+	v.SetDefault("request.keepaliveTime", "0s")
+	v.SetDefault("request.keepaliveTimeout", "0s")
+	v.SetDefault("request.keepalivePermitWithoutStream", false)
+	v.SetDefault("request.initialWindowSize", 0)
+	v.SetDefault("request.initialConnWindowSize", 0)
+	v.SetDefault("request.maxHeaderListSize", 0)
+	v.SetDefault("request.maxRecvMsgSize", 0)
+	v.SetDefault("request.maxSendMsgSize", 0)
+	v.SetDefault("request.compression", "")
+	v.SetDefault("request.retry", 0)
+	v.SetDefault("request.retryBackoff", "100ms")
+	v.SetDefault("request.retryMaxBackoff", "5s")
+	v.SetDefault("request.retryCodes", []string{})
+	v.SetDefault("request.connectTimeout", "0s")
+	v.SetDefault("request.waitForReady", false)
+	v.SetDefault("request.record", "")
+	v.SetDefault("request.replay", "")
+	v.SetDefault("request.denyDeprecated", false)
+	v.SetDefault("request.trace", false)
+	v.SetDefault("request.tracePropagation", true)
+	v.SetDefault("request.traceparent", "")
+	v.SetDefault("request.otlpEndpoint", "")
+	v.SetDefault("request.oauth2.flow", "")
+	v.SetDefault("request.oauth2.tokenURL", "")
+	v.SetDefault("request.oauth2.deviceAuthURL", "")
+	v.SetDefault("request.oauth2.clientID", "")
+	v.SetDefault("request.oauth2.clientSecret", "")
+	v.SetDefault("request.oauth2.scopes", []string{})
+	v.SetDefault("request.jwt.keyFile", "")
+	v.SetDefault("request.jwt.algorithm", "")
+	v.SetDefault("request.jwt.claims", "")
+	v.SetDefault("request.jwt.claimsFile", "")
+	v.SetDefault("request.jwt.header", "authorization")
+	v.SetDefault("request.jwt.expiry", time.Hour)
+	v.SetDefault("request.auth.provider", "")
+	v.SetDefault("request.auth.header", "")
+	v.SetDefault("request.auth.value", "")
+	v.SetDefault("request.auth.username", "")
+	v.SetDefault("request.auth.password", "")
+	v.SetDefault("request.auth.command", "")
+	v.SetDefault("request.timeout", "0s")
+
+	v.SetDefault("output.compact", false)
+	v.SetDefault("output.indent", 2)
+	v.SetDefault("output.sortKeys", false)
+	v.SetDefault("output.emitDefaults", false)
+	v.SetDefault("output.protoNames", false)
+	v.SetDefault("output.int64AsNumber", false)
+	v.SetDefault("output.bytesEncoding", "base64")
 
 	return v
 }
@@ -175,21 +673,80 @@ func newDefaultViper() *viper.Viper {
 func bindFlags(vp *viper.Viper, fs *pflag.FlagSet) {
 	// kv defines the mapping from a viper config name to a flag name.
 	kv := map[string]string{
-		"default.protoPath":   "path",
-		"default.protoFile":   "proto",
-		"default.package":     "package",
-		"default.service":     "service",
-		"server.host":         "host",
-		"server.port":         "port",
-		"server.reflection":   "reflection",
-		"server.tls":          "tls",
-		"server.name":         "servername",
-		"request.header":      "header",
-		"request.web":         "web",
-		"request.cacertFile":  "cacert",
-		"request.certFile":    "cert",
-		"request.certKeyFile": "certkey",
-		"repl.silent":         "silent",
+		"default.protoPath":                    "path",
+		"default.protoFile":                    "proto",
+		"default.autoProtoPath":                "auto-path",
+		"default.protoset":                     "protoset",
+		"default.bsr":                          "bsr",
+		"default.package":                      "package",
+		"default.service":                      "service",
+		"server.host":                          "host",
+		"server.loadBalancingPolicy":           "lb-policy",
+		"server.xdsBootstrap":                  "xds-bootstrap",
+		"server.port":                          "port",
+		"server.reflection":                    "reflection",
+		"server.tls":                           "tls",
+		"server.name":                          "servername",
+		"server.noCache":                       "no-cache",
+		"request.header":                       "header",
+		"request.web":                          "web",
+		"request.twirp":                        "twirp",
+		"request.twirpEncoding":                "twirp-encoding",
+		"request.transcoding":                  "transcoding",
+		"request.proxy":                        "proxy",
+		"request.h2c":                          "h2c",
+		"request.keepaliveTime":                "keepalive-time",
+		"request.keepaliveTimeout":             "keepalive-timeout",
+		"request.keepalivePermitWithoutStream": "keepalive-permit-without-stream",
+		"request.initialWindowSize":            "initial-window-size",
+		"request.initialConnWindowSize":        "initial-conn-window-size",
+		"request.maxHeaderListSize":            "max-header-list-size",
+		"request.maxRecvMsgSize":               "max-recv-msg-size",
+		"request.maxSendMsgSize":               "max-send-msg-size",
+		"request.compression":                  "compression",
+		"request.retry":                        "retry",
+		"request.retryBackoff":                 "retry-backoff",
+		"request.retryMaxBackoff":              "retry-max-backoff",
+		"request.retryCodes":                   "retry-codes",
+		"request.connectTimeout":               "connect-timeout",
+		"request.waitForReady":                 "wait-for-ready",
+		"request.record":                       "record",
+		"request.replay":                       "replay",
+		"request.trace":                        "verbose",
+		"request.tracePropagation":             "trace-propagation",
+		"request.traceparent":                  "traceparent",
+		"request.otlpEndpoint":                 "otlp-endpoint",
+		"request.oauth2.flow":                  "oauth2-flow",
+		"request.oauth2.tokenURL":              "oauth2-token-url",
+		"request.oauth2.deviceAuthURL":         "oauth2-device-auth-url",
+		"request.oauth2.clientID":              "oauth2-client-id",
+		"request.oauth2.clientSecret":          "oauth2-client-secret",
+		"request.oauth2.scopes":                "oauth2-scopes",
+		"request.jwt.keyFile":                  "jwt-key",
+		"request.jwt.algorithm":                "jwt-alg",
+		"request.jwt.claims":                   "jwt-claims",
+		"request.jwt.claimsFile":               "jwt-claims-file",
+		"request.jwt.header":                   "jwt-header",
+		"request.jwt.expiry":                   "jwt-expiry",
+		"request.auth.provider":                "auth-provider",
+		"request.auth.header":                  "auth-header",
+		"request.auth.value":                   "auth-value",
+		"request.auth.username":                "auth-username",
+		"request.auth.password":                "auth-password",
+		"request.auth.command":                 "auth-command",
+		"request.cacertFile":                   "cacert",
+		"request.certFile":                     "cert",
+		"request.certKeyFile":                  "certkey",
+		"request.timeout":                      "timeout",
+		"repl.silent":                          "silent",
+		"repl.watch":                           "watch",
+		"output.compact":                       "compact",
+		"output.indent":                        "indent",
+		"output.sortKeys":                      "sort-keys",
+		"output.emitDefaults":                  "emit-defaults",
+		"output.protoNames":                    "proto-names",
+		"output.int64AsNumber":                 "int64-as-number",
+		"output.bytesEncoding":                 "bytes-encoding",
 	}
 	for k, v := range kv {
 		f := fs.Lookup(v)
@@ -334,6 +891,9 @@ func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 			}
 		}
 
+		if err == nil {
+			err = resolveProfiles(v, cfg)
+		}
 		if err == nil {
 			setupConfig(cfg)
 		}
@@ -401,22 +961,81 @@ func initConfig(fs *pflag.FlagSet) (cfg *Config, err error) {
 	return &mergedCfg, nil
 }
 
+// resolveProfiles merges each profile in vp with top-level settings, then sets them to cfg.Profiles.
+// Because vp knows only keys which are specified in the profile, unspecified keys are inherited
+// from top-level settings including flags.
+func resolveProfiles(vp *viper.Viper, cfg *Config) error {
+	names := vp.GetStringMap("profiles")
+	cfg.Profiles = make(map[string]*Profile, len(names))
+	if len(names) == 0 {
+		return nil
+	}
+
+	for name := range names {
+		// MergeConfigMap modifies nested maps of the passed map, so we need a fresh copy for each profile.
+		base := copyMap(vp.AllSettings())
+		delete(base, "profiles")
+
+		pv := viper.New()
+		if err := pv.MergeConfigMap(base); err != nil {
+			return errors.Wrap(err, "failed to merge top-level settings")
+		}
+		if err := pv.MergeConfigMap(vp.GetStringMap("profiles." + name)); err != nil {
+			return errors.Wrapf(err, "failed to merge profile '%s'", name)
+		}
+		var p Profile
+		if err := pv.Unmarshal(&p); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal profile '%s'", name)
+		}
+		cfg.Profiles[name] = &p
+	}
+	return nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if mm, ok := v.(map[string]interface{}); ok {
+			v = copyMap(mm)
+		}
+		out[k] = v
+	}
+	return out
+}
+
 func setupConfig(c *Config) {
+	setupDefault(c.Default)
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	for _, p := range c.Profiles {
+		setupDefault(p.Default)
+	}
+}
+
+func setupDefault(d *Default) {
 	// To show protofile and protopath field in a config file, set slice which has empty string
 	// if these are nil. (please see default values.)
 	// Conversely, trim the empty string element when config loading.
-	if c.Default.ProtoFile == nil {
-		c.Default.ProtoFile = []string{}
+	if d.ProtoFile == nil {
+		d.ProtoFile = []string{}
 	}
-	if len(c.Default.ProtoFile) >= 1 && c.Default.ProtoFile[0] == "" {
-		c.Default.ProtoFile = c.Default.ProtoFile[1:]
+	if len(d.ProtoFile) >= 1 && d.ProtoFile[0] == "" {
+		d.ProtoFile = d.ProtoFile[1:]
 	}
 
-	if c.Default.ProtoPath == nil {
-		c.Default.ProtoPath = []string{}
+	if d.ProtoPath == nil {
+		d.ProtoPath = []string{}
 	}
-	if len(c.Default.ProtoPath) >= 1 && c.Default.ProtoPath[0] == "" {
-		c.Default.ProtoPath = c.Default.ProtoPath[1:]
+	if len(d.ProtoPath) >= 1 && d.ProtoPath[0] == "" {
+		d.ProtoPath = d.ProtoPath[1:]
+	}
+
+	if d.Protoset == nil {
+		d.Protoset = []string{}
+	}
+	if len(d.Protoset) >= 1 && d.Protoset[0] == "" {
+		d.Protoset = d.Protoset[1:]
 	}
 }
 
@@ -437,11 +1056,7 @@ func Edit() error {
 			return err
 		}
 	}
-	editor := getEditor()
-	if editor == "" {
-		return errors.New("--edit requires one of $EDITOR value or Vim")
-	}
-	return runEditor(editor, p)
+	return EditFile(p)
 }
 
 // EditGlobal is the same as Edit, but edit the global config.
@@ -453,6 +1068,13 @@ func EditGlobal() error {
 			return err
 		}
 	}
+	return EditFile(p)
+}
+
+// EditFile opens the file p with an editor.
+// $EDITOR is used as an editor if it is configured. Else, Vim is used.
+// $EDITOR can have arguments such that "code --wait".
+func EditFile(p string) error {
 	editor := getEditor()
 	if editor == "" {
 		return errors.New("--edit requires one of $EDITOR value or Vim")
@@ -460,8 +1082,9 @@ func EditGlobal() error {
 	return runEditor(editor, p)
 }
 
-var runEditor = func(editor string, cfgPath string) error {
-	cmd := exec.Command(editor, cfgPath)
+var runEditor = func(editor string, path string) error {
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
 	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 	if err := cmd.Run(); err != nil {
 		return errors.Wrapf(err, "failed to execute %s", editor)
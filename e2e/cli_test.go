@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -19,11 +22,16 @@ import (
 	"github.com/pkg/errors"
 )
 
-var replacer = regexp.MustCompile(`access-control-expose-headers: .*\n`)
+var (
+	replacer     = regexp.MustCompile(`access-control-expose-headers: .*\n`)
+	timeReplacer = regexp.MustCompile(`"time":[0-9.e-]+`)
+)
 
 func TestE2E_CLI(t *testing.T) {
 	commonFlags := []string{"--verbose"}
 
+	rawResponsePath := filepath.Join(os.TempDir(), "evans-e2e-raw-response.bin")
+
 	cases := map[string]struct {
 		// Common flags all sub-commands can have.
 		commonFlags string
@@ -169,6 +177,136 @@ func TestE2E_CLI(t *testing.T) {
 			args:        "--file testdata/unary_call.in api.Example.Unary",
 			expectedOut: `{ "message": "hello, oumae" }`,
 		},
+		"call unary RPC with an input file and a glob pattern by CLI mode": {
+			commonFlags: "--proto testdata/*.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"call unary RPC with an input file and a directory by CLI mode": {
+			commonFlags: "--path testdata --proto .",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"cannot launch CLI mode because no proto files match the glob pattern": {
+			commonFlags:  "--proto testdata/*.txt",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with an input file and import path discovery by CLI mode": {
+			commonFlags: "--auto-path --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"call unary RPC with an input file and a protoset by CLI mode": {
+			commonFlags: "--protoset testdata/test.protoset",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"cannot launch CLI mode because both of proto files and protosets are passed": {
+			commonFlags:  "--proto testdata/test.proto --protoset testdata/test.protoset",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot launch CLI mode because both of proto files and a BSR module are passed": {
+			commonFlags:  "--proto testdata/test.proto --bsr buf.build/acme/weather",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot launch CLI mode because the BSR module is invalid": {
+			commonFlags:  "--bsr acme/weather",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with --raw-request and --raw-response": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--raw-request testdata/unary_call.bin --raw-response " + rawResponsePath + " api.Example.Unary",
+			beforeTest: func(t *testing.T) func(t *testing.T) {
+				return func(t *testing.T) {
+					os.Remove(rawResponsePath)
+				}
+			},
+			assertTest: func(t *testing.T, output string) {
+				if expected := `{ "message": "hello, oumae" }`; output != expected {
+					t.Errorf("expected '%s', but got '%s'", expected, output)
+				}
+				b, err := ioutil.ReadFile(rawResponsePath)
+				if err != nil {
+					t.Fatalf("failed to read the raw response: %s", err)
+				}
+				if expected := "\n\x0chello, oumae"; string(b) != expected {
+					t.Errorf("expected raw response %q, but got %q", expected, b)
+				}
+			},
+		},
+		"call unary RPC with --raw-request and --file": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--raw-request testdata/unary_call.bin --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with --dry-run": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--dry-run --file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "name": "oumae" } wire size: 7 bytes`,
+		},
+		"call client streaming RPC with --dry-run": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--dry-run --file testdata/client_streaming.in api.Example.ClientStreaming",
+			expectedOut: `{ "name": "oumae" } wire size: 7 bytes { "name": "kousaka" } wire size: 9 bytes { "name": "kawashima" } wire size: 11 bytes { "name": "kato" } wire size: 6 bytes`,
+		},
+		"cannot call RPC with --dry-run because the request is invalid": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--dry-run --file testdata/invalid.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot call RPC with --dry-run and gRPC reflection": {
+			commonFlags:  "-r",
+			cmd:          "call",
+			args:         "--dry-run --file testdata/unary_call.in api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"call unary RPC with expectations": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--expect-code OK --expect-body-contains oumae --expect-body-contains \"message\" --file testdata/unary_call.in api.Example.Unary",
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"call unary RPC which fails with the expected status code": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--expect-code INTERNAL --file testdata/unary_call.in api.Example.UnaryHeaderTrailerFailure",
+		},
+		"call unary RPC with an unexpected status code": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--expect-code NotFound --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"call unary RPC with an unexpected response body": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--expect-body-contains kaguya --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
+		"cannot call RPC with an unknown status code as the expectation": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "call",
+			args:         "--expect-code Foo --file testdata/unary_call.in api.Example.Unary",
+			expectedCode: 1,
+		},
 		"call fully-qualified unary RPC with an input file by CLI mode": {
 			commonFlags: "--proto testdata/test.proto",
 			cmd:         "call",
@@ -238,6 +376,19 @@ func TestE2E_CLI(t *testing.T) {
 				}
 			},
 		},
+		"call bidi streaming RPC with invalid input by CLI mode": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "call",
+			args:        "api.Example.BidiStreaming",
+			beforeTest: func(t *testing.T) func(*testing.T) {
+				old := mode.DefaultCLIReader
+				mode.DefaultCLIReader = strings.NewReader(`{"name": "oumae"}` + "\n" + `{"name": 1}`)
+				return func(t *testing.T) {
+					mode.DefaultCLIReader = old
+				}
+			},
+			expectedCode: 1,
+		},
 		"call unary RPC with an input file and custom headers by CLI mode": {
 			commonFlags: "--header ogiso=setsuna --header touma=kazusa,youko --header sound=of=destiny --proto testdata/test.proto",
 			cmd:         "call",
@@ -290,6 +441,20 @@ func TestE2E_CLI(t *testing.T) {
 			reflection:  true,
 			expectedOut: `{ "message": "hello, oumae" }`,
 		},
+		"call unary RPC by CLI mode with reflection and proto files": {
+			commonFlags: "--reflection --proto testdata/test.proto",
+			cmd:         "call",
+			args:        "--file testdata/unary_call.in api.Example.Unary",
+			reflection:  true,
+			expectedOut: `{ "message": "hello, oumae" }`,
+		},
+		"cannot launch CLI mode with reflection because the proto file is not found": {
+			commonFlags:  "--reflection --proto testdata/not_found.proto",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
 
 		// call command with TLS
 
@@ -446,6 +611,142 @@ func TestE2E_CLI(t *testing.T) {
 			unflatten:        true,
 			assertWithGolden: true,
 		},
+		"call unary RPC with --enrich flag and YAML input and output": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.yaml --input yaml --enrich --output yaml api.Example.UnaryHeaderTrailer",
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with --enrich flag and prototext input and output": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.txtpb --input prototext --enrich --output prototext api.Example.UnaryHeaderTrailer",
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with --enrich and --compact flags": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich --output json --compact api.Example.UnaryHeaderTrailer",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with --compression and --enrich flags": {
+			commonFlags:      "-r --compression gzip",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich api.Example.UnaryHeaderTrailer",
+			reflection:       true,
+			unflatten:        true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with an unknown compressor": {
+			commonFlags:  "-r --compression snappy",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"call unary RPC with --indent flag": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --indent 4 --sort-keys --emit-defaults api.Example.Unary",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with original field names": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_message_proto_names.in --proto-names api.Example.UnaryMessage",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call server streaming RPC with --template flag": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --template msg={{.message}} api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call server streaming RPC with table output": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --output table api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call server streaming RPC with table output and --max-column-width flag": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --output table --columns message --max-column-width 16 api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call server streaming RPC with CSV output": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --output csv api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"cannot specify --max-column-width with CSV output": {
+			commonFlags:  "-r",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --output csv --max-column-width 10 api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"cannot use Twirp with gRPC reflection": {
+			commonFlags:  "-r --twirp",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"cannot use HTTP/JSON transcoding with gRPC reflection": {
+			commonFlags:  "-r --transcoding",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"cannot specify --columns without table output": {
+			commonFlags:  "-r",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --columns message api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"call server streaming RPC with --filter flag": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --filter .message api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call unary RPC with an invalid filter": {
+			commonFlags:  "-r",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --filter message api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"cannot specify both of --template and --output": {
+			commonFlags:  "-r",
+			cmd:          "call",
+			args:         "--file testdata/unary_call.in --template {{.message}} --output json api.Example.Unary",
+			reflection:   true,
+			expectedCode: 1,
+		},
+		"call server streaming RPC with prototext format": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --output prototext api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
 		"call failure unary RPC with --enrich flag": {
 			commonFlags:      "-r",
 			cmd:              "call",
@@ -464,6 +765,21 @@ func TestE2E_CLI(t *testing.T) {
 			assertWithGolden: true,
 			expectedCode:     1,
 		},
+		"call server streaming RPC with NDJSON format": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/server_streaming.in --output ndjson api.Example.ServerStreaming",
+			reflection:       true,
+			assertWithGolden: true,
+		},
+		"call failure unary RPC with --enrich and NDJSON format": {
+			commonFlags:      "-r",
+			cmd:              "call",
+			args:             "--file testdata/unary_call.in --enrich --output ndjson api.Example.UnaryHeaderTrailerFailure",
+			reflection:       true,
+			assertWithGolden: true,
+			expectedCode:     1,
+		},
 		"call unary RPC with --enrich flag against to gRPC-Web server": {
 			commonFlags:      "--web -r",
 			cmd:              "call",
@@ -596,12 +912,96 @@ func TestE2E_CLI(t *testing.T) {
 			args:             "api.SimpleRequest",
 			assertWithGolden: true,
 		},
+		"describe all service descriptors with JSON format": {
+			commonFlags:      "--proto testdata/test.proto,testdata/empty_package.proto",
+			cmd:              "desc",
+			args:             "-o json",
+			assertWithGolden: true,
+		},
+		"describe a message descriptor with JSON format": {
+			commonFlags:      "--proto testdata/test.proto,testdata/empty_package.proto",
+			cmd:              "desc",
+			args:             "-o json api.SimpleRequest",
+			assertWithGolden: true,
+		},
+		"describe with an invalid output format": {
+			commonFlags:  "--proto testdata/test.proto,testdata/empty_package.proto",
+			cmd:          "desc",
+			args:         "-o yaml api.SimpleRequest",
+			expectedCode: 1,
+		},
 		"invalid symbol": {
 			commonFlags:  "--proto testdata/test.proto,testdata/empty_package.proto",
 			cmd:          "desc",
 			args:         "api.Foo",
 			expectedCode: 1,
 		},
+
+		// export command
+
+		"print export command usage": {
+			commonFlags:      "",
+			cmd:              "export",
+			args:             "-h",
+			assertWithGolden: true,
+		},
+		"export files as proto files without output directory": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "export",
+			args:         "--format proto",
+			expectedCode: 1,
+		},
+		"export an unknown service": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "export",
+			args:         "api.Foo",
+			expectedCode: 1,
+		},
+
+		// batch command
+
+		"run a batch file": {
+			commonFlags: "--proto testdata/test.proto",
+			cmd:         "batch",
+			args:        "--parallel 2 testdata/batch.ndjson",
+			unflatten:   true,
+			assertTest: func(t *testing.T, output string) {
+				expected := `{"index":0,"method":"api.Example.Unary","status":{"code":"OK"},"responses":[{"message":"hello, oumae"}],"time":0}
+{"index":1,"method":"api.Example.ClientStreaming","status":{"code":"OK"},"responses":[{"message":"you sent requests 2 times (oumae, kousaka)."}],"time":0}
+{"index":2,"method":"api.Example.Unary","status":{"code":"OK"},"responses":[{"message":"hello, "}],"time":0}
+`
+				if diff := cmp.Diff(expected, timeReplacer.ReplaceAllString(output, `"time":0`)); diff != "" {
+					t.Errorf("unexpected output (-want, +got):\n%s", diff)
+				}
+			},
+		},
+		"run a batch file which has failed requests": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "batch",
+			args:         "testdata/batch_failure.json",
+			unflatten:    true,
+			expectedCode: 1,
+			assertTest: func(t *testing.T, output string) {
+				lines := strings.Split(strings.TrimSpace(output), "\n")
+				if len(lines) != 3 {
+					t.Fatalf("expected 3 results, but got %d:\n%s", len(lines), output)
+				}
+				if !strings.Contains(lines[0], `"code":"OK"`) {
+					t.Errorf("the first request must succeed, but got %s", lines[0])
+				}
+				if !strings.Contains(lines[1], `"error":"failed to find method 'api.Example.Foo'`) {
+					t.Errorf("the second request must fail because of the unknown method, but got %s", lines[1])
+				}
+				if !strings.Contains(lines[2], `"error":"'api.Example.Unary' accepts exactly one request, but got 2"`) {
+					t.Errorf("the third request must fail because of too many requests, but got %s", lines[2])
+				}
+			},
+		},
+		"run a batch file without the file": {
+			commonFlags:  "--proto testdata/test.proto",
+			cmd:          "batch",
+			expectedCode: 1,
+		},
 	}
 	for name, c := range cases {
 		c := c
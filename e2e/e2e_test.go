@@ -24,7 +24,7 @@ import (
 //
 //   - Set log output to ioutil.Discard.
 //   - Remove .evans.toml in this project root.
-//   - Change $XDG_CONFIG_HOME and $XDG_CACHE_HOME to ignore the root config and cache data.
+//   - Change $XDG_CONFIG_HOME, $XDG_CACHE_HOME and $XDG_DATA_HOME to ignore the root config, cache and data.
 //     These envvars are reset at the end of E2E testing.
 //
 func TestMain(m *testing.M) {
@@ -53,6 +53,10 @@ func TestMain(m *testing.M) {
 	cleanup2 := setEnv("XDG_CACHE_HOME", cacheDir)
 	defer cleanup2()
 
+	dataDir := os.TempDir()
+	cleanup3 := setEnv("XDG_DATA_HOME", dataDir)
+	defer cleanup3()
+
 	goleak.VerifyTestMain(m, goleak.IgnoreTopFunction("github.com/desertbit/timer.timerRoutine"))
 }
 
@@ -214,6 +214,14 @@ func (p *recorderPrompt) Select(message string, options []string) (string, error
 	return s, err
 }
 
+func (p *recorderPrompt) FuzzySelect(message string, items []*prompt.SelectItem) (int, error) {
+	i, err := p.Prompt.FuzzySelect(message, items)
+	if err == nil {
+		p.inputHistory = append(p.inputHistory, items[i].Name)
+	}
+	return i, err
+}
+
 func filterArgs(args []string) []string {
 	newArgs := make([]string, 0, len(args))
 	for i := 0; i < len(args); i++ {
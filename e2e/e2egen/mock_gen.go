@@ -9,6 +9,7 @@ import (
 )
 
 var (
+	lockPromptMockFuzzySelect       sync.RWMutex
 	lockPromptMockGetCommandHistory sync.RWMutex
 	lockPromptMockInput             sync.RWMutex
 	lockPromptMockSelect            sync.RWMutex
@@ -27,6 +28,9 @@ var _ prompt.Prompt = &PromptMock{}
 //
 //         // make and configure a mocked Prompt
 //         mockedPrompt := &PromptMock{
+//             FuzzySelectFunc: func(message string, items []*prompt.SelectItem) (int, error) {
+// 	               panic("mock out the FuzzySelect method")
+//             },
 //             GetCommandHistoryFunc: func() []string {
 // 	               panic("mock out the GetCommandHistory method")
 //             },
@@ -52,6 +56,9 @@ var _ prompt.Prompt = &PromptMock{}
 //
 //     }
 type PromptMock struct {
+	// FuzzySelectFunc mocks the FuzzySelect method.
+	FuzzySelectFunc func(message string, items []*prompt.SelectItem) (int, error)
+
 	// GetCommandHistoryFunc mocks the GetCommandHistory method.
 	GetCommandHistoryFunc func() []string
 
@@ -72,6 +79,13 @@ type PromptMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// FuzzySelect holds details about calls to the FuzzySelect method.
+		FuzzySelect []struct {
+			// Message is the message argument value.
+			Message string
+			// Items is the items argument value.
+			Items []*prompt.SelectItem
+		}
 		// GetCommandHistory holds details about calls to the GetCommandHistory method.
 		GetCommandHistory []struct {
 		}
@@ -103,6 +117,41 @@ type PromptMock struct {
 	}
 }
 
+// FuzzySelect calls FuzzySelectFunc.
+func (mock *PromptMock) FuzzySelect(message string, items []*prompt.SelectItem) (int, error) {
+	if mock.FuzzySelectFunc == nil {
+		panic("PromptMock.FuzzySelectFunc: method is nil but Prompt.FuzzySelect was just called")
+	}
+	callInfo := struct {
+		Message string
+		Items   []*prompt.SelectItem
+	}{
+		Message: message,
+		Items:   items,
+	}
+	lockPromptMockFuzzySelect.Lock()
+	mock.calls.FuzzySelect = append(mock.calls.FuzzySelect, callInfo)
+	lockPromptMockFuzzySelect.Unlock()
+	return mock.FuzzySelectFunc(message, items)
+}
+
+// FuzzySelectCalls gets all the calls that were made to FuzzySelect.
+// Check the length with:
+//     len(mockedPrompt.FuzzySelectCalls())
+func (mock *PromptMock) FuzzySelectCalls() []struct {
+	Message string
+	Items   []*prompt.SelectItem
+} {
+	var calls []struct {
+		Message string
+		Items   []*prompt.SelectItem
+	}
+	lockPromptMockFuzzySelect.RLock()
+	calls = mock.calls.FuzzySelect
+	lockPromptMockFuzzySelect.RUnlock()
+	return calls
+}
+
 // GetCommandHistory calls GetCommandHistoryFunc.
 func (mock *PromptMock) GetCommandHistory() []string {
 	if mock.GetCommandHistoryFunc == nil {
@@ -360,27 +360,84 @@ var expectedUsageOut = fmt.Sprintf(`evans %s
 Usage: evans [global options ...] <command>
 
 Options:
-        --silent, -s                     hide redundant output (default "false")
-        --path strings                   comma-separated proto file paths (default "[]")
-        --proto strings                  comma-separated proto file names (default "[]")
-        --host string                    gRPC server host
-        --port, -p string                gRPC server port (default "50051")
-        --header slice of strings        default headers that set to each requests (example: foo=bar) (default "[]")
-        --web                            use gRPC-Web protocol (default "false")
-        --reflection, -r                 use gRPC reflection (default "false")
-        --tls, -t                        use a secure TLS connection (default "false")
-        --cacert string                  the CA certificate file for verifying the server
-        --cert string                    the certificate file for mutual TLS auth. it must be provided with --certkey.
-        --certkey string                 the private key file for mutual TLS auth. it must be provided with --cert.
-        --servername string              override the server name used to verify the hostname (ignored if --tls is disabled)
-        --edit, -e                       edit the project config file by using $EDITOR (default "false")
-        --edit-global                    edit the global config file by using $EDITOR (default "false")
-        --verbose                        verbose output (default "false")
-        --version, -v                    display version and exit (default "false")
-        --help, -h                       display help text and exit (default "false")
+        --silent, -s                             hide redundant output (default "false")
+        --path strings                           comma-separated proto file paths (default "[]")
+        --proto strings                          comma-separated proto file names. glob patterns such that api/**/*.proto and directories are expanded (default "[]")
+        --auto-path                              resolve imports which are not found in --path from parent directories and vendor directories such that vendor/ or third_party/ (default "false")
+        --protoset strings                       comma-separated FileDescriptorSet files such that outputs of protoc --descriptor_set_out or buf build. they are used instead of proto files (default "[]")
+        --bsr string                             Buf Schema Registry module such that buf.build/<owner>/<repository>:<reference>. it is used instead of proto files. $BUF_TOKEN is used as an API token
+        --host string                            gRPC server host. comma-separated hosts or multiple --host flags are balanced by --lb-policy. "xds:///name" is resolved by xDS
+        --port, -p string                        gRPC server port (default "50051")
+        --header slice of strings                default headers that set to each requests (example: foo=bar) (default "[]")
+        --lb-policy string                       the load balancing policy for multiple hosts. one of "pick_first" or "round_robin" (default "pick_first")
+        --xds-bootstrap string                   the xDS bootstrap file for xds:/// hosts. if empty, GRPC_XDS_BOOTSTRAP is used
+        --web                                    use gRPC-Web protocol (default "false")
+        --twirp                                  use Twirp protocol. streaming RPCs and gRPC reflection are not supported (default "false")
+        --twirp-encoding string                  the encoding of Twirp request bodies. one of "protobuf" or "json" (default "protobuf")
+        --transcoding                            send RPCs as REST requests annotated by google.api.http options. streaming RPCs and gRPC reflection are not supported (default "false")
+        --proxy string                           proxy URL such that "http://host:port" or "socks5://host:port". if empty, HTTPS_PROXY and ALL_PROXY are used
+        --h2c                                    use cleartext HTTP/2 with prior knowledge for Twirp or HTTP/JSON transcoding (default "false")
+        --reflection, -r                         use gRPC reflection (default "false")
+        --no-cache                               neither read nor write the on-disk cache of descriptors fetched by gRPC reflection (default "false")
+        --tls, -t                                use a secure TLS connection (default "false")
+        --cacert string                          the CA certificate file for verifying the server
+        --cert string                            the certificate file for mutual TLS auth. it must be provided with --certkey.
+        --certkey string                         the private key file for mutual TLS auth. it must be provided with --cert.
+        --servername string                      override the server name used to verify the hostname (ignored if --tls is disabled)
+        --timeout duration                       the timeout for each RPC call such that 5s or 500ms (0 means no timeout) (default "0s")
+        --keepalive-time duration                the interval of gRPC keepalive pings such that 30s (0 means no keepalive pings) (default "0s")
+        --keepalive-timeout duration             the time to wait for the ack of a keepalive ping (0 means the gRPC default) (default "0s")
+        --keepalive-permit-without-stream        send keepalive pings even if there are no active streams (default "false")
+        --initial-window-size int32              the HTTP/2 initial window size of each stream (0 means the gRPC default) (default "0")
+        --initial-conn-window-size int32         the HTTP/2 initial window size of each connection (0 means the gRPC default) (default "0")
+        --max-header-list-size uint32            the HTTP/2 maximum header list size (0 means the gRPC default) (default "0")
+        --max-recv-msg-size int                  the maximum size in bytes of messages the client can receive (0 means the gRPC default, 4MB) (default "0")
+        --max-send-msg-size int                  the maximum size in bytes of messages the client can send (0 means the gRPC default) (default "0")
+        --compression string                     the compressor used to compress requests such that "gzip" (empty means no compression)
+        --connect-timeout duration               the timeout to connect to the server. if set, Evans waits for the connection at startup (0 means lazy connection) (default "0s")
+        --wait-for-ready                         make RPCs wait until the connection becomes ready instead of failing immediately (default "false")
+        --record string                          record every RPC to the cassette file
+        --replay string                          answer RPCs from the cassette file without connecting to the server
+        --retry int                              the maximum number of retries of failed unary RPCs (0 means no retries) (default "0")
+        --retry-backoff duration                 the backoff before the first retry. it is doubled for each retry with jitter (default "100ms")
+        --retry-max-backoff duration             the upper limit of retry backoffs (0 means no limit) (default "5s")
+        --trace-propagation                      send the W3C traceparent header with every RPC (default "true")
+        --traceparent string                     the W3C traceparent header of an existing trace. RPCs are sent as its children
+        --otlp-endpoint string                   the OTLP/HTTP endpoint such that "http://localhost:4318" to export client spans to
+        --oauth2-flow string                     acquire access tokens by the OAuth 2.0 flow. one of "client_credentials" or "device_code"
+        --oauth2-token-url string                the token endpoint of the OAuth 2.0 authorization server
+        --oauth2-device-auth-url string          the device authorization endpoint used by the device code flow
+        --oauth2-client-id string                the OAuth 2.0 client ID
+        --oauth2-client-secret string            the OAuth 2.0 client secret
+        --oauth2-scopes strings                  comma-separated OAuth 2.0 scopes (default "[]")
+        --jwt-key string                         sign a JWT for every RPC with the PEM encoded RSA or ECDSA private key, or the HMAC secret in the file
+        --jwt-alg string                         the JWT signing algorithm such that "RS256". if empty, it is inferred from the key
+        --jwt-claims string                      JWT claims as a JSON object such that '{"sub":"alice"}'. it overrides --jwt-claims-file
+        --jwt-claims-file string                 the JSON file which has JWT claims
+        --jwt-header string                      the header JWTs are sent as. JWTs are sent as "Bearer <token>" for authorization (default "authorization")
+        --jwt-expiry duration                    the lifetime of JWTs used for iat and exp claims (0 means no expiry) (default "1h0m0s")
+        --auth-provider string                   the credential provider. one of "static", "basic", "oauth2", "jwt" or "exec"
+        --auth-header string                     the header the static provider sends
+        --auth-value string                      the header value the static provider sends such that an API key
+        --auth-username string                   the username of the basic provider
+        --auth-password string                   the password of the basic provider
+        --auth-command string                    the command the exec provider runs to get credentials
+        --retry-codes strings                    comma-separated status codes to be retried. UNAVAILABLE and RESOURCE_EXHAUSTED if empty (default "[]")
+        --edit, -e                               edit the project config file by using $EDITOR (default "false")
+        --edit-global                            edit the global config file by using $EDITOR (default "false")
+        --verbose                                verbose output. DNS resolution, connections, TLS handshakes, headers, message sizes and trailers of gRPC are traced like curl -v (default "false")
+        --version, -v                            display version and exit (default "false")
+        --help, -h                               display help text and exit (default "false")
 
 Available Commands:
-        cli         CLI mode
-        repl        REPL mode
+        channelz        inspect the server by channelz
+        cli             CLI mode
+        fuzz            send requests filled with boundary values for robustness testing
+        health          check the serving status of the server or services
+        mock            serve RPCs with fake responses
+        perf            load test a method and report the throughput and latencies
+        repl            REPL mode
+        schema          inspect schemas
+        test            run test cases defined in a YAML file
 
 `, meta.Version)
@@ -220,7 +220,7 @@ func TestE2E_OldREPL(t *testing.T) {
 		},
 		"add two values to a key": {
 			args:  "testdata/test.proto",
-			input: []interface{}{"header touma=youko", "header touma=kazusa", "show header"},
+			input: []interface{}{"header touma=youko", "header -a touma=kazusa", "show header"},
 		},
 		"add two values in one command": {
 			args:  "testdata/test.proto",
@@ -361,6 +361,21 @@ func (p *stubPrompt) Select(string, []string) (string, error) {
 	return p.Input()
 }
 
+// FuzzySelect selects the item whose name is the next input.
+func (p *stubPrompt) FuzzySelect(_ string, items []*prompt.SelectItem) (int, error) {
+	s, err := p.Input()
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if item.Name == s {
+			return i, nil
+		}
+	}
+	p.t.Fatalf("no items are named '%s'", s)
+	return 0, nil
+}
+
 var (
 	goldenPathReplacer = strings.NewReplacer(
 		"/", "-",
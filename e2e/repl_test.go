@@ -71,6 +71,23 @@ func TestE2E_REPL(t *testing.T) {
 			input:       []interface{}{"package api", "service Example", "call --enrich UnaryHeaderTrailerFailure", "kaguya"},
 			hasErr:      true,
 		},
+		"call Unary with JSON output": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"package api", "service Example", "call -o json --enrich Unary", "kaguya"},
+		},
+		"call UnaryHeaderTrailerFailure with enriched output enabled by set command": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"package api", "service Example", "set enrich true", "set output json", "call UnaryHeaderTrailerFailure", "kaguya"},
+			hasErr:      true,
+		},
+		"call Unary with enriched output disabled explicitly": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"package api", "service Example", "set enrich true", "call --enrich=false Unary", "kaguya"},
+		},
+		"call Unary by selecting it by the fuzzy finder": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call", "api.Example.Unary", "kaguya"},
+		},
 		"call Unary by selecting only service": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"service Example", "call Unary", "kaguya"},
@@ -94,6 +111,128 @@ func TestE2E_REPL(t *testing.T) {
 			// io.EOF means end of inputting.
 			input: []interface{}{"call BidiStreaming", "kaguya", "chika", "miko", io.EOF},
 		},
+		"call Unary with --file": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --file testdata/unary_call.in Unary"},
+		},
+		"call ClientStreaming with --file": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --file testdata/client_streaming.in ClientStreaming"},
+		},
+		"call ClientStreaming with --file which has a JSON array": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --file testdata/client_streaming_array.in ClientStreaming"},
+		},
+		"call ClientStreaming with --file which has a YAML sequence": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --input yaml --file testdata/client_streaming.yaml ClientStreaming"},
+		},
+		"call ClientStreaming with --file which has prototext messages": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --input prototext --file testdata/client_streaming.txtpb ClientStreaming"},
+		},
+		"call Unary with YAML output": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"set input yaml", "set output yaml", "call --enrich --file testdata/unary_call.yaml UnaryHeaderTrailer"},
+		},
+		"call Unary with compact JSON output": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"set output json", "set compact true", "call Unary", "kaguya", "set compact false", "set indent 4", "call Unary", "kaguya"},
+		},
+		"call Unary with --file which is missing": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --file testdata/missing.in Unary"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"call Unary with a template saved by template command": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call Unary", "mizore", "template save mizore", "call --template mizore Unary"},
+		},
+		"call Unary with a missing template": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --template nozomi Unary"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"call Unary after restoring a session": {
+			commonFlags: "--proto testdata/test.proto",
+			input: []interface{}{
+				"header kumiko=oumae", "set output json", "session save work",
+				"header kumiko", "set output curl", "session load work", "show header", "call Unary", "kaguya",
+			},
+		},
+		"load a missing session": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"session load asuka"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"call Unary with --fill-random": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --fill-random Unary"},
+			// Requests are filled with random values, so that the output is not deterministic.
+			skipGolden: true,
+		},
+		"call ClientStreaming with --fill-random": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --fill-random ClientStreaming"},
+			skipGolden:  true,
+		},
+		"call Unary with --fill-random and --edit": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call --fill-random --edit Unary"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"resend Unary from the request history": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call Unary", "kaguya", "history requests Unary -n 1", "history requests Unary 1"},
+			// The request history is shared with other cases, so that the output is not deterministic.
+			skipGolden: true,
+		},
+		"resend a missing entry of the request history": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"history requests Unary 10000"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"recall Unary with overriding a field": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call Unary", "kaguya", "recall", "recall name=chika"},
+		},
+		"call Unary with stats": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"set stats true", "call Unary", "kaguya", "recall --stats=false", "stats"},
+			// Stats have the elapsed time, so that the output is not deterministic.
+			skipGolden: true,
+		},
+		"show stats without calling any RPCs": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"stats"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"recall without calling any RPCs": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"recall"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"call Unary with a variable": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"set $name kaguya", "call Unary", "$name", "env"},
+		},
+		"call Unary with the last response": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call Unary", "kaguya", "call Unary", "$resp.message"},
+		},
+		"set a variable with an invalid name": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"set $1st kaguya"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 		"call UnaryMessage": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryMessage", "kaguya", "shinomiya"},
@@ -111,18 +250,57 @@ func TestE2E_REPL(t *testing.T) {
 			input:       []interface{}{"call UnaryMap", "key1", "val1", "key2", "val2", io.EOF},
 			skipGolden:  true,
 		},
+		"call UnaryMapMessage": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryMapMessage", "kumiko", "kumiko", "oumae", "reina", "reina", "kousaka", io.EOF},
+			skipGolden:  true,
+		},
+		"call UnaryMapMessage with an entry": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryMapMessage", "kumiko", "kumiko", "oumae", io.EOF},
+		},
+		"call UnaryWithMapResponse": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryWithMapResponse", "kumiko"},
+		},
 		"call UnaryOneof": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryOneof", "msg", "ai", "hayasaka"},
 		},
+		"call UnaryOneof with none": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryOneof", "(none)"},
+		},
 		"call UnaryEnum": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryEnum", "Male"},
 		},
+		"call UnaryEnum with a number": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryEnum", "1"},
+		},
+		"call UnaryEnum with an unknown number": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryEnum", "100"},
+		},
+		"call UnaryEnum with an unknown name": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryEnum", "Other"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 		"call UnaryBytes": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryBytes", "\\u3084\\u306f\\u308a\\u4ffa\\u306e\\u9752\\u6625\\u30e9\\u30d6\\u30b3\\u30e1\\u306f\\u307e\\u3061\\u304c\\u3063\\u3066\\u3044\\u308b\\u3002"},
 		},
+		"call UnaryBytes with Base64": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryBytes", "base64:Rm9v"},
+		},
+		"call UnaryBytes with a file": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"call UnaryBytes", "@testdata/unary_call.in"},
+		},
 		"call UnaryRepeatedEnum": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"call UnaryRepeatedEnum", "Male", "Male", "Female", io.EOF},
@@ -191,6 +369,39 @@ func TestE2E_REPL(t *testing.T) {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"show rpc"},
 		},
+		"show tree": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show tree"},
+		},
+		"show tree with empty package": {
+			commonFlags:                 "--proto testdata/test.proto,testdata/empty_package.proto",
+			registerEmptyPackageService: true,
+			input:                       []interface{}{"show tree"},
+		},
+		"show tree filtered by a pattern": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show tree 'api.Example.*Streaming'"},
+		},
+		"show package with --format json": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show --format json package"},
+		},
+		"show tree with --format json": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show tree --format json 'api.Example.*Streaming'"},
+		},
+		"show with an invalid format": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show --format yaml package"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
+		"show tree with a pattern which matches nothing": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"show tree foo.*"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 		"show an invalid target": {
 			commonFlags: "--proto testdata/test.proto",
 			input:       []interface{}{"show foo"},
@@ -253,7 +464,19 @@ func TestE2E_REPL(t *testing.T) {
 		},
 		"add two values to a key": {
 			commonFlags: "--proto testdata/test.proto",
-			input:       []interface{}{"header touma=youko", "header touma=kazusa", "show header"},
+			input:       []interface{}{"header touma=youko", "header -a touma=kazusa", "show header"},
+		},
+		"add a scoped header": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"header x-route=default", "header --scope api.Example x-route=example", "show header"},
+		},
+		"replace values of a key": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"header touma=youko,kazusa", "header Touma=setsuna", "show header"},
+		},
+		"undo and redo removing a header": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"header grpc-client", "undo", "show header", "redo", "show header"},
 		},
 		"add two values in one command": {
 			commonFlags: "--proto testdata/test.proto",
@@ -302,6 +525,28 @@ func TestE2E_REPL(t *testing.T) {
 			skipGolden:  true,
 			hasErr:      true,
 		},
+		"desc simple message with --full": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"desc --full SimpleRequest"},
+		},
+		"desc an enum": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"desc Gender"},
+		},
+		"desc a method by the fully-qualified name": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"desc api.Example.Unary"},
+		},
+		"desc simple message with --format json": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"desc --format json SimpleRequest"},
+		},
+		"desc with an invalid format": {
+			commonFlags: "--proto testdata/test.proto",
+			input:       []interface{}{"desc --format yaml SimpleRequest"},
+			skipGolden:  true,
+			hasErr:      true,
+		},
 
 		// exit and quit command.
 
@@ -315,6 +560,34 @@ func TestE2E_REPL(t *testing.T) {
 			skipGolden:  true,
 		},
 
+		// --exec flag.
+
+		"execute a script": {
+			commonFlags: "--proto testdata/test.proto",
+			args:        "--exec testdata/script.evans",
+		},
+		"execute an invalid script": {
+			commonFlags:  "--proto testdata/test.proto",
+			args:         "--exec testdata/invalid.evans",
+			skipGolden:   true,
+			hasErr:       true,
+			expectedCode: 1,
+		},
+		"execute a script with a report of an unknown format": {
+			commonFlags:  "--proto testdata/test.proto",
+			args:         "--exec testdata/script.evans --report results.txt",
+			skipGolden:   true,
+			hasErr:       true,
+			expectedCode: 1,
+		},
+		"--report without --exec": {
+			commonFlags:  "--proto testdata/test.proto",
+			args:         "--report results.json",
+			skipGolden:   true,
+			hasErr:       true,
+			expectedCode: 1,
+		},
+
 		// special keys.
 
 		"ctrl-c skips the rest of fields if there are no message type fields": {
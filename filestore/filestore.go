@@ -0,0 +1,120 @@
+// Package filestore provides a file-based store of named JSON files. It is the base of stores such that
+// request templates, sessions and header sets. Each file is stored such that
+//
+//   <dir>/kumiko.json
+//
+package filestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const ext = ".json"
+
+// Options configures a Store.
+type Options struct {
+	// Kind is the noun of stored files such that "session". It is used in error messages.
+	Kind string
+	// ErrNotFound is returned if a file is missing.
+	ErrNotFound error
+	// ErrInvalidName is returned if a name is empty or contains path separators.
+	ErrInvalidName error
+	// DirMode and FileMode are permissions of created directories and files.
+	DirMode, FileMode os.FileMode
+}
+
+// Store stores named JSON files under a directory.
+type Store struct {
+	dir  string
+	opts Options
+}
+
+// New returns a new store which stores files under dir.
+func New(dir string, opts Options) *Store {
+	return &Store{dir: dir, opts: opts}
+}
+
+// Sub returns a store which stores files under the subdirectory name with the same options.
+func (s *Store) Sub(name string) *Store {
+	return &Store{dir: filepath.Join(s.dir, name), opts: s.opts}
+}
+
+// Names returns all file names without the extension in ascending order.
+func (s *Store) Names() ([]string, error) {
+	fis, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the %s directory", s.opts.Kind)
+	}
+	names := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ext {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(fi.Name(), ext))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load loads the file named name. Load returns Options.ErrNotFound if the file is missing.
+func (s *Store) Load(name string) ([]byte, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, s.opts.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the %s '%s'", s.opts.Kind, name)
+	}
+	return b, nil
+}
+
+// Save saves b as the file named name. If the file already exists, it is overwritten.
+func (s *Store) Save(name string, b []byte) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, s.opts.DirMode); err != nil {
+		return errors.Wrapf(err, "failed to create the %s directory", s.opts.Kind)
+	}
+	if err := ioutil.WriteFile(p, b, s.opts.FileMode); err != nil {
+		return errors.Wrapf(err, "failed to write the %s '%s'", s.opts.Kind, name)
+	}
+	return nil
+}
+
+// Delete deletes the file named name. Delete returns Options.ErrNotFound if the file is missing.
+func (s *Store) Delete(name string) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return s.opts.ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete the %s '%s'", s.opts.Kind, name)
+	}
+	return nil
+}
+
+func (s *Store) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", s.opts.ErrInvalidName
+	}
+	return filepath.Join(s.dir, name+ext), nil
+}
@@ -0,0 +1,56 @@
+package filestore_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/filestore"
+)
+
+func TestStore_Sub(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	errNotFound := errors.New("not found")
+	s := filestore.New(dir, filestore.Options{Kind: "file", ErrNotFound: errNotFound, DirMode: 0700, FileMode: 0600})
+	sub := s.Sub("api.Example.Unary")
+	if err := sub.Save("kumiko", []byte("{}")); err != nil {
+		t.Fatalf("Save must not return an error, but got '%s'", err)
+	}
+
+	names, err := s.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("files in subdirectories must not be listed, but got %v", names)
+	}
+	names, err = sub.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"kumiko"}, names); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+	if _, err := s.Load("kumiko"); !errors.Is(err, errNotFound) {
+		t.Errorf("Load must return the configured error, but got '%v'", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(dir, "api.Example.Unary", "kumiko.json"))
+		if err != nil {
+			t.Fatalf("failed to stat the file: %s", err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("expected the permission 600, but got %o", perm)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package proto
+
+import (
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/prompt"
+	"github.com/pkg/errors"
+)
+
+const (
+	anyMessageName   = "google.protobuf.Any"
+	anyTypeHint      = "Any: type URL"
+	anyTypeURLPrefix = "type.googleapis.com/"
+)
+
+// MessageResolver resolves message types which are embedded in google.protobuf.Any fields.
+type MessageResolver interface {
+	// MessageNames returns fully-qualified message names which are suggested as type URLs.
+	MessageNames() []string
+	// ResolveMessage returns the descriptor of the passed fully-qualified message name.
+	ResolveMessage(fqmn string) (*desc.MessageDescriptor, error)
+}
+
+// messageNameCompleter suggests type URLs from message names.
+type messageNameCompleter struct {
+	names []string
+}
+
+func (c *messageNameCompleter) Complete(d prompt.Document) []*prompt.Suggest {
+	s := make([]*prompt.Suggest, 0, len(c.names))
+	for _, n := range c.names {
+		s = append(s, prompt.NewSuggestion(anyTypeURLPrefix+n, ""))
+	}
+	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), true)
+}
+
+// parseTypeURL returns the normalized type URL and the fully-qualified message name of in.
+// in is either a type URL such that "type.googleapis.com/api.Book" or a bare message name such that "api.Book".
+func parseTypeURL(in string) (typeURL, fqmn string) {
+	i := strings.LastIndex(in, "/")
+	if i < 0 {
+		return anyTypeURLPrefix + in, in
+	}
+	return in, in[i+1:]
+}
+
+// newAny packs msg into a new google.protobuf.Any message which is described by md.
+func newAny(md *desc.MessageDescriptor, typeURL string, msg *dynamic.Message) (*dynamic.Message, error) {
+	b, err := msg.Marshal()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal a message of type %s", msg.GetMessageDescriptor().GetFullyQualifiedName())
+	}
+	a := dynamic.NewMessage(md)
+	if err := a.TrySetFieldByName("type_url", typeURL); err != nil {
+		return nil, errors.Wrap(err, "failed to set the type URL")
+	}
+	if err := a.TrySetFieldByName("value", b); err != nil {
+		return nil, errors.Wrap(err, "failed to set the value")
+	}
+	return a, nil
+}
+
+func isAnyField(field *desc.FieldDescriptor) bool {
+	return field.GetMessageType() != nil && field.GetMessageType().GetFullyQualifiedName() == anyMessageName
+}
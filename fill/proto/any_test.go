@@ -0,0 +1,136 @@
+package proto
+
+import (
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/prompt"
+)
+
+type anyTestPrompt struct {
+	prompt.Prompt
+	inputs []string
+}
+
+func (p *anyTestPrompt) Input() (string, error) {
+	if len(p.inputs) == 0 {
+		return "", io.EOF
+	}
+	in := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	return in, nil
+}
+
+func (p *anyTestPrompt) SetPrefix(string)              {}
+func (p *anyTestPrompt) SetPrefixColor(prompt.Color)   {}
+func (p *anyTestPrompt) SetCompleter(prompt.Completer) {}
+
+type anyTestResolver struct {
+	fd *desc.FileDescriptor
+}
+
+func (r *anyTestResolver) MessageNames() []string {
+	return []string{"api.Book", "api.Envelope"}
+}
+
+func (r *anyTestResolver) ResolveMessage(fqmn string) (*desc.MessageDescriptor, error) {
+	md := r.fd.FindMessage(fqmn)
+	if md == nil {
+		return nil, idl.ErrUnknownSymbol
+	}
+	return md, nil
+}
+
+func TestInteractiveFiller_inputAnyField(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto3";
+package api;
+import "google/protobuf/any.proto";
+message Envelope {
+  google.protobuf.Any payload = 1;
+}
+message Book {
+  string title = 1;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	resolver := &anyTestResolver{fd: fds[0]}
+
+	cases := map[string]struct {
+		inputs []string
+
+		expectedTypeURL string
+		unset           bool
+		hasErr          bool
+	}{
+		"type URL":        {inputs: []string{"type.googleapis.com/api.Book", "Hibike! Euphonium"}, expectedTypeURL: "type.googleapis.com/api.Book"},
+		"bare name":       {inputs: []string{"api.Book", "Hibike! Euphonium"}, expectedTypeURL: "type.googleapis.com/api.Book"},
+		"custom host":     {inputs: []string{"example.com/types/api.Book", "Hibike! Euphonium"}, expectedTypeURL: "example.com/types/api.Book"},
+		"empty input":     {inputs: []string{""}, unset: true},
+		"unknown message": {inputs: []string{"api.Kumiko"}, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := NewInteractiveFiller(&anyTestPrompt{inputs: c.inputs}, "{ancestor}{name} ({type}) => ", WithMessageResolver(resolver))
+			msg := dynamic.NewMessage(resolver.fd.FindMessage("api.Envelope"))
+			err := f.Fill(msg, false)
+			if c.hasErr {
+				if err == nil {
+					t.Fatal("Fill must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fill must not return an error, but got '%s'", err)
+			}
+			if c.unset {
+				if msg.HasFieldName("payload") {
+					t.Errorf("payload must be unset, but got '%v'", msg.GetFieldByName("payload"))
+				}
+				return
+			}
+
+			var a any.Any
+			if err := msg.GetFieldByName("payload").(*dynamic.Message).ConvertTo(&a); err != nil {
+				t.Fatalf("ConvertTo must not return an error, but got '%s'", err)
+			}
+			if a.GetTypeUrl() != c.expectedTypeURL {
+				t.Errorf("expected type URL '%s', but got '%s'", c.expectedTypeURL, a.GetTypeUrl())
+			}
+			book := dynamic.NewMessage(resolver.fd.FindMessage("api.Book"))
+			if err := book.Unmarshal(a.GetValue()); err != nil {
+				t.Fatalf("Unmarshal must not return an error, but got '%s'", err)
+			}
+			if title := book.GetFieldByName("title"); title != "Hibike! Euphonium" {
+				t.Errorf("expected title 'Hibike! Euphonium', but got '%v'", title)
+			}
+		})
+	}
+}
+
+func TestMessageNameCompleter(t *testing.T) {
+	c := &messageNameCompleter{names: []string{"api.Book", "api.Person"}}
+	s := c.Complete(&anyTestDocument{word: "type.googleapis.com/api.B"})
+	if len(s) != 1 || s[0].Text != "type.googleapis.com/api.Book" {
+		t.Errorf("expected only 'type.googleapis.com/api.Book', but got %v", s)
+	}
+}
+
+type anyTestDocument struct {
+	word string
+}
+
+func (d *anyTestDocument) GetWordBeforeCursor() string { return d.word }
+func (d *anyTestDocument) TextBeforeCursor() string    { return d.word }
@@ -1,8 +1,11 @@
 package proto
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
 	"strconv"
+	"strings"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/pkg/errors"
@@ -75,13 +78,8 @@ func convertValue(pv string, fieldType descriptor.FieldDescriptorProto_Type) (in
 		// already string
 		v = pv
 
-	// Use strconv.Unquote to interpret byte literals and Unicode literals.
-	// For example, a user inputs `\x6f\x67\x69\x73\x6f`,
-	// His expects "ogiso" in string, but backslashes in the input are not interpreted as an escape sequence.
-	// So, we need to call strconv.Unquote to interpret backslashes as an escape sequence.
 	case descriptor.FieldDescriptorProto_TYPE_BYTES:
-		pv, err = strconv.Unquote(`"` + pv + `"`)
-		v = []byte(pv)
+		v, err = convertBytes(pv)
 
 	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
 		v, err = strconv.ParseInt(pv, 10, 64)
@@ -105,3 +103,43 @@ func convertValue(pv string, fieldType descriptor.FieldDescriptorProto_Type) (in
 	}
 	return v, nil
 }
+
+const (
+	bytesFilePrefix   = "@"
+	bytesBase64Prefix = "base64:"
+)
+
+// convertBytes converts a string input pv to bytes. pv is interpreted as one of the followings:
+//
+//   - @<path>: the contents of the file.
+//   - base64:<encoded>: Base64 encoded bytes.
+//   - Otherwise: byte literals and Unicode literals.
+//
+func convertBytes(pv string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(pv, bytesFilePrefix):
+		b, err := ioutil.ReadFile(strings.TrimPrefix(pv, bytesFilePrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the file")
+		}
+		return b, nil
+	case strings.HasPrefix(pv, bytesBase64Prefix):
+		// Trim paddings to accept both of padded and unpadded forms.
+		encoded := strings.TrimRight(strings.TrimPrefix(pv, bytesBase64Prefix), "=")
+		b, err := base64.RawStdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode Base64 encoded bytes")
+		}
+		return b, nil
+	default:
+		// Use strconv.Unquote to interpret byte literals and Unicode literals.
+		// For example, a user inputs `\x6f\x67\x69\x73\x6f`,
+		// His expects "ogiso" in string, but backslashes in the input are not interpreted as an escape sequence.
+		// So, we need to call strconv.Unquote to interpret backslashes as an escape sequence.
+		s, err := strconv.Unquote(`"` + pv + `"`)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+}
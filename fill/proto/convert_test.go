@@ -85,6 +85,31 @@ func Test_convertValue(t *testing.T) {
 			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
 			expected:  []byte("小木曽"),
 		},
+		"bytes (Base64)": {
+			v:         "base64:b2dpc28=",
+			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
+			expected:  []byte("ogiso"),
+		},
+		"bytes (unpadded Base64)": {
+			v:         "base64:b2dpc28",
+			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
+			expected:  []byte("ogiso"),
+		},
+		"bytes (invalid Base64)": {
+			v:         "base64:b2dp*28",
+			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
+			hasErr:    true,
+		},
+		"bytes (file)": {
+			v:         "@testdata/bytes.txt",
+			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
+			expected:  []byte("ogiso"),
+		},
+		"bytes (missing file)": {
+			v:         "@testdata/kumiko.txt",
+			fieldType: descriptor.FieldDescriptorProto_TYPE_BYTES,
+			hasErr:    true,
+		},
 		"sfixed64": {
 			v:         "100",
 			fieldType: descriptor.FieldDescriptorProto_TYPE_SFIXED64,
@@ -0,0 +1,45 @@
+package proto
+
+import (
+	"strconv"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/ktr0731/evans/prompt"
+	"github.com/pkg/errors"
+)
+
+// enumValueCompleter suggests value names of an enum.
+type enumValueCompleter struct {
+	enum *desc.EnumDescriptor
+}
+
+func (c *enumValueCompleter) Complete(d prompt.Document) []*prompt.Suggest {
+	values := c.enum.GetValues()
+	s := make([]*prompt.Suggest, 0, len(values))
+	for _, v := range values {
+		s = append(s, prompt.NewSuggestion(v.GetName(), strconv.Itoa(int(v.GetNumber()))))
+	}
+	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), true)
+}
+
+// convertEnum converts an input in to a number of the enum field.
+// in is either a value name or a number. A number which is not defined in the enum is also accepted
+// for testing the forward compatibility of servers.
+// If in is empty, convertEnum returns the default value of the field.
+func convertEnum(in string, field *desc.FieldDescriptor) (int32, error) {
+	enum := field.GetEnumType()
+	if in == "" {
+		if d, ok := field.GetDefaultValue().(int32); ok {
+			return d, nil
+		}
+		return enum.GetValues()[0].GetNumber(), nil
+	}
+	if v := enum.FindValueByName(in); v != nil {
+		return v.GetNumber(), nil
+	}
+	n, err := strconv.ParseInt(in, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("unknown enum value '%s' of %s", in, enum.GetFullyQualifiedName())
+	}
+	return int32(n), nil
+}
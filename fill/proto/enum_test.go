@@ -0,0 +1,63 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+func Test_convertEnum(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto3";
+package api;
+enum Part {
+  PART_UNSPECIFIED = 0;
+  EUPHONIUM = 1;
+  TRUMPET = 2;
+}
+message Member {
+  Part part = 1;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	field := fds[0].FindMessage("api.Member").FindFieldByName("part")
+
+	cases := map[string]struct {
+		in string
+
+		expected int32
+		hasErr   bool
+	}{
+		"name":          {in: "TRUMPET", expected: 2},
+		"number":        {in: "1", expected: 1},
+		"unknown value": {in: "100", expected: 100},
+		"negative":      {in: "-1", expected: -1},
+		"empty input":   {in: "", expected: 0},
+		"unknown name":  {in: "TUBA", hasErr: true},
+		"out of range":  {in: "2147483648", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual, err := convertEnum(c.in, field)
+			if c.hasErr {
+				if err == nil {
+					t.Fatal("convertEnum must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertEnum must not return an error, but got '%s'", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected %d, but got %d", c.expected, actual)
+			}
+		})
+	}
+}
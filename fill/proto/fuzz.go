@@ -0,0 +1,232 @@
+package proto
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/fill"
+)
+
+const (
+	// fuzzHugeSize is the length of huge strings and bytes.
+	fuzzHugeSize = 1 << 16
+	// fuzzManyElements is the number of elements of large repeated fields and maps.
+	fuzzManyElements = 100
+)
+
+var (
+	fuzzStrings = []string{
+		"",
+		" ",
+		"\x00",
+		"‮مرحبا",
+		"🍣🍺",
+		"%s%d%n",
+		"' OR '1'='1",
+		"../../../../etc/passwd",
+		"<script>alert(1)</script>",
+		"null",
+		"-1",
+	}
+	fuzzFloats = []float64{
+		0, math.Copysign(0, -1), 1, -1,
+		math.NaN(), math.Inf(1), math.Inf(-1),
+		math.MaxFloat64, -math.MaxFloat64, math.SmallestNonzeroFloat64,
+	}
+	fuzzFloat32s = []float32{
+		0, float32(math.Copysign(0, -1)), 1, -1,
+		float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1)),
+		math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32,
+	}
+	// fuzzTimestamps are pairs of seconds and nanos. They are in the valid range of google.protobuf.Timestamp,
+	// from 0001-01-01T00:00:00Z to 9999-12-31T23:59:59.999999999Z.
+	fuzzTimestamps = [][2]int64{
+		{0, 0},
+		{-1, 999999999},
+		{-62135596800, 0},
+		{253402300799, 999999999},
+		{1<<31 - 1, 0},
+		{1 << 31, 0},
+	}
+	// fuzzDurations are pairs of seconds and nanos in the valid range of google.protobuf.Duration.
+	fuzzDurations = [][2]int64{
+		{0, 0},
+		{0, 1},
+		{0, -1},
+		{315576000000, 999999999},
+		{-315576000000, -999999999},
+	}
+)
+
+// FuzzFiller is an implementation of fill.Filler. It fills fields with structurally valid but boundary-heavy values
+// such that the maximum integers, huge strings, empty repeated fields and absurd timestamps.
+// FuzzFiller fills only one message, so that it returns io.EOF from the second call.
+type FuzzFiller struct {
+	walker *Walker
+	filled bool
+}
+
+// NewFuzzFiller returns a new filler which fills fields with boundary values chosen by r.
+// The same source always generates the same message.
+func NewFuzzFiller(r *rand.Rand) *FuzzFiller {
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &FuzzFiller{walker: NewWalker(&fuzzGenerator{r: r}, maxRandomDepth)}
+}
+
+// Fill receives v that is an instance of *dynamic.Message, and fills fields of v with boundary values.
+func (f *FuzzFiller) Fill(v interface{}) error {
+	msg, ok := v.(*dynamic.Message)
+	if !ok {
+		return fill.ErrCodecMismatch
+	}
+	if f.filled {
+		return io.EOF
+	}
+	f.filled = true
+	f.walker.Fill(msg)
+	return nil
+}
+
+// fuzzGenerator is an implementation of ValueGenerator. It chooses boundary values of each field type.
+type fuzzGenerator struct {
+	r *rand.Rand
+}
+
+// OneOf returns one of the fields of o, or nil to leave o unset.
+func (g *fuzzGenerator) OneOf(o *desc.OneOfDescriptor) *desc.FieldDescriptor {
+	choices := o.GetChoices()
+	if i := g.r.Intn(len(choices) + 1); i < len(choices) {
+		return choices[i]
+	}
+	return nil
+}
+
+// Len leaves some fields unset. Repeated fields and maps are empty, have an element, or have many elements.
+func (g *fuzzGenerator) Len(field *desc.FieldDescriptor) int {
+	if g.r.Intn(5) == 0 {
+		return 0
+	}
+	if field.IsRepeated() {
+		return []int{0, 1, fuzzManyElements}[g.r.Intn(3)]
+	}
+	return 1
+}
+
+// Value returns a boundary value of the type of field.
+func (g *fuzzGenerator) Value(field *desc.FieldDescriptor) interface{} {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return g.string()
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		switch g.r.Intn(3) {
+		case 0:
+			return []byte{}
+		case 1:
+			return []byte{0}
+		default:
+			b := make([]byte, fuzzHugeSize)
+			g.r.Read(b) //nolint:errcheck
+			return b
+		}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return g.r.Intn(2) == 0
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return []int32{0, 1, -1, math.MaxInt32, math.MinInt32}[g.r.Intn(5)]
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return []int64{0, 1, -1, math.MaxInt64, math.MinInt64}[g.r.Intn(5)]
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return []uint32{0, 1, math.MaxUint32}[g.r.Intn(3)]
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return []uint64{0, 1, math.MaxUint64}[g.r.Intn(3)]
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return fuzzFloat32s[g.r.Intn(len(fuzzFloat32s))]
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return fuzzFloats[g.r.Intn(len(fuzzFloats))]
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return g.enum(field)
+	default:
+		return nil
+	}
+}
+
+// string returns one of tricky strings or a huge string.
+func (g *fuzzGenerator) string() string {
+	switch g.r.Intn(len(fuzzStrings) + 2) {
+	case 0:
+		return strings.Repeat("a", fuzzHugeSize)
+	case 1:
+		return strings.Repeat("🍣", fuzzHugeSize/4)
+	default:
+		return fuzzStrings[g.r.Intn(len(fuzzStrings))]
+	}
+}
+
+// enum returns the smallest or largest defined number, or a random defined number.
+// Enums of proto3 are open, so that an undefined number is also returned.
+func (g *fuzzGenerator) enum(field *desc.FieldDescriptor) int32 {
+	vals := field.GetEnumType().GetValues()
+	min, max := vals[0].GetNumber(), vals[0].GetNumber()
+	for _, v := range vals {
+		if n := v.GetNumber(); n < min {
+			min = n
+		} else if n > max {
+			max = n
+		}
+	}
+	n := 3
+	if field.GetFile().IsProto3() && max < math.MaxInt32 {
+		n++
+	}
+	switch g.r.Intn(n) {
+	case 0:
+		return min
+	case 1:
+		return max
+	case 2:
+		return vals[g.r.Intn(len(vals))].GetNumber()
+	default:
+		return max + 1
+	}
+}
+
+// Message returns a boundary value of well-known types, an empty message, or a message filled by the walker.
+func (g *fuzzGenerator) Message(field *desc.FieldDescriptor) (*dynamic.Message, bool) {
+	md := field.GetMessageType()
+	m := dynamic.NewMessage(md)
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		return g.secondsAndNanos(m, fuzzTimestamps), false
+	case "google.protobuf.Duration":
+		return g.secondsAndNanos(m, fuzzDurations), false
+	case "google.protobuf.Empty":
+		return m, false
+	case "google.protobuf.Any", "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue", "google.protobuf.FieldMask":
+		return nil, false
+	}
+	if strings.HasPrefix(md.GetFullyQualifiedName(), "google.protobuf.") && strings.HasSuffix(md.GetName(), "Value") {
+		value := md.FindFieldByName("value")
+		if err := m.TrySetField(value, g.Value(value)); err != nil {
+			return nil, false
+		}
+		return m, false
+	}
+	// An empty message is also a boundary value.
+	return m, g.r.Intn(4) != 0
+}
+
+// secondsAndNanos sets one of pairs to seconds and nanos fields of m.
+func (g *fuzzGenerator) secondsAndNanos(m *dynamic.Message, pairs [][2]int64) *dynamic.Message {
+	p := pairs[g.r.Intn(len(pairs))]
+	if m.TrySetFieldByName("seconds", p[0]) != nil || m.TrySetFieldByName("nanos", int32(p[1])) != nil {
+		return nil
+	}
+	return m
+}
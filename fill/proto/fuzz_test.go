@@ -0,0 +1,68 @@
+package proto
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestFuzzFiller(t *testing.T) {
+	md := newRandomTestDescriptor(t).FindMessage("api.Member")
+
+	fill := func(seed int64) *dynamic.Message {
+		f := NewFuzzFiller(rand.New(rand.NewSource(seed)))
+		msg := dynamic.NewMessage(md)
+		if err := f.Fill(msg); err != nil {
+			t.Fatalf("Fill must not return an error, but got '%s'", err)
+		}
+		if err := f.Fill(dynamic.NewMessage(md)); err != io.EOF {
+			t.Fatalf("the second Fill must return io.EOF, but got '%v'", err)
+		}
+		return msg
+	}
+
+	var hugeString, emptyTags, manyTags, maxAge, undefinedPart bool
+	for seed := int64(0); seed < 200; seed++ {
+		msg := fill(seed)
+		if !dynamic.MessagesEqual(msg, fill(seed)) {
+			t.Fatalf("the same seed must generate the same message, seed: %d", seed)
+		}
+		if _, err := msg.Marshal(); err != nil {
+			t.Fatalf("the generated message must be serializable, but got '%s' (seed: %d)", err, seed)
+		}
+		if msg.HasFieldName("nickname") && msg.HasFieldName("grade") {
+			t.Errorf("only one field of a oneof must be filled (seed: %d)", seed)
+		}
+
+		if s, ok := msg.GetFieldByName("user_id").(string); ok && len(s) >= fuzzHugeSize {
+			hugeString = true
+		}
+		// An empty repeated field is the same as the unset one.
+		switch msg.FieldLength(md.FindFieldByName("tags")) {
+		case 0:
+			emptyTags = true
+		case fuzzManyElements:
+			manyTags = true
+		}
+		if msg.GetFieldByName("age").(int32) == math.MaxInt32 {
+			maxAge = true
+		}
+		if msg.GetFieldByName("part").(int32) == 2 {
+			undefinedPart = true
+		}
+	}
+	for name, generated := range map[string]bool{
+		"a huge string":           hugeString,
+		"an empty repeated field": emptyTags,
+		"a large repeated field":  manyTags,
+		"the maximum integer":     maxAge,
+		"an undefined enum value": undefinedPart,
+	} {
+		if !generated {
+			t.Errorf("expected %s is generated", name)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package proto
+
+import (
+	"github.com/ktr0731/evans/prompt"
+)
+
+// historyCompleter suggests previously inputted values of a field. The newest value is suggested first.
+type historyCompleter struct {
+	// values is inputted values in ascending order.
+	values []string
+}
+
+func (c *historyCompleter) Complete(d prompt.Document) []*prompt.Suggest {
+	s := make([]*prompt.Suggest, 0, len(c.values))
+	for i := len(c.values) - 1; i >= 0; i-- {
+		s = append(s, prompt.NewSuggestion(c.values[i], ""))
+	}
+	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), false)
+}
@@ -0,0 +1,109 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+type inputHistory map[string][]string
+
+func (h inputHistory) Load(path string) []string { return h[path] }
+func (h inputHistory) Add(path, value string)    { h[path] = append(h[path], value) }
+
+// historyStubPrompt records histories set by SetCommandHistory.
+type historyStubPrompt struct {
+	*stubPrompt
+	histories [][]string
+}
+
+func (p *historyStubPrompt) SetCommandHistory(h []string) {
+	p.histories = append(p.histories, h)
+}
+
+func TestInteractiveFiller_inputHistory(t *testing.T) {
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Member"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:   proto.String("name"),
+						Number: proto.Int32(1),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:   proto.String("grade"),
+						Number: proto.Int32(2),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+				},
+			},
+			{
+				Name: proto.String("Band"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:     proto.String("leader"),
+						Number:   proto.Int32(1),
+						Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".api.Member"),
+					},
+					{
+						Name:   proto.String("name"),
+						Number: proto.Int32(2),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	h := inputHistory{"api.Band.leader.name": {"reina"}}
+	p := &historyStubPrompt{stubPrompt: &stubPrompt{inputs: []string{"kumiko", "two", "", "$band"}}}
+	expander := func(s string) string { return "kitauji" }
+	f := NewInteractiveFiller(p, "{name} => ", WithInputHistory(h), WithInputExpander(expander))
+	err = f.Fill(dynamic.NewMessage(fd.FindMessage("api.Band")), false)
+	if err == nil {
+		t.Fatal("Fill must return an error because 'two' is not a number")
+	}
+
+	// The invalid input is not added to the history. The empty input leaves the field unset.
+	p.stubPrompt.inputs = []string{"kumiko", "", "$band"}
+	if err := f.Fill(dynamic.NewMessage(fd.FindMessage("api.Band")), false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+
+	expectedHistory := inputHistory{
+		"api.Band.leader.name": {"reina", "kumiko", "kumiko"},
+		// Inputs are added before expanding variables.
+		"api.Band.name": {"$band"},
+	}
+	if diff := cmp.Diff(expectedHistory, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"reina"}, p.histories[0]); diff != "" {
+		t.Errorf("the prompt history must be the history of the field (-want, +got)\n%s", diff)
+	}
+}
+
+func TestHistoryCompleter(t *testing.T) {
+	c := &historyCompleter{values: []string{"kumiko", "reina", "kanade"}}
+	s := c.Complete(&anyTestDocument{word: "k"})
+	if len(s) != 2 || s[0].Text != "kanade" || s[1].Text != "kumiko" {
+		t.Errorf("expected 'kanade' and 'kumiko' in this order, but got %v", s)
+	}
+}
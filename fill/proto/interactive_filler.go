@@ -3,12 +3,14 @@ package proto
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/ktr0731/evans/fill"
+	idlproto "github.com/ktr0731/evans/idl/proto"
 	"github.com/ktr0731/evans/prompt"
 	"github.com/pkg/errors"
 )
@@ -21,14 +23,105 @@ type InteractiveFiller struct {
 	state        promptInputterState
 
 	digManually bool
+
+	// resolver is used to input google.protobuf.Any fields. If it is nil, Any fields are inputted as normal messages.
+	resolver MessageResolver
+
+	// factory instantiates nested messages. If it is nil, nested messages don't recognize their extensions.
+	factory MessageFactory
+
+	// expander expands inputs of scalar fields and well-known type fields. If it is nil, inputs are used as it is.
+	expander func(string) string
+
+	// inputHistory stores inputted values for each field path. If it is nil, the history is not used.
+	inputHistory InputHistory
+
+	// random generates random values for the rest of fields after the prompt returns prompt.ErrFillRandom.
+	// fillRandom is true after that.
+	random     *Walker
+	fillRandom bool
+
+	// commentWriter is written leading comments of fields before prompting them. If it is nil, comments are not shown.
+	// lastCommented is the descriptor whose comment is written last, which prevents repeated fields from showing
+	// the same comment for each element.
+	commentWriter io.Writer
+	lastCommented desc.Descriptor
+}
+
+// InputHistory stores inputted values for each field path.
+// A field path is the fully-qualified name of the request message followed by field names such that
+// "api.CreateUserRequest.user.id".
+type InputHistory interface {
+	// Load returns inputted values of the field path in ascending order.
+	Load(path string) []string
+	// Add adds an inputted value of the field path.
+	Add(path, value string)
+}
+
+// MessageFactory instantiates messages which recognize their extensions.
+type MessageFactory interface {
+	// NewMessage returns a new message of md.
+	NewMessage(md *desc.MessageDescriptor) (*dynamic.Message, error)
+}
+
+// historySetter is implemented by prompts whose command history can be replaced.
+type historySetter interface {
+	SetCommandHistory(h []string)
+}
+
+// InteractiveFillerOption is an option for NewInteractiveFiller.
+type InteractiveFillerOption func(*InteractiveFiller)
+
+// WithMessageResolver enables inputting google.protobuf.Any fields by a type URL and the fields of the embedded message.
+func WithMessageResolver(r MessageResolver) InteractiveFillerOption {
+	return func(f *InteractiveFiller) {
+		f.resolver = r
+	}
+}
+
+// WithMessageFactory makes the filler instantiate nested messages by mf, which enables inputting extensions of
+// nested messages. Extensions of the message passed to Fill are inputted regardless of this option.
+func WithMessageFactory(mf MessageFactory) InteractiveFillerOption {
+	return func(f *InteractiveFiller) {
+		f.factory = mf
+	}
+}
+
+// WithInputExpander expands inputs of scalar fields and well-known type fields by f before converting them.
+// It is used to replace references to variables with their values.
+func WithInputExpander(f func(string) string) InteractiveFillerOption {
+	return func(filler *InteractiveFiller) {
+		filler.expander = f
+	}
+}
+
+// WithInputHistory makes the filler offer previously inputted values of each field as the prompt history and
+// suggestions. Inputted values of scalar, enum and well-known type fields and type URLs of Any fields are added to h.
+func WithInputHistory(h InputHistory) InteractiveFillerOption {
+	return func(f *InteractiveFiller) {
+		f.inputHistory = h
+	}
+}
+
+// WithCommentWriter makes the filler write leading comments of fields and oneofs in proto files to w before
+// prompting them. Descriptors without source code info have no comments.
+func WithCommentWriter(w io.Writer) InteractiveFillerOption {
+	return func(f *InteractiveFiller) {
+		f.commentWriter = w
+	}
 }
 
 // NewInteractiveFiller instantiates a new filler that fills each field interactively.
-func NewInteractiveFiller(prompt prompt.Prompt, prefixFormat string) *InteractiveFiller {
-	return &InteractiveFiller{
+func NewInteractiveFiller(prompt prompt.Prompt, prefixFormat string, opts ...InteractiveFillerOption) *InteractiveFiller {
+	f := &InteractiveFiller{
 		prompt:       prompt,
 		prefixFormat: prefixFormat,
+		random:       NewWalker(newRandomGenerator(nil), maxRandomDepth),
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 // Fill receives v that is an instance of *dynamic.Message.
@@ -37,6 +130,8 @@ func NewInteractiveFiller(prompt prompt.Prompt, prefixFormat string) *Interactiv
 // Note that Fill resets the previous state when it is called again.
 func (f *InteractiveFiller) Fill(v interface{}, digManually bool) error {
 	f.digManually = digManually
+	f.fillRandom = false
+	f.lastCommented = nil
 
 	msg, ok := v.(*dynamic.Message)
 	if !ok {
@@ -44,6 +139,7 @@ func (f *InteractiveFiller) Fill(v interface{}, digManually bool) error {
 	}
 
 	f.state = initialPromptInputterState.clone()
+	f.state.root = msg.GetMessageDescriptor().GetFullyQualifiedName()
 	err := f.inputMessage(msg)
 	// If io.EOF is returned, it means CTRL+d is entered.
 	// In this case, Input skips rest fields and finishes normally.
@@ -84,7 +180,15 @@ func (f *InteractiveFiller) inputMessage(dmsg *dynamic.Message) error {
 		f.state = currentState
 	}()
 
-	for _, field := range dmsg.GetMessageDescriptor().GetFields() {
+	// Extensions known to dmsg are inputted after normal fields in ascending order of field numbers.
+	// GetFields returns the slice owned by the descriptor, so fields are copied before appending extensions.
+	exts := dmsg.GetKnownExtensions()
+	sort.Slice(exts, func(i, j int) bool {
+		return exts[i].GetNumber() < exts[j].GetNumber()
+	})
+	fields := dmsg.GetMessageDescriptor().GetFields()
+	fields = append(append(make([]*desc.FieldDescriptor, 0, len(fields)+len(exts)), fields...), exts...)
+	for _, field := range fields {
 		err := f.inputField(dmsg, field, false)
 		if errors.Is(err, io.EOF) {
 			return io.EOF
@@ -99,10 +203,26 @@ func (f *InteractiveFiller) inputMessage(dmsg *dynamic.Message) error {
 
 // inputField tries to set a inputted value to a field of the passed message dmsg.
 // An argument partOfRepeatedField means inputField is called from inputRepeatedField.
+// If prompt.ErrFillRandom is returned while inputting the field, the field and the rest of fields are filled with
+// random values.
 //
 // inputField returns following errors:
 //   - io.EOF: CTRL+d is entered.
-func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldDescriptor, partOfRepeatedField bool) error {
+func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldDescriptor, partOfRepeatedField bool) (err error) {
+	if f.fillRandom {
+		return f.inputRandomField(dmsg, field, partOfRepeatedField)
+	}
+	defer func() {
+		if errors.Is(err, prompt.ErrFillRandom) {
+			err = f.setRandomValue(dmsg, field, partOfRepeatedField)
+		}
+	}()
+
+	// Map fields are also repeated fields, so it must be checked before repeated fields.
+	if field.IsMap() {
+		return f.inputMapField(dmsg, field)
+	}
+
 	// If a repeated field is found, call inputRepeatedField instead.
 	if !partOfRepeatedField && field.IsRepeated() {
 		return f.inputRepeatedField(dmsg, field)
@@ -112,12 +232,17 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 		if f.isSelectedOneOf(field) {
 			return nil
 		}
+		oneof := field.GetOneOf()
 		var err error
 		field, err = f.selectOneOf(field)
 		if err != nil {
 			return err
 		}
-		f.state.selectedOneOf[field.GetOneOf().GetFullyQualifiedName()] = nil
+		f.state.selectedOneOf[oneof.GetFullyQualifiedName()] = nil
+		// "(none)" is selected. The oneof is left unset.
+		if field == nil {
+			return nil
+		}
 	}
 
 	old := f.state.hasAncestorAndHasRepeatedField
@@ -126,28 +251,80 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 		f.state.hasAncestorAndHasRepeatedField = old
 	}()
 
+	// An empty input leaves the field unset for fields which are not a part of repeated fields.
+	// A oneof field is excepted because selecting it means that the field should be set.
+	unsettable := !partOfRepeatedField && !isOneOfField(field)
+
 	switch {
 	case field.GetEnumType() != nil:
-		v, err := f.selectEnum(field)
+		f.prompt.SetPrefix(f.makePrefix(field))
+		v, err := f.inputEnumField(field, unsettable)
 		if err != nil {
 			return err
 		}
+		if v == nil {
+			return nil
+		}
 		if partOfRepeatedField {
-			if err := dmsg.TryAddRepeatedField(field, v.GetNumber()); err != nil {
+			if err := dmsg.TryAddRepeatedField(field, v); err != nil {
 				return err
 			}
 		} else {
-			if err := dmsg.TrySetField(field, v.GetNumber()); err != nil {
+			if err := dmsg.TrySetField(field, v); err != nil {
 				return err
 			}
 		}
+	case f.resolver != nil && isAnyField(field):
+		v, err := f.inputAnyField(field)
+		if err != nil && !errors.Is(err, prompt.ErrAbort) {
+			return err
+		}
+		// Empty input means the field is left unset.
+		if v == nil {
+			return err
+		}
+
+		if partOfRepeatedField {
+			if err := dmsg.TryAddRepeatedField(field, v); err != nil {
+				return errors.Wrap(err, "failed to add an inputted message to a repeated field")
+			}
+		} else {
+			if err := dmsg.TrySetField(field, v); err != nil {
+				return errors.Wrap(err, "failed to set an inputted message to a field")
+			}
+		}
+
+		if partOfRepeatedField && errors.Is(err, prompt.ErrAbort) {
+			return prompt.ErrAbort
+		}
+		f.state.color.Next()
+	case isWellKnownTypeField(field):
+		f.prompt.SetPrefix(f.makePrefix(field))
+		v, err := f.inputWellKnownTypeField(field)
+		if err != nil {
+			return err
+		}
+		// Empty input means the field is left unset.
+		if v == nil {
+			return nil
+		}
+
+		if partOfRepeatedField {
+			if err := dmsg.TryAddRepeatedField(field, v); err != nil {
+				return errors.Wrapf(err, "failed to add inputted value to repeated field '%s'", field.GetName())
+			}
+		} else {
+			if err := dmsg.TrySetField(field, v); err != nil {
+				return errors.Wrapf(err, "failed to set inputted value to field '%s'", field.GetName())
+			}
+		}
 	case field.GetMessageType() != nil:
 		if f.isCirculatedField(field) {
 			prefix := strings.Join(f.state.ancestor, ancestorDelimiter)
 			if prefix != "" {
 				prefix += ancestorDelimiter
 			}
-			prefix += field.GetName()
+			prefix += displayName(field)
 
 			choice, err := f.prompt.Select(
 				fmt.Sprintf("circulated field was found. dig down or finish? field: %s (%s)", prefix, strings.Join(f.state.circulatedMessages[field.GetMessageType().GetFullyQualifiedName()], ">")),
@@ -165,8 +342,9 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 		}
 
 		ancestorLen := len(f.state.ancestor)
-		f.state.ancestor = append(f.state.ancestor, field.GetName())
+		f.state.ancestor = append(f.state.ancestor, displayName(field))
 
+		f.writeComment(field)
 		if f.digManually {
 			choice, err := f.prompt.Select(
 				fmt.Sprintf(
@@ -184,8 +362,11 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 			}
 		}
 
-		msg := dynamic.NewMessage(field.GetMessageType())
-		err := f.inputMessage(msg)
+		msg, err := f.newMessage(field.GetMessageType())
+		if err != nil {
+			return err
+		}
+		err = f.inputMessage(msg)
 		// If io.EOF is returned, msg isn't nil (see inputMessage comments).
 		if err != nil && !errors.Is(err, prompt.ErrAbort) {
 			return err
@@ -213,10 +394,13 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 		f.state.color.Next()
 	default: // Normal fields.
 		f.prompt.SetPrefix(f.makePrefix(field))
-		v, err := f.inputPrimitiveField(field)
+		v, err := f.inputPrimitiveField(field, unsettable)
 		if err != nil {
 			return err
 		}
+		if v == nil {
+			return nil
+		}
 
 		if partOfRepeatedField {
 			if err := dmsg.TryAddRepeatedField(field, v); err != nil {
@@ -232,20 +416,27 @@ func (f *InteractiveFiller) inputField(dmsg *dynamic.Message, field *desc.FieldD
 	return nil
 }
 
+// selectOneOf lets the user select one of the fields of the oneof which field belongs to.
+// If the user selects noneOption, selectOneOf returns nil.
 func (f *InteractiveFiller) selectOneOf(field *desc.FieldDescriptor) (*desc.FieldDescriptor, error) {
 	oneof := field.GetOneOf()
 
-	options := make([]string, len(oneof.GetChoices()))
+	options := make([]string, 0, len(oneof.GetChoices())+1)
 	fieldOf := map[string]*desc.FieldDescriptor{}
-	for i, choice := range oneof.GetChoices() {
-		options[i] = choice.GetName()
+	for _, choice := range oneof.GetChoices() {
+		options = append(options, choice.GetName())
 		fieldOf[choice.GetName()] = choice
 	}
+	options = append(options, noneOption)
 
-	choice, err := f.prompt.Select(oneof.GetName(), options)
+	f.writeComment(oneof)
+	choice, err := f.prompt.Select(f.makeOneOfMessage(oneof), options)
 	if err != nil {
 		return nil, err
 	}
+	if choice == noneOption {
+		return nil, nil
+	}
 
 	desc, ok := fieldOf[choice]
 	if !ok {
@@ -255,26 +446,29 @@ func (f *InteractiveFiller) selectOneOf(field *desc.FieldDescriptor) (*desc.Fiel
 	return desc, nil
 }
 
-func (f *InteractiveFiller) selectEnum(enum *desc.FieldDescriptor) (*desc.EnumValueDescriptor, error) {
-	values := enum.GetEnumType().GetValues()
-	options := make([]string, 0, len(values))
-	valOf := map[string]*desc.EnumValueDescriptor{}
-	for _, v := range values {
-		options = append(options, v.GetName())
-		valOf[v.GetName()] = v
+// inputEnumField reads a value name or a number of the enum field with completion of value names.
+// If unsettable is true and the input is empty, inputEnumField returns nil, which means the field is left unset.
+// If CTRL+d is entered, inputEnumField returns io.EOF.
+func (f *InteractiveFiller) inputEnumField(field *desc.FieldDescriptor, unsettable bool) (interface{}, error) {
+	f.prompt.SetCompleter(&enumValueCompleter{enum: field.GetEnumType()})
+	in, err := f.input(field)
+	f.prompt.SetCompleter(nil)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
 	}
-
-	choice, err := f.prompt.Select(enum.GetName(), options)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to read user input")
 	}
-
-	c, ok := valOf[choice]
-	if !ok {
-		return nil, errors.Errorf("unknown enum '%s'", choice)
+	in = strings.TrimSpace(in)
+	if unsettable && in == "" {
+		return nil, nil
 	}
-
-	return c, nil
+	v, err := convertEnum(in, field)
+	if err != nil {
+		return nil, err
+	}
+	f.addInputHistory(field, in)
+	return v, nil
 }
 
 func (f *InteractiveFiller) inputRepeatedField(dmsg *dynamic.Message, field *desc.FieldDescriptor) error {
@@ -299,18 +493,242 @@ func (f *InteractiveFiller) inputRepeatedField(dmsg *dynamic.Message, field *des
 	}
 }
 
+// inputMapField inputs entries of a map field. It prompts a key and then the value repeatedly until CTRL+d is entered.
+// The value is inputted in the same way as a normal field, so message-typed values are also supported.
+// An entry which is partially inputted when CTRL+d is entered is discarded.
+func (f *InteractiveFiller) inputMapField(dmsg *dynamic.Message, field *desc.FieldDescriptor) error {
+	keyField, valueField := field.GetMapKeyType(), field.GetMapValueType()
+
+	old := f.state.hasAncestorAndHasRepeatedField
+	ancestorLen := len(f.state.ancestor)
+	f.state.hasAncestorAndHasRepeatedField = true
+	f.state.ancestor = append(f.state.ancestor, displayName(field))
+	defer func() {
+		f.state.hasAncestorAndHasRepeatedField = old
+		f.state.ancestor = f.state.ancestor[:ancestorLen]
+	}()
+
+	for {
+		if f.fillRandom {
+			return nil
+		}
+		f.prompt.SetPrefixColor(f.state.color)
+
+		f.prompt.SetPrefix(f.makePrefix(keyField))
+		k, err := f.inputPrimitiveField(keyField, false)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := dynamic.NewMessage(field.GetMessageType())
+		err = f.inputField(entry, valueField, false)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, prompt.ErrAbort) {
+			return err
+		}
+
+		if perr := dmsg.TryPutMapField(field, k, entry.GetField(valueField)); perr != nil {
+			return errors.Wrapf(perr, "failed to put an inputted entry to map field '%s'", field.GetName())
+		}
+		if errors.Is(err, prompt.ErrAbort) {
+			return prompt.ErrAbort
+		}
+
+		f.state.color.Next()
+	}
+}
+
 // inputPrimitiveField reads an input and converts it to a Go type.
+// If unsettable is true and the input is empty, inputPrimitiveField returns nil, which means the field is left unset.
 // If CTRL+d is entered, inputPrimitiveField returns io.EOF.
-func (f *InteractiveFiller) inputPrimitiveField(field *desc.FieldDescriptor) (interface{}, error) {
-	in, err := f.prompt.Input()
+func (f *InteractiveFiller) inputPrimitiveField(field *desc.FieldDescriptor, unsettable bool) (interface{}, error) {
+	if f.inputHistory != nil {
+		f.prompt.SetCompleter(&historyCompleter{values: f.inputHistory.Load(f.fieldPath(field))})
+		defer f.prompt.SetCompleter(nil)
+	}
+	in, err := f.input(field)
 	if errors.Is(err, io.EOF) {
 		return "", io.EOF
 	}
 	if err != nil {
 		return "", errors.Wrap(err, "failed to read user input")
 	}
+	if unsettable && in == "" {
+		return nil, nil
+	}
+
+	v, err := convertValue(f.expand(in), descriptor.FieldDescriptorProto_Type(descriptor.FieldDescriptorProto_Type_value[field.GetType().String()]))
+	if err != nil {
+		return nil, err
+	}
+	f.addInputHistory(field, in)
+	return v, nil
+}
+
+// expand expands in by the expander if it is set.
+func (f *InteractiveFiller) expand(in string) string {
+	if f.expander == nil {
+		return in
+	}
+	return f.expander(in)
+}
+
+// inputWellKnownTypeField reads an input and converts it to a message of the well-known type.
+// If CTRL+d is entered, inputWellKnownTypeField returns io.EOF.
+func (f *InteractiveFiller) inputWellKnownTypeField(field *desc.FieldDescriptor) (*dynamic.Message, error) {
+	if f.inputHistory != nil {
+		f.prompt.SetCompleter(&historyCompleter{values: f.inputHistory.Load(f.fieldPath(field))})
+		defer f.prompt.SetCompleter(nil)
+	}
+	in, err := f.input(field)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user input")
+	}
+
+	t, _ := lookupWellKnownType(field)
+	v, err := convertWellKnownType(f.expand(in), t, field.GetMessageType())
+	if err != nil || v == nil {
+		return v, err
+	}
+	f.addInputHistory(field, in)
+	return v, nil
+}
+
+// input reads an input for field. If the input history is available, the prompt history is replaced with
+// previously inputted values of the field.
+// If prompt.ErrFillRandom is returned with a non-empty input, the input is used for field and the rest of fields
+// are filled with random values.
+func (f *InteractiveFiller) input(field *desc.FieldDescriptor) (string, error) {
+	f.writeComment(field)
+	if f.inputHistory != nil {
+		if p, ok := f.prompt.(historySetter); ok {
+			p.SetCommandHistory(f.inputHistory.Load(f.fieldPath(field)))
+		}
+	}
+	in, err := f.prompt.Input()
+	if errors.Is(err, prompt.ErrFillRandom) {
+		f.fillRandom = true
+		if in != "" {
+			return in, nil
+		}
+	}
+	return in, err
+}
+
+// writeComment writes the leading comment of d to the comment writer as comment lines of proto files.
+// The comment is not written if it is the same descriptor as the last one.
+func (f *InteractiveFiller) writeComment(d desc.Descriptor) {
+	if f.commentWriter == nil || d == f.lastCommented {
+		return
+	}
+	f.lastCommented = d
+	c := idlproto.Comment(d)
+	if c == "" {
+		return
+	}
+	for _, l := range strings.Split(c, "\n") {
+		fmt.Fprintf(f.commentWriter, "// %s\n", l)
+	}
+}
+
+// inputRandomField fills field with random values instead of inputting it.
+// If partOfRepeatedField is true, inputRandomField returns io.EOF to finish inputting the repeated field.
+func (f *InteractiveFiller) inputRandomField(dmsg *dynamic.Message, field *desc.FieldDescriptor, partOfRepeatedField bool) error {
+	if partOfRepeatedField {
+		return io.EOF
+	}
+	if isOneOfField(field) {
+		if f.isSelectedOneOf(field) {
+			return nil
+		}
+		f.state.selectedOneOf[field.GetOneOf().GetFullyQualifiedName()] = nil
+		field = f.random.gen.OneOf(field.GetOneOf())
+	}
+	return f.setRandomValue(dmsg, field, false)
+}
+
+// setRandomValue sets random values to field of dmsg. If partOfRepeatedField is true, an element is added.
+func (f *InteractiveFiller) setRandomValue(dmsg *dynamic.Message, field *desc.FieldDescriptor, partOfRepeatedField bool) error {
+	depth := len(f.state.ancestor)
+	if !partOfRepeatedField {
+		f.random.fillField(dmsg, field, depth)
+		return nil
+	}
+	if v := f.random.value(field, depth); v != nil {
+		if err := dmsg.TryAddRepeatedField(field, v); err != nil {
+			return errors.Wrapf(err, "failed to add a random value to repeated field '%s'", field.GetName())
+		}
+	}
+	return nil
+}
+
+// addInputHistory adds in to the input history of field if the history is available. Empty inputs are ignored.
+func (f *InteractiveFiller) addInputHistory(field *desc.FieldDescriptor, in string) {
+	if f.inputHistory == nil || strings.TrimSpace(in) == "" {
+		return
+	}
+	f.inputHistory.Add(f.fieldPath(field), in)
+}
+
+// fieldPath returns the path of field from the message passed to Fill such that "api.CreateUserRequest.user.id".
+func (f *InteractiveFiller) fieldPath(field *desc.FieldDescriptor) string {
+	return strings.Join(append(append([]string{f.state.root}, f.state.ancestor...), displayName(field)), ".")
+}
+
+// inputAnyField reads a type URL, and then inputs fields of the message specified by the type URL.
+// The inputted message is packed into a google.protobuf.Any message.
+// If the type URL is empty, inputAnyField returns nil, which means the field is left unset.
+// If CTRL+d is entered, inputAnyField returns io.EOF.
+func (f *InteractiveFiller) inputAnyField(field *desc.FieldDescriptor) (*dynamic.Message, error) {
+	f.prompt.SetPrefix(f.makeAnyPrefix(field))
+	f.prompt.SetCompleter(&messageNameCompleter{names: f.resolver.MessageNames()})
+	in, err := f.input(field)
+	f.prompt.SetCompleter(nil)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user input")
+	}
+	in = strings.TrimSpace(in)
+	if in == "" {
+		return nil, nil
+	}
+
+	typeURL, fqmn := parseTypeURL(in)
+	md, err := f.resolver.ResolveMessage(fqmn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the message type of '%s'", in)
+	}
+	f.addInputHistory(field, in)
+
+	ancestorLen := len(f.state.ancestor)
+	f.state.ancestor = append(f.state.ancestor, displayName(field))
+	defer func() {
+		f.state.ancestor = f.state.ancestor[:ancestorLen]
+	}()
 
-	return convertValue(in, descriptor.FieldDescriptorProto_Type(descriptor.FieldDescriptorProto_Type_value[field.GetType().String()]))
+	msg, err := f.newMessage(md)
+	if err != nil {
+		return nil, err
+	}
+	err = f.inputMessage(msg)
+	if err != nil && !errors.Is(err, prompt.ErrAbort) {
+		return nil, err
+	}
+	a, aerr := newAny(field.GetMessageType(), typeURL, msg)
+	if aerr != nil {
+		return nil, aerr
+	}
+	return a, err
 }
 
 func (f *InteractiveFiller) isSelectedOneOf(field *desc.FieldDescriptor) bool {
@@ -369,11 +787,34 @@ func (f *InteractiveFiller) isCirculatedField(field *desc.FieldDescriptor) bool
 	return len(f.state.circulatedMessages[field.GetFullyQualifiedName()]) != 0
 }
 
+// newMessage instantiates a nested message of md by the message factory if it is available.
+func (f *InteractiveFiller) newMessage(md *desc.MessageDescriptor) (*dynamic.Message, error) {
+	if f.factory == nil {
+		return dynamic.NewMessage(md), nil
+	}
+	msg, err := f.factory.NewMessage(md)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to instantiate message '%s'", md.GetFullyQualifiedName())
+	}
+	return msg, nil
+}
+
 // makePrefix makes prefix for field f.
 func (f *InteractiveFiller) makePrefix(field *desc.FieldDescriptor) string {
 	return makePrefix(f.prefixFormat, field, f.state.ancestor, f.state.hasAncestorAndHasRepeatedField)
 }
 
+// makeOneOfMessage makes the message of the select prompt for oneof.
+func (f *InteractiveFiller) makeOneOfMessage(oneof *desc.OneOfDescriptor) string {
+	return fmt.Sprintf("oneof %s%s", makeAncestorPrefix(f.state.ancestor), oneof.GetName())
+}
+
+// makeAnyPrefix makes prefix for google.protobuf.Any field f.
+func (f *InteractiveFiller) makeAnyPrefix(field *desc.FieldDescriptor) string {
+	format := strings.Replace(f.prefixFormat, "{type}", anyTypeHint, -1)
+	return makePrefix(format, field, f.state.ancestor, f.state.hasAncestorAndHasRepeatedField)
+}
+
 var initialPromptInputterState = promptInputterState{
 	selectedOneOf:      make(map[string]interface{}),
 	circulatedMessages: make(map[string][]string),
@@ -392,6 +833,8 @@ type promptInputterState struct {
 	// A key is assigned at calling a RPC that requires the message.
 	circulatedMessages map[string][]string
 
+	// root is the fully-qualified name of the message passed to Fill.
+	root     string
 	ancestor []string
 	color    prompt.Color
 	// The field has parent fields and one or more fields is/are repeated.
@@ -416,25 +859,59 @@ func (s promptInputterState) clone() promptInputterState {
 
 const (
 	repeatedStr       = "<repeated> "
+	requiredStr       = "<required> "
+	deprecatedStr     = "<deprecated> "
 	ancestorDelimiter = "::"
+	// noneOption is the option of oneof selection that leaves the oneof unset.
+	noneOption = "(none)"
 )
 
-func makePrefix(s string, field *desc.FieldDescriptor, ancestor []string, ancestorHasRepeated bool) string {
+// displayName returns the name of field. Extensions are named by their fully-qualified names in brackets such that
+// "[api.nickname]", which is the same as keys of JSON.
+func displayName(field *desc.FieldDescriptor) string {
+	if field.IsExtension() {
+		return "[" + field.GetFullyQualifiedName() + "]"
+	}
+	return field.GetName()
+}
+
+func makeAncestorPrefix(ancestor []string) string {
 	joinedAncestor := strings.Join(ancestor, ancestorDelimiter)
 	if joinedAncestor != "" {
 		joinedAncestor += ancestorDelimiter
 	}
+	return joinedAncestor
+}
 
-	s = strings.Replace(s, "{ancestor}", joinedAncestor, -1)
-	s = strings.Replace(s, "{name}", field.GetName(), -1)
-	s = strings.Replace(s, "{type}", field.GetType().String(), -1)
+func makePrefix(s string, field *desc.FieldDescriptor, ancestor []string, ancestorHasRepeated bool) string {
+	s = strings.Replace(s, "{ancestor}", makeAncestorPrefix(ancestor), -1)
+	s = strings.Replace(s, "{name}", displayName(field), -1)
+	typ := field.GetType().String()
+	if t, ok := lookupWellKnownType(field); ok {
+		typ = t.hint
+	}
+	s = strings.Replace(s, "{type}", typ, -1)
 
+	// Required fields are marked to avoid requests rejected by servers due to missing fields.
+	if idlproto.IsRequired(field) {
+		s = requiredStr + s
+	}
+	if field.GetFieldOptions().GetDeprecated() {
+		s = deprecatedStr + s
+	}
 	if field.IsRepeated() || ancestorHasRepeated {
 		return repeatedStr + s
 	}
 	return s
 }
 
+func isWellKnownTypeField(field *desc.FieldDescriptor) bool {
+	_, ok := lookupWellKnownType(field)
+	return ok
+}
+
+// isOneOfField reports whether field belongs to a oneof. A synthetic oneof of a proto3 optional field is not treated as a oneof,
+// because it has only the field itself.
 func isOneOfField(field *desc.FieldDescriptor) bool {
-	return field.GetOneOf() != nil
+	return field.GetOneOf() != nil && !field.AsFieldDescriptorProto().GetProto3Optional()
 }
@@ -0,0 +1,305 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestInteractiveFiller_emptyInputLeavesFieldsUnset(t *testing.T) {
+	// protoparse doesn't support proto3 optional yet, so the descriptor is built by hand.
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Member"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:   proto.String("name"),
+						Number: proto.Int32(1),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:           proto.String("nickname"),
+						Number:         proto.Int32(2),
+						Label:          descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:           descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+						OneofIndex:     proto.Int32(0),
+						Proto3Optional: proto.Bool(true),
+					},
+					{
+						Name:   proto.String("grade"),
+						Number: proto.Int32(3),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{{Name: proto.String("_nickname")}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	// If the synthetic oneof is selected, Select consumes an input and fails.
+	f := NewInteractiveFiller(&stubPrompt{inputs: []string{"", "", "2"}}, "{name} => ")
+	msg := dynamic.NewMessage(fd.FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+	if msg.HasFieldName("name") {
+		t.Errorf("name must be unset, but got '%v'", msg.GetFieldByName("name"))
+	}
+	if msg.HasFieldName("nickname") {
+		t.Errorf("nickname must be unset, but got '%v'", msg.GetFieldByName("nickname"))
+	}
+	if grade := msg.GetFieldByName("grade"); grade != int32(2) {
+		t.Errorf("expected grade 2, but got '%v'", grade)
+	}
+}
+
+func TestInteractiveFiller_selectNoneOfOneOf(t *testing.T) {
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Identity"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:       proto.String("name"),
+						Number:     proto.Int32(1),
+						Label:      descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:       descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+						OneofIndex: proto.Int32(0),
+					},
+					{
+						Name:       proto.String("id"),
+						Number:     proto.Int32(2),
+						Label:      descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:       descriptor.FieldDescriptorProto_TYPE_INT64.Enum(),
+						OneofIndex: proto.Int32(0),
+					},
+					{
+						Name:   proto.String("note"),
+						Number: proto.Int32(3),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{{Name: proto.String("identity")}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	f := NewInteractiveFiller(&stubPrompt{inputs: []string{noneOption, "kumiko"}}, "{name} => ")
+	msg := dynamic.NewMessage(fd.FindMessage("api.Identity"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+	if msg.HasFieldName("name") || msg.HasFieldName("id") {
+		t.Errorf("the oneof must be unset, but got: %s", msg)
+	}
+	if note := msg.GetFieldByName("note"); note != "kumiko" {
+		t.Errorf("expected note 'kumiko', but got '%v'", note)
+	}
+}
+
+func TestInteractiveFiller_inputExpander(t *testing.T) {
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Member"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:   proto.String("name"),
+						Number: proto.Int32(1),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:   proto.String("grade"),
+						Number: proto.Int32(2),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	vars := map[string]string{"$name": "kumiko", "$grade": "2"}
+	expander := func(s string) string {
+		if v, ok := vars[s]; ok {
+			return v
+		}
+		return s
+	}
+	f := NewInteractiveFiller(&stubPrompt{inputs: []string{"$name", "$grade"}}, "{name} => ", WithInputExpander(expander))
+	msg := dynamic.NewMessage(fd.FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+	if name := msg.GetFieldByName("name"); name != "kumiko" {
+		t.Errorf("expected name 'kumiko', but got '%v'", name)
+	}
+	if grade := msg.GetFieldByName("grade"); grade != int32(2) {
+		t.Errorf("expected grade 2, but got '%v'", grade)
+	}
+}
+
+func TestInteractiveFiller_commentWriter(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto3";
+package api;
+message Member {
+  // The full name.
+  // It must not be empty.
+  string name = 1;
+  int32 grade = 2;
+  // Instruments the member plays.
+  repeated string parts = 3;
+}`,
+		}),
+		IncludeSourceCodeInfo: true,
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+
+	var buf bytes.Buffer
+	// After all inputs are consumed, io.EOF finishes the repeated field.
+	prompt := &stubPrompt{inputs: []string{"kumiko", "2", "euphonium", "contrabass"}}
+	f := NewInteractiveFiller(prompt, "{name} => ", WithCommentWriter(&buf))
+	msg := dynamic.NewMessage(fds[0].FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+
+	// The comment of the repeated field is written only once.
+	expected := "// The full name.\n// It must not be empty.\n// Instruments the member plays.\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected comments:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func Test_makePrefixMarksFields(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto2";
+package api;
+message Member {
+  required string name = 1;
+  optional int32 grade = 2;
+  repeated string parts = 3;
+  optional string nickname = 4 [deprecated = true];
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	md := fds[0].FindMessage("api.Member")
+
+	cases := map[string]string{
+		"name":     "<required> name => ",
+		"grade":    "grade => ",
+		"parts":    "<repeated> parts => ",
+		"nickname": "<deprecated> nickname => ",
+	}
+	for name, expected := range cases {
+		if actual := makePrefix("{name} => ", md.FindFieldByName(name), nil, false); actual != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, actual)
+		}
+	}
+}
+
+// dynamicMessageFactory instantiates messages by *dynamic.MessageFactory.
+type dynamicMessageFactory struct {
+	mf *dynamic.MessageFactory
+}
+
+func (f *dynamicMessageFactory) NewMessage(md *desc.MessageDescriptor) (*dynamic.Message, error) {
+	return f.mf.NewDynamicMessage(md), nil
+}
+
+func TestInteractiveFiller_extensions(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto2";
+package api;
+message Member {
+  optional string name = 1;
+  optional Part part = 2;
+  extensions 100 to 199;
+}
+message Part {
+  optional string name = 1;
+  extensions 100 to 199;
+}`,
+			"ext.proto": `
+syntax = "proto2";
+package api.ext;
+import "api.proto";
+extend api.Member {
+  optional int32 grade = 100;
+}
+extend api.Part {
+  optional string key = 100;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto", "ext.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	var er dynamic.ExtensionRegistry
+	er.AddExtensionsFromFile(fds[1])
+	mf := dynamic.NewMessageFactoryWithExtensionRegistry(&er)
+
+	// Extensions are inputted after normal fields.
+	prompt := &stubPrompt{inputs: []string{"kumiko", "euphonium", "B flat", "2"}}
+	f := NewInteractiveFiller(prompt, "{name} => ", WithMessageFactory(&dynamicMessageFactory{mf: mf}))
+	msg := mf.NewDynamicMessage(fds[0].FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+
+	b, err := msg.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON must not return an error, but got '%s'", err)
+	}
+	expected := `{"name":"kumiko","part":{"name":"euphonium","[api.ext.key]":"B flat"},"[api.ext.grade]":2}`
+	if actual := string(b); actual != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, actual)
+	}
+
+	if actual := makePrefix("{name} => ", fds[1].FindExtensionByName("api.ext.grade"), nil, false); actual != "[api.ext.grade] => " {
+		t.Errorf("extensions must be named by fully-qualified names in brackets, but got '%s'", actual)
+	}
+}
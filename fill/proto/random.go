@@ -0,0 +1,311 @@
+package proto
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/fill"
+)
+
+// maxRandomDepth is the maximum depth of nested messages filled with random values. It stops recursive message types.
+const maxRandomDepth = 3
+
+var (
+	randomFirstNames = []string{"Kumiko", "Reina", "Hazuki", "Sapphire", "Shuichi", "Asuka", "Kaori", "Natsuki", "Yuko", "Mizore", "Nozomi", "Kanade"}
+	randomLastNames  = []string{"Oumae", "Kousaka", "Katou", "Kawashima", "Tsukamoto", "Tanaka", "Nakaseko", "Yoshikawa", "Yoroizuka", "Kasaki", "Hisaishi"}
+	randomCities     = []string{"Tokyo", "Kyoto", "Uji", "Osaka", "Sapporo", "Fukuoka"}
+	randomCountries  = []string{"Japan", "United States", "Germany", "France", "Canada", "Australia"}
+	randomWords      = []string{"euphonium", "trumpet", "oboe", "flute", "contrabass", "saxophone", "trombone", "tuba", "clarinet", "horn"}
+)
+
+// RandomFiller is an implementation of fill.Filler. It fills all fields with plausible random values which are
+// generated from field types and field names such that email addresses for "email" fields.
+// RandomFiller fills only one message, so that it returns io.EOF from the second call.
+type RandomFiller struct {
+	walker *Walker
+	filled bool
+}
+
+// NewRandomFiller returns a new filler which fills fields with random values generated by r.
+// If r is nil, a random source seeded by the current time is used.
+func NewRandomFiller(r *rand.Rand) *RandomFiller {
+	return &RandomFiller{walker: NewWalker(newRandomGenerator(r), maxRandomDepth)}
+}
+
+// Fill receives v that is an instance of *dynamic.Message, and fills all fields of v with random values.
+func (f *RandomFiller) Fill(v interface{}) error {
+	msg, ok := v.(*dynamic.Message)
+	if !ok {
+		return fill.ErrCodecMismatch
+	}
+	if f.filled {
+		return io.EOF
+	}
+	f.filled = true
+	f.walker.Fill(msg)
+	return nil
+}
+
+// randomGenerator is an implementation of ValueGenerator. It generates random values based on field types and
+// field name heuristics.
+type randomGenerator struct {
+	r *rand.Rand
+}
+
+func newRandomGenerator(r *rand.Rand) *randomGenerator {
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &randomGenerator{r: r}
+}
+
+// OneOf returns one of the fields of o at random, so that a oneof is always filled.
+func (g *randomGenerator) OneOf(o *desc.OneOfDescriptor) *desc.FieldDescriptor {
+	choices := o.GetChoices()
+	return choices[g.r.Intn(len(choices))]
+}
+
+// Len returns one to three for repeated fields, and one or two for map fields.
+func (g *randomGenerator) Len(field *desc.FieldDescriptor) int {
+	switch {
+	case field.IsMap():
+		return 1 + g.r.Intn(2)
+	case field.IsRepeated():
+		return 1 + g.r.Intn(3)
+	default:
+		return 1
+	}
+}
+
+// Value returns a random value of the type of field.
+func (g *randomGenerator) Value(field *desc.FieldDescriptor) interface{} {
+	return g.namedValue(field, newFieldName(field.GetName()))
+}
+
+// namedValue returns a random value of the type of field for the field named name.
+func (g *randomGenerator) namedValue(field *desc.FieldDescriptor, name fieldName) interface{} {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return g.string(name)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		b := make([]byte, 16)
+		g.r.Read(b) //nolint:errcheck
+		return b
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return g.r.Intn(2) == 0
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int32(g.int(name, 1<<31-1))
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return g.int(name, 1<<63-1)
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint32(g.int(name, 1<<32-1))
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return uint64(g.int(name, 1<<63-1))
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float32(g.float(name))
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return g.float(name)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		// Prefer non-zero values because the zero value is usually UNSPECIFIED.
+		vals := field.GetEnumType().GetValues()
+		var nonZero []int32
+		for _, v := range vals {
+			if v.GetNumber() != 0 {
+				nonZero = append(nonZero, v.GetNumber())
+			}
+		}
+		if len(nonZero) == 0 {
+			return vals[0].GetNumber()
+		}
+		return nonZero[g.r.Intn(len(nonZero))]
+	default:
+		return nil
+	}
+}
+
+// Message returns a random value of well-known types, or an empty message filled by the walker.
+func (g *randomGenerator) Message(field *desc.FieldDescriptor) (*dynamic.Message, bool) {
+	md := field.GetMessageType()
+	m := dynamic.NewMessage(md)
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		// A time within 30 days from now.
+		t := time.Now().Add(time.Duration(g.r.Int63n(int64(60*24*time.Hour))) - 30*24*time.Hour).Truncate(time.Second)
+		ts, err := ptypes.TimestampProto(t)
+		if err != nil || m.ConvertFrom(ts) != nil {
+			return nil, false
+		}
+		return m, false
+	case "google.protobuf.Duration":
+		if m.ConvertFrom(ptypes.DurationProto(time.Duration(1+g.r.Intn(3600))*time.Second)) != nil {
+			return nil, false
+		}
+		return m, false
+	case "google.protobuf.Empty":
+		return m, false
+	case "google.protobuf.Any", "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue", "google.protobuf.FieldMask":
+		// These types have no fields which can be filled meaningfully.
+		return nil, false
+	}
+	if strings.HasPrefix(md.GetFullyQualifiedName(), "google.protobuf.") && strings.HasSuffix(md.GetName(), "Value") {
+		// The value of a wrapper type is generated from the name of the wrapper field.
+		value := md.FindFieldByName("value")
+		if err := m.TrySetField(value, g.namedValue(value, newFieldName(field.GetName()))); err != nil {
+			return nil, false
+		}
+		return m, false
+	}
+	return m, true
+}
+
+// string returns a random string for the field named name.
+func (g *randomGenerator) string(name fieldName) string {
+	first := randomFirstNames[g.r.Intn(len(randomFirstNames))]
+	last := randomLastNames[g.r.Intn(len(randomLastNames))]
+	switch {
+	case name.has("email") || name.is("mail"):
+		return fmt.Sprintf("%s.%s@example.com", strings.ToLower(first), strings.ToLower(last))
+	case name.has("uuid") || name.isID():
+		return g.uuid()
+	case name.has("url") || name.is("uri") || name.has("website") || name.has("link"):
+		return fmt.Sprintf("https://example.com/%s", randomWords[g.r.Intn(len(randomWords))])
+	case name.has("phone") || name.is("tel"):
+		return fmt.Sprintf("+1-555-01%02d", g.r.Intn(100))
+	case name.has("username") || name.has("login") || name.has("nickname"):
+		return fmt.Sprintf("%s%d", strings.ToLower(first), g.r.Intn(1000))
+	case name.has("firstname") || name.has("givenname"):
+		return first
+	case name.has("lastname") || name.has("familyname") || name.has("surname"):
+		return last
+	case name.has("name") || name.has("author"):
+		return first + " " + last
+	case name.has("city"):
+		return randomCities[g.r.Intn(len(randomCities))]
+	case name.has("country"):
+		return randomCountries[g.r.Intn(len(randomCountries))]
+	case name.has("address") || name.has("street"):
+		return fmt.Sprintf("%d %s Street", 1+g.r.Intn(999), last)
+	case name.has("time") || name.has("date") || name.isTime():
+		return time.Now().Add(time.Duration(g.r.Int63n(int64(60*24*time.Hour))) - 30*24*time.Hour).Truncate(time.Second).Format(time.RFC3339)
+	case name.has("description") || name.has("comment") || name.has("message") ||
+		name.has("text") || name.has("content") || name.has("body"):
+		words := make([]string, 3+g.r.Intn(5))
+		for i := range words {
+			words[i] = randomWords[g.r.Intn(len(randomWords))]
+		}
+		return strings.ToUpper(words[0][:1]) + strings.Join(words, " ")[1:] + "."
+	default:
+		return randomWords[g.r.Intn(len(randomWords))]
+	}
+}
+
+// int returns a random non-negative integer for the field named name. The result is less than or equal to max.
+func (g *randomGenerator) int(name fieldName, max int64) int64 {
+	var min, n int64
+	switch {
+	case name.is("age"):
+		min, n = 18, 63
+	case name.has("year"):
+		min, n = 1990, 41
+	case name.has("time") || name.isTime():
+		// Unix time within 30 days from now.
+		min, n = time.Now().Add(-30*24*time.Hour).Unix(), int64(60*24*time.Hour/time.Second)
+	case name.has("count") || name.has("size") || name.has("limit") ||
+		name.is("num") || name.has("number") || name.has("quantity"):
+		min, n = 1, 100
+	default:
+		min, n = 1, 1000
+	}
+	v := min + g.r.Int63n(n)
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// float returns a random float for the field named name.
+func (g *randomGenerator) float(name fieldName) float64 {
+	v := float64(g.r.Intn(10000)) / 100
+	if name.has("lat") {
+		return v*1.8 - 90
+	}
+	if name.has("lng") || name.has("lon") {
+		return v*3.6 - 180
+	}
+	return v
+}
+
+// uuid returns a random UUID version 4.
+func (g *randomGenerator) uuid() string {
+	b := make([]byte, 16)
+	g.r.Read(b) //nolint:errcheck
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// fieldName is a field name for heuristics. It is split into lower-case words such that "created_at" and "createdAt"
+// are both ["created", "at"].
+type fieldName []string
+
+func newFieldName(s string) fieldName {
+	var (
+		words     []string
+		word      []rune
+		prevUpper bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '_':
+			if len(word) != 0 {
+				words, word = append(words, string(word)), nil
+			}
+		case unicode.IsUpper(r):
+			// Consecutive upper-case letters such that "ID" are a word.
+			if len(word) != 0 && !prevUpper {
+				words, word = append(words, string(word)), nil
+			}
+			word = append(word, unicode.ToLower(r))
+		default:
+			word = append(word, r)
+		}
+		prevUpper = unicode.IsUpper(r)
+	}
+	if len(word) != 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// has reports whether the name contains s. Word boundaries are ignored.
+func (n fieldName) has(s string) bool {
+	return strings.Contains(strings.Join(n, ""), s)
+}
+
+// is reports whether the name has the word s.
+func (n fieldName) is(s string) bool {
+	for _, w := range n {
+		if w == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isID reports whether the name is an identifier such that "id" or "user_id".
+func (n fieldName) isID() bool {
+	return len(n) != 0 && (n[len(n)-1] == "id" || n[len(n)-1] == "ids")
+}
+
+// isTime reports whether the name is a point in time such that "created_at".
+func (n fieldName) isTime() bool {
+	return len(n) != 0 && n[len(n)-1] == "at"
+}
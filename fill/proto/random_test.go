@@ -0,0 +1,166 @@
+package proto
+
+import (
+	"io"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/prompt"
+)
+
+func newRandomTestDescriptor(t *testing.T) *desc.FileDescriptor {
+	t.Helper()
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptor.EnumDescriptorProto{
+			{
+				Name: proto.String("Part"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("PART_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("EUPHONIUM"), Number: proto.Int32(1)},
+				},
+			},
+		},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Member"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("user_id"), Number: proto.Int32(1), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("email"), Number: proto.Int32(2), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("age"), Number: proto.Int32(3), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_INT32.Enum()},
+					{Name: proto.String("part"), Number: proto.Int32(4), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_ENUM.Enum(), TypeName: proto.String(".api.Part")},
+					{
+						Name:   proto.String("tags"),
+						Number: proto.Int32(5),
+						Label:  descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{Name: proto.String("mentor"), Number: proto.Int32(6), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".api.Member")},
+					{Name: proto.String("nickname"), Number: proto.Int32(7), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_STRING.Enum(), OneofIndex: proto.Int32(0)},
+					{Name: proto.String("grade"), Number: proto.Int32(8), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_INT32.Enum(), OneofIndex: proto.Int32(0)},
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{{Name: proto.String("profile")}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+	return fd
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRandomFiller(t *testing.T) {
+	fd := newRandomTestDescriptor(t)
+	f := NewRandomFiller(rand.New(rand.NewSource(1)))
+	msg := dynamic.NewMessage(fd.FindMessage("api.Member"))
+	if err := f.Fill(msg); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+
+	if id := msg.GetFieldByName("user_id").(string); !uuidRegexp.MatchString(id) {
+		t.Errorf("user_id must be a UUID, but got '%s'", id)
+	}
+	if email := msg.GetFieldByName("email").(string); !regexp.MustCompile(`^[a-z]+\.[a-z]+@example\.com$`).MatchString(email) {
+		t.Errorf("email must be an email address, but got '%s'", email)
+	}
+	if age := msg.GetFieldByName("age").(int32); age < 18 || age > 80 {
+		t.Errorf("age must be between 18 and 80, but got %d", age)
+	}
+	if part := msg.GetFieldByName("part").(int32); part != 1 {
+		t.Errorf("part must be a non-zero value, but got %d", part)
+	}
+	if n := len(msg.GetFieldByName("tags").([]interface{})); n < 1 || n > 3 {
+		t.Errorf("tags must have one to three elements, but got %d", n)
+	}
+	if set, _ := msg.GetOneOfField(fd.FindMessage("api.Member").GetOneOfs()[0]); set == nil {
+		t.Error("one of the oneof fields must be set")
+	}
+
+	// Recursive messages are filled until the max depth.
+	depth := 0
+	for m := msg; m.HasFieldName("mentor"); depth++ {
+		m = m.GetFieldByName("mentor").(*dynamic.Message)
+	}
+	if depth != maxRandomDepth {
+		t.Errorf("expected depth %d, but got %d", maxRandomDepth, depth)
+	}
+
+	if err := f.Fill(dynamic.NewMessage(fd.FindMessage("api.Member"))); err != io.EOF {
+		t.Errorf("the second Fill must return io.EOF, but got '%v'", err)
+	}
+}
+
+// fillRandomStubPrompt returns prompt.ErrFillRandom instead of the input "<random>".
+type fillRandomStubPrompt struct {
+	*stubPrompt
+}
+
+func (p *fillRandomStubPrompt) Input() (string, error) {
+	in, err := p.stubPrompt.Input()
+	if in == "<random>" {
+		return "", prompt.ErrFillRandom
+	}
+	return in, err
+}
+
+func TestInteractiveFiller_fillRandom(t *testing.T) {
+	fd := newRandomTestDescriptor(t)
+	// user_id and email are inputted, and the rest of fields are filled with random values.
+	// The second element of tags is random, and then tags is finished.
+	f := NewInteractiveFiller(&fillRandomStubPrompt{&stubPrompt{inputs: []string{"1234", "kumiko@example.com", "", "", "band", "<random>"}}}, "{name} => ")
+	msg := dynamic.NewMessage(fd.FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+	if id := msg.GetFieldByName("user_id"); id != "1234" {
+		t.Errorf("expected user_id '1234', but got '%v'", id)
+	}
+	if msg.HasFieldName("age") {
+		t.Errorf("age must be unset because it is skipped, but got '%v'", msg.GetFieldByName("age"))
+	}
+	tags := msg.GetFieldByName("tags").([]interface{})
+	if len(tags) != 2 || tags[0] != "band" {
+		t.Errorf("expected 'band' and a random tag, but got %v", tags)
+	}
+	if !msg.HasFieldName("mentor") {
+		t.Error("mentor must be filled with random values")
+	}
+	if set, _ := msg.GetOneOfField(fd.FindMessage("api.Member").GetOneOfs()[0]); set == nil {
+		t.Error("one of the oneof fields must be set")
+	}
+
+	// The state is reset by the next Fill.
+	f.prompt = &stubPrompt{inputs: []string{"5678"}}
+	msg = dynamic.NewMessage(fd.FindMessage("api.Member"))
+	if err := f.Fill(msg, false); err != io.EOF {
+		t.Fatalf("Fill must return io.EOF, but got '%v'", err)
+	}
+	if diff := cmp.Diff("5678", msg.GetFieldByName("user_id")); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func Test_newFieldName(t *testing.T) {
+	cases := map[string][]string{
+		"created_at": {"created", "at"},
+		"createdAt":  {"created", "at"},
+		"userID":     {"user", "id"},
+		"email":      {"email"},
+	}
+	for in, expected := range cases {
+		if diff := cmp.Diff(fieldName(expected), newFieldName(in)); diff != "" {
+			t.Errorf("%s: -want, +got\n%s", in, diff)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package proto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/fill"
+	"github.com/pkg/errors"
+)
+
+// SetField sets a value converted from in to the field of v specified by path.
+// path is dot-separated field names such that "user.name". Missing messages on the path are created.
+// in is converted in the same way as the interactive filler. Repeated fields, map fields and message fields
+// except for well-known types are set from in as JSON such that `["foo", "bar"]`.
+// If v is not a Protocol Buffers message, SetField returns fill.ErrCodecMismatch.
+func SetField(v interface{}, path, in string) error {
+	dmsg, ok := v.(*dynamic.Message)
+	if !ok {
+		return fill.ErrCodecMismatch
+	}
+
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		field, err := findField(dmsg, name)
+		if err != nil {
+			return err
+		}
+		if field.GetMessageType() == nil || field.IsRepeated() {
+			return errors.Errorf("field '%s' is not a message field", name)
+		}
+		if !dmsg.HasField(field) {
+			if err := dmsg.TrySetField(field, dynamic.NewMessage(field.GetMessageType())); err != nil {
+				return errors.Wrapf(err, "failed to set a new message to field '%s'", name)
+			}
+		}
+		m, ok := dmsg.GetField(field).(proto.Message)
+		if !ok {
+			return errors.Errorf("field '%s' is not a message field", name)
+		}
+		child, err := dynamic.AsDynamicMessage(m)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the message of field '%s'", name)
+		}
+		// AsDynamicMessage may return a copy, so set it back after modifying.
+		defer dmsg.SetField(field, child)
+		dmsg = child
+	}
+
+	field, err := findField(dmsg, names[len(names)-1])
+	if err != nil {
+		return err
+	}
+	return setFieldValue(dmsg, field, in)
+}
+
+func findField(dmsg *dynamic.Message, name string) (*desc.FieldDescriptor, error) {
+	md := dmsg.GetMessageDescriptor()
+	if field := md.FindFieldByName(name); field != nil {
+		return field, nil
+	}
+	if field := md.FindFieldByJSONName(name); field != nil {
+		return field, nil
+	}
+	return nil, errors.Errorf("unknown field '%s' of message '%s'", name, md.GetFullyQualifiedName())
+}
+
+func setFieldValue(dmsg *dynamic.Message, field *desc.FieldDescriptor, in string) error {
+	var (
+		v   interface{}
+		err error
+	)
+	switch {
+	case field.IsRepeated() || (field.GetMessageType() != nil && !isWellKnownTypeField(field)):
+		dmsg.ClearField(field)
+		if err := dmsg.UnmarshalMergeJSON([]byte(fmt.Sprintf(`{%q: %s}`, field.GetName(), in))); err != nil {
+			return errors.Wrapf(err, "failed to set '%s' to field '%s' as JSON", in, field.GetName())
+		}
+		return nil
+	case isWellKnownTypeField(field):
+		t, _ := lookupWellKnownType(field)
+		v, err = convertWellKnownType(in, t, field.GetMessageType())
+		if v == (*dynamic.Message)(nil) {
+			dmsg.ClearField(field)
+			return err
+		}
+	case field.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM:
+		v, err = convertEnum(in, field)
+	default:
+		v, err = convertValue(in, field.GetType())
+	}
+	if err != nil {
+		return err
+	}
+	if err := dmsg.TrySetField(field, v); err != nil {
+		return errors.Wrapf(err, "failed to set '%s' to field '%s'", in, field.GetName())
+	}
+	return nil
+}
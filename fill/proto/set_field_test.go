@@ -0,0 +1,79 @@
+package proto_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	fillproto "github.com/ktr0731/evans/fill/proto"
+)
+
+func TestSetField(t *testing.T) {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:    proto.String("api.proto"),
+		Package: proto.String("api"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Profile"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("age"), JsonName: proto.String("age"), Number: proto.Int32(1), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_INT32.Enum()},
+				},
+			},
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("user_profile"), JsonName: proto.String("userProfile"), Number: proto.Int32(2), Label: optional, Type: descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".api.Profile")},
+					{Name: proto.String("tags"), JsonName: proto.String("tags"), Number: proto.Int32(3), Label: descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptor.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	cases := map[string]struct {
+		path, in string
+		expected string
+		hasErr   bool
+	}{
+		"scalar":              {path: "name", in: "kumiko", expected: `{"name":"kumiko"}`},
+		"nested field":        {path: "user_profile.age", in: "16", expected: `{"userProfile":{"age":16}}`},
+		"JSON name":           {path: "userProfile.age", in: "16", expected: `{"userProfile":{"age":16}}`},
+		"repeated field":      {path: "tags", in: `["a", "b"]`, expected: `{"tags":["a","b"]}`},
+		"message field":       {path: "user_profile", in: `{"age": 17}`, expected: `{"userProfile":{"age":17}}`},
+		"unknown field":       {path: "kumiko", in: "oumae", hasErr: true},
+		"invalid value":       {path: "user_profile.age", in: "kumiko", hasErr: true},
+		"not a message field": {path: "name.age", in: "16", hasErr: true},
+		"invalid JSON":        {path: "tags", in: `["a"`, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			msg := dynamic.NewMessage(fd.FindMessage("api.Request"))
+			err := fillproto.SetField(msg, c.path, c.in)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("SetField must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetField must not return an error, but got '%s'", err)
+			}
+			b, err := msg.MarshalJSON()
+			if err != nil {
+				t.Fatalf("MarshalJSON must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, string(b)); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package proto
+
+import (
+	"io"
+
+	"github.com/ktr0731/evans/prompt"
+)
+
+// stubPrompt returns inputs in order for both of Input and Select. After all inputs are consumed, it returns io.EOF.
+// Methods which are not overridden panic.
+type stubPrompt struct {
+	prompt.Prompt
+	inputs []string
+}
+
+func (p *stubPrompt) Input() (string, error) {
+	if len(p.inputs) == 0 {
+		return "", io.EOF
+	}
+	in := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	return in, nil
+}
+
+func (p *stubPrompt) Select(string, []string) (string, error) {
+	return p.Input()
+}
+
+func (p *stubPrompt) SetPrefix(string)              {}
+func (p *stubPrompt) SetPrefixColor(prompt.Color)   {}
+func (p *stubPrompt) SetCompleter(prompt.Completer) {}
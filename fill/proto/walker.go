@@ -0,0 +1,102 @@
+package proto
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// ValueGenerator generates values of fields for a Walker.
+type ValueGenerator interface {
+	// OneOf returns the field of o which is filled, or nil if o is left unset.
+	OneOf(o *desc.OneOfDescriptor) *desc.FieldDescriptor
+	// Len returns the number of values set to field. It is the number of elements of repeated fields and maps,
+	// and other fields are left unset if it is 0.
+	Len(field *desc.FieldDescriptor) int
+	// Value returns a value of the type of field which is not a message field.
+	// It returns nil if the value should be left unset.
+	Value(field *desc.FieldDescriptor) interface{}
+	// Message returns a message of the type of field. If walk is true, the Walker fills fields of the message
+	// recursively. It returns nil if the field should be left unset.
+	Message(field *desc.FieldDescriptor) (m *dynamic.Message, walk bool)
+}
+
+// Walker fills fields of messages with values generated by a ValueGenerator. Oneofs, repeated fields, maps and
+// nested messages are handled by the Walker, so that a ValueGenerator only generates values of each field.
+type Walker struct {
+	gen      ValueGenerator
+	maxDepth int
+}
+
+// NewWalker returns a new walker which fills fields with values generated by gen. Nested messages deeper than
+// maxDepth are left unset, which stops recursive message types.
+func NewWalker(gen ValueGenerator, maxDepth int) *Walker {
+	return &Walker{gen: gen, maxDepth: maxDepth}
+}
+
+// Fill fills fields of m which are not set yet.
+func (w *Walker) Fill(m *dynamic.Message) {
+	w.fillMessage(m, 0)
+}
+
+// fillMessage fills fields of m which are not set yet. Oneofs which already have a field are also skipped.
+func (w *Walker) fillMessage(m *dynamic.Message, depth int) {
+	walkedOneOfs := make(map[string]bool)
+	for _, field := range m.GetMessageDescriptor().GetFields() {
+		if isOneOfField(field) {
+			oneof := field.GetOneOf()
+			if walkedOneOfs[oneof.GetName()] {
+				continue
+			}
+			walkedOneOfs[oneof.GetName()] = true
+			if set, _ := m.GetOneOfField(oneof); set != nil {
+				continue
+			}
+			if field = w.gen.OneOf(oneof); field == nil {
+				continue
+			}
+		} else if m.HasField(field) {
+			continue
+		}
+		w.fillField(m, field, depth)
+	}
+}
+
+// fillField sets generated values to field of m.
+func (w *Walker) fillField(m *dynamic.Message, field *desc.FieldDescriptor, depth int) {
+	for i := w.gen.Len(field); i > 0; i-- {
+		switch {
+		case field.IsMap():
+			k, v := w.value(field.GetMapKeyType(), depth), w.value(field.GetMapValueType(), depth)
+			if k != nil && v != nil {
+				m.PutMapField(field, k, v)
+			}
+		case field.IsRepeated():
+			if v := w.value(field, depth); v != nil {
+				m.AddRepeatedField(field, v)
+			}
+		default:
+			if v := w.value(field, depth); v != nil {
+				m.SetField(field, v)
+			}
+		}
+	}
+}
+
+// value returns a generated value of the type of field. It returns nil if the value should be left unset.
+func (w *Walker) value(field *desc.FieldDescriptor, depth int) interface{} {
+	if t := field.GetType(); t != descriptor.FieldDescriptorProto_TYPE_MESSAGE && t != descriptor.FieldDescriptorProto_TYPE_GROUP {
+		return w.gen.Value(field)
+	}
+	m, walk := w.gen.Message(field)
+	if m == nil {
+		return nil
+	}
+	if walk {
+		if depth >= w.maxDepth {
+			return nil
+		}
+		w.fillMessage(m, depth+1)
+	}
+	return m
+}
@@ -0,0 +1,66 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// stubGenerator fills the first field of each oneof, two elements of repeated fields, and zero values.
+type stubGenerator struct{}
+
+func (stubGenerator) OneOf(o *desc.OneOfDescriptor) *desc.FieldDescriptor { return o.GetChoices()[0] }
+
+func (stubGenerator) Len(field *desc.FieldDescriptor) int {
+	if field.IsRepeated() {
+		return 2
+	}
+	return 1
+}
+
+func (stubGenerator) Value(field *desc.FieldDescriptor) interface{} {
+	if field.GetType() == descriptor.FieldDescriptorProto_TYPE_STRING {
+		return "kumiko"
+	}
+	return field.GetDefaultValue()
+}
+
+func (stubGenerator) Message(field *desc.FieldDescriptor) (*dynamic.Message, bool) {
+	return dynamic.NewMessage(field.GetMessageType()), true
+}
+
+func TestWalker(t *testing.T) {
+	fd := newRandomTestDescriptor(t)
+	md := fd.FindMessage("api.Member")
+	msg := dynamic.NewMessage(md)
+	msg.SetFieldByName("user_id", "1234")
+	msg.SetFieldByName("grade", int32(2))
+
+	NewWalker(stubGenerator{}, 2).Fill(msg)
+
+	if id := msg.GetFieldByName("user_id"); id != "1234" {
+		t.Errorf("user_id must be kept, but got '%v'", id)
+	}
+	if email := msg.GetFieldByName("email"); email != "kumiko" {
+		t.Errorf("expected email 'kumiko', but got '%v'", email)
+	}
+	if n := msg.FieldLength(md.FindFieldByName("tags")); n != 2 {
+		t.Errorf("tags must have 2 elements, but got %d", n)
+	}
+	if msg.HasFieldName("nickname") || msg.GetFieldByName("grade") != int32(2) {
+		t.Errorf("the oneof which is already set must be kept, but got nickname '%v' and grade '%v'", msg.GetFieldByName("nickname"), msg.GetFieldByName("grade"))
+	}
+
+	depth := 0
+	for m := msg; m.HasFieldName("mentor"); depth++ {
+		m = m.GetFieldByName("mentor").(*dynamic.Message)
+		if !m.HasFieldName("nickname") {
+			t.Errorf("the first field of the oneof must be filled at depth %d", depth+1)
+		}
+	}
+	if depth != 2 {
+		t.Errorf("expected depth 2, but got %d", depth)
+	}
+}
@@ -0,0 +1,85 @@
+package proto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/ptypes"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+// wellKnownType is a well-known type which can be inputted by a natural representation
+// instead of inputting each field of the message.
+type wellKnownType struct {
+	// hint is displayed as the type of the field in the prompt.
+	hint string
+	// convert converts an input to a message of the well-known type.
+	// in is never empty.
+	convert func(in string) (proto.Message, error)
+}
+
+var wellKnownTypes = map[string]*wellKnownType{
+	"google.protobuf.Timestamp": {
+		hint: "Timestamp: RFC3339",
+		convert: func(in string) (proto.Message, error) {
+			t, err := time.Parse(time.RFC3339Nano, in)
+			if err != nil {
+				return nil, err
+			}
+			return ptypes.TimestampProto(t)
+		},
+	},
+	"google.protobuf.Duration": {
+		hint: "Duration: e.g. 1.5s",
+		convert: func(in string) (proto.Message, error) {
+			d, err := time.ParseDuration(in)
+			if err != nil {
+				return nil, err
+			}
+			return ptypes.DurationProto(d), nil
+		},
+	},
+	"google.protobuf.FieldMask": {
+		hint: "FieldMask: comma-separated paths",
+		convert: func(in string) (proto.Message, error) {
+			var paths []string
+			for _, p := range strings.Split(in, ",") {
+				if p := strings.TrimSpace(p); p != "" {
+					paths = append(paths, p)
+				}
+			}
+			return &field_mask.FieldMask{Paths: paths}, nil
+		},
+	},
+}
+
+// lookupWellKnownType returns the well-known type of the message field.
+// If the field is not a message field or the message is not a well-known type, it returns false.
+func lookupWellKnownType(field *desc.FieldDescriptor) (*wellKnownType, bool) {
+	if field.GetMessageType() == nil {
+		return nil, false
+	}
+	t, ok := wellKnownTypes[field.GetMessageType().GetFullyQualifiedName()]
+	return t, ok
+}
+
+// convertWellKnownType converts an input in to a message of the well-known type t.
+// If in is empty, convertWellKnownType returns nil, which means the field is left unset.
+func convertWellKnownType(in string, t *wellKnownType, md *desc.MessageDescriptor) (*dynamic.Message, error) {
+	if in == "" {
+		return nil, nil
+	}
+	m, err := t.convert(in)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert an inputted value '%s' to type %s", in, md.GetFullyQualifiedName())
+	}
+	dmsg := dynamic.NewMessage(md)
+	if err := dmsg.ConvertFrom(m); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert to a message of type %s", md.GetFullyQualifiedName())
+	}
+	return dmsg, nil
+}
@@ -0,0 +1,93 @@
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+func Test_convertWellKnownType(t *testing.T) {
+	ts, err := ptypes.TimestampProto(time.Date(2020, 5, 1, 12, 30, 0, 500, time.UTC))
+	if err != nil {
+		t.Fatalf("TimestampProto must not return an error, but got '%s'", err)
+	}
+
+	cases := map[string]struct {
+		in  string
+		msg proto.Message
+
+		expected proto.Message
+		hasErr   bool
+	}{
+		"timestamp": {
+			in:       "2020-05-01T12:30:00.0000005Z",
+			msg:      &timestamp.Timestamp{},
+			expected: ts,
+		},
+		"invalid timestamp": {
+			in:     "2020/05/01",
+			msg:    &timestamp.Timestamp{},
+			hasErr: true,
+		},
+		"duration": {
+			in:       "1.5s",
+			msg:      &duration.Duration{},
+			expected: &duration.Duration{Seconds: 1, Nanos: 500000000},
+		},
+		"invalid duration": {
+			in:     "1.5",
+			msg:    &duration.Duration{},
+			hasErr: true,
+		},
+		"field mask": {
+			in:       "name, profile.address,,",
+			msg:      &field_mask.FieldMask{},
+			expected: &field_mask.FieldMask{Paths: []string{"name", "profile.address"}},
+		},
+		"empty input": {
+			msg: &duration.Duration{},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			md, err := desc.LoadMessageDescriptorForMessage(c.msg)
+			if err != nil {
+				t.Fatalf("LoadMessageDescriptorForMessage must not return an error, but got '%s'", err)
+			}
+			wkt, ok := wellKnownTypes[md.GetFullyQualifiedName()]
+			if !ok {
+				t.Fatalf("'%s' must be a well-known type", md.GetFullyQualifiedName())
+			}
+
+			actual, err := convertWellKnownType(c.in, wkt, md)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("convertWellKnownType must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertWellKnownType must not return an error, but got '%s'", err)
+			}
+			if c.expected == nil {
+				if actual != nil {
+					t.Errorf("convertWellKnownType must return nil for an empty input, but got '%s'", actual)
+				}
+				return
+			}
+			if err := actual.ConvertTo(c.msg); err != nil {
+				t.Fatalf("ConvertTo must not return an error, but got '%s'", err)
+			}
+			if !proto.Equal(c.expected, c.msg) {
+				t.Errorf("expected '%s', but got '%s'", c.expected, c.msg)
+			}
+		})
+	}
+}
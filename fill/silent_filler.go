@@ -1,27 +1,50 @@
 package fill
 
 import (
+	"bufio"
 	"encoding/json"
 	"io"
+	"unicode"
 
 	"github.com/pkg/errors"
 )
 
 // SilentFilter is a Filler implementation that doesn't behave interactive actions.
 type SilentFiller struct {
+	in  *bufio.Reader
 	dec *json.Decoder
+
+	// inArray is true if the input is a JSON array. In that case, each element is treated as a request.
+	inArray bool
+	started bool
 }
 
 // NewSilentFiller receives input as io.Reader and returns an instance of SilentFiller.
+// The input is a sequence of JSON values such that newline-delimited JSON, or a JSON array.
+// Keys of messages may be either JSON names or original field names in the proto file.
 func NewSilentFiller(in io.Reader) *SilentFiller {
+	r := bufio.NewReader(in)
 	return &SilentFiller{
-		dec: json.NewDecoder(in),
+		in:  r,
+		dec: json.NewDecoder(r),
 	}
 }
 
 // Fill fills values of each field from a JSON string. If the JSON string is invalid JSON format or v is a nil pointer,
 // Fill returns ErrCodecMismatch.
 func (f *SilentFiller) Fill(v interface{}) error {
+	if !f.started {
+		f.started = true
+		if err := f.readArrayStart(); err != nil {
+			return err
+		}
+	}
+	if f.inArray {
+		if !f.dec.More() {
+			return io.EOF
+		}
+	}
+
 	err := f.dec.Decode(v)
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -37,3 +60,30 @@ func (f *SilentFiller) Fill(v interface{}) error {
 	}
 	return nil
 }
+
+// readArrayStart consumes the opening bracket if the input is a JSON array.
+func (f *SilentFiller) readArrayStart() error {
+	for {
+		r, _, err := f.in.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read input")
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if err := f.in.UnreadRune(); err != nil {
+			return errors.Wrap(err, "failed to read input")
+		}
+		if r != '[' {
+			return nil
+		}
+		if _, err := f.dec.Token(); err != nil {
+			return ErrCodecMismatch
+		}
+		f.inArray = true
+		return nil
+	}
+}
@@ -1,9 +1,14 @@
 package fill_test
 
 import (
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
 	"github.com/ktr0731/evans/fill"
 )
 
@@ -31,3 +36,60 @@ func TestSilentFiller(t *testing.T) {
 		})
 	}
 }
+
+func TestSilentFiller_Stream(t *testing.T) {
+	cases := map[string]struct {
+		in string
+	}{
+		"NDJSON":     {in: "{\"foo\": \"bar\"}\n{\"foo\": \"baz\"}\n"},
+		"JSON array": {in: `  [{"foo": "bar"}, {"foo": "baz"}]`},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewSilentFiller(strings.NewReader(c.in))
+			var actual []string
+			for {
+				var v struct{ Foo string }
+				err := f.Fill(&v)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Fill must not return an error, but got '%s'", err)
+				}
+				actual = append(actual, v.Foo)
+			}
+			if len(actual) != 2 || actual[0] != "bar" || actual[1] != "baz" {
+				t.Errorf("expected [bar baz], but got %v", actual)
+			}
+		})
+	}
+}
+
+func TestSilentFiller_protoNames(t *testing.T) {
+	md, err := desc.LoadMessageDescriptorForMessage(&descriptor.FileDescriptorProto{})
+	if err != nil {
+		t.Fatalf("LoadMessageDescriptorForMessage must not return an error, but got '%s'", err)
+	}
+	cases := map[string]string{
+		"JSON names":     `{"messageType": [{"name": "Foo", "nestedType": [{"name": "Bar"}]}]}`,
+		"original names": `{"message_type": [{"name": "Foo", "nested_type": [{"name": "Bar"}]}]}`,
+	}
+	for name, in := range cases {
+		in := in
+		t.Run(name, func(t *testing.T) {
+			msg := dynamic.NewMessage(md)
+			if err := fill.NewSilentFiller(strings.NewReader(in)).Fill(msg); err != nil {
+				t.Fatalf("Fill must not return an error, but got '%s'", err)
+			}
+			var actual descriptor.FileDescriptorProto
+			if err := msg.ConvertTo(&actual); err != nil {
+				t.Fatalf("ConvertTo must not return an error, but got '%s'", err)
+			}
+			if n := actual.GetMessageType()[0].GetNestedType()[0].GetName(); n != "Bar" {
+				t.Errorf("expected 'Bar', but got '%s'", n)
+			}
+		})
+	}
+}
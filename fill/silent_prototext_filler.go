@@ -0,0 +1,75 @@
+package fill
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/pkg/errors"
+)
+
+// PrototextSeparator is a line which separates messages in the protobuf text format input.
+const PrototextSeparator = "---"
+
+// SilentPrototextFiller is a Filler implementation that reads messages formatted as the protobuf text format
+// without interactive actions.
+type SilentPrototextFiller struct {
+	in *bufio.Reader
+}
+
+// NewSilentPrototextFiller receives input as io.Reader and returns an instance of SilentPrototextFiller.
+// The input is a sequence of messages separated by lines which consist of PrototextSeparator.
+func NewSilentPrototextFiller(in io.Reader) *SilentPrototextFiller {
+	return &SilentPrototextFiller{in: bufio.NewReader(in)}
+}
+
+// Fill fills values of each field from a message formatted as the protobuf text format.
+// If the input is invalid format or v is not a Protocol Buffers message, Fill returns ErrCodecMismatch.
+// Fields written as unknown field numbers are skipped because their wire types cannot be determined.
+func (f *SilentPrototextFiller) Fill(v interface{}) error {
+	text, err := f.next()
+	if err != nil {
+		return err
+	}
+	switch m := v.(type) {
+	case interface{ UnmarshalText([]byte) error }: // *dynamic.Message.
+		err = m.UnmarshalText([]byte(text))
+	case proto.Message:
+		err = proto.UnmarshalText(text, m)
+	default:
+		return ErrCodecMismatch
+	}
+	if err != nil {
+		return ErrCodecMismatch
+	}
+	return nil
+}
+
+// next reads lines until a separator or the end of input. Empty messages between separators are skipped.
+func (f *SilentPrototextFiller) next() (string, error) {
+	for {
+		var b strings.Builder
+		var eof bool
+		for {
+			line, err := f.in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return "", errors.Wrap(err, "failed to read input")
+			}
+			eof = errors.Is(err, io.EOF)
+			if strings.TrimSpace(line) == PrototextSeparator {
+				break
+			}
+			b.WriteString(line)
+			if eof {
+				break
+			}
+		}
+		if strings.TrimSpace(b.String()) != "" {
+			return b.String(), nil
+		}
+		if eof {
+			return "", io.EOF
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package fill_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestSilentPrototextFiller(t *testing.T) {
+	cases := map[string]struct {
+		in     string
+		v      interface{}
+		hasErr bool
+	}{
+		"normal":           {in: `reason: "kumiko" domain: "kitauji"`, v: &errdetails.ErrorInfo{}},
+		"invalid format":   {in: `reason: `, v: &errdetails.ErrorInfo{}, hasErr: true},
+		"unknown field":    {in: `name: "kumiko"`, v: &errdetails.ErrorInfo{}, hasErr: true},
+		"not a proto type": {in: `reason: "kumiko"`, v: &struct{}{}, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewSilentPrototextFiller(strings.NewReader(c.in))
+			err := f.Fill(c.v)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Fill must return an error, but got nil")
+				}
+			} else if err != nil {
+				t.Errorf("Fill must not return an error, but got an error: '%s'", err)
+			}
+		})
+	}
+}
+
+func TestSilentPrototextFiller_Stream(t *testing.T) {
+	in := "reason: \"bar\"\n---\n---\n# comment\nreason: \"baz\"\n"
+	f := fill.NewSilentPrototextFiller(strings.NewReader(in))
+	var actual []string
+	for {
+		var v errdetails.ErrorInfo
+		err := f.Fill(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Fill must not return an error, but got '%s'", err)
+		}
+		actual = append(actual, v.Reason)
+	}
+	if len(actual) != 2 || actual[0] != "bar" || actual[1] != "baz" {
+		t.Errorf("expected [bar baz], but got %v", actual)
+	}
+}
+
+func TestSilentPrototextFiller_unknownFields(t *testing.T) {
+	md, err := desc.LoadMessageDescriptorForMessage(&errdetails.ErrorInfo{})
+	if err != nil {
+		t.Fatalf("LoadMessageDescriptorForMessage must not return an error, but got '%s'", err)
+	}
+	msg := dynamic.NewMessage(md)
+	f := fill.NewSilentPrototextFiller(strings.NewReader(`reason: "kumiko" 100: 2`))
+	if err := f.Fill(msg); err != nil {
+		t.Fatalf("Fill must not return an error, but got '%s'", err)
+	}
+	if reason := msg.GetFieldByName("reason"); reason != "kumiko" {
+		t.Errorf("expected reason 'kumiko', but got '%v'", reason)
+	}
+}
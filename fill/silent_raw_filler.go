@@ -0,0 +1,81 @@
+package fill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/pkg/errors"
+)
+
+// RawFiller provides serialized Protocol Buffers messages as they are instead of filling fields.
+type RawFiller interface {
+	// FillRaw returns the next serialized message. If delimited is true, each message is prefixed by its length
+	// encoded as a varint. Otherwise, the whole input is treated as a message.
+	// FillRaw returns io.EOF at the end of input.
+	FillRaw(delimited bool) ([]byte, error)
+}
+
+// SilentRawFiller is a Filler and RawFiller implementation that reads serialized messages.
+type SilentRawFiller struct {
+	in   *bufio.Reader
+	done bool
+}
+
+// NewSilentRawFiller receives input as io.Reader and returns an instance of SilentRawFiller.
+func NewSilentRawFiller(in io.Reader) *SilentRawFiller {
+	return &SilentRawFiller{in: bufio.NewReader(in)}
+}
+
+// Fill decodes the whole input into v. If the input is malformed or v is not a Protocol Buffers message,
+// Fill returns ErrCodecMismatch.
+func (f *SilentRawFiller) Fill(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrCodecMismatch
+	}
+	b, err := f.FillRaw(false)
+	if err != nil {
+		return err
+	}
+	if u, ok := m.(proto.Unmarshaler); ok { // *dynamic.Message.
+		err = u.Unmarshal(b)
+	} else {
+		err = proto.Unmarshal(b, m)
+	}
+	if err != nil {
+		return ErrCodecMismatch
+	}
+	return nil
+}
+
+// FillRaw implements RawFiller.
+func (f *SilentRawFiller) FillRaw(delimited bool) ([]byte, error) {
+	if f.done {
+		return nil, io.EOF
+	}
+	if !delimited {
+		f.done = true
+		b, err := ioutil.ReadAll(f.in)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read input")
+		}
+		return b, nil
+	}
+
+	n, err := binary.ReadUvarint(f.in)
+	if errors.Is(err, io.EOF) {
+		f.done = true
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the length of the message")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(f.in, b); err != nil {
+		return nil, errors.Wrap(err, "failed to read the message")
+	}
+	return b, nil
+}
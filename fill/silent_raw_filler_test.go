@@ -0,0 +1,68 @@
+package fill_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ktr0731/evans/fill"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestSilentRawFiller(t *testing.T) {
+	t.Run("Fill", func(t *testing.T) {
+		f := fill.NewSilentRawFiller(bytes.NewReader([]byte("\x0a\x06kumiko")))
+		var v errdetails.ErrorInfo
+		if err := f.Fill(&v); err != nil {
+			t.Fatalf("Fill must not return an error, but got '%s'", err)
+		}
+		if v.Reason != "kumiko" {
+			t.Errorf("expected reason 'kumiko', but got '%s'", v.Reason)
+		}
+		if err := f.Fill(&v); !errors.Is(err, io.EOF) {
+			t.Errorf("Fill must return io.EOF at the end of input, but got '%v'", err)
+		}
+	})
+
+	t.Run("Fill with malformed input", func(t *testing.T) {
+		f := fill.NewSilentRawFiller(bytes.NewReader([]byte("\x0a\x06kumi")))
+		var v errdetails.ErrorInfo
+		if err := f.Fill(&v); !errors.Is(err, fill.ErrCodecMismatch) {
+			t.Errorf("Fill must return ErrCodecMismatch, but got '%v'", err)
+		}
+	})
+
+	t.Run("FillRaw", func(t *testing.T) {
+		in := []byte("\x0a\x06kumiko")
+		f := fill.NewSilentRawFiller(bytes.NewReader(in))
+		b, err := f.FillRaw(false)
+		if err != nil {
+			t.Fatalf("FillRaw must not return an error, but got '%s'", err)
+		}
+		if !bytes.Equal(in, b) {
+			t.Errorf("expected %q, but got %q", in, b)
+		}
+		if _, err := f.FillRaw(false); !errors.Is(err, io.EOF) {
+			t.Errorf("FillRaw must return io.EOF at the end of input, but got '%v'", err)
+		}
+	})
+
+	t.Run("FillRaw with delimited input", func(t *testing.T) {
+		f := fill.NewSilentRawFiller(bytes.NewReader([]byte("\x03foo\x00\x05barbz")))
+		var actual []string
+		for {
+			b, err := f.FillRaw(true)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("FillRaw must not return an error, but got '%s'", err)
+			}
+			actual = append(actual, string(b))
+		}
+		if len(actual) != 3 || actual[0] != "foo" || actual[1] != "" || actual[2] != "barbz" {
+			t.Errorf("expected [foo  barbz], but got %q", actual)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package fill
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SilentYAMLFiller is a Filler implementation that reads YAML documents without interactive actions.
+type SilentYAMLFiller struct {
+	dec *yaml.Decoder
+
+	// pending is the rest of elements of a YAML sequence. In that case, each element is treated as a request.
+	pending []interface{}
+	inArray bool
+}
+
+// NewSilentYAMLFiller receives input as io.Reader and returns an instance of SilentYAMLFiller.
+// The input is a sequence of YAML documents separated by "---", or a YAML sequence.
+func NewSilentYAMLFiller(in io.Reader) *SilentYAMLFiller {
+	return &SilentYAMLFiller{dec: yaml.NewDecoder(in)}
+}
+
+// Fill fills values of each field from a YAML document. If the YAML document is invalid format or v is a nil pointer,
+// Fill returns ErrCodecMismatch.
+func (f *SilentYAMLFiller) Fill(v interface{}) error {
+	doc, err := f.next()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return ErrCodecMismatch
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		switch err.(type) {
+		case *json.InvalidUnmarshalError, *json.SyntaxError:
+			return ErrCodecMismatch
+		default:
+			return errors.Wrap(err, "failed to read input as YAML")
+		}
+	}
+	return nil
+}
+
+// next returns the next request as a JSON-encodable value.
+func (f *SilentYAMLFiller) next() (interface{}, error) {
+	if f.inArray {
+		if len(f.pending) == 0 {
+			return nil, io.EOF
+		}
+		v := f.pending[0]
+		f.pending = f.pending[1:]
+		return toJSONValue(v)
+	}
+
+	var v interface{}
+	if err := f.dec.Decode(&v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, ErrCodecMismatch
+	}
+	if s, ok := v.([]interface{}); ok {
+		f.inArray = true
+		f.pending = s
+		return f.next()
+	}
+	return toJSONValue(v)
+}
+
+// toJSONValue converts maps decoded by yaml.v2 into maps which have string keys.
+func toJSONValue(v interface{}) (interface{}, error) {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			jv, err := toJSONValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = jv
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(vv))
+		for i, e := range vv {
+			jv, err := toJSONValue(e)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = jv
+		}
+		return s, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,70 @@
+package fill_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/fill"
+)
+
+func TestSilentYAMLFiller(t *testing.T) {
+	cases := map[string]struct {
+		in     string
+		hasErr bool
+	}{
+		"normal":       {in: "foo: bar\nbaz:\n  qux: 1\n"},
+		"JSON":         {in: `{"foo": "bar"}`},
+		"invalid YAML": {in: `foo: [`, hasErr: true},
+		"not a map":    {in: `foo`, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewSilentYAMLFiller(strings.NewReader(c.in))
+			var v struct {
+				Foo string
+				Baz struct{ Qux int }
+			}
+			err := f.Fill(&v)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Fill must return an error, but got nil")
+				}
+			} else if err != nil {
+				t.Errorf("Fill must not return an error, but got an error: '%s'", err)
+			}
+		})
+	}
+}
+
+func TestSilentYAMLFiller_Stream(t *testing.T) {
+	cases := map[string]struct {
+		in string
+	}{
+		"multiple documents": {in: "foo: bar\n---\nfoo: baz\n"},
+		"sequence":           {in: "- foo: bar\n- foo: baz\n"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f := fill.NewSilentYAMLFiller(strings.NewReader(c.in))
+			var actual []string
+			for {
+				var v struct{ Foo string }
+				err := f.Fill(&v)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Fill must not return an error, but got '%s'", err)
+				}
+				actual = append(actual, v.Foo)
+			}
+			if len(actual) != 2 || actual[0] != "bar" || actual[1] != "baz" {
+				t.Errorf("expected [bar baz], but got %v", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package csv provides a CSV formatter implementation.
+package csv
+
+import (
+	gocsv "encoding/csv"
+	gojson "encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/format/filter"
+	"github.com/ktr0731/evans/format/table"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseFormatter is a formatter that formats messages into CSV records with a header line.
+// Rows are the same as the table formatter. By default, columns are top-level scalar fields of rows.
+// Header, trailer and status are not formatted.
+type responseFormatter struct {
+	w        io.Writer
+	opts     format.JSONOptions
+	selected bool
+	columns  []string
+	included map[string]bool
+	rows     []map[string]string
+}
+
+// NewResponseFormatter returns a CSV formatter. If columns is not empty, only the fields are written in this order,
+// and non-scalar values are written as compact JSON. Records are written after all messages are formatted.
+func NewResponseFormatter(w io.Writer, columns []string, opts format.JSONOptions) format.ResponseFormatterInterface {
+	p := &responseFormatter{w: w, opts: opts, selected: len(columns) != 0, included: map[string]bool{}}
+	for _, c := range columns {
+		p.addColumn(c)
+	}
+	return p
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	dv, err := filter.Decode(b)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the marshaled message")
+	}
+	for _, r := range table.Rows(dv) {
+		p.appendRow(r)
+	}
+	return nil
+}
+
+func (p *responseFormatter) appendRow(v interface{}) {
+	row := map[string]string{}
+	obj, ok := v.(*filter.Object)
+	if !ok {
+		p.setField(row, "value", v)
+		p.rows = append(p.rows, row)
+		return
+	}
+	for _, k := range obj.Keys() {
+		fv, _ := obj.Get(k)
+		p.setField(row, k, fv)
+	}
+	p.rows = append(p.rows, row)
+}
+
+func (p *responseFormatter) setField(row map[string]string, k string, v interface{}) {
+	s, scalar := field(v)
+	if !p.selected && scalar {
+		p.addColumn(k)
+	}
+	if !p.included[k] {
+		return
+	}
+	row[k] = s
+}
+
+func (p *responseFormatter) addColumn(c string) {
+	if p.included[c] {
+		return
+	}
+	p.included[c] = true
+	p.columns = append(p.columns, c)
+}
+
+// field converts v into a string. scalar is true if v is a string, number or boolean.
+func field(v interface{}) (s string, scalar bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case gojson.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		b, err := gojson.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(b), false
+	}
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {}
+
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	if len(p.rows) == 0 || len(p.columns) == 0 {
+		return nil
+	}
+	w := gocsv.NewWriter(p.w)
+	if err := w.Write(p.columns); err != nil {
+		return errors.Wrap(err, "failed to write the header line")
+	}
+	for _, r := range p.rows {
+		rec := make([]string, len(p.columns))
+		for i, c := range p.columns {
+			rec[i] = r[c]
+		}
+		if err := w.Write(rec); err != nil {
+			return errors.Wrap(err, "failed to write a record")
+		}
+	}
+	w.Flush()
+	return errors.Wrap(w.Error(), "failed to write records")
+}
@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/format"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	list := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{Name: proto.String("foo.proto"), Package: proto.String("foo")},
+			{Name: proto.String("bar,baz.proto"), Dependency: []string{"foo.proto"}},
+		},
+	}
+	cases := map[string]struct {
+		columns  []string
+		msgs     []proto.Message
+		expected string
+	}{
+		"list": {
+			msgs:     []proto.Message{list},
+			expected: "name,package\nfoo.proto,foo\n\"bar,baz.proto\",\n",
+		},
+		"stream": {
+			msgs: []proto.Message{
+				&descriptor.FieldDescriptorProto{Name: proto.String("id"), Number: proto.Int32(1)},
+				&descriptor.FieldDescriptorProto{Name: proto.String("name"), Number: proto.Int32(2), Options: &descriptor.FieldOptions{Deprecated: proto.Bool(true)}},
+			},
+			expected: "name,number\nid,1\nname,2\n",
+		},
+		"columns": {
+			columns:  []string{"dependency", "name"},
+			msgs:     []proto.Message{list},
+			expected: "dependency,name\n,foo.proto\n\"[\"\"foo.proto\"\"]\",\"bar,baz.proto\"\n",
+		},
+		"empty": {
+			msgs: []proto.Message{&descriptor.FileDescriptorSet{}},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewResponseFormatter(&buf, c.columns, format.DefaultJSONOptions)
+			for _, m := range c.msgs {
+				if err := f.FormatMessage(m); err != nil {
+					t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+				}
+			}
+			if err := f.Done(); err != nil {
+				t.Fatalf("Done must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, buf.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,40 +2,35 @@
 package curl
 
 import (
-	"bytes"
 	gojson "encoding/json"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
 
-	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
-	"github.com/golang/protobuf/proto"  //nolint:staticcheck
-	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
 	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/present"
 	"github.com/ktr0731/evans/present/json"
 	"github.com/pkg/errors"
-	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 type responseFormatter struct {
 	w io.Writer
 
-	json        present.Presenter
-	pbMarshaler *jsonpb.Marshaler
+	json present.Presenter
+	opts format.JSONOptions
 
 	wroteHeader, wroteMessage, wroteTrailer bool
 }
 
-func NewResponseFormatter(w io.Writer) format.ResponseFormatterInterface {
+func NewResponseFormatter(w io.Writer, opts format.JSONOptions) format.ResponseFormatterInterface {
 	return &responseFormatter{
-		w:           w,
-		json:        json.NewPresenter("  "),
-		pbMarshaler: &jsonpb.Marshaler{},
+		w:    w,
+		json: json.NewPresenter(opts.IndentString()),
+		opts: opts,
 	}
 }
 
@@ -58,7 +53,15 @@ func (p *responseFormatter) FormatMessage(v interface{}) error {
 	if p.wroteHeader {
 		fmt.Fprintf(p.w, "\n")
 	}
-	msg, err := p.json.Format(v)
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	msg, err := p.json.Format(b)
 	if err != nil {
 		return err
 	}
@@ -93,25 +96,15 @@ func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
 
 var replacer = strings.NewReplacer("\n", "", ",", ", ")
 
-func (p *responseFormatter) FormatStatus(status *status.Status) error {
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
 	if p.wroteHeader || p.wroteMessage || p.wroteTrailer {
 		fmt.Fprintf(p.w, "\n")
 	}
-	fmt.Fprintf(p.w, "code: %s\nnumber: %d\nmessage: %q\n", status.Code().String(), status.Code(), status.Message())
-	if len(status.Details()) > 0 {
-		details := make([]string, 0, len(status.Details()))
-		for _, d := range status.Details() {
-			d, ok := d.(proto.Message)
-			if !ok {
-				continue
-			}
-			// Convert to Any to insert @type field.
-			m, err := p.convertProtoMessageAsAnyToMap(d)
-			if err != nil {
-				return err
-			}
-
-			b, err := gojson.MarshalIndent(m, "", "")
+	fmt.Fprintf(p.w, "code: %s\nnumber: %d\nmessage: %q\n", status.Code.String(), status.Code, status.Message)
+	if len(status.Details) > 0 {
+		details := make([]string, 0, len(status.Details))
+		for _, d := range status.Details {
+			b, err := gojson.MarshalIndent(d, "", "")
 			if err != nil {
 				return err
 			}
@@ -119,7 +112,7 @@ func (p *responseFormatter) FormatStatus(status *status.Status) error {
 		}
 		fmt.Fprintf(p.w, "details: \n%s\n", strings.Join(details, "\n"))
 	}
-	if status.Code() != codes.OK {
+	if status.Code != codes.OK {
 		fmt.Fprintf(p.w, "\n")
 	}
 	return nil
@@ -128,24 +121,3 @@ func (p *responseFormatter) FormatStatus(status *status.Status) error {
 func (p *responseFormatter) Done() error {
 	return nil
 }
-
-func (p *responseFormatter) convertProtoMessageToMap(m proto.Message) (map[string]interface{}, error) {
-	var buf bytes.Buffer
-	err := p.pbMarshaler.Marshal(&buf, m)
-	if err != nil {
-		return nil, err
-	}
-	var res map[string]interface{}
-	if err := gojson.Unmarshal(buf.Bytes(), &res); err != nil {
-		return nil, err
-	}
-	return res, nil
-}
-
-func (p *responseFormatter) convertProtoMessageAsAnyToMap(m proto.Message) (map[string]interface{}, error) {
-	any, err := ptypes.MarshalAny(m)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to convert a message to *any.Any")
-	}
-	return p.convertProtoMessageToMap(any)
-}
@@ -0,0 +1,418 @@
+// Package filter provides a formatter implementation that shows values extracted from each message by a jq-style
+// filter expression.
+package filter
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/present"
+	"github.com/ktr0731/evans/present/json"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// Filter is a compiled filter expression. It supports a subset of jq such that ".", ".foo", ".foo.bar",
+// ".[\"foo\"]", ".[0]", ".[-1]", ".[]" and pipes such that ".items[] | .name".
+type Filter struct {
+	expr  string
+	steps []step
+}
+
+// step is a single operation of a filter. Each step receives a value and returns zero or more values.
+type step func(v interface{}) ([]interface{}, error)
+
+// Compile parses expr as a filter expression.
+func Compile(expr string) (*Filter, error) {
+	p := &parser{s: expr}
+	steps, err := p.parse()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid filter '%s'", expr)
+	}
+	return &Filter{expr: expr, steps: steps}, nil
+}
+
+// Apply applies the filter to v which is a value decoded by Decode, and returns the results.
+func (f *Filter) Apply(v interface{}) ([]interface{}, error) {
+	vs := []interface{}{v}
+	for _, s := range f.steps {
+		var next []interface{}
+		for _, v := range vs {
+			res, err := s(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to apply filter '%s'", f.expr)
+			}
+			next = append(next, res...)
+		}
+		vs = next
+	}
+	return vs, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) parse() ([]step, error) {
+	var steps []step
+	for {
+		p.skipSpaces()
+		if !p.consume('.') {
+			return nil, errors.Errorf("'.' is expected at %d", p.pos)
+		}
+		if p.peek() == '.' {
+			return nil, errors.New("recursive descent is not supported")
+		}
+		// A single "." is the identity.
+		if name, ok := p.ident(); ok {
+			steps = append(steps, key(name))
+		} else if p.peek() == '"' {
+			name, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, key(name))
+		}
+		for {
+			if p.consume('.') {
+				if name, ok := p.ident(); ok {
+					steps = append(steps, key(name))
+					continue
+				}
+				if p.peek() == '"' {
+					name, err := p.quoted()
+					if err != nil {
+						return nil, err
+					}
+					steps = append(steps, key(name))
+					continue
+				}
+				if p.peek() != '[' {
+					return nil, errors.Errorf("a field name is expected at %d", p.pos)
+				}
+			}
+			if !p.consume('[') {
+				break
+			}
+			s, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		}
+		p.skipSpaces()
+		if p.pos == len(p.s) {
+			return steps, nil
+		}
+		if !p.consume('|') {
+			return nil, errors.Errorf("unexpected character '%c' at %d", p.s[p.pos], p.pos)
+		}
+	}
+}
+
+// bracket parses the inside of [] after '['.
+func (p *parser) bracket() (step, error) {
+	p.skipSpaces()
+	var s step
+	switch c := p.peek(); {
+	case c == ']':
+		s = iterate
+	case c == '"':
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		s = key(name)
+	default:
+		start := p.pos
+		if c == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.s) && '0' <= p.s[p.pos] && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(p.s[start:p.pos])
+		if err != nil {
+			return nil, errors.Errorf("an index, a quoted key or nothing is expected at %d", start)
+		}
+		s = index(n)
+	}
+	p.skipSpaces()
+	if !p.consume(']') {
+		return nil, errors.Errorf("']' is expected at %d", p.pos)
+	}
+	return s, nil
+}
+
+func (p *parser) ident() (string, bool) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || p.pos > start && '0' <= c && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.s[start:p.pos], p.pos > start
+}
+
+func (p *parser) quoted() (string, error) {
+	dec := gojson.NewDecoder(strings.NewReader(p.s[p.pos:]))
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		return "", errors.Errorf("invalid quoted string at %d", p.pos)
+	}
+	p.pos += int(dec.InputOffset())
+	return s, nil
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func key(name string) step {
+	return func(v interface{}) ([]interface{}, error) {
+		switch vv := v.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case *Object:
+			return []interface{}{vv.values[name]}, nil
+		default:
+			return nil, errors.Errorf("cannot index %s with %q", typeName(v), name)
+		}
+	}
+}
+
+func index(n int) step {
+	return func(v interface{}) ([]interface{}, error) {
+		switch vv := v.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case []interface{}:
+			i := n
+			if i < 0 {
+				i += len(vv)
+			}
+			if i < 0 || i >= len(vv) {
+				return []interface{}{nil}, nil
+			}
+			return []interface{}{vv[i]}, nil
+		default:
+			return nil, errors.Errorf("cannot index %s with number", typeName(v))
+		}
+	}
+}
+
+func iterate(v interface{}) ([]interface{}, error) {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv, nil
+	case *Object:
+		res := make([]interface{}, 0, len(vv.keys))
+		for _, k := range vv.keys {
+			res = append(res, vv.values[k])
+		}
+		return res, nil
+	default:
+		return nil, errors.Errorf("cannot iterate over %s", typeName(v))
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case *Object:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return "number"
+	}
+}
+
+// Object is a JSON object which keeps the order of keys.
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Keys returns keys of o in the original order.
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+// Get returns the value of key.
+func (o *Object) Get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// MarshalJSON encodes o as a JSON object in the original order of keys.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, err := gojson.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := gojson.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Decode decodes b into a value which Filter.Apply accepts. Objects are decoded as *Object and numbers are
+// decoded as json.Number to keep them as they are.
+func Decode(b []byte) (interface{}, error) {
+	dec := gojson.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *gojson.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case gojson.Delim('{'):
+		o := &Object{values: map[string]interface{}{}}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k, ok := kt.(string)
+			if !ok {
+				return nil, fmt.Errorf("a key should be a string, but got %T", kt)
+			}
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := o.values[k]; !ok {
+				o.keys = append(o.keys, k)
+			}
+			o.values[k] = v
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case gojson.Delim('['):
+		a := []interface{}{}
+		for dec.More() {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return a, nil
+	default:
+		return t, nil
+	}
+}
+
+// responseFormatter is a formatter that writes each result of the filter applied to each message as JSON.
+// Header, trailer and status are not formatted.
+type responseFormatter struct {
+	w      io.Writer
+	filter *Filter
+	p      present.Presenter
+	opts   format.JSONOptions
+}
+
+// NewResponseFormatter compiles expr and returns a formatter that applies it to each message.
+// Each result is written as JSON like jq.
+func NewResponseFormatter(w io.Writer, expr string, opts format.JSONOptions) (format.ResponseFormatterInterface, error) {
+	f, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &responseFormatter{w: w, filter: f, p: json.NewPresenter(opts.IndentString()), opts: opts}, nil
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	dv, err := Decode(b)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the marshaled message")
+	}
+	res, err := p.filter.Apply(dv)
+	if err != nil {
+		return err
+	}
+	for _, r := range res {
+		s, err := p.p.Format(r)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(p.w, s+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {}
+
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/format"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestFilter(t *testing.T) {
+	in := `{"name": "oumae", "items": [{"id": 1, "name": "euphonium"}, {"id": 2, "name": "trumpet"}], "nested": {"a b": {"c": true}}, "empty": null}`
+	cases := map[string]struct {
+		expr      string
+		expected  []string
+		hasErr    bool
+		compError bool
+	}{
+		"identity":             {expr: ".", expected: []string{in}},
+		"field":                {expr: ".name", expected: []string{`"oumae"`}},
+		"iterate":              {expr: ".items[].name", expected: []string{`"euphonium"`, `"trumpet"`}},
+		"iterate with pipe":    {expr: ".items[] | .id", expected: []string{`1`, `2`}},
+		"index":                {expr: ".items[1].id", expected: []string{`2`}},
+		"negative index":       {expr: ".items[-1].name", expected: []string{`"trumpet"`}},
+		"out of range":         {expr: ".items[5]", expected: []string{`null`}},
+		"quoted key":           {expr: `.nested["a b"].c`, expected: []string{`true`}},
+		"quoted field":         {expr: `.nested."a b"`, expected: []string{`{"c": true}`}},
+		"bracket without dot":  {expr: `.["name"]`, expected: []string{`"oumae"`}},
+		"missing field":        {expr: ".foo.bar", expected: []string{`null`}},
+		"null":                 {expr: ".empty[0]", expected: []string{`null`}},
+		"iterate over object":  {expr: ".items[0][]", expected: []string{`1`, `"euphonium"`}},
+		"index a string":       {expr: ".name.foo", hasErr: true},
+		"iterate over string":  {expr: ".name[]", hasErr: true},
+		"empty":                {expr: "", compError: true},
+		"no dot":               {expr: "name", compError: true},
+		"recursive descent":    {expr: "..", compError: true},
+		"unclosed bracket":     {expr: ".items[0", compError: true},
+		"trailing characters":  {expr: ".name foo", compError: true},
+		"invalid bracket body": {expr: ".items[foo]", compError: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f, err := Compile(c.expr)
+			if c.compError {
+				if err == nil {
+					t.Fatal("Compile should return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile should not return an error, but got '%s'", err)
+			}
+			v, err := Decode([]byte(in))
+			if err != nil {
+				t.Fatalf("Decode should not return an error, but got '%s'", err)
+			}
+			res, err := f.Apply(v)
+			if c.hasErr {
+				if err == nil {
+					t.Fatal("Apply should return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply should not return an error, but got '%s'", err)
+			}
+			actual := make([]string, 0, len(res))
+			for _, r := range res {
+				b, err := gojson.Marshal(r)
+				if err != nil {
+					t.Fatalf("Marshal should not return an error, but got '%s'", err)
+				}
+				actual = append(actual, string(b))
+			}
+			expected := make([]string, 0, len(c.expected))
+			for _, e := range c.expected {
+				var buf bytes.Buffer
+				if err := gojson.Compact(&buf, []byte(e)); err != nil {
+					t.Fatalf("Compact should not return an error, but got '%s'", err)
+				}
+				expected = append(expected, buf.String())
+			}
+			if diff := cmp.Diff(expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResponseFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewResponseFormatter(&buf, ".fieldViolations[].field", format.DefaultJSONOptions)
+	if err != nil {
+		t.Fatalf("NewResponseFormatter should not return an error, but got '%s'", err)
+	}
+	msg := &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name"}, {Field: "age"}},
+	}
+	if err := f.FormatMessage(msg); err != nil {
+		t.Fatalf("FormatMessage should not return an error, but got '%s'", err)
+	}
+	if expected, actual := "\"name\"\n\"age\"\n", buf.String(); expected != actual {
+		t.Errorf("expected %q, but got %q", expected, actual)
+	}
+}
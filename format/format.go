@@ -2,10 +2,19 @@
 package format
 
 import (
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
+// Status represents the status of a gRPC response.
+type Status struct {
+	Code    codes.Code
+	Message string
+	// Details is the error details of the status.
+	// Each detail is decoded into a JSON-compatible map which has "@type" key.
+	Details []map[string]interface{}
+}
+
 // ResponseFormatter provides formatting feature for gRPC response.
 type ResponseFormatter struct {
 	enrich bool
@@ -13,7 +22,7 @@ type ResponseFormatter struct {
 	impl ResponseFormatterInterface
 }
 
-func (f *ResponseFormatter) Format(s *status.Status, header, trailer metadata.MD, v interface{}) error {
+func (f *ResponseFormatter) Format(s *Status, header, trailer metadata.MD, v interface{}) error {
 	f.FormatHeader(header)
 	if err := f.FormatMessage(v); err != nil {
 		return err
@@ -37,7 +46,7 @@ func (f *ResponseFormatter) FormatMessage(v interface{}) error {
 	return f.impl.FormatMessage(v)
 }
 
-func (f *ResponseFormatter) FormatTrailer(status *status.Status, trailer metadata.MD) error {
+func (f *ResponseFormatter) FormatTrailer(status *Status, trailer metadata.MD) error {
 	if f.enrich {
 		f.impl.FormatTrailer(trailer)
 		if err := f.impl.FormatStatus(status); err != nil {
@@ -65,7 +74,7 @@ type ResponseFormatterInterface interface {
 	// FormatMessage formats the response message (body).
 	FormatMessage(v interface{}) error
 	// FormatStatus formats the response status.
-	FormatStatus(status *status.Status) error
+	FormatStatus(status *Status) error
 	// FormatTrailer formats the response trailer.
 	FormatTrailer(trailer metadata.MD)
 	// Done indicates all response information is formatted.
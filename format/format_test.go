@@ -6,7 +6,6 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 type formatter struct {
@@ -22,7 +21,7 @@ func (f *formatter) FormatMessage(v interface{}) error {
 	return nil
 }
 
-func (f *formatter) FormatStatus(status *status.Status) error {
+func (f *formatter) FormatStatus(status *Status) error {
 	f.FormatStatusCalled = true
 	return nil
 }
@@ -52,7 +51,7 @@ func TestResponseFormatter(t *testing.T) {
 			if err := f.FormatMessage(struct{}{}); err != nil {
 				t.Fatalf("FormatMessage should not return an error, but got '%s'", err)
 			}
-			if err := f.FormatTrailer(status.New(codes.Internal, "internal error"), metadata.Pairs("key", "val")); err != nil {
+			if err := f.FormatTrailer(&Status{Code: codes.Internal, Message: "internal error"}, metadata.Pairs("key", "val")); err != nil {
 				t.Fatalf("FormatTrailer should not return an error, but got '%s'", err)
 			}
 			if err := f.Done(); err != nil {
@@ -72,7 +71,7 @@ func TestResponseFormatter(t *testing.T) {
 				impl := &formatter{}
 				f := NewResponseFormatter(impl, c.enrich)
 				err := f.Format(
-					status.New(codes.Internal, "internal error"),
+					&Status{Code: codes.Internal, Message: "internal error"},
 					metadata.Pairs("key", "val"),
 					metadata.Pairs("key", "val"),
 					struct{}{},
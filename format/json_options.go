@@ -0,0 +1,80 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/pkg/errors"
+)
+
+// JSONOptions is options for formatting messages as JSON.
+type JSONOptions struct {
+	// Compact formats JSON in a single line. Indent is ignored if Compact is true.
+	Compact bool
+	// Indent is the number of spaces for each indentation level.
+	Indent int
+	// SortKeys orders keys of objects alphabetically. If it is false, fields are ordered as defined in the schema.
+	SortKeys bool
+	// EmitDefaults emits fields that have the default value.
+	EmitDefaults bool
+	// ProtoNames uses original field names in the proto file instead of lowerCamelCase JSON names.
+	ProtoNames bool
+	// Int64AsNumber renders 64-bit integers as JSON numbers instead of strings.
+	Int64AsNumber bool
+	// BytesEncoding is the encoding of bytes fields, one of BytesBase64, BytesHex or BytesUTF8.
+	// If it is empty, BytesBase64 is used.
+	BytesEncoding string
+}
+
+// DefaultJSONOptions is the JSONOptions used if no options are configured.
+var DefaultJSONOptions = JSONOptions{Indent: 2}
+
+// IndentString returns the string used for each indentation level. It returns an empty string if the output should
+// be compact.
+func (o JSONOptions) IndentString() string {
+	if o.Compact {
+		return ""
+	}
+	return strings.Repeat(" ", o.Indent)
+}
+
+// MarshalMessage marshals m into compact JSON according to o.
+// Indentation is not applied; it is the responsibility of the caller.
+func (o JSONOptions) MarshalMessage(m proto.Message) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{EmitDefaults: o.EmitDefaults, OrigName: o.ProtoNames}
+	if err := marshaler.Marshal(&buf, m); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the message")
+	}
+	b := buf.Bytes()
+	r := &scalarRewriter{opts: o}
+	if r.needed() {
+		md, err := messageDescriptor(m)
+		if err != nil {
+			return nil, err
+		}
+		b, err = r.message(b, md)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to rewrite the marshaled message")
+		}
+	}
+	if !o.SortKeys {
+		return b, nil
+	}
+
+	// encoding/json sorts map keys, so decoding into generic values and encoding them again sorts all keys.
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the marshaled message")
+	}
+	sorted, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sort keys of the message")
+	}
+	return sorted, nil
+}
@@ -0,0 +1,143 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestJSONOptions_MarshalMessage(t *testing.T) {
+	msg := &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "required"},
+		},
+	}
+	cases := map[string]struct {
+		opts     JSONOptions
+		msg      *errdetails.BadRequest
+		expected string
+	}{
+		"schema order": {
+			opts:     DefaultJSONOptions,
+			msg:      msg,
+			expected: `{"fieldViolations":[{"field":"name","description":"required"}]}`,
+		},
+		"sort keys": {
+			opts:     JSONOptions{SortKeys: true},
+			msg:      msg,
+			expected: `{"fieldViolations":[{"description":"required","field":"name"}]}`,
+		},
+		"proto names": {
+			opts:     JSONOptions{ProtoNames: true},
+			msg:      msg,
+			expected: `{"field_violations":[{"field":"name","description":"required"}]}`,
+		},
+		"omit defaults": {
+			opts:     JSONOptions{},
+			msg:      &errdetails.BadRequest{},
+			expected: `{}`,
+		},
+		"emit defaults": {
+			opts:     JSONOptions{EmitDefaults: true},
+			msg:      &errdetails.BadRequest{},
+			expected: `{"fieldViolations":[]}`,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			b, err := c.opts.MarshalMessage(c.msg)
+			if err != nil {
+				t.Fatalf("MarshalMessage should not return an error, but got '%s'", err)
+			}
+			if actual := string(b); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJSONOptions_IndentString(t *testing.T) {
+	cases := map[string]struct {
+		opts     JSONOptions
+		expected string
+	}{
+		"default":          {opts: DefaultJSONOptions, expected: "  "},
+		"indent 4":         {opts: JSONOptions{Indent: 4}, expected: "    "},
+		"compact":          {opts: JSONOptions{Compact: true, Indent: 4}, expected: ""},
+		"indent 0 is flat": {opts: JSONOptions{}, expected: ""},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if actual := c.opts.IndentString(); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJSONOptions_MarshalMessage_scalars(t *testing.T) {
+	msg := &descriptor.UninterpretedOption{
+		PositiveIntValue: proto.Uint64(1 << 60),
+		NegativeIntValue: proto.Int64(-5),
+		StringValue:      []byte("h\xffi\n"),
+	}
+	cases := map[string]struct {
+		opts     JSONOptions
+		msg      proto.Message
+		expected string
+	}{
+		"default": {
+			msg:      msg,
+			expected: `{"positiveIntValue":"1152921504606846976","negativeIntValue":"-5","stringValue":"aP9pCg=="}`,
+		},
+		"int64 as number": {
+			opts:     JSONOptions{Int64AsNumber: true},
+			msg:      msg,
+			expected: `{"positiveIntValue":1152921504606846976,"negativeIntValue":-5,"stringValue":"aP9pCg=="}`,
+		},
+		"hex": {
+			opts:     JSONOptions{BytesEncoding: BytesHex},
+			msg:      msg,
+			expected: `{"positiveIntValue":"1152921504606846976","negativeIntValue":"-5","stringValue":"68ff690a"}`,
+		},
+		"utf8": {
+			opts:     JSONOptions{BytesEncoding: BytesUTF8},
+			msg:      msg,
+			expected: `{"positiveIntValue":"1152921504606846976","negativeIntValue":"-5","stringValue":"h\\xffi\\x0a"}`,
+		},
+		"int64 as number with sorted keys": {
+			opts:     JSONOptions{Int64AsNumber: true, SortKeys: true},
+			msg:      msg,
+			expected: `{"negativeIntValue":-5,"positiveIntValue":1152921504606846976,"stringValue":"aP9pCg=="}`,
+		},
+		"repeated messages": {
+			opts: JSONOptions{Int64AsNumber: true},
+			msg: &descriptor.FieldOptions{
+				UninterpretedOption: []*descriptor.UninterpretedOption{{NegativeIntValue: proto.Int64(-1)}},
+			},
+			expected: `{"uninterpretedOption":[{"negativeIntValue":-1}]}`,
+		},
+		"wrapper": {
+			opts:     JSONOptions{Int64AsNumber: true},
+			msg:      &wrappers.Int64Value{Value: 10},
+			expected: `10`,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			b, err := c.opts.MarshalMessage(c.msg)
+			if err != nil {
+				t.Fatalf("MarshalMessage should not return an error, but got '%s'", err)
+			}
+			if actual := string(b); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package format
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/pkg/errors"
+)
+
+// Available encodings of bytes fields.
+const (
+	// BytesBase64 encodes bytes as standard base64. It is the default encoding of the JSON mapping.
+	BytesBase64 = "base64"
+	// BytesHex encodes bytes as lower-case hex digits.
+	BytesHex = "hex"
+	// BytesUTF8 shows bytes as a UTF-8 string. Invalid bytes and non-printable characters are escaped as \xNN.
+	BytesUTF8 = "utf8"
+)
+
+// descriptorHolder is implemented by dynamic messages.
+type descriptorHolder interface {
+	GetMessageDescriptor() *desc.MessageDescriptor
+}
+
+// messageDescriptor returns the descriptor of m.
+func messageDescriptor(m proto.Message) (*desc.MessageDescriptor, error) {
+	if h, ok := m.(descriptorHolder); ok {
+		return h.GetMessageDescriptor(), nil
+	}
+	md, err := desc.LoadMessageDescriptorForMessage(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the message descriptor")
+	}
+	return md, nil
+}
+
+// scalarRewriter rewrites 64-bit integers and bytes in JSON marshaled by jsonpb according to opts.
+// The order of keys is kept.
+type scalarRewriter struct {
+	opts JSONOptions
+}
+
+func (r *scalarRewriter) needed() bool {
+	return r.opts.Int64AsNumber || (r.opts.BytesEncoding != "" && r.opts.BytesEncoding != BytesBase64)
+}
+
+// message rewrites b which is a JSON object of a message of md.
+func (r *scalarRewriter) message(b []byte, md *desc.MessageDescriptor) ([]byte, error) {
+	if md == nil || isFreeForm(md) || isNull(b) {
+		return b, nil
+	}
+	// Wrapper types are represented as their scalar values.
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return r.int64(b), nil
+	case "google.protobuf.BytesValue":
+		return r.bytes(b)
+	}
+	if b := bytes.TrimSpace(b); len(b) == 0 || b[0] != '{' {
+		// Other well-known types such as google.protobuf.Timestamp.
+		return b, nil
+	}
+	var buf bytes.Buffer
+	err := r.object(&buf, b, func(key string, v []byte) ([]byte, error) {
+		fd := md.FindFieldByJSONName(key)
+		if fd == nil {
+			fd = md.FindFieldByName(key)
+		}
+		if fd == nil {
+			// Such as "@type" of google.protobuf.Any.
+			return v, nil
+		}
+		return r.field(v, fd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *scalarRewriter) field(b []byte, fd *desc.FieldDescriptor) ([]byte, error) {
+	switch {
+	case isNull(b):
+		return b, nil
+	case fd.IsMap():
+		var buf bytes.Buffer
+		vfd := fd.GetMapValueType()
+		if err := r.object(&buf, b, func(_ string, v []byte) ([]byte, error) { return r.single(v, vfd) }); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case fd.IsRepeated():
+		var elems []json.RawMessage
+		if err := json.Unmarshal(b, &elems); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode field '%s'", fd.GetName())
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, e := range elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			nb, err := r.single(e, fd)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(nb)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	default:
+		return r.single(b, fd)
+	}
+}
+
+// single rewrites b which is a non-repeated value of fd.
+func (r *scalarRewriter) single(b []byte, fd *desc.FieldDescriptor) ([]byte, error) {
+	if isNull(b) {
+		return b, nil
+	}
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return r.int64(b), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return r.bytes(b)
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return r.message(b, fd.GetMessageType())
+	default:
+		return b, nil
+	}
+}
+
+// int64 converts b which is a 64-bit integer represented as a JSON string into a JSON number.
+func (r *scalarRewriter) int64(b []byte) []byte {
+	if !r.opts.Int64AsNumber {
+		return b
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// Already a number.
+		return b
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return []byte(s)
+	}
+	if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return []byte(s)
+	}
+	return b
+}
+
+// bytes converts b which is a base64-encoded JSON string into the configured encoding.
+func (r *scalarRewriter) bytes(b []byte) ([]byte, error) {
+	if r.opts.BytesEncoding == "" || r.opts.BytesEncoding == BytesBase64 {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, errors.Wrap(err, "failed to decode a bytes value")
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode a bytes value as base64")
+	}
+	switch r.opts.BytesEncoding {
+	case BytesHex:
+		s = hex.EncodeToString(raw)
+	case BytesUTF8:
+		s = escapeUTF8(raw)
+	default:
+		return nil, errors.Errorf("unknown bytes encoding '%s'", r.opts.BytesEncoding)
+	}
+	return json.Marshal(s)
+}
+
+// object calls f with each key and value of the JSON object b and writes the results to buf.
+func (r *scalarRewriter) object(buf *bytes.Buffer, b []byte, f func(key string, v []byte) ([]byte, error)) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return errors.Wrap(err, "failed to decode an object")
+	}
+	buf.WriteByte('{')
+	for i := 0; dec.More(); i++ {
+		t, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, "failed to decode a key")
+		}
+		key, ok := t.(string)
+		if !ok {
+			return errors.Errorf("a key of an object should be a string, but got %T", t)
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return errors.Wrapf(err, "failed to decode the value of '%s'", key)
+		}
+		nv, err := f(key, v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(nv)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// isFreeForm reports whether md is a well-known type whose JSON representation has arbitrary keys.
+func isFreeForm(md *desc.MessageDescriptor) bool {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue":
+		return true
+	}
+	return false
+}
+
+func isNull(b []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(b), []byte("null"))
+}
+
+// escapeUTF8 converts b into a string. Invalid bytes and non-printable characters are escaped as \xNN.
+func escapeUTF8(b []byte) string {
+	var buf bytes.Buffer
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if (r == utf8.RuneError && size == 1) || !unicode.IsPrint(r) && r != ' ' {
+			for _, c := range b[:size] {
+				fmt.Fprintf(&buf, `\x%02x`, c)
+			}
+		} else {
+			buf.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return buf.String()
+}
@@ -0,0 +1,89 @@
+// Package ndjson provides a newline-delimited JSON formatter implementation.
+package ndjson
+
+import (
+	gojson "encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseFormatter is a formatter that writes each part of a response as a line of JSON.
+// Unlike the JSON formatter, it writes a message as soon as it is received, so that it is suitable for streaming RPCs.
+// Messages are written as they are. Header, trailer and status are written as an object which has "header",
+// "trailer" and "status" key respectively.
+type responseFormatter struct {
+	w    io.Writer
+	opts format.JSONOptions
+}
+
+// NewResponseFormatter returns a newline-delimited JSON formatter. Each line is always compact, so that only
+// SortKeys and EmitDefaults of opts are respected.
+func NewResponseFormatter(w io.Writer, opts format.JSONOptions) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w, opts: opts}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	_ = p.writeLine(struct {
+		Header metadata.MD `json:"header"`
+	}{header})
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	_ = p.writeLine(struct {
+		Trailer metadata.MD `json:"trailer"`
+	}{trailer})
+}
+
+func (p *responseFormatter) FormatStatus(s *format.Status) error {
+	return p.writeLine(struct {
+		Status struct {
+			Code    string                   `json:"code"`
+			Number  uint32                   `json:"number"`
+			Message string                   `json:"message"`
+			Details []map[string]interface{} `json:"details,omitempty"`
+		} `json:"status"`
+	}{
+		Status: struct {
+			Code    string                   `json:"code"`
+			Number  uint32                   `json:"number"`
+			Message string                   `json:"message"`
+			Details []map[string]interface{} `json:"details,omitempty"`
+		}{
+			Code:    s.Code.String(),
+			Number:  uint32(s.Code),
+			Message: s.Message,
+			Details: s.Details,
+		},
+	})
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
+
+func (p *responseFormatter) writeLine(v interface{}) error {
+	b, err := gojson.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal v")
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
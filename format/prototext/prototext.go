@@ -0,0 +1,93 @@
+// Package prototext provides a formatter implementation for the protobuf text format.
+package prototext
+
+import (
+	gojson "encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/format"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseFormatter is a formatter that formats messages as the protobuf text format.
+// Header, trailer and status are written as comments, so that the output can be used as the input of
+// fill.SilentPrototextFiller as it is. Messages are separated by fill.PrototextSeparator.
+// Unknown fields of messages are written with their field numbers.
+type responseFormatter struct {
+	w io.Writer
+
+	wroteMessage bool
+}
+
+func NewResponseFormatter(w io.Writer) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	p.writeMetadata(header)
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch m := v.(type) {
+	case interface{ MarshalTextIndent() ([]byte, error) }: // *dynamic.Message.
+		b, err = m.MarshalTextIndent()
+	case proto.Message:
+		b = []byte(proto.MarshalTextString(m))
+	default:
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the message")
+	}
+	if p.wroteMessage {
+		fmt.Fprintln(p.w, fill.PrototextSeparator)
+	}
+	if s := strings.TrimRight(string(b), "\n"); s != "" {
+		fmt.Fprintln(p.w, s)
+	}
+	p.wroteMessage = true
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	p.writeMetadata(trailer)
+}
+
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
+	fmt.Fprintf(p.w, "# code: %s\n# number: %d\n# message: %q\n", status.Code.String(), status.Code, status.Message)
+	for _, d := range status.Details {
+		b, err := gojson.Marshal(d)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.w, "# details: %s\n", b)
+	}
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
+
+func (p *responseFormatter) writeMetadata(md metadata.MD) {
+	var s []string
+	for k, v := range md {
+		for _, vv := range v {
+			s = append(s, fmt.Sprintf("# %s: %s", k, vv))
+		}
+	}
+	sort.Strings(s)
+	for _, l := range s {
+		fmt.Fprintln(p.w, l)
+	}
+}
@@ -0,0 +1,188 @@
+// Package table provides a table formatter implementation.
+package table
+
+import (
+	gojson "encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/format/filter"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultMaxWidth is the default maximum width of each cell.
+const DefaultMaxWidth = 40
+
+// Options is options for the table formatter.
+type Options struct {
+	// Columns are field names shown as columns in this order. If it is empty, all fields are shown.
+	Columns []string
+	// MaxWidth is the maximum number of characters of each cell. Longer values are truncated.
+	// 0 means no limit.
+	MaxWidth int
+}
+
+// responseFormatter is a formatter that formats messages into a table which has a column per field.
+// If a message has only one field and it is a list of messages such that responses of List* RPCs,
+// each element of the list is a row. Otherwise, each message is a row.
+// Header, trailer and status are not formatted.
+type responseFormatter struct {
+	w        io.Writer
+	opts     format.JSONOptions
+	tblOpts  Options
+	columns  []string
+	included map[string]bool
+	rows     []map[string]string
+}
+
+// NewResponseFormatter returns a table formatter. The table is written after all messages are formatted.
+func NewResponseFormatter(w io.Writer, tblOpts Options, opts format.JSONOptions) format.ResponseFormatterInterface {
+	p := &responseFormatter{w: w, opts: opts, tblOpts: tblOpts, included: map[string]bool{}}
+	for _, c := range tblOpts.Columns {
+		p.addColumn(c)
+	}
+	return p
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	dv, err := filter.Decode(b)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the marshaled message")
+	}
+	for _, r := range Rows(dv) {
+		p.appendRow(r)
+	}
+	return nil
+}
+
+// Rows returns values which are formatted as rows from v decoded by filter.Decode.
+// If v has only one field and it is a list of objects, Rows returns the elements. Otherwise, it returns v itself.
+// v may not be an object if the message is a well-known type such that google.protobuf.Value.
+func Rows(v interface{}) []interface{} {
+	obj, ok := v.(*filter.Object)
+	if !ok {
+		return []interface{}{v}
+	}
+	if len(obj.Keys()) != 1 {
+		return []interface{}{obj}
+	}
+	fv, _ := obj.Get(obj.Keys()[0])
+	l, ok := fv.([]interface{})
+	if !ok {
+		return []interface{}{obj}
+	}
+	for _, e := range l {
+		if _, ok := e.(*filter.Object); !ok {
+			return []interface{}{obj}
+		}
+	}
+	return l
+}
+
+func (p *responseFormatter) appendRow(v interface{}) {
+	row := map[string]string{}
+	obj, ok := v.(*filter.Object)
+	if !ok {
+		// Non-object values are shown in a column named "value".
+		p.setCell(row, "value", v)
+		p.rows = append(p.rows, row)
+		return
+	}
+	for _, k := range obj.Keys() {
+		fv, _ := obj.Get(k)
+		p.setCell(row, k, fv)
+	}
+	p.rows = append(p.rows, row)
+}
+
+func (p *responseFormatter) setCell(row map[string]string, k string, v interface{}) {
+	if len(p.tblOpts.Columns) == 0 {
+		p.addColumn(k)
+	}
+	if !p.included[k] {
+		return
+	}
+	row[k] = p.truncate(cell(v))
+}
+
+func (p *responseFormatter) addColumn(c string) {
+	if p.included[c] {
+		return
+	}
+	p.included[c] = true
+	p.columns = append(p.columns, c)
+}
+
+func (p *responseFormatter) truncate(s string) string {
+	max := p.tblOpts.MaxWidth
+	r := []rune(s)
+	if max <= 0 || len(r) <= max {
+		return s
+	}
+	if max <= 3 {
+		return string(r[:max])
+	}
+	return string(r[:max-3]) + "..."
+}
+
+// cell converts v into a string for a cell. Strings, numbers and booleans are shown as they are, and
+// the others are shown as compact JSON.
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(v)
+	case gojson.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, err := gojson.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {}
+
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	if len(p.rows) == 0 || len(p.columns) == 0 {
+		return nil
+	}
+	rows := make([][]string, len(p.rows))
+	for i, r := range p.rows {
+		rows[i] = make([]string, len(p.columns))
+		for j, c := range p.columns {
+			rows[i][j] = r[c]
+		}
+	}
+	t := tablewriter.NewWriter(p.w)
+	t.SetHeader(p.columns)
+	t.SetAutoFormatHeaders(false)
+	t.SetAutoWrapText(false)
+	t.AppendBulk(rows)
+	t.Render()
+	return nil
+}
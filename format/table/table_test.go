@@ -0,0 +1,93 @@
+package table
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/format"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	list := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{Name: proto.String("foo.proto"), Package: proto.String("foo")},
+			{Name: proto.String("bar.proto"), Dependency: []string{"foo.proto"}},
+		},
+	}
+	cases := map[string]struct {
+		tblOpts  Options
+		msgs     []proto.Message
+		expected string
+	}{
+		"list": {
+			msgs: []proto.Message{list},
+			expected: `+-----------+---------+---------------+
+|   name    | package |  dependency   |
++-----------+---------+---------------+
+| foo.proto | foo     |               |
+| bar.proto |         | ["foo.proto"] |
++-----------+---------+---------------+
+`,
+		},
+		"stream": {
+			msgs: []proto.Message{
+				&descriptor.DescriptorProto{Name: proto.String("Foo")},
+				&descriptor.DescriptorProto{Name: proto.String("Bar"), Field: []*descriptor.FieldDescriptorProto{{Name: proto.String("id")}}},
+			},
+			expected: `+------+-----------------+
+| name |      field      |
++------+-----------------+
+| Foo  |                 |
+| Bar  | [{"name":"id"}] |
++------+-----------------+
+`,
+		},
+		"columns": {
+			tblOpts: Options{Columns: []string{"package", "name"}},
+			msgs:    []proto.Message{list},
+			expected: `+---------+-----------+
+| package |   name    |
++---------+-----------+
+| foo     | foo.proto |
+|         | bar.proto |
++---------+-----------+
+`,
+		},
+		"truncation": {
+			tblOpts: Options{Columns: []string{"name"}, MaxWidth: 6},
+			msgs:    []proto.Message{list},
+			expected: `+--------+
+|  name  |
++--------+
+| foo... |
+| bar... |
++--------+
+`,
+		},
+		"empty": {
+			msgs: []proto.Message{&descriptor.FileDescriptorSet{}},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewResponseFormatter(&buf, c.tblOpts, format.DefaultJSONOptions)
+			for _, m := range c.msgs {
+				if err := f.FormatMessage(m); err != nil {
+					t.Fatalf("FormatMessage must not return an error, but got '%s'", err)
+				}
+			}
+			if err := f.Done(); err != nil {
+				t.Fatalf("Done must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, buf.String()); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
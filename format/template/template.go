@@ -0,0 +1,80 @@
+// Package template provides a formatter implementation that formats each message by a Go template.
+package template
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"io"
+	"text/template"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseFormatter is a formatter that executes a template with each message.
+// Header, trailer and status are not formatted.
+type responseFormatter struct {
+	w    io.Writer
+	tmpl *template.Template
+	opts format.JSONOptions
+}
+
+// NewResponseFormatter parses text as a Go template and returns a formatter that executes it with each message.
+// The data passed to the template is the message decoded from JSON, so that fields are referenced by their
+// JSON names such that {{.user.name}}. A newline is appended to the output if it doesn't end with a newline.
+func NewResponseFormatter(w io.Writer, text string, opts format.JSONOptions) (format.ResponseFormatterInterface, error) {
+	tmpl, err := template.New("output").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the output template")
+	}
+	return &responseFormatter{w: w, tmpl: tmpl, opts: opts}, nil
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	dec := gojson.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return errors.Wrap(err, "failed to decode the marshaled message")
+	}
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return errors.Wrap(err, "failed to execute the output template")
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err = buf.WriteTo(p.w)
+	return err
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {}
+
+func (p *responseFormatter) FormatStatus(status *format.Status) error {
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	return nil
+}
+
+// toJSON formats v as compact JSON. It is used to show a message field or a repeated field as it is.
+func toJSON(v interface{}) (string, error) {
+	b, err := gojson.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
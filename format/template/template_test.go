@@ -0,0 +1,51 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ktr0731/evans/format"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestResponseFormatter(t *testing.T) {
+	msg := &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "required"},
+			{Field: "age", Description: "too young"},
+		},
+	}
+	cases := map[string]struct {
+		text     string
+		opts     format.JSONOptions
+		expected string
+	}{
+		"field":         {text: `{{(index .fieldViolations 0).field}}`, expected: "name\n"},
+		"range":         {text: "{{range .fieldViolations}}{{.field}}: {{.description}}\n{{end}}", expected: "name: required\nage: too young\n"},
+		"json":          {text: `{{json (index .fieldViolations 1)}}`, expected: `{"description":"too young","field":"age"}` + "\n"},
+		"proto names":   {text: `{{len .field_violations}}`, opts: format.JSONOptions{ProtoNames: true}, expected: "2\n"},
+		"missing field": {text: `{{.foo}}`, expected: "<no value>\n"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f, err := NewResponseFormatter(&buf, c.text, c.opts)
+			if err != nil {
+				t.Fatalf("NewResponseFormatter should not return an error, but got '%s'", err)
+			}
+			if err := f.FormatMessage(msg); err != nil {
+				t.Fatalf("FormatMessage should not return an error, but got '%s'", err)
+			}
+			if actual := buf.String(); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+
+	t.Run("invalid template", func(t *testing.T) {
+		if _, err := NewResponseFormatter(&bytes.Buffer{}, `{{.foo`, format.JSONOptions{}); err == nil {
+			t.Error("NewResponseFormatter should return an error, but got nil")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+// Package yaml provides a YAML formatter implementation.
+package yaml
+
+import (
+	gojson "encoding/json"
+	"io"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/present"
+	"github.com/ktr0731/evans/present/yaml"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// responseFormatter is a formatter that formats a response into a YAML document.
+// The structure of the document is the same as the JSON formatter.
+type responseFormatter struct {
+	w io.Writer
+	s struct {
+		Status *struct {
+			Code    string                   `json:"code"`
+			Number  uint32                   `json:"number"`
+			Message string                   `json:"message"`
+			Details []map[string]interface{} `json:"details,omitempty"`
+		} `json:"status,omitempty"`
+		Header *metadata.MD `json:"header,omitempty"`
+		// Messages are kept as raw JSON to keep the order of fields.
+		Messages []gojson.RawMessage `json:"messages,omitempty"`
+		Trailer  *metadata.MD        `json:"trailer,omitempty"`
+	}
+	p    present.Presenter
+	opts format.JSONOptions
+}
+
+// NewResponseFormatter returns a YAML formatter. Messages are converted from JSON marshaled with opts, so that
+// SortKeys and EmitDefaults of opts are respected.
+func NewResponseFormatter(w io.Writer, opts format.JSONOptions) format.ResponseFormatterInterface {
+	return &responseFormatter{w: w, p: yaml.NewPresenter(), opts: opts}
+}
+
+func (p *responseFormatter) FormatHeader(header metadata.MD) {
+	p.s.Header = &header
+}
+
+func (p *responseFormatter) FormatMessage(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("v should be a proto.Message, but got %T", v)
+	}
+	b, err := p.opts.MarshalMessage(m)
+	if err != nil {
+		return err
+	}
+	p.s.Messages = append(p.s.Messages, b)
+	return nil
+}
+
+func (p *responseFormatter) FormatTrailer(trailer metadata.MD) {
+	p.s.Trailer = &trailer
+}
+
+func (p *responseFormatter) FormatStatus(s *format.Status) error {
+	p.s.Status = &struct {
+		Code    string                   `json:"code"`
+		Number  uint32                   `json:"number"`
+		Message string                   `json:"message"`
+		Details []map[string]interface{} `json:"details,omitempty"`
+	}{
+		Code:    s.Code.String(),
+		Number:  uint32(s.Code),
+		Message: s.Message,
+		Details: s.Details,
+	}
+	return nil
+}
+
+func (p *responseFormatter) Done() error {
+	s, err := p.p.Format(p.s)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(p.w, s+"\n")
+	return err
+}
@@ -4,6 +4,7 @@ go 1.12
 
 require (
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
+	github.com/envoyproxy/protoc-gen-validate v0.1.0
 	github.com/fatih/color v1.9.0
 	github.com/golang/protobuf v1.4.1
 	github.com/google/go-cmp v0.4.0
@@ -43,10 +44,13 @@ require (
 	github.com/zchee/go-xdgbasedir v1.0.3
 	go.uber.org/goleak v0.10.0
 	golang.org/x/mod v0.2.0 // indirect
-	golang.org/x/net v0.0.0-20200425230154-ff2c4b7c35a0 // indirect
+	golang.org/x/net v0.0.0-20200425230154-ff2c4b7c35a0
+	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
 	golang.org/x/sys v0.0.0-20200428200454-593003d681fa // indirect
 	golang.org/x/tools v0.0.0-20200221224223-e1da425f72fd
 	google.golang.org/genproto v0.0.0-20200428115010-c45acf45369a
 	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.22.0
+	gopkg.in/yaml.v2 v2.2.8
 )
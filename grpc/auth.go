@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/ktr0731/evans/auth"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type authClient struct {
+	Client
+
+	creds auth.CredentialProvider
+}
+
+// NewAuthClient returns a client which attaches metadata provided by creds to every RPC sent via client.
+// Keys which the outgoing metadata already has, such that specified by --header, are not overwritten.
+//
+// If creds implements auth.Invalidator and an RPC fails with codes.Unauthenticated, the credentials are invalidated.
+// Unary RPCs are retried once with new credentials. Streaming RPCs are not retried because sent messages cannot be
+// replayed, but the next RPC uses new credentials.
+func NewAuthClient(client Client, creds auth.CredentialProvider) Client {
+	return &authClient{Client: client, creds: creds}
+}
+
+// attach returns the context which has the credentials. attached is false if all keys of the credentials were
+// already specified by the user.
+func (c *authClient) attach(ctx context.Context) (_ context.Context, attached bool, _ error) {
+	md, err := c.creds.RequestMetadata(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get credentials")
+	}
+	out, _ := metadata.FromOutgoingContext(ctx)
+	kv := make([]string, 0, len(md)*2)
+	for k, v := range md {
+		if len(out.Get(k)) != 0 {
+			continue
+		}
+		kv = append(kv, k, v)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...), len(kv) != 0, nil
+}
+
+// invalidate invalidates the credentials if err is caused by them. It reports whether they are invalidated.
+func (c *authClient) invalidate(fqrn string, err error) bool {
+	inv, ok := c.creds.(auth.Invalidator)
+	if !ok || status.Code(errors.Cause(err)) != codes.Unauthenticated {
+		return false
+	}
+	inv.Invalidate()
+	logger.Printf("%s failed with Unauthenticated, refreshing credentials", fqrn)
+	return true
+}
+
+func (c *authClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	actx, attached, err := c.attach(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, trailer, err := c.Client.Invoke(actx, fqrn, req, res)
+	if !attached || !c.invalidate(fqrn, err) {
+		return header, trailer, err
+	}
+	actx, _, err = c.attach(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.Client.Invoke(actx, fqrn, req, res)
+}
+
+func (c *authClient) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	ctx, attached, err := c.attach(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Client.NewClientStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		if attached {
+			c.invalidate(fqrn, err)
+		}
+		return nil, err
+	}
+	if !attached {
+		return s, nil
+	}
+	return &authClientStream{ClientStream: s, c: c, fqrn: fqrn}, nil
+}
+
+func (c *authClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	ctx, attached, err := c.attach(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Client.NewServerStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		if attached {
+			c.invalidate(fqrn, err)
+		}
+		return nil, err
+	}
+	if !attached {
+		return s, nil
+	}
+	return &authServerStream{ServerStream: s, c: c, fqrn: fqrn}, nil
+}
+
+func (c *authClient) NewBidiStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	ctx, attached, err := c.attach(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Client.NewBidiStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		if attached {
+			c.invalidate(fqrn, err)
+		}
+		return nil, err
+	}
+	if !attached {
+		return s, nil
+	}
+	return &authBidiStream{BidiStream: s, c: c, fqrn: fqrn}, nil
+}
+
+type authClientStream struct {
+	ClientStream
+	c    *authClient
+	fqrn string
+}
+
+func (s *authClientStream) CloseAndReceive(res interface{}) error {
+	err := s.ClientStream.CloseAndReceive(res)
+	s.c.invalidate(s.fqrn, err)
+	return err
+}
+
+type authServerStream struct {
+	ServerStream
+	c    *authClient
+	fqrn string
+}
+
+func (s *authServerStream) Receive(res interface{}) error {
+	err := s.ServerStream.Receive(res)
+	s.c.invalidate(s.fqrn, err)
+	return err
+}
+
+type authBidiStream struct {
+	BidiStream
+	c    *authClient
+	fqrn string
+}
+
+func (s *authBidiStream) Receive(res interface{}) error {
+	err := s.BidiStream.Receive(res)
+	s.c.invalidate(s.fqrn, err)
+	return err
+}
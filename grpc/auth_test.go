@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type staticCredentials map[string]string
+
+func (c staticCredentials) RequestMetadata(context.Context) (map[string]string, error) { return c, nil }
+
+func TestAuthClient(t *testing.T) {
+	inner := &headerClient{}
+	client := NewAuthClient(inner, staticCredentials{"authorization": "Bearer token"})
+
+	client.Invoke(context.Background(), "api.Example.Unary", nil, nil) //nolint:errcheck
+	if v := inner.md.Get("authorization"); len(v) != 1 || v[0] != "Bearer token" {
+		t.Errorf("expected the authorization header 'Bearer token', but got %v", v)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer user")
+	client.Invoke(ctx, "api.Example.Unary", nil, nil) //nolint:errcheck
+	if v := inner.md.Get("authorization"); len(v) != 1 || v[0] != "Bearer user" {
+		t.Errorf("the header specified by the user must not be overwritten, but got %v", v)
+	}
+}
+
+// rotatingCredentials issues a new token after invalidated.
+type rotatingCredentials struct {
+	token int
+}
+
+func (c *rotatingCredentials) RequestMetadata(context.Context) (map[string]string, error) {
+	return map[string]string{"authorization": fmt.Sprintf("Bearer token%d", c.token)}, nil
+}
+
+func (c *rotatingCredentials) Invalidate() { c.token++ }
+
+// authServerClient accepts only the valid token.
+type authServerClient struct {
+	Client
+	valid string
+	calls int
+}
+
+func (c *authServerClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	c.calls++
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get("authorization"); len(v) == 0 || v[len(v)-1] != c.valid {
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil, nil, nil
+}
+
+func TestAuthClient_refresh(t *testing.T) {
+	t.Run("retry with new credentials", func(t *testing.T) {
+		inner := &authServerClient{valid: "Bearer token1"}
+		creds := &rotatingCredentials{}
+		client := NewAuthClient(inner, creds)
+		if _, _, err := client.Invoke(context.Background(), "api.Example.Unary", nil, nil); err != nil {
+			t.Fatalf("Invoke must succeed with new credentials, but got '%s'", err)
+		}
+		if inner.calls != 2 || creds.token != 1 {
+			t.Errorf("the RPC must be retried once with new credentials, but got %d calls and token%d", inner.calls, creds.token)
+		}
+	})
+
+	t.Run("retry only once", func(t *testing.T) {
+		inner := &authServerClient{valid: "Bearer token5"}
+		client := NewAuthClient(inner, &rotatingCredentials{})
+		if _, _, err := client.Invoke(context.Background(), "api.Example.Unary", nil, nil); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Invoke must return Unauthenticated, but got '%v'", err)
+		}
+		if inner.calls != 2 {
+			t.Errorf("the RPC must be retried only once, but called %d times", inner.calls)
+		}
+	})
+
+	t.Run("not retry RPCs with credentials specified by the user", func(t *testing.T) {
+		inner := &authServerClient{valid: "Bearer token1"}
+		client := NewAuthClient(inner, &rotatingCredentials{})
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer user")
+		if _, _, err := client.Invoke(ctx, "api.Example.Unary", nil, nil); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Invoke must return Unauthenticated, but got '%v'", err)
+		}
+		if inner.calls != 1 {
+			t.Errorf("the RPC must not be retried, but called %d times", inner.calls)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CallOptions is the settings of CallWithOptions.
+type CallOptions struct {
+	// Headers is sent as the metadata of the RPC. References in the values are not expanded, so that they must be
+	// expanded by ExpandHeaders in advance if needed.
+	Headers map[string][]string
+	// ExtraHeaders is sent in addition to Headers. The values are sent verbatim except for binary headers.
+	ExtraHeaders map[string]string
+	// Timeout cancels the RPC after it if it is positive.
+	Timeout time.Duration
+}
+
+// CallWithOptions calls the RPC with reqs like Call, and returns received responses and the status returned from
+// the server. Binary header values are decoded, but references in header values are not expanded. The error is returned only if the RPC failed without the status, such that headers are invalid
+// or the number of reqs doesn't match the RPC.
+func CallWithOptions(ctx context.Context, client Client, rpc *RPC, reqs []interface{}, opts CallOptions) ([]interface{}, *status.Status, error) {
+	if !rpc.IsClientStreaming && len(reqs) != 1 {
+		return nil, nil, errors.Errorf("'%s' accepts exactly one request, but got %d", rpc.FullyQualifiedName, len(reqs))
+	}
+	md, err := resolveHeaders(opts.Headers, opts.ExtraHeaders)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	res, err := Call(ctx, client, rpc, reqs)
+	stat, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return nil, nil, errors.Wrapf(err, "failed to call '%s'", rpc.FullyQualifiedName)
+	}
+	return res, stat, nil
+}
+
+// resolveHeaders returns metadata which has headers and extra. Binary header values are decoded.
+func resolveHeaders(headers map[string][]string, extra map[string]string) (metadata.MD, error) {
+	md := metadata.New(nil)
+	add := func(k, v string) error {
+		if IsBinaryHeader(k) {
+			b, err := BinaryHeader(v)
+			if err != nil {
+				return errors.Wrapf(err, "invalid header '%s'", k)
+			}
+			v = b
+		}
+		md.Append(k, v)
+		return nil
+	}
+	for k, v := range headers {
+		for _, vv := range v {
+			if err := add(k, vv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for k, v := range extra {
+		if err := add(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+// Call calls the RPC with reqs by client and returns all received responses.
+// reqs must have exactly one request unless the RPC is client streaming or bidi streaming.
+func Call(ctx context.Context, client Client, rpc *RPC, reqs []interface{}) ([]interface{}, error) {
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    rpc.Name,
+		ServerStreams: rpc.IsServerStreaming,
+		ClientStreams: rpc.IsClientStreaming,
+	}
+	switch {
+	case rpc.IsClientStreaming && rpc.IsServerStreaming:
+		stream, err := client.NewBidiStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			if err := stream.Send(req); err != nil {
+				return nil, err
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			return nil, err
+		}
+		return receiveAll(rpc, stream.Receive)
+	case rpc.IsClientStreaming:
+		stream, err := client.NewClientStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			if err := stream.Send(req); err != nil {
+				return nil, err
+			}
+		}
+		res, err := rpc.ResponseType.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a response")
+		}
+		if err := stream.CloseAndReceive(res); err != nil {
+			return nil, err
+		}
+		return []interface{}{res}, nil
+	case rpc.IsServerStreaming:
+		stream, err := client.NewServerStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(reqs[0]); err != nil {
+			return nil, err
+		}
+		return receiveAll(rpc, stream.Receive)
+	default:
+		res, err := rpc.ResponseType.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a response")
+		}
+		if _, _, err := client.Invoke(ctx, rpc.FullyQualifiedName, reqs[0], res); err != nil {
+			return nil, err
+		}
+		return []interface{}{res}, nil
+	}
+}
+
+// receiveAll receives responses by receive until the stream is closed.
+func receiveAll(rpc *RPC, receive func(res interface{}) error) ([]interface{}, error) {
+	var responses []interface{}
+	for {
+		res, err := rpc.ResponseType.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a response")
+		}
+		err = receive(res)
+		if errors.Is(err, io.EOF) {
+			return responses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, res)
+	}
+}
@@ -0,0 +1,24 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/metadata"
+)
+
+func Test_resolveHeaders(t *testing.T) {
+	md, err := resolveHeaders(map[string][]string{"authorization": {"$(echo token)"}}, map[string]string{"x-trace-bin": "AQI", "x-user": "${USER}"})
+	if err != nil {
+		t.Fatalf("resolveHeaders must not return an error, but got '%s'", err)
+	}
+	// References are not expanded, but binary headers are decoded.
+	expected := metadata.MD{"authorization": {"$(echo token)"}, "x-trace-bin": {"\x01\x02"}, "x-user": {"${USER}"}}
+	if diff := cmp.Diff(expected, md); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	if _, err := resolveHeaders(nil, map[string]string{"x-trace-bin": "!"}); err == nil {
+		t.Error("resolveHeaders must return an error if a binary header is invalid")
+	}
+}
@@ -1,3 +1,72 @@
 package grpc
 
-import _ "google.golang.org/grpc/encoding/gzip" // GZIP
+import (
+	"context"
+	"sync"
+
+	_ "google.golang.org/grpc/encoding/gzip" // GZIP
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// compressionHeaderKey is the header key to show the compression algorithm of the response.
+// gRPC doesn't expose it as a header metadata because it is a reserved header.
+const compressionHeaderKey = "grpc-encoding"
+
+// compressionRecorder records the compression algorithm of a response.
+type compressionRecorder struct {
+	mu   sync.Mutex
+	name string
+}
+
+// addTo adds the compression algorithm to md if the response was compressed.
+func (r *compressionRecorder) addTo(md metadata.MD) metadata.MD {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.name == "" || r.name == "identity" {
+		return md
+	}
+	if md == nil {
+		md = metadata.MD{}
+	}
+	if len(md.Get(compressionHeaderKey)) == 0 {
+		md.Set(compressionHeaderKey, r.name)
+	}
+	return md
+}
+
+type compressionRecorderKey struct{}
+
+// withCompressionRecorder returns a context which records the compression algorithm of the response.
+func withCompressionRecorder(ctx context.Context) (context.Context, *compressionRecorder) {
+	r := &compressionRecorder{}
+	return context.WithValue(ctx, compressionRecorderKey{}, r), r
+}
+
+// compressionHandler is a stats.Handler which records the compression algorithm of response headers
+// into the compressionRecorder of the RPC context.
+type compressionHandler struct{}
+
+func (compressionHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (compressionHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	in, ok := s.(*stats.InHeader)
+	if !ok || !in.Client {
+		return
+	}
+	r, ok := ctx.Value(compressionRecorderKey{}).(*compressionRecorder)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	r.name = in.Compression
+	r.mu.Unlock()
+}
+
+func (compressionHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (compressionHandler) HandleConn(context.Context, stats.ConnStats) {}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newConnectProxy starts a HTTP CONNECT proxy which records the tunneled addresses into tunneled.
+func newConnectProxy(t *testing.T, tunneled *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" { // user:pass
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		*tunneled = append(*tunneled, r.Host)
+		src, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack the connection: %s", err)
+			return
+		}
+		src.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")) //nolint:errcheck
+		go func() {
+			defer dst.Close()
+			io.Copy(dst, src) //nolint:errcheck
+		}()
+		go func() {
+			defer src.Close()
+			io.Copy(src, dst) //nolint:errcheck
+		}()
+	}))
+}
+
+func Test_newHTTPClient(t *testing.T) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto)) //nolint:errcheck
+	})
+
+	cases := map[string]struct {
+		proxy    bool
+		h2c      bool
+		expected string
+	}{
+		"direct":             {expected: "HTTP/1.1"},
+		"HTTP CONNECT proxy": {proxy: true, expected: "HTTP/1.1"},
+		"h2c":                {h2c: true, expected: "HTTP/2.0"},
+		"h2c with a proxy":   {proxy: true, h2c: true, expected: "HTTP/2.0"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(h2c.NewHandler(hello, &http2.Server{}))
+			defer srv.Close()
+
+			var tunneled []string
+			var proxyURL string
+			if c.proxy {
+				p := newConnectProxy(t, &tunneled)
+				defer p.Close()
+				proxyURL = strings.Replace(p.URL, "http://", "http://user:pass@", 1)
+			}
+
+			client, scheme, err := newHTTPClient("", false, "", "", "", proxyURL, c.h2c)
+			if err != nil {
+				t.Fatalf("newHTTPClient must not return an error, but got '%s'", err)
+			}
+			defer client.CloseIdleConnections()
+			addr := strings.TrimPrefix(srv.URL, "http://")
+			resp, err := client.Get(scheme + "://" + addr)
+			if err != nil {
+				t.Fatalf("Get must not return an error, but got '%s'", err)
+			}
+			defer resp.Body.Close()
+			b, _ := ioutil.ReadAll(resp.Body)
+			if string(b) != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, string(b))
+			}
+			if c.proxy && (len(tunneled) != 1 || tunneled[0] != addr) {
+				t.Errorf("expected the request is tunneled to '%s', but got %v", addr, tunneled)
+			}
+		})
+	}
+}
+
+func Test_newDialer(t *testing.T) {
+	cases := map[string]struct {
+		proxy    string
+		env      map[string]string
+		addr     string
+		expected string
+		hasErr   bool
+	}{
+		"explicit proxy":                    {proxy: "socks5://localhost:1080", addr: "example.com:443", expected: "socks5://localhost:1080"},
+		"explicit proxy takes precedence":   {proxy: "http://localhost:8080", env: map[string]string{"HTTPS_PROXY": "http://env:8080"}, addr: "example.com:443", expected: "http://localhost:8080"},
+		"HTTPS_PROXY":                       {env: map[string]string{"HTTPS_PROXY": "http://env:8080"}, addr: "example.com:443", expected: "http://env:8080"},
+		"ALL_PROXY":                         {env: map[string]string{"ALL_PROXY": "socks5h://env:1080"}, addr: "example.com:443", expected: "socks5://env:1080"},
+		"NO_PROXY":                          {env: map[string]string{"HTTPS_PROXY": "http://env:8080", "NO_PROXY": "example.com"}, addr: "example.com:443"},
+		"localhost is never proxied by env": {env: map[string]string{"HTTPS_PROXY": "http://env:8080"}, addr: "localhost:50051"},
+		"unsupported scheme":                {proxy: "ftp://localhost:21", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"HTTPS_PROXY", "https_proxy", "ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+				defer setEnv(k, c.env[k])()
+			}
+			d, err := newDialer(c.proxy)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("newDialer must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newDialer must not return an error, but got '%s'", err)
+			}
+			u, err := d.proxy(c.addr)
+			if err != nil {
+				t.Fatalf("proxy must not return an error, but got '%s'", err)
+			}
+			var actual string
+			if u != nil {
+				actual = u.String()
+			}
+			if actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+// setEnv sets or unsets an environment variable. The returned function restores it.
+func setEnv(k, v string) func() {
+	old, ok := os.LookupEnv(k)
+	if v == "" {
+		os.Unsetenv(k)
+	} else {
+		os.Setenv(k, v)
+	}
+	return func() {
+		if ok {
+			os.Setenv(k, old)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+}
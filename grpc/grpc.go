@@ -11,19 +11,36 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+	"net"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
 	"github.com/ktr0731/evans/logger"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 var ErrMutualAuthParamsAreNotEnough = errors.New("cert and certkey are required to authenticate mutually")
 
+// ConnectionError is returned if an RPC failed because the client couldn't connect to the server.
+// It is distinguished from gRPC errors returned from the server such that an RPC timeout.
+type ConnectionError struct {
+	Target string
+	State  connectivity.State
+	Err    error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("couldn't connect to the server '%s' (state: %s): %s", e.Target, e.State, status.Convert(e.Err).Message())
+}
+
 // RPC represents a RPC which belongs to a gRPC service.
 type RPC struct {
 	Name               string
@@ -32,6 +49,13 @@ type RPC struct {
 	ResponseType       *Type
 	IsServerStreaming  bool
 	IsClientStreaming  bool
+	// Comment is the leading comment of the RPC. It is empty if the schema doesn't have source code info
+	// such that schemas fetched by gRPC reflection.
+	Comment string
+	// Deprecated is true if the RPC is marked as deprecated.
+	Deprecated bool
+	// ServiceDeprecated is true if the service of the RPC is marked as deprecated.
+	ServiceDeprecated bool
 }
 
 // Type is a type for representing requests/responses.
@@ -97,6 +121,84 @@ type BidiStream interface {
 	CloseSend() error
 }
 
+// ConnOptions is the connection, keepalive, HTTP/2, message size, compression and retry settings of gRPC connections.
+// Zero values mean the defaults of gRPC.
+type ConnOptions struct {
+	// KeepaliveTime is the interval of keepalive pings. Zero disables keepalive pings.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is the time to wait for the ack of a keepalive ping before closing the connection.
+	KeepaliveTimeout time.Duration
+	// KeepalivePermitWithoutStream sends keepalive pings even if there are no active streams.
+	KeepalivePermitWithoutStream bool
+	// InitialWindowSize is the initial window size of each stream. It must be 64KiB or more.
+	InitialWindowSize int32
+	// InitialConnWindowSize is the initial window size of the connection. It must be 64KiB or more.
+	InitialConnWindowSize int32
+	// MaxHeaderListSize is the maximum size of the header list the client is willing to accept.
+	MaxHeaderListSize uint32
+	// MaxRecvMsgSize is the maximum message size in bytes the client can receive. gRPC's default is 4MB.
+	MaxRecvMsgSize int
+	// MaxSendMsgSize is the maximum message size in bytes the client can send.
+	MaxSendMsgSize int
+	// Compression is the name of the compressor used to compress requests such that "gzip".
+	// It must be registered by encoding.RegisterCompressor.
+	Compression string
+	// Retry is the retry policy of unary RPCs.
+	Retry RetryPolicy
+	// ConnectTimeout is the timeout to establish the connection. If it is zero, NewClient doesn't wait for
+	// the connection, and it is established lazily.
+	ConnectTimeout time.Duration
+	// WaitForReady makes RPCs wait until the connection becomes ready instead of failing immediately.
+	WaitForReady bool
+	// LoadBalancingPolicy is the policy to balance RPCs to the addresses, one of LoadBalancingPickFirst
+	// or LoadBalancingRoundRobin. Empty means LoadBalancingPickFirst.
+	// It is ignored for xDS targets because the policy is provided by the control plane.
+	LoadBalancingPolicy string
+	// XDSBootstrap is the path of the xDS bootstrap file used for xDS targets such that "xds:///service-name".
+	// If it is empty, the GRPC_XDS_BOOTSTRAP environment variable is used.
+	XDSBootstrap string
+	// Trace is the destination of the wire-level trace such that DNS resolution, connection establishment,
+	// TLS handshakes, headers, message sizes and trailers. If it is nil, nothing is traced.
+	Trace io.Writer
+}
+
+func (o ConnOptions) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if o.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                o.KeepaliveTime,
+			Timeout:             o.KeepaliveTimeout,
+			PermitWithoutStream: o.KeepalivePermitWithoutStream,
+		}))
+	}
+	if o.InitialWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(o.InitialWindowSize))
+	}
+	if o.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(o.InitialConnWindowSize))
+	}
+	if o.MaxHeaderListSize > 0 {
+		opts = append(opts, grpc.WithMaxHeaderListSize(o.MaxHeaderListSize))
+	}
+	var callOpts []grpc.CallOption
+	if o.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(o.MaxSendMsgSize))
+	}
+	if o.Compression != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(o.Compression))
+	}
+	if o.WaitForReady {
+		callOpts = append(callOpts, grpc.WaitForReady(true))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	return opts
+}
+
 type client struct {
 	conn    *grpc.ClientConn
 	headers Headers
@@ -105,7 +207,8 @@ type client struct {
 }
 
 // NewClient creates a new gRPC client. It dials to the server specified by addr.
-// addr format is the same as the first argument of grpc.Dial.
+// addr format is the same as the first argument of grpc.Dial, or a comma-separated list of addresses.
+// The addresses are balanced by connOpts.LoadBalancingPolicy.
 // If serverName is not empty, it overrides the gRPC server name used to
 // verify the hostname on the returned certificates.
 // If useReflection is true, the gRPC client enables gRPC reflection.
@@ -114,45 +217,86 @@ type client struct {
 // The set of cert and certKey enables mutual authentication if useTLS is enabled.
 // If one of it is not found, NewClient returns ErrMutualAuthParamsAreNotEnough.
 // If useTLS is false, cacert, cert and certKey are ignored.
-func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert, certKey string) (Client, error) {
-	var opts []grpc.DialOption
+//
+// If proxy is not empty, the connection is tunneled through the proxy. proxy is a URL whose scheme is one of
+// "http" (HTTP CONNECT), "socks5" or "socks5h". If proxy is empty, the proxy is resolved from
+// HTTPS_PROXY, ALL_PROXY and NO_PROXY environment variables.
+// Cleartext connections always use HTTP/2 with prior knowledge.
+// connOpts configures connection, keepalive, HTTP/2, message size, compression and retry settings.
+// Regardless of connOpts, responses compressed by registered compressors are accepted,
+// and the compression algorithm of a response is shown as "grpc-encoding" in the response header.
+func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert, certKey, proxy string, connOpts ConnOptions) (Client, error) {
+	if connOpts.Compression != "" && encoding.GetCompressor(connOpts.Compression) == nil {
+		return nil, errors.Errorf("unknown compressor '%s'", connOpts.Compression)
+	}
+	d, err := newDialer(proxy)
+	if err != nil {
+		return nil, err
+	}
+	var t *tracer
+	if connOpts.Trace != nil {
+		t = newTracer(connOpts.Trace)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		}),
+		grpc.WithStatsHandler(compressionHandler{}),
+	}
+	if t != nil {
+		opts = append(opts,
+			grpc.WithContextDialer(t.dialer(d)),
+			grpc.WithStatsHandler(multiStatsHandler{compressionHandler{}, &traceHandler{t: t}}),
+		)
+	}
+	if connOpts.Retry.MaxRetries > 0 {
+		interceptor, err := connOpts.Retry.unaryInterceptor()
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid retry policy")
+		}
+		opts = append(opts, grpc.WithUnaryInterceptor(interceptor))
+	}
+	opts = append(opts, connOpts.dialOptions()...)
 	if !useTLS {
 		opts = append(opts, grpc.WithInsecure())
 	} else { // Enable TLS authentication
-		var tlsCfg tls.Config
-		if cacert != "" {
-			b, err := ioutil.ReadFile(cacert)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to read the CA certificate")
-			}
-			cp := x509.NewCertPool()
-			if !cp.AppendCertsFromPEM(b) {
-				return nil, errors.New("failed to append the client certificate")
-			}
-			tlsCfg.RootCAs = cp
-		}
-		if cert != "" && certKey != "" {
-			// Enable mutual authentication
-			certificate, err := tls.LoadX509KeyPair(cert, certKey)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to read the client certificate")
-			}
-			tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
-		} else if cert != "" || certKey != "" {
-			return nil, ErrMutualAuthParamsAreNotEnough
+		tlsCfg, err := newTLSConfig(cacert, cert, certKey)
+		if err != nil {
+			return nil, err
 		}
 
-		creds := credentials.NewTLS(&tlsCfg)
+		creds := credentials.NewTLS(tlsCfg)
 		if serverName != "" {
 			if err := creds.OverrideServerName(serverName); err != nil {
 				return nil, errors.Wrapf(err, "failed to override the server name by '%s'", serverName)
 			}
 		}
+		if t != nil {
+			creds = &traceCredentials{TransportCredentials: creds, t: t}
+		}
 		opts = append(opts, grpc.WithTransportCredentials(creds))
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 7*time.Second)
+	if connOpts.XDSBootstrap != "" {
+		if err := setXDSBootstrap(connOpts.XDSBootstrap); err != nil {
+			return nil, err
+		}
+	}
+	target, resolverOpts, err := resolveTarget(addr, connOpts.LoadBalancingPolicy)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, resolverOpts...)
+	timeout := 7 * time.Second
+	if connOpts.ConnectTimeout > 0 {
+		timeout = connOpts.ConnectTimeout
+		opts = append(opts, grpc.WithBlock())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	conn, err := grpc.DialContext(ctx, addr, opts...)
+	conn, err := grpc.DialContext(ctx, target, opts...)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, errors.Errorf("couldn't connect to the server '%s' within the connect timeout %s", addr, timeout)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to dial to gRPC server")
 	}
@@ -169,6 +313,34 @@ func NewClient(addr, serverName string, useReflection, useTLS bool, cacert, cert
 	return client, nil
 }
 
+// newTLSConfig returns a TLS config which verifies the server by cacert.
+// If both of cert and certKey are not empty, the config enables mutual authentication.
+func newTLSConfig(cacert, cert, certKey string) (*tls.Config, error) {
+	var tlsCfg tls.Config
+	if cacert != "" {
+		b, err := ioutil.ReadFile(cacert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the CA certificate")
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(b) {
+			return nil, errors.New("failed to append the client certificate")
+		}
+		tlsCfg.RootCAs = cp
+	}
+	if cert != "" && certKey != "" {
+		// Enable mutual authentication
+		certificate, err := tls.LoadX509KeyPair(cert, certKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the client certificate")
+		}
+		tlsCfg.Certificates = append(tlsCfg.Certificates, certificate)
+	} else if cert != "" || certKey != "" {
+		return nil, ErrMutualAuthParamsAreNotEnough
+	}
+	return &tlsCfg, nil
+}
+
 func (c *client) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
 	logger.Scriptln(func() []interface{} {
 		md, ok := metadata.FromOutgoingContext(ctx)
@@ -184,9 +356,24 @@ func (c *client) Invoke(ctx context.Context, fqrn string, req, res interface{})
 	}
 	loggingRequest(req)
 	wakeUpClientConn(c.conn)
+	ctx, cr := withCompressionRecorder(ctx)
 	opts := []grpc.CallOption{grpc.Header(&header), grpc.Trailer(&trailer)}
 	err = c.conn.Invoke(ctx, endpoint, req, res, opts...)
-	return header, trailer, err
+	return cr.addTo(header), trailer, c.connectionError(err)
+}
+
+// connectionError returns a ConnectionError if err is caused by the connection which isn't ready.
+// Otherwise, it returns err as it is.
+func (c *client) connectionError(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+	default:
+		return err
+	}
+	if state := c.conn.GetState(); state != connectivity.Ready {
+		return &ConnectionError{Target: c.conn.Target(), State: state, Err: err}
+	}
+	return err
 }
 
 func (c *client) Close(ctx context.Context) error {
@@ -216,10 +403,15 @@ func (c *client) Header() Headers {
 
 type clientStream struct {
 	cs grpc.ClientStream
+	cr *compressionRecorder
 }
 
 func (s *clientStream) Header() (metadata.MD, error) {
-	return s.cs.Header()
+	md, err := s.cs.Header()
+	if err != nil {
+		return md, err
+	}
+	return s.cr.addTo(md), nil
 }
 
 func (s *clientStream) Trailer() metadata.MD {
@@ -249,11 +441,12 @@ func (c *client) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDes
 		return nil, errors.Wrap(err, "failed to convert fqrn to endpoint")
 	}
 	wakeUpClientConn(c.conn)
+	ctx, cr := withCompressionRecorder(ctx)
 	cs, err := c.conn.NewStream(ctx, streamDesc, endpoint)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to instantiate gRPC stream")
+		return nil, errors.Wrap(c.connectionError(err), "failed to instantiate gRPC stream")
 	}
-	return &clientStream{cs}, nil
+	return &clientStream{cs: cs, cr: cr}, nil
 }
 
 type serverStream struct {
@@ -1,8 +1,15 @@
 package grpc
 
 import (
+	"context"
+	"errors"
+	"net"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
 )
 
 func Test_fqrnToEndpoint(t *testing.T) {
@@ -46,7 +53,7 @@ func TestNewClient(t *testing.T) {
 	for name, c := range cases {
 		c := c
 		t.Run(name, func(t *testing.T) {
-			_, err := NewClient(c.addr, "", c.useReflection, c.useTLS, c.cacert, c.cert, c.certKey)
+			_, err := NewClient(c.addr, "", c.useReflection, c.useTLS, c.cacert, c.cert, c.certKey, "", ConnOptions{})
 			if c.err != nil {
 				if err == nil {
 					t.Fatalf("NewClient must return an error, but got nil")
@@ -68,3 +75,85 @@ func TestNewClient(t *testing.T) {
 		})
 	}
 }
+
+func TestConnOptions_dialOptions(t *testing.T) {
+	cases := map[string]struct {
+		opts     ConnOptions
+		expected int
+	}{
+		"zero value":                     {expected: 0},
+		"keepalive timeout without time": {opts: ConnOptions{KeepaliveTimeout: time.Second}, expected: 0},
+		"keepalive":                      {opts: ConnOptions{KeepaliveTime: 30 * time.Second, KeepalivePermitWithoutStream: true}, expected: 1},
+		"all": {
+			opts: ConnOptions{
+				KeepaliveTime:         30 * time.Second,
+				KeepaliveTimeout:      5 * time.Second,
+				InitialWindowSize:     1 << 20,
+				InitialConnWindowSize: 1 << 20,
+				MaxHeaderListSize:     1 << 16,
+			},
+			expected: 4,
+		},
+		"call options are a single dial option": {
+			opts:     ConnOptions{MaxRecvMsgSize: 16 << 20, MaxSendMsgSize: 16 << 20, Compression: "gzip"},
+			expected: 1,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if n := len(c.opts.dialOptions()); n != c.expected {
+				t.Errorf("expected %d dial options, but got %d", c.expected, n)
+			}
+		})
+	}
+}
+
+func TestNewClient_connectTimeout(t *testing.T) {
+	addr := unusedAddr(t)
+	_, err := NewClient(addr, "", false, false, "", "", "", "", ConnOptions{ConnectTimeout: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatalf("NewClient must return an error, but got nil")
+	}
+	if !strings.Contains(err.Error(), "couldn't connect to the server") {
+		t.Errorf("expected a connection error, but got '%s'", err)
+	}
+}
+
+func TestClient_Invoke_connectionError(t *testing.T) {
+	cases := map[string]struct {
+		waitForReady bool
+	}{
+		"fail fast":      {},
+		"wait for ready": {waitForReady: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(unusedAddr(t), "", false, false, "", "", "", "", ConnOptions{WaitForReady: c.waitForReady})
+			if err != nil {
+				t.Fatalf("NewClient must not return an error, but got '%s'", err)
+			}
+			defer client.Close(context.Background())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			_, _, err = client.Invoke(ctx, "api.Example.Unary", &empty.Empty{}, &empty.Empty{})
+			var cerr *ConnectionError
+			if !errors.As(err, &cerr) {
+				t.Errorf("expected a ConnectionError, but got '%v'", err)
+			}
+		})
+	}
+}
+
+// unusedAddr returns an address which no servers listen on.
+func unusedAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
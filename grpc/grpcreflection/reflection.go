@@ -8,16 +8,24 @@ import (
 
 	"github.com/jhump/protoreflect/desc"
 	gr "github.com/jhump/protoreflect/grpcreflect"
+	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/grpc-web-go-client/grpcweb"
-	"github.com/ktr0731/grpc-web-go-client/grpcweb/grpcweb_reflection_v1alpha"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
 )
 
-// ServiceName represents the gRPC reflection service name.
-const ServiceName = "grpc.reflection.v1alpha.ServerReflection"
+const (
+	// ServiceName represents the gRPC reflection service name (v1alpha).
+	ServiceName = "grpc.reflection.v1alpha.ServerReflection"
+	// ServiceNameV1 represents the gRPC reflection service name (v1).
+	ServiceNameV1 = "grpc.reflection.v1.ServerReflection"
+)
+
+// ServiceNames lists all gRPC reflection service names Evans supports. The order is the same as the negotiation order.
+var ServiceNames = []string{ServiceNameV1, ServiceName}
 
 var ErrTLSHandshakeFailed = errors.New("TLS handshake failed")
 
@@ -27,30 +35,136 @@ type Client interface {
 	// ListPackages returns these errors:
 	//   - ErrTLSHandshakeFailed: TLS misconfig.
 	ListPackages() ([]*desc.FileDescriptor, error)
+	// ListServiceFiles lists file descriptors which define services the gRPC reflection server provides.
+	// It is cheaper than ListPackages because files which define extensions are not resolved.
+	// ListServiceFiles returns the same errors as ListPackages.
+	ListServiceFiles() ([]*desc.FileDescriptor, error)
 	// Reset clears internal states of Client.
 	Reset()
 }
 
 type client struct {
-	client *gr.Client
+	// candidates are reflection clients ordered by the preference.
+	// The first one that the server supports is used as client.
+	candidates []*gr.Client
+	client     *gr.Client
 }
 
 // NewClient returns an instance of gRPC reflection client for gRPC protocol.
+// The client tries to use grpc.reflection.v1 at first. If the server doesn't support it,
+// the client falls back to grpc.reflection.v1alpha.
 func NewClient(conn grpc.ClientConnInterface) Client {
 	return &client{
-		client: gr.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn)),
+		candidates: []*gr.Client{
+			gr.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(&v1ClientConn{conn})),
+			gr.NewClient(context.Background(), grpc_reflection_v1alpha.NewServerReflectionClient(conn)),
+		},
 	}
 }
 
 // NewWebClient returns an instance of gRPC reflection client for gRPC-Web protocol.
+// The negotiation is the same as NewClient.
 func NewWebClient(conn *grpcweb.ClientConn) Client {
 	return &client{
-		client: gr.NewClient(context.Background(), grpcweb_reflection_v1alpha.NewServerReflectionClient(conn)),
+		candidates: []*gr.Client{
+			gr.NewClient(context.Background(), newWebServerReflectionClient(conn, ServiceNameV1)),
+			gr.NewClient(context.Background(), newWebServerReflectionClient(conn, ServiceName)),
+		},
 	}
 }
 
 func (c *client) ListPackages() ([]*desc.FileDescriptor, error) {
-	ssvcs, err := c.client.ListServices()
+	fds, err := c.ListServiceFiles()
+	if err != nil {
+		return nil, err
+	}
+	return append(fds, extensionFiles(c.client, fds)...), nil
+}
+
+func (c *client) ListServiceFiles() ([]*desc.FileDescriptor, error) {
+	ssvcs, err := c.serviceNames()
+	if err != nil {
+		return nil, err
+	}
+
+	fds := make([]*desc.FileDescriptor, 0, len(ssvcs))
+	for _, s := range ssvcs {
+		svc, err := c.client.ResolveService(s)
+		if err != nil {
+			return nil, err
+		}
+		fds = append(fds, svc.GetFile())
+	}
+	return fds, nil
+}
+
+// extensionResolver resolves extensions by gRPC reflection. It is satisfied by *gr.Client.
+type extensionResolver interface {
+	AllExtensionNumbersForType(extendedMessageName string) ([]int32, error)
+	ResolveExtension(extendedType string, extensionID int32) (*desc.FieldDescriptor, error)
+}
+
+// extensionFiles returns files which define extensions of extendable messages used by RPCs of services in fds.
+// Extensions are usually defined in files other than the files of services, so they are fetched separately.
+// Servers may not support listing extensions, so errors are only logged.
+func extensionFiles(r extensionResolver, fds []*desc.FileDescriptor) []*desc.FileDescriptor {
+	var (
+		out     []*desc.FileDescriptor
+		visited = map[string]bool{}
+		files   = map[string]bool{}
+	)
+	for _, fd := range fds {
+		files[fd.GetName()] = true
+	}
+	var walk func(md *desc.MessageDescriptor)
+	walk = func(md *desc.MessageDescriptor) {
+		fqmn := md.GetFullyQualifiedName()
+		if visited[fqmn] {
+			return
+		}
+		visited[fqmn] = true
+		for _, field := range md.GetFields() {
+			if m := field.GetMessageType(); m != nil {
+				walk(m)
+			}
+		}
+		if !md.IsExtendable() {
+			return
+		}
+		nums, err := r.AllExtensionNumbersForType(fqmn)
+		if err != nil {
+			logger.Printf("failed to list extension numbers of '%s': %s", fqmn, err)
+			return
+		}
+		for _, num := range nums {
+			ext, err := r.ResolveExtension(fqmn, num)
+			if err != nil {
+				logger.Printf("failed to resolve extension %d of '%s': %s", num, fqmn, err)
+				continue
+			}
+			if f := ext.GetFile(); !files[f.GetName()] {
+				files[f.GetName()] = true
+				out = append(out, f)
+			}
+			if m := ext.GetMessageType(); m != nil {
+				walk(m)
+			}
+		}
+	}
+	for _, fd := range fds {
+		for _, svc := range fd.GetServices() {
+			for _, m := range svc.GetMethods() {
+				walk(m.GetInputType())
+				walk(m.GetOutputType())
+			}
+		}
+	}
+	return out
+}
+
+// serviceNames lists fully-qualified service names. TLS misconfig is reported as ErrTLSHandshakeFailed.
+func (c *client) serviceNames() ([]string, error) {
+	ssvcs, err := c.listServices()
 	if err != nil {
 		msg := status.Convert(err).Message()
 		// Check whether the error message contains TLS related error.
@@ -63,19 +177,51 @@ func (c *client) ListPackages() ([]*desc.FileDescriptor, error) {
 		}
 		return nil, errors.Wrap(err, "failed to list services from reflecton enabled gRPC server")
 	}
+	return ssvcs, nil
+}
 
-	fds := make([]*desc.FileDescriptor, 0, len(ssvcs))
-	for _, s := range ssvcs {
-		svc, err := c.client.ResolveService(s)
+// listServices lists service names. If the reflection version is not negotiated yet,
+// listServices tries each candidate and uses the first one the server doesn't respond with Unimplemented.
+func (c *client) listServices() ([]string, error) {
+	if c.client != nil {
+		return c.client.ListServices()
+	}
+
+	var lastErr error
+	for i, cand := range c.candidates {
+		svcs, err := cand.ListServices()
+		if status.Code(errors.Cause(err)) == codes.Unimplemented {
+			logger.Printf("%s is not supported by the server: %s", ServiceNames[i], err)
+			cand.Reset()
+			lastErr = err
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
-		fds = append(fds, svc.GetFile())
+		logger.Printf("use %s for gRPC reflection", ServiceNames[i])
+		c.client = cand
+		return svcs, nil
 	}
-
-	return fds, nil
+	return nil, lastErr
 }
 
 func (c *client) Reset() {
-	c.client.Reset()
+	for _, cand := range c.candidates {
+		cand.Reset()
+	}
+}
+
+// v1ClientConn is a grpc.ClientConnInterface that rewrites v1alpha reflection method names to v1 ones.
+// Messages of grpc.reflection.v1 are the same as v1alpha's, so we can reuse v1alpha types for v1.
+type v1ClientConn struct {
+	grpc.ClientConnInterface
+}
+
+func (c *v1ClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.ClientConnInterface.NewStream(ctx, desc, toV1Method(method), opts...)
+}
+
+func toV1Method(method string) string {
+	return strings.Replace(method, "/"+ServiceName+"/", "/"+ServiceNameV1+"/", 1)
 }
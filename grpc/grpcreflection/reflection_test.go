@@ -0,0 +1,118 @@
+package grpcreflection
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/pkg/errors"
+)
+
+// fakeExtensionResolver resolves extensions from the passed files. Messages in unsupported fail to list extensions.
+type fakeExtensionResolver struct {
+	files       []*desc.FileDescriptor
+	unsupported map[string]bool
+}
+
+func (r *fakeExtensionResolver) AllExtensionNumbersForType(name string) ([]int32, error) {
+	if r.unsupported[name] {
+		return nil, errors.New("unsupported")
+	}
+	var nums []int32
+	for _, f := range r.files {
+		for _, ext := range f.GetExtensions() {
+			if ext.GetOwner().GetFullyQualifiedName() == name {
+				nums = append(nums, ext.GetNumber())
+			}
+		}
+	}
+	return nums, nil
+}
+
+func (r *fakeExtensionResolver) ResolveExtension(name string, num int32) (*desc.FieldDescriptor, error) {
+	for _, f := range r.files {
+		for _, ext := range f.GetExtensions() {
+			if ext.GetOwner().GetFullyQualifiedName() == name && ext.GetNumber() == num {
+				return ext, nil
+			}
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func TestExtensionFiles(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto2";
+package api;
+service Service {
+  rpc Get(Request) returns (Response);
+}
+message Request {
+  optional Item item = 1;
+}
+message Item {
+  extensions 100 to 199;
+}
+message Response {
+  extensions 100 to 199;
+}`,
+			"item_ext.proto": `
+syntax = "proto2";
+package api.ext;
+import "api.proto";
+extend api.Item {
+  optional Detail detail = 100;
+}
+message Detail {
+  extensions 100 to 199;
+}`,
+			"detail_ext.proto": `
+syntax = "proto2";
+package api.ext;
+import "item_ext.proto";
+extend api.ext.Detail {
+  optional string detail_note = 100;
+}`,
+			"response_ext.proto": `
+syntax = "proto2";
+package api.ext;
+import "api.proto";
+extend api.Response {
+  optional string note = 100;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto", "item_ext.proto", "detail_ext.proto", "response_ext.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+
+	cases := map[string]struct {
+		unsupported map[string]bool
+		expected    []string
+	}{
+		"extensions of nested messages and extension types are resolved": {
+			expected: []string{"item_ext.proto", "detail_ext.proto", "response_ext.proto"},
+		},
+		"messages which fail to list extensions are skipped": {
+			unsupported: map[string]bool{"api.Item": true},
+			expected:    []string{"response_ext.proto"},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := &fakeExtensionResolver{files: fds, unsupported: c.unsupported}
+			var actual []string
+			for _, f := range extensionFiles(r, fds[:1]) {
+				actual = append(actual, f.GetName())
+			}
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
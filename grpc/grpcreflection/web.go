@@ -0,0 +1,90 @@
+package grpcreflection
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/ktr0731/grpc-web-go-client/grpcweb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	pb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// webServerReflectionClient is almost the same as grpcweb_reflection_v1alpha's one,
+// but it is able to specify the reflection service name for supporting several versions.
+type webServerReflectionClient struct {
+	cc      *grpcweb.ClientConn
+	svcName string
+}
+
+func newWebServerReflectionClient(cc *grpcweb.ClientConn, svcName string) pb.ServerReflectionClient {
+	return &webServerReflectionClient{cc: cc, svcName: svcName}
+}
+
+func (c *webServerReflectionClient) ServerReflectionInfo(ctx context.Context, opts ...grpc.CallOption) (pb.ServerReflection_ServerReflectionInfoClient, error) {
+	if len(opts) != 0 {
+		return nil, errors.New("gRPC-Web reflection client does not support grpc.CallOption")
+	}
+
+	stream, err := c.cc.NewBidiStream(
+		&grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		"/"+c.svcName+"/ServerReflectionInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	return &webServerReflectionInfoClient{ctx: ctx, stream: stream}, nil
+}
+
+type webServerReflectionInfoClient struct {
+	ctx    context.Context
+	stream grpcweb.BidiStream
+
+	// To satisfy pb.ServerReflection_ServerReflectionInfoClient.
+	grpc.ClientStream
+}
+
+func (x *webServerReflectionInfoClient) Send(m *pb.ServerReflectionRequest) error {
+	return x.stream.Send(x.ctx, m)
+}
+
+func (x *webServerReflectionInfoClient) Recv() (*pb.ServerReflectionResponse, error) {
+	var res pb.ServerReflectionResponse
+	if err := x.stream.Receive(x.ctx, &res); err != nil {
+		return nil, x.statusFromHeader(err)
+	}
+	return &res, nil
+}
+
+// statusFromHeader converts err to the status error if the server responded with a trailers-only response.
+// The response is returned when the server doesn't know the reflection service, but grpcweb.BidiStream
+// reports it as io.ErrUnexpectedEOF because the stream is not closed by CloseSend yet.
+func (x *webServerReflectionInfoClient) statusFromHeader(err error) error {
+	if errors.Cause(err) != io.ErrUnexpectedEOF {
+		return err
+	}
+	h, herr := x.stream.Header()
+	if herr != nil {
+		return err
+	}
+	codeStr := h.Get("grpc-status")
+	if len(codeStr) == 0 {
+		return err
+	}
+	code, cerr := strconv.Atoi(codeStr[0])
+	if cerr != nil {
+		return err
+	}
+	var msg string
+	if msgs := h.Get("grpc-message"); len(msgs) != 0 {
+		msg = msgs[0]
+	}
+	return status.Error(codes.Code(code), msg)
+}
+
+func (x *webServerReflectionInfoClient) CloseSend() error {
+	return x.stream.CloseSend()
+}
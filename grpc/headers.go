@@ -1,16 +1,59 @@
 package grpc
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
 	"unicode"
 
+	"github.com/ktr0731/go-shellstring"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
 )
 
+// headerRefPattern matches $$, $(command), ${name} and $name.
+var headerRefPattern = regexp.MustCompile(`\$\$|\$\(([^)]*)\)|\$\{([^}]+)\}|\$([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_]+)*)`)
+
 // Headers represents gRPC headers. A key corresponds to one or more values.
 type Headers map[string][]string
 
 // Add appends a value v to a key k. k must be consisted of other than '-', '_' and '.'.
+// Keys are case-insensitive, so k is normalized to lower case. A key can have two or more values,
+// which are sent as repeated metadata.
+// If k is a binary header which has "-bin" suffix, v must be base64 encoded bytes or "@<file>". See BinaryHeader.
 func (h Headers) Add(k, v string) error {
+	k = strings.ToLower(k)
+	if err := h.validate(k, v); err != nil {
+		return err
+	}
+	h[k] = distinct(append(h[k], v))
+	return nil
+}
+
+// Set replaces values of a key k with vs. The requirements for k and vs are the same as Add.
+func (h Headers) Set(k string, vs ...string) error {
+	k = strings.ToLower(k)
+	for _, v := range vs {
+		if err := h.validate(k, v); err != nil {
+			return err
+		}
+	}
+	h[k] = distinct(vs)
+	return nil
+}
+
+// Remove deletes values corresponds to a key k.
+func (h Headers) Remove(k string) {
+	delete(h, strings.ToLower(k))
+}
+
+func (h Headers) validate(k, v string) error {
 	// If k is already in h, k is valid key name.
 	if _, ok := h[k]; !ok {
 		for _, r := range k {
@@ -19,13 +62,49 @@ func (h Headers) Add(k, v string) error {
 			}
 		}
 	}
-	h[k] = distinct(append(h[k], v))
+	// Values which have references are validated after they are expanded.
+	if IsBinaryHeader(k) && !strings.HasPrefix(v, "@") && !strings.Contains(v, "$") {
+		if _, err := decodeBase64(v); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// Remove deletes values corresponds to a key k.
-func (h Headers) Remove(k string) {
-	delete(h, k)
+// ScopedHeaders is default headers which are sent only with RPCs in the scope. A key is a scope, which is
+// a fully-qualified package, service or RPC name such that "api.Example".
+type ScopedHeaders map[string]Headers
+
+// For returns headers sent with an RPC fqrn. Headers of scopes which contain fqrn are merged into global from
+// the broadest scope, and values of a key replace ones of the same key in broader scopes.
+// global is not modified.
+func (s ScopedHeaders) For(fqrn string, global Headers) Headers {
+	scopes := make([]string, 0, len(s))
+	for scope := range s {
+		if InScope(scope, fqrn) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return global
+	}
+	// A narrower scope is always longer than broader ones which contain it.
+	sort.Slice(scopes, func(i, j int) bool { return len(scopes[i]) < len(scopes[j]) })
+	h := make(Headers, len(global))
+	for k, v := range global {
+		h[k] = v
+	}
+	for _, scope := range scopes {
+		for k, v := range s[scope] {
+			h[k] = v
+		}
+	}
+	return h
+}
+
+// InScope reports whether an RPC fqrn is in scope, which is a fully-qualified package, service or RPC name.
+func InScope(scope, fqrn string) bool {
+	return fqrn == scope || strings.HasPrefix(fqrn, scope+".")
 }
 
 // distinct removes duplicated elements.
@@ -40,3 +119,168 @@ func distinct(s []string) []string {
 	}
 	return newSlice
 }
+
+// ExpandHeader expands references in a header value v. ${name} and $name are replaced with the value lookup returns,
+// and $(command) is replaced with the output of command without trailing newlines. $$ is replaced with $.
+// References lookup doesn't know are left as it is.
+// If lookup is nil, os.LookupEnv is used.
+//
+// Header values are expanded just before each RPC, so secrets such that API keys don't have to be written
+// in config files. Commands are killed if ctx is canceled.
+func ExpandHeader(ctx context.Context, v string, lookup func(name string) (string, bool)) (string, error) {
+	if !strings.Contains(v, "$") {
+		return v, nil
+	}
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var rerr error
+	expanded := headerRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+		if rerr != nil {
+			return ref
+		}
+		if ref == "$$" {
+			return "$"
+		}
+		if strings.HasPrefix(ref, "$(") {
+			out, err := runHeaderCommand(ctx, ref[2:len(ref)-1])
+			if err != nil {
+				rerr = err
+				return ref
+			}
+			return out
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(ref, "$"), "{"), "}")
+		if v, ok := lookup(name); ok {
+			return v
+		}
+		return ref
+	})
+	if rerr != nil {
+		return "", rerr
+	}
+	return expanded, nil
+}
+
+// ExpandHeaders returns a copy of h whose values are expanded by ExpandHeader.
+func ExpandHeaders(ctx context.Context, h Headers, lookup func(name string) (string, bool)) (Headers, error) {
+	expanded := make(Headers, len(h))
+	for k, v := range h {
+		vs := make([]string, 0, len(v))
+		for _, vv := range v {
+			ev, err := ExpandHeader(ctx, vv, lookup)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid header '%s'", k)
+			}
+			vs = append(vs, ev)
+		}
+		expanded[k] = vs
+	}
+	return expanded, nil
+}
+
+// Expand returns a copy of s whose values are expanded by ExpandHeader.
+func (s ScopedHeaders) Expand(ctx context.Context, lookup func(name string) (string, bool)) (ScopedHeaders, error) {
+	expanded := make(ScopedHeaders, len(s))
+	for scope, h := range s {
+		eh, err := ExpandHeaders(ctx, h, lookup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid scoped headers of '%s'", scope)
+		}
+		expanded[scope] = eh
+	}
+	return expanded, nil
+}
+
+// ResolveHeader returns the value of a header k sent actually. References in v are expanded by ExpandHeader,
+// then v is decoded by BinaryHeader if k is a binary header.
+func ResolveHeader(ctx context.Context, k, v string, lookup func(name string) (string, bool)) (string, error) {
+	v, err := ExpandHeader(ctx, v, lookup)
+	if err != nil {
+		return "", err
+	}
+	if !IsBinaryHeader(k) {
+		return v, nil
+	}
+	return BinaryHeader(v)
+}
+
+// IsBinaryHeader reports whether k is a binary header. The value of binary headers is arbitrary bytes.
+func IsBinaryHeader(k string) bool {
+	return strings.HasSuffix(strings.ToLower(k), "-bin")
+}
+
+// BinaryHeader returns the bytes of a binary header value v. v is base64 encoded bytes (padded or unpadded),
+// or "@<file>" which reads the bytes from the file.
+// The returned bytes are base64 encoded by gRPC again when they are sent, as the gRPC metadata spec requires.
+func BinaryHeader(v string) (string, error) {
+	if strings.HasPrefix(v, "@") {
+		b, err := ioutil.ReadFile(v[1:])
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read the binary header value")
+		}
+		return string(b), nil
+	}
+	b, err := decodeBase64(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// httpHeaderValue returns v as the value of an HTTP header k. Binary header values are base64 encoded because
+// HTTP headers cannot have arbitrary bytes. gRPC encodes them by itself, but HTTP based protocols don't.
+func httpHeaderValue(k, v string) string {
+	if !IsBinaryHeader(k) {
+		return v
+	}
+	return base64.StdEncoding.EncodeToString([]byte(v))
+}
+
+// encodeBinaryHeaders returns a new context whose outgoing binary header values are base64 encoded.
+func encodeBinaryHeaders(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	encoded := make(metadata.MD, len(md))
+	for k, v := range md {
+		for _, vv := range v {
+			encoded[k] = append(encoded[k], httpHeaderValue(k, vv))
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, encoded)
+}
+
+func decodeBase64(v string) ([]byte, error) {
+	enc := base64.StdEncoding
+	if len(v)%4 != 0 {
+		enc = base64.RawStdEncoding
+	}
+	b, err := enc.DecodeString(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "binary header value '%s' must be base64 encoded or '@<file>'", v)
+	}
+	return b, nil
+}
+
+// runHeaderCommand runs command and returns its stdout. command is split into arguments like shells.
+func runHeaderCommand(ctx context.Context, command string) (string, error) {
+	args, err := shellstring.Parse(command)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the command '%s'", command)
+	}
+	if len(args) == 0 {
+		return "", errors.New("the command is empty")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrapf(err, "the command '%s' failed: %s", command, msg)
+		}
+		return "", errors.Wrapf(err, "the command '%s' failed", command)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
@@ -1,7 +1,11 @@
 package grpc_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ktr0731/evans/grpc"
@@ -47,3 +51,212 @@ func TestHeaders_Add_distinct(t *testing.T) {
 		t.Errorf("-want, +got\n%s", diff)
 	}
 }
+
+func TestHeaders_Set(t *testing.T) {
+	h := grpc.Headers{}
+	if err := h.Add("Touma", "youko"); err != nil {
+		t.Fatalf("Add must not return an error, but got '%s'", err)
+	}
+	if err := h.Add("touma", "kazusa"); err != nil {
+		t.Fatalf("Add must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(grpc.Headers{"touma": {"youko", "kazusa"}}, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	if err := h.Set("TOUMA", "setsuna", "setsuna"); err != nil {
+		t.Fatalf("Set must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(grpc.Headers{"touma": {"setsuna"}}, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	if err := h.Set("touma/", "setsuna"); err == nil {
+		t.Error("Set must return an error because the key is invalid")
+	}
+
+	h.Remove("Touma")
+	if diff := cmp.Diff(grpc.Headers{}, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func TestExpandHeader(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		v, ok := map[string]string{"API_KEY": "secret", "resp.id": "1"}[name]
+		return v, ok
+	}
+	cases := map[string]struct {
+		in, expected string
+		hasErr       bool
+	}{
+		"no references":      {in: "miyamori", expected: "miyamori"},
+		"braces":             {in: "Bearer ${API_KEY}", expected: "Bearer secret"},
+		"no braces":          {in: "$API_KEY", expected: "secret"},
+		"dotted name":        {in: "$resp.id", expected: "1"},
+		"undefined":          {in: "${UNDEFINED}", expected: "${UNDEFINED}"},
+		"escaped":            {in: "$$API_KEY $$(echo foo)", expected: "$API_KEY $(echo foo)"},
+		"command":            {in: "Bearer $(echo -n foo)-$(printf 'bar\\n\\n')", expected: "Bearer foo-bar"},
+		"command with quote": {in: `$(echo "foo bar")`, expected: "foo bar"},
+		"failed command":     {in: "$(false)", hasErr: true},
+		"empty command":      {in: "$()", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual, err := grpc.ExpandHeader(context.Background(), c.in, lookup)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("ExpandHeader must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandHeader must not return an error, but got '%s'", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpandHeader_env(t *testing.T) {
+	os.Setenv("EVANS_TEST_API_KEY", "secret")
+	defer os.Unsetenv("EVANS_TEST_API_KEY")
+	actual, err := grpc.ExpandHeader(context.Background(), "${EVANS_TEST_API_KEY}", nil)
+	if err != nil {
+		t.Fatalf("ExpandHeader must not return an error, but got '%s'", err)
+	}
+	if actual != "secret" {
+		t.Errorf("expected 'secret', but got '%s'", actual)
+	}
+}
+
+func TestHeaders_Add_binary(t *testing.T) {
+	cases := map[string]struct {
+		v      string
+		hasErr bool
+	}{
+		"base64":          {v: "AAEC/w=="},
+		"unpadded base64": {v: "AAEC/w"},
+		"file":            {v: "@testdata/missing"},
+		"reference":       {v: "${BIN}"},
+		"invalid base64":  {v: "not base64!", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			err := grpc.Headers{}.Add("trace-Bin", c.v)
+			if c.hasErr && err == nil {
+				t.Errorf("Add must return an error, but got nil")
+			}
+			if !c.hasErr && err != nil {
+				t.Errorf("Add must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
+
+func TestResolveHeader(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write([]byte{0, 1, 2, 0xff}); err != nil {
+		t.Fatalf("failed to write to the temp file: %s", err)
+	}
+	f.Close()
+
+	lookup := func(name string) (string, bool) { return "AAEC/w==", name == "BIN" }
+	cases := map[string]struct {
+		k, v     string
+		expected string
+		hasErr   bool
+	}{
+		"text":            {k: "foo", v: "AAEC/w==", expected: "AAEC/w=="},
+		"base64":          {k: "foo-bin", v: "AAEC/w==", expected: "\x00\x01\x02\xff"},
+		"unpadded base64": {k: "foo-bin", v: "AAEC/w", expected: "\x00\x01\x02\xff"},
+		"file":            {k: "foo-bin", v: "@" + f.Name(), expected: "\x00\x01\x02\xff"},
+		"reference":       {k: "foo-bin", v: "${BIN}", expected: "\x00\x01\x02\xff"},
+		"missing file":    {k: "foo-bin", v: "@" + f.Name() + ".missing", hasErr: true},
+		"invalid base64":  {k: "foo-bin", v: "!", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual, err := grpc.ResolveHeader(context.Background(), c.k, c.v, lookup)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("ResolveHeader must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveHeader must not return an error, but got '%s'", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScopedHeaders_For(t *testing.T) {
+	global := grpc.Headers{"x-route": {"default"}, "grpc-client": {"evans"}}
+	scoped := grpc.ScopedHeaders{
+		"api":                 {"x-route": {"api"}},
+		"api.Example":         {"x-route": {"example"}, "x-tenant": {"a", "b"}},
+		"api.Example.Unary":   {"x-route": {"unary"}},
+		"api.ExampleV2":       {"x-route": {"v2"}},
+		"api.v2.Example.Echo": {"x-route": {"echo"}},
+	}
+	cases := map[string]grpc.Headers{
+		"api.Example.Unary":           {"grpc-client": {"evans"}, "x-route": {"unary"}, "x-tenant": {"a", "b"}},
+		"api.Example.Echo":            {"grpc-client": {"evans"}, "x-route": {"example"}, "x-tenant": {"a", "b"}},
+		"api.ExampleV2.Unary":         {"grpc-client": {"evans"}, "x-route": {"v2"}},
+		"api.Other.Unary":             {"grpc-client": {"evans"}, "x-route": {"api"}},
+		"apiv2.Example.Unary":         {"grpc-client": {"evans"}, "x-route": {"default"}},
+		"grpc.health.v1.Health.Check": {"grpc-client": {"evans"}, "x-route": {"default"}},
+	}
+	for fqrn, expected := range cases {
+		if diff := cmp.Diff(expected, scoped.For(fqrn, global)); diff != "" {
+			t.Errorf("%s: -want, +got\n%s", fqrn, diff)
+		}
+	}
+	if diff := cmp.Diff(grpc.Headers{"x-route": {"default"}, "grpc-client": {"evans"}}, global); diff != "" {
+		t.Errorf("global headers must not be modified: -want, +got\n%s", diff)
+	}
+}
+
+func TestScopedHeaders_Expand(t *testing.T) {
+	scoped := grpc.ScopedHeaders{"api.Example": {"authorization": {"Bearer $(echo -n token)"}, "x-route": {"$$example"}}}
+	actual, err := scoped.Expand(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Expand must not return an error, but got '%s'", err)
+	}
+	expected := grpc.ScopedHeaders{"api.Example": {"authorization": {"Bearer token"}, "x-route": {"$example"}}}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	if diff := cmp.Diff("Bearer $(echo -n token)", scoped["api.Example"]["authorization"][0]); diff != "" {
+		t.Errorf("the original headers must not be modified: -want, +got\n%s", diff)
+	}
+
+	if _, err := (grpc.ScopedHeaders{"api": {"authorization": {"$(false)"}}}).Expand(context.Background(), nil); err == nil {
+		t.Error("Expand must return an error if a command fails")
+	}
+}
+
+func TestExpandHeader_canceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := grpc.ExpandHeader(ctx, "$(sleep 10)", nil); err == nil {
+		t.Error("ExpandHeader must return an error if the context is canceled")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("the command must be killed by canceling the context, but it took %s", d)
+	}
+}
@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+const (
+	// LoadBalancingPickFirst connects to the first reachable address and sends all RPCs to it.
+	LoadBalancingPickFirst = "pick_first"
+	// LoadBalancingRoundRobin connects to all addresses and distributes RPCs to them in turn.
+	LoadBalancingRoundRobin = "round_robin"
+)
+
+// multiAddrScheme is the resolver scheme for multiple addresses given by users.
+const multiAddrScheme = "evans"
+
+// resolveTarget returns the dial target for addr and the dial options to resolve it.
+// addr is a comma-separated list of addresses. If it contains two or more addresses, all of them are passed
+// to the load balancer. If it is a single address and lbPolicy is round_robin, it is resolved by DNS
+// so that all A records are used. xDS targets are returned as it is.
+func resolveTarget(addr, lbPolicy string) (string, []grpc.DialOption, error) {
+	if strings.Contains(addr, "xds:///") {
+		if strings.Contains(addr, ",") {
+			return "", nil, errors.New("xDS target cannot be used with other addresses")
+		}
+		return addr, nil, nil
+	}
+
+	var opts []grpc.DialOption
+	if lbPolicy != "" && lbPolicy != LoadBalancingPickFirst {
+		if balancer.Get(lbPolicy) == nil {
+			return "", nil, errors.Errorf("unknown load balancing policy '%s'", lbPolicy)
+		}
+		opts = append(opts, grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingPolicy": %q}`, lbPolicy)))
+	}
+
+	addrs := strings.Split(addr, ",")
+	if len(addrs) == 1 {
+		if lbPolicy == LoadBalancingRoundRobin && !strings.Contains(addr, ":///") {
+			return "dns:///" + addr, opts, nil
+		}
+		return addr, opts, nil
+	}
+
+	state := resolver.State{Addresses: make([]resolver.Address, 0, len(addrs))}
+	for _, a := range addrs {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			return "", nil, errors.Errorf("empty address in '%s'", addr)
+		}
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: a})
+	}
+	r := manual.NewBuilderWithScheme(multiAddrScheme)
+	r.InitialState(state)
+	// The first address is used as the authority.
+	return fmt.Sprintf("%s:///%s", multiAddrScheme, state.Addresses[0].Addr), append(opts, grpc.WithResolvers(r)), nil
+}
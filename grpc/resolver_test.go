@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startIDServer starts a gRPC server which returns id as the error message of any RPCs.
+func startIDServer(t *testing.T, id string) (addr string, stop func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(interface{}, grpc.ServerStream) error {
+		return status.Error(codes.Unknown, id)
+	}))
+	go srv.Serve(l) //nolint:errcheck
+	return l.Addr().String(), srv.Stop
+}
+
+func TestNewClient_loadBalancing(t *testing.T) {
+	addr1, stop1 := startIDServer(t, "1")
+	defer stop1()
+	addr2, stop2 := startIDServer(t, "2")
+	defer stop2()
+
+	cases := map[string]struct {
+		policy   string
+		expected map[string]bool
+	}{
+		"pick_first":  {policy: LoadBalancingPickFirst, expected: map[string]bool{"1": true}},
+		"round_robin": {policy: LoadBalancingRoundRobin, expected: map[string]bool{"1": true, "2": true}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(addr1+","+addr2, "", false, false, "", "", "", "", ConnOptions{LoadBalancingPolicy: c.policy, WaitForReady: true})
+			if err != nil {
+				t.Fatalf("NewClient must not return an error, but got '%s'", err)
+			}
+			defer client.Close(context.Background())
+
+			actual := map[string]bool{}
+			for i := 0; i < 10; i++ {
+				_, _, err := client.Invoke(context.Background(), "api.Example.Unary", &empty.Empty{}, &empty.Empty{})
+				actual[status.Convert(err).Message()] = true
+			}
+			if len(actual) != len(c.expected) {
+				t.Errorf("expected RPCs are sent to %v, but got %v", c.expected, actual)
+			}
+			for id := range c.expected {
+				if !actual[id] {
+					t.Errorf("expected RPCs are sent to %v, but got %v", c.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func Test_resolveTarget(t *testing.T) {
+	cases := map[string]struct {
+		addr, policy string
+		expected     string
+		hasErr       bool
+	}{
+		"single address":                  {addr: "localhost:50051", expected: "localhost:50051"},
+		"single address with round robin": {addr: "svc.local:50051", policy: LoadBalancingRoundRobin, expected: "dns:///svc.local:50051"},
+		"multiple addresses":              {addr: "10.0.0.1:50051,10.0.0.2:50051", expected: "evans:///10.0.0.1:50051"},
+		"empty address":                   {addr: "10.0.0.1:50051,", hasErr: true},
+		"xDS target":                      {addr: "xds:///example", policy: LoadBalancingRoundRobin, expected: "xds:///example"},
+		"xDS target with other addresses": {addr: "xds:///example,10.0.0.1:50051", hasErr: true},
+		"unknown policy":                  {addr: "localhost:50051", policy: "random", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			target, _, err := resolveTarget(c.addr, c.policy)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("resolveTarget must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveTarget must not return an error, but got '%s'", err)
+			}
+			if target != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, target)
+			}
+		})
+	}
+}
+
+func TestNewClient_xdsBootstrap(t *testing.T) {
+	_, err := NewClient("xds:///example", "", false, false, "", "", "", "", ConnOptions{XDSBootstrap: "not-found.json"})
+	if err == nil {
+		t.Errorf("NewClient must return an error, but got nil")
+	}
+}
@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRetryCodes is the status codes retried if RetryPolicy.Codes is empty.
+var DefaultRetryCodes = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"}
+
+const (
+	retryBackoffMultiplier = 2
+	retryJitter            = 0.2
+)
+
+// RetryPolicy is the policy to retry failed unary RPCs.
+// The backoff before the n-th retry is InitialBackoff * 2^(n-1), capped by MaxBackoff, and randomized by ±20% jitter.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper limit of backoffs. Zero means no limit.
+	MaxBackoff time.Duration
+	// Codes is the status code names such that "UNAVAILABLE" to be retried.
+	// If it is empty, DefaultRetryCodes is used.
+	Codes []string
+}
+
+// ParseCode parses a status code name such that "UNAVAILABLE", "Unavailable" or "unavailable".
+// A number such that "14" is also accepted.
+func ParseCode(name string) (codes.Code, error) {
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil && n <= uint64(codes.Unauthenticated) {
+		return codes.Code(n), nil
+	}
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		n := c.String()
+		if strings.EqualFold(n, name) || strings.EqualFold(toSnakeCase(n), name) {
+			return c, nil
+		}
+	}
+	return 0, errors.Errorf("unknown status code '%s'", name)
+}
+
+// toSnakeCase converts a camel-cased code name such that "ResourceExhausted" to "Resource_Exhausted".
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && 'A' <= r && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unaryInterceptor returns an interceptor which retries unary RPCs according to p.
+func (p RetryPolicy) unaryInterceptor() (grpc.UnaryClientInterceptor, error) {
+	names := p.Codes
+	if len(names) == 0 {
+		names = DefaultRetryCodes
+	}
+	retryable := make(map[codes.Code]bool, len(names))
+	for _, n := range names {
+		c, err := ParseCode(n)
+		if err != nil {
+			return nil, err
+		}
+		retryable[c] = true
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for n := 1; ; n++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			code := status.Code(err)
+			if err == nil || !retryable[code] || n > p.MaxRetries {
+				return err
+			}
+			backoff := p.backoff(n)
+			logger.Printf("retrying RPC '%s' (%d/%d) in %s because of %s: %s", method, n, p.MaxRetries, backoff, code, err)
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+		}
+	}, nil
+}
+
+// backoff returns the backoff before the n-th retry.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < n; i++ {
+		d *= retryBackoffMultiplier
+		if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	return time.Duration(d * (1 + retryJitter*(rand.Float64()*2-1))) //nolint:gosec
+}
@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseCode(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected codes.Code
+		hasErr   bool
+	}{
+		"upper snake case": {name: "RESOURCE_EXHAUSTED", expected: codes.ResourceExhausted},
+		"camel case":       {name: "ResourceExhausted", expected: codes.ResourceExhausted},
+		"lower case":       {name: "unavailable", expected: codes.Unavailable},
+		"OK":               {name: "OK", expected: codes.OK},
+		"number":           {name: "14", expected: codes.Unavailable},
+		"unknown":          {name: "foo", hasErr: true},
+		"unknown number":   {name: "17", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			code, err := ParseCode(c.name)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("ParseCode must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCode must not return an error, but got '%s'", err)
+			}
+			if code != c.expected {
+				t.Errorf("expected %s, but got %s", c.expected, code)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_unaryInterceptor(t *testing.T) {
+	cases := map[string]struct {
+		policy   RetryPolicy
+		errs     []error
+		attempts int
+		code     codes.Code
+	}{
+		"succeeded after retries": {
+			policy:   RetryPolicy{MaxRetries: 3},
+			errs:     []error{status.Error(codes.Unavailable, ""), status.Error(codes.ResourceExhausted, ""), nil},
+			attempts: 3,
+			code:     codes.OK,
+		},
+		"retries are exhausted": {
+			policy:   RetryPolicy{MaxRetries: 1},
+			errs:     []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), nil},
+			attempts: 2,
+			code:     codes.Unavailable,
+		},
+		"not retryable code": {
+			policy:   RetryPolicy{MaxRetries: 3},
+			errs:     []error{status.Error(codes.InvalidArgument, ""), nil},
+			attempts: 1,
+			code:     codes.InvalidArgument,
+		},
+		"custom codes": {
+			policy:   RetryPolicy{MaxRetries: 3, Codes: []string{"aborted"}},
+			errs:     []error{status.Error(codes.Aborted, ""), status.Error(codes.Unavailable, ""), nil},
+			attempts: 2,
+			code:     codes.Unavailable,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			c.policy.InitialBackoff = time.Millisecond
+			interceptor, err := c.policy.unaryInterceptor()
+			if err != nil {
+				t.Fatalf("unaryInterceptor must not return an error, but got '%s'", err)
+			}
+			var attempts int
+			invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				err := c.errs[attempts]
+				attempts++
+				return err
+			}
+			err = interceptor(context.Background(), "/api.Example/Unary", nil, nil, nil, invoker)
+			if code := status.Code(err); code != c.code {
+				t.Errorf("expected code %s, but got %s", c.code, code)
+			}
+			if attempts != c.attempts {
+				t.Errorf("expected %d attempts, but got %d", c.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	cases := map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	}
+	for n, expected := range cases {
+		min, max := time.Duration(float64(expected)*0.8), time.Duration(float64(expected)*1.2)
+		if d := p.backoff(n); d < min || d > max {
+			t.Errorf("expected the backoff of the %d-th retry is between %s and %s, but got %s", n, min, max, d)
+		}
+	}
+}
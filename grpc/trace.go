@@ -0,0 +1,210 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// tracer writes the wire-level trace of connections and RPCs like curl -v.
+// Each line is prefixed by the timestamp and a marker: "*" for events, ">" for sent data and "<" for received data.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+}
+
+func newTracer(w io.Writer) *tracer {
+	return &tracer{w: w, now: time.Now}
+}
+
+func (t *tracer) printf(marker string, format string, a ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s %s %s\n", t.now().Format("15:04:05.000000"), marker, fmt.Sprintf(format, a...))
+}
+
+// printMetadata prints each pair of md sorted by keys. Binary header values are printed as base64 like on the wire.
+func (t *tracer) printMetadata(marker string, md metadata.MD) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			t.printf(marker, "%s: %s", k, httpHeaderValue(k, v))
+		}
+	}
+}
+
+// dialer wraps dial to trace DNS resolution and connection establishment.
+// Host names dialed through proxies are resolved by the proxies, so they are not traced.
+func (t *tracer) dialer(d *dialer) func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if u, err := d.proxy(addr); err == nil && u != nil {
+			t.printf("*", "Connecting to %s through the proxy %s", addr, u.Host)
+		} else if host, _, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+			start := t.now()
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				t.printf("*", "Could not resolve host %s: %s", host, err)
+			} else {
+				t.printf("*", "Resolved %s to %s in %s", host, strings.Join(addrs, ", "), t.now().Sub(start))
+			}
+		}
+		start := t.now()
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			t.printf("*", "Failed to connect to %s: %s", addr, err)
+			return nil, err
+		}
+		t.printf("*", "Connected to %s (%s) from %s in %s", addr, conn.RemoteAddr(), conn.LocalAddr(), t.now().Sub(start))
+		return conn, nil
+	}
+}
+
+// traceCredentials is a credentials.TransportCredentials which traces TLS handshakes.
+type traceCredentials struct {
+	credentials.TransportCredentials
+	t *tracer
+}
+
+func (c *traceCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	c.t.printf("*", "TLS handshake with %s", authority)
+	start := c.t.now()
+	conn, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		c.t.printf("*", "TLS handshake failed: %s", err)
+		return conn, info, err
+	}
+	c.t.printf("*", "TLS handshake completed in %s", c.t.now().Sub(start))
+	if tlsInfo, ok := info.(credentials.TLSInfo); ok {
+		c.printState(tlsInfo.State)
+	}
+	return conn, info, nil
+}
+
+func (c *traceCredentials) printState(s tls.ConnectionState) {
+	c.t.printf("*", "%s, cipher suite 0x%04x, ALPN: %s", tlsVersionName(s.Version), s.CipherSuite, s.NegotiatedProtocol)
+	for i, cert := range s.PeerCertificates {
+		c.t.printf("*", "Server certificate #%d:", i)
+		c.t.printf("*", "  subject: %s", cert.Subject)
+		c.t.printf("*", "  issuer: %s", cert.Issuer)
+		c.t.printf("*", "  validity: %s - %s", cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		if len(cert.DNSNames) != 0 {
+			c.t.printf("*", "  DNS names: %s", strings.Join(cert.DNSNames, ", "))
+		}
+	}
+}
+
+func (c *traceCredentials) Clone() credentials.TransportCredentials {
+	return &traceCredentials{TransportCredentials: c.TransportCredentials.Clone(), t: c.t}
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLSv1.0"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	default:
+		return fmt.Sprintf("TLS 0x%04x", v)
+	}
+}
+
+// traceHandler is a stats.Handler which traces headers, messages and trailers of RPCs.
+type traceHandler struct {
+	t *tracer
+}
+
+func (h *traceHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *traceHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if !s.IsClient() {
+		return
+	}
+	switch s := s.(type) {
+	case *stats.OutHeader:
+		h.t.printf(">", "POST %s", s.FullMethod)
+		if s.Compression != "" {
+			h.t.printf(">", "%s: %s", compressionHeaderKey, s.Compression)
+		}
+		h.t.printMetadata(">", s.Header)
+	case *stats.OutPayload:
+		h.t.printf(">", "[message: %d bytes, %d bytes on the wire]", s.Length, s.WireLength)
+	case *stats.InHeader:
+		h.t.printf("<", "[header: %d bytes on the wire]", s.WireLength)
+		h.t.printMetadata("<", s.Header)
+	case *stats.InPayload:
+		h.t.printf("<", "[message: %d bytes, %d bytes on the wire]", s.Length, s.WireLength)
+	case *stats.InTrailer:
+		h.t.printf("<", "[trailer: %d bytes on the wire]", s.WireLength)
+		h.t.printMetadata("<", s.Trailer)
+	case *stats.End:
+		stat := status.Convert(s.Error)
+		if stat.Message() == "" {
+			h.t.printf("*", "RPC completed in %s: %s", s.EndTime.Sub(s.BeginTime), stat.Code())
+			return
+		}
+		h.t.printf("*", "RPC completed in %s: %s (%s)", s.EndTime.Sub(s.BeginTime), stat.Code(), stat.Message())
+	}
+}
+
+func (h *traceHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *traceHandler) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnEnd); ok && s.IsClient() {
+		h.t.printf("*", "Connection closed")
+	}
+}
+
+// multiStatsHandler is a stats.Handler which calls all handlers in order.
+// gRPC accepts only one stats.Handler for each connection.
+type multiStatsHandler []stats.Handler
+
+func (hs multiStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	for _, h := range hs {
+		ctx = h.TagRPC(ctx, info)
+	}
+	return ctx
+}
+
+func (hs multiStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	for _, h := range hs {
+		h.HandleRPC(ctx, s)
+	}
+}
+
+func (hs multiStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	for _, h := range hs {
+		ctx = h.TagConn(ctx, info)
+	}
+	return ctx
+}
+
+func (hs multiStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	for _, h := range hs {
+		h.HandleConn(ctx, s)
+	}
+}
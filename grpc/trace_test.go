@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+func TestTraceHandler(t *testing.T) {
+	var buf bytes.Buffer
+	tr := newTracer(&buf)
+	tr.now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC) }
+	h := &traceHandler{t: tr}
+
+	begin := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, s := range []stats.RPCStats{
+		&stats.Begin{Client: true, BeginTime: begin},
+		&stats.OutHeader{Client: true, FullMethod: "/api.Example/Unary", Header: metadata.Pairs("grpc-client", "evans", "authorization", "Bearer token")},
+		&stats.OutPayload{Client: true, Length: 8, WireLength: 13},
+		&stats.InHeader{Client: true, WireLength: 32, Header: metadata.Pairs("content-type", "application/grpc")},
+		&stats.InPayload{Client: true, Length: 15, WireLength: 20},
+		&stats.InTrailer{Client: true, WireLength: 24, Trailer: metadata.Pairs("foo", "bar", "foo-bin", "\x00\x01\x02\xff")},
+		&stats.End{Client: true, BeginTime: begin, EndTime: begin.Add(1500 * time.Microsecond), Error: status.Error(codes.NotFound, "not found")},
+		// Server-side stats are ignored.
+		&stats.InHeader{Client: false, FullMethod: "/api.Example/Unary"},
+	} {
+		h.HandleRPC(context.Background(), s)
+	}
+	h.HandleConn(context.Background(), &stats.ConnEnd{Client: true})
+
+	expected := `03:04:05.000006 > POST /api.Example/Unary
+03:04:05.000006 > authorization: Bearer token
+03:04:05.000006 > grpc-client: evans
+03:04:05.000006 > [message: 8 bytes, 13 bytes on the wire]
+03:04:05.000006 < [header: 32 bytes on the wire]
+03:04:05.000006 < content-type: application/grpc
+03:04:05.000006 < [message: 15 bytes, 20 bytes on the wire]
+03:04:05.000006 < [trailer: 24 bytes on the wire]
+03:04:05.000006 < foo: bar
+03:04:05.000006 < foo-bin: AAEC/w==
+03:04:05.000006 * RPC completed in 1.5ms: NotFound (not found)
+03:04:05.000006 * Connection closed
+`
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func TestNewClient_trace(t *testing.T) {
+	var buf bytes.Buffer
+	client, err := NewClient("localhost:50051", "", false, false, "", "", "", "", ConnOptions{Trace: &buf})
+	if err != nil {
+		t.Fatalf("NewClient must not return an error, but got '%s'", err)
+	}
+	defer client.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	client.Invoke(ctx, "api.Example.Unary", nil, nil) //nolint:errcheck
+
+	if !bytes.Contains(buf.Bytes(), []byte("* Resolved localhost to ")) {
+		t.Errorf("the trace must contain DNS resolution, but got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,153 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ktr0731/evans/tracing"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tracingClient struct {
+	Client
+
+	tracer *tracing.Tracer
+}
+
+// NewTracingClient returns a client which sends the W3C traceparent header with every RPC sent via client.
+// A client span is started by tracer for each RPC, and it is finished when the RPC finishes.
+// RPCs which already have the traceparent header, such that specified by --header, are sent as they are.
+func NewTracingClient(client Client, tracer *tracing.Tracer) Client {
+	return &tracingClient{Client: client, tracer: tracer}
+}
+
+// start starts a span of the RPC and returns the context which has the traceparent header.
+// It returns nil as the span if ctx already has the header.
+func (c *tracingClient) start(ctx context.Context, fqrn string) (context.Context, *tracingSpan) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(tracing.TraceparentKey)) != 0 {
+		return ctx, nil
+	}
+	name := fqrn
+	var svc, mtd string
+	if i := strings.LastIndex(fqrn, "."); i != -1 {
+		svc, mtd = fqrn[:i], fqrn[i+1:]
+		name = svc + "/" + mtd
+	}
+	s := c.tracer.Start(name)
+	s.Attributes["rpc.system"] = "grpc"
+	s.Attributes["rpc.service"] = svc
+	s.Attributes["rpc.method"] = mtd
+	return metadata.AppendToOutgoingContext(ctx, tracing.TraceparentKey, s.Context.Traceparent()), &tracingSpan{t: c.tracer, s: s}
+}
+
+func (c *tracingClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	ctx, span := c.start(ctx, fqrn)
+	header, trailer, err := c.Client.Invoke(ctx, fqrn, req, res)
+	span.finish(err)
+	return header, trailer, err
+}
+
+func (c *tracingClient) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	ctx, span := c.start(ctx, fqrn)
+	s, err := c.Client.NewClientStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		span.finish(err)
+		return nil, err
+	}
+	return &tracingClientStream{ClientStream: s, span: span}, nil
+}
+
+func (c *tracingClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	ctx, span := c.start(ctx, fqrn)
+	s, err := c.Client.NewServerStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		span.finish(err)
+		return nil, err
+	}
+	return &tracingServerStream{ServerStream: s, span: span}, nil
+}
+
+func (c *tracingClient) NewBidiStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	ctx, span := c.start(ctx, fqrn)
+	s, err := c.Client.NewBidiStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		span.finish(err)
+		return nil, err
+	}
+	return &tracingBidiStream{BidiStream: s, span: span}, nil
+}
+
+// Close waits for spans being exported, and closes the client.
+func (c *tracingClient) Close(ctx context.Context) error {
+	if err := c.tracer.Close(ctx); err != nil {
+		return err
+	}
+	return c.Client.Close(ctx)
+}
+
+// tracingSpan finishes the span only once. A nil *tracingSpan does nothing.
+type tracingSpan struct {
+	t    *tracing.Tracer
+	s    *tracing.Span
+	once sync.Once
+}
+
+// finish finishes the span with the status represented by err. err is nil or io.EOF if the RPC succeeded.
+func (s *tracingSpan) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		stat, ok := status.FromError(errors.Cause(err))
+		if !ok {
+			stat = status.New(codes.Unknown, err.Error())
+		}
+		s.t.Finish(s.s, stat.Code(), stat.Message())
+	})
+}
+
+type tracingClientStream struct {
+	ClientStream
+	span *tracingSpan
+}
+
+func (s *tracingClientStream) CloseAndReceive(res interface{}) error {
+	err := s.ClientStream.CloseAndReceive(res)
+	s.span.finish(err)
+	return err
+}
+
+type tracingServerStream struct {
+	ServerStream
+	span *tracingSpan
+}
+
+func (s *tracingServerStream) Receive(res interface{}) error {
+	err := s.ServerStream.Receive(res)
+	if err != nil {
+		s.span.finish(err)
+	}
+	return err
+}
+
+type tracingBidiStream struct {
+	BidiStream
+	span *tracingSpan
+}
+
+func (s *tracingBidiStream) Receive(res interface{}) error {
+	err := s.BidiStream.Receive(res)
+	if err != nil {
+		s.span.finish(err)
+	}
+	return err
+}
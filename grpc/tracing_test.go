@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ktr0731/evans/tracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerClient records outgoing headers of the last RPC.
+type headerClient struct {
+	Client
+	md metadata.MD
+}
+
+func (c *headerClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	return nil, nil, status.Error(codes.NotFound, "not found")
+}
+
+func (c *headerClient) Close(ctx context.Context) error { return nil }
+
+func TestTracingClient(t *testing.T) {
+	parent, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent must not return an error, but got '%s'", err)
+	}
+	inner := &headerClient{}
+	client := NewTracingClient(inner, tracing.NewTracer(&parent, nil))
+	defer client.Close(context.Background())
+
+	t.Run("inject traceparent", func(t *testing.T) {
+		_, _, err := client.Invoke(context.Background(), "api.Example.Unary", nil, nil)
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("the error must be returned as it is, but got '%s'", err)
+		}
+		v := inner.md.Get(tracing.TraceparentKey)
+		if len(v) != 1 {
+			t.Fatalf("expected one traceparent header, but got %v", v)
+		}
+		sc, err := tracing.ParseTraceparent(v[0])
+		if err != nil {
+			t.Fatalf("the header must be a valid traceparent, but got '%s'", err)
+		}
+		if sc.TraceID != parent.TraceID {
+			t.Errorf("expected trace ID %x, but got %x", parent.TraceID, sc.TraceID)
+		}
+		if sc.SpanID == parent.SpanID {
+			t.Error("the header must have the span ID of the client span")
+		}
+	})
+
+	t.Run("keep traceparent specified by the user", func(t *testing.T) {
+		const tp = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		ctx := metadata.AppendToOutgoingContext(context.Background(), tracing.TraceparentKey, tp)
+		client.Invoke(ctx, "api.Example.Unary", nil, nil) //nolint:errcheck
+		if v := inner.md.Get(tracing.TraceparentKey); len(v) != 1 || v[0] != tp {
+			t.Errorf("expected traceparent %s, but got %v", tp, v)
+		}
+	})
+}
@@ -0,0 +1,446 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrTranscodingStreamingNotSupported is returned if a streaming RPC is called with HTTP/JSON transcoding.
+var ErrTranscodingStreamingNotSupported = errors.New("transcoding: streaming RPCs are not supported")
+
+const (
+	// transcodingHeaderPrefix and transcodingTrailerPrefix are prefixes of HTTP headers
+	// which grpc-gateway uses for gRPC headers and trailers.
+	transcodingHeaderPrefix  = "Grpc-Metadata-"
+	transcodingTrailerPrefix = "Grpc-Trailer-"
+)
+
+// MethodResolver returns the method descriptor of a fully-qualified RPC name.
+type MethodResolver func(fqrn string) (*desc.MethodDescriptor, error)
+
+type transcodingClient struct {
+	client  *http.Client
+	baseURL string
+	resolve MethodResolver
+	headers Headers
+}
+
+// NewTranscodingClient creates a new client which sends each RPC as the REST request annotated by
+// the google.api.http option of the method, such that requests to grpc-gateway.
+// resolve is used to get the option of a method.
+// Responses and errors are converted back into messages and gRPC statuses. Headers and trailers are
+// extracted from HTTP headers prefixed by "Grpc-Metadata-" and "Grpc-Trailer-".
+// Streaming RPCs and gRPC reflection are not supported.
+//
+// If useTLS is true, the client sends requests over HTTPS. serverName, cacert, cert, certKey and proxy are the same as NewClient.
+// If h2c is true, requests are sent over cleartext HTTP/2 with prior knowledge instead of HTTP/1.1.
+func NewTranscodingClient(addr, serverName string, useTLS bool, cacert, cert, certKey, proxy string, h2c bool, resolve MethodResolver) (Client, error) {
+	client, scheme, err := newHTTPClient(serverName, useTLS, cacert, cert, certKey, proxy, h2c)
+	if err != nil {
+		return nil, err
+	}
+	return &transcodingClient{
+		client:  client,
+		baseURL: fmt.Sprintf("%s://%s", scheme, addr),
+		resolve: resolve,
+		headers: Headers{},
+	}, nil
+}
+
+func (c *transcodingClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	method, err := c.resolve(fqrn)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "transcoding: failed to resolve the method '%s'", fqrn)
+	}
+	rule, err := httpRule(method)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loggingRequest(req)
+
+	r, err := c.newRequest(rule, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	r = r.WithContext(ctx)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		for k, v := range md {
+			for _, vv := range v {
+				r.Header.Add(k, httpHeaderValue(k, vv))
+			}
+		}
+	}
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, nil, errors.Wrap(err, "transcoding: failed to send a request")
+	}
+	defer resp.Body.Close()
+
+	header, trailer = metadata.MD{}, metadata.MD{}
+	for k, v := range resp.Header {
+		switch {
+		case strings.HasPrefix(k, transcodingHeaderPrefix):
+			header.Append(strings.TrimPrefix(k, transcodingHeaderPrefix), v...)
+		case strings.HasPrefix(k, transcodingTrailerPrefix):
+			trailer.Append(strings.TrimPrefix(k, transcodingTrailerPrefix), v...)
+		}
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return header, trailer, errors.Wrap(err, "transcoding: failed to read the response body")
+	}
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return header, trailer, transcodingErrorToStatus(resp.StatusCode, b).Err()
+	}
+	if f := rule.GetResponseBody(); f != "" {
+		fb, err := json.Marshal(f)
+		if err != nil {
+			return header, trailer, errors.Wrap(err, "transcoding: failed to marshal the response body field")
+		}
+		b = []byte(fmt.Sprintf("{%s:%s}", fb, b))
+	}
+	m, ok := res.(proto.Message)
+	if !ok {
+		return header, trailer, errors.Errorf("res should be a proto.Message, but got %T", res)
+	}
+	if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(b), m); err != nil {
+		return header, trailer, errors.Wrap(err, "transcoding: failed to unmarshal the response")
+	}
+	return header, trailer, nil
+}
+
+// httpRule returns the google.api.http option of md.
+func httpRule(md *desc.MethodDescriptor) (*annotations.HttpRule, error) {
+	opts := md.GetMethodOptions()
+	if opts == nil || !proto.HasExtension(opts, annotations.E_Http) {
+		return nil, errors.Errorf("transcoding: method '%s' doesn't have the google.api.http option", md.GetFullyQualifiedName())
+	}
+	ext, err := proto.GetExtension(opts, annotations.E_Http)
+	if err != nil {
+		return nil, errors.Wrap(err, "transcoding: failed to get the google.api.http option")
+	}
+	rule, ok := ext.(*annotations.HttpRule)
+	if !ok {
+		return nil, errors.Errorf("transcoding: unexpected type of the google.api.http option: %T", ext)
+	}
+	return rule, nil
+}
+
+// newRequest builds the REST request of req according to rule. Fields bound to the path template are removed from
+// the request, and then fields which are not bound to the body are sent as query parameters.
+func (c *transcodingClient) newRequest(rule *annotations.HttpRule, req interface{}) (*http.Request, error) {
+	var method, tmpl string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		method, tmpl = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		method, tmpl = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		method, tmpl = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		method, tmpl = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		method, tmpl = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		method, tmpl = p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return nil, errors.New("transcoding: the google.api.http option has no pattern")
+	}
+
+	m, ok := req.(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("req should be a proto.Message, but got %T", req)
+	}
+	// Path variables refer to fields which have the default value, so that they are decoded with defaults.
+	withDefaults, err := decodeRequest(m, true)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeRequest(m, false)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := expandPathTemplate(tmpl, withDefaults, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	switch f := rule.GetBody(); f {
+	case "":
+	case "*":
+		body, err = json.Marshal(fields)
+		fields = nil
+	default:
+		v, ok := fields[f]
+		if !ok {
+			v = withDefaults[f]
+		}
+		if v == nil {
+			v = map[string]interface{}{}
+		}
+		body, err = json.Marshal(v)
+		delete(fields, f)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "transcoding: failed to marshal the request body")
+	}
+	q := url.Values{}
+	if err := appendQuery(q, "", fields); err != nil {
+		return nil, err
+	}
+	u := c.baseURL + path
+	if len(q) != 0 {
+		u += "?" + q.Encode()
+	}
+
+	var r *http.Request
+	if body == nil {
+		r, err = http.NewRequest(method, u, nil)
+	} else {
+		r, err = http.NewRequest(method, u, bytes.NewReader(body))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "transcoding: failed to create a new request")
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return r, nil
+}
+
+// decodeRequest converts m into a JSON object keyed by original field names.
+func decodeRequest(m proto.Message, emitDefaults bool) (map[string]interface{}, error) {
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{OrigName: true, EmitDefaults: emitDefaults}).Marshal(&buf, m); err != nil {
+		return nil, errors.Wrap(err, "transcoding: failed to marshal the request")
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "transcoding: failed to decode the request")
+	}
+	return v, nil
+}
+
+// expandPathTemplate replaces variables in tmpl such that {name} and {name=shelves/*} with values in values.
+// Fields bound to variables are removed from fields.
+func expandPathTemplate(tmpl string, values, fields map[string]interface{}) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i == -1 {
+			sb.WriteString(tmpl)
+			return sb.String(), nil
+		}
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j == -1 {
+			return "", errors.Errorf("transcoding: invalid path template '%s'", tmpl)
+		}
+		sb.WriteString(tmpl[:i])
+		fieldPath, pattern := tmpl[i+1:i+j], ""
+		if k := strings.IndexByte(fieldPath, '='); k != -1 {
+			fieldPath, pattern = fieldPath[:k], fieldPath[k+1:]
+		}
+		tmpl = tmpl[i+j+1:]
+
+		v, ok := lookupField(values, fieldPath)
+		if !ok {
+			return "", errors.Errorf("transcoding: field '%s' in the path template is not found", fieldPath)
+		}
+		s, ok := scalarString(v)
+		if !ok {
+			return "", errors.Errorf("transcoding: field '%s' in the path template must be a scalar value", fieldPath)
+		}
+		if strings.Contains(pattern, "/") || strings.Contains(pattern, "**") {
+			// Multi-segment variables keep slashes.
+			segs := strings.Split(s, "/")
+			for i := range segs {
+				segs[i] = url.PathEscape(segs[i])
+			}
+			sb.WriteString(strings.Join(segs, "/"))
+		} else {
+			sb.WriteString(url.PathEscape(s))
+		}
+		deleteField(fields, fieldPath)
+	}
+}
+
+func lookupField(m map[string]interface{}, fieldPath string) (interface{}, bool) {
+	var v interface{} = m
+	for _, f := range strings.Split(fieldPath, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = obj[f]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func deleteField(m map[string]interface{}, fieldPath string) {
+	fs := strings.Split(fieldPath, ".")
+	for _, f := range fs[:len(fs)-1] {
+		obj, ok := m[f].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = obj
+	}
+	delete(m, fs[len(fs)-1])
+}
+
+// appendQuery adds fields to q. Nested messages are flattened into dotted names such that a.b=c.
+func appendQuery(q url.Values, prefix string, fields map[string]interface{}) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := prefix + k
+		switch v := fields[k].(type) {
+		case nil:
+		case map[string]interface{}:
+			if err := appendQuery(q, name+".", v); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, e := range v {
+				s, ok := scalarString(e)
+				if !ok {
+					return errors.Errorf("transcoding: field '%s' cannot be sent as a query parameter", name)
+				}
+				q.Add(name, s)
+			}
+		default:
+			s, _ := scalarString(v)
+			q.Add(name, s)
+		}
+	}
+	return nil
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// transcodingErrorToStatus converts an error response into a gRPC status. If the body is the status returned by
+// grpc-gateway, the code and message are used. Otherwise, the code is determined by the HTTP status code.
+func transcodingErrorToStatus(statusCode int, body []byte) *status.Status {
+	var e struct {
+		Code    *int   `json:"code"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err == nil && e.Code != nil {
+		msg := e.Message
+		if msg == "" {
+			msg = e.Error
+		}
+		return status.New(codes.Code(*e.Code), msg)
+	}
+
+	var code codes.Code
+	switch statusCode {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.Aborted
+	case http.StatusPreconditionFailed:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Unknown
+	}
+	msg := fmt.Sprintf("HTTP status code %d %q", statusCode, http.StatusText(statusCode))
+	if s := strings.TrimSpace(string(body)); s != "" {
+		msg += ": " + s
+	}
+	return status.New(code, msg)
+}
+
+func (c *transcodingClient) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	return nil, ErrTranscodingStreamingNotSupported
+}
+
+func (c *transcodingClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	return nil, ErrTranscodingStreamingNotSupported
+}
+
+func (c *transcodingClient) NewBidiStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	return nil, ErrTranscodingStreamingNotSupported
+}
+
+func (c *transcodingClient) Close(ctx context.Context) error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func (c *transcodingClient) Header() Headers {
+	return c.headers
+}
+
+// ListPackages always returns an error because gRPC reflection is not available with HTTP/JSON transcoding.
+func (c *transcodingClient) ListPackages() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("transcoding: gRPC reflection is not supported")
+}
+
+// ListServiceFiles always returns an error because gRPC reflection is not available with HTTP/JSON transcoding.
+func (c *transcodingClient) ListServiceFiles() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("transcoding: gRPC reflection is not supported")
+}
+
+func (c *transcodingClient) Reset() {}
@@ -0,0 +1,216 @@
+package grpc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const transcodingTestProto = `
+syntax = "proto3";
+package api;
+import "google/api/annotations.proto";
+
+message GetBookRequest {
+  string name = 1;
+  int32 version = 2;
+  repeated string tags = 3;
+}
+message Book {
+  string name = 1;
+  string title = 2;
+}
+message CreateBookRequest {
+  string parent = 1;
+  Book book = 2;
+}
+
+service Library {
+  rpc GetBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/v1/{name=shelves/*/books/*}" };
+  }
+  rpc CreateBook(CreateBookRequest) returns (Book) {
+    option (google.api.http) = { post: "/v1/{parent}/books" body: "book" };
+  }
+  rpc UpdateBook(Book) returns (Book) {
+    option (google.api.http) = { patch: "/v1/books/{name}" body: "*" response_body: "title" };
+  }
+  rpc NoRule(Book) returns (Book);
+}
+`
+
+// googleAPIProtos is a subset of google/api/http.proto and google/api/annotations.proto.
+var googleAPIProtos = map[string]string{
+	"google/api/http.proto": `
+syntax = "proto3";
+package google.api;
+message HttpRule {
+  string selector = 1;
+  oneof pattern {
+    string get = 2;
+    string put = 3;
+    string post = 4;
+    string delete = 5;
+    string patch = 6;
+    CustomHttpPattern custom = 8;
+  }
+  string body = 7;
+  string response_body = 12;
+  repeated HttpRule additional_bindings = 11;
+}
+message CustomHttpPattern {
+  string kind = 1;
+  string path = 2;
+}
+`,
+	"google/api/annotations.proto": `
+syntax = "proto3";
+package google.api;
+import "google/api/http.proto";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MethodOptions {
+  HttpRule http = 72295728;
+}
+`,
+}
+
+func TestTranscodingClient_Invoke(t *testing.T) {
+	files := map[string]string{"api.proto": transcodingTestProto}
+	for k, v := range googleAPIProtos {
+		files[k] = v
+	}
+	p := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(files)}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	svc := fds[0].FindService("api.Library")
+	resolve := func(fqrn string) (*desc.MethodDescriptor, error) {
+		return svc.FindMethodByName(fqrn[strings.LastIndex(fqrn, ".")+1:]), nil
+	}
+
+	cases := map[string]struct {
+		method   string
+		req      map[string]interface{}
+		handler  http.HandlerFunc
+		expected map[string]interface{}
+		code     codes.Code
+		msg      string
+		hasErr   bool
+	}{
+		"path template with a multi-segment variable and query parameters": {
+			method: "GetBook",
+			req:    map[string]interface{}{"name": "shelves/1/books/2", "version": int32(3), "tags": []string{"a", "b"}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.EscapedPath() != "/v1/shelves/1/books/2" || r.URL.RawQuery != "tags=a&tags=b&version=3" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`{"name":"shelves/1/books/2","title":"Evans"}`)) //nolint:errcheck
+			},
+			expected: map[string]interface{}{"name": "shelves/1/books/2", "title": "Evans"},
+		},
+		"body field": {
+			method: "CreateBook",
+			req:    map[string]interface{}{"parent": "shelves/1"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.URL.EscapedPath() != "/v1/shelves%2F1/books" || string(b) != `{}` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`{"name":"shelves/1/books/3"}`)) //nolint:errcheck
+			},
+			expected: map[string]interface{}{"name": "shelves/1/books/3"},
+		},
+		"whole body and response body": {
+			method: "UpdateBook",
+			req:    map[string]interface{}{"name": "1", "title": "Evans"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if r.Method != http.MethodPatch || r.URL.EscapedPath() != "/v1/books/1" || string(b) != `{"title":"Evans"}` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`"Evans 2"`)) //nolint:errcheck
+			},
+			expected: map[string]interface{}{"title": "Evans 2"},
+		},
+		"error status": {
+			method: "GetBook",
+			req:    map[string]interface{}{"name": "shelves/1/books/2"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":5,"message":"book not found"}`)) //nolint:errcheck
+			},
+			code: codes.NotFound,
+			msg:  "book not found",
+		},
+		"no google.api.http option": {
+			method: "NoRule",
+			hasErr: true,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Grpc-Metadata-Foo", r.Header.Get("foo"))
+				c.handler(w, r)
+			}))
+			defer srv.Close()
+
+			client, err := grpc.NewTranscodingClient(strings.TrimPrefix(srv.URL, "http://"), "", false, "", "", "", "", false, resolve)
+			if err != nil {
+				t.Fatalf("NewTranscodingClient must not return an error, but got '%s'", err)
+			}
+			md := svc.FindMethodByName(c.method)
+			req := dynamic.NewMessage(md.GetInputType())
+			for k, v := range c.req {
+				req.SetFieldByName(k, v)
+			}
+			res := dynamic.NewMessage(md.GetOutputType())
+			ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("foo", "bar"))
+			header, _, err := client.Invoke(ctx, "api.Library."+c.method, req, res)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Invoke must return an error, but got nil")
+				}
+				return
+			}
+			if c.code != codes.OK {
+				stat, ok := status.FromError(err)
+				if !ok {
+					t.Fatalf("expected a gRPC status error, but got '%v'", err)
+				}
+				if stat.Code() != c.code || stat.Message() != c.msg {
+					t.Errorf("expected '%s: %s', but got '%s: %s'", c.code, c.msg, stat.Code(), stat.Message())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Invoke must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff([]string{"bar"}, header.Get("foo")); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+			for k, v := range c.expected {
+				if actual := res.GetFieldByName(k); actual != v {
+					t.Errorf("expected %s is '%v', but got '%v'", k, v, actual)
+				}
+			}
+		})
+	}
+}
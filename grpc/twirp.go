@@ -0,0 +1,252 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// TwirpEncodingProtobuf sends Twirp requests with protobuf bodies.
+	TwirpEncodingProtobuf = "protobuf"
+	// TwirpEncodingJSON sends Twirp requests with JSON bodies.
+	TwirpEncodingJSON = "json"
+)
+
+// ErrTwirpStreamingNotSupported is returned if a streaming RPC is called with Twirp protocol.
+var ErrTwirpStreamingNotSupported = errors.New("twirp: streaming RPCs are not supported")
+
+type twirpClient struct {
+	client  *http.Client
+	baseURL string
+	json    bool
+	headers Headers
+}
+
+// NewTwirpClient creates a new client for Twirp protocol. Each RPC is sent as a POST request to
+// <scheme>://<addr>/twirp/<package>.<service>/<method>. The request body is encoded by encoding, one of
+// TwirpEncodingProtobuf or TwirpEncodingJSON.
+// Twirp errors are converted into gRPC statuses, and the error meta is returned as the trailer.
+// Twirp doesn't support streaming RPCs and gRPC reflection.
+//
+// If useTLS is true, the client sends requests over HTTPS. serverName, cacert, cert, certKey and proxy are the same as NewClient.
+// If h2c is true, requests are sent over cleartext HTTP/2 with prior knowledge instead of HTTP/1.1.
+func NewTwirpClient(addr, serverName string, useTLS bool, cacert, cert, certKey, proxy string, h2c bool, encoding string) (Client, error) {
+	var useJSON bool
+	switch encoding {
+	case "", TwirpEncodingProtobuf:
+	case TwirpEncodingJSON:
+		useJSON = true
+	default:
+		return nil, errors.Errorf("unknown Twirp encoding '%s'", encoding)
+	}
+	client, scheme, err := newHTTPClient(serverName, useTLS, cacert, cert, certKey, proxy, h2c)
+	if err != nil {
+		return nil, err
+	}
+	return &twirpClient{
+		client:  client,
+		baseURL: fmt.Sprintf("%s://%s/twirp", scheme, addr),
+		json:    useJSON,
+		headers: Headers{},
+	}, nil
+}
+
+func (c *twirpClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	endpoint, err := fqrnToEndpoint(fqrn)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "twirp: failed to convert FQRN to endpoint")
+	}
+
+	loggingRequest(req)
+
+	body, contentType, err := c.marshal(req)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "twirp: failed to marshal the request")
+	}
+	r, err := http.NewRequest(http.MethodPost, c.baseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "twirp: failed to create a new request")
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", contentType)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		for k, v := range md {
+			for _, vv := range v {
+				r.Header.Add(k, httpHeaderValue(k, vv))
+			}
+		}
+	}
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, nil, errors.Wrap(err, "twirp: failed to send a request")
+	}
+	defer resp.Body.Close()
+
+	header = metadata.MD{}
+	for k, v := range resp.Header {
+		header.Append(k, v...)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return header, nil, errors.Wrap(err, "twirp: failed to read the response body")
+	}
+	if resp.StatusCode != http.StatusOK {
+		stat, meta := twirpErrorToStatus(resp.StatusCode, b)
+		return header, meta, stat.Err()
+	}
+	if err := c.unmarshal(b, res); err != nil {
+		return header, nil, errors.Wrap(err, "twirp: failed to unmarshal the response")
+	}
+	return header, metadata.MD{}, nil
+}
+
+func (c *twirpClient) marshal(req interface{}) ([]byte, string, error) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return nil, "", errors.Errorf("req should be a proto.Message, but got %T", req)
+	}
+	if c.json {
+		var buf bytes.Buffer
+		if err := (&jsonpb.Marshaler{OrigName: true}).Marshal(&buf, m); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/json", nil
+	}
+	if mm, ok := m.(proto.Marshaler); ok {
+		b, err := mm.Marshal()
+		return b, "application/protobuf", err
+	}
+	b, err := proto.Marshal(m)
+	return b, "application/protobuf", err
+}
+
+func (c *twirpClient) unmarshal(b []byte, res interface{}) error {
+	m, ok := res.(proto.Message)
+	if !ok {
+		return errors.Errorf("res should be a proto.Message, but got %T", res)
+	}
+	if c.json {
+		return (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(b), m)
+	}
+	if u, ok := m.(proto.Unmarshaler); ok {
+		return u.Unmarshal(b)
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// twirpCodes maps Twirp error codes to gRPC codes.
+var twirpCodes = map[string]codes.Code{
+	"canceled":            codes.Canceled,
+	"unknown":             codes.Unknown,
+	"invalid_argument":    codes.InvalidArgument,
+	"malformed":           codes.InvalidArgument,
+	"deadline_exceeded":   codes.DeadlineExceeded,
+	"not_found":           codes.NotFound,
+	"bad_route":           codes.Unimplemented,
+	"already_exists":      codes.AlreadyExists,
+	"permission_denied":   codes.PermissionDenied,
+	"unauthenticated":     codes.Unauthenticated,
+	"resource_exhausted":  codes.ResourceExhausted,
+	"failed_precondition": codes.FailedPrecondition,
+	"aborted":             codes.Aborted,
+	"out_of_range":        codes.OutOfRange,
+	"unimplemented":       codes.Unimplemented,
+	"internal":            codes.Internal,
+	"unavailable":         codes.Unavailable,
+	"dataloss":            codes.DataLoss,
+}
+
+// twirpErrorToStatus converts a Twirp error response into a gRPC status. The error meta is returned as meta.
+// If the body is not a Twirp error such that a response from a proxy, the status is determined by
+// the HTTP status code in the same way as Twirp clients.
+func twirpErrorToStatus(statusCode int, body []byte) (_ *status.Status, meta metadata.MD) {
+	var twerr struct {
+		Code string            `json:"code"`
+		Msg  string            `json:"msg"`
+		Meta map[string]string `json:"meta"`
+	}
+	if err := json.Unmarshal(body, &twerr); err == nil && twerr.Code != "" {
+		code, ok := twirpCodes[twerr.Code]
+		if !ok {
+			code = codes.Unknown
+		}
+		meta = metadata.MD{}
+		for k, v := range twerr.Meta {
+			meta.Append(k, v)
+		}
+		return status.New(code, twerr.Msg), meta
+	}
+
+	var code codes.Code
+	switch {
+	case 300 <= statusCode && statusCode < 400, statusCode == http.StatusBadRequest:
+		code = codes.Internal
+	case statusCode == http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case statusCode == http.StatusForbidden:
+		code = codes.PermissionDenied
+	case statusCode == http.StatusNotFound:
+		code = codes.Unimplemented
+	case statusCode == http.StatusTooManyRequests, statusCode == http.StatusBadGateway,
+		statusCode == http.StatusServiceUnavailable, statusCode == http.StatusGatewayTimeout:
+		code = codes.Unavailable
+	default:
+		code = codes.Unknown
+	}
+	msg := fmt.Sprintf("error from intermediary with HTTP status code %d %q", statusCode, http.StatusText(statusCode))
+	if s := strings.TrimSpace(string(body)); s != "" {
+		msg += ": " + s
+	}
+	return status.New(code, msg), metadata.MD{}
+}
+
+func (c *twirpClient) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	return nil, ErrTwirpStreamingNotSupported
+}
+
+func (c *twirpClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	return nil, ErrTwirpStreamingNotSupported
+}
+
+func (c *twirpClient) NewBidiStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	return nil, ErrTwirpStreamingNotSupported
+}
+
+func (c *twirpClient) Close(ctx context.Context) error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func (c *twirpClient) Header() Headers {
+	return c.headers
+}
+
+// ListPackages always returns an error because Twirp doesn't support gRPC reflection.
+func (c *twirpClient) ListPackages() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("twirp: gRPC reflection is not supported")
+}
+
+// ListServiceFiles always returns an error because Twirp doesn't support gRPC reflection.
+func (c *twirpClient) ListServiceFiles() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("twirp: gRPC reflection is not supported")
+}
+
+func (c *twirpClient) Reset() {}
@@ -0,0 +1,147 @@
+package grpc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTwirpClient_Invoke(t *testing.T) {
+	cases := map[string]struct {
+		encoding    string
+		handler     http.HandlerFunc
+		expected    string
+		code        codes.Code
+		msg         string
+		trailer     metadata.MD
+		contentType string
+	}{
+		"protobuf": {
+			encoding:    grpc.TwirpEncodingProtobuf,
+			contentType: "application/protobuf",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				var req wrappers.StringValue
+				if err := proto.Unmarshal(b, &req); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				b, _ = proto.Marshal(&wrappers.StringValue{Value: "hello, " + req.Value})
+				w.Write(b) //nolint:errcheck
+			},
+			expected: "hello, ktr",
+		},
+		"json": {
+			encoding:    grpc.TwirpEncodingJSON,
+			contentType: "application/json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if string(b) != `"ktr"` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(`"hello, ktr"`)) //nolint:errcheck
+			},
+			expected: "hello, ktr",
+		},
+		"Twirp error": {
+			encoding: grpc.TwirpEncodingProtobuf,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","msg":"user not found","meta":{"id":"1"}}`)) //nolint:errcheck
+			},
+			code:    codes.NotFound,
+			msg:     "user not found",
+			trailer: metadata.Pairs("id", "1"),
+		},
+		"error from an intermediary": {
+			encoding: grpc.TwirpEncodingProtobuf,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			code:    codes.Unavailable,
+			msg:     `error from intermediary with HTTP status code 503 "Service Unavailable"`,
+			trailer: metadata.MD{},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/twirp/api.Example/Unary" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if r.Header.Get("foo") != "bar" || r.Header.Get("trace-bin") != "AAEC/w==" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if c.contentType != "" && r.Header.Get("Content-Type") != c.contentType {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				c.handler(w, r)
+			}))
+			defer srv.Close()
+
+			client, err := grpc.NewTwirpClient(strings.TrimPrefix(srv.URL, "http://"), "", false, "", "", "", "", false, c.encoding)
+			if err != nil {
+				t.Fatalf("NewTwirpClient must not return an error, but got '%s'", err)
+			}
+			ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("foo", "bar", "trace-bin", "\x00\x01\x02\xff"))
+			var res wrappers.StringValue
+			_, trailer, err := client.Invoke(ctx, "api.Example.Unary", &wrappers.StringValue{Value: "ktr"}, &res)
+			if c.code == codes.OK {
+				if err != nil {
+					t.Fatalf("Invoke must not return an error, but got '%s'", err)
+				}
+				if res.Value != c.expected {
+					t.Errorf("expected '%s', but got '%s'", c.expected, res.Value)
+				}
+				return
+			}
+
+			stat, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("expected a gRPC status error, but got '%v'", err)
+			}
+			if stat.Code() != c.code {
+				t.Errorf("expected code %s, but got %s", c.code, stat.Code())
+			}
+			if stat.Message() != c.msg {
+				t.Errorf("expected message '%s', but got '%s'", c.msg, stat.Message())
+			}
+			if diff := cmp.Diff(c.trailer, trailer); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTwirpClient_streaming(t *testing.T) {
+	client, err := grpc.NewTwirpClient("", "", false, "", "", "", "", false, "")
+	if err != nil {
+		t.Fatalf("NewTwirpClient must not return an error, but got '%s'", err)
+	}
+	if _, err := client.NewClientStream(context.Background(), nil, "api.Example.ClientStreaming"); err != grpc.ErrTwirpStreamingNotSupported {
+		t.Errorf("expected ErrTwirpStreamingNotSupported, but got '%v'", err)
+	}
+	if _, err := client.NewServerStream(context.Background(), nil, "api.Example.ServerStreaming"); err != grpc.ErrTwirpStreamingNotSupported {
+		t.Errorf("expected ErrTwirpStreamingNotSupported, but got '%v'", err)
+	}
+	if _, err := client.NewBidiStream(context.Background(), nil, "api.Example.BidiStreaming"); err != grpc.ErrTwirpStreamingNotSupported {
+		t.Errorf("expected ErrTwirpStreamingNotSupported, but got '%v'", err)
+	}
+}
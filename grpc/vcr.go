@@ -0,0 +1,489 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Cassette is a recording of RPC sessions. It is saved as a JSON file.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded RPC. Requests and responses are stored in the wire format,
+// so that replayed responses are identical to recorded ones.
+type Interaction struct {
+	// Method is the fully-qualified RPC name such that "api.Example.Unary".
+	Method string `json:"method"`
+	// RequestHeader is the metadata sent to the server. Values of credential-bearing keys are redacted.
+	RequestHeader metadata.MD     `json:"requestHeader,omitempty"`
+	Requests      [][]byte        `json:"requests"`
+	Header        metadata.MD     `json:"header,omitempty"`
+	Responses     [][]byte        `json:"responses"`
+	Trailer       metadata.MD     `json:"trailer,omitempty"`
+	Status        *RecordedStatus `json:"status"`
+}
+
+// RecordedStatus is the status an RPC finished with.
+type RecordedStatus struct {
+	// Code is the status code name such that "OK" or "NotFound".
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newRecordedStatus(stat *status.Status) *RecordedStatus {
+	return &RecordedStatus{Code: stat.Code().String(), Message: stat.Message()}
+}
+
+// err returns the error represented by s. It returns nil if the code is OK.
+func (s *RecordedStatus) err() error {
+	code, err := ParseCode(s.Code)
+	if err != nil {
+		return errors.Wrap(err, "invalid recorded status")
+	}
+	return status.Error(code, s.Message)
+}
+
+// LoadCassette loads a cassette from fname.
+func LoadCassette(fname string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the cassette")
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the cassette")
+	}
+	return &c, nil
+}
+
+// Save writes c to fname. The file is readable only by the owner because it may contain sensitive data
+// such as request messages.
+func (c *Cassette) Save(fname string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the cassette")
+	}
+	if err := ioutil.WriteFile(fname, append(b, '\n'), 0600); err != nil {
+		return errors.Wrap(err, "failed to write the cassette")
+	}
+	return nil
+}
+
+// marshalMessage encodes v in the wire format. Map entries are ordered deterministically if v supports it,
+// so that requests having the same content are encoded to the same bytes.
+func marshalMessage(v interface{}) ([]byte, error) {
+	if m, ok := v.(interface{ MarshalDeterministic() ([]byte, error) }); ok {
+		return m.MarshalDeterministic()
+	}
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("%T is not a Protocol Buffers message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func unmarshalMessage(b []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Errorf("%T is not a Protocol Buffers message", v)
+	}
+	return errors.Wrap(proto.Unmarshal(b, m), "failed to decode the recorded response")
+}
+
+type recordingClient struct {
+	Client
+
+	fname string
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecordingClient returns a client which records every RPC sent via client to the cassette file fname.
+// If fname already exists, new interactions are appended to it. The file is updated each time an RPC finishes.
+// RPCs which failed before the server responded, such as connection errors, are not recorded.
+func NewRecordingClient(client Client, fname string) (Client, error) {
+	cassette := &Cassette{}
+	if _, err := os.Stat(fname); err == nil {
+		cassette, err = LoadCassette(fname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &recordingClient{Client: client, fname: fname, cassette: cassette}, nil
+}
+
+func (c *recordingClient) record(i *Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cassette.Interactions = append(c.cassette.Interactions, i)
+	if err := c.cassette.Save(c.fname); err != nil {
+		logger.Printf("failed to record '%s': %s", i.Method, err)
+	}
+}
+
+// redactedKeys are metadata keys whose values are not recorded because they carry credentials.
+var redactedKeys = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+const redactedValue = "REDACTED"
+
+// redact returns a copy of md whose values of redactedKeys are replaced with redactedValue.
+func redact(md metadata.MD) metadata.MD {
+	if md == nil {
+		return nil
+	}
+	redacted := make(metadata.MD, len(md))
+	for k, v := range md {
+		if redactedKeys[k] {
+			v = []string{redactedValue}
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+func newInteraction(ctx context.Context, fqrn string) *Interaction {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return &Interaction{Method: fqrn, RequestHeader: redact(md)}
+}
+
+func (c *recordingClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	header, trailer, err := c.Client.Invoke(ctx, fqrn, req, res)
+	stat, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return header, trailer, err
+	}
+	i := newInteraction(ctx, fqrn)
+	i.Header, i.Trailer, i.Status = redact(header), redact(trailer), newRecordedStatus(stat)
+	if b, merr := marshalMessage(req); merr == nil {
+		i.Requests = append(i.Requests, b)
+	}
+	if err == nil {
+		if b, merr := marshalMessage(res); merr == nil {
+			i.Responses = append(i.Responses, b)
+		}
+	}
+	c.record(i)
+	return header, trailer, err
+}
+
+func (c *recordingClient) NewClientStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	s, err := c.Client.NewClientStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingClientStream{recordingStream: newRecordingStream(ctx, c, s, fqrn), s: s}, nil
+}
+
+func (c *recordingClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	s, err := c.Client.NewServerStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingServerStream{recordingStream: newRecordingStream(ctx, c, s, fqrn), s: s}, nil
+}
+
+func (c *recordingClient) NewBidiStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	s, err := c.Client.NewBidiStream(ctx, streamDesc, fqrn)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingBidiStream{
+		recordingServerStream: &recordingServerStream{recordingStream: newRecordingStream(ctx, c, s, fqrn), s: s},
+		s:                     s,
+	}, nil
+}
+
+// stream is the common part of ClientStream, ServerStream and BidiStream.
+type stream interface {
+	Header() (metadata.MD, error)
+	Trailer() metadata.MD
+	Send(req interface{}) error
+}
+
+// recordingStream records requests and responses of a stream. The interaction is recorded when the stream finishes.
+type recordingStream struct {
+	stream
+
+	c *recordingClient
+
+	mu       sync.Mutex
+	i        *Interaction
+	finished bool
+}
+
+func newRecordingStream(ctx context.Context, c *recordingClient, s stream, fqrn string) *recordingStream {
+	return &recordingStream{stream: s, c: c, i: newInteraction(ctx, fqrn)}
+}
+
+func (s *recordingStream) Send(req interface{}) error {
+	if err := s.stream.Send(req); err != nil {
+		return err
+	}
+	if b, err := marshalMessage(req); err == nil {
+		s.mu.Lock()
+		s.i.Requests = append(s.i.Requests, b)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *recordingStream) addResponse(res interface{}) {
+	if b, err := marshalMessage(res); err == nil {
+		s.mu.Lock()
+		s.i.Responses = append(s.i.Responses, b)
+		s.mu.Unlock()
+	}
+}
+
+// finish records the interaction with the status represented by err. err is nil or io.EOF if the RPC succeeded.
+func (s *recordingStream) finish(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	stat, ok := status.FromError(errors.Cause(err))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !ok || s.finished {
+		return
+	}
+	s.finished = true
+	header, _ := s.stream.Header()
+	s.i.Header = redact(header)
+	s.i.Trailer = redact(s.stream.Trailer())
+	s.i.Status = newRecordedStatus(stat)
+	s.c.record(s.i)
+}
+
+type recordingClientStream struct {
+	*recordingStream
+	s ClientStream
+}
+
+func (s *recordingClientStream) CloseAndReceive(res interface{}) error {
+	err := s.s.CloseAndReceive(res)
+	if err == nil {
+		s.addResponse(res)
+	}
+	s.finish(err)
+	return err
+}
+
+type recordingServerStream struct {
+	*recordingStream
+	s ServerStream
+}
+
+func (s *recordingServerStream) Receive(res interface{}) error {
+	err := s.s.Receive(res)
+	if err == nil {
+		s.addResponse(res)
+		return nil
+	}
+	s.finish(err)
+	return err
+}
+
+type recordingBidiStream struct {
+	*recordingServerStream
+	s BidiStream
+}
+
+func (s *recordingBidiStream) CloseSend() error {
+	return s.s.CloseSend()
+}
+
+// ErrNoInteraction is returned from clients created by NewReplayClient if no recorded interaction matches the RPC.
+var ErrNoInteraction = errors.New("no recorded interaction matches the RPC")
+
+type replayClient struct {
+	cassette *Cassette
+	headers  Headers
+}
+
+// NewReplayClient returns a client which answers RPCs from the cassette file fname without connecting to any servers.
+// An RPC is answered by the first interaction which has the same method and requests. Bidi streaming RPCs are matched
+// by requests sent before the first response is received. gRPC reflection is not available.
+func NewReplayClient(fname string) (Client, error) {
+	cassette, err := LoadCassette(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &replayClient{cassette: cassette, headers: Headers{}}, nil
+}
+
+// find returns the first interaction of fqrn which has reqs as its requests.
+// If prefix is true, interactions whose requests start with reqs also match.
+func (c *replayClient) find(fqrn string, reqs [][]byte, prefix bool) (*Interaction, error) {
+	for _, i := range c.cassette.Interactions {
+		if i.Method != fqrn || len(i.Requests) < len(reqs) || (!prefix && len(i.Requests) != len(reqs)) {
+			continue
+		}
+		match := true
+		for n, req := range reqs {
+			if !bytes.Equal(i.Requests[n], req) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrNoInteraction, "method '%s'", fqrn)
+}
+
+func (c *replayClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	b, err := marshalMessage(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, err := c.find(fqrn, [][]byte{b}, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := i.Status.err(); err != nil {
+		return i.Header, i.Trailer, err
+	}
+	if len(i.Responses) == 0 {
+		return nil, nil, errors.Errorf("the recorded interaction of '%s' has no response", fqrn)
+	}
+	return i.Header, i.Trailer, unmarshalMessage(i.Responses[0], res)
+}
+
+func (c *replayClient) NewClientStream(ctx context.Context, _ *grpc.StreamDesc, fqrn string) (ClientStream, error) {
+	return &replayStream{c: c, fqrn: fqrn}, nil
+}
+
+func (c *replayClient) NewServerStream(ctx context.Context, _ *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	return &replayStream{c: c, fqrn: fqrn}, nil
+}
+
+func (c *replayClient) NewBidiStream(ctx context.Context, _ *grpc.StreamDesc, fqrn string) (BidiStream, error) {
+	return &replayStream{c: c, fqrn: fqrn, prefix: true}, nil
+}
+
+func (c *replayClient) Close(ctx context.Context) error {
+	return nil
+}
+
+func (c *replayClient) Header() Headers {
+	return c.headers
+}
+
+// ListPackages always returns an error because the replay client doesn't connect to any servers.
+func (c *replayClient) ListPackages() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("gRPC reflection is not available in the replay mode")
+}
+
+// ListServiceFiles always returns an error because the replay client doesn't connect to any servers.
+func (c *replayClient) ListServiceFiles() ([]*desc.FileDescriptor, error) {
+	return nil, errors.New("gRPC reflection is not available in the replay mode")
+}
+
+func (c *replayClient) Reset() {}
+
+// replayStream implements ClientStream, ServerStream and BidiStream. The interaction is looked up when
+// the first response or the header is requested.
+type replayStream struct {
+	c      *replayClient
+	fqrn   string
+	prefix bool
+
+	mu   sync.Mutex
+	reqs [][]byte
+	i    *Interaction
+	err  error
+	next int
+}
+
+func (s *replayStream) match() (*Interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.i == nil && s.err == nil {
+		s.i, s.err = s.c.find(s.fqrn, s.reqs, s.prefix)
+	}
+	return s.i, s.err
+}
+
+func (s *replayStream) Header() (metadata.MD, error) {
+	i, err := s.match()
+	if err != nil {
+		return nil, err
+	}
+	return i.Header, nil
+}
+
+func (s *replayStream) Trailer() metadata.MD {
+	i, err := s.match()
+	if err != nil {
+		return nil
+	}
+	return i.Trailer
+}
+
+func (s *replayStream) Send(req interface{}) error {
+	b, err := marshalMessage(req)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, b)
+	return nil
+}
+
+func (s *replayStream) Receive(res interface{}) error {
+	i, err := s.match()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next < len(i.Responses) {
+		s.next++
+		return unmarshalMessage(i.Responses[s.next-1], res)
+	}
+	if err := i.Status.err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (s *replayStream) CloseAndReceive(res interface{}) error {
+	i, err := s.match()
+	if err != nil {
+		return err
+	}
+	if err := i.Status.err(); err != nil {
+		return err
+	}
+	if len(i.Responses) == 0 {
+		return errors.Errorf("the recorded interaction of '%s' has no response", s.fqrn)
+	}
+	return unmarshalMessage(i.Responses[0], res)
+}
+
+func (s *replayStream) CloseSend() error {
+	return nil
+}
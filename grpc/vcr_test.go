@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// echoClient responds with requests as it is. Requests which have "error" fail with codes.NotFound.
+type echoClient struct {
+	Client
+}
+
+func (c *echoClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (metadata.MD, metadata.MD, error) {
+	v := req.(*wrappers.StringValue).Value
+	if v == "error" {
+		return metadata.Pairs("foo", "bar"), nil, status.Error(codes.NotFound, "not found")
+	}
+	res.(*wrappers.StringValue).Value = v
+	return metadata.Pairs("foo", "bar"), metadata.Pairs("hoge", "fuga"), nil
+}
+
+func (c *echoClient) NewServerStream(ctx context.Context, streamDesc *grpc.StreamDesc, fqrn string) (ServerStream, error) {
+	return &echoServerStream{}, nil
+}
+
+type echoServerStream struct {
+	reqs []string
+}
+
+func (s *echoServerStream) Header() (metadata.MD, error) { return metadata.Pairs("foo", "bar"), nil }
+func (s *echoServerStream) Trailer() metadata.MD         { return nil }
+
+func (s *echoServerStream) Send(req interface{}) error {
+	v := req.(*wrappers.StringValue).Value
+	s.reqs = append(s.reqs, v, v)
+	return nil
+}
+
+func (s *echoServerStream) Receive(res interface{}) error {
+	if len(s.reqs) == 0 {
+		return io.EOF
+	}
+	res.(*wrappers.StringValue).Value = s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer token", "user", "foo")
+
+	rc, err := NewRecordingClient(&echoClient{}, fname)
+	if err != nil {
+		t.Fatalf("NewRecordingClient must not return an error, but got '%s'", err)
+	}
+	for _, v := range []string{"foo", "error"} {
+		rc.Invoke(ctx, "api.Example.Unary", &wrappers.StringValue{Value: v}, &wrappers.StringValue{}) //nolint:errcheck
+	}
+	s, err := rc.NewServerStream(ctx, nil, "api.Example.ServerStreaming")
+	if err != nil {
+		t.Fatalf("NewServerStream must not return an error, but got '%s'", err)
+	}
+	if err := s.Send(&wrappers.StringValue{Value: "bar"}); err != nil {
+		t.Fatalf("Send must not return an error, but got '%s'", err)
+	}
+	for s.Receive(&wrappers.StringValue{}) == nil {
+	}
+
+	cassette, err := LoadCassette(fname)
+	if err != nil {
+		t.Fatalf("LoadCassette must not return an error, but got '%s'", err)
+	}
+	if n := len(cassette.Interactions); n != 3 {
+		t.Fatalf("expected 3 interactions, but got %d", n)
+	}
+	if diff := cmp.Diff(metadata.Pairs("authorization", "REDACTED", "user", "foo"), cassette.Interactions[0].RequestHeader); diff != "" {
+		t.Errorf("credentials must be redacted (-want, +got)\n%s", diff)
+	}
+	fi, err := os.Stat(fname)
+	if err != nil {
+		t.Fatalf("os.Stat must not return an error, but got '%s'", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected the cassette to be written with 0600, but got %o", perm)
+	}
+
+	c, err := NewReplayClient(fname)
+	if err != nil {
+		t.Fatalf("NewReplayClient must not return an error, but got '%s'", err)
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		var res wrappers.StringValue
+		header, trailer, err := c.Invoke(ctx, "api.Example.Unary", &wrappers.StringValue{Value: "foo"}, &res)
+		if err != nil {
+			t.Fatalf("Invoke must not return an error, but got '%s'", err)
+		}
+		if res.Value != "foo" {
+			t.Errorf("expected 'foo', but got '%s'", res.Value)
+		}
+		if v := header.Get("foo"); len(v) != 1 || v[0] != "bar" {
+			t.Errorf("expected the recorded header, but got %v", header)
+		}
+		if v := trailer.Get("hoge"); len(v) != 1 || v[0] != "fuga" {
+			t.Errorf("expected the recorded trailer, but got %v", trailer)
+		}
+	})
+
+	t.Run("unary with an error status", func(t *testing.T) {
+		_, _, err := c.Invoke(ctx, "api.Example.Unary", &wrappers.StringValue{Value: "error"}, &wrappers.StringValue{})
+		if stat := status.Convert(err); stat.Code() != codes.NotFound || stat.Message() != "not found" {
+			t.Errorf("expected the recorded status, but got '%s'", err)
+		}
+	})
+
+	t.Run("unknown request", func(t *testing.T) {
+		_, _, err := c.Invoke(ctx, "api.Example.Unary", &wrappers.StringValue{Value: "baz"}, &wrappers.StringValue{})
+		if !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("expected ErrNoInteraction, but got '%v'", err)
+		}
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		s, err := c.NewServerStream(ctx, nil, "api.Example.ServerStreaming")
+		if err != nil {
+			t.Fatalf("NewServerStream must not return an error, but got '%s'", err)
+		}
+		if err := s.Send(&wrappers.StringValue{Value: "bar"}); err != nil {
+			t.Fatalf("Send must not return an error, but got '%s'", err)
+		}
+		var got []string
+		for {
+			var res wrappers.StringValue
+			err := s.Receive(&res)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Receive must not return an error, but got '%s'", err)
+			}
+			got = append(got, res.Value)
+		}
+		if len(got) != 2 || got[0] != "bar" || got[1] != "bar" {
+			t.Errorf("expected the recorded responses, but got %v", got)
+		}
+	})
+}
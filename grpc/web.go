@@ -36,6 +36,7 @@ func NewWebClient(addr string, useReflection, useTLS bool, cacert, cert, certKey
 }
 
 func (c *webClient) Invoke(ctx context.Context, fqrn string, req, res interface{}) (header, trailer metadata.MD, _ error) {
+	ctx = encodeBinaryHeaders(ctx)
 	endpoint, err := fqrnToEndpoint(fqrn)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "grpc-web: failed to convert FQRN to endpoint")
@@ -77,6 +78,7 @@ func (s *webClientStream) CloseAndReceive(res interface{}) error {
 }
 
 func (c *webClient) NewClientStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (ClientStream, error) {
+	ctx = encodeBinaryHeaders(ctx)
 	endpoint, err := fqrnToEndpoint(fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
@@ -125,6 +127,7 @@ func (s *webServerStream) Receive(res interface{}) error {
 }
 
 func (c *webClient) NewServerStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (ServerStream, error) {
+	ctx = encodeBinaryHeaders(ctx)
 	endpoint, err := fqrnToEndpoint(fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
@@ -178,6 +181,7 @@ func (s *webBidiStream) CloseSend() error {
 }
 
 func (c *webClient) NewBidiStream(ctx context.Context, streamDesc *gogrpc.StreamDesc, fqrn string) (BidiStream, error) {
+	ctx = encodeBinaryHeaders(ctx)
 	endpoint, err := fqrnToEndpoint(fqrn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to convert FQRN to endpoint")
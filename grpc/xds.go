@@ -0,0 +1,26 @@
+package grpc
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	_ "google.golang.org/grpc/xds/experimental" // Register the xDS resolver and balancers.
+)
+
+// xdsBootstrapEnv is the environment variable gRPC reads the path of the xDS bootstrap file from.
+const xdsBootstrapEnv = "GRPC_XDS_BOOTSTRAP"
+
+// IsXDSTarget returns true if addr is a target resolved by xDS such that "xds:///service-name".
+func IsXDSTarget(addr string) bool {
+	return strings.HasPrefix(addr, "xds:///")
+}
+
+// setXDSBootstrap sets the path of the xDS bootstrap file.
+// gRPC reads the file when an xDS target is resolved.
+func setXDSBootstrap(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return errors.Wrap(err, "failed to find the xDS bootstrap file")
+	}
+	return os.Setenv(xdsBootstrapEnv, path)
+}
@@ -0,0 +1,42 @@
+// Package headerset provides a file-based store of named header sets, which are snapshots of REPL headers.
+// Each header set is stored as a JSON file such that
+//
+//   <dir>/admin.json
+//
+package headerset
+
+import (
+	"path/filepath"
+
+	"github.com/ktr0731/evans/filestore"
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+var (
+	ErrNotFound    = errors.New("header set not found")
+	ErrInvalidName = errors.New("invalid header set name")
+)
+
+// DefaultDir returns the default directory of header sets. It is under the config directory.
+func DefaultDir() string {
+	return filepath.Join(xdgbasedir.ConfigHome(), meta.AppName, "headersets")
+}
+
+// Store stores header sets under a directory. Load and Delete return ErrNotFound if the header set is missing.
+// Header sets are readable only by the user because they usually have credentials.
+type Store struct {
+	*filestore.Store
+}
+
+// NewStore returns a new store which stores header sets under dir.
+func NewStore(dir string) *Store {
+	return &Store{filestore.New(dir, filestore.Options{
+		Kind:           "header set",
+		ErrNotFound:    ErrNotFound,
+		ErrInvalidName: ErrInvalidName,
+		DirMode:        0700,
+		FileMode:       0600,
+	})}
+}
@@ -0,0 +1,74 @@
+package headerset_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/headerset"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := headerset.NewStore(filepath.Join(dir, "headersets"))
+
+	names, err := s.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no header sets, but got %v", names)
+	}
+
+	for _, name := range []string{"admin", "tenant-a"} {
+		if err := s.Save(name, []byte(`{"header": {"x-tenant": ["`+name+`"]}}`)); err != nil {
+			t.Fatalf("Save must not return an error, but got '%s'", err)
+		}
+	}
+	names, err = s.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"admin", "tenant-a"}, names); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	b, err := s.Load("admin")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if string(b) != `{"header": {"x-tenant": ["admin"]}}` {
+		t.Errorf("unexpected header set: %s", b)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(dir, "headersets", "admin.json"))
+		if err != nil {
+			t.Fatalf("failed to stat the header set: %s", err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("header sets must be readable only by the user, but the permission is %o", perm)
+		}
+	}
+
+	if err := s.Delete("admin"); err != nil {
+		t.Fatalf("Delete must not return an error, but got '%s'", err)
+	}
+	if _, err := s.Load("admin"); !errors.Is(err, headerset.ErrNotFound) {
+		t.Errorf("Load must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Delete("admin"); !errors.Is(err, headerset.ErrNotFound) {
+		t.Errorf("Delete must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Save("../admin", nil); !errors.Is(err, headerset.ErrInvalidName) {
+		t.Errorf("Save must return ErrInvalidName, but got '%v'", err)
+	}
+}
@@ -0,0 +1,107 @@
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+// DefaultFieldFile returns the default file of field input history. It is under the data directory.
+func DefaultFieldFile() string {
+	return filepath.Join(xdgbasedir.DataHome(), meta.AppName, "fields.json")
+}
+
+// FieldStore stores inputted values for each field path such that "api.CreateUserRequest.user.id".
+// All histories are stored in a JSON file which has an object keyed by field paths.
+// Values added by Add are kept in memory until Save is called.
+type FieldStore struct {
+	fname   string
+	maxSize int
+
+	mu sync.Mutex
+	// loaded is values read from the file. added is values added after loading.
+	loaded map[string][]string
+	added  map[string][]string
+}
+
+// NewFieldStore returns a new store which stores field input history in fname.
+// Each field keeps the newest maxSize values.
+func NewFieldStore(fname string, maxSize int) *FieldStore {
+	return &FieldStore{fname: fname, maxSize: maxSize, added: make(map[string][]string)}
+}
+
+// Load returns inputted values of the field path in ascending order.
+// If the file cannot be read, Load returns only values added by Add.
+func (s *FieldStore) Load(path string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded == nil {
+		s.loaded, _ = s.read()
+		if s.loaded == nil {
+			s.loaded = make(map[string][]string)
+		}
+	}
+	h := append(append([]string{}, s.loaded[path]...), s.added[path]...)
+	return Tidy(h, s.maxSize)
+}
+
+// Add adds an inputted value of the field path.
+func (s *FieldStore) Add(path, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.added[path] = append(s.added[path], value)
+}
+
+// Save writes values added by Add to the file. Values are appended to the file which is loaded just before saving,
+// so that values inputted by REPLs running concurrently are not lost.
+func (s *FieldStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.added) == 0 {
+		return nil
+	}
+	m, err := s.read()
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m = make(map[string][]string)
+	}
+	for path, values := range s.added {
+		m[path] = Tidy(append(m[path], values...), s.maxSize)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the field input history")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.fname), 0755); err != nil {
+		return errors.Wrap(err, "failed to create the history directory")
+	}
+	if err := ioutil.WriteFile(s.fname, b, 0600); err != nil {
+		return errors.Wrap(err, "failed to write the field input history")
+	}
+	s.loaded, s.added = m, make(map[string][]string)
+	return nil
+}
+
+// read reads the file. It returns nil if the file doesn't exist.
+func (s *FieldStore) read() (map[string][]string, error) {
+	b, err := ioutil.ReadFile(s.fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the field input history")
+	}
+	var m map[string][]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the field input history")
+	}
+	return m, nil
+}
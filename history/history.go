@@ -0,0 +1,133 @@
+// Package history provides a file-based store of REPL command history.
+// Each history is stored as a text file which has a command per line such that
+//
+//	<dir>/global
+//	<dir>/127.0.0.1_50051
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+// GlobalName is the name of the history shared by all hosts.
+const GlobalName = "global"
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// DefaultDir returns the default directory of history files. It is under the data directory,
+// so that history is kept even if the cache is cleared by upgrading Evans.
+func DefaultDir() string {
+	return filepath.Join(xdgbasedir.DataHome(), meta.AppName, "history")
+}
+
+// Name returns the name of the history for addr such that "127.0.0.1:50051".
+// If addr is empty, Name returns GlobalName.
+func Name(addr string) string {
+	if addr == "" {
+		return GlobalName
+	}
+	return invalidNameChars.ReplaceAllString(addr, "_")
+}
+
+// Store stores command history under a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a new store which stores command history under dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Load loads the history named name in ascending order. It returns nil if the history doesn't exist.
+func (s *Store) Load(name string) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the history '%s'", name)
+	}
+	var h []string
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			h = append(h, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the history '%s'", name)
+	}
+	return h, nil
+}
+
+// Append appends commands to the history named name. Commands are appended to the history which is loaded
+// just before saving, so that commands of REPLs running concurrently are not lost.
+// Duplicated commands are removed, and the history is truncated to the newest maxSize commands.
+func (s *Store) Append(name string, commands []string, maxSize int) error {
+	h, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	h = Tidy(append(h, commands...), maxSize)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create the history directory")
+	}
+	var buf bytes.Buffer
+	for _, c := range h {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.dir, name), buf.Bytes(), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write the history '%s'", name)
+	}
+	return nil
+}
+
+// Tidy removes duplicated and empty commands from h. Only the newest one of duplicated commands is kept.
+// If the number of commands exceeds maxSize, older commands are removed.
+func Tidy(h []string, maxSize int) []string {
+	m := make(map[string]int)
+	for i := range h {
+		if strings.TrimSpace(h[i]) == "" {
+			continue
+		}
+		m[h[i]] = i
+	}
+	s := make([]int, 0, len(m))
+	for _, i := range m {
+		s = append(s, i)
+	}
+	sort.Ints(s)
+	history := make([]string, 0, len(s))
+	for _, i := range s {
+		history = append(history, h[i])
+	}
+	if len(history) > maxSize {
+		history = history[len(history)-maxSize:]
+	}
+	return history
+}
+
+// Filter returns commands of h which contain query. The order is kept.
+func Filter(h []string, query string) []string {
+	var res []string
+	for _, c := range h {
+		if strings.Contains(c, query) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
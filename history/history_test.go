@@ -0,0 +1,171 @@
+package history_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/history"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := history.NewStore(dir)
+	name := history.Name("127.0.0.1:50051")
+	if name != "127.0.0.1_50051" {
+		t.Errorf("unexpected history name '%s'", name)
+	}
+
+	h, err := s.Load(name)
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if len(h) != 0 {
+		t.Errorf("expected empty history, but got %v", h)
+	}
+
+	// Two REPLs append commands to the same history.
+	if err := s.Append(name, []string{"call Unary", "show service"}, 3); err != nil {
+		t.Fatalf("Append must not return an error, but got '%s'", err)
+	}
+	if err := s.Append(name, []string{"call Unary", "header foo=bar", "desc Request"}, 3); err != nil {
+		t.Fatalf("Append must not return an error, but got '%s'", err)
+	}
+	h, err = s.Load(name)
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"call Unary", "header foo=bar", "desc Request"}, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	if h, _ := s.Load(history.GlobalName); len(h) != 0 {
+		t.Errorf("histories of other names must not be changed, but got %v", h)
+	}
+}
+
+func TestTidy(t *testing.T) {
+	cases := map[string]struct {
+		history     []string
+		historySize int
+		expected    []string
+	}{
+		"empty": {
+			history:     nil,
+			historySize: 100,
+			expected:    []string{},
+		},
+		"simple": {
+			history:     []string{"foo", "bar"},
+			historySize: 100,
+			expected:    []string{"foo", "bar"},
+		},
+		"remove duplicated items": {
+			history:     []string{"foo", "bar", "foo", "baz"},
+			historySize: 100,
+			expected:    []string{"bar", "foo", "baz"},
+		},
+		"remove empty items": {
+			history:     []string{"foo", "", " ", "bar"},
+			historySize: 100,
+			expected:    []string{"foo", "bar"},
+		},
+		"over history size": {
+			history:     []string{"foo", "bar", "baz"},
+			historySize: 2,
+			expected:    []string{"bar", "baz"},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual := history.Tidy(c.history, c.historySize)
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	actual := history.Filter([]string{"call Unary", "show service", "call --edit Unary"}, "call")
+	if diff := cmp.Diff([]string{"call Unary", "call --edit Unary"}, actual); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func TestRequestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := history.NewRequestStore(dir, 2)
+	h, err := s.Load("api.Example.Unary")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if len(h) != 0 {
+		t.Errorf("expected empty history, but got %v", h)
+	}
+
+	for _, body := range []string{"{\n  \"name\": \"kumiko\"\n}\n", `{"name": "reina"}`, "{\n  \"name\": \"kumiko\"\n}\n", `{"name": "hazuki"}`} {
+		if err := s.Append("api.Example.Unary", body); err != nil {
+			t.Fatalf("Append must not return an error, but got '%s'", err)
+		}
+	}
+	h, err = s.Load("api.Example.Unary")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"{\n  \"name\": \"kumiko\"\n}\n", `{"name": "hazuki"}`}, h); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	if h, _ := s.Load("api.Example.ClientStreaming"); len(h) != 0 {
+		t.Errorf("histories of other RPCs must not be changed, but got %v", h)
+	}
+}
+
+func TestFieldStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "fields.json")
+
+	// Two REPLs add values to the same file.
+	s1, s2 := history.NewFieldStore(fname, 2), history.NewFieldStore(fname, 2)
+	if h := s1.Load("api.Request.id"); len(h) != 0 {
+		t.Errorf("expected empty history, but got %v", h)
+	}
+	s1.Add("api.Request.id", "1")
+	s1.Add("api.Request.id", "2")
+	s2.Add("api.Request.id", "3")
+	s2.Add("api.Request.name", "kumiko")
+	if diff := cmp.Diff([]string{"1", "2"}, s1.Load("api.Request.id")); diff != "" {
+		t.Errorf("added values must be loaded before saving (-want, +got)\n%s", diff)
+	}
+	for _, s := range []*history.FieldStore{s1, s2} {
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save must not return an error, but got '%s'", err)
+		}
+	}
+
+	s := history.NewFieldStore(fname, 2)
+	if diff := cmp.Diff([]string{"2", "3"}, s.Load("api.Request.id")); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"kumiko"}, s.Load("api.Request.name")); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+// DefaultRequestDir returns the default directory of request history. It is under the data directory.
+func DefaultRequestDir() string {
+	return filepath.Join(xdgbasedir.DataHome(), meta.AppName, "requests")
+}
+
+// RequestStore stores sent request bodies for each RPC. Each history is stored as a JSON array of bodies
+// in a file per fully-qualified RPC name such that
+//
+//	<dir>/api.Example.Unary.json
+type RequestStore struct {
+	dir     string
+	maxSize int
+}
+
+// NewRequestStore returns a new store which stores request history under dir.
+// Each history keeps the newest maxSize bodies.
+func NewRequestStore(dir string, maxSize int) *RequestStore {
+	return &RequestStore{dir: dir, maxSize: maxSize}
+}
+
+// Load loads request bodies of the RPC in ascending order. It returns nil if the history doesn't exist.
+func (s *RequestStore) Load(fqrn string) ([]string, error) {
+	b, err := ioutil.ReadFile(s.path(fqrn))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the request history of '%s'", fqrn)
+	}
+	var h []string
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the request history of '%s'", fqrn)
+	}
+	return h, nil
+}
+
+// Append appends body to the request history of the RPC. If the same body is already in the history,
+// it is moved to the newest one.
+func (s *RequestStore) Append(fqrn, body string) error {
+	h, err := s.Load(fqrn)
+	if err != nil {
+		return err
+	}
+	h = Tidy(append(h, body), s.maxSize)
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the request history")
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create the request history directory")
+	}
+	if err := ioutil.WriteFile(s.path(fqrn), b, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write the request history of '%s'", fqrn)
+	}
+	return nil
+}
+
+func (s *RequestStore) path(fqrn string) string {
+	return filepath.Join(s.dir, invalidNameChars.ReplaceAllString(fqrn, "_")+".json")
+}
@@ -58,8 +58,35 @@ type Spec interface {
 	//
 	ResolveSymbol(symbol string) (interface{}, error)
 
+	// MessageType returns the type of a message.
+	// The message name should be fully-qualified.
+	// MessageType may returns these errors:
+	//
+	//   - ErrUnknownSymbol: the message is not loaded.
+	//
+	MessageType(fqmn string) (*grpc.Type, error)
+
+	// MessageNames returns all message names the spec loaded including nested ones.
+	// Message names are fully-qualified.
+	// The returned slice is ordered by ascending order.
+	MessageNames() []string
+
 	// FormatDescriptor formats v according to its IDL type.
 	FormatDescriptor(v interface{}) (string, error)
+
+	// ExportFiles returns IDL files which define the passed fully-qualified services and their dependencies.
+	// The key is a file name and the value is the reconstructed file content.
+	// If svcNames is empty, all loaded files are returned.
+	// ExportFiles may return these errors:
+	//
+	//   - ErrUnknownServiceName: one of svcNames is not contained to ServiceNames().
+	//
+	ExportFiles(svcNames []string) (map[string]string, error)
+
+	// ExportDescriptorSet returns the serialized descriptor set (FileDescriptorSet in Protocol Buffers)
+	// which contains the same files as ExportFiles. Dependencies precede files which import them.
+	// ExportDescriptorSet may return the same errors as ExportFiles.
+	ExportDescriptorSet(svcNames []string) ([]byte, error)
 }
 
 // FullyQualifiedMethodName returns the fully-qualified method joined with '.'.
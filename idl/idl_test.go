@@ -111,6 +111,12 @@ func TestSpec(t *testing.T) {
 					if actual == nil {
 						t.Errorf("actual must not be nil")
 					}
+
+					for _, symbol := range []string{"api.Example", "api.Example.RPC", "api.Person", "api.Request.person"} {
+						if _, err := spec.ResolveSymbol(symbol); err != nil {
+							t.Errorf("ResolveSymbol must return the descriptor of %s, but got an error: '%s'", symbol, err)
+						}
+					}
 				})
 			})
 
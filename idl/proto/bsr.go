@@ -0,0 +1,136 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/ktr0731/evans/idl"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// bsrGetImagePath is the path of buf.alpha.registry.v1alpha1.ImageService/GetImage.
+const bsrGetImagePath = "/buf.alpha.registry.v1alpha1.ImageService/GetImage"
+
+// BSRModule represents a module reference of Buf Schema Registry.
+type BSRModule struct {
+	Remote     string
+	Owner      string
+	Repository string
+	// Reference is a tag, a commit or a branch. If it is empty, the latest commit of the main branch is used.
+	Reference string
+}
+
+// ParseBSRModule parses a module reference in the form of <remote>/<owner>/<repository>[:<reference>]
+// such that "buf.build/acme/weather:v1".
+func ParseBSRModule(s string) (*BSRModule, error) {
+	name, ref := s, ""
+	if i := strings.LastIndex(s, ":"); i != -1 {
+		name, ref = s[:i], s[i+1:]
+	}
+	sp := strings.Split(name, "/")
+	if len(sp) != 3 || sp[0] == "" || sp[1] == "" || sp[2] == "" || (ref == "" && name != s) {
+		return nil, errors.Errorf("invalid BSR module '%s'. it must be the form of <remote>/<owner>/<repository>[:<reference>]", s)
+	}
+	return &BSRModule{Remote: sp[0], Owner: sp[1], Repository: sp[2], Reference: ref}, nil
+}
+
+func (m *BSRModule) String() string {
+	s := fmt.Sprintf("%s/%s/%s", m.Remote, m.Owner, m.Repository)
+	if m.Reference != "" {
+		s += ":" + m.Reference
+	}
+	return s
+}
+
+// LoadBSR fetches the image of the passed module from Buf Schema Registry, then instantiates a new idl.Spec from it.
+// The image contains the module and its dependencies, so that no proto files are required locally.
+// token is an API token of the registry. It may be empty for public modules.
+func LoadBSR(client *http.Client, module *BSRModule, token string) (idl.Spec, error) {
+	set, err := fetchBSRImage(client, module, token)
+	if err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to fetch the image of BSR module '%s'", module)
+	}
+	fileDescs, err := createFileDescriptorsFromSet(set)
+	if err != nil {
+		return nil, errors.Wrapf(err, "proto: failed to create file descriptors from the image of BSR module '%s'", module)
+	}
+	return newSpec(withDependencies(fileDescs)), nil
+}
+
+// fetchBSRImage calls ImageService/GetImage by the Connect protocol.
+// Image (buf.alpha.image.v1.Image) is wire compatible with FileDescriptorSet, so that the response is decoded as it.
+func fetchBSRImage(client *http.Client, module *BSRModule, token string) (*descriptor.FileDescriptorSet, error) {
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.BytesType) // owner
+	body = protowire.AppendString(body, module.Owner)
+	body = protowire.AppendTag(body, 2, protowire.BytesType) // repository
+	body = protowire.AppendString(body, module.Repository)
+	if module.Reference != "" {
+		body = protowire.AppendTag(body, 3, protowire.BytesType) // reference
+		body = protowire.AppendString(body, module.Reference)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api."+module.Remote+bsrGetImagePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a request")
+	}
+	req.Header.Set("Content-Type", "application/proto")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send a request")
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the response")
+	}
+	if res.StatusCode != http.StatusOK {
+		// Errors of the Connect protocol are encoded as JSON.
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(b, &e); err != nil || e.Code == "" {
+			return nil, errors.Errorf("unexpected status %s", res.Status)
+		}
+		return nil, errors.Errorf("%s: %s", e.Code, e.Message)
+	}
+
+	// Extract image (field 1) from GetImageResponse.
+	var set descriptor.FileDescriptorSet
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, errors.Wrap(protowire.ParseError(n), "failed to decode the response")
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, errors.Wrap(protowire.ParseError(n), "failed to decode the response")
+			}
+			if err := proto.Unmarshal(v, &set); err != nil {
+				return nil, errors.Wrap(err, "failed to decode the image")
+			}
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, errors.Wrap(protowire.ParseError(n), "failed to decode the response")
+		}
+		b = b[n:]
+	}
+	return &set, nil
+}
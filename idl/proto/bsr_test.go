@@ -0,0 +1,143 @@
+package proto_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	protov1 "github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/ktr0731/evans/idl/proto"
+)
+
+func TestParseBSRModule(t *testing.T) {
+	cases := map[string]struct {
+		in       string
+		expected *proto.BSRModule
+		hasErr   bool
+	}{
+		"normal": {
+			in:       "buf.build/acme/weather",
+			expected: &proto.BSRModule{Remote: "buf.build", Owner: "acme", Repository: "weather"},
+		},
+		"with reference": {
+			in:       "buf.build/acme/weather:v1",
+			expected: &proto.BSRModule{Remote: "buf.build", Owner: "acme", Repository: "weather", Reference: "v1"},
+		},
+		"missing remote":    {in: "acme/weather", hasErr: true},
+		"empty reference":   {in: "buf.build/acme/weather:", hasErr: true},
+		"empty repository":  {in: "buf.build/acme/", hasErr: true},
+		"too many elements": {in: "buf.build/acme/weather/v1", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m, err := proto.ParseBSRModule(c.in)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("ParseBSRModule must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBSRModule must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, m); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+			if s := m.String(); s != c.in {
+				t.Errorf("String must return the original reference '%s', but got '%s'", c.in, s)
+			}
+		})
+	}
+}
+
+// rewriteTransport sends all requests to the test server.
+type rewriteTransport struct {
+	u *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = t.u.Scheme, t.u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestLoadBSR(t *testing.T) {
+	p := &protoparse.Parser{ImportPaths: []string{"testdata"}}
+	fds, err := p.ParseFiles("message.proto", "api.proto")
+	if err != nil {
+		t.Fatalf("failed to parse proto files: %s", err)
+	}
+	var set descriptor.FileDescriptorSet
+	for _, fd := range fds {
+		set.File = append(set.File, fd.AsFileDescriptorProto())
+	}
+	image, err := protov1.Marshal(&set)
+	if err != nil {
+		t.Fatalf("failed to marshal the image: %s", err)
+	}
+	// GetImageResponse has the image as field 1.
+	res := append(append([]byte{0x0a}, protov1.EncodeVarint(uint64(len(image)))...), image...)
+
+	var (
+		gotReq  []byte
+		gotPath string
+		gotAuth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		gotReq, _ = ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/proto" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if gotAuth == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"unauthenticated","message":"you must be logged in"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/proto")
+		w.Write(res)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	client := &http.Client{Transport: &rewriteTransport{u: u}}
+
+	m := &proto.BSRModule{Remote: "buf.build", Owner: "acme", Repository: "weather", Reference: "v1"}
+
+	t.Run("normal", func(t *testing.T) {
+		spec, err := proto.LoadBSR(client, m, "tok")
+		if err != nil {
+			t.Fatalf("LoadBSR must not return an error, but got '%s'", err)
+		}
+		if gotPath != "/buf.alpha.registry.v1alpha1.ImageService/GetImage" {
+			t.Errorf("unexpected path '%s'", gotPath)
+		}
+		if gotAuth != "Bearer tok" {
+			t.Errorf("unexpected Authorization header '%s'", gotAuth)
+		}
+		expectedReq := []byte("\x0a\x04acme\x12\x07weather\x1a\x02v1")
+		if diff := cmp.Diff(expectedReq, gotReq); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"api.Example"}, spec.ServiceNames()); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := proto.LoadBSR(client, m, "")
+		if err == nil {
+			t.Fatalf("LoadBSR must return an error, but got nil")
+		}
+		if expected := "unauthenticated: you must be logged in"; !strings.HasSuffix(err.Error(), expected) {
+			t.Errorf("expected '%s', but got '%s'", expected, err)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package proto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/ktr0731/evans/idl"
+)
+
+// ChangeKind represents how an element of the schema is changed.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "+"
+	ChangeRemoved ChangeKind = "-"
+	ChangeChanged ChangeKind = "~"
+)
+
+// Change represents a difference of an element between two schemas.
+type Change struct {
+	Kind ChangeKind
+	// Element is the kind of the element, one of "service", "method", "message", "field", "enum" or "enum value".
+	Element string
+	// Name is the fully-qualified name of the element.
+	Name string
+	// Detail describes how the element is changed. It is set only if Kind is ChangeChanged.
+	Detail string
+	// Breaking reports whether the change breaks clients or servers built with the old schema.
+	Breaking bool
+}
+
+func (c *Change) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Kind, c.Element, c.Name)
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	if c.Breaking {
+		s += " (breaking)"
+	}
+	return s
+}
+
+// Diff compares services, methods, messages, fields, enums and enum values of two specs, then returns
+// changes from oldSpec to newSpec sorted by their names. Removing elements and changing types, numbers or
+// streaming types are regarded as breaking changes. Both specs must be instantiated by this package.
+func Diff(oldSpec, newSpec idl.Spec) []*Change {
+	o, n := diffSymbols(oldSpec), diffSymbols(newSpec)
+	var changes []*Change
+	for name, od := range o {
+		nd, ok := n[name]
+		if !ok {
+			changes = append(changes, &Change{Kind: ChangeRemoved, Element: elementName(od), Name: name, Breaking: true})
+			continue
+		}
+		if elementName(od) != elementName(nd) {
+			changes = append(changes, &Change{
+				Kind:     ChangeChanged,
+				Element:  elementName(nd),
+				Name:     name,
+				Detail:   fmt.Sprintf("%s is changed to %s", elementName(od), elementName(nd)),
+				Breaking: true,
+			})
+			continue
+		}
+		changes = append(changes, diffDescriptor(od, nd)...)
+	}
+	for name, nd := range n {
+		if _, ok := o[name]; !ok {
+			changes = append(changes, &Change{Kind: ChangeAdded, Element: elementName(nd), Name: name})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// diffSymbols indexes all elements which are compared by Diff with their fully-qualified names.
+func diffSymbols(s idl.Spec) map[string]desc.Descriptor {
+	ps, ok := s.(*spec)
+	if !ok {
+		panic(fmt.Sprintf("Diff accepts only specs instantiated by package proto, but got %T", s))
+	}
+	symbols := make(map[string]desc.Descriptor)
+	for name, d := range ps.symbols {
+		switch d := d.(type) {
+		case *desc.MessageDescriptor:
+			// Changes of map entries are reported as changes of map fields.
+			if d.IsMapEntry() {
+				continue
+			}
+			for _, f := range d.GetFields() {
+				symbols[f.GetFullyQualifiedName()] = f
+			}
+		case *desc.EnumDescriptor:
+			for _, v := range d.GetValues() {
+				symbols[v.GetFullyQualifiedName()] = v
+			}
+		}
+		symbols[name] = d
+	}
+	return symbols
+}
+
+func diffDescriptor(o, n desc.Descriptor) []*Change {
+	var details []string
+	add := func(format string, a ...interface{}) {
+		details = append(details, fmt.Sprintf(format, a...))
+	}
+	switch o := o.(type) {
+	case *desc.MethodDescriptor:
+		n := n.(*desc.MethodDescriptor)
+		if oi, ni := o.GetInputType().GetFullyQualifiedName(), n.GetInputType().GetFullyQualifiedName(); oi != ni {
+			add("request type is changed from %s to %s", oi, ni)
+		}
+		if oo, no := o.GetOutputType().GetFullyQualifiedName(), n.GetOutputType().GetFullyQualifiedName(); oo != no {
+			add("response type is changed from %s to %s", oo, no)
+		}
+		if os, ns := streamingType(o), streamingType(n); os != ns {
+			add("streaming type is changed from %s to %s", os, ns)
+		}
+	case *desc.FieldDescriptor:
+		n := n.(*desc.FieldDescriptor)
+		if o.GetNumber() != n.GetNumber() {
+			add("number is changed from %d to %d", o.GetNumber(), n.GetNumber())
+		}
+		if ot, nt := fieldType(o), fieldType(n); ot != nt {
+			add("type is changed from %s to %s", ot, nt)
+		}
+		if ol, nl := fieldLabel(o), fieldLabel(n); ol != nl {
+			add("label is changed from %s to %s", ol, nl)
+		}
+		if oo, no := oneofName(o), oneofName(n); oo != no {
+			add("oneof is changed from %s to %s", oo, no)
+		}
+	case *desc.EnumValueDescriptor:
+		n := n.(*desc.EnumValueDescriptor)
+		if o.GetNumber() != n.GetNumber() {
+			add("number is changed from %d to %d", o.GetNumber(), n.GetNumber())
+		}
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return []*Change{{
+		Kind:     ChangeChanged,
+		Element:  elementName(n),
+		Name:     n.GetFullyQualifiedName(),
+		Detail:   strings.Join(details, ", "),
+		Breaking: true,
+	}}
+}
+
+func elementName(d desc.Descriptor) string {
+	switch d.(type) {
+	case *desc.ServiceDescriptor:
+		return "service"
+	case *desc.MethodDescriptor:
+		return "method"
+	case *desc.MessageDescriptor:
+		return "message"
+	case *desc.FieldDescriptor:
+		return "field"
+	case *desc.EnumDescriptor:
+		return "enum"
+	case *desc.EnumValueDescriptor:
+		return "enum value"
+	default:
+		return "element"
+	}
+}
+
+func streamingType(m *desc.MethodDescriptor) string {
+	switch {
+	case m.IsClientStreaming() && m.IsServerStreaming():
+		return "bidi streaming"
+	case m.IsClientStreaming():
+		return "client streaming"
+	case m.IsServerStreaming():
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
+
+// fieldType returns the type name of f as it is written in proto files.
+func fieldType(f *desc.FieldDescriptor) string {
+	if f.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(f.GetMapKeyType()), fieldType(f.GetMapValueType()))
+	}
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return f.GetMessageType().GetFullyQualifiedName()
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return f.GetEnumType().GetFullyQualifiedName()
+	default:
+		return strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+	}
+}
+
+func fieldLabel(f *desc.FieldDescriptor) string {
+	switch {
+	case f.IsMap():
+		return "map"
+	case f.IsRepeated():
+		return "repeated"
+	case f.IsRequired():
+		return "required"
+	default:
+		return "optional"
+	}
+}
+
+func oneofName(f *desc.FieldDescriptor) string {
+	if f.GetOneOf() == nil {
+		return "none"
+	}
+	return f.GetOneOf().GetName()
+}
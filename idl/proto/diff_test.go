@@ -0,0 +1,49 @@
+package proto_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/idl/proto"
+)
+
+func TestDiff(t *testing.T) {
+	oldSpec, err := proto.LoadFiles([]string{"testdata/diff/old"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	newSpec, err := proto.LoadFiles([]string{"testdata/diff/new"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		var actual []string
+		for _, c := range proto.Diff(oldSpec, newSpec) {
+			actual = append(actual, c.String())
+		}
+		expected := []string{
+			"+ message api.Added",
+			"+ method api.Example.Added",
+			"- method api.Example.Removed (breaking)",
+			"~ method api.Example.Unary: streaming type is changed from unary to server streaming (breaking)",
+			"- service api.Removed (breaking)",
+			"- method api.Removed.Unary (breaking)",
+			"~ field api.Request.a: oneof is changed from kind to none (breaking)",
+			"~ field api.Request.age: type is changed from int32 to int64 (breaking)",
+			"+ field api.Request.labels",
+			"- field api.Request.removed (breaking)",
+			"~ enum value api.Status.OK: number is changed from 1 to 3 (breaking)",
+			"- enum value api.Status.REMOVED (breaking)",
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		if changes := proto.Diff(oldSpec, oldSpec); len(changes) != 0 {
+			t.Errorf("expected no changes, but got %v", changes)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package proto
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// expandFiles expands glob patterns and directories contained in fnames to proto file names.
+// Patterns and directories are interpreted as relative paths from each import path, or the current directory
+// if importPaths is empty. "**" in patterns matches zero or more directories, and directories are expanded to
+// all proto files under them recursively. Expanded files are sorted in lexical order for each element of fnames,
+// and files which appear more than once are removed. Other elements are returned as it is.
+func expandFiles(importPaths, fnames []string) ([]string, error) {
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	var (
+		files       []string
+		encountered = make(map[string]interface{})
+	)
+	add := func(fname string) {
+		if _, ok := encountered[fname]; ok {
+			return
+		}
+		encountered[fname] = nil
+		files = append(files, fname)
+	}
+	for _, fname := range fnames {
+		// Absolute paths are not relative to import paths.
+		roots, prefix := importPaths, ""
+		if filepath.IsAbs(fname) {
+			roots, prefix = []string{"/"}, "/"
+		}
+		isGlob := strings.ContainsAny(fname, "*?[")
+		if !isGlob && !isDir(roots, fname) {
+			add(fname)
+			continue
+		}
+		pattern := strings.TrimPrefix(path.Clean(filepath.ToSlash(fname)), "/")
+		if !isGlob {
+			pattern = path.Join(pattern, "**", "*.proto")
+		}
+		var matched []string
+		for _, root := range roots {
+			m, err := globFiles(root, pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "proto: failed to expand '%s'", fname)
+			}
+			for _, f := range m {
+				matched = append(matched, prefix+f)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, errors.Errorf("proto: no proto files match '%s'", fname)
+		}
+		sort.Strings(matched)
+		for _, m := range matched {
+			add(m)
+		}
+	}
+	return files, nil
+}
+
+// isDir reports whether fname is a directory under any of roots.
+func isDir(roots []string, fname string) bool {
+	for _, root := range roots {
+		if fi, err := os.Stat(filepath.Join(root, fname)); err == nil && fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// globFiles returns slash-separated relative paths of files under root which match pattern.
+func globFiles(root, pattern string) ([]string, error) {
+	// Walk only under the directory which doesn't contain meta characters.
+	segs := strings.Split(pattern, "/")
+	var base []string
+	for _, seg := range segs[:len(segs)-1] {
+		if strings.ContainsAny(seg, "*?[") {
+			break
+		}
+		base = append(base, seg)
+	}
+	dir := filepath.Join(root, filepath.FromSlash(path.Join(base...)))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		ok, err := matchSegments(segs, strings.Split(rel, "/"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// matchSegments reports whether the path segments match the pattern segments.
+// "**" matches zero or more segments, and others are matched by path.Match.
+func matchSegments(pattern, segs []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(segs) == 0, nil
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			ok, err := matchSegments(pattern[1:], segs[i:])
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	if len(segs) == 0 {
+		return false, nil
+	}
+	ok, err := path.Match(pattern[0], segs[0])
+	if err != nil || !ok {
+		return false, err
+	}
+	return matchSegments(pattern[1:], segs[1:])
+}
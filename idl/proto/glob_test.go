@@ -0,0 +1,110 @@
+package proto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_expandFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, fname := range []string{
+		"api/v1/b.proto",
+		"api/v1/a.proto",
+		"api/v2/c.proto",
+		"api/v2/README.md",
+		"api/root.proto",
+		"other/d.proto",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(fname))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0600); err != nil {
+			t.Fatalf("failed to create a file: %s", err)
+		}
+	}
+
+	cases := map[string]struct {
+		importPaths []string
+		fnames      []string
+		expected    []string
+		hasErr      bool
+	}{
+		"file names": {
+			importPaths: []string{dir},
+			fnames:      []string{"other/d.proto", "api/v1/a.proto"},
+			expected:    []string{"other/d.proto", "api/v1/a.proto"},
+		},
+		"not found file names are returned as it is": {
+			importPaths: []string{dir},
+			fnames:      []string{"not_found.proto"},
+			expected:    []string{"not_found.proto"},
+		},
+		"glob": {
+			importPaths: []string{dir},
+			fnames:      []string{"api/*/*.proto"},
+			expected:    []string{"api/v1/a.proto", "api/v1/b.proto", "api/v2/c.proto"},
+		},
+		"recursive glob": {
+			importPaths: []string{dir},
+			fnames:      []string{"./api/**/*.proto"},
+			expected:    []string{"api/root.proto", "api/v1/a.proto", "api/v1/b.proto", "api/v2/c.proto"},
+		},
+		"directory": {
+			importPaths: []string{dir},
+			fnames:      []string{"api/v1", "other"},
+			expected:    []string{"api/v1/a.proto", "api/v1/b.proto", "other/d.proto"},
+		},
+		"relative to import paths": {
+			importPaths: []string{filepath.Join(dir, "api"), filepath.Join(dir, "other")},
+			fnames:      []string{"**/*.proto"},
+			expected:    []string{"d.proto", "root.proto", "v1/a.proto", "v1/b.proto", "v2/c.proto"},
+		},
+		"duplicated files": {
+			importPaths: []string{dir},
+			fnames:      []string{"api/v1/b.proto", "api/v1", "api/**/*.proto"},
+			expected:    []string{"api/v1/b.proto", "api/v1/a.proto", "api/root.proto", "api/v2/c.proto"},
+		},
+		"absolute path": {
+			importPaths: []string{filepath.Join(dir, "other")},
+			fnames:      []string{filepath.Join(dir, "api", "v1")},
+			expected:    []string{filepath.Join(dir, "api", "v1", "a.proto"), filepath.Join(dir, "api", "v1", "b.proto")},
+		},
+		"no files match": {
+			importPaths: []string{dir},
+			fnames:      []string{"api/**/*.txt"},
+			hasErr:      true,
+		},
+		"invalid pattern": {
+			importPaths: []string{dir},
+			fnames:      []string{"api/[.proto"},
+			hasErr:      true,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual, err := expandFiles(c.importPaths, c.fnames)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("expandFiles must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandFiles must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package proto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// CustomOptions returns custom options of d such that validate.rules or google.api.field_behavior. Each option is
+// formatted as "(fully-qualified extension name) = value" and options are sorted by names.
+// Options are decoded by extensions defined in the file of d and its dependencies.
+func CustomOptions(d desc.Descriptor) []string {
+	opts := d.GetOptions()
+	if opts == nil {
+		return nil
+	}
+	md, err := desc.LoadMessageDescriptorForMessage(opts)
+	if err != nil {
+		return nil
+	}
+	// Extensions unknown to the options message are kept as unknown fields, so they are decoded again by
+	// a dynamic message which recognizes extensions the file depends on.
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	var er dynamic.ExtensionRegistry
+	er.AddExtensionsFromFileRecursively(d.GetFile())
+	dmsg := dynamic.NewMessageFactoryWithExtensionRegistry(&er).NewDynamicMessage(md)
+	if err := dmsg.Unmarshal(b); err != nil {
+		return nil
+	}
+
+	var out []string
+	for _, ext := range er.AllExtensionsForType(md.GetFullyQualifiedName()) {
+		if !dmsg.HasField(ext) {
+			continue
+		}
+		out = append(out, fmt.Sprintf("(%s) = %s", ext.GetFullyQualifiedName(), formatOptionValue(ext, dmsg.GetField(ext))))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func formatOptionValue(fd *desc.FieldDescriptor, v interface{}) string {
+	if vs, ok := v.([]interface{}); ok {
+		elems := make([]string, len(vs))
+		for i, e := range vs {
+			elems[i] = formatOptionValue(fd, e)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	switch v := v.(type) {
+	case int32:
+		if et := fd.GetEnumType(); et != nil {
+			if ev := et.FindValueByNumber(v); ev != nil {
+				return ev.GetName()
+			}
+		}
+	case string:
+		return fmt.Sprintf("%q", v)
+	case proto.Message:
+		dmsg, err := dynamic.AsDynamicMessage(v)
+		if err != nil {
+			return proto.CompactTextString(v)
+		}
+		b, err := dmsg.MarshalText()
+		if err != nil {
+			return proto.CompactTextString(v)
+		}
+		return "{" + string(b) + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+// IsRequired reports whether field must be set. A field is required if it has the required label of proto2 or
+// it is annotated by google.api.field_behavior REQUIRED.
+func IsRequired(field *desc.FieldDescriptor) bool {
+	if field.IsRequired() {
+		return true
+	}
+	opts := field.GetFieldOptions()
+	if opts == nil || !proto.HasExtension(opts, annotations.E_FieldBehavior) {
+		return false
+	}
+	ext, err := proto.GetExtension(opts, annotations.E_FieldBehavior)
+	if err != nil {
+		return false
+	}
+	behaviors, ok := ext.([]annotations.FieldBehavior)
+	if !ok {
+		return false
+	}
+	for _, b := range behaviors {
+		if b == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeprecatedService reports whether v is a service descriptor marked as deprecated.
+func IsDeprecatedService(v interface{}) bool {
+	svc, ok := v.(*desc.ServiceDescriptor)
+	return ok && svc.GetServiceOptions().GetDeprecated()
+}
+
+// DeprecatedFields returns fully-qualified names of fields which are set in v and marked as deprecated.
+// Fields of nested messages are also returned. If v is not a *dynamic.Message, it returns nil.
+func DeprecatedFields(v interface{}) []string {
+	msg, ok := v.(*dynamic.Message)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, field := range msg.GetKnownFields() {
+		if !msg.HasField(field) {
+			continue
+		}
+		if field.GetFieldOptions().GetDeprecated() {
+			names = append(names, field.GetFullyQualifiedName())
+		}
+		if field.GetMessageType() == nil {
+			continue
+		}
+		var vals []interface{}
+		switch val := msg.GetField(field).(type) {
+		case []interface{}:
+			vals = val
+		case map[interface{}]interface{}:
+			for _, e := range val {
+				vals = append(vals, e)
+			}
+		default:
+			vals = []interface{}{val}
+		}
+		for _, e := range vals {
+			names = append(names, DeprecatedFields(e)...)
+		}
+	}
+	return names
+}
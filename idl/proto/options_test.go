@@ -0,0 +1,159 @@
+package proto_test
+
+import (
+	"testing"
+
+	protov1 "github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/ktr0731/evans/idl/proto"
+)
+
+var fieldBehaviorProto = `
+syntax = "proto3";
+package google.api;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FieldOptions {
+  repeated google.api.FieldBehavior field_behavior = 1052;
+}
+enum FieldBehavior {
+  FIELD_BEHAVIOR_UNSPECIFIED = 0;
+  OPTIONAL = 1;
+  REQUIRED = 2;
+  OUTPUT_ONLY = 3;
+}`
+
+func TestCustomOptions(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"google/api/field_behavior.proto": fieldBehaviorProto,
+			"api.proto": `
+syntax = "proto3";
+package api;
+import "google/api/field_behavior.proto";
+message Book {
+  string name = 1 [(google.api.field_behavior) = OUTPUT_ONLY];
+  string title = 2 [(google.api.field_behavior) = REQUIRED, (google.api.field_behavior) = OUTPUT_ONLY];
+  string note = 3 [deprecated = true];
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	md := fds[0].FindMessage("api.Book")
+
+	cases := map[string]struct {
+		field    string
+		expected []string
+	}{
+		"a custom option":               {field: "name", expected: []string{"(google.api.field_behavior) = [OUTPUT_ONLY]"}},
+		"a repeated custom option":      {field: "title", expected: []string{"(google.api.field_behavior) = [REQUIRED, OUTPUT_ONLY]"}},
+		"standard options are excluded": {field: "note", expected: nil},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual := proto.CustomOptions(md.FindFieldByName(c.field))
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCustomOptions_unknownExtension(t *testing.T) {
+	descFile, err := desc.LoadFileDescriptor("google/protobuf/descriptor.proto")
+	if err != nil {
+		t.Fatalf("LoadFileDescriptor must not return an error, but got '%s'", err)
+	}
+	extFile, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:       protov1.String("ext.proto"),
+		Package:    protov1.String("api"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptor.FieldDescriptorProto{
+			{
+				Name:     protov1.String("scope"),
+				Number:   protov1.Int32(50000),
+				Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Extendee: protov1.String(".google.protobuf.FieldOptions"),
+			},
+		},
+	}, descFile)
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+	// Options received from gRPC reflection keep extensions unknown to Go as unknown fields.
+	// The bytes are the field 50000 with the string "write".
+	var opts descriptor.FieldOptions
+	if err := protov1.Unmarshal([]byte{0x82, 0xb5, 0x18, 0x05, 'w', 'r', 'i', 't', 'e'}, &opts); err != nil {
+		t.Fatalf("Unmarshal must not return an error, but got '%s'", err)
+	}
+	fd, err := desc.CreateFileDescriptor(&descriptor.FileDescriptorProto{
+		Name:       protov1.String("api.proto"),
+		Package:    protov1.String("api"),
+		Syntax:     protov1.String("proto3"),
+		Dependency: []string{"ext.proto"},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: protov1.String("Book"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:    protov1.String("name"),
+						Number:  protov1.Int32(1),
+						Label:   descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:    descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Options: &opts,
+					},
+				},
+			},
+		},
+	}, extFile)
+	if err != nil {
+		t.Fatalf("CreateFileDescriptor must not return an error, but got '%s'", err)
+	}
+
+	actual := proto.CustomOptions(fd.FindMessage("api.Book").FindFieldByName("name"))
+	if diff := cmp.Diff([]string{`(api.scope) = "write"`}, actual); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
+
+func TestIsRequired(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"google/api/field_behavior.proto": fieldBehaviorProto,
+			"api.proto": `
+syntax = "proto2";
+package api;
+import "google/api/field_behavior.proto";
+message Book {
+  required string name = 1;
+  optional string title = 2 [(google.api.field_behavior) = REQUIRED];
+  optional string isbn = 3 [(google.api.field_behavior) = OUTPUT_ONLY];
+  optional string note = 4;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	md := fds[0].FindMessage("api.Book")
+
+	cases := map[string]bool{
+		"name":  true,
+		"title": true,
+		"isbn":  false,
+		"note":  false,
+	}
+	for field, expected := range cases {
+		if actual := proto.IsRequired(md.FindFieldByName(field)); actual != expected {
+			t.Errorf("%s: expected %t, but got %t", field, expected, actual)
+		}
+	}
+}
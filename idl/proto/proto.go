@@ -3,9 +3,16 @@ package proto
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/desc/protoprint"
@@ -23,8 +30,17 @@ type spec struct {
 	svcDescs []*desc.ServiceDescriptor
 	// key: fully qualified service name, val: method descriptors belong to the service.
 	rpcDescs map[string][]*desc.MethodDescriptor
+	// key: fully qualified service name, val: a map that has method names as keys.
+	rpcIndex map[string]map[string]*desc.MethodDescriptor
 	// key: fully qualified message name, val: the message descriptor.
 	msgDescs map[string]*desc.MessageDescriptor
+	// msgFactory instantiates messages which recognize extensions defined in all loaded files.
+	msgFactory *dynamic.MessageFactory
+	// anyResolver resolves message types embedded in google.protobuf.Any from all loaded files.
+	anyResolver jsonpb.AnyResolver
+	// key: fully qualified symbol name, val: the descriptor of the symbol.
+	// It contains messages, enums, services and methods including nested ones.
+	symbols map[string]desc.Descriptor
 }
 
 func (s *spec) ServiceNames() []string {
@@ -61,43 +77,47 @@ func (s *spec) RPC(svcName, rpcName string) (*grpc.RPC, error) {
 		return nil, idl.ErrServiceUnselected
 	}
 
-	rpcDescs, ok := s.rpcDescs[svcName]
+	rpcs, ok := s.rpcIndex[svcName]
 	if !ok {
 		return nil, idl.ErrUnknownServiceName
 	}
 
-	for _, d := range rpcDescs {
-		if d.GetName() == rpcName {
-			return &grpc.RPC{
-				Name:               d.GetName(),
-				FullyQualifiedName: d.GetFullyQualifiedName(),
-				RequestType: &grpc.Type{
-					Name:               d.GetInputType().GetName(),
-					FullyQualifiedName: d.GetInputType().GetFullyQualifiedName(),
-					New: func() (interface{}, error) {
-						m := dynamic.NewMessage(d.GetInputType())
-						return m, nil
-					},
-				},
-				ResponseType: &grpc.Type{
-					Name:               d.GetOutputType().GetName(),
-					FullyQualifiedName: d.GetOutputType().GetFullyQualifiedName(),
-					New: func() (interface{}, error) {
-						m := dynamic.NewMessage(d.GetOutputType())
-						return m, nil
-					},
-				},
-				IsServerStreaming: d.IsServerStreaming(),
-				IsClientStreaming: d.IsClientStreaming(),
-			}, nil
-		}
-	}
-	return nil, idl.ErrUnknownRPCName
+	d, ok := rpcs[rpcName]
+	if !ok {
+		return nil, idl.ErrUnknownRPCName
+	}
+	return &grpc.RPC{
+		Name:               d.GetName(),
+		FullyQualifiedName: d.GetFullyQualifiedName(),
+		// Requests are filled by fillers which require *dynamic.Message, so they are not wrapped.
+		RequestType:       newType(d.GetInputType(), s.msgFactory, nil),
+		ResponseType:      newType(d.GetOutputType(), s.msgFactory, s.anyResolver),
+		IsServerStreaming: d.IsServerStreaming(),
+		IsClientStreaming: d.IsClientStreaming(),
+		Comment:           Comment(d),
+		Deprecated:        d.GetMethodOptions().GetDeprecated(),
+		ServiceDeprecated: d.GetService().GetServiceOptions().GetDeprecated(),
+	}, nil
+}
+
+// Comment returns the leading comment of d. The space after "//" is trimmed from each line.
+// It returns an empty string if d has no source code info such that descriptors fetched by gRPC reflection
+// from servers which don't keep comments.
+func Comment(d desc.Descriptor) string {
+	lines := strings.Split(strings.TrimSpace(d.GetSourceInfo().GetLeadingComments()), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, " ")
+	}
+	return strings.Join(lines, "\n")
 }
 
 // ResolveSymbol returns the descriptor of the passed fully-qualified descriptor name.
 // The actual type of the returned interface{} implements desc.Descriptor.
 func (s *spec) ResolveSymbol(symbol string) (interface{}, error) {
+	if d, ok := s.symbols[symbol]; ok {
+		return d, nil
+	}
+	// Fallback for symbols that are not indexed such as fields or enum values.
 	for _, f := range s.fileDescs {
 		d := f.FindSymbol(symbol)
 		if d != nil {
@@ -107,6 +127,93 @@ func (s *spec) ResolveSymbol(symbol string) (interface{}, error) {
 	return nil, idl.ErrUnknownSymbol
 }
 
+// MessageType returns the type of the passed fully-qualified message name.
+func (s *spec) MessageType(fqmn string) (*grpc.Type, error) {
+	d, err := s.ResolveSymbol(fqmn)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return nil, idl.ErrUnknownSymbol
+	}
+	return newType(md, s.msgFactory, s.anyResolver), nil
+}
+
+// MessageNames returns all loaded message names. Map entry messages are excluded because
+// they are generated implicitly for map fields.
+func (s *spec) MessageNames() []string {
+	var names []string
+	for name, d := range s.symbols {
+		if md, ok := d.(*desc.MessageDescriptor); ok && !md.IsMapEntry() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newType returns the type of md. Instances of the type are created by mf.
+// If r is not nil, instances of the type resolve message types embedded in
+// google.protobuf.Any by r when they are marshaled to or unmarshaled from JSON.
+func newType(md *desc.MessageDescriptor, mf *dynamic.MessageFactory, r jsonpb.AnyResolver) *grpc.Type {
+	return &grpc.Type{
+		Name:               md.GetName(),
+		FullyQualifiedName: md.GetFullyQualifiedName(),
+		New: func() (interface{}, error) {
+			m := mf.NewDynamicMessage(md)
+			if r == nil {
+				return m, nil
+			}
+			return &message{Message: m, anyResolver: r}, nil
+		},
+	}
+}
+
+// message is a dynamic message which resolves message types embedded in google.protobuf.Any by anyResolver.
+// *dynamic.Message itself resolves them only from the file that defines the message and its dependencies,
+// but types embedded in Any are often defined in other files.
+type message struct {
+	*dynamic.Message
+	anyResolver jsonpb.AnyResolver
+}
+
+// UnwrapMessage returns the dynamic message of v which is instantiated by grpc.Type of the spec.
+// If v is neither a *dynamic.Message nor a message wrapping it, UnwrapMessage returns false.
+func UnwrapMessage(v interface{}) (*dynamic.Message, bool) {
+	switch m := v.(type) {
+	case *dynamic.Message:
+		return m, true
+	case *message:
+		return m.Message, true
+	}
+	return nil, false
+}
+
+func (m *message) MarshalJSON() ([]byte, error) {
+	return m.MarshalJSONPB(&jsonpb.Marshaler{})
+}
+
+func (m *message) MarshalJSONPB(opts *jsonpb.Marshaler) ([]byte, error) {
+	o := *opts
+	if o.AnyResolver == nil {
+		o.AnyResolver = m.anyResolver
+	}
+	return m.Message.MarshalJSONPB(&o)
+}
+
+func (m *message) UnmarshalJSON(js []byte) error {
+	return m.UnmarshalJSONPB(&jsonpb.Unmarshaler{}, js)
+}
+
+func (m *message) UnmarshalJSONPB(opts *jsonpb.Unmarshaler, js []byte) error {
+	o := *opts
+	if o.AnyResolver == nil {
+		o.AnyResolver = m.anyResolver
+	}
+	return m.Message.UnmarshalJSONPB(&o, js)
+}
+
 // FormatDescriptor formats v as a Protocol Buffers descriptor type.
 // If v doesn't implement desc.Descriptor, it returns an error.
 func (s *spec) FormatDescriptor(v interface{}) (string, error) {
@@ -126,23 +233,281 @@ func (s *spec) FormatDescriptor(v interface{}) (string, error) {
 	return strings.TrimSpace(str), nil
 }
 
+// ExportFiles returns proto files which define the passed services and their dependencies.
+// Each file is reconstructed from its descriptor, so that comments and formatting may differ from the original.
+func (s *spec) ExportFiles(svcNames []string) (map[string]string, error) {
+	fds, err := s.exportedFiles(svcNames)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoprint.Printer{}
+	files := make(map[string]string, len(fds))
+	for _, fd := range fds {
+		str, err := p.PrintProtoToString(fd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to print file '%s'", fd.GetName())
+		}
+		files[fd.GetName()] = str
+	}
+	return files, nil
+}
+
+// ExportDescriptorSet returns the serialized FileDescriptorSet which contains the same files as ExportFiles.
+// It is the same format as outputs of protoc --include_imports --descriptor_set_out, so that it can be
+// loaded by LoadProtosets.
+func (s *spec) ExportDescriptorSet(svcNames []string) ([]byte, error) {
+	fds, err := s.exportedFiles(svcNames)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptor.FileDescriptorSet
+	for _, fd := range fds {
+		set.File = append(set.File, fd.AsFileDescriptorProto())
+	}
+	b, err := proto.Marshal(&set)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal FileDescriptorSet")
+	}
+	return b, nil
+}
+
+// exportedFiles returns files which define svcNames and their dependencies in topological order.
+// If svcNames is empty, all loaded files are returned.
+func (s *spec) exportedFiles(svcNames []string) ([]*desc.FileDescriptor, error) {
+	var roots []*desc.FileDescriptor
+	if len(svcNames) == 0 {
+		roots = s.fileDescs
+	}
+	for _, name := range svcNames {
+		d, ok := s.symbols[name].(*desc.ServiceDescriptor)
+		if !ok {
+			return nil, idl.ErrUnknownServiceName
+		}
+		roots = append(roots, d.GetFile())
+	}
+
+	var (
+		fds         []*desc.FileDescriptor
+		encountered = make(map[string]interface{})
+		visit       func(fd *desc.FileDescriptor)
+	)
+	visit = func(fd *desc.FileDescriptor) {
+		if _, ok := encountered[fd.GetName()]; ok {
+			return
+		}
+		encountered[fd.GetName()] = nil
+		for _, dep := range fd.GetDependencies() {
+			visit(dep)
+		}
+		fds = append(fds, fd)
+	}
+	for _, fd := range roots {
+		visit(fd)
+	}
+	return fds, nil
+}
+
+// Merge merges files contained in the passed specs into a new idl.Spec. It is used to complement
+// descriptors which are not provided by gRPC reflection such that messages embedded in google.protobuf.Any.
+// If files which have the same name are contained in several specs, the file in the preceding spec is used.
+// All specs must be instantiated by this package.
+func Merge(specs ...idl.Spec) idl.Spec {
+	var fds []*desc.FileDescriptor
+	for _, s := range specs {
+		ps, ok := s.(*spec)
+		if !ok {
+			panic(fmt.Sprintf("Merge accepts only specs instantiated by package proto, but got %T", s))
+		}
+		fds = append(fds, ps.fileDescs...)
+	}
+	return newSpec(fds)
+}
+
 // LoadFiles receives proto file names and import paths like protoc's options.
 // Then, LoadFiles parses these files and instantiates a new idl.Spec.
+// File names may be glob patterns such that "api/**/*.proto" or directories, which are expanded
+// to proto files relative to the import paths.
 func LoadFiles(importPaths []string, fnames []string) (idl.Spec, error) {
+	fnames, err := expandFiles(importPaths, fnames)
+	if err != nil {
+		return nil, err
+	}
 	p := &protoparse.Parser{
-		ImportPaths: importPaths,
+		ImportPaths:           importPaths,
+		IncludeSourceCodeInfo: true,
 	}
 	fileDescs, err := p.ParseFiles(fnames...)
 	if err != nil {
 		return nil, errors.Wrap(err, "proto: failed to parse passed proto files")
 	}
 
-	// Collect dependency file descriptors
-	for _, d := range fileDescs {
-		fileDescs = append(fileDescs, d.GetDependencies()...)
+	return newSpec(withDependencies(fileDescs)), nil
+}
+
+// ResolvedImport describes a file which is found by import path discovery.
+type ResolvedImport struct {
+	// Name is the file name referred by import statements.
+	Name string
+	// ImportPath is the discovered import path which contains the file.
+	ImportPath string
+}
+
+// wellKnownVendorDirs is directories which often contain vendored proto files.
+// They are joined with each directory walked by import path discovery.
+var wellKnownVendorDirs = []string{"vendor", "third_party", filepath.Join("third_party", "googleapis"), "googleapis"}
+
+// LoadFilesWithDiscovery is similar to LoadFiles, but it resolves files which are not found in importPaths
+// by discovered import paths. They are the directories of importPaths and fnames, their parent directories and
+// well-known vendor directories such that vendor/ or third_party/ under them. Nearer directories take precedence.
+// LoadFilesWithDiscovery also returns which files are resolved from which discovered import paths.
+func LoadFilesWithDiscovery(importPaths []string, fnames []string) (idl.Spec, []*ResolvedImport, error) {
+	explicitPaths := importPaths
+	if len(explicitPaths) == 0 {
+		explicitPaths = []string{"."}
+	}
+	fnames, err := expandFiles(importPaths, fnames)
+	if err != nil {
+		return nil, nil, err
+	}
+	discoveredPaths := discoverImportPaths(explicitPaths, fnames)
+
+	var resolved []*ResolvedImport
+	p := &protoparse.Parser{
+		Accessor: func(name string) (io.ReadCloser, error) {
+			var firstErr error
+			for _, path := range explicitPaths {
+				f, err := os.Open(filepath.Join(path, name))
+				if err == nil {
+					return f, nil
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			for _, path := range discoveredPaths {
+				f, err := os.Open(filepath.Join(path, name))
+				if err == nil {
+					resolved = append(resolved, &ResolvedImport{Name: name, ImportPath: path})
+					return f, nil
+				}
+			}
+			return nil, firstErr
+		},
+		IncludeSourceCodeInfo: true,
+	}
+	fileDescs, err := p.ParseFiles(fnames...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "proto: failed to parse passed proto files")
+	}
+	sort.Slice(resolved, func(i, j int) bool {
+		return resolved[i].Name < resolved[j].Name
+	})
+	return newSpec(withDependencies(fileDescs)), resolved, nil
+}
+
+// discoverImportPaths returns existing directories which may be import paths. explicitPaths are excluded.
+func discoverImportPaths(explicitPaths []string, fnames []string) []string {
+	var starts []string
+	for _, path := range explicitPaths {
+		starts = append(starts, path)
+		for _, fname := range fnames {
+			starts = append(starts, filepath.Dir(filepath.Join(path, fname)))
+		}
 	}
 
-	return newSpec(fileDescs), nil
+	encountered := make(map[string]interface{})
+	for _, path := range explicitPaths {
+		if abs, err := filepath.Abs(path); err == nil {
+			encountered[abs] = nil
+		}
+	}
+	var paths []string
+	add := func(dir string) {
+		if _, ok := encountered[dir]; ok {
+			return
+		}
+		encountered[dir] = nil
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			paths = append(paths, dir)
+		}
+	}
+	for _, start := range starts {
+		dir, err := filepath.Abs(start)
+		if err != nil {
+			continue
+		}
+		for {
+			add(dir)
+			for _, v := range wellKnownVendorDirs {
+				add(filepath.Join(dir, v))
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return paths
+}
+
+// LoadProtosets receives file names of FileDescriptorSets such that outputs of protoc --descriptor_set_out or buf build.
+// Then, LoadProtosets instantiates a new idl.Spec from all files contained in them.
+// Each set must contain its dependencies (e.g. built by protoc --include_imports) unless they are contained in other sets.
+func LoadProtosets(fnames []string) (idl.Spec, error) {
+	var (
+		set         descriptor.FileDescriptorSet
+		encountered = make(map[string]interface{})
+	)
+	for _, fname := range fnames {
+		b, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "proto: failed to read protoset '%s'", fname)
+		}
+		var fds descriptor.FileDescriptorSet
+		if err := proto.Unmarshal(b, &fds); err != nil {
+			return nil, errors.Wrapf(err, "proto: failed to unmarshal protoset '%s'", fname)
+		}
+		for _, fd := range fds.GetFile() {
+			if _, ok := encountered[fd.GetName()]; ok {
+				continue
+			}
+			encountered[fd.GetName()] = nil
+			set.File = append(set.File, fd)
+		}
+	}
+	fileDescs, err := createFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, errors.Wrap(err, "proto: failed to create file descriptors from protosets. dependencies may be missing")
+	}
+	return newSpec(withDependencies(fileDescs)), nil
+}
+
+// LoadDescriptorSet instantiates a new idl.Spec from a serialized FileDescriptorSet such that
+// the one exported by idl.Spec.ExportDescriptorSet.
+func LoadDescriptorSet(b []byte) (idl.Spec, error) {
+	var set descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, errors.Wrap(err, "proto: failed to unmarshal the descriptor set")
+	}
+	fileDescs, err := createFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, errors.Wrap(err, "proto: failed to create file descriptors from the descriptor set")
+	}
+	return newSpec(withDependencies(fileDescs)), nil
+}
+
+// createFileDescriptorsFromSet creates file descriptors in the same order as set.
+func createFileDescriptorsFromSet(set *descriptor.FileDescriptorSet) ([]*desc.FileDescriptor, error) {
+	files, err := desc.CreateFileDescriptorsFromSet(set)
+	if err != nil {
+		return nil, err
+	}
+	fileDescs := make([]*desc.FileDescriptor, 0, len(files))
+	for _, fd := range set.GetFile() {
+		fileDescs = append(fileDescs, files[fd.GetName()])
+	}
+	return fileDescs, nil
 }
 
 // LoadByReflection receives a gRPC reflection client, then tries to instantiate a new idl.Spec by using gRPC reflection.
@@ -151,19 +516,40 @@ func LoadByReflection(client grpcreflection.Client) (idl.Spec, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list packages by gRPC reflection")
 	}
-	return newSpec(fileDescs), nil
+	// Messages in imported files are also needed to resolve symbols, the same as LoadFiles.
+	return newSpec(withDependencies(fileDescs)), nil
+}
+
+// withDependencies collects dependency file descriptors.
+func withDependencies(fds []*desc.FileDescriptor) []*desc.FileDescriptor {
+	for _, d := range fds {
+		fds = append(fds, d.GetDependencies()...)
+	}
+	return fds
 }
 
 func newSpec(fds []*desc.FileDescriptor) idl.Spec {
 	var (
-		encounteredPkgs = make(map[string]interface{})
-		encounteredSvcs = make(map[string]interface{})
-		pkgNames        []string
-		svcDescs        []*desc.ServiceDescriptor
-		rpcDescs        = make(map[string][]*desc.MethodDescriptor)
-		msgDescs        = make(map[string]*desc.MessageDescriptor)
+		encounteredFiles = make(map[string]interface{})
+		encounteredPkgs  = make(map[string]interface{})
+		encounteredSvcs  = make(map[string]interface{})
+		pkgNames         []string
+		svcDescs         []*desc.ServiceDescriptor
+		rpcDescs         = make(map[string][]*desc.MethodDescriptor)
+		rpcIndex         = make(map[string]map[string]*desc.MethodDescriptor)
+		msgDescs         = make(map[string]*desc.MessageDescriptor)
+		symbols          = make(map[string]desc.Descriptor)
+		files            []*desc.FileDescriptor
 	)
 	for _, f := range fds {
+		// The same file may be passed several times. For example, gRPC reflection returns
+		// the same file for each service belonging to it.
+		if _, encountered := encounteredFiles[f.GetName()]; encountered {
+			continue
+		}
+		encounteredFiles[f.GetName()] = nil
+		files = append(files, f)
+
 		if _, encountered := encounteredPkgs[f.GetPackage()]; !encountered {
 			pkgNames = append(pkgNames, f.GetPackage())
 			encounteredPkgs[f.GetPackage()] = nil
@@ -175,10 +561,25 @@ func newSpec(fds []*desc.FileDescriptor) idl.Spec {
 				encounteredSvcs[fqsn] = nil
 			}
 			rpcDescs[fqsn] = append(rpcDescs[fqsn], svc.GetMethods()...)
+			if _, ok := rpcIndex[fqsn]; !ok {
+				rpcIndex[fqsn] = make(map[string]*desc.MethodDescriptor)
+			}
+			symbols[fqsn] = svc
+			for _, m := range svc.GetMethods() {
+				// Keep the first one if there are duplicated methods, the same as the previous linear scan.
+				if _, found := rpcIndex[fqsn][m.GetName()]; !found {
+					rpcIndex[fqsn][m.GetName()] = m
+				}
+				symbols[m.GetFullyQualifiedName()] = m
+			}
 		}
 
 		for _, m := range f.GetMessageTypes() {
 			msgDescs[m.GetFullyQualifiedName()] = m
+			indexMessage(symbols, m)
+		}
+		for _, e := range f.GetEnumTypes() {
+			symbols[e.GetFullyQualifiedName()] = e
 		}
 	}
 
@@ -186,12 +587,36 @@ func newSpec(fds []*desc.FileDescriptor) idl.Spec {
 		return pkgNames[i] < pkgNames[j]
 	})
 
+	// Extensions may be defined in files other than the file defining the extended message,
+	// so that they are collected from all files.
+	er := dynamic.NewExtensionRegistryWithDefaults()
+	for _, f := range files {
+		er.AddExtensionsFromFile(f)
+	}
+	mf := dynamic.NewMessageFactoryWithExtensionRegistry(er)
+
 	return &spec{
 		fileDescs: fds,
 		pkgNames:  pkgNames,
 		svcDescs:  svcDescs,
 		rpcDescs:  rpcDescs,
+		rpcIndex:  rpcIndex,
 		msgDescs:  msgDescs,
+		symbols:   symbols,
+
+		msgFactory:  mf,
+		anyResolver: dynamic.AnyResolver(mf, files...),
+	}
+}
+
+// indexMessage registers m and its nested messages and enums to symbols.
+func indexMessage(symbols map[string]desc.Descriptor, m *desc.MessageDescriptor) {
+	symbols[m.GetFullyQualifiedName()] = m
+	for _, n := range m.GetNestedMessageTypes() {
+		indexMessage(symbols, n)
+	}
+	for _, e := range m.GetNestedEnumTypes() {
+		symbols[e.GetFullyQualifiedName()] = e
 	}
 }
 
@@ -225,3 +650,16 @@ func ParseFullyQualifiedServiceName(fqsn string) (string, string) {
 	}
 	return fqsn[:i], fqsn[i+1:]
 }
+
+// LookupRPC returns the RPC specified by the fully-qualified method name fqmn such that "api.Example.Unary".
+func LookupRPC(spec idl.Spec, fqmn string) (*grpc.RPC, error) {
+	i := strings.LastIndex(fqmn, ".")
+	if i == -1 {
+		return nil, errors.Errorf("invalid fully-qualified method name '%s'", fqmn)
+	}
+	rpc, err := spec.RPC(fqmn[:i], fqmn[i+1:])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find method '%s'", fqmn)
+	}
+	return rpc, nil
+}
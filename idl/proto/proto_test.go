@@ -1,11 +1,21 @@
 package proto_test
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	protov1 "github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
 	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
+	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
 )
 
@@ -35,6 +45,73 @@ func TestLoadFiles(t *testing.T) {
 	}
 }
 
+func TestLoadProtosets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// writeProtoset writes a FileDescriptorSet which contains fnames.
+	writeProtoset := func(name string, fnames ...string) string {
+		p := &protoparse.Parser{ImportPaths: []string{"testdata"}}
+		fds, err := p.ParseFiles(fnames...)
+		if err != nil {
+			t.Fatalf("failed to parse proto files: %s", err)
+		}
+		var set descriptor.FileDescriptorSet
+		for _, fd := range fds {
+			set.File = append(set.File, fd.AsFileDescriptorProto())
+		}
+		b, err := protov1.Marshal(&set)
+		if err != nil {
+			t.Fatalf("failed to marshal the protoset: %s", err)
+		}
+		fname := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fname, b, 0600); err != nil {
+			t.Fatalf("failed to write the protoset: %s", err)
+		}
+		return fname
+	}
+	withImports := writeProtoset("with_imports.pb", "message.proto", "api.proto")
+	withoutImports := writeProtoset("without_imports.pb", "api.proto")
+	imports := writeProtoset("imports.pb", "message.proto")
+	invalid := filepath.Join(dir, "invalid.pb")
+	if err := ioutil.WriteFile(invalid, []byte("invalid"), 0600); err != nil {
+		t.Fatalf("failed to write the protoset: %s", err)
+	}
+
+	cases := map[string]struct {
+		fnames []string
+		hasErr bool
+	}{
+		"normal":                           {fnames: []string{withImports}},
+		"dependencies in another protoset": {fnames: []string{withoutImports, imports}},
+		"duplicated files":                 {fnames: []string{withImports, imports}},
+		"dependencies are missing":         {fnames: []string{withoutImports}, hasErr: true},
+		"invalid protoset":                 {fnames: []string{invalid}, hasErr: true},
+		"not found":                        {fnames: []string{filepath.Join(dir, "not_found.pb")}, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			spec, err := proto.LoadProtosets(c.fnames)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("LoadProtosets must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadProtosets must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff([]string{"api.Example"}, spec.ServiceNames()); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
+
 type reflectionClient struct {
 	grpcreflection.Client
 	descs []*desc.FileDescriptor
@@ -54,6 +131,30 @@ func TestLoadByReflection(t *testing.T) {
 		}
 	})
 
+	t.Run("same files and dependencies", func(t *testing.T) {
+		p := &protoparse.Parser{ImportPaths: []string{"testdata"}}
+		fds, err := p.ParseFiles("api.proto")
+		if err != nil {
+			t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+		}
+		// gRPC reflection returns the same file for each service, and it doesn't contain dependencies.
+		refCli := &reflectionClient{descs: []*desc.FileDescriptor{fds[0], fds[0]}}
+		spec, err := proto.LoadByReflection(refCli)
+		if err != nil {
+			t.Fatalf("must not return an error, but got '%s'", err)
+		}
+		rpcs, err := spec.RPCs("api.Example")
+		if err != nil {
+			t.Fatalf("RPCs must not return an error, but got '%s'", err)
+		}
+		if n := len(rpcs); n != 1 {
+			t.Errorf("expected 1 RPC, but got %d", n)
+		}
+		if _, err := spec.ResolveSymbol("api.Person"); err != nil {
+			t.Errorf("ResolveSymbol must resolve a message in the dependency, but got '%s'", err)
+		}
+	})
+
 	t.Run("reflection client returns an error", func(t *testing.T) {
 		refCli := &reflectionClient{err: errors.New("an err")}
 		_, err := proto.LoadByReflection(refCli)
@@ -62,3 +163,311 @@ func TestLoadByReflection(t *testing.T) {
 		}
 	})
 }
+
+func TestSpec_MessageType(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+
+	cases := map[string]struct {
+		fqmn   string
+		hasErr bool
+	}{
+		"normal":          {fqmn: "api.Request"},
+		"dependency":      {fqmn: "api.Person"},
+		"not a message":   {fqmn: "api.Example", hasErr: true},
+		"unknown message": {fqmn: "api.Kumiko", hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			typ, err := spec.MessageType(c.fqmn)
+			if c.hasErr {
+				if !errors.Is(err, idl.ErrUnknownSymbol) {
+					t.Errorf("MessageType must return ErrUnknownSymbol, but got '%v'", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MessageType must not return an error, but got '%s'", err)
+			}
+			if typ.FullyQualifiedName != c.fqmn {
+				t.Errorf("expected '%s', but got '%s'", c.fqmn, typ.FullyQualifiedName)
+			}
+			if _, err := typ.New(); err != nil {
+				t.Errorf("New must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
+
+func TestSpec_ResponseTypeResolvesAny(t *testing.T) {
+	// message.proto isn't imported by any.proto, so only the spec knows api.Book.
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"any.proto", "message.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	rpc, err := spec.RPC("api.AnyService", "Get")
+	if err != nil {
+		t.Fatalf("RPC must not return an error, but got '%s'", err)
+	}
+	res, err := rpc.ResponseType.New()
+	if err != nil {
+		t.Fatalf("New must not return an error, but got '%s'", err)
+	}
+
+	in := `{"payload":{"@type":"type.googleapis.com/api.Book","title":"Hibike! Euphonium"}}`
+	if err := json.Unmarshal([]byte(in), res); err != nil {
+		t.Fatalf("Unmarshal must not return an error, but got '%s'", err)
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("Marshal must not return an error, but got '%s'", err)
+	}
+	if string(b) != in {
+		t.Errorf("expected '%s', but got '%s'", in, string(b))
+	}
+}
+
+func TestSpec_RPCComment(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"comment.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	cases := map[string]string{
+		"Get":  "Get returns the entity.\nIt fails if the entity is not found.",
+		"List": "",
+	}
+	for name, expected := range cases {
+		rpc, err := spec.RPC("api.CommentService", name)
+		if err != nil {
+			t.Fatalf("RPC must not return an error, but got '%s'", err)
+		}
+		if rpc.Comment != expected {
+			t.Errorf("%s: expected '%s', but got '%s'", name, expected, rpc.Comment)
+		}
+	}
+}
+
+func TestSpec_MessageNames(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	expected := []string{"api.Book", "api.Person", "api.Request", "api.Response"}
+	if diff := cmp.Diff(expected, spec.MessageNames()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
+
+func TestSpec_Export(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto", "any.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+
+	t.Run("ExportDescriptorSet", func(t *testing.T) {
+		b, err := spec.ExportDescriptorSet([]string{"api.Example"})
+		if err != nil {
+			t.Fatalf("ExportDescriptorSet must not return an error, but got '%s'", err)
+		}
+		var set descriptor.FileDescriptorSet
+		if err := protov1.Unmarshal(b, &set); err != nil {
+			t.Fatalf("failed to unmarshal the exported protoset: %s", err)
+		}
+		var fnames []string
+		for _, fd := range set.GetFile() {
+			fnames = append(fnames, fd.GetName())
+		}
+		// Dependencies must precede files which import them.
+		if diff := cmp.Diff([]string{"message.proto", "api.proto"}, fnames); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+		fname := filepath.Join(dir, "exported.pb")
+		if err := ioutil.WriteFile(fname, b, 0600); err != nil {
+			t.Fatalf("failed to write the protoset: %s", err)
+		}
+		exported, err := proto.LoadProtosets([]string{fname})
+		if err != nil {
+			t.Fatalf("LoadProtosets must load the exported protoset, but got '%s'", err)
+		}
+		if diff := cmp.Diff([]string{"api.Example"}, exported.ServiceNames()); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+
+	t.Run("ExportFiles", func(t *testing.T) {
+		files, err := spec.ExportFiles(nil)
+		if err != nil {
+			t.Fatalf("ExportFiles must not return an error, but got '%s'", err)
+		}
+		for _, fname := range []string{"api.proto", "message.proto", "any.proto"} {
+			if _, ok := files[fname]; !ok {
+				t.Errorf("'%s' must be exported", fname)
+			}
+		}
+		if !strings.Contains(files["api.proto"], "service Example") {
+			t.Errorf("exported api.proto must contain the service, but got '%s'", files["api.proto"])
+		}
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		_, err := spec.ExportFiles([]string{"api.Kumiko"})
+		if !errors.Is(err, idl.ErrUnknownServiceName) {
+			t.Errorf("ExportFiles must return ErrUnknownServiceName, but got '%v'", err)
+		}
+	})
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	b, err := spec.ExportDescriptorSet(nil)
+	if err != nil {
+		t.Fatalf("ExportDescriptorSet must not return an error, but got '%s'", err)
+	}
+
+	loaded, err := proto.LoadDescriptorSet(b)
+	if err != nil {
+		t.Fatalf("LoadDescriptorSet must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(spec.ServiceNames(), loaded.ServiceNames()); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	if _, err := loaded.ResolveSymbol("api.Person"); err != nil {
+		t.Errorf("ResolveSymbol must resolve a message in the dependency, but got '%s'", err)
+	}
+
+	if _, err := proto.LoadDescriptorSet([]byte("invalid")); err == nil {
+		t.Errorf("LoadDescriptorSet must return an error if the descriptor set is invalid")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	load := func(fnames ...string) idl.Spec {
+		t.Helper()
+		spec, err := proto.LoadFiles([]string{"testdata"}, fnames)
+		if err != nil {
+			t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+		}
+		return spec
+	}
+
+	t.Run("Any", func(t *testing.T) {
+		// The server provides only any.proto, so api.Book is supplied by the local file.
+		spec := proto.Merge(load("any.proto"), load("message.proto"))
+		if diff := cmp.Diff([]string{"api.AnyService"}, spec.ServiceNames()); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+		rpc, err := spec.RPC("api.AnyService", "Get")
+		if err != nil {
+			t.Fatalf("RPC must not return an error, but got '%s'", err)
+		}
+		res, err := rpc.ResponseType.New()
+		if err != nil {
+			t.Fatalf("New must not return an error, but got '%s'", err)
+		}
+		in := `{"payload":{"@type":"type.googleapis.com/api.Book","title":"Hibike! Euphonium"}}`
+		if err := json.Unmarshal([]byte(in), res); err != nil {
+			t.Errorf("Unmarshal must resolve the type in the merged file, but got '%s'", err)
+		}
+	})
+
+	t.Run("extension", func(t *testing.T) {
+		spec := proto.Merge(load("proto2.proto"), load("extension.proto"))
+		rpc, err := spec.RPC("api.Proto2Service", "Get")
+		if err != nil {
+			t.Fatalf("RPC must not return an error, but got '%s'", err)
+		}
+		res, err := rpc.ResponseType.New()
+		if err != nil {
+			t.Fatalf("New must not return an error, but got '%s'", err)
+		}
+		// Field 100 (nickname) = "kumiko".
+		if err := protov1.Unmarshal([]byte("\xa2\x06\x06kumiko"), res.(protov1.Message)); err != nil {
+			t.Fatalf("Unmarshal must not return an error, but got '%s'", err)
+		}
+		b, err := json.Marshal(res)
+		if err != nil {
+			t.Fatalf("Marshal must not return an error, but got '%s'", err)
+		}
+		if expected := `{"[api.nickname]":"kumiko"}`; string(b) != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, string(b))
+		}
+	})
+
+	t.Run("extension input", func(t *testing.T) {
+		spec := proto.Merge(load("proto2.proto"), load("extension.proto"))
+		typ, err := spec.MessageType("api.Proto2Message")
+		if err != nil {
+			t.Fatalf("MessageType must not return an error, but got '%s'", err)
+		}
+		v, err := typ.New()
+		if err != nil {
+			t.Fatalf("New must not return an error, but got '%s'", err)
+		}
+		if err := json.Unmarshal([]byte(`{"name":"oumae","[api.nickname]":"kumiko"}`), v); err != nil {
+			t.Fatalf("Unmarshal must accept the extension key, but got '%s'", err)
+		}
+		msg, ok := proto.UnwrapMessage(v)
+		if !ok {
+			t.Fatalf("UnwrapMessage must unwrap the message instantiated by the spec")
+		}
+		exts := msg.GetKnownExtensions()
+		if n := len(exts); n != 1 {
+			t.Fatalf("expected 1 known extension, but got %d", n)
+		}
+		if actual := msg.GetField(exts[0]); actual != "kumiko" {
+			t.Errorf("expected 'kumiko', but got '%v'", actual)
+		}
+	})
+
+	t.Run("same files", func(t *testing.T) {
+		spec := proto.Merge(load("api.proto"), load("api.proto", "any.proto"))
+		rpcs, err := spec.RPCs("api.Example")
+		if err != nil {
+			t.Fatalf("RPCs must not return an error, but got '%s'", err)
+		}
+		if n := len(rpcs); n != 1 {
+			t.Errorf("expected 1 RPC, but got %d", n)
+		}
+		if diff := cmp.Diff([]string{"api.Example", "api.AnyService"}, spec.ServiceNames()); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+}
+
+func TestLoadFilesWithDiscovery(t *testing.T) {
+	importPaths, fnames := []string{filepath.Join("testdata", "discovery", "api")}, []string{"service.proto"}
+	if _, err := proto.LoadFiles(importPaths, fnames); err == nil {
+		t.Fatalf("LoadFiles must return an error because imports are not found in the import paths")
+	}
+
+	spec, resolved, err := proto.LoadFilesWithDiscovery(importPaths, fnames)
+	if err != nil {
+		t.Fatalf("LoadFilesWithDiscovery must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"api.DiscoveryService"}, spec.ServiceNames()); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	dir, err := filepath.Abs(filepath.Join("testdata", "discovery"))
+	if err != nil {
+		t.Fatalf("failed to get the absolute path: %s", err)
+	}
+	expected := []*proto.ResolvedImport{
+		{Name: "common/types.proto", ImportPath: dir},
+		{Name: "vendored/lib.proto", ImportPath: filepath.Join(dir, "third_party")},
+	}
+	if diff := cmp.Diff(expected, resolved); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	fillproto "github.com/ktr0731/evans/fill/proto"
+)
+
+// maxFakeDepth is the maximum depth of nested messages filled with fake values. It stops recursive message types.
+const maxFakeDepth = 3
+
+// fakeMessage fills all fields of m with fake values.
+func fakeMessage(m *dynamic.Message) {
+	fillproto.NewWalker(fakeGenerator{}, maxFakeDepth).Fill(m)
+}
+
+// fakeGenerator is an implementation of fillproto.ValueGenerator. It generates the same fake values every time.
+// Repeated fields and maps have an element, and only the first field of each oneof is filled.
+type fakeGenerator struct{}
+
+func (fakeGenerator) OneOf(o *desc.OneOfDescriptor) *desc.FieldDescriptor {
+	return o.GetChoices()[0]
+}
+
+func (fakeGenerator) Len(*desc.FieldDescriptor) int {
+	return 1
+}
+
+// Value returns a fake value of the type of f. Strings and bytes are the field name.
+func (fakeGenerator) Value(f *desc.FieldDescriptor) interface{} {
+	switch f.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return f.GetName()
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return []byte(f.GetName())
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return true
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int32(1)
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return int64(1)
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint32(1)
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return uint64(1)
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float32(1.5)
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return 1.5
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		// Prefer a non-zero value because the zero value is usually UNSPECIFIED.
+		vals := f.GetEnumType().GetValues()
+		for _, v := range vals {
+			if v.GetNumber() != 0 {
+				return v.GetNumber()
+			}
+		}
+		return vals[0].GetNumber()
+	default:
+		return nil
+	}
+}
+
+// Message returns an empty message of the type of f which is filled by the walker.
+func (fakeGenerator) Message(f *desc.FieldDescriptor) (*dynamic.Message, bool) {
+	// The type URL of Any must be resolvable by clients, so that it is left unset.
+	if f.GetMessageType().GetFullyQualifiedName() == "google.protobuf.Any" {
+		return nil, false
+	}
+	return dynamic.NewMessage(f.GetMessageType()), true
+}
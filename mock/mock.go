@@ -0,0 +1,193 @@
+// Package mock provides a gRPC server which serves all RPCs of the loaded spec without a real implementation.
+// Each RPC responds with canned responses if they are given, or fake responses generated from the response type.
+package mock
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/fill"
+	evansgrpc "github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// Responses is canned responses keyed by fully-qualified method names such that "api.Example.Unary".
+// Each value is a YAML document which represents a response or a sequence of responses.
+type Responses map[string][]byte
+
+// LoadResponses loads canned responses from the YAML file. Top-level keys of the file are fully-qualified
+// method names, and each value is a response or a sequence of responses.
+func LoadResponses(fname string) (Responses, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the responses file")
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the responses file as YAML")
+	}
+	res := make(Responses, len(m))
+	for k, v := range m {
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode responses of '%s'", k)
+		}
+		res[k] = b
+	}
+	return res, nil
+}
+
+// Server is a mock gRPC server.
+type Server struct {
+	spec idl.Spec
+	// responses is decoded canned responses keyed by fully-qualified method names.
+	responses map[string][]interface{}
+	server    *grpc.Server
+}
+
+// NewServer returns a new mock server which serves all RPCs of spec.
+// RPCs which have canned responses respond with them, and other RPCs respond with a fake message.
+// Unary and client streaming RPCs respond with the first response, server streaming RPCs respond with all
+// responses, and bidi streaming RPCs respond with the next response for each request.
+func NewServer(spec idl.Spec, responses Responses) (*Server, error) {
+	s := &Server{spec: spec, responses: make(map[string][]interface{})}
+	for name, b := range responses {
+		rpc, err := s.rpc(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unknown method '%s'", name)
+		}
+		filler := fill.NewSilentYAMLFiller(bytes.NewReader(b))
+		for {
+			res, err := rpc.ResponseType.New()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to instantiate a response of '%s'", name)
+			}
+			err = filler.Fill(res)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid response of '%s'", name)
+			}
+			s.responses[name] = append(s.responses[name], res)
+		}
+		if len(s.responses[name]) == 0 {
+			return nil, errors.Errorf("no responses for '%s'", name)
+		}
+	}
+	s.server = grpc.NewServer(grpc.UnknownServiceHandler(s.handle))
+	return s, nil
+}
+
+// Serve accepts connections on l and serves RPCs until Stop is called.
+func (s *Server) Serve(l net.Listener) error {
+	return s.server.Serve(l)
+}
+
+// Stop stops the server after all pending RPCs are finished.
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// rpc returns the RPC specified by a fully-qualified method name such that "api.Example.Unary".
+func (s *Server) rpc(fqmn string) (*evansgrpc.RPC, error) {
+	i := strings.LastIndex(fqmn, ".")
+	if i == -1 {
+		return nil, idl.ErrUnknownRPCName
+	}
+	return s.spec.RPC(fqmn[:i], fqmn[i+1:])
+}
+
+func (s *Server) handle(_ interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	// method has the form of "/<service>/<method>".
+	fqmn := strings.Replace(strings.TrimPrefix(method, "/"), "/", ".", 1)
+	rpc, err := s.rpc(fqmn)
+	if err != nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	logger.Printf("mock: %s is called", fqmn)
+
+	responses, ok := s.responses[fqmn]
+	if !ok {
+		res, err := s.fakeResponse(rpc)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to generate a fake response: %s", err)
+		}
+		responses = []interface{}{res}
+	}
+	recv := func() error {
+		req, err := rpc.RequestType.New()
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to instantiate a request: %s", err)
+		}
+		return stream.RecvMsg(req)
+	}
+
+	switch {
+	case rpc.IsClientStreaming && rpc.IsServerStreaming:
+		for i := 0; ; i++ {
+			err := recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.SendMsg(responses[i%len(responses)]); err != nil {
+				return err
+			}
+		}
+	case rpc.IsClientStreaming:
+		for {
+			err := recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return stream.SendMsg(responses[0])
+	case rpc.IsServerStreaming:
+		if err := recv(); err != nil {
+			return err
+		}
+		for _, res := range responses {
+			if err := stream.SendMsg(res); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if err := recv(); err != nil {
+			return err
+		}
+		return stream.SendMsg(responses[0])
+	}
+}
+
+func (s *Server) fakeResponse(rpc *evansgrpc.RPC) (*dynamic.Message, error) {
+	d, err := s.spec.ResolveSymbol(rpc.ResponseType.FullyQualifiedName)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("'%s' is not a message", rpc.ResponseType.FullyQualifiedName)
+	}
+	m := dynamic.NewMessage(md)
+	fakeMessage(m)
+	return m, nil
+}
@@ -0,0 +1,194 @@
+package mock_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/idl/proto"
+	"github.com/ktr0731/evans/mock"
+	"google.golang.org/grpc"
+)
+
+func startServer(t *testing.T, responses mock.Responses) (*grpc.ClientConn, func()) {
+	t.Helper()
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	srv, err := mock.NewServer(spec, responses)
+	if err != nil {
+		t.Fatalf("NewServer must not return an error, but got '%s'", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go srv.Serve(l)
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func newMessage(t *testing.T, name string) *dynamic.Message {
+	t.Helper()
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	d, err := spec.ResolveSymbol(name)
+	if err != nil {
+		t.Fatalf("ResolveSymbol must not return an error, but got '%s'", err)
+	}
+	return dynamic.NewMessage(d.(*desc.MessageDescriptor))
+}
+
+func toJSON(t *testing.T, m *dynamic.Message) map[string]interface{} {
+	t.Helper()
+	b, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal the message: %s", err)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("failed to unmarshal the message: %s", err)
+	}
+	return v
+}
+
+func TestServer_Fake(t *testing.T) {
+	conn, stop := startServer(t, nil)
+	defer stop()
+
+	res := newMessage(t, "api.Response")
+	if err := conn.Invoke(context.Background(), "/api.Example/Unary", newMessage(t, "api.Request"), res); err != nil {
+		t.Fatalf("Invoke must not return an error, but got '%s'", err)
+	}
+	v := toJSON(t, res)
+	for k, expected := range map[string]interface{}{
+		"message": "message",
+		"count":   float64(1),
+		"tags":    []interface{}{"tags"},
+		"scores":  map[string]interface{}{"key": "1"},
+		"status":  "ACTIVE",
+		"a":       "a",
+	} {
+		if diff := cmp.Diff(expected, v[k]); diff != "" {
+			t.Errorf("%s: -want, +got\n%s", k, diff)
+		}
+	}
+	if _, ok := v["b"]; ok {
+		t.Errorf("only the first field of the oneof must be filled")
+	}
+	if _, ok := v["child"].(map[string]interface{}); !ok {
+		t.Errorf("the nested message must be filled, but got %v", v["child"])
+	}
+	if _, ok := v["createdAt"].(string); !ok {
+		t.Errorf("the timestamp must be filled, but got %v", v["createdAt"])
+	}
+}
+
+func TestServer_Responses(t *testing.T) {
+	responses, err := mock.LoadResponses("testdata/responses.yaml")
+	if err != nil {
+		t.Fatalf("LoadResponses must not return an error, but got '%s'", err)
+	}
+	conn, stop := startServer(t, responses)
+	defer stop()
+	ctx := context.Background()
+
+	t.Run("unary", func(t *testing.T) {
+		res := newMessage(t, "api.Response")
+		if err := conn.Invoke(ctx, "/api.Example/Unary", newMessage(t, "api.Request"), res); err != nil {
+			t.Fatalf("Invoke must not return an error, but got '%s'", err)
+		}
+		if msg := res.GetFieldByName("message"); msg != "hello" {
+			t.Errorf("expected 'hello', but got '%s'", msg)
+		}
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/api.Example/ServerStreaming")
+		if err != nil {
+			t.Fatalf("NewStream must not return an error, but got '%s'", err)
+		}
+		if err := stream.SendMsg(newMessage(t, "api.Request")); err != nil {
+			t.Fatalf("SendMsg must not return an error, but got '%s'", err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			t.Fatalf("CloseSend must not return an error, but got '%s'", err)
+		}
+		var msgs []interface{}
+		for {
+			res := newMessage(t, "api.Response")
+			err := stream.RecvMsg(res)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("RecvMsg must not return an error, but got '%s'", err)
+			}
+			msgs = append(msgs, res.GetFieldByName("message"))
+		}
+		if diff := cmp.Diff([]interface{}{"foo", "bar"}, msgs); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+
+	t.Run("bidi streaming", func(t *testing.T) {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, "/api.Example/BidiStreaming")
+		if err != nil {
+			t.Fatalf("NewStream must not return an error, but got '%s'", err)
+		}
+		var msgs []interface{}
+		for i := 0; i < 3; i++ {
+			if err := stream.SendMsg(newMessage(t, "api.Request")); err != nil {
+				t.Fatalf("SendMsg must not return an error, but got '%s'", err)
+			}
+			res := newMessage(t, "api.Response")
+			if err := stream.RecvMsg(res); err != nil {
+				t.Fatalf("RecvMsg must not return an error, but got '%s'", err)
+			}
+			msgs = append(msgs, res.GetFieldByName("message"))
+		}
+		if diff := cmp.Diff([]interface{}{"foo", "bar", "foo"}, msgs); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		if err := conn.Invoke(ctx, "/api.Example/Unknown", newMessage(t, "api.Request"), newMessage(t, "api.Response")); err == nil {
+			t.Errorf("Invoke must return an error for unknown methods")
+		}
+	})
+}
+
+func TestNewServer(t *testing.T) {
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	cases := map[string]mock.Responses{
+		"unknown method": {"api.Example.Unknown": []byte("message: foo")},
+		"unknown field":  {"api.Example.Unary": []byte("foo: bar")},
+		"no responses":   {"api.Example.Unary": []byte("[]")},
+	}
+	for name, responses := range cases {
+		responses := responses
+		t.Run(name, func(t *testing.T) {
+			if _, err := mock.NewServer(spec, responses); err == nil {
+				t.Errorf("NewServer must return an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package mode
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ktr0731/evans/batch"
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/format"
+	"github.com/pkg/errors"
+)
+
+// RunBatch runs requests in the batch file, and writes the result of each request as a line of JSON.
+// fname is newline-delimited JSON or a JSON array, and "-" means stdin. At most opts.Parallel requests are run
+// concurrently, the rate of requests is limited by opts.Rate or opts.Stages, and results are written in the same
+// order as the requests.
+// RunBatch returns an error if one or more requests fail or return non-OK statuses.
+func RunBatch(cfg *config.Config, ui cui.UI, fname string, opts batch.Options) error {
+	var in io.Reader = os.Stdin
+	if fname != "-" {
+		f, err := os.Open(fname)
+		if err != nil {
+			return errors.Wrap(err, "failed to open the batch file")
+		}
+		defer f.Close()
+		in = f
+	}
+	reqs, err := batch.Load(in)
+	if err != nil {
+		return err
+	}
+	gRPCClient, err := newGRPCClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		gRPCClient.Close(ctx)
+	}()
+	spec, err := newSpec(cfg, gRPCClient)
+	if err != nil {
+		return err
+	}
+
+	// Each result must be a line, so that JSON is always compact.
+	jsonOpts := format.JSONOptions{
+		Compact:       true,
+		SortKeys:      cfg.Output.SortKeys,
+		EmitDefaults:  cfg.Output.EmitDefaults,
+		ProtoNames:    cfg.Output.ProtoNames,
+		Int64AsNumber: cfg.Output.Int64AsNumber,
+		BytesEncoding: cfg.Output.BytesEncoding,
+	}
+	runner := batch.NewRunner(spec, gRPCClient, cfg.Request.Header, newScopedHeaders(cfg.Request.ScopedHeaders), cfg.Request.Timeout, jsonOpts)
+	enc := json.NewEncoder(ui.Writer())
+	var (
+		failed int
+		encErr error
+	)
+	err = runner.Run(context.Background(), reqs, opts, func(r *batch.Result) {
+		if r.Failed() {
+			failed++
+		}
+		if encErr == nil {
+			encErr = enc.Encode(r)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if encErr != nil {
+		return errors.Wrap(encErr, "failed to write results")
+	}
+	if failed != 0 {
+		return errors.Errorf("%d of %d requests failed", failed, len(reqs))
+	}
+	return nil
+}
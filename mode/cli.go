@@ -3,22 +3,32 @@ package mode
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/fill"
 	"github.com/ktr0731/evans/format"
+	fmtcsv "github.com/ktr0731/evans/format/csv"
 	"github.com/ktr0731/evans/format/curl"
+	"github.com/ktr0731/evans/format/filter"
 	fmtjson "github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/format/ndjson"
+	"github.com/ktr0731/evans/format/prototext"
+	fmttable "github.com/ktr0731/evans/format/table"
+	fmttemplate "github.com/ktr0731/evans/format/template"
+	fmtyaml "github.com/ktr0731/evans/format/yaml"
 	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
 	"github.com/ktr0731/evans/present"
 	"github.com/ktr0731/evans/present/json"
 	"github.com/ktr0731/evans/present/name"
+	"github.com/ktr0731/evans/present/table"
 	"github.com/ktr0731/evans/usecase"
-	"github.com/ktr0731/go-multierror"
 	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 )
@@ -29,58 +39,144 @@ var DefaultCLIReader io.Reader = os.Stdin
 // CLIInvoker represents an invokable function for CLI mode.
 type CLIInvoker func(context.Context) error
 
-// NewCallCLIInvoker returns an CLIInvoker implementation for calling RPCs.
-// If filePath is empty, the invoker tries to read input from stdin.
-func NewCallCLIInvoker(ui cui.UI, methodName, filePath string, headers config.Header, enrich bool, formatType string) (CLIInvoker, error) {
+// CallCLIOptions is options for NewCallCLIInvoker.
+type CallCLIOptions struct {
+	// FilePath is the file of the input. If it is empty, the invoker tries to read input from stdin.
+	FilePath string
+	// Headers are added to each request.
+	Headers config.Header
+	// Enrich makes the output include headers, trailers and the status in addition to messages.
+	Enrich bool
+	// Stats writes the statistics of the call to the error output, so that they don't mix with responses.
+	Stats bool
+	// DenyDeprecated makes calling deprecated RPCs or sending deprecated fields fail.
+	// Otherwise, they are warned to the error output.
+	DenyDeprecated bool
+
+	// InputType is the format of the input, one of "json", "yaml", "prototext" or "binary".
+	// If it is empty, "json" is used. "binary" means serialized messages which are sent as they are.
+	InputType string
+	// FormatType is the format of responses such that "curl", "json" or "table".
+	FormatType string
+	// OutputTemplate is a Go template which formats each message instead of FormatType.
+	OutputTemplate string
+	// OutputFilter is a jq-style filter. Values extracted from each message are shown instead of FormatType.
+	// OutputTemplate and OutputFilter cannot be specified at the same time.
+	OutputFilter string
+	// RawResponsePath is the file which serialized responses are written to if it is not empty.
+	RawResponsePath string
+
+	// Output is the settings for formatting responses as JSON. It is required.
+	Output *config.Output
+	// Table is used if FormatType is "table". Columns of Table is also used if FormatType is "csv".
+	Table fmttable.Options
+	// Expect asserts the result of the call if it is not nil.
+	Expect *Expectation
+}
+
+// NewCallCLIInvoker returns an CLIInvoker implementation for calling methodName with opts.
+func NewCallCLIInvoker(ui cui.UI, methodName string, opts CallCLIOptions) (CLIInvoker, error) {
 	if methodName == "" {
 		return nil, errors.New("method is required")
 	}
+	switch opts.InputType {
+	case "", "json", "yaml", "prototext", "binary":
+	default:
+		return nil, errors.Errorf("unknown input format '%s'", opts.InputType)
+	}
+	if opts.OutputTemplate != "" && opts.OutputFilter != "" {
+		return nil, errors.New("output template and output filter cannot be specified at the same time")
+	}
+	jsonOpts := format.JSONOptions{
+		Compact:       opts.Output.Compact,
+		Indent:        opts.Output.Indent,
+		SortKeys:      opts.Output.SortKeys,
+		EmitDefaults:  opts.Output.EmitDefaults,
+		ProtoNames:    opts.Output.ProtoNames,
+		Int64AsNumber: opts.Output.Int64AsNumber,
+		BytesEncoding: opts.Output.BytesEncoding,
+	}
+	var rfi format.ResponseFormatterInterface
+	switch opts.FormatType {
+	case "curl":
+		rfi = curl.NewResponseFormatter(ui.Writer(), jsonOpts)
+	case "json":
+		rfi = fmtjson.NewResponseFormatter(ui.Writer(), jsonOpts)
+	case "ndjson":
+		rfi = ndjson.NewResponseFormatter(ui.Writer(), jsonOpts)
+	case "yaml":
+		rfi = fmtyaml.NewResponseFormatter(ui.Writer(), jsonOpts)
+	case "prototext":
+		rfi = prototext.NewResponseFormatter(ui.Writer())
+	case "table":
+		rfi = fmttable.NewResponseFormatter(ui.Writer(), opts.Table, jsonOpts)
+	case "csv":
+		rfi = fmtcsv.NewResponseFormatter(ui.Writer(), opts.Table.Columns, jsonOpts)
+	default:
+		rfi = curl.NewResponseFormatter(ui.Writer(), jsonOpts)
+	}
+	if opts.OutputTemplate != "" {
+		var err error
+		rfi, err = fmttemplate.NewResponseFormatter(ui.Writer(), opts.OutputTemplate, jsonOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.OutputFilter != "" {
+		var err error
+		rfi, err = filter.NewResponseFormatter(ui.Writer(), opts.OutputFilter, jsonOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var a *assertion
+	if opts.Expect != nil {
+		var err error
+		a, err = newAssertion(opts.Expect)
+		if err != nil {
+			return nil, err
+		}
+		rfi = a.wrap(rfi)
+	}
 	return func(ctx context.Context) error {
-		in := DefaultCLIReader
-		if filePath != "" {
-			f, err := os.Open(filePath)
+		in, closeInput, err := openCLIInput(opts.FilePath)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+		usecase.InjectPartially(usecase.Dependencies{
+			ResponseFormatter: format.NewResponseFormatter(rfi, opts.Enrich),
+			Filler:            newCLIFiller(in, opts.InputType),
+		})
+		warnDeprecatedUsage(ui, opts.DenyDeprecated)
+
+		if opts.RawResponsePath != "" {
+			f, err := os.Create(opts.RawResponsePath)
 			if err != nil {
-				return errors.Wrap(err, "failed to open the script file")
+				return errors.Wrap(err, "failed to create the raw response file")
 			}
 			defer f.Close()
-			in = f
-		}
-		filler := fill.NewSilentFiller(in)
-		var rfi format.ResponseFormatterInterface
-		switch formatType {
-		case "curl":
-			rfi = curl.NewResponseFormatter(ui.Writer())
-		case "json":
-			rfi = fmtjson.NewResponseFormatter(ui.Writer())
-		default:
-			rfi = curl.NewResponseFormatter(ui.Writer())
+			usecase.SetRawResponseWriter(f)
 		}
-		usecase.InjectPartially(usecase.Dependencies{
-			ResponseFormatter: format.NewResponseFormatter(rfi, enrich),
-			Filler:            filler,
-		})
 
-		for k, v := range headers {
+		for k, v := range opts.Headers {
 			for _, vv := range v {
 				usecase.AddHeader(k, vv)
 			}
 		}
 
-		// Try to parse methodName as a fully-qualified method name.
-		// If it is valid, use its fully-qualified service.
-		fqsn, mtd, err := usecase.ParseFullyQualifiedMethodName(methodName)
-		if err == nil {
-			pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
-			if err := usecase.UsePackage(pkg); err != nil {
-				return errors.Wrapf(err, "failed to use package '%s'", pkg)
-			}
-			if err := usecase.UseService(svc); err != nil {
-				return errors.Wrapf(err, "failed to use service '%s'", svc)
-			}
-			methodName = mtd
+		methodName, err := useMethodService(methodName)
+		if err != nil {
+			return err
 		}
 
 		err = usecase.CallRPC(ctx, ui.Writer(), methodName)
+		if s := usecase.LastCallStats(); opts.Stats && s != nil {
+			ui.Warn(s.String())
+		}
+		if a != nil && a.handles(err) {
+			return a.assert(err)
+		}
 		if err != nil {
 			return errors.Wrapf(err, "failed to call RPC '%s'", methodName)
 		}
@@ -88,6 +184,80 @@ func NewCallCLIInvoker(ui cui.UI, methodName, filePath string, headers config.He
 	}, nil
 }
 
+// NewDryRunCLIInvoker returns an CLIInvoker implementation for constructing requests of RPCs without sending them.
+// Each request is validated and written as JSON with its size in the wire format.
+// filePath and inputType are the same as CallCLIOptions.
+func NewDryRunCLIInvoker(ui cui.UI, methodName, filePath, inputType string) (CLIInvoker, error) {
+	if methodName == "" {
+		return nil, errors.New("method is required")
+	}
+	switch inputType {
+	case "", "json", "yaml", "prototext", "binary":
+	default:
+		return nil, errors.Errorf("unknown input format '%s'", inputType)
+	}
+	return func(ctx context.Context) error {
+		in, closeInput, err := openCLIInput(filePath)
+		if err != nil {
+			return err
+		}
+		defer closeInput()
+		usecase.InjectPartially(usecase.Dependencies{Filler: newCLIFiller(in, inputType)})
+
+		methodName, err := useMethodService(methodName)
+		if err != nil {
+			return err
+		}
+		if err := usecase.DryRunRPC(ui.Writer(), methodName); err != nil {
+			return errors.Wrapf(err, "failed to construct requests of RPC '%s'", methodName)
+		}
+		return nil
+	}, nil
+}
+
+// openCLIInput opens filePath as the input of requests. If filePath is empty, DefaultCLIReader is used.
+func openCLIInput(filePath string) (io.Reader, func(), error) {
+	if filePath == "" {
+		return DefaultCLIReader, func() {}, nil
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open the script file")
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func newCLIFiller(in io.Reader, inputType string) fill.Filler {
+	switch inputType {
+	case "yaml":
+		return fill.NewSilentYAMLFiller(in)
+	case "prototext":
+		return fill.NewSilentPrototextFiller(in)
+	case "binary":
+		return fill.NewSilentRawFiller(in)
+	default:
+		return fill.NewSilentFiller(in)
+	}
+}
+
+// useMethodService tries to parse methodName as a fully-qualified method name.
+// If it is valid, useMethodService uses its fully-qualified service and returns the method name.
+// Otherwise, methodName is returned as it is.
+func useMethodService(methodName string) (string, error) {
+	fqsn, mtd, err := usecase.ParseFullyQualifiedMethodName(methodName)
+	if err != nil {
+		return methodName, nil
+	}
+	pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
+	if err := usecase.UsePackage(pkg); err != nil {
+		return "", errors.Wrapf(err, "failed to use package '%s'", pkg)
+	}
+	if err := usecase.UseService(svc); err != nil {
+		return "", errors.Wrapf(err, "failed to use service '%s'", svc)
+	}
+	return mtd, nil
+}
+
 func NewListCLIInvoker(ui cui.UI, fqn, format string) CLIInvoker {
 	const (
 		fname = "name"
@@ -153,46 +323,188 @@ func NewListCLIInvoker(ui cui.UI, fqn, format string) CLIInvoker {
 	}
 }
 
-func NewDescribeCLIInvoker(ui cui.UI, fqn string) CLIInvoker {
+// NewDescribeCLIInvoker returns an CLIInvoker implementation for describing the descriptor of fqn.
+// If fqn is empty, all service descriptors are described. format is one of "proto" or "json".
+func NewDescribeCLIInvoker(ui cui.UI, fqn, format string) (CLIInvoker, error) {
+	var (
+		formatDescriptor         func(string) (string, error)
+		formatServiceDescriptors func() (string, error)
+	)
+	switch format {
+	case "proto":
+		formatDescriptor, formatServiceDescriptors = usecase.FormatDescriptor, usecase.FormatServiceDescriptors
+	case "json":
+		formatDescriptor, formatServiceDescriptors = usecase.FormatDescriptorJSON, usecase.FormatServiceDescriptorsJSON
+	default:
+		return nil, errors.Errorf("unknown output format '%s'", format)
+	}
 	return func(context.Context) error {
 		var (
 			out string
 			err error
 		)
 		if fqn != "" {
-			out, err = usecase.FormatDescriptor(fqn)
+			out, err = formatDescriptor(fqn)
 		} else {
-			out, err = usecase.FormatServiceDescriptors()
+			out, err = formatServiceDescriptors()
 		}
 		if err != nil {
 			return errors.Wrap(err, "failed to describe")
 		}
 		ui.Output(out)
 		return nil
+	}, nil
+}
+
+// NewHealthCLIInvoker returns an CLIInvoker implementation for checking the serving status of services.
+// If services is empty, the overall health of the server is checked.
+// If watch is true, changes of the serving status are watched instead.
+func NewHealthCLIInvoker(ui cui.UI, services []string, watch bool) CLIInvoker {
+	return func(ctx context.Context) error {
+		if watch {
+			return usecase.WatchHealth(ctx, ui.Writer(), services)
+		}
+		return usecase.CheckHealth(ctx, ui.Writer(), services)
+	}
+}
+
+// NewChannelzCLIInvoker returns an CLIInvoker implementation for inspecting the server by grpc.channelz.v1.Channelz.
+// target is one of "channels", "servers" or "sockets". "sockets" requires the server ID as args[0].
+// format is one of "table" or "json".
+func NewChannelzCLIInvoker(ui cui.UI, target string, args []string, format string) (CLIInvoker, error) {
+	var presenter present.Presenter
+	switch format {
+	case "table":
+		presenter = table.NewPresenter()
+	case "json":
+		presenter = json.NewPresenter("  ")
+	default:
+		return nil, errors.Errorf("unknown output format '%s'", format)
+	}
+	var f func(context.Context) (string, error)
+	switch target {
+	case "channels":
+		f = usecase.FormatChannelzChannels
+	case "servers":
+		f = usecase.FormatChannelzServers
+	case "sockets":
+		if len(args) == 0 {
+			return nil, errors.New("server ID is required")
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid server ID '%s'", args[0])
+		}
+		f = func(ctx context.Context) (string, error) {
+			return usecase.FormatChannelzSockets(ctx, id)
+		}
+	default:
+		return nil, errors.Errorf("unknown target '%s'", target)
+	}
+	return func(ctx context.Context) error {
+		usecase.InjectPartially(usecase.Dependencies{ResourcePresenter: presenter})
+		out, err := f(ctx)
+		if err != nil {
+			return err
+		}
+		ui.Output(out)
+		return nil
+	}, nil
+}
+
+// NewExportCLIInvoker returns an CLIInvoker implementation for exporting the schema of the passed services.
+// If services is empty, all loaded files are exported.
+// format is one of "protoset" or "proto". "protoset" writes a FileDescriptorSet to the file out, or stdout if out is empty.
+// "proto" writes reconstructed proto files under the directory out, so that out is required.
+func NewExportCLIInvoker(ui cui.UI, services []string, format, out string) (CLIInvoker, error) {
+	switch format {
+	case "protoset":
+		return func(context.Context) error {
+			b, err := usecase.ExportDescriptorSet(services)
+			if err != nil {
+				return err
+			}
+			if out == "" {
+				_, err := ui.Writer().Write(b)
+				return errors.Wrap(err, "failed to write the protoset")
+			}
+			if err := ioutil.WriteFile(out, b, 0644); err != nil {
+				return errors.Wrapf(err, "failed to write the protoset to '%s'", out)
+			}
+			return nil
+		}, nil
+	case "proto":
+		if out == "" {
+			return nil, errors.New("output directory is required to export proto files")
+		}
+		return func(context.Context) error {
+			files, err := usecase.ExportFiles(services)
+			if err != nil {
+				return err
+			}
+			for fname, content := range files {
+				path := filepath.Join(out, filepath.FromSlash(fname))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					return errors.Wrapf(err, "failed to create the directory for '%s'", fname)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					return errors.Wrapf(err, "failed to write '%s'", fname)
+				}
+			}
+			return nil
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown export format '%s'", format)
+	}
+}
+
+// RunAsCLIModeWithoutSpec starts Evans as CLI mode without loading the spec. It is used by commands which call
+// well-known services such as grpc.health.v1.Health, so that they work even if the server doesn't support
+// gRPC reflection.
+func RunAsCLIModeWithoutSpec(cfg *config.Config, invoker CLIInvoker) error {
+	if cfg.Request.Twirp || cfg.Request.Transcoding {
+		return errors.New("the command is supported only for gRPC and gRPC-Web")
+	}
+	gRPCClient, err := newGRPCClient(cfg)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		gRPCClient.Close(ctx)
+	}()
+
+	usecase.InjectPartially(usecase.Dependencies{GRPCClient: gRPCClient})
+	usecase.SetTimeout(cfg.Request.Timeout)
+	for k, v := range cfg.Request.Header {
+		for _, vv := range v {
+			usecase.AddHeader(k, vv)
+		}
+	}
+	addScopedHeaders(cfg.Request.ScopedHeaders)
+	addPropagatedHeaders(cfg.Request.PropagatedHeaders)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return invoker(ctx)
 }
 
 // RunAsCLIMode starts Evans as CLI mode.
 func RunAsCLIMode(cfg *config.Config, invoker CLIInvoker) error {
-	var injectResult error
 	gRPCClient, err := newGRPCClient(cfg)
 	if err != nil {
-		injectResult = multierror.Append(injectResult, err)
-	} else {
-		defer func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			defer cancel()
-			gRPCClient.Close(ctx)
-		}()
+		return err
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		gRPCClient.Close(ctx)
+	}()
 
 	spec, err := newSpec(cfg, gRPCClient)
 	if err != nil {
-		injectResult = multierror.Append(injectResult, err)
-	}
-
-	if injectResult != nil {
-		return injectResult
+		return err
 	}
 
 	usecase.InjectPartially(
@@ -202,6 +514,35 @@ func RunAsCLIMode(cfg *config.Config, invoker CLIInvoker) error {
 			ResourcePresenter: json.NewPresenter("  "),
 		},
 	)
+	usecase.SetTimeout(cfg.Request.Timeout)
+	usecase.SetProtoNames(cfg.Output.ProtoNames)
+	addScopedHeaders(cfg.Request.ScopedHeaders)
+	addPropagatedHeaders(cfg.Request.PropagatedHeaders)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := setDefault(cfg); err != nil {
+		return err
+	}
+
+	return invoker(ctx)
+}
+
+// RunAsDryRunCLIMode starts Evans as CLI mode without connecting to the server.
+// gRPC reflection is not available because it requires the connection.
+func RunAsDryRunCLIMode(cfg *config.Config, invoker CLIInvoker) error {
+	if cfg.Server.Reflection {
+		return errors.New("dry-run cannot be used with gRPC reflection. specify proto files, protosets or a BSR module instead")
+	}
+	spec, err := newLocalSpec(cfg)
+	if err != nil {
+		return err
+	}
+
+	usecase.InjectPartially(usecase.Dependencies{Spec: spec})
+	usecase.SetProtoNames(cfg.Output.ProtoNames)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -0,0 +1,21 @@
+package mode
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+)
+
+func TestNewCallCLIInvoker_templateWithFilter(t *testing.T) {
+	ui := cui.New(cui.Writer(ioutil.Discard))
+	_, err := NewCallCLIInvoker(ui, "Unary", CallCLIOptions{
+		OutputTemplate: "{{ .message }}",
+		OutputFilter:   ".message",
+		Output:         &config.Output{},
+	})
+	if err == nil {
+		t.Errorf("NewCallCLIInvoker must return an error if both of a template and a filter are specified")
+	}
+}
@@ -1,38 +1,345 @@
 package mode
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
 
+	protov1 "github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc"
+	"github.com/ktr0731/evans/auth"
+	"github.com/ktr0731/evans/cache"
 	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
 	"github.com/ktr0731/evans/grpc"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
 	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
+	"github.com/ktr0731/evans/logger"
+	"github.com/ktr0731/evans/meta"
+	"github.com/ktr0731/evans/tracing"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 )
 
-func newSpec(cfg *config.Config, grpcClient grpcreflection.Client) (spec idl.Spec, err error) {
-	if cfg.Server.Reflection {
-		spec, err = proto.LoadByReflection(grpcClient)
-	} else {
-		spec, err = proto.LoadFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+// newSpec instantiates the spec from gRPC reflection or local files.
+// If both of them are specified, local files are merged into the spec loaded by gRPC reflection
+// to complement incomplete descriptors returned from the server.
+func newSpec(cfg *config.Config, grpcClient grpcreflection.Client) (idl.Spec, error) {
+	hasLocal := len(cfg.Default.ProtoFile) != 0 || len(cfg.Default.Protoset) != 0 || cfg.Default.BSR != ""
+	if !cfg.Server.Reflection {
+		return newLocalSpec(cfg)
 	}
+	spec, err := loadByReflection(cfg.Server, grpcClient)
 	if errors.Is(err, grpcreflection.ErrTLSHandshakeFailed) {
 		return nil, errors.New("TLS handshake failed. check whether client or server is misconfigured")
 	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate the spec by gRPC reflection")
+	}
+	if !hasLocal {
+		return spec, nil
+	}
+	local, err := newLocalSpec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Merge(spec, local), nil
+}
+
+// loadByReflection loads the spec by gRPC reflection.
+// Unless s.NoCache is set, the descriptors are cached on disk keyed by the server address and the hash of
+// files which define services the server lists. The cache is used while the hash is unchanged because resolving
+// these files is much cheaper than resolving all descriptors including extensions.
+func loadByReflection(s *config.Server, client grpcreflection.Client) (idl.Spec, error) {
+	if s.NoCache {
+		return proto.LoadByReflection(client)
+	}
+	fds, err := client.ListServiceFiles()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := descriptorsHash(fds)
+	if err != nil {
+		return nil, err
+	}
+	addr := serverAddr(s)
+	b, ok, err := cache.GetDescriptorSet(addr, hash)
+	if err != nil {
+		logger.Printf("failed to get the cached descriptors: %s", err)
+	}
+	if ok {
+		spec, err := proto.LoadDescriptorSet(b)
+		if err == nil {
+			logger.Printf("use the cached descriptors for %s", addr)
+			return spec, nil
+		}
+		logger.Printf("failed to load the cached descriptors, fall back to gRPC reflection: %s", err)
+	}
+
+	spec, err := proto.LoadByReflection(client)
+	if err != nil {
+		return nil, err
+	}
+	b, err = spec.ExportDescriptorSet(nil)
+	if err != nil {
+		logger.Printf("failed to export the descriptors to be cached: %s", err)
+		return spec, nil
+	}
+	if err := cache.SaveDescriptorSet(addr, hash, b); err != nil {
+		logger.Printf("failed to cache the descriptors: %s", err)
+	}
+	return spec, nil
+}
+
+// descriptorsHash returns the hash of fds and files they depend on regardless of their order.
+// Any changes of services, RPCs and messages change the hash.
+func descriptorsHash(fds []*desc.FileDescriptor) (string, error) {
+	files := map[string]*desc.FileDescriptor{}
+	var collect func(fd *desc.FileDescriptor)
+	collect = func(fd *desc.FileDescriptor) {
+		if _, ok := files[fd.GetName()]; ok {
+			return
+		}
+		files[fd.GetName()] = fd
+		for _, dep := range fd.GetDependencies() {
+			collect(dep)
+		}
+	}
+	for _, fd := range fds {
+		collect(fd)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		b, err := protov1.Marshal(files[name].AsFileDescriptorProto())
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to marshal the file descriptor '%s'", name)
+		}
+		fmt.Fprintf(h, "%s\n%d\n", name, len(b))
+		h.Write(b) //nolint:errcheck
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newLocalSpec instantiates the spec from protosets, a BSR module or proto files.
+func newLocalSpec(cfg *config.Config) (spec idl.Spec, err error) {
+	switch {
+	case len(cfg.Default.Protoset) != 0:
+		spec, err = proto.LoadProtosets(cfg.Default.Protoset)
+	case cfg.Default.BSR != "":
+		var m *proto.BSRModule
+		m, err = proto.ParseBSRModule(cfg.Default.BSR)
+		if err != nil {
+			return nil, err
+		}
+		spec, err = proto.LoadBSR(http.DefaultClient, m, bufToken(m.Remote))
+	case cfg.Default.AutoProtoPath:
+		var resolved []*proto.ResolvedImport
+		spec, resolved, err = proto.LoadFilesWithDiscovery(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+		for _, r := range resolved {
+			logger.Printf("resolved '%s' from the discovered import path '%s'", r.Name, r.ImportPath)
+		}
+	default:
+		spec, err = proto.LoadFiles(cfg.Default.ProtoPath, cfg.Default.ProtoFile)
+	}
+	if err != nil {
 		return nil, errors.Wrap(err, "failed to instantiate the spec from proto files")
 	}
 	return spec, nil
 }
 
+// newGRPCClient returns a client for the protocol cfg specifies. If request.replay is set, the returned client
+// answers RPCs from the cassette without connecting to the server. If request.record is set, the client records RPCs.
 func newGRPCClient(cfg *config.Config) (grpc.Client, error) {
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
+	if cfg.Request.Replay != "" {
+		client, err := grpc.NewReplayClient(cfg.Request.Replay)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a replay client")
+		}
+		return client, nil
+	}
+	client, err := dialGRPCClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err = wrapTracingClient(cfg, client)
+	if err != nil {
+		client.Close(context.Background())
+		return nil, err
+	}
+	creds, err := newCredentials(cfg)
+	if err != nil {
+		client.Close(context.Background())
+		return nil, err
+	}
+	for _, c := range creds {
+		client = grpc.NewAuthClient(client, c)
+	}
+	if cfg.Request.Record != "" {
+		rc, err := grpc.NewRecordingClient(client, cfg.Request.Record)
+		if err != nil {
+			client.Close(context.Background())
+			return nil, errors.Wrap(err, "failed to instantiate a recording client")
+		}
+		return rc, nil
+	}
+	return client, nil
+}
+
+// newCredentials returns credential providers specified by request.auth.provider. If it is empty, providers are
+// inferred from request.oauth2 and request.jwt. It returns nil if no providers are configured.
+func newCredentials(cfg *config.Config) ([]auth.CredentialProvider, error) {
+	a := cfg.Request.Auth
+	switch a.Provider {
+	case "":
+		var creds []auth.CredentialProvider
+		if cfg.Request.OAuth2.Flow != "" {
+			c, err := newOAuth2Provider(cfg)
+			if err != nil {
+				return nil, err
+			}
+			creds = append(creds, c)
+		}
+		if cfg.Request.JWT.KeyFile != "" {
+			c, err := newJWTProvider(cfg)
+			if err != nil {
+				return nil, err
+			}
+			creds = append(creds, c)
+		}
+		return creds, nil
+	case auth.ProviderStatic:
+		return []auth.CredentialProvider{auth.NewStatic(a.Header, a.Value)}, nil
+	case auth.ProviderBasic:
+		return []auth.CredentialProvider{auth.NewBasic(a.Username, a.Password)}, nil
+	case auth.ProviderOAuth2:
+		c, err := newOAuth2Provider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []auth.CredentialProvider{c}, nil
+	case auth.ProviderJWT:
+		c, err := newJWTProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []auth.CredentialProvider{c}, nil
+	case auth.ProviderExec:
+		c, err := auth.NewExec(a.Command)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate the exec provider")
+		}
+		return []auth.CredentialProvider{c}, nil
+	}
+	return nil, errors.Errorf("unknown credential provider '%s'", a.Provider)
+}
+
+func newOAuth2Provider(cfg *config.Config) (auth.CredentialProvider, error) {
+	o := cfg.Request.OAuth2
+	c, err := auth.NewOAuth2(auth.OAuth2Config{
+		Flow:          o.Flow,
+		TokenURL:      o.TokenURL,
+		DeviceAuthURL: o.DeviceAuthURL,
+		ClientID:      o.ClientID,
+		ClientSecret:  o.ClientSecret,
+		Scopes:        o.Scopes,
+	}, os.Stderr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate OAuth 2.0 credentials")
+	}
+	return c, nil
+}
+
+func newJWTProvider(cfg *config.Config) (auth.CredentialProvider, error) {
+	j := cfg.Request.JWT
+	c, err := auth.NewJWT(auth.JWTConfig{
+		KeyFile:    j.KeyFile,
+		Algorithm:  j.Algorithm,
+		Claims:     j.Claims,
+		ClaimsFile: j.ClaimsFile,
+		Header:     j.Header,
+		Expiry:     j.Expiry,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate the JWT signer")
+	}
+	return c, nil
+}
+
+// wrapTracingClient wraps client to propagate W3C Trace Context and export client spans if they are enabled.
+func wrapTracingClient(cfg *config.Config, client grpc.Client) (grpc.Client, error) {
+	if !cfg.Request.TracePropagation && cfg.Request.Traceparent == "" && cfg.Request.OTLPEndpoint == "" {
+		return client, nil
+	}
+	var parent *tracing.SpanContext
+	if cfg.Request.Traceparent != "" {
+		sc, err := tracing.ParseTraceparent(cfg.Request.Traceparent)
+		if err != nil {
+			return client, errors.Wrap(err, "failed to parse the traceparent")
+		}
+		parent = &sc
+	}
+	var exporter *tracing.Exporter
+	if cfg.Request.OTLPEndpoint != "" {
+		e, err := tracing.NewExporter(cfg.Request.OTLPEndpoint, meta.AppName)
+		if err != nil {
+			return client, errors.Wrap(err, "failed to instantiate an OTLP exporter")
+		}
+		exporter = e
+	}
+	return grpc.NewTracingClient(client, tracing.NewTracer(parent, exporter)), nil
+}
+
+func dialGRPCClient(cfg *config.Config) (grpc.Client, error) {
+	addr := serverAddr(cfg.Server)
 	if cfg.Request.Web {
 		//TODO: remove second arg
 		return grpc.NewWebClient(addr, cfg.Server.Reflection, false, "", "", ""), nil
 	}
+	if cfg.Request.Twirp {
+		client, err := grpc.NewTwirpClient(
+			addr,
+			cfg.Server.Name,
+			cfg.Server.TLS,
+			cfg.Request.CACertFile,
+			cfg.Request.CertFile,
+			cfg.Request.CertKeyFile,
+			cfg.Request.Proxy,
+			cfg.Request.H2C,
+			cfg.Request.TwirpEncoding)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a Twirp client")
+		}
+		return client, nil
+	}
+	if cfg.Request.Transcoding {
+		client, err := grpc.NewTranscodingClient(
+			addr,
+			cfg.Server.Name,
+			cfg.Server.TLS,
+			cfg.Request.CACertFile,
+			cfg.Request.CertFile,
+			cfg.Request.CertKeyFile,
+			cfg.Request.Proxy,
+			cfg.Request.H2C,
+			resolveMethod)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a HTTP/JSON transcoding client")
+		}
+		return client, nil
+	}
 	client, err := grpc.NewClient(
 		addr,
 		cfg.Server.Name,
@@ -40,22 +347,106 @@ func newGRPCClient(cfg *config.Config) (grpc.Client, error) {
 		cfg.Server.TLS,
 		cfg.Request.CACertFile,
 		cfg.Request.CertFile,
-		cfg.Request.CertKeyFile)
+		cfg.Request.CertKeyFile,
+		cfg.Request.Proxy,
+		grpc.ConnOptions{
+			KeepaliveTime:                cfg.Request.KeepaliveTime,
+			KeepaliveTimeout:             cfg.Request.KeepaliveTimeout,
+			KeepalivePermitWithoutStream: cfg.Request.KeepalivePermitWithoutStream,
+			InitialWindowSize:            cfg.Request.InitialWindowSize,
+			InitialConnWindowSize:        cfg.Request.InitialConnWindowSize,
+			MaxHeaderListSize:            cfg.Request.MaxHeaderListSize,
+			MaxRecvMsgSize:               cfg.Request.MaxRecvMsgSize,
+			MaxSendMsgSize:               cfg.Request.MaxSendMsgSize,
+			Compression:                  cfg.Request.Compression,
+			Retry: grpc.RetryPolicy{
+				MaxRetries:     cfg.Request.Retry,
+				InitialBackoff: cfg.Request.RetryBackoff,
+				MaxBackoff:     cfg.Request.RetryMaxBackoff,
+				Codes:          cfg.Request.RetryCodes,
+			},
+			ConnectTimeout:      cfg.Request.ConnectTimeout,
+			WaitForReady:        cfg.Request.WaitForReady,
+			LoadBalancingPolicy: cfg.Server.LoadBalancingPolicy,
+			XDSBootstrap:        cfg.Server.XDSBootstrap,
+			Trace:               traceWriter(cfg),
+		})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to instantiate a gRPC client")
 	}
 	return client, nil
 }
 
+// traceWriter returns the destination of the wire-level trace. It returns nil if tracing is disabled.
+func traceWriter(cfg *config.Config) io.Writer {
+	if !cfg.Request.Trace {
+		return nil
+	}
+	return os.Stderr
+}
+
+// serverAddr returns the comma-separated addresses of the server.
+// Hosts which don't have a port are combined with the default port. xDS targets are used as it is.
+func serverAddr(s *config.Server) string {
+	hosts := strings.Split(s.Host, ",")
+	addrs := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		h = strings.TrimSpace(h)
+		if grpc.IsXDSTarget(h) {
+			addrs = append(addrs, h)
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err == nil {
+			addrs = append(addrs, h)
+			continue
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:%s", h, s.Port))
+	}
+	return strings.Join(addrs, ",")
+}
+
+// bufToken returns the API token for the remote of Buf Schema Registry from $BUF_TOKEN.
+// $BUF_TOKEN is a token, or comma-separated pairs of a token and a remote in the form of <token>@<remote>
+// which is the same as the Buf CLI.
+func bufToken(remote string) string {
+	v := os.Getenv("BUF_TOKEN")
+	if !strings.Contains(v, "@") {
+		return v
+	}
+	for _, p := range strings.Split(v, ",") {
+		i := strings.LastIndex(p, "@")
+		if i != -1 && strings.TrimSpace(p[i+1:]) == remote {
+			return strings.TrimSpace(p[:i])
+		}
+	}
+	return ""
+}
+
+// resolveMethod resolves the method descriptor from the spec currently used.
+func resolveMethod(fqrn string) (*desc.MethodDescriptor, error) {
+	d, err := usecase.GetTypeDescriptor(fqrn)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(*desc.MethodDescriptor)
+	if !ok {
+		return nil, errors.Errorf("'%s' is not a method", fqrn)
+	}
+	return md, nil
+}
+
 func gRPCReflectionPackageFilteredPackages(pkgNames []string) []string {
-	pkgs := make([]string, len(pkgNames))
-	copy(pkgs, pkgNames)
+	reflectionPkgs := make(map[string]struct{}, len(grpcreflection.ServiceNames))
+	for _, n := range grpcreflection.ServiceNames {
+		reflectionPkgs[n[:strings.LastIndex(n, ".")]] = struct{}{}
+	}
 
-	n := grpcreflection.ServiceName[:strings.LastIndex(grpcreflection.ServiceName, ".")]
-	for i := range pkgs {
-		if n == pkgs[i] {
-			return append(pkgs[:i], pkgs[i+1:]...)
+	pkgs := make([]string, 0, len(pkgNames))
+	for _, pkg := range pkgNames {
+		if _, ok := reflectionPkgs[pkg]; ok {
+			continue
 		}
+		pkgs = append(pkgs, pkg)
 	}
 	return pkgs
 }
@@ -103,3 +494,141 @@ func setDefault(cfg *config.Config) error {
 	}
 	return nil
 }
+
+// addScopedHeaders adds scoped default headers in the config to the usecase.
+func addScopedHeaders(shs []*config.ScopedHeader) {
+	for _, sh := range shs {
+		for k, v := range sh.Header {
+			for _, vv := range v {
+				usecase.AddScopedHeader(sh.Scope, k, vv)
+			}
+		}
+	}
+}
+
+// addPropagatedHeaders adds rules to propagate response headers in the config to the usecase.
+func addPropagatedHeaders(phs []*config.PropagatedHeader) {
+	for _, ph := range phs {
+		usecase.PropagateHeader(ph.From, ph.To)
+	}
+}
+
+// warnDeprecatedUsage writes a warning to ui when a deprecated RPC is called or a request which sets deprecated fields
+// is sent. If deny is true, the call fails instead.
+func warnDeprecatedUsage(ui cui.UI, deny bool) {
+	usecase.OnDeprecatedUsage(func(warning string) error {
+		if deny {
+			return errors.Errorf("%s. specify --allow-deprecated to use it", warning)
+		}
+		ui.Warn("evans: " + warning)
+		return nil
+	})
+}
+
+// newScopedHeaders converts scoped default headers in the config for modes which don't use the usecase.
+func newScopedHeaders(shs []*config.ScopedHeader) grpc.ScopedHeaders {
+	s := grpc.ScopedHeaders{}
+	for _, sh := range shs {
+		if _, ok := s[sh.Scope]; !ok {
+			s[sh.Scope] = grpc.Headers{}
+		}
+		for k, v := range sh.Header {
+			for _, vv := range v {
+				if err := s[sh.Scope].Add(k, vv); err != nil {
+					logger.Printf("failed to add a header %s=%s to %s: %s", k, vv, sh.Scope, err)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// profileLoader implements usecase.ProfileLoader. It reconnects to the server of the loaded profile.
+type profileLoader struct {
+	cfg *config.Config
+}
+
+func newProfileLoader(cfg *config.Config) *profileLoader {
+	return &profileLoader{cfg: cfg}
+}
+
+func (l *profileLoader) Names() []string {
+	names := make([]string, 0, len(l.cfg.Profiles))
+	for name := range l.cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load connects to the server of the profile, then applies the profile to the config.
+// The config is modified only if the connection succeeded.
+func (l *profileLoader) Load(name string) (*usecase.Profile, error) {
+	p, ok := l.cfg.Profiles[name]
+	if !ok {
+		return nil, errors.Errorf("unknown profile name '%s'", name)
+	}
+
+	pcfg := *l.cfg
+	pcfg.Server, pcfg.Request, pcfg.Default = p.Server, p.Request, p.Default
+	client, err := newGRPCClient(&pcfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate a new gRPC client")
+	}
+	spec, err := newSpec(&pcfg, client)
+	if err != nil {
+		client.Close(context.Background())
+		return nil, errors.Wrap(err, "failed to instantiate a new spec")
+	}
+
+	if err := l.cfg.ApplyProfile(name); err != nil {
+		client.Close(context.Background())
+		return nil, err
+	}
+
+	pkg := p.Default.Package
+	if pkg == "" {
+		// If the spec has only one package, mark it as the default package.
+		if pkgs := gRPCReflectionPackageFilteredPackages(packageNames(spec)); len(pkgs) == 1 {
+			pkg = pkgs[0]
+		}
+	}
+
+	svc := p.Default.Service
+	if svc == "" {
+		// If the package has only one service, mark it as the default service.
+		var svcs []string
+		for _, fqsn := range spec.ServiceNames() {
+			if p, s := proto.ParseFullyQualifiedServiceName(fqsn); p == pkg {
+				svcs = append(svcs, s)
+			}
+		}
+		if len(svcs) == 1 {
+			svc = svcs[0]
+		}
+	}
+
+	return &usecase.Profile{
+		Spec:       spec,
+		GRPCClient: client,
+		Header:     p.Request.Header,
+		Package:    pkg,
+		Service:    svc,
+		Timeout:    p.Request.Timeout,
+	}, nil
+}
+
+// packageNames returns package names which have one or more services.
+func packageNames(spec idl.Spec) []string {
+	encountered := make(map[string]interface{})
+	var pkgs []string
+	for _, fqsn := range spec.ServiceNames() {
+		pkg, _ := proto.ParseFullyQualifiedServiceName(fqsn)
+		if _, ok := encountered[pkg]; ok {
+			continue
+		}
+		encountered[pkg] = nil
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
@@ -0,0 +1,171 @@
+package mode
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/grpc/grpcreflection"
+)
+
+func Test_gRPCReflectionPackageFilteredPackages(t *testing.T) {
+	cases := map[string]struct {
+		pkgNames []string
+		expected []string
+	}{
+		"no reflection packages": {
+			pkgNames: []string{"api", "foo"},
+			expected: []string{"api", "foo"},
+		},
+		"v1alpha": {
+			pkgNames: []string{"api", "grpc.reflection.v1alpha"},
+			expected: []string{"api"},
+		},
+		"v1 and v1alpha": {
+			pkgNames: []string{"grpc.reflection.v1", "api", "grpc.reflection.v1alpha"},
+			expected: []string{"api"},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual := gRPCReflectionPackageFilteredPackages(c.pkgNames)
+			if diff := cmp.Diff(c.expected, actual); diff != "" {
+				t.Errorf("-want, +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_serverAddr(t *testing.T) {
+	cases := map[string]struct {
+		host     string
+		expected string
+	}{
+		"single host":       {host: "localhost", expected: "localhost:50051"},
+		"multiple hosts":    {host: "10.0.0.1, 10.0.0.2:50052", expected: "10.0.0.1:50051,10.0.0.2:50052"},
+		"xDS target":        {host: "xds:///example", expected: "xds:///example"},
+		"xDS target w/port": {host: "xds:///example:8080", expected: "xds:///example:8080"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			actual := serverAddr(&config.Server{Host: c.host, Port: "50051"})
+			if actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_bufToken(t *testing.T) {
+	cases := map[string]struct {
+		env      string
+		expected string
+	}{
+		"not set":             {env: "", expected: ""},
+		"single token":        {env: "tok", expected: "tok"},
+		"token for remote":    {env: "tok1@buf.build,tok2@buf.example.com", expected: "tok1"},
+		"no token for remote": {env: "tok2@buf.example.com", expected: ""},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			old := os.Getenv("BUF_TOKEN")
+			defer os.Setenv("BUF_TOKEN", old)
+			os.Setenv("BUF_TOKEN", c.env)
+
+			if actual := bufToken("buf.build"); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+type reflectionClient struct {
+	grpcreflection.Client
+	descs []*desc.FileDescriptor
+
+	listPackagesCalled int
+}
+
+func (c *reflectionClient) ListServiceFiles() ([]*desc.FileDescriptor, error) {
+	return c.descs, nil
+}
+
+func (c *reflectionClient) ListPackages() ([]*desc.FileDescriptor, error) {
+	c.listPackagesCalled++
+	return c.descs, nil
+}
+
+func Test_loadByReflection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	old := os.Getenv("XDG_CACHE_HOME")
+	defer os.Setenv("XDG_CACHE_HOME", old)
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	p := &protoparse.Parser{ImportPaths: []string{"../idl/proto/testdata"}}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	client := &reflectionClient{descs: fds}
+
+	assertLoad := func(s *config.Server, expectedCalled int) {
+		t.Helper()
+		spec, err := loadByReflection(s, client)
+		if err != nil {
+			t.Fatalf("loadByReflection must not return an error, but got '%s'", err)
+		}
+		if diff := cmp.Diff([]string{"api.Example"}, spec.ServiceNames()); diff != "" {
+			t.Errorf("-want, +got\n%s", diff)
+		}
+		if client.listPackagesCalled != expectedCalled {
+			t.Errorf("expected ListPackages is called %d times, but got %d", expectedCalled, client.listPackagesCalled)
+		}
+	}
+
+	s := &config.Server{Host: "localhost", Port: "50051"}
+	assertLoad(s, 1)
+	// The cache is used.
+	assertLoad(s, 1)
+	// The cache is not used for another server.
+	assertLoad(&config.Server{Host: "localhost", Port: "50052"}, 2)
+	// The cache is bypassed.
+	assertLoad(&config.Server{Host: "localhost", Port: "50051", NoCache: true}, 3)
+
+	// The cache is invalidated if messages are changed even though the services are the same.
+	b, err := ioutil.ReadFile("../idl/proto/testdata/api.proto")
+	if err != nil {
+		t.Fatalf("ReadFile must not return an error, but got '%s'", err)
+	}
+	p = &protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": string(b),
+			"message.proto": `
+syntax = "proto3";
+package api;
+message Person {
+  string name = 1;
+  int32 age = 2;
+}
+message Book {
+  string title = 1;
+}`,
+		}),
+	}
+	client.descs, err = p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	assertLoad(s, 4)
+	assertLoad(s, 4)
+}
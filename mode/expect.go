@@ -0,0 +1,116 @@
+package mode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Expectation is a set of assertions for the result of an RPC call. It makes a call of CLI mode a contract test.
+type Expectation struct {
+	// Code is the expected status code such that "OK", "NotFound", "NOT_FOUND" or "5".
+	// If it is empty, the call must succeed as usual.
+	Code string
+	// BodyContains are substrings which the response body must contain. The body is formatted as compact JSON
+	// such that {"message":"hello"}. Messages of streaming RPCs are separated by newlines.
+	BodyContains []string
+}
+
+// assertion asserts the result of an RPC call by an Expectation.
+type assertion struct {
+	code         *codes.Code
+	bodyContains []string
+	body         []string
+}
+
+func newAssertion(e *Expectation) (*assertion, error) {
+	a := &assertion{bodyContains: e.BodyContains}
+	if e.Code != "" {
+		c, err := grpc.ParseCode(e.Code)
+		if err != nil {
+			return nil, err
+		}
+		a.code = &c
+	}
+	return a, nil
+}
+
+// wrap returns a formatter which records response messages to the assertion in addition to formatting by f.
+func (a *assertion) wrap(f format.ResponseFormatterInterface) format.ResponseFormatterInterface {
+	return &bodyRecorder{ResponseFormatterInterface: f, a: a}
+}
+
+// handles reports whether the assertion handles err returned from usecase.CallRPC.
+// Non-OK statuses are handled only if the status code is expected. Other errors are never handled.
+func (a *assertion) handles(err error) bool {
+	if err == nil {
+		return true
+	}
+	_, ok := statusOf(err)
+	return ok && a.code != nil
+}
+
+// assert asserts the result of the call. err is the error returned from usecase.CallRPC, and it must be handled
+// by the assertion.
+func (a *assertion) assert(err error) error {
+	code, msg := codes.OK, ""
+	if s, ok := statusOf(err); ok {
+		code, msg = codes.Code(s.Code()), s.Message()
+	}
+
+	var failures []string
+	if a.code != nil && *a.code != code {
+		s := fmt.Sprintf("expected status code %s, but got %s", *a.code, code)
+		if msg != "" {
+			s += fmt.Sprintf(" (message: %q)", msg)
+		}
+		failures = append(failures, s)
+	}
+	body := strings.Join(a.body, "\n")
+	for _, s := range a.bodyContains {
+		if !strings.Contains(body, s) {
+			failures = append(failures, fmt.Sprintf("expected the response body contains %q", s))
+		}
+	}
+	if len(failures) != 0 {
+		return errors.Errorf("expectation failed: %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// statusError is an error which has the status of an RPC such that errors returned from usecase.CallRPC.
+type statusError interface {
+	Code() usecase.ErrorCode
+	Message() string
+}
+
+func statusOf(err error) (statusError, bool) {
+	var s statusError
+	if err == nil || !errors.As(err, &s) {
+		return nil, false
+	}
+	return s, true
+}
+
+// bodyRecorder records each response message as compact JSON.
+type bodyRecorder struct {
+	format.ResponseFormatterInterface
+	a *assertion
+}
+
+func (r *bodyRecorder) FormatMessage(v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		b, err := format.JSONOptions{}.MarshalMessage(m)
+		if err != nil {
+			return err
+		}
+		r.a.body = append(r.a.body, string(b))
+	}
+	return r.ResponseFormatterInterface.FormatMessage(v)
+}
@@ -0,0 +1,84 @@
+package mode
+
+import (
+	"testing"
+
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusErr is an error which has the status like errors returned from usecase.CallRPC.
+type statusErr struct {
+	s *status.Status
+}
+
+func (e *statusErr) Code() usecase.ErrorCode { return usecase.ErrorCode(e.s.Code()) }
+func (e *statusErr) Message() string         { return e.s.Message() }
+func (e *statusErr) Error() string           { return e.s.Err().Error() }
+
+func TestAssertion(t *testing.T) {
+	notFound := errors.Wrap(&statusErr{status.New(codes.NotFound, "not found")}, "failed")
+	cases := map[string]struct {
+		expect  Expectation
+		body    []string
+		err     error
+		handles bool
+		hasErr  bool
+	}{
+		"succeeded without expectations of the status code": {
+			body:    []string{`{"name":"kaguya"}`},
+			expect:  Expectation{BodyContains: []string{`"name":"kaguya"`}},
+			handles: true,
+		},
+		"a non-OK status without expectations of the status code": {
+			expect: Expectation{BodyContains: []string{"kaguya"}},
+			err:    notFound,
+		},
+		"the expected non-OK status": {
+			expect:  Expectation{Code: "NotFound"},
+			err:     notFound,
+			handles: true,
+		},
+		"an unexpected status": {
+			expect:  Expectation{Code: "OK"},
+			err:     notFound,
+			handles: true,
+			hasErr:  true,
+		},
+		"the body which doesn't contain the expected string": {
+			body:    []string{`{"name":"kaguya"}`, `{"name":"chika"}`},
+			expect:  Expectation{Code: "OK", BodyContains: []string{"chika", "miko"}},
+			handles: true,
+			hasErr:  true,
+		},
+		"not a status error": {
+			expect: Expectation{Code: "Unavailable"},
+			err:    errors.New("failed to open the file"),
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			a, err := newAssertion(&c.expect)
+			if err != nil {
+				t.Fatalf("newAssertion must not return an error, but got '%s'", err)
+			}
+			a.body = c.body
+			if handles := a.handles(c.err); handles != c.handles {
+				t.Fatalf("expected handles returns %t, but got %t", c.handles, handles)
+			}
+			if !c.handles {
+				return
+			}
+			err = a.assert(c.err)
+			if c.hasErr && err == nil {
+				t.Errorf("assert must return an error")
+			}
+			if !c.hasErr && err != nil {
+				t.Errorf("assert must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package mode
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	fillproto "github.com/ktr0731/evans/fill/proto"
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/format/curl"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// NewFuzzCLIInvoker returns an CLIInvoker implementation for sending requests filled with boundary values
+// to the RPC iterations times. Responses are discarded, and non-OK statuses are reported with the seed of
+// each request. The i-th request (0-origin) is generated from seed+i, so that the same request is sent again
+// by passing seed+i as seed with 1 as iterations.
+// If showRequests is true, failed requests are also shown as JSON.
+func NewFuzzCLIInvoker(ui cui.UI, methodName string, headers config.Header, iterations int, seed int64, showRequests bool) (CLIInvoker, error) {
+	if methodName == "" {
+		return nil, errors.New("method is required")
+	}
+	if iterations <= 0 {
+		return nil, errors.New("iterations must be greater than 0")
+	}
+	return func(ctx context.Context) error {
+		usecase.InjectPartially(usecase.Dependencies{
+			ResponseFormatter: format.NewResponseFormatter(curl.NewResponseFormatter(ioutil.Discard, format.JSONOptions{}), false),
+		})
+		for k, v := range headers {
+			for _, vv := range v {
+				usecase.AddHeader(k, vv)
+			}
+		}
+
+		fqmn := methodName
+		methodName, err := useMethodService(methodName)
+		if err != nil {
+			return err
+		}
+
+		w := ui.Writer()
+		fmt.Fprintf(w, "fuzzing %s with seed %d\n", fqmn, seed)
+		failures := make(map[codes.Code]int)
+		for i := 0; i < iterations; i++ {
+			s := seed + int64(i)
+			usecase.InjectPartially(usecase.Dependencies{Filler: fillproto.NewFuzzFiller(rand.New(rand.NewSource(s)))})
+			err := usecase.CallRPC(ctx, ioutil.Discard, methodName)
+			if err == nil {
+				continue
+			}
+			var e interface {
+				Code() usecase.ErrorCode
+				Message() string
+			}
+			if !errors.As(err, &e) {
+				return errors.Wrapf(err, "failed to call RPC '%s' (seed: %d)", methodName, s)
+			}
+			code := codes.Code(e.Code())
+			failures[code]++
+			msg := fmt.Sprintf("FAIL #%d (seed: %d): code = %s, message = %q", i+1, s, code, e.Message())
+			if code == codes.Unavailable {
+				msg += " (the server may have crashed)"
+			}
+			fmt.Fprintln(w, msg)
+			if showRequests {
+				req, err := usecase.FormatRequest(methodName)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, req)
+			}
+		}
+
+		var n int
+		summary := make([]string, 0, len(failures))
+		for code, c := range failures {
+			n += c
+			summary = append(summary, fmt.Sprintf("%s: %d", code, c))
+		}
+		sort.Strings(summary)
+		if n == 0 {
+			fmt.Fprintf(w, "%d requests, no failures\n", iterations)
+			return nil
+		}
+		fmt.Fprintf(w, "%d requests, %d failures (%s)\n", iterations, n, strings.Join(summary, ", "))
+		return errors.Errorf("%d of %d requests failed", n, iterations)
+	}, nil
+}
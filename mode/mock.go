@@ -0,0 +1,57 @@
+package mode
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/mock"
+	"github.com/pkg/errors"
+)
+
+// RunAsMockServer serves all RPCs of the spec by a mock server listening on the server host and port
+// until the process receives an interrupt signal. responsesPath is the YAML file which has canned responses.
+// If it is empty, all RPCs respond with fake responses.
+func RunAsMockServer(cfg *config.Config, ui cui.UI, responsesPath string) error {
+	if cfg.Server.Reflection {
+		return errors.New("the mock server cannot load the spec by gRPC reflection. specify proto files, protosets or a BSR module instead")
+	}
+	spec, err := newLocalSpec(cfg)
+	if err != nil {
+		return err
+	}
+	var responses mock.Responses
+	if responsesPath != "" {
+		responses, err = mock.LoadResponses(responsesPath)
+		if err != nil {
+			return err
+		}
+	}
+	srv, err := mock.NewServer(spec, responses)
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate the mock server")
+	}
+
+	// Only the first host is used if two or more hosts are specified.
+	host := strings.TrimSpace(strings.Split(cfg.Server.Host, ",")[0])
+	l, err := net.Listen("tcp", net.JoinHostPort(host, cfg.Server.Port))
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		srv.Stop()
+	}()
+
+	ui.Info(fmt.Sprintf("mock server is listening on %s", l.Addr()))
+	return srv.Serve(l)
+}
@@ -0,0 +1,126 @@
+package mode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/perf"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// RunPerf calls the method repeatedly with the request read from dataFile, and reports the throughput, the error rate
+// and latencies. dataFile is a JSON file, and "-" means stdin. If it is empty, an empty request is sent.
+// Each call of a streaming RPC sends the request once and receives all responses.
+// Interrupting the process stops the test and reports the result so far.
+func RunPerf(cfg *config.Config, ui cui.UI, methodName, dataFile string, opts perf.Options) error {
+	i := strings.LastIndex(methodName, ".")
+	if i == -1 {
+		return errors.Errorf("invalid fully-qualified method name '%s'", methodName)
+	}
+	gRPCClient, err := newGRPCClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		gRPCClient.Close(ctx)
+	}()
+	spec, err := newSpec(cfg, gRPCClient)
+	if err != nil {
+		return err
+	}
+	rpc, err := spec.RPC(methodName[:i], methodName[i+1:])
+	if err != nil {
+		return errors.Wrapf(err, "failed to find method '%s'", methodName)
+	}
+	req, err := perfRequest(rpc, dataFile)
+	if err != nil {
+		return err
+	}
+
+	// Header values are expanded only once to not run commands for every request.
+	md := metadata.New(nil)
+	for k, v := range newScopedHeaders(cfg.Request.ScopedHeaders).For(rpc.FullyQualifiedName, grpc.Headers(cfg.Request.Header)) {
+		for _, vv := range v {
+			ev, err := grpc.ResolveHeader(context.Background(), k, vv, nil)
+			if err != nil {
+				return errors.Wrapf(err, "invalid header '%s'", k)
+			}
+			md.Append(k, ev)
+		}
+	}
+	call := func(ctx context.Context) error {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+		if cfg.Request.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Request.Timeout)
+			defer cancel()
+		}
+		_, err := grpc.Call(ctx, gRPCClient, rpc, []interface{}{req})
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	msg := fmt.Sprintf("calling %s with %d workers for %s", rpc.FullyQualifiedName, opts.Concurrency, opts.TotalDuration())
+	switch {
+	case len(opts.Stages) != 0:
+		msg += fmt.Sprintf(" in %d stages", len(opts.Stages))
+	case opts.Rate > 0:
+		msg += fmt.Sprintf(" at %g req/s", opts.Rate)
+	}
+	ui.Info(msg)
+	res, err := perf.Run(ctx, opts, call)
+	if err != nil {
+		return err
+	}
+	res.Report(ui.Writer())
+	return nil
+}
+
+// perfRequest decodes the request from the JSON file fname.
+func perfRequest(rpc *grpc.RPC, fname string) (interface{}, error) {
+	req, err := rpc.RequestType.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate a request")
+	}
+	if fname == "" {
+		return req, nil
+	}
+	var in io.Reader = os.Stdin
+	if fname != "-" {
+		f, err := os.Open(fname)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open the data file")
+		}
+		defer f.Close()
+		in = f
+	}
+	if err := fill.NewSilentFiller(in).Fill(req); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the data file as a request")
+	}
+	return req, nil
+}
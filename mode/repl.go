@@ -2,46 +2,169 @@ package mode
 
 import (
 	"context"
-	"sort"
+	"io"
 
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
 	"github.com/ktr0731/evans/cache"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/fill"
 	"github.com/ktr0731/evans/fill/proto"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/headerset"
+	"github.com/ktr0731/evans/history"
+	idlproto "github.com/ktr0731/evans/idl/proto"
 	"github.com/ktr0731/evans/logger"
 	"github.com/ktr0731/evans/present/table"
 	"github.com/ktr0731/evans/prompt"
 	"github.com/ktr0731/evans/repl"
+	"github.com/ktr0731/evans/report"
+	"github.com/ktr0731/evans/session"
+	"github.com/ktr0731/evans/template"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/pkg/errors"
 )
 
 func RunAsREPLMode(cfg *config.Config, ui cui.UI, cache *cache.Cache) error {
-	gRPCClient, err := newGRPCClient(cfg)
+	fieldHistory := history.NewFieldStore(history.DefaultFieldFile(), cfg.REPL.HistorySize)
+	defer func() {
+		if err := fieldHistory.Save(); err != nil {
+			logger.Printf("failed to write field input history: %s", err)
+		}
+	}()
+	filler := proto.NewInteractiveFiller(
+		prompt.New(prompt.WithFillRandomKey(prompt.KeyControlG)),
+		cfg.REPL.InputPromptFormat,
+		proto.WithMessageResolver(&messageResolver{}),
+		proto.WithMessageFactory(&messageFactory{}),
+		proto.WithInputExpander(usecase.ExpandVariables),
+		proto.WithInputHistory(fieldHistory),
+		proto.WithCommentWriter(ui.Writer()),
+	)
+	gRPCClient, err := setupREPL(cfg, filler)
+	if err != nil {
+		return err
+	}
+	defer gRPCClient.Close(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	historyStore := history.NewStore(history.DefaultDir())
+	historyName := history.GlobalName
+	if cfg.REPL.HistoryPerHost {
+		historyName = history.Name(serverAddr(cfg.Server))
+	}
+	commandHistory, err := historyStore.Load(historyName)
+	if err != nil {
+		logger.Printf("failed to load command history: %s", err)
+	}
+	// Command history was stored in the cache before, which is cleared when Evans is upgraded.
+	// It is migrated to the history store at the end of the session.
+	var migrated []string
+	if commandHistory == nil && historyName == history.GlobalName {
+		commandHistory, migrated = cache.CommandHistory, cache.CommandHistory
+	}
+
+	replPrompt := prompt.New(
+		prompt.WithCommandHistory(commandHistory),
+		prompt.WithKeyBind(prompt.KeyControlX, repl.ToggleEditFlag),
+	)
+
+	defer func() {
+		// Only commands entered in this session are appended so that commands entered by other REPLs are kept.
+		entered := append([]string{}, migrated...)
+		if h := replPrompt.GetCommandHistory(); len(h) > len(commandHistory) {
+			entered = append(entered, h[len(commandHistory):]...)
+		}
+		if err := historyStore.Append(historyName, entered, cfg.REPL.HistorySize); err != nil {
+			logger.Printf("failed to write command history: %s", err)
+		}
+	}()
+
+	var opts []repl.Option
+	if cfg.REPL.Watch {
+		r, err := newSpecReloader(cfg, gRPCClient)
+		if err != nil {
+			return errors.Wrap(err, "failed to watch the spec")
+		}
+		opts = append(opts, repl.WithSpecReloader(r.Reload))
+	}
+
+	repl, err := repl.New(cfg, replPrompt, ui, cfg.Default.Package, cfg.Default.Service, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to launch a new REPL")
+	}
+	// Registered after New so that the service selected implicitly at the start is warned when it is used.
+	warnDeprecatedUsage(ui, false)
+	return repl.Run(ctx)
+}
+
+// RunAsScriptMode executes REPL commands read from script non-interactively.
+// The interactive filler is replaced with the request body written in script for each command.
+// If reportFile is not empty, the result of each call command is written to it. See report.WriteFile.
+func RunAsScriptMode(cfg *config.Config, ui cui.UI, script io.Reader, reportFile string) error {
+	gRPCClient, err := setupREPL(cfg, nil)
 	if err != nil {
-		return errors.Wrap(err, "failed to instantiate a new gRPC client")
+		return err
 	}
 	defer gRPCClient.Close(context.Background())
 
+	var (
+		opts []repl.Option
+		rep  = &report.Report{Name: "script"}
+	)
+	if reportFile != "" {
+		opts = append(opts, repl.WithScriptRecorder(func(r *report.Result) {
+			rep.Results = append(rep.Results, r)
+		}))
+	}
+	repl, err := repl.New(cfg, prompt.New(), ui, cfg.Default.Package, cfg.Default.Service, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to launch a new REPL")
+	}
+	warnDeprecatedUsage(ui, cfg.Request.DenyDeprecated)
+	err = repl.RunScript(script)
+	if reportFile != "" {
+		if rerr := report.WriteFile(reportFile, rep); rerr != nil && err == nil {
+			return rerr
+		}
+	}
+	return err
+}
+
+// setupREPL injects dependencies which are used in REPL commands. Also, it selects the default package and service,
+// and adds default headers. The returned gRPC client must be closed by the caller.
+func setupREPL(cfg *config.Config, interactiveFiller fill.InteractiveFiller) (grpc.Client, error) {
+	gRPCClient, err := newGRPCClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate a new gRPC client")
+	}
+
 	spec, err := newSpec(cfg, gRPCClient)
 	if err != nil {
-		return errors.Wrap(err, "failed to instantiate a new spec")
+		gRPCClient.Close(context.Background())
+		return nil, errors.Wrap(err, "failed to instantiate a new spec")
 	}
 
 	usecase.Inject(
 		usecase.Dependencies{
-			Spec:              spec,
-			InteractiveFiller: proto.NewInteractiveFiller(prompt.New(), cfg.REPL.InputPromptFormat),
-			GRPCClient:        gRPCClient,
-			ResourcePresenter: table.NewPresenter(),
+			Spec:                spec,
+			InteractiveFiller:   interactiveFiller,
+			GRPCClient:          gRPCClient,
+			ResourcePresenter:   table.NewPresenter(),
+			ProfileLoader:       newProfileLoader(cfg),
+			TemplateStore:       template.NewStore(template.DefaultDir()),
+			SessionStore:        session.NewStore(session.DefaultDir()),
+			HeaderSetStore:      headerset.NewStore(headerset.DefaultDir()),
+			RequestHistoryStore: history.NewRequestStore(history.DefaultRequestDir(), cfg.REPL.HistorySize),
 		},
 	)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	if err := setDefault(cfg); err != nil {
-		return err
+		gRPCClient.Close(context.Background())
+		return nil, err
 	}
 
 	for k, v := range cfg.Request.Header {
@@ -49,43 +172,44 @@ func RunAsREPLMode(cfg *config.Config, ui cui.UI, cache *cache.Cache) error {
 			usecase.AddHeader(k, vv)
 		}
 	}
+	addScopedHeaders(cfg.Request.ScopedHeaders)
+	addPropagatedHeaders(cfg.Request.PropagatedHeaders)
+	usecase.SetTimeout(cfg.Request.Timeout)
+	usecase.SetProtoNames(cfg.Output.ProtoNames)
+	return gRPCClient, nil
+}
 
-	replPrompt := prompt.New(prompt.WithCommandHistory(cache.CommandHistory))
-	replPrompt.SetPrefixColor(prompt.ColorBlue)
+// messageResolver resolves messages embedded in google.protobuf.Any fields from the injected spec.
+type messageResolver struct{}
 
-	defer func() {
-		history := tidyUpHistory(replPrompt.GetCommandHistory(), cfg.REPL.HistorySize)
-		cache.CommandHistory = history
-		if err := cache.Save(); err != nil {
-			logger.Printf("failed to write command history: %s", err)
-		}
-	}()
+func (r *messageResolver) MessageNames() []string {
+	return usecase.ListMessages()
+}
 
-	repl, err := repl.New(cfg, replPrompt, ui, cfg.Default.Package, cfg.Default.Service)
+func (r *messageResolver) ResolveMessage(fqmn string) (*desc.MessageDescriptor, error) {
+	d, err := usecase.GetTypeDescriptor(fqmn)
 	if err != nil {
-		return errors.Wrap(err, "failed to launch a new REPL")
+		return nil, err
 	}
-	return repl.Run(ctx)
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("'%s' is not a message", fqmn)
+	}
+	return md, nil
 }
 
-func tidyUpHistory(h []string, maxHistorySize int) []string {
-	m := make(map[string]int)
-	for i := range h {
-		m[h[i]] = i
-	}
-	s := make([]int, 0, len(m))
-	for _, i := range m {
-		s = append(s, i)
-	}
-	sort.Slice(s, func(i, j int) bool {
-		return s[i] < s[j]
-	})
-	history := make([]string, 0, len(h))
-	for _, i := range s {
-		history = append(history, h[i])
+// messageFactory instantiates messages by the injected spec so that they recognize extensions defined in all
+// loaded files.
+type messageFactory struct{}
+
+func (f *messageFactory) NewMessage(md *desc.MessageDescriptor) (*dynamic.Message, error) {
+	v, err := usecase.NewMessage(md.GetFullyQualifiedName())
+	if err != nil {
+		return nil, err
 	}
-	if len(history) > maxHistorySize {
-		history = history[len(history)-maxHistorySize:]
+	msg, ok := idlproto.UnwrapMessage(v)
+	if !ok {
+		return nil, errors.Errorf("'%s' is not a Protocol Buffers message", md.GetFullyQualifiedName())
 	}
-	return history
+	return msg, nil
 }
@@ -0,0 +1,86 @@
+package mode
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/idl/proto"
+	"github.com/pkg/errors"
+)
+
+// ErrBreakingChanges is returned from RunSchemaDiff if one or more breaking changes are found.
+var ErrBreakingChanges = errors.New("one or more breaking changes are found")
+
+// RunSchemaDiff compares schemas loaded from oldSource and newSource, then writes changes to ui.
+// Each source is a protoset, a proto file, a directory containing proto files, or the address of a server
+// which supports gRPC reflection such that "localhost:50051". Connection settings such that TLS are taken
+// from cfg. RunSchemaDiff returns ErrBreakingChanges if one or more changes break the old schema.
+func RunSchemaDiff(cfg *config.Config, ui cui.UI, oldSource, newSource string) error {
+	oldSpec, err := loadSchemaSource(cfg, oldSource)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the old schema from '%s'", oldSource)
+	}
+	newSpec, err := loadSchemaSource(cfg, newSource)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the new schema from '%s'", newSource)
+	}
+
+	changes := proto.Diff(oldSpec, newSpec)
+	if len(changes) == 0 {
+		ui.Output("no changes")
+		return nil
+	}
+	var breaking bool
+	for _, c := range changes {
+		ui.Output(c.String())
+		if c.Breaking {
+			breaking = true
+		}
+	}
+	if breaking {
+		return ErrBreakingChanges
+	}
+	return nil
+}
+
+// loadSchemaSource loads the spec from src. src is regarded as the server address if it is not an existing file.
+func loadSchemaSource(cfg *config.Config, src string) (idl.Spec, error) {
+	fi, err := os.Stat(src)
+	switch {
+	case err == nil && fi.IsDir():
+		return proto.LoadFiles([]string{src}, []string{"."})
+	case err == nil && filepath.Ext(src) == ".proto":
+		return proto.LoadFiles([]string{filepath.Dir(src)}, []string{filepath.Base(src)})
+	case err == nil:
+		return proto.LoadProtosets([]string{src})
+	}
+
+	host, port, err := net.SplitHostPort(src)
+	if err != nil {
+		host, port = src, cfg.Server.Port
+	}
+	server := *cfg.Server
+	server.Host, server.Port = host, port
+	server.Reflection = true
+	// Always query the server because the cache may not reflect changes of messages.
+	server.NoCache = true
+	c := *cfg
+	c.Server, c.Default = &server, &config.Default{}
+
+	client, err := newGRPCClient(&c)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		client.Close(ctx)
+	}()
+	return newSpec(&c, client)
+}
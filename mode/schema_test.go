@@ -0,0 +1,40 @@
+package mode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/pkg/errors"
+)
+
+func TestRunSchemaDiff(t *testing.T) {
+	const (
+		oldDir = "../idl/proto/testdata/diff/old"
+		newDir = "../idl/proto/testdata/diff/new"
+	)
+	cases := map[string]struct {
+		oldSource, newSource string
+		err                  error
+		expected             string
+	}{
+		"breaking changes": {oldSource: oldDir, newSource: newDir, err: ErrBreakingChanges, expected: "- service api.Removed (breaking)\n"},
+		"no changes":       {oldSource: newDir, newSource: newDir + "/api.proto", expected: "no changes\n"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &config.Config{Server: &config.Server{}}
+			err := RunSchemaDiff(cfg, cui.New(cui.Writer(&buf)), c.oldSource, c.newSource)
+			if !errors.Is(err, c.err) {
+				t.Errorf("expected error '%v', but got '%v'", c.err, err)
+			}
+			if actual := buf.String(); !strings.Contains(actual, c.expected) {
+				t.Errorf("output must contain '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package mode
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/report"
+	"github.com/ktr0731/evans/suite"
+	"github.com/pkg/errors"
+)
+
+// RunTestSuite runs test cases in the suite file against the server, and reports the result of each case.
+// At most parallel cases are run concurrently. RunTestSuite returns an error if one or more cases fail.
+// If reportFile is not empty, results are also written to it. See report.WriteFile.
+func RunTestSuite(cfg *config.Config, ui cui.UI, fname string, parallel int, reportFile string) error {
+	s, err := suite.Load(fname)
+	if err != nil {
+		return err
+	}
+	gRPCClient, err := newGRPCClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		gRPCClient.Close(ctx)
+	}()
+	spec, err := newSpec(cfg, gRPCClient)
+	if err != nil {
+		return err
+	}
+
+	runner := suite.NewRunner(spec, gRPCClient, cfg.Request.Header, newScopedHeaders(cfg.Request.ScopedHeaders), cfg.Request.Timeout)
+	results, err := runner.Run(context.Background(), s, parallel)
+	if err != nil {
+		return err
+	}
+	failed := suite.Report(ui.Writer(), results)
+	if reportFile != "" {
+		rep := &report.Report{Name: strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname))}
+		for _, r := range results {
+			rep.Results = append(rep.Results, &report.Result{Name: r.Case.Name, Method: r.Case.Method, Duration: r.Duration, Err: r.Err})
+		}
+		if err := report.WriteFile(reportFile, rep); err != nil {
+			return err
+		}
+	}
+	if failed != 0 {
+		return errors.Errorf("%d of %d cases failed", failed, len(results))
+	}
+	return nil
+}
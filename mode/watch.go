@@ -0,0 +1,132 @@
+package mode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/grpc/grpcreflection"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/pkg/errors"
+)
+
+// remoteSpecReloadInterval is the interval to re-query the schema from gRPC reflection or Buf Schema Registry.
+const remoteSpecReloadInterval = 5 * time.Second
+
+// specReloader reloads the spec used by usecase when the schema is changed.
+// Local files are reloaded when their modification times are changed, and remote schema provided by
+// gRPC reflection or Buf Schema Registry is re-queried at remoteSpecReloadInterval.
+// The states of usecase such that headers and the selected service are kept.
+type specReloader struct {
+	cfg    *config.Config
+	client grpcreflection.Client
+
+	// descSet is the serialized descriptor set of the current spec. It is used to detect changes of the schema.
+	descSet []byte
+	// modTimes is the modification times of the local files the current spec is loaded from.
+	modTimes map[string]time.Time
+	loadedAt time.Time
+}
+
+// newSpecReloader returns a new specReloader for the spec currently injected to usecase.
+func newSpecReloader(cfg *config.Config, client grpcreflection.Client) (*specReloader, error) {
+	// The descriptor cache is bypassed because it is invalidated only when the set of services is changed.
+	server := *cfg.Server
+	server.NoCache = true
+	rcfg := *cfg
+	rcfg.Server = &server
+	r := &specReloader{cfg: &rcfg, client: client}
+	b, err := usecase.ExportDescriptorSet(nil)
+	if err != nil {
+		return nil, err
+	}
+	r.snapshot(b)
+	return r, nil
+}
+
+// Reload reloads the spec if the schema may be changed. It reports whether the schema is actually changed.
+func (r *specReloader) Reload() (bool, error) {
+	if !r.remote() || time.Since(r.loadedAt) < remoteSpecReloadInterval {
+		if !r.modified() {
+			return false, nil
+		}
+	}
+	spec, err := newSpec(r.cfg, r.client)
+	if err != nil {
+		// Take a snapshot to avoid reporting the same error until the files are modified again.
+		r.snapshot(r.descSet)
+		return false, err
+	}
+	b, err := spec.ExportDescriptorSet(nil)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to export the descriptor set of the reloaded spec")
+	}
+	changed := !bytes.Equal(r.descSet, b)
+	if changed {
+		usecase.InjectPartially(usecase.Dependencies{Spec: spec})
+	}
+	r.snapshot(b)
+	return changed, nil
+}
+
+func (r *specReloader) remote() bool {
+	return r.cfg.Server.Reflection || r.cfg.Default.BSR != ""
+}
+
+func (r *specReloader) snapshot(descSet []byte) {
+	r.descSet = descSet
+	r.loadedAt = time.Now()
+	r.modTimes = make(map[string]time.Time)
+	for _, fname := range r.localFiles() {
+		if fi, err := os.Stat(fname); err == nil {
+			r.modTimes[fname] = fi.ModTime()
+		}
+	}
+}
+
+func (r *specReloader) modified() bool {
+	for fname, t := range r.modTimes {
+		fi, err := os.Stat(fname)
+		if err != nil || !fi.ModTime().Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// localFiles returns paths of protosets, or proto files contained in the current spec which are found in import paths.
+func (r *specReloader) localFiles() []string {
+	if len(r.cfg.Default.Protoset) != 0 {
+		return r.cfg.Default.Protoset
+	}
+	if len(r.cfg.Default.ProtoFile) == 0 {
+		return nil
+	}
+	var set descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(r.descSet, &set); err != nil {
+		return nil
+	}
+	importPaths := r.cfg.Default.ProtoPath
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	var fnames []string
+	for _, fd := range set.GetFile() {
+		if filepath.IsAbs(fd.GetName()) {
+			fnames = append(fnames, fd.GetName())
+			continue
+		}
+		for _, path := range importPaths {
+			fname := filepath.Join(path, fd.GetName())
+			if _, err := os.Stat(fname); err == nil {
+				fnames = append(fnames, fname)
+				break
+			}
+		}
+	}
+	return fnames
+}
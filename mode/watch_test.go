@@ -0,0 +1,95 @@
+package mode
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/config"
+	"github.com/ktr0731/evans/usecase"
+)
+
+func Test_specReloader(t *testing.T) {
+	defer usecase.Clear()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "api.proto")
+	mtime := time.Now()
+	writeProto := func(content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(fname, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write the proto file: %s", err)
+		}
+		// Change the modification time explicitly because the resolution of it may be coarse.
+		mtime = mtime.Add(time.Second)
+		if err := os.Chtimes(fname, mtime, mtime); err != nil {
+			t.Fatalf("failed to change the modification time: %s", err)
+		}
+	}
+	const (
+		before = `syntax = "proto3"; package api; message M {} service Foo { rpc Get(M) returns (M); }`
+		after  = `syntax = "proto3"; package api; message M {} service Foo { rpc Get(M) returns (M); } service Bar {}`
+	)
+	writeProto(before)
+
+	cfg := &config.Config{
+		Default: &config.Default{ProtoPath: []string{dir}, ProtoFile: []string{"api.proto"}},
+		Server:  &config.Server{},
+	}
+	spec, err := newSpec(cfg, nil)
+	if err != nil {
+		t.Fatalf("newSpec must not return an error, but got '%s'", err)
+	}
+	usecase.Inject(usecase.Dependencies{Spec: spec})
+	if err := usecase.UsePackage("api"); err != nil {
+		t.Fatalf("UsePackage must not return an error, but got '%s'", err)
+	}
+	if err := usecase.UseService("Foo"); err != nil {
+		t.Fatalf("UseService must not return an error, but got '%s'", err)
+	}
+
+	r, err := newSpecReloader(cfg, nil)
+	if err != nil {
+		t.Fatalf("newSpecReloader must not return an error, but got '%s'", err)
+	}
+	assertReload := func(expected, hasErr bool) {
+		t.Helper()
+		changed, err := r.Reload()
+		if hasErr != (err != nil) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if changed != expected {
+			t.Errorf("expected %t, but got %t", expected, changed)
+		}
+	}
+
+	assertReload(false, false)
+
+	writeProto(after)
+	assertReload(true, false)
+	if diff := cmp.Diff([]string{"api.Foo", "api.Bar"}, usecase.ListServices()); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+	if dsn := usecase.GetDomainSourceName(); dsn != "api.Foo" {
+		t.Errorf("the selected service must be kept after reloading, but got '%s'", dsn)
+	}
+
+	// Modified, but the schema is not changed.
+	writeProto(after + "\n")
+	assertReload(false, false)
+
+	// The error is reported only once until the file is modified again.
+	writeProto("invalid")
+	assertReload(false, true)
+	assertReload(false, false)
+	if diff := cmp.Diff([]string{"api.Foo", "api.Bar"}, usecase.ListServices()); diff != "" {
+		t.Errorf("the previous spec must be kept, but got diff: -want, +got\n%s", diff)
+	}
+}
@@ -0,0 +1,279 @@
+// Package perf provides a load tester which calls an RPC concurrently for a while, and reports the throughput,
+// the error rate and the latency distribution.
+package perf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options is the settings of a load test.
+type Options struct {
+	// Concurrency is the number of workers which call the RPC concurrently.
+	Concurrency int
+	// Duration is how long workers start new calls. Calls in flight at the end are waited for.
+	Duration time.Duration
+	// Rate is the maximum number of calls per second in total. If it is zero, calls are not limited.
+	Rate float64
+	// Stages is the schedule of the rate. If it is not empty, Duration and Rate must be zero,
+	// and the test lasts for the total duration of the stages.
+	Stages []Stage
+}
+
+// schedule returns the stages which the rate follows, or nil if the rate is unlimited.
+func (o Options) schedule() []Stage {
+	if len(o.Stages) != 0 {
+		return o.Stages
+	}
+	if o.Rate > 0 {
+		return []Stage{{From: o.Rate, To: o.Rate, Duration: o.Duration}}
+	}
+	return nil
+}
+
+// TotalDuration returns how long the test lasts.
+func (o Options) TotalDuration() time.Duration {
+	if len(o.Stages) == 0 {
+		return o.Duration
+	}
+	var d time.Duration
+	for _, s := range o.Stages {
+		d += s.Duration
+	}
+	return d
+}
+
+func (o Options) validate() error {
+	if o.Concurrency <= 0 {
+		return errors.New("concurrency must be greater than 0")
+	}
+	if o.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+	if len(o.Stages) == 0 {
+		if o.Duration <= 0 {
+			return errors.New("duration must be greater than 0")
+		}
+		return nil
+	}
+	if o.Duration != 0 || o.Rate != 0 {
+		return errors.New("duration and rate cannot be used with stages")
+	}
+	for _, s := range o.Stages {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Call calls the RPC once. It returns a gRPC status error if the call fails.
+type Call func(ctx context.Context) error
+
+// Result is the result of a load test.
+type Result struct {
+	// Elapsed is the time from the start to the end of the last call.
+	Elapsed time.Duration
+	// Latencies is the latencies of all calls in ascending order.
+	Latencies []time.Duration
+	// Codes is the number of calls for each status code. Errors which are not gRPC statuses are counted as Unknown.
+	Codes map[codes.Code]int
+}
+
+// Run calls call with opts.Concurrency workers until the duration elapses or ctx is canceled.
+// If the rate is limited, workers wait for their turn before each call.
+// Calls which are aborted because ctx is canceled are not counted.
+func Run(ctx context.Context, opts Options, call Call) (*Result, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	type record struct {
+		latencies []time.Duration
+		codes     map[codes.Code]int
+	}
+	records := make([]*record, opts.Concurrency)
+	start := time.Now()
+	deadline := start.Add(opts.TotalDuration())
+	var tokens <-chan struct{}
+	if stages := opts.schedule(); stages != nil {
+		limiterCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		tokens = limit(limiterCtx, start, stages)
+	}
+	var wg sync.WaitGroup
+	for i := range records {
+		rec := &record{codes: make(map[codes.Code]int)}
+		records[i] = rec
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && time.Now().Before(deadline) {
+				if tokens != nil {
+					if _, ok := <-tokens; !ok {
+						return
+					}
+				}
+				s := time.Now()
+				err := call(ctx)
+				d := time.Since(s)
+				if ctx.Err() != nil {
+					return
+				}
+				rec.latencies = append(rec.latencies, d)
+				rec.codes[status.Code(errors.Cause(err))]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := &Result{Elapsed: time.Since(start), Codes: make(map[codes.Code]int)}
+	for _, rec := range records {
+		res.Latencies = append(res.Latencies, rec.latencies...)
+		for code, n := range rec.codes {
+			res.Codes[code] += n
+		}
+	}
+	sort.Slice(res.Latencies, func(i, j int) bool { return res.Latencies[i] < res.Latencies[j] })
+	return res, nil
+}
+
+// Requests returns the number of calls.
+func (r *Result) Requests() int {
+	return len(r.Latencies)
+}
+
+// Errors returns the number of calls which didn't return OK.
+func (r *Result) Errors() int {
+	return r.Requests() - r.Codes[codes.OK]
+}
+
+// Percentile returns the p-th percentile latency by the nearest-rank method. p must be in (0, 100].
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(r.Latencies)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return r.Latencies[i]
+}
+
+func (r *Result) mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, l := range r.Latencies {
+		sum += l
+	}
+	return sum / time.Duration(len(r.Latencies))
+}
+
+const (
+	histogramBuckets  = 10
+	histogramBarWidth = 40
+)
+
+// Report writes the summary, latency percentiles, the latency histogram and the number of each status code to w.
+func (r *Result) Report(w io.Writer) {
+	n := r.Requests()
+	fmt.Fprintf(w, "Summary:\n")
+	fmt.Fprintf(w, "  Requests:    %d\n", n)
+	fmt.Fprintf(w, "  Elapsed:     %s\n", round(r.Elapsed))
+	fmt.Fprintf(w, "  Throughput:  %.2f req/s\n", float64(n)/r.Elapsed.Seconds())
+	var rate float64
+	if n != 0 {
+		rate = float64(r.Errors()) / float64(n) * 100
+	}
+	fmt.Fprintf(w, "  Errors:      %d (%.2f%%)\n", r.Errors(), rate)
+	if n == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nLatency:\n")
+	fmt.Fprintf(w, "  min  %s\n", round(r.Latencies[0]))
+	fmt.Fprintf(w, "  avg  %s\n", round(r.mean()))
+	fmt.Fprintf(w, "  p50  %s\n", round(r.Percentile(50)))
+	fmt.Fprintf(w, "  p95  %s\n", round(r.Percentile(95)))
+	fmt.Fprintf(w, "  p99  %s\n", round(r.Percentile(99)))
+	fmt.Fprintf(w, "  max  %s\n", round(r.Latencies[n-1]))
+
+	fmt.Fprintf(w, "\nHistogram:\n")
+	r.writeHistogram(w)
+
+	fmt.Fprintf(w, "\nStatus codes:\n")
+	codeList := make([]codes.Code, 0, len(r.Codes))
+	for code := range r.Codes {
+		codeList = append(codeList, code)
+	}
+	sort.Slice(codeList, func(i, j int) bool { return codeList[i] < codeList[j] })
+	for _, code := range codeList {
+		fmt.Fprintf(w, "  %-18s %d\n", code, r.Codes[code])
+	}
+}
+
+// writeHistogram writes the histogram of latencies which divides the range from the minimum to the maximum
+// into histogramBuckets buckets. Each bucket is labeled with its upper bound.
+func (r *Result) writeHistogram(w io.Writer) {
+	min, max := r.Latencies[0], r.Latencies[len(r.Latencies)-1]
+	width := (max - min) / histogramBuckets
+	if width == 0 {
+		fmt.Fprintf(w, "  %s [%d] |%s\n", round(max), len(r.Latencies), strings.Repeat("∎", histogramBarWidth))
+		return
+	}
+
+	var counts [histogramBuckets]int
+	for _, l := range r.Latencies {
+		i := int((l - min) / width)
+		if i >= histogramBuckets {
+			i = histogramBuckets - 1
+		}
+		counts[i]++
+	}
+	var most int
+	labels := make([]string, histogramBuckets)
+	var labelWidth, countWidth int
+	for i, c := range counts {
+		if c > most {
+			most = c
+		}
+		labels[i] = round(min + width*time.Duration(i+1)).String()
+		if i == histogramBuckets-1 {
+			labels[i] = round(max).String()
+		}
+		if len(labels[i]) > labelWidth {
+			labelWidth = len(labels[i])
+		}
+		if n := len(fmt.Sprint(c)); n > countWidth {
+			countWidth = n
+		}
+	}
+	for i, c := range counts {
+		bar := strings.Repeat("∎", c*histogramBarWidth/most)
+		fmt.Fprintf(w, "  %-*s [%*d] |%s\n", labelWidth, labels[i], countWidth, c, bar)
+	}
+}
+
+// round rounds d to a readable precision.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,141 @@
+package perf_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/perf"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRun(t *testing.T) {
+	var n int64
+	call := func(ctx context.Context) error {
+		time.Sleep(time.Millisecond)
+		switch atomic.AddInt64(&n, 1) % 4 {
+		case 0:
+			return errors.Wrap(status.Error(codes.Unavailable, "unavailable"), "failed to call")
+		case 1:
+			return errors.New("not a status error")
+		default:
+			return nil
+		}
+	}
+	res, err := perf.Run(context.Background(), perf.Options{Concurrency: 4, Duration: 50 * time.Millisecond}, call)
+	if err != nil {
+		t.Fatalf("Run must not return an error, but got '%s'", err)
+	}
+	if int64(res.Requests()) != atomic.LoadInt64(&n) {
+		t.Errorf("expected %d requests, but got %d", n, res.Requests())
+	}
+	var sum int
+	for _, c := range res.Codes {
+		sum += c
+	}
+	if sum != res.Requests() {
+		t.Errorf("the sum of status codes must be the number of requests, but got %d and %d", sum, res.Requests())
+	}
+	if res.Codes[codes.Unavailable] == 0 || res.Codes[codes.Unknown] == 0 {
+		t.Errorf("wrapped status errors and non-status errors must be counted, but got %v", res.Codes)
+	}
+	if res.Errors() != res.Requests()-res.Codes[codes.OK] {
+		t.Errorf("unexpected number of errors: %d", res.Errors())
+	}
+	for i := 1; i < len(res.Latencies); i++ {
+		if res.Latencies[i-1] > res.Latencies[i] {
+			t.Fatalf("latencies must be sorted")
+		}
+	}
+
+	var buf bytes.Buffer
+	res.Report(&buf)
+	for _, s := range []string{"Requests:", "Throughput:", "p99", "Histogram:", "Unavailable"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("the report must contain '%s', but got:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestRun_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int64
+	call := func(ctx context.Context) error {
+		if atomic.AddInt64(&n, 1) == 10 {
+			cancel()
+		}
+		<-time.After(time.Millisecond)
+		return ctx.Err()
+	}
+	res, err := perf.Run(ctx, perf.Options{Concurrency: 1, Duration: time.Minute}, call)
+	if err != nil {
+		t.Fatalf("Run must not return an error, but got '%s'", err)
+	}
+	if res.Requests() != 9 {
+		t.Errorf("calls aborted by the cancellation must not be counted, but got %d requests", res.Requests())
+	}
+	if res.Errors() != 0 {
+		t.Errorf("expected no errors, but got %d", res.Errors())
+	}
+}
+
+func TestRun_rateLimited(t *testing.T) {
+	call := func(context.Context) error { return nil }
+	cases := map[string]perf.Options{
+		"rate":   {Concurrency: 4, Duration: 200 * time.Millisecond, Rate: 50},
+		"stages": {Concurrency: 4, Stages: []perf.Stage{{From: 0, To: 50, Duration: 200 * time.Millisecond}, {From: 50, To: 50, Duration: 100 * time.Millisecond}}},
+	}
+	expected := map[string]int{"rate": 10, "stages": 10}
+	for name, opts := range cases {
+		res, err := perf.Run(context.Background(), opts, call)
+		if err != nil {
+			t.Fatalf("%s: Run must not return an error, but got '%s'", name, err)
+		}
+		if n := res.Requests(); n > expected[name] {
+			t.Errorf("%s: expected at most %d requests, but got %d", name, expected[name], n)
+		}
+	}
+}
+
+func TestRun_invalidOptions(t *testing.T) {
+	cases := map[string]perf.Options{
+		"no workers":             {Duration: time.Second},
+		"no duration":            {Concurrency: 1},
+		"negative rate":          {Concurrency: 1, Duration: time.Second, Rate: -1},
+		"stages with a rate":     {Concurrency: 1, Rate: 1, Stages: []perf.Stage{{From: 1, To: 1, Duration: time.Second}}},
+		"an invalid stage":       {Concurrency: 1, Stages: []perf.Stage{{From: 1, To: 1}}},
+		"stages with a duration": {Concurrency: 1, Duration: time.Second, Stages: []perf.Stage{{From: 1, To: 1, Duration: time.Second}}},
+	}
+	for name, opts := range cases {
+		if _, err := perf.Run(context.Background(), opts, nil); err == nil {
+			t.Errorf("%s: Run must return an error", name)
+		}
+	}
+}
+
+func TestResult_Percentile(t *testing.T) {
+	res := &perf.Result{}
+	if p := res.Percentile(50); p != 0 {
+		t.Errorf("the percentile of no latencies must be 0, but got %s", p)
+	}
+	for i := 1; i <= 100; i++ {
+		res.Latencies = append(res.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	cases := map[float64]time.Duration{
+		1:   time.Millisecond,
+		50:  50 * time.Millisecond,
+		95:  95 * time.Millisecond,
+		99:  99 * time.Millisecond,
+		100: 100 * time.Millisecond,
+	}
+	for p, expected := range cases {
+		if actual := res.Percentile(p); actual != expected {
+			t.Errorf("p%v: expected %s, but got %s", p, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package perf
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stage is a period in which the rate (calls per second) changes linearly from From to To.
+type Stage struct {
+	From, To float64
+	Duration time.Duration
+}
+
+// ParseStage parses a stage such that "100:30s" (100 calls per second for 30 seconds) or "10-100:1m"
+// (ramping up from 10 to 100 calls per second over a minute).
+func ParseStage(s string) (Stage, error) {
+	i := strings.LastIndex(s, ":")
+	if i == -1 {
+		return Stage{}, errors.Errorf("invalid stage '%s'. the format must be RATE:DURATION or FROM-TO:DURATION", s)
+	}
+	d, err := time.ParseDuration(s[i+1:])
+	if err != nil {
+		return Stage{}, errors.Wrapf(err, "invalid duration of the stage '%s'", s)
+	}
+	from, to := s[:i], s[:i]
+	if j := strings.Index(s[:i], "-"); j != -1 {
+		from, to = s[:j], s[j+1:i]
+	}
+	var stage Stage
+	stage.Duration = d
+	stage.From, err = strconv.ParseFloat(from, 64)
+	if err != nil {
+		return Stage{}, errors.Wrapf(err, "invalid rate of the stage '%s'", s)
+	}
+	stage.To, err = strconv.ParseFloat(to, 64)
+	if err != nil {
+		return Stage{}, errors.Wrapf(err, "invalid rate of the stage '%s'", s)
+	}
+	if err := stage.validate(); err != nil {
+		return Stage{}, err
+	}
+	return stage, nil
+}
+
+func (s Stage) validate() error {
+	if s.From < 0 || s.To < 0 {
+		return errors.New("rates of stages must not be negative")
+	}
+	if s.Duration <= 0 {
+		return errors.New("durations of stages must be greater than 0")
+	}
+	return nil
+}
+
+// calls returns the number of calls in the stage.
+func (s Stage) calls() float64 {
+	return (s.From + s.To) / 2 * s.Duration.Seconds()
+}
+
+// offset returns the time from the start of the stage at which the n-th call is made.
+// The number of calls until t is the integral of the rate, that is, From*t + (To-From)/(2*Duration)*t^2.
+// n must not be greater than calls.
+func (s Stage) offset(n float64) time.Duration {
+	a := (s.To - s.From) / (2 * s.Duration.Seconds())
+	var t float64
+	if a == 0 {
+		t = n / s.From
+	} else {
+		t = (-s.From + math.Sqrt(s.From*s.From+4*a*n)) / (2 * a)
+	}
+	return time.Duration(t * float64(time.Second))
+}
+
+// offsetOf returns the time from start at which the n-th call is made according to stages.
+// It returns false if the n-th call is after the last stage.
+func offsetOf(stages []Stage, n float64) (time.Duration, bool) {
+	var base time.Duration
+	for _, s := range stages {
+		if c := s.calls(); n > c {
+			n -= c
+			base += s.Duration
+			continue
+		}
+		return base + s.offset(n), true
+	}
+	return 0, false
+}
+
+// Limit returns a channel which receives a value each time a call is allowed. Calls are limited to rate calls per
+// second, or follow stages if they are not empty. Unlike load tests, the rate at the end of the last stage is kept
+// after it, so that any number of calls are allowed. The channel is closed when ctx is canceled.
+// Limit returns nil if rate is zero and stages are empty.
+func Limit(ctx context.Context, rate float64, stages []Stage) (<-chan struct{}, error) {
+	if rate < 0 {
+		return nil, errors.New("rate must not be negative")
+	}
+	if len(stages) == 0 && rate == 0 {
+		return nil, nil
+	}
+	if len(stages) != 0 && rate != 0 {
+		return nil, errors.New("rate cannot be used with stages")
+	}
+	for _, s := range stages {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(stages) != 0 {
+		rate = stages[len(stages)-1].To
+	}
+	if rate == 0 {
+		return nil, errors.New("the rate at the end of the last stage must be greater than 0")
+	}
+	stages = append(stages[:len(stages):len(stages)], Stage{From: rate, To: rate, Duration: math.MaxInt64})
+	return limit(ctx, time.Now(), stages), nil
+}
+
+// limit returns a channel which receives a value each time a call is allowed according to stages.
+// The channel is closed after the last stage or when ctx is canceled.
+func limit(ctx context.Context, start time.Time, stages []Stage) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		<-timer.C
+		for n := 1; ; n++ {
+			offset, ok := offsetOf(stages, float64(n))
+			if !ok {
+				return
+			}
+			timer.Reset(time.Until(start.Add(offset)))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case ch <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
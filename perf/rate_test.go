@@ -0,0 +1,122 @@
+package perf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseStage(t *testing.T) {
+	cases := map[string]struct {
+		expected Stage
+		hasErr   bool
+	}{
+		"100:30s":     {expected: Stage{From: 100, To: 100, Duration: 30 * time.Second}},
+		"10-100:1m":   {expected: Stage{From: 10, To: 100, Duration: time.Minute}},
+		"100-0.5:10s": {expected: Stage{From: 100, To: 0.5, Duration: 10 * time.Second}},
+		"100":         {hasErr: true},
+		"100:foo":     {hasErr: true},
+		"a-100:10s":   {hasErr: true},
+		"-10:10s":     {hasErr: true},
+		"100:0s":      {hasErr: true},
+	}
+	for in, c := range cases {
+		actual, err := ParseStage(in)
+		if c.hasErr {
+			if err == nil {
+				t.Errorf("%s: ParseStage must return an error", in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseStage must not return an error, but got '%s'", in, err)
+			continue
+		}
+		if diff := cmp.Diff(c.expected, actual); diff != "" {
+			t.Errorf("%s: -want, +got\n%s", in, diff)
+		}
+	}
+}
+
+func TestOffsetOf(t *testing.T) {
+	stages := []Stage{
+		// 10 calls in total: 1st at 1s, ..., 10th at 10s.
+		{From: 1, To: 1, Duration: 10 * time.Second},
+		// 50 calls in total. The n-th call is made at sqrt(2n) seconds because the rate at t is t.
+		{From: 0, To: 10, Duration: 10 * time.Second},
+	}
+	cases := map[float64]time.Duration{
+		1:  time.Second,
+		10: 10 * time.Second,
+		11: 10*time.Second + 1414213562*time.Nanosecond, // The first call of the second stage.
+		18: 14 * time.Second,                            // The 8th call of the second stage.
+		60: 20 * time.Second,                            // The last call of the second stage.
+	}
+	for n, expected := range cases {
+		actual, ok := offsetOf(stages, n)
+		if !ok {
+			t.Errorf("%v: offsetOf must return true", n)
+			continue
+		}
+		if d := actual - expected; d < -time.Millisecond || time.Millisecond < d {
+			t.Errorf("%v: expected %s, but got %s", n, expected, actual)
+		}
+	}
+	if _, ok := offsetOf(stages, 61); ok {
+		t.Errorf("offsetOf must return false after the last stage")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	start := time.Now()
+	var n int
+	for range limit(context.Background(), start, []Stage{{From: 200, To: 200, Duration: 100 * time.Millisecond}}) {
+		n++
+	}
+	if n != 20 {
+		t.Errorf("expected 20 calls, but got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed < 95*time.Millisecond {
+		t.Errorf("calls must be spread over the stage, but finished in %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range limit(ctx, time.Now(), []Stage{{From: 1, To: 1, Duration: time.Hour}}) {
+		t.Fatalf("no calls must be allowed after the cancellation")
+	}
+}
+
+func TestLimit_keepLastRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokens, err := Limit(ctx, 0, []Stage{{From: 1000, To: 1000, Duration: 5 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("Limit must not return an error, but got '%s'", err)
+	}
+	// The stage allows only 5 calls, but calls are allowed after it.
+	for i := 0; i < 10; i++ {
+		if _, ok := <-tokens; !ok {
+			t.Fatalf("calls must be allowed after the last stage, but the channel is closed after %d calls", i)
+		}
+	}
+
+	if tokens, err := Limit(ctx, 0, nil); tokens != nil || err != nil {
+		t.Errorf("Limit must return nil if the rate is not limited, but got %v, %v", tokens, err)
+	}
+	cases := map[string]struct {
+		rate   float64
+		stages []Stage
+	}{
+		"negative rate":    {rate: -1},
+		"rate with stages": {rate: 1, stages: []Stage{{From: 1, To: 1, Duration: time.Second}}},
+		"zero last rate":   {stages: []Stage{{From: 1, To: 0, Duration: time.Second}}},
+	}
+	for name, c := range cases {
+		if _, err := Limit(ctx, c.rate, c.stages); err == nil {
+			t.Errorf("%s: Limit must return an error", name)
+		}
+	}
+}
@@ -14,7 +14,15 @@ type Presenter struct {
 
 // Format formats v into JSON string.
 func (p *Presenter) Format(v interface{}) (string, error) {
-	b, err := gojson.MarshalIndent(v, "", p.indent)
+	var (
+		b   []byte
+		err error
+	)
+	if p.indent == "" {
+		b, err = gojson.Marshal(v)
+	} else {
+		b, err = gojson.MarshalIndent(v, "", p.indent)
+	}
 	if err != nil {
 		return "", errors.Wrap(err, "failed to format v into JSON string")
 	}
@@ -22,7 +30,7 @@ func (p *Presenter) Format(v interface{}) (string, error) {
 }
 
 // NewPresenter instantiates a JSON presenter.
-// If indent is not empty, Format indents the output.
+// If indent is not empty, Format indents the output. Or else, the output is compact.
 func NewPresenter(indent string) *Presenter {
 	return &Presenter{indent: indent}
 }
@@ -0,0 +1,114 @@
+// Package yaml provides a YAML presenter that formatting.
+package yaml
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	goyaml "gopkg.in/yaml.v2"
+)
+
+// Presenter is a presenter that formats v into YAML string.
+// v is encoded as JSON once, so that JSON struct tags and json.Marshaler implementations are respected.
+// The order of object keys is kept as it is in the JSON representation.
+type Presenter struct{}
+
+// Format formats v into YAML string.
+func (p *Presenter) Format(v interface{}) (string, error) {
+	b, err := gojson.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format v into JSON string")
+	}
+	s, err := FromJSON(b)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format v into YAML string")
+	}
+	return s, nil
+}
+
+// NewPresenter instantiates a YAML presenter.
+func NewPresenter() *Presenter {
+	return &Presenter{}
+}
+
+// FromJSON converts a JSON value into YAML string. The trailing newline is trimmed.
+func FromJSON(b []byte) (string, error) {
+	dec := gojson.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return "", err
+	}
+	out, err := goyaml.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal YAML")
+	}
+	return string(bytes.TrimRight(out, "\n")), nil
+}
+
+// decodeJSONValue decodes a JSON value with keeping the order of object keys.
+// Objects are decoded into yaml.MapSlice.
+func decodeJSONValue(dec *gojson.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JSON")
+	}
+	switch t := tok.(type) {
+	case gojson.Delim:
+		switch t {
+		case '{':
+			m := goyaml.MapSlice{}
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to decode JSON")
+				}
+				v, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, goyaml.MapItem{Key: k, Value: v})
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, errors.Wrap(err, "failed to decode JSON")
+			}
+			return m, nil
+		case '[':
+			s := []interface{}{}
+			for dec.More() {
+				v, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, errors.Wrap(err, "failed to decode JSON")
+			}
+			return s, nil
+		default:
+			return nil, errors.Errorf("unexpected delimiter '%s'", t)
+		}
+	case gojson.Number:
+		if i, err := strconv.ParseInt(t.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid number '%s'", t)
+		}
+		return f, nil
+	default:
+		// string, bool or nil.
+		return t, nil
+	}
+}
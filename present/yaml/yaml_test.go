@@ -0,0 +1,49 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPresenter(t *testing.T) {
+	type member struct {
+		Name  string   `json:"name"`
+		Grade int      `json:"grade"`
+		Tags  []string `json:"tags,omitempty"`
+	}
+	v := struct {
+		Members []member          `json:"members"`
+		Score   float64           `json:"score"`
+		Extra   map[string]string `json:"extra"`
+		Active  bool              `json:"active"`
+		Note    *string           `json:"note"`
+	}{
+		Members: []member{{Name: "kumiko", Grade: 2, Tags: []string{"euphonium", "yes"}}, {Name: "reina", Grade: 2}},
+		Score:   1.5,
+		Extra:   map[string]string{"b": "2", "a": "1"},
+		Active:  true,
+	}
+	const expected = `members:
+- name: kumiko
+  grade: 2
+  tags:
+  - euphonium
+  - "yes"
+- name: reina
+  grade: 2
+score: 1.5
+extra:
+  a: "1"
+  b: "2"
+active: true
+note: null`
+
+	actual, err := NewPresenter().Format(v)
+	if err != nil {
+		t.Fatalf("Format must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
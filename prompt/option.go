@@ -1,7 +1,11 @@
 package prompt
 
+import goprompt "github.com/ktr0731/go-prompt"
+
 type opt struct {
 	commandHistory []string
+	keyBinds       []goprompt.KeyBind
+	fillRandomKey  []byte
 }
 
 type Option func(*opt)
@@ -11,3 +15,44 @@ func WithCommandHistory(h []string) Option {
 		o.commandHistory = h
 	}
 }
+
+// Key represents a key which can be bound by WithKeyBind.
+type Key goprompt.Key
+
+// Available keys for WithKeyBind.
+var (
+	KeyControlG = Key(goprompt.ControlG)
+	KeyControlX = Key(goprompt.ControlX)
+)
+
+// WithKeyBind binds f to key. f receives the current input text and returns the new one.
+func WithKeyBind(key Key, f func(text string) string) Option {
+	return func(o *opt) {
+		o.keyBinds = append(o.keyBinds, goprompt.KeyBind{
+			Key: goprompt.Key(key),
+			Fn: func(b *goprompt.Buffer) {
+				replaceText(b, f(b.Text()))
+			},
+		})
+	}
+}
+
+// WithFillRandomKey makes Input return the current input text with ErrFillRandom when key is pressed.
+// Unlike WithKeyBind, the input is finished by the key without pressing Enter.
+func WithFillRandomKey(key Key) Option {
+	return func(o *opt) {
+		for _, s := range goprompt.ASCIISequences {
+			if s.Key == goprompt.Key(key) {
+				o.fillRandomKey = s.ASCIICode
+				return
+			}
+		}
+	}
+}
+
+// replaceText replaces the whole text of b with text.
+func replaceText(b *goprompt.Buffer, text string) {
+	b.DeleteBeforeCursor(len([]rune(b.Document().TextBeforeCursor())))
+	b.Delete(len([]rune(b.Document().TextAfterCursor())))
+	b.InsertText(text, false, true)
+}
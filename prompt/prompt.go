@@ -2,8 +2,12 @@
 package prompt
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync/atomic"
 
 	"github.com/chzyer/readline"
 	goprompt "github.com/ktr0731/go-prompt"
@@ -38,11 +42,39 @@ var (
 
 var (
 	ErrAbort = errors.New("abort")
+	// ErrFillRandom is returned from Input when the key specified by WithFillRandomKey is pressed.
+	ErrFillRandom = errors.New("fill random")
 )
 
 // Color represents a valid color for a prompt prefix.
 type Color goprompt.Color
 
+var colorNames = map[string]Color{
+	"default":   Color(goprompt.DefaultColor),
+	"black":     Color(goprompt.Black),
+	"darkred":   Color(goprompt.DarkRed),
+	"darkgreen": Color(goprompt.DarkGreen),
+	"brown":     Color(goprompt.Brown),
+	"darkblue":  Color(goprompt.DarkBlue),
+	"purple":    Color(goprompt.Purple),
+	"cyan":      Color(goprompt.Cyan),
+	"lightgray": Color(goprompt.LightGray),
+	"darkgray":  Color(goprompt.DarkGray),
+	"red":       Color(goprompt.Red),
+	"green":     Color(goprompt.Green),
+	"yellow":    Color(goprompt.Yellow),
+	"blue":      Color(goprompt.Blue),
+	"fuchsia":   Color(goprompt.Fuchsia),
+	"turquoise": Color(goprompt.Turquoise),
+	"white":     Color(goprompt.White),
+}
+
+// ColorByName returns the color named name such that "red" or "darkgreen". The name is case-insensitive.
+func ColorByName(name string) (Color, bool) {
+	c, ok := colorNames[strings.ToLower(name)]
+	return c, ok
+}
+
 // Next returns the next color of c. Note that Next will circular if c is the end of colors.
 func (c *Color) Next() {
 	*c = (*c + 1) % 16
@@ -55,6 +87,11 @@ type Prompt interface {
 	Input() (string, error)
 	Select(message string, options []string) (selected string, _ error)
 
+	// FuzzySelect shows items and lets the user select one of them with narrowing them down by fuzzy search.
+	// It returns the index of the selected item in items.
+	// If ctrl+d is entered, FuzzySelect returns io.EOF. If ctrl+c is entered, FuzzySelect returns ErrAbort.
+	FuzzySelect(message string, items []*SelectItem) (int, error)
+
 	// SetPrefix changes the current prefix to the passed one.
 	SetPrefix(prefix string)
 
@@ -86,7 +123,27 @@ func newPrompt(opts ...Option) Prompt {
 			_, res, err := s.Run()
 			return res, err
 		},
+		FuzzySelectFunc: func(message string, items []*SelectItem) (int, error) {
+			s := promptui.Select{
+				Label: message,
+				Items: items,
+				Size:  10,
+				Searcher: func(input string, i int) bool {
+					return FuzzyMatch(input, items[i].Name)
+				},
+				StartInSearchMode: true,
+				Templates: &promptui.SelectTemplates{
+					Active:   fmt.Sprintf("%s {{ .Name | underline }}", promptui.IconSelect),
+					Inactive: "  {{ .Name }}",
+					Selected: fmt.Sprintf(`{{ "%s" | green }} {{ .Name | faint }}`, promptui.IconGood),
+					Details:  "{{ .Description }}",
+				},
+			}
+			i, _, err := s.Run()
+			return i, err
+		},
 		commandHistory: opt.commandHistory,
+		fillRandomKey:  opt.fillRandomKey,
 	}
 
 	p.options = []goprompt.Option{
@@ -103,6 +160,8 @@ func newPrompt(opts ...Option) Prompt {
 		goprompt.OptionSelectedDescriptionTextColor(goprompt.Black),
 
 		goprompt.OptionHistory(p.commandHistory),
+		goprompt.OptionAddKeyBind(goprompt.KeyBind{Key: goprompt.ControlR, Fn: p.searchHistory}),
+		goprompt.OptionAddKeyBind(opt.keyBinds...),
 	}
 	return p
 }
@@ -113,30 +172,93 @@ type prompt struct {
 	completer      Completer
 	commandHistory []string
 	options        []goprompt.Option
+	search         *historySearch
+	fillRandomKey  []byte
 
 	// Treat prompt functions as fields for testing.
-	InputFunc  func(prefix string, completer goprompt.Completer, opts ...goprompt.Option) (string, error)
-	SelectFunc func(message string, options []string) (string, error)
+	InputFunc       func(prefix string, completer goprompt.Completer, opts ...goprompt.Option) (string, error)
+	SelectFunc      func(message string, options []string) (string, error)
+	FuzzySelectFunc func(message string, items []*SelectItem) (int, error)
 }
 
 func (p *prompt) Input() (in string, err error) {
-	in, err = p.InputFunc(
-		p.prefix,
-		toGoPromptCompleter(p.completer),
-		append(
-			p.options,
-			goprompt.OptionPrefixTextColor(goprompt.Color(p.prefixColor)),
-			goprompt.OptionHistory(p.commandHistory),
-		)...)
+	p.search = nil
+	opts := append(
+		p.options,
+		goprompt.OptionPrefixTextColor(goprompt.Color(p.prefixColor)),
+		goprompt.OptionHistory(p.commandHistory),
+	)
+	var parser *submitKeyParser
+	if p.fillRandomKey != nil {
+		parser = &submitKeyParser{ConsoleParser: goprompt.NewStandardInputParser(), key: p.fillRandomKey}
+		opts = append(opts, goprompt.OptionParser(parser))
+	}
+	in, err = p.InputFunc(p.prefix, toGoPromptCompleter(p.completer), opts...)
 	if errors.Is(err, goprompt.ErrAbort) {
 		return "", ErrAbort
 	} else if err != nil {
 		return "", err
 	}
-	p.commandHistory = append(p.commandHistory, in)
+	if parser != nil && parser.pressed() {
+		return in, ErrFillRandom
+	}
+	// Skip empty inputs and the same input as the previous one.
+	if n := len(p.commandHistory); in != "" && (n == 0 || p.commandHistory[n-1] != in) {
+		p.commandHistory = append(p.commandHistory, in)
+	}
 	return in, nil
 }
 
+// submitKeyParser reads inputs from the underlying parser. It replaces key with Enter to finish the input,
+// and records that key is pressed.
+type submitKeyParser struct {
+	goprompt.ConsoleParser
+	key []byte
+
+	keyPressed int32
+}
+
+func (p *submitKeyParser) Read() ([]byte, error) {
+	b, err := p.ConsoleParser.Read()
+	if err == nil && bytes.Equal(b, p.key) {
+		atomic.StoreInt32(&p.keyPressed, 1)
+		return []byte{'\r'}, nil
+	}
+	return b, err
+}
+
+func (p *submitKeyParser) pressed() bool {
+	return atomic.LoadInt32(&p.keyPressed) == 1
+}
+
+// historySearch is the state of the reverse incremental search.
+type historySearch struct {
+	// query is the text which was in the buffer when the search started.
+	query string
+	// found is the last found command. pos is the index of it.
+	found string
+	pos   int
+}
+
+// searchHistory replaces the text of b with the newest command in the history which contains the text.
+// If the text is the command found by the last search, older commands are searched by the same query.
+// It is bound to Ctrl-R.
+func (p *prompt) searchHistory(b *goprompt.Buffer) {
+	text := b.Text()
+	if p.search == nil || p.search.found != text {
+		p.search = &historySearch{query: text, pos: len(p.commandHistory)}
+	}
+	for i := p.search.pos - 1; i >= 0; i-- {
+		c := p.commandHistory[i]
+		if c == text || !strings.Contains(c, p.search.query) {
+			continue
+		}
+		p.search.found, p.search.pos = c, i
+		replaceText(b, c)
+		return
+	}
+}
+
 func (p *prompt) Select(message string, options []string) (string, error) {
 	for {
 		res, err := p.SelectFunc(message, options)
@@ -153,6 +275,20 @@ func (p *prompt) Select(message string, options []string) (string, error) {
 	}
 }
 
+func (p *prompt) FuzzySelect(message string, items []*SelectItem) (int, error) {
+	i, err := p.FuzzySelectFunc(message, items)
+	if errors.Is(err, promptui.ErrInterrupt) {
+		return 0, ErrAbort
+	}
+	if errors.Is(err, promptui.ErrEOF) {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to select an item")
+	}
+	return i, nil
+}
+
 func (p *prompt) SetPrefix(prefix string) {
 	p.prefix = prefix
 }
@@ -169,6 +305,11 @@ func (p *prompt) GetCommandHistory() []string {
 	return p.commandHistory
 }
 
+// SetCommandHistory replaces the command history with h. The order of h must be asc.
+func (p *prompt) SetCommandHistory(h []string) {
+	p.commandHistory = h
+}
+
 func (p *prompt) livePrefix() (string, bool) {
 	return p.prefix, true
 }
@@ -202,6 +343,34 @@ func NewSuggestion(text, description string) *Suggest {
 	}
 }
 
+// SelectItem is an item of FuzzySelect.
+type SelectItem struct {
+	// Name is shown in the list and matched with the search query.
+	Name string
+	// Description is shown in the details pane while the item is highlighted.
+	Description string
+}
+
+// FuzzyMatch reports whether s matches query such that fzf does. query is split into words by white spaces,
+// and each word must appear in s in order, though other characters may be between them.
+// Matching is case-insensitive.
+func FuzzyMatch(query, s string) bool {
+	s = strings.ToLower(s)
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		rs := []rune(w)
+		i := 0
+		for _, r := range s {
+			if i < len(rs) && r == rs[i] {
+				i++
+			}
+		}
+		if i < len(rs) {
+			return false
+		}
+	}
+	return true
+}
+
 // FilterHasPrefix filters s by whether have sub as the prefix.
 // If ignoreCase is true, differences between upper and lower casing are ignored.
 func FilterHasPrefix(s []*Suggest, sub string, ignoreCase bool) []*Suggest {
@@ -119,6 +119,70 @@ func TestPrompt_Select(t *testing.T) {
 	}
 }
 
+func TestPrompt_FuzzySelect(t *testing.T) {
+	cases := map[string]struct {
+		err         error
+		expectedErr error
+	}{
+		"normal": {},
+		"returns ErrAbort if promptui.ErrInterrupt is returned from FuzzySelectFunc": {
+			err:         promptui.ErrInterrupt,
+			expectedErr: ErrAbort,
+		},
+		"returns io.EOF if promptui.ErrEOF is returned from FuzzySelectFunc": {
+			err:         promptui.ErrEOF,
+			expectedErr: io.EOF,
+		},
+		"returns an error if an error is returned from FuzzySelectFunc": {
+			err:         io.ErrUnexpectedEOF,
+			expectedErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			p := newPrompt()
+			p.(*prompt).FuzzySelectFunc = func(message string, items []*SelectItem) (int, error) {
+				return 1, c.err
+			}
+			i, err := p.FuzzySelect("", []*SelectItem{{Name: "foo"}, {Name: "bar"}})
+			if c.expectedErr == nil {
+				if err != nil {
+					t.Fatalf("FuzzySelect must not return an error, but got '%s'", err)
+				}
+				if i != 1 {
+					t.Errorf("expected 1, but got %d", i)
+				}
+				return
+			}
+			if errors.Cause(err) != c.expectedErr {
+				t.Errorf("expected error '%s', but got '%s'", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	cases := []struct {
+		query, s string
+		expected bool
+	}{
+		{query: "", s: "api.Example.Unary", expected: true},
+		{query: "unary", s: "api.Example.Unary", expected: true},
+		{query: "aeu", s: "api.Example.Unary", expected: true},
+		{query: "ex una", s: "api.Example.Unary", expected: true},
+		{query: "una ex", s: "api.Example.Unary", expected: true},
+		{query: "uae", s: "api.Example.Unary", expected: false},
+		{query: "ex bidi", s: "api.Example.Unary", expected: false},
+	}
+	for _, c := range cases {
+		if actual := FuzzyMatch(c.query, c.s); actual != c.expected {
+			t.Errorf("FuzzyMatch(%q, %q): expected %t, but got %t", c.query, c.s, c.expected, actual)
+		}
+	}
+}
+
 type dummyCompleter struct{}
 
 func (c *dummyCompleter) Complete(d Document) []*Suggest {
@@ -139,3 +203,46 @@ func Test_toGoPromptCompleter(t *testing.T) {
 		t.Errorf("expected 'bar', but got '%s'", suggestions[1].Text)
 	}
 }
+
+func TestPrompt_searchHistory(t *testing.T) {
+	p := newPrompt(WithCommandHistory([]string{"call Unary", "show service", "call --edit Unary", "header foo=bar"})).(*prompt)
+	b := goprompt.NewBuffer()
+	b.InsertText("call", false, true)
+
+	for _, expected := range []string{"call --edit Unary", "call Unary", "call Unary"} {
+		p.searchHistory(b)
+		if actual := b.Text(); actual != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, actual)
+		}
+	}
+
+	// Editing the found command starts a new search.
+	b.InsertText(" --edit", false, true)
+	p.searchHistory(b)
+	if actual := b.Text(); actual != "call Unary --edit" {
+		t.Errorf("expected the text is kept if no commands match, but got '%s'", actual)
+	}
+}
+
+type stubConsoleParser struct {
+	goprompt.ConsoleParser
+	inputs [][]byte
+}
+
+func (p *stubConsoleParser) Read() ([]byte, error) {
+	b := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	return b, nil
+}
+
+func Test_submitKeyParser(t *testing.T) {
+	key := []byte{0x7}
+	p := &submitKeyParser{ConsoleParser: &stubConsoleParser{inputs: [][]byte{[]byte("a"), key}}, key: key}
+
+	if b, _ := p.Read(); string(b) != "a" || p.pressed() {
+		t.Errorf("expected the input as it is, but got '%s' (pressed: %t)", b, p.pressed())
+	}
+	if b, _ := p.Read(); string(b) != "\r" || !p.pressed() {
+		t.Errorf("expected Enter and the key is pressed, but got '%q' (pressed: %t)", b, p.pressed())
+	}
+}
@@ -3,15 +3,35 @@ package repl
 import (
 	"bytes"
 	"context"
+	gojson "encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/ktr0731/evans/fill"
+	fillproto "github.com/ktr0731/evans/fill/proto"
 	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/format/csv"
 	"github.com/ktr0731/evans/format/curl"
+	"github.com/ktr0731/evans/format/json"
+	"github.com/ktr0731/evans/format/ndjson"
+	"github.com/ktr0731/evans/format/prototext"
+	"github.com/ktr0731/evans/format/table"
+	"github.com/ktr0731/evans/format/yaml"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/history"
 	"github.com/ktr0731/evans/idl"
+	jsonpresenter "github.com/ktr0731/evans/present/json"
+	tablepresenter "github.com/ktr0731/evans/present/table"
+	"github.com/ktr0731/evans/prompt"
 	"github.com/ktr0731/evans/usecase"
+	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -62,12 +82,7 @@ func (c *packageCommand) Validate(args []string) error {
 }
 
 func (c *packageCommand) Run(_ io.Writer, args []string) error {
-	pkgName := args[0]
-	err := usecase.UsePackage(pkgName)
-	if errors.Is(err, idl.ErrUnknownPackageName) {
-		return errors.Errorf("unknown package name '%s'", args[0])
-	}
-	return err
+	return usecase.UsePackage(args[0])
 }
 
 type serviceCommand struct{}
@@ -96,24 +111,37 @@ func (c *serviceCommand) Run(_ io.Writer, args []string) error {
 	switch errors.Cause(err) {
 	case idl.ErrPackageUnselected:
 		return errors.New("package unselected. please execute 'package' command at the first")
-	case idl.ErrUnknownServiceName:
-		return errors.Errorf("unknown service name '%s'", args[0])
 	}
 	return err
 }
 
-type showCommand struct{}
+type showCommand struct {
+	format string
+}
 
 func (c *showCommand) Synopsis() string {
 	return "show package, service or RPC names"
 }
 
 func (c *showCommand) Help() string {
-	return "usage: show <package | service | message | rpc | header>"
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: show [options ...] <package | service | message | rpc | header | tree [<pattern>]>
+
+"tree" shows packages, services and RPCs as a tree. <pattern> is a glob pattern such that 'api.*'
+which filters them by fully-qualified names.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
 }
 
 func (c *showCommand) FlagSet() (*pflag.FlagSet, bool) {
-	return nil, false
+	fs := pflag.NewFlagSet("show", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.StringVar(&c.format, "format", "table", `output format. one of "table" or "json". with "json", tree is shown as nested objects.`)
+	return fs, true
 }
 
 func (c *showCommand) Validate(args []string) error {
@@ -124,6 +152,9 @@ func (c *showCommand) Validate(args []string) error {
 }
 
 func (c *showCommand) Run(w io.Writer, args []string) error {
+	if c.format != "table" && c.format != "json" {
+		return errors.Errorf("unknown format '%s'", c.format)
+	}
 	target := args[0]
 
 	var f func() (string, error)
@@ -139,14 +170,30 @@ func (c *showCommand) Run(w io.Writer, args []string) error {
 		f = usecase.FormatMethods
 	case "h", "header", "headers":
 		f = usecase.FormatHeaders
+	case "t", "tree":
+		var pattern string
+		if len(args) > 1 {
+			pattern = args[1]
+		}
+		f = func() (string, error) { return usecase.FormatTree(pattern) }
+		if c.format == "json" {
+			f = func() (string, error) { return usecase.FormatSchema(pattern) }
+		}
 	default:
 		return errors.Errorf("unknown target '%s'", target)
 	}
 
+	if c.format == "json" {
+		usecase.InjectPartially(usecase.Dependencies{ResourcePresenter: jsonpresenter.NewPresenter("  ")})
+		defer usecase.InjectPartially(usecase.Dependencies{ResourcePresenter: tablepresenter.NewPresenter()})
+	}
 	out, err := f()
 	if err != nil {
 		return errors.Wrap(err, "failed to format")
 	}
+	if c.format == "json" {
+		out += "\n"
+	}
 	if _, err := io.WriteString(w, out); err != nil {
 		return errors.Wrap(err, "failed to write formatted output to w")
 	}
@@ -155,14 +202,31 @@ func (c *showCommand) Run(w io.Writer, args []string) error {
 }
 
 type callCommand struct {
-	enrich, digManually bool
+	opts *options
+
+	enrich, digManually, edit bool
+	input, output             string
+	file                      string
+	template                  string
+	fillRandom                bool
+	stats                     bool
+
+	// fuzzySelect is used to select the RPC if no RPC names are passed.
+	fuzzySelect func(message string, items []*prompt.SelectItem) (int, error)
 }
 
 func (c *callCommand) FlagSet() (*pflag.FlagSet, bool) {
 	fs := pflag.NewFlagSet("call", pflag.ContinueOnError)
 	fs.Usage = func() {} // Disable help output when an error occurred.
-	fs.BoolVar(&c.enrich, "enrich", false, "enrich response output includes header, message, trailer and status")
+	fs.BoolVar(&c.enrich, "enrich", c.opts.enrich, "enrich response output includes header, message, trailer and status")
 	fs.BoolVar(&c.digManually, "dig-manually", false, "prompt asks whether to dig down if it encountered to a message field")
+	fs.StringVarP(&c.output, "output", "o", c.opts.output, `output format. one of "json", "ndjson", "yaml", "prototext", "table", "csv" or "curl". "curl" is a curl-like format.`)
+	fs.StringVar(&c.input, "input", c.opts.input, `input format of --file. one of "json", "yaml" or "prototext".`)
+	fs.StringVarP(&c.file, "file", "f", "", `read the request body from the file instead of the prompt. "-" means stdin. streaming RPCs accept newline-delimited JSON, a JSON array, a YAML sequence, or YAML documents or prototext messages separated by "---".`)
+	fs.BoolVarP(&c.edit, "edit", "e", false, "edit the request body with $EDITOR instead of the prompt. it is pre-populated with the last request of the RPC.")
+	fs.StringVarP(&c.template, "template", "t", "", "send the request body saved as the template. with --edit, the template is edited before sending.")
+	fs.BoolVar(&c.fillRandom, "fill-random", false, "fill all fields with random values based on types and field names instead of the prompt")
+	fs.BoolVar(&c.stats, "stats", c.opts.stats, "show the elapsed time, the number of sent and received messages and their size after the call")
 	return fs, true
 }
 
@@ -175,46 +239,253 @@ func (c *callCommand) Help() string {
 	fs, _ := c.FlagSet()
 	fs.SetOutput(&buf)
 	fs.PrintDefaults()
-	return fmt.Sprintf(`usage: call <method name>
+	return fmt.Sprintf(`usage: call [<method name>]
+
+If <method name> is omitted, RPCs of all services are listed and one of them can be selected by fuzzy search.
 
 Options:
 %s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
 }
 
 func (c *callCommand) Validate(args []string) error {
-	if len(args) < 1 {
-		return errArgumentRequired
-	}
 	return nil
 }
 
 func (c *callCommand) Run(w io.Writer, args []string) error {
+	rfi, err := newResponseFormatter(w, c.output, c.opts.json)
+	if err != nil {
+		return err
+	}
 	usecase.InjectPartially(
 		usecase.Dependencies{
-			ResponseFormatter: format.NewResponseFormatter(curl.NewResponseFormatter(w), c.enrich),
+			ResponseFormatter: format.NewResponseFormatter(rfi, c.enrich),
 		},
 	)
 
-	err := usecase.CallRPCInteractively(context.Background(), w, args[0], c.digManually)
+	if c.stats {
+		defer printCallStats(w, usecase.LastCallStats())
+	}
+
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	if c.file != "" && (c.edit || c.template != "") {
+		return errors.New("--file cannot be specified with --edit or --template")
+	}
+	if c.fillRandom && (c.file != "" || c.edit || c.template != "") {
+		return errors.New("--fill-random cannot be specified with --file, --edit or --template")
+	}
+	if len(args) == 0 {
+		rpcName, err := c.selectRPC()
+		if errors.Is(err, prompt.ErrAbort) || errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		args = []string{rpcName}
+	}
+	if c.fillRandom {
+		usecase.InjectPartially(usecase.Dependencies{Filler: fillproto.NewRandomFiller(nil)})
+		return usecase.CallRPC(ctx, w, args[0])
+	}
+	if c.file != "" {
+		return c.callWithFile(ctx, w, args[0])
+	}
+	if c.edit {
+		return c.callWithEditor(ctx, w, args[0])
+	}
+	if c.template != "" {
+		body, err := usecase.LoadRequestTemplate(args[0], c.template)
+		if err != nil {
+			return err
+		}
+		return callWithReader(ctx, w, args[0], strings.NewReader(body), "json")
+	}
+	err = usecase.CallRPCInteractively(ctx, w, args[0], c.digManually)
 	if errors.Is(err, io.EOF) {
 		return errors.New("inputting canceled")
 	}
 	return err
 }
 
+// selectRPC lets the user select one of RPCs of all services by fuzzy search.
+// It returns the fully-qualified name of the selected RPC.
+func (c *callCommand) selectRPC() (string, error) {
+	rpcs, err := usecase.ListAllRPCs()
+	if err != nil {
+		return "", err
+	}
+	if len(rpcs) == 0 {
+		return "", errors.New("no RPCs are loaded")
+	}
+	items := make([]*prompt.SelectItem, len(rpcs))
+	for i, rpc := range rpcs {
+		items[i] = &prompt.SelectItem{Name: rpc.FullyQualifiedName, Description: describeRPC(rpc)}
+	}
+	i, err := c.fuzzySelect("RPC", items)
+	if err != nil {
+		return "", err
+	}
+	return rpcs[i].FullyQualifiedName, nil
+}
+
+// describeRPC returns the comment, the request type and the response type of rpc for the preview of selectRPC.
+func describeRPC(rpc *grpc.RPC) string {
+	typeName := func(t *grpc.Type, stream bool) string {
+		if stream {
+			return "stream " + t.FullyQualifiedName
+		}
+		return t.FullyQualifiedName
+	}
+	var b strings.Builder
+	if rpc.Comment != "" {
+		fmt.Fprintf(&b, "%s\n\n", rpc.Comment)
+	}
+	fmt.Fprintf(&b, "request:\t%s\n", typeName(rpc.RequestType, rpc.IsClientStreaming))
+	fmt.Fprintf(&b, "response:\t%s", typeName(rpc.ResponseType, rpc.IsServerStreaming))
+	return b.String()
+}
+
+// callWithFile calls the RPC with the request body read from c.file instead of the prompt.
+func (c *callCommand) callWithFile(ctx context.Context, w io.Writer, rpcName string) error {
+	var in io.Reader = os.Stdin
+	if c.file != "-" {
+		f, err := os.Open(c.file)
+		if err != nil {
+			return errors.Wrap(err, "failed to open the request file")
+		}
+		defer f.Close()
+		in = f
+	}
+	return callWithReader(ctx, w, rpcName, in, c.input)
+}
+
+// callWithEditor calls the RPC with the request body edited by an editor.
+// The edited body is validated before sending any requests.
+func (c *callCommand) callWithEditor(ctx context.Context, w io.Writer, rpcName string) error {
+	var (
+		text string
+		err  error
+	)
+	if c.template != "" {
+		text, err = usecase.LoadRequestTemplate(rpcName, c.template)
+	} else {
+		text, err = usecase.FormatRequest(rpcName)
+	}
+	if err != nil {
+		return err
+	}
+	return editAndCall(ctx, w, rpcName, text)
+}
+
+// editAndCall calls the RPC with the request body which is text edited by an editor.
+// The edited body is validated before sending any requests.
+func editAndCall(ctx context.Context, w io.Writer, rpcName, text string) error {
+	edited, err := editText(text)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(edited) == "" {
+		return errors.New("inputting canceled")
+	}
+	if err := usecase.ValidateRequest(rpcName, strings.NewReader(edited)); err != nil {
+		return err
+	}
+	return callWithReader(ctx, w, rpcName, strings.NewReader(edited), "json")
+}
+
+// callWithReader calls the RPC with the request body read from in. input is the format of in, one of "json", "yaml" or "prototext".
+func callWithReader(ctx context.Context, w io.Writer, rpcName string, in io.Reader, input string) error {
+	filler, err := newSilentFiller(in, input)
+	if err != nil {
+		return err
+	}
+	usecase.InjectPartially(usecase.Dependencies{Filler: filler})
+	err = usecase.CallRPC(ctx, w, rpcName)
+	if errors.Is(err, fill.ErrCodecMismatch) {
+		return errors.Errorf("the request body must be formatted as %s", inputFormatNames[input])
+	}
+	return err
+}
+
+// inputFormatNames is the display names of input formats.
+var inputFormatNames = map[string]string{
+	"json":      "JSON",
+	"yaml":      "YAML",
+	"prototext": "the protobuf text format",
+}
+
+func newSilentFiller(in io.Reader, input string) (fill.Filler, error) {
+	switch input {
+	case "json":
+		return fill.NewSilentFiller(in), nil
+	case "yaml":
+		return fill.NewSilentYAMLFiller(in), nil
+	case "prototext":
+		return fill.NewSilentPrototextFiller(in), nil
+	default:
+		return nil, errors.Errorf("unknown input format '%s'", input)
+	}
+}
+
+// withInterrupt returns a new context which is canceled when the process received an interrupt signal such that Ctrl-C.
+// It allows users to abort only the in-flight RPC instead of the whole REPL.
+// The signal is handled until the returned cancel function is called.
+func withInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel()
+	}
+}
+
+func newResponseFormatter(w io.Writer, output string, opts format.JSONOptions) (format.ResponseFormatterInterface, error) {
+	switch output {
+	case "curl":
+		return curl.NewResponseFormatter(w, opts), nil
+	case "json":
+		return json.NewResponseFormatter(w, opts), nil
+	case "ndjson":
+		return ndjson.NewResponseFormatter(w, opts), nil
+	case "yaml":
+		return yaml.NewResponseFormatter(w, opts), nil
+	case "prototext":
+		return prototext.NewResponseFormatter(w), nil
+	case "table":
+		return table.NewResponseFormatter(w, table.Options{MaxWidth: table.DefaultMaxWidth}, opts), nil
+	case "csv":
+		return csv.NewResponseFormatter(w, nil, opts), nil
+	default:
+		return nil, errors.Errorf("unknown output format '%s'", output)
+	}
+}
+
 type headerCommand struct {
-	raw bool
+	raw    bool
+	append bool
+	scope  string
 }
 
 func (c *headerCommand) FlagSet() (*pflag.FlagSet, bool) {
 	fs := pflag.NewFlagSet("header", pflag.ContinueOnError)
 	fs.Usage = func() {} // Disable help output when an error occurred.
 	fs.BoolVarP(&c.raw, "raw", "r", false, "treat the value as a raw string")
+	fs.BoolVarP(&c.append, "append", "a", false, "append the values to the key instead of replacing")
+	fs.StringVarP(&c.scope, "scope", "s", "", "send the headers only with RPCs in the package, service or RPC (example: api.Example)")
 	return fs, true
 }
 
 func (c *headerCommand) Synopsis() string {
-	return "set/unset headers to each request. if header value is empty, the header is removed."
+	return "set/append/unset headers to each request. if header value is empty, the header is removed."
 }
 
 func (c *headerCommand) Help() string {
@@ -223,6 +494,21 @@ func (c *headerCommand) Help() string {
 	fs.SetOutput(&buf)
 	fs.PrintDefaults()
 	return fmt.Sprintf(`usage: header [options ...] <key>=<value>[, <key>=<value>...]
+       header <save <name> | use <name> | list>
+
+Values of the key are replaced with the comma-separated values. With --append, they are appended,
+so the key is sent as repeated metadata.
+
+With --scope, the headers are sent only with RPCs in the fully-qualified package, service or RPC name.
+They replace values of the same key in broader scopes.
+
+Values are expanded just before each RPC. ${NAME} is replaced with the variable or the environment variable,
+and $(command) is replaced with the output of the command. Use $$ to send $ as it is.
+
+Values of binary headers, whose keys end with "-bin", are base64 encoded bytes or @<file> which has the bytes.
+
+save saves all headers including scoped ones as the header set named name. use replaces all headers with the
+saved header set. list lists saved header sets. To remove a header named save, use or list, run "header save=".
 
 Options:
 %s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
@@ -232,36 +518,834 @@ func (c *headerCommand) Validate(args []string) error {
 	if len(args) < 1 {
 		return errArgumentRequired
 	}
+	switch args[0] {
+	case "save", "use":
+		if len(args) != 2 {
+			return errors.Errorf("usage: header %s <name>", args[0])
+		}
+	case "list":
+		if len(args) != 1 {
+			return errors.New("usage: header list")
+		}
+	}
 	return nil
 }
 
-func (c *headerCommand) Run(_ io.Writer, args []string) error {
-	headers := usecase.ListHeaders()
+func (c *headerCommand) Run(w io.Writer, args []string) error {
+	switch args[0] {
+	case "save":
+		return usecase.SaveHeaderSet(args[1])
+	case "use":
+		return usecase.UseHeaderSet(args[1])
+	case "list":
+		names, err := usecase.ListHeaderSets()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return errors.New("no header sets are saved")
+		}
+		_, err = io.WriteString(w, strings.Join(names, "\n")+"\n")
+		return err
+	}
+
 	for _, h := range args {
 		sp := strings.SplitN(h, "=", 2)
 
 		// Remove the key.
 		if len(sp) == 1 || sp[1] == "" {
-			headers.Remove(sp[0])
+			if c.scope != "" {
+				usecase.RemoveScopedHeader(c.scope, sp[0])
+			} else {
+				usecase.RemoveHeader(sp[0])
+			}
 			continue
 		}
 
-		if c.raw {
-			if err := headers.Add(sp[0], sp[1]); err != nil {
-				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], sp[1])
+		vals := []string{sp[1]}
+		if !c.raw {
+			vals = strings.Split(sp[1], ",")
+		}
+
+		var err error
+		switch {
+		case c.scope != "" && c.append:
+			err = usecase.AppendScopedHeader(c.scope, sp[0], vals...)
+		case c.scope != "":
+			err = usecase.SetScopedHeader(c.scope, sp[0], vals...)
+		case c.append:
+			err = usecase.AppendHeader(sp[0], vals...)
+		default:
+			err = usecase.SetHeader(sp[0], vals...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type profileCommand struct{}
+
+func (c *profileCommand) Synopsis() string {
+	return "list profiles or switch the current connection to a profile"
+}
+
+func (c *profileCommand) Help() string {
+	return `usage: profile [use <profile name>]
+
+With no arguments, profile lists all profiles. The current profile is marked with '*'.`
+}
+
+func (c *profileCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *profileCommand) Validate(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] != "use" {
+		return errors.Errorf("unknown subcommand '%s'", args[0])
+	}
+	if len(args) < 2 {
+		return errArgumentRequired
+	}
+	return nil
+}
+
+func (c *profileCommand) Run(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		names := usecase.ListProfiles()
+		if len(names) == 0 {
+			return errors.New("no profiles are defined")
+		}
+		current := usecase.GetCurrentProfile()
+		for _, name := range names {
+			mark := " "
+			if name == current {
+				mark = "*"
 			}
-			return nil
+			if _, err := fmt.Fprintf(w, "%s %s\n", mark, name); err != nil {
+				return errors.Wrap(err, "failed to write profiles")
+			}
+		}
+		return nil
+	}
+	return usecase.UseProfile(args[1])
+}
+
+type setCommand struct {
+	opts *options
+}
+
+func (c *setCommand) Synopsis() string {
+	return "set an option such that the timeout for each RPC call"
+}
+
+func (c *setCommand) Help() string {
+	return `usage: set <option> <value>
+
+Available options:
+  timeout    the timeout for each RPC call such that 5s or 500ms. 0 means no timeout.
+             for streaming RPCs, it is applied to the whole stream.
+  enrich     true or false. if true, call command shows header, trailer and status in addition to messages.
+  output     the output format of call command. one of "json", "ndjson", "yaml", "prototext", "table", "csv" or "curl".
+  input      the input format of call --file. one of "json", "yaml" or "prototext".
+  compact    true or false. if true, JSON output is formatted in a single line.
+  indent     the number of spaces for each indentation level of JSON output.
+  sort-keys  true or false. if true, keys of messages are ordered alphabetically instead of the schema order.
+  emit-defaults
+             true or false. if true, fields that have the default value are also shown.
+  proto-names
+             true or false. if true, original field names in the proto file are used instead of lowerCamelCase
+             JSON names. it is also applied to request bodies such that call --edit.
+  int64-as-number
+             true or false. if true, 64-bit integers are shown as JSON numbers instead of strings.
+  bytes-encoding
+             the encoding of bytes fields. one of "base64", "hex" or "utf8".
+             "utf8" shows bytes as a string, and invalid bytes are escaped as \xNN.
+  stats      true or false. if true, call command shows the elapsed time, the number of sent and received
+             messages and their size after each call.
+
+A name which starts with $ defines a variable such that "set $token abc123".
+Variables are expanded in header values and field inputs by $token or ${token}, and $$ means $ itself.
+$resp is a builtin variable which references the last response such that $resp.id or $resp.items.0.id.
+An empty value such that "set $token ''" removes the variable.`
+}
+
+func (c *setCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *setCommand) Validate(args []string) error {
+	if len(args) < 2 {
+		return errArgumentRequired
+	}
+	return nil
+}
+
+func (c *setCommand) Run(_ io.Writer, args []string) error {
+	if strings.HasPrefix(args[0], "$") {
+		return usecase.SetVariable(strings.TrimPrefix(args[0], "$"), args[1])
+	}
+
+	switch opt, val := args[0], args[1]; opt {
+	case "timeout":
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return errors.Wrapf(err, "invalid timeout '%s'", val)
+		}
+		if d < 0 {
+			return errors.Errorf("timeout must not be negative, but got '%s'", val)
+		}
+		usecase.SetTimeout(d)
+		return nil
+	case "enrich":
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.Errorf("enrich must be true or false, but got '%s'", val)
+		}
+		c.opts.enrich = b
+		return nil
+	case "output":
+		if _, err := newResponseFormatter(ioutil.Discard, val, c.opts.json); err != nil {
+			return err
+		}
+		c.opts.output = val
+		return nil
+	case "input":
+		if _, err := newSilentFiller(nil, val); err != nil {
+			return err
+		}
+		c.opts.input = val
+		return nil
+	case "stats":
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.Errorf("stats must be true or false, but got '%s'", val)
+		}
+		c.opts.stats = b
+		return nil
+	case "compact", "sort-keys", "emit-defaults", "proto-names", "int64-as-number":
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.Errorf("%s must be true or false, but got '%s'", opt, val)
+		}
+		switch opt {
+		case "compact":
+			c.opts.json.Compact = b
+		case "sort-keys":
+			c.opts.json.SortKeys = b
+		case "emit-defaults":
+			c.opts.json.EmitDefaults = b
+		case "proto-names":
+			c.opts.json.ProtoNames = b
+			usecase.SetProtoNames(b)
+		case "int64-as-number":
+			c.opts.json.Int64AsNumber = b
+		}
+		return nil
+	case "indent":
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return errors.Errorf("indent must be a non-negative integer, but got '%s'", val)
+		}
+		c.opts.json.Indent = n
+		return nil
+	case "bytes-encoding":
+		switch val {
+		case format.BytesBase64, format.BytesHex, format.BytesUTF8:
+		default:
+			return errors.Errorf(`bytes-encoding must be one of "base64", "hex" or "utf8", but got '%s'`, val)
+		}
+		c.opts.json.BytesEncoding = val
+		return nil
+	default:
+		return errors.Errorf("unknown option '%s'", opt)
+	}
+}
+
+type envCommand struct{}
+
+func (c *envCommand) Synopsis() string {
+	return "show variables defined by set command"
+}
+
+func (c *envCommand) Help() string {
+	return `usage: env`
+}
+
+func (c *envCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *envCommand) Validate(args []string) error {
+	return nil
+}
+
+func (c *envCommand) Run(w io.Writer, _ []string) error {
+	for _, v := range usecase.ListVariables() {
+		fmt.Fprintf(w, "$%s=%s\n", v.Name, v.Value)
+	}
+	return nil
+}
+
+type recallCommand struct {
+	opts *options
+
+	enrich bool
+	output string
+	stats  bool
+}
+
+func (c *recallCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("recall", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.BoolVar(&c.enrich, "enrich", c.opts.enrich, "enrich response output includes header, message, trailer and status")
+	fs.StringVarP(&c.output, "output", "o", c.opts.output, `output format. one of "json", "ndjson", "yaml", "prototext", "table", "csv" or "curl". "curl" is a curl-like format.`)
+	fs.BoolVar(&c.stats, "stats", c.opts.stats, "show the elapsed time, the number of sent and received messages and their size after the call")
+	return fs, true
+}
+
+func (c *recallCommand) Synopsis() string {
+	return "call the last called RPC again with the same request"
+}
+
+func (c *recallCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: recall [options ...] [<field>=<value> ...]
+
+Each <field>=<value> overrides the field of the last request. <field> is dot-separated field names
+such that user.name. Repeated fields, map fields and message fields accept JSON values.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *recallCommand) Validate(args []string) error {
+	for _, arg := range args {
+		if sp := strings.SplitN(arg, "=", 2); len(sp) != 2 || sp[0] == "" {
+			return errors.Errorf("'%s' must be formatted as <field>=<value>", arg)
+		}
+	}
+	return nil
+}
+
+func (c *recallCommand) Run(w io.Writer, args []string) error {
+	rfi, err := newResponseFormatter(w, c.output, c.opts.json)
+	if err != nil {
+		return err
+	}
+	usecase.InjectPartially(
+		usecase.Dependencies{
+			ResponseFormatter: format.NewResponseFormatter(rfi, c.enrich),
+		},
+	)
+
+	if c.stats {
+		defer printCallStats(w, usecase.LastCallStats())
+	}
+
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	return usecase.RecallRPC(ctx, w, func(req interface{}) error {
+		for _, arg := range args {
+			sp := strings.SplitN(arg, "=", 2)
+			if err := fillproto.SetField(req, sp[0], usecase.ExpandVariables(sp[1])); err != nil {
+				return errors.Wrapf(err, "failed to override field '%s'", sp[0])
+			}
+		}
+		return nil
+	})
+}
+
+// printCallStats prints the statistics of the last call if it is not prev. It is deferred before calling RPCs,
+// so that nothing is printed if the call failed before starting the RPC.
+func printCallStats(w io.Writer, prev *usecase.CallStats) {
+	if s := usecase.LastCallStats(); s != nil && s != prev {
+		fmt.Fprintln(w, s)
+	}
+}
+
+type statsCommand struct {
+	reset bool
+}
+
+func (c *statsCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("stats", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.BoolVar(&c.reset, "reset", false, "clear the statistics instead of showing them")
+	return fs, true
+}
+
+func (c *statsCommand) Synopsis() string {
+	return "show the statistics of calls in the session"
+}
+
+func (c *statsCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: stats [options ...]
+
+stats shows the number of calls, the number of errors and the mean latency for each called method.
+Errors are calls which failed or returned non-OK status codes.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *statsCommand) Validate(args []string) error {
+	return nil
+}
+
+func (c *statsCommand) Run(w io.Writer, _ []string) error {
+	if c.reset {
+		usecase.ClearStats()
+		return nil
+	}
+	stats := usecase.ListMethodStats()
+	if len(stats) == 0 {
+		return errors.New("no RPCs have been called yet")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"method", "calls", "errors", "mean latency"})
+	for _, s := range stats {
+		table.Append([]string{s.Method, strconv.Itoa(s.Calls), strconv.Itoa(s.Errors), s.Mean().Round(time.Microsecond).String()})
+	}
+	table.Render()
+	return nil
+}
+
+type templateCommand struct{}
+
+func (c *templateCommand) Synopsis() string {
+	return "save, list or delete request templates"
+}
+
+func (c *templateCommand) Help() string {
+	return `usage: template <save <name> | list <method name> | delete <method name> <name>>
+
+save saves the request of the last call as the template which belongs to the called method.
+Saved templates are sent by call --template <name> <method name>.`
+}
+
+func (c *templateCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *templateCommand) Validate(args []string) error {
+	if len(args) < 1 {
+		return errArgumentRequired
+	}
+	switch args[0] {
+	case "save", "list":
+		if len(args) < 2 {
+			return errArgumentRequired
+		}
+	case "delete":
+		if len(args) < 3 {
+			return errArgumentRequired
 		}
+	default:
+		return errors.Errorf("unknown subcommand '%s'", args[0])
+	}
+	return nil
+}
 
-		for _, v := range strings.Split(sp[1], ",") {
-			if err := headers.Add(sp[0], v); err != nil {
-				return errors.Wrapf(err, "failed to add a header '%s=%s'", sp[0], v)
+func (c *templateCommand) Run(w io.Writer, args []string) error {
+	switch args[0] {
+	case "save":
+		return usecase.SaveRequestTemplate(args[1])
+	case "list":
+		names, err := usecase.ListRequestTemplates(args[1])
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return errors.Errorf("no templates are saved for '%s'", args[1])
+		}
+		_, err = io.WriteString(w, strings.Join(names, "\n")+"\n")
+		return err
+	default:
+		return usecase.DeleteRequestTemplate(args[1], args[2])
+	}
+}
+
+type sessionCommand struct {
+	opts *options
+}
+
+// sessionOptions is options of call command which are saved in sessions.
+type sessionOptions struct {
+	Enrich bool               `json:"enrich"`
+	Input  string             `json:"input"`
+	Output string             `json:"output"`
+	JSON   format.JSONOptions `json:"json"`
+	Stats  bool               `json:"stats"`
+}
+
+func (c *sessionCommand) Synopsis() string {
+	return "save, load or list sessions"
+}
+
+func (c *sessionCommand) Help() string {
+	return `usage: session <save <name> | load <name> | list>
+
+save saves the selected package and service, headers, variables and options changed by set command
+as the session named name. load restores the saved session, headers and variables are replaced with saved ones.`
+}
+
+func (c *sessionCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *sessionCommand) Validate(args []string) error {
+	if len(args) < 1 {
+		return errArgumentRequired
+	}
+	switch args[0] {
+	case "save", "load":
+		if len(args) < 2 {
+			return errArgumentRequired
+		}
+	case "list":
+	default:
+		return errors.Errorf("unknown subcommand '%s'", args[0])
+	}
+	return nil
+}
+
+func (c *sessionCommand) Run(w io.Writer, args []string) error {
+	switch args[0] {
+	case "save":
+		return usecase.SaveSession(args[1], &sessionOptions{
+			Enrich: c.opts.enrich,
+			Input:  c.opts.input,
+			Output: c.opts.output,
+			JSON:   c.opts.json,
+			Stats:  c.opts.stats,
+		})
+	case "load":
+		opts := sessionOptions{
+			Enrich: c.opts.enrich,
+			Input:  c.opts.input,
+			Output: c.opts.output,
+			JSON:   c.opts.json,
+			Stats:  c.opts.stats,
+		}
+		if err := usecase.LoadSession(args[1], &opts); err != nil {
+			return err
+		}
+		c.opts.enrich, c.opts.input, c.opts.output, c.opts.json = opts.Enrich, opts.Input, opts.Output, opts.JSON
+		c.opts.stats = opts.Stats
+		usecase.SetProtoNames(opts.JSON.ProtoNames)
+		return nil
+	default:
+		names, err := usecase.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return errors.New("no sessions are saved")
+		}
+		_, err = io.WriteString(w, strings.Join(names, "\n")+"\n")
+		return err
+	}
+}
+
+type historyCommand struct {
+	opts *options
+	// history returns the command history in ascending order.
+	history func() []string
+
+	limit int
+	edit  bool
+}
+
+func (c *historyCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("history", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.IntVarP(&c.limit, "limit", "n", 0, "show only the newest n entries (0 means all entries)")
+	fs.BoolVarP(&c.edit, "edit", "e", false, "edit the request body of the entry with $EDITOR before sending it")
+	return fs, true
+}
+
+func (c *historyCommand) Synopsis() string {
+	return "show the command history or the request history"
+}
+
+func (c *historyCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: history [options ...] [<query> | requests <method name> [<n>]]
+
+history shows commands in the history which contain <query>. Duplicated commands are shown only once.
+In the prompt, Ctrl-R searches the history for the command which contains the input.
+
+history requests shows request bodies sent to the method. If <n> is specified, the n-th request body
+is sent again.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *historyCommand) Validate(args []string) error {
+	if c.limit < 0 {
+		return errors.Errorf("limit must not be negative, but got %d", c.limit)
+	}
+	if len(args) == 0 || args[0] != "requests" {
+		if c.edit {
+			return errors.New("--edit is available only for request history")
+		}
+		return nil
+	}
+	switch len(args) {
+	case 1:
+		return errArgumentRequired
+	case 2:
+		if c.edit {
+			return errors.New("--edit requires the entry number")
+		}
+		return nil
+	case 3:
+		if n, err := strconv.Atoi(args[2]); err != nil || n < 1 {
+			return errors.Errorf("the entry number must be a positive integer, but got '%s'", args[2])
+		}
+		return nil
+	default:
+		return errors.New("too many arguments")
+	}
+}
+
+func (c *historyCommand) Run(w io.Writer, args []string) error {
+	if len(args) != 0 && args[0] == "requests" {
+		if len(args) == 3 {
+			n, _ := strconv.Atoi(args[2])
+			return c.resend(w, args[1], n)
+		}
+		h, err := usecase.ListRequestHistory(args[1])
+		if err != nil {
+			return err
+		}
+		if len(h) == 0 {
+			return errors.Errorf("no requests have been sent to '%s'", args[1])
+		}
+		for i := range h {
+			var buf bytes.Buffer
+			if err := gojson.Compact(&buf, []byte(h[i])); err == nil {
+				h[i] = buf.String()
 			}
 		}
+		return c.print(w, h)
+	}
+
+	h := c.history()
+	return c.print(w, history.Filter(history.Tidy(h, len(h)), strings.Join(args, " ")))
+}
+
+// print prints entries of h with their numbers. If c.limit is positive, only the newest c.limit entries are printed,
+// but their numbers are kept.
+func (c *historyCommand) print(w io.Writer, h []string) error {
+	offset := 0
+	if c.limit > 0 && len(h) > c.limit {
+		offset = len(h) - c.limit
+	}
+	for i := offset; i < len(h); i++ {
+		if _, err := fmt.Fprintf(w, "%4d  %s\n", i+1, h[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resend calls the RPC again with the n-th request body of the request history.
+func (c *historyCommand) resend(w io.Writer, rpcName string, n int) error {
+	body, err := usecase.LoadRequestHistory(rpcName, n)
+	if err != nil {
+		return err
+	}
+	rfi, err := newResponseFormatter(w, c.opts.output, c.opts.json)
+	if err != nil {
+		return err
+	}
+	usecase.InjectPartially(
+		usecase.Dependencies{
+			ResponseFormatter: format.NewResponseFormatter(rfi, c.opts.enrich),
+		},
+	)
+
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	if c.edit {
+		return editAndCall(ctx, w, rpcName, body)
+	}
+	return callWithReader(ctx, w, rpcName, strings.NewReader(body), "json")
+}
+
+type healthCommand struct {
+	watch bool
+}
+
+func (c *healthCommand) FlagSet() (*pflag.FlagSet, bool) {
+	fs := pflag.NewFlagSet("health", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.BoolVarP(&c.watch, "watch", "w", false, "watch changes of the serving status until interrupted")
+	return fs, true
+}
+
+func (c *healthCommand) Synopsis() string {
+	return "check the serving status of the server or services"
+}
+
+func (c *healthCommand) Help() string {
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: health [options ...] [<fully-qualified service name> ...]
+
+health calls grpc.health.v1.Health/Check (or Watch with --watch) for each service.
+If no services are passed, the overall health of the server is checked.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+}
+
+func (c *healthCommand) Validate([]string) error { return nil }
+
+func (c *healthCommand) Run(w io.Writer, args []string) error {
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+	if c.watch {
+		return usecase.WatchHealth(ctx, w, args)
+	}
+	return usecase.CheckHealth(ctx, w, args)
+}
+
+type channelzCommand struct{}
+
+func (c *channelzCommand) Synopsis() string {
+	return "show channels, servers or sockets of the server by channelz"
+}
+
+func (c *channelzCommand) Help() string {
+	return `usage: channelz <channels | servers | sockets <server id>>
+
+channelz shows the summary of the server by grpc.channelz.v1.Channelz. The server must register the channelz service.`
+}
+
+func (c *channelzCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *channelzCommand) Validate(args []string) error {
+	if len(args) < 1 {
+		return errArgumentRequired
+	}
+	switch args[0] {
+	case "channels", "servers":
+	case "sockets":
+		if len(args) < 2 {
+			return errArgumentRequired
+		}
+		if _, err := strconv.ParseInt(args[1], 10, 64); err != nil {
+			return errors.Errorf("invalid server ID '%s'", args[1])
+		}
+	default:
+		return errors.Errorf("unknown target '%s'", args[0])
+	}
+	return nil
+}
+
+func (c *channelzCommand) Run(w io.Writer, args []string) error {
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+
+	var (
+		out string
+		err error
+	)
+	switch args[0] {
+	case "channels":
+		out, err = usecase.FormatChannelzChannels(ctx)
+	case "servers":
+		out, err = usecase.FormatChannelzServers(ctx)
+	default:
+		id, _ := strconv.ParseInt(args[1], 10, 64) // Validated by Validate.
+		out, err = usecase.FormatChannelzSockets(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, out); err != nil {
+		return errors.Wrap(err, "failed to write formatted output to w")
+	}
+	return nil
+}
+
+type undoCommand struct{}
+
+func (c *undoCommand) Synopsis() string {
+	return "undo the last change of headers, variables or the selection"
+}
+
+func (c *undoCommand) Help() string {
+	return `usage: undo
+
+undo restores the state before the last command which changed the selected package and service, headers,
+variables or the timeout. Up to 100 changes can be undone. Switching profiles can't be undone, and clears the history.`
+}
+
+func (c *undoCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *undoCommand) Validate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("undo takes no arguments")
 	}
 	return nil
 }
 
+func (c *undoCommand) Run(io.Writer, []string) error {
+	return usecase.Undo()
+}
+
+type redoCommand struct{}
+
+func (c *redoCommand) Synopsis() string {
+	return "redo the change undone by the last undo"
+}
+
+func (c *redoCommand) Help() string {
+	return `usage: redo
+
+redo restores the change undone by the last undo. Changes undone can't be redone after another change.`
+}
+
+func (c *redoCommand) FlagSet() (*pflag.FlagSet, bool) {
+	return nil, false
+}
+
+func (c *redoCommand) Validate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("redo takes no arguments")
+	}
+	return nil
+}
+
+func (c *redoCommand) Run(io.Writer, []string) error {
+	return usecase.Redo()
+}
+
 type exitCommand struct{}
 
 func (c *exitCommand) Synopsis() string {
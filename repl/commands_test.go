@@ -1,6 +1,14 @@
 package repl
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/format"
+)
 
 func TestValidate(t *testing.T) {
 	type testCase struct {
@@ -46,7 +54,7 @@ func TestValidate(t *testing.T) {
 			cmd: &callCommand{},
 			testCases: []testCase{
 				{args: []string{"kumiko"}},
-				{args: []string{}, hasErr: true},
+				{args: []string{}},
 			},
 		},
 		"header": cmdTestCase{
@@ -56,6 +64,98 @@ func TestValidate(t *testing.T) {
 				{args: []string{}, hasErr: true},
 			},
 		},
+		"profile": cmdTestCase{
+			cmd: &profileCommand{},
+			testCases: []testCase{
+				{args: []string{}},
+				{args: []string{"use", "kumiko"}},
+				{args: []string{"use"}, hasErr: true},
+				{args: []string{"kumiko"}, hasErr: true},
+			},
+		},
+		"set": cmdTestCase{
+			cmd: &setCommand{},
+			testCases: []testCase{
+				{args: []string{"timeout", "5s"}},
+				{args: []string{"enrich", "true"}},
+				{args: []string{"$token", "abc123"}},
+				{args: []string{"input", "yaml"}},
+				{args: []string{"timeout"}, hasErr: true},
+				{args: []string{"$token"}, hasErr: true},
+				{args: []string{}, hasErr: true},
+			},
+		},
+		"recall": cmdTestCase{
+			cmd: &recallCommand{},
+			testCases: []testCase{
+				{args: []string{}},
+				{args: []string{"name=kumiko", "profile.age=16"}},
+				{args: []string{"name="}},
+				{args: []string{"name"}, hasErr: true},
+				{args: []string{"=kumiko"}, hasErr: true},
+			},
+		},
+		"template": cmdTestCase{
+			cmd: &templateCommand{},
+			testCases: []testCase{
+				{args: []string{"save", "kumiko"}},
+				{args: []string{"list", "Unary"}},
+				{args: []string{"delete", "Unary", "kumiko"}},
+				{args: []string{"delete", "Unary"}, hasErr: true},
+				{args: []string{"save"}, hasErr: true},
+				{args: []string{"kumiko"}, hasErr: true},
+				{args: []string{}, hasErr: true},
+			},
+		},
+		"session": cmdTestCase{
+			cmd: &sessionCommand{},
+			testCases: []testCase{
+				{args: []string{"save", "kumiko"}},
+				{args: []string{"load", "kumiko"}},
+				{args: []string{"list"}},
+				{args: []string{"load"}, hasErr: true},
+				{args: []string{"kumiko"}, hasErr: true},
+				{args: []string{}, hasErr: true},
+			},
+		},
+		"history": cmdTestCase{
+			cmd: &historyCommand{},
+			testCases: []testCase{
+				{args: []string{}},
+				{args: []string{"call"}},
+				{args: []string{"requests", "Unary"}},
+				{args: []string{"requests", "Unary", "2"}},
+				{args: []string{"requests"}, hasErr: true},
+				{args: []string{"requests", "Unary", "0"}, hasErr: true},
+				{args: []string{"requests", "Unary", "kumiko"}, hasErr: true},
+				{args: []string{"requests", "Unary", "2", "3"}, hasErr: true},
+			},
+		},
+		"env": cmdTestCase{
+			cmd: &envCommand{},
+			testCases: []testCase{
+				{args: []string{}},
+			},
+		},
+		"health": cmdTestCase{
+			cmd: &healthCommand{},
+			testCases: []testCase{
+				{args: []string{}},
+				{args: []string{"api.Example", "api.Foo"}},
+			},
+		},
+		"channelz": cmdTestCase{
+			cmd: &channelzCommand{},
+			testCases: []testCase{
+				{args: []string{"channels"}},
+				{args: []string{"servers"}},
+				{args: []string{"sockets", "2"}},
+				{args: []string{"sockets"}, hasErr: true},
+				{args: []string{"sockets", "kumiko"}, hasErr: true},
+				{args: []string{"kumiko"}, hasErr: true},
+				{args: []string{}, hasErr: true},
+			},
+		},
 		"exit": cmdTestCase{
 			cmd: &exitCommand{},
 			testCases: []testCase{
@@ -80,3 +180,93 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestSetCommand(t *testing.T) {
+	cases := map[string]struct {
+		args     []string
+		expected options
+		hasErr   bool
+	}{
+		"enrich":                 {args: []string{"enrich", "true"}, expected: options{enrich: true, output: "curl"}},
+		"output":                 {args: []string{"output", "json"}, expected: options{output: "json"}},
+		"invalid enrich value":   {args: []string{"enrich", "kumiko"}, expected: options{output: "curl"}, hasErr: true},
+		"unknown output":         {args: []string{"output", "kumiko"}, expected: options{output: "curl"}, hasErr: true},
+		"unknown option":         {args: []string{"kumiko", "true"}, expected: options{output: "curl"}, hasErr: true},
+		"compact":                {args: []string{"compact", "true"}, expected: options{output: "curl", json: format.JSONOptions{Compact: true}}},
+		"indent":                 {args: []string{"indent", "4"}, expected: options{output: "curl", json: format.JSONOptions{Indent: 4}}},
+		"negative indent":        {args: []string{"indent", "-1"}, expected: options{output: "curl"}, hasErr: true},
+		"sort-keys":              {args: []string{"sort-keys", "true"}, expected: options{output: "curl", json: format.JSONOptions{SortKeys: true}}},
+		"emit-defaults":          {args: []string{"emit-defaults", "true"}, expected: options{output: "curl", json: format.JSONOptions{EmitDefaults: true}}},
+		"proto-names":            {args: []string{"proto-names", "true"}, expected: options{output: "curl", json: format.JSONOptions{ProtoNames: true}}},
+		"int64-as-number":        {args: []string{"int64-as-number", "true"}, expected: options{output: "curl", json: format.JSONOptions{Int64AsNumber: true}}},
+		"bytes-encoding":         {args: []string{"bytes-encoding", "hex"}, expected: options{output: "curl", json: format.JSONOptions{BytesEncoding: "hex"}}},
+		"unknown bytes-encoding": {args: []string{"bytes-encoding", "kumiko"}, expected: options{output: "curl"}, hasErr: true},
+		"invalid compact":        {args: []string{"compact", "kumiko"}, expected: options{output: "curl"}, hasErr: true},
+		"stats":                  {args: []string{"stats", "true"}, expected: options{output: "curl", stats: true}},
+		"invalid stats":          {args: []string{"stats", "kumiko"}, expected: options{output: "curl"}, hasErr: true},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			opts := &options{output: "curl"}
+			cmd := &setCommand{opts: opts}
+			err := cmd.Run(nil, c.args)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("should return an error, but got nil")
+				}
+			} else if err != nil {
+				t.Fatalf("should not return an error, but got '%s'", err)
+			}
+			if *opts != c.expected {
+				t.Errorf("expected options %+v, but got %+v", c.expected, *opts)
+			}
+		})
+	}
+}
+
+func TestHistoryCommand(t *testing.T) {
+	h := []string{"call Unary", "show service", "call --edit Unary", "call Unary", "header foo=bar"}
+	cases := map[string]struct {
+		args     []string
+		limit    int
+		expected string
+	}{
+		"all":               {expected: "   1  show service\n   2  call --edit Unary\n   3  call Unary\n   4  header foo=bar\n"},
+		"query":             {args: []string{"call"}, expected: "   1  call --edit Unary\n   2  call Unary\n"},
+		"limit":             {limit: 1, expected: "   4  header foo=bar\n"},
+		"query with spaces": {args: []string{"call", "Unary"}, expected: "   1  call Unary\n"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := &historyCommand{history: func() []string { return h }, limit: c.limit}
+			if err := cmd.Run(&buf, c.args); err != nil {
+				t.Fatalf("Run must not return an error, but got '%s'", err)
+			}
+			if actual := buf.String(); actual != c.expected {
+				t.Errorf("expected:\n%s\nbut got:\n%s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWithInterrupt(t *testing.T) {
+	ctx, cancel := withInterrupt(context.Background())
+	defer cancel()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("os.FindProcess must not return an error, but got '%s'", err)
+	}
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("sending an interrupt signal is not supported: %s", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context must be canceled by the interrupt signal")
+	}
+}
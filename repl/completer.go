@@ -84,6 +84,7 @@ func newCompleter(cmds map[string]commander) *completer {
 						prompt.NewSuggestion("message", "show loaded messsage names"),
 						prompt.NewSuggestion("rpc", "show RPC names belong to the current selected service"),
 						prompt.NewSuggestion("header", "show headers which will be added to each request"),
+						prompt.NewSuggestion("tree", "show packages, services and RPCs as a tree"),
 					}
 				}
 				return s
@@ -109,6 +110,54 @@ func newCompleter(cmds map[string]commander) *completer {
 				}
 				return s
 			},
+			"profile": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{prompt.NewSuggestion("use", "switch the current connection to a profile")}
+				case 2:
+					if args[0] != "use" {
+						return nil
+					}
+					for _, name := range usecase.ListProfiles() {
+						s = append(s, prompt.NewSuggestion(name, ""))
+					}
+				}
+				return s
+			},
+			"set": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{
+						prompt.NewSuggestion("timeout", "the timeout for each RPC call"),
+						prompt.NewSuggestion("enrich", "show header, trailer and status in addition to messages"),
+						prompt.NewSuggestion("output", "the output format of call command"),
+						prompt.NewSuggestion("input", "the input format of call --file"),
+						prompt.NewSuggestion("compact", "format JSON output in a single line"),
+						prompt.NewSuggestion("indent", "the number of spaces for each indentation level of JSON output"),
+						prompt.NewSuggestion("sort-keys", "order keys of messages alphabetically"),
+						prompt.NewSuggestion("emit-defaults", "show fields that have the default value"),
+						prompt.NewSuggestion("proto-names", "use original field names instead of JSON names"),
+						prompt.NewSuggestion("int64-as-number", "show 64-bit integers as JSON numbers"),
+						prompt.NewSuggestion("bytes-encoding", "the encoding of bytes fields"),
+						prompt.NewSuggestion("stats", "show the statistics of each call"),
+					}
+					for _, v := range usecase.ListVariables() {
+						s = append(s, prompt.NewSuggestion("$"+v.Name, v.Value))
+					}
+				case 2:
+					switch args[0] {
+					case "enrich", "compact", "sort-keys", "emit-defaults", "proto-names", "int64-as-number", "stats":
+						s = []*prompt.Suggest{prompt.NewSuggestion("true", ""), prompt.NewSuggestion("false", "")}
+					case "bytes-encoding":
+						s = []*prompt.Suggest{prompt.NewSuggestion("base64", ""), prompt.NewSuggestion("hex", ""), prompt.NewSuggestion("utf8", "")}
+					case "output":
+						s = []*prompt.Suggest{prompt.NewSuggestion("curl", ""), prompt.NewSuggestion("json", ""), prompt.NewSuggestion("ndjson", ""), prompt.NewSuggestion("yaml", ""), prompt.NewSuggestion("prototext", ""), prompt.NewSuggestion("table", ""), prompt.NewSuggestion("csv", "")}
+					case "input":
+						s = []*prompt.Suggest{prompt.NewSuggestion("json", ""), prompt.NewSuggestion("yaml", ""), prompt.NewSuggestion("prototext", "")}
+					}
+				}
+				return s
+			},
 			"call": func(args []string) (s []*prompt.Suggest) {
 				if len(args) == 1 {
 					rpcs, err := usecase.ListRPCs("")
@@ -121,6 +170,117 @@ func newCompleter(cmds map[string]commander) *completer {
 				}
 				return s
 			},
+			"template": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{
+						prompt.NewSuggestion("save", "save the request of the last call as a template"),
+						prompt.NewSuggestion("list", "list templates of a method"),
+						prompt.NewSuggestion("delete", "delete a template of a method"),
+					}
+				case 2:
+					if args[0] == "save" {
+						return nil
+					}
+					rpcs, err := usecase.ListRPCs("")
+					if err != nil {
+						return nil
+					}
+					for _, rpc := range rpcs {
+						s = append(s, prompt.NewSuggestion(rpc.Name, ""))
+					}
+				case 3:
+					if args[0] != "delete" {
+						return nil
+					}
+					names, err := usecase.ListRequestTemplates(args[1])
+					if err != nil {
+						return nil
+					}
+					for _, name := range names {
+						s = append(s, prompt.NewSuggestion(name, ""))
+					}
+				}
+				return s
+			},
+			"history": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{prompt.NewSuggestion("requests", "show request bodies sent to a method")}
+				case 2:
+					if args[0] != "requests" {
+						return nil
+					}
+					rpcs, err := usecase.ListRPCs("")
+					if err != nil {
+						return nil
+					}
+					for _, rpc := range rpcs {
+						s = append(s, prompt.NewSuggestion(rpc.Name, ""))
+					}
+				}
+				return s
+			},
+			"health": func(args []string) (s []*prompt.Suggest) {
+				for _, svc := range usecase.ListServices() {
+					s = append(s, prompt.NewSuggestion(svc, ""))
+				}
+				return s
+			},
+			"header": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{
+						prompt.NewSuggestion("save", "save all headers as a header set"),
+						prompt.NewSuggestion("use", "replace all headers with a saved header set"),
+						prompt.NewSuggestion("list", "list saved header sets"),
+					}
+				case 2:
+					if args[0] != "use" {
+						return nil
+					}
+					names, err := usecase.ListHeaderSets()
+					if err != nil {
+						return nil
+					}
+					for _, name := range names {
+						s = append(s, prompt.NewSuggestion(name, ""))
+					}
+				}
+				return s
+			},
+			"session": func(args []string) (s []*prompt.Suggest) {
+				switch len(args) {
+				case 1:
+					s = []*prompt.Suggest{
+						prompt.NewSuggestion("save", "save the current session"),
+						prompt.NewSuggestion("load", "restore a saved session"),
+						prompt.NewSuggestion("list", "list saved sessions"),
+					}
+				case 2:
+					if args[0] != "load" {
+						return nil
+					}
+					names, err := usecase.ListSessions()
+					if err != nil {
+						return nil
+					}
+					for _, name := range names {
+						s = append(s, prompt.NewSuggestion(name, ""))
+					}
+				}
+				return s
+			},
+			"channelz": func(args []string) (s []*prompt.Suggest) {
+				if len(args) == 1 {
+					s = []*prompt.Suggest{
+						prompt.NewSuggestion("channels", "show top channels the server has"),
+						prompt.NewSuggestion("servers", "show servers and their listen sockets"),
+						prompt.NewSuggestion("sockets", "show sockets accepted by the server"),
+					}
+				}
+				return s
+			},
 			"desc": func(args []string) (s []*prompt.Suggest) {
 				if len(args) != 1 {
 					return nil
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/idl/proto"
 	"github.com/ktr0731/evans/usecase"
 )
@@ -22,7 +23,7 @@ func (d *dummyDocument) TextBeforeCursor() string {
 }
 
 func TestCompleter(t *testing.T) {
-	cmpl := newCompleter(commands)
+	cmpl := newCompleter(newCommands(format.DefaultJSONOptions))
 	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"test.proto"})
 	if err != nil {
 		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
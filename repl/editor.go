@@ -0,0 +1,48 @@
+package repl
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ktr0731/evans/config"
+	"github.com/pkg/errors"
+)
+
+// editText opens text with an editor and returns the edited text. The editor is the same as config.EditFile.
+var editText = func(text string) (string, error) {
+	f, err := ioutil.TempFile("", "evans-*.json")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create a temp file")
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	f.Close()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write the text to the temp file")
+	}
+
+	if err := config.EditFile(f.Name()); err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the edited text")
+	}
+	return string(b), nil
+}
+
+// ToggleEditFlag toggles --edit flag of call command in the input line.
+// It is used as a key binding of the REPL prompt. Lines other than call command are returned as it is.
+func ToggleEditFlag(line string) string {
+	args := strings.Fields(line)
+	if len(args) == 0 || args[0] != "call" {
+		return line
+	}
+	for i, arg := range args {
+		if arg == "--edit" || arg == "-e" {
+			return strings.Join(append(args[:i:i], args[i+1:]...), " ")
+		}
+	}
+	return strings.Join(append([]string{"call", "--edit"}, args[1:]...), " ")
+}
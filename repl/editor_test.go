@@ -0,0 +1,24 @@
+package repl
+
+import "testing"
+
+func TestToggleEditFlag(t *testing.T) {
+	cases := map[string]struct {
+		in, expected string
+	}{
+		"add":              {in: "call Unary", expected: "call --edit Unary"},
+		"add with flags":   {in: "call --enrich Unary", expected: "call --edit --enrich Unary"},
+		"remove":           {in: "call --enrich --edit Unary", expected: "call --enrich Unary"},
+		"remove shorthand": {in: "call -e Unary", expected: "call Unary"},
+		"other command":    {in: "show service", expected: "show service"},
+		"empty":            {in: "", expected: ""},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if actual := ToggleEditFlag(c.in); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
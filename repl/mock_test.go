@@ -10,11 +10,15 @@ import (
 )
 
 var (
-	lockSpecMockFormatDescriptor sync.RWMutex
-	lockSpecMockRPC              sync.RWMutex
-	lockSpecMockRPCs             sync.RWMutex
-	lockSpecMockResolveSymbol    sync.RWMutex
-	lockSpecMockServiceNames     sync.RWMutex
+	lockSpecMockExportDescriptorSet sync.RWMutex
+	lockSpecMockExportFiles         sync.RWMutex
+	lockSpecMockFormatDescriptor    sync.RWMutex
+	lockSpecMockMessageNames        sync.RWMutex
+	lockSpecMockMessageType         sync.RWMutex
+	lockSpecMockRPC                 sync.RWMutex
+	lockSpecMockRPCs                sync.RWMutex
+	lockSpecMockResolveSymbol       sync.RWMutex
+	lockSpecMockServiceNames        sync.RWMutex
 )
 
 // Ensure, that SpecMock does implement idl.Spec.
@@ -27,9 +31,21 @@ var _ idl.Spec = &SpecMock{}
 //
 //         // make and configure a mocked idl.Spec
 //         mockedSpec := &SpecMock{
+//             ExportDescriptorSetFunc: func(svcNames []string) ([]byte, error) {
+// 	               panic("mock out the ExportDescriptorSet method")
+//             },
+//             ExportFilesFunc: func(svcNames []string) (map[string]string, error) {
+// 	               panic("mock out the ExportFiles method")
+//             },
 //             FormatDescriptorFunc: func(v interface{}) (string, error) {
 // 	               panic("mock out the FormatDescriptor method")
 //             },
+//             MessageNamesFunc: func() []string {
+// 	               panic("mock out the MessageNames method")
+//             },
+//             MessageTypeFunc: func(fqmn string) (*grpc.Type, error) {
+// 	               panic("mock out the MessageType method")
+//             },
 //             RPCFunc: func(svcName string, rpcName string) (*grpc.RPC, error) {
 // 	               panic("mock out the RPC method")
 //             },
@@ -49,9 +65,21 @@ var _ idl.Spec = &SpecMock{}
 //
 //     }
 type SpecMock struct {
+	// ExportDescriptorSetFunc mocks the ExportDescriptorSet method.
+	ExportDescriptorSetFunc func(svcNames []string) ([]byte, error)
+
+	// ExportFilesFunc mocks the ExportFiles method.
+	ExportFilesFunc func(svcNames []string) (map[string]string, error)
+
 	// FormatDescriptorFunc mocks the FormatDescriptor method.
 	FormatDescriptorFunc func(v interface{}) (string, error)
 
+	// MessageNamesFunc mocks the MessageNames method.
+	MessageNamesFunc func() []string
+
+	// MessageTypeFunc mocks the MessageType method.
+	MessageTypeFunc func(fqmn string) (*grpc.Type, error)
+
 	// RPCFunc mocks the RPC method.
 	RPCFunc func(svcName string, rpcName string) (*grpc.RPC, error)
 
@@ -66,11 +94,29 @@ type SpecMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// ExportDescriptorSet holds details about calls to the ExportDescriptorSet method.
+		ExportDescriptorSet []struct {
+			// SvcNames is the svcNames argument value.
+			SvcNames []string
+		}
+		// ExportFiles holds details about calls to the ExportFiles method.
+		ExportFiles []struct {
+			// SvcNames is the svcNames argument value.
+			SvcNames []string
+		}
 		// FormatDescriptor holds details about calls to the FormatDescriptor method.
 		FormatDescriptor []struct {
 			// V is the v argument value.
 			V interface{}
 		}
+		// MessageNames holds details about calls to the MessageNames method.
+		MessageNames []struct {
+		}
+		// MessageType holds details about calls to the MessageType method.
+		MessageType []struct {
+			// Fqmn is the fqmn argument value.
+			Fqmn string
+		}
 		// RPC holds details about calls to the RPC method.
 		RPC []struct {
 			// SvcName is the svcName argument value.
@@ -94,6 +140,68 @@ type SpecMock struct {
 	}
 }
 
+// ExportDescriptorSet calls ExportDescriptorSetFunc.
+func (mock *SpecMock) ExportDescriptorSet(svcNames []string) ([]byte, error) {
+	if mock.ExportDescriptorSetFunc == nil {
+		panic("SpecMock.ExportDescriptorSetFunc: method is nil but Spec.ExportDescriptorSet was just called")
+	}
+	callInfo := struct {
+		SvcNames []string
+	}{
+		SvcNames: svcNames,
+	}
+	lockSpecMockExportDescriptorSet.Lock()
+	mock.calls.ExportDescriptorSet = append(mock.calls.ExportDescriptorSet, callInfo)
+	lockSpecMockExportDescriptorSet.Unlock()
+	return mock.ExportDescriptorSetFunc(svcNames)
+}
+
+// ExportDescriptorSetCalls gets all the calls that were made to ExportDescriptorSet.
+// Check the length with:
+//     len(mockedSpec.ExportDescriptorSetCalls())
+func (mock *SpecMock) ExportDescriptorSetCalls() []struct {
+	SvcNames []string
+} {
+	var calls []struct {
+		SvcNames []string
+	}
+	lockSpecMockExportDescriptorSet.RLock()
+	calls = mock.calls.ExportDescriptorSet
+	lockSpecMockExportDescriptorSet.RUnlock()
+	return calls
+}
+
+// ExportFiles calls ExportFilesFunc.
+func (mock *SpecMock) ExportFiles(svcNames []string) (map[string]string, error) {
+	if mock.ExportFilesFunc == nil {
+		panic("SpecMock.ExportFilesFunc: method is nil but Spec.ExportFiles was just called")
+	}
+	callInfo := struct {
+		SvcNames []string
+	}{
+		SvcNames: svcNames,
+	}
+	lockSpecMockExportFiles.Lock()
+	mock.calls.ExportFiles = append(mock.calls.ExportFiles, callInfo)
+	lockSpecMockExportFiles.Unlock()
+	return mock.ExportFilesFunc(svcNames)
+}
+
+// ExportFilesCalls gets all the calls that were made to ExportFiles.
+// Check the length with:
+//     len(mockedSpec.ExportFilesCalls())
+func (mock *SpecMock) ExportFilesCalls() []struct {
+	SvcNames []string
+} {
+	var calls []struct {
+		SvcNames []string
+	}
+	lockSpecMockExportFiles.RLock()
+	calls = mock.calls.ExportFiles
+	lockSpecMockExportFiles.RUnlock()
+	return calls
+}
+
 // FormatDescriptor calls FormatDescriptorFunc.
 func (mock *SpecMock) FormatDescriptor(v interface{}) (string, error) {
 	if mock.FormatDescriptorFunc == nil {
@@ -125,6 +233,63 @@ func (mock *SpecMock) FormatDescriptorCalls() []struct {
 	return calls
 }
 
+// MessageNames calls MessageNamesFunc.
+func (mock *SpecMock) MessageNames() []string {
+	if mock.MessageNamesFunc == nil {
+		panic("SpecMock.MessageNamesFunc: method is nil but Spec.MessageNames was just called")
+	}
+	callInfo := struct {
+	}{}
+	lockSpecMockMessageNames.Lock()
+	mock.calls.MessageNames = append(mock.calls.MessageNames, callInfo)
+	lockSpecMockMessageNames.Unlock()
+	return mock.MessageNamesFunc()
+}
+
+// MessageNamesCalls gets all the calls that were made to MessageNames.
+// Check the length with:
+//     len(mockedSpec.MessageNamesCalls())
+func (mock *SpecMock) MessageNamesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	lockSpecMockMessageNames.RLock()
+	calls = mock.calls.MessageNames
+	lockSpecMockMessageNames.RUnlock()
+	return calls
+}
+
+// MessageType calls MessageTypeFunc.
+func (mock *SpecMock) MessageType(fqmn string) (*grpc.Type, error) {
+	if mock.MessageTypeFunc == nil {
+		panic("SpecMock.MessageTypeFunc: method is nil but Spec.MessageType was just called")
+	}
+	callInfo := struct {
+		Fqmn string
+	}{
+		Fqmn: fqmn,
+	}
+	lockSpecMockMessageType.Lock()
+	mock.calls.MessageType = append(mock.calls.MessageType, callInfo)
+	lockSpecMockMessageType.Unlock()
+	return mock.MessageTypeFunc(fqmn)
+}
+
+// MessageTypeCalls gets all the calls that were made to MessageType.
+// Check the length with:
+//     len(mockedSpec.MessageTypeCalls())
+func (mock *SpecMock) MessageTypeCalls() []struct {
+	Fqmn string
+} {
+	var calls []struct {
+		Fqmn string
+	}
+	lockSpecMockMessageType.RLock()
+	calls = mock.calls.MessageType
+	lockSpecMockMessageType.RUnlock()
+	return calls
+}
+
 // RPC calls RPCFunc.
 func (mock *SpecMock) RPC(svcName string, rpcName string) (*grpc.RPC, error) {
 	if mock.RPCFunc == nil {
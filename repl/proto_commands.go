@@ -1,31 +1,54 @@
 package repl
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
+	idlproto "github.com/ktr0731/evans/idl/proto"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
-type descCommand struct{}
+type descCommand struct {
+	full   bool
+	format string
+}
 
 func (c *descCommand) Synopsis() string {
-	return "describe the structure of selected message"
+	return "describe the structure of a message, enum, service or method"
 }
 
 func (c *descCommand) Help() string {
-	return "usage: desc <message name>"
+	var buf bytes.Buffer
+	fs, _ := c.FlagSet()
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	return fmt.Sprintf(`usage: desc [options ...] <symbol>
+
+The symbol is a message name in the current package or a fully-qualified name of
+a message, enum, service or method. Except for messages without --full, desc shows
+the full descriptor. With --format json, desc always shows the full descriptor as
+the JSON representation of the descriptor proto.
+
+Options:
+%s`, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
 }
 
 func (c *descCommand) FlagSet() (*pflag.FlagSet, bool) {
-	return nil, false
+	fs := pflag.NewFlagSet("desc", pflag.ContinueOnError)
+	fs.Usage = func() {} // Disable help output when an error occurred.
+	fs.BoolVarP(&c.full, "full", "f", false, "show the full descriptor includes field numbers, labels, oneofs and nested types")
+	fs.StringVar(&c.format, "format", "table", `output format. one of "table" or "json".`)
+	return fs, true
 }
 
 func (c *descCommand) Validate(args []string) error {
@@ -36,22 +59,65 @@ func (c *descCommand) Validate(args []string) error {
 }
 
 func (c *descCommand) Run(w io.Writer, args []string) error {
+	if c.format != "table" && c.format != "json" {
+		return errors.Errorf("unknown format '%s'", c.format)
+	}
 	td, err := usecase.GetTypeDescriptor(args[0])
 	if err != nil {
 		return errors.Wrap(err, "failed to get the type descriptor")
 	}
 
+	if c.format == "json" {
+		out, err := usecase.FormatDescriptorJSON(td.(desc.Descriptor).GetFullyQualifiedName())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out+"\n")
+		return err
+	}
+
+	md, ok := td.(*desc.MessageDescriptor)
+	if c.full || !ok {
+		out, err := usecase.FormatDescriptor(td.(desc.Descriptor).GetFullyQualifiedName())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out)
+		return err
+	}
+
 	table := tablewriter.NewWriter(w)
-	table.SetHeader([]string{"field", "type", "repeated"})
-	fields := td.(*desc.MessageDescriptor).GetFields()
+	header := []string{"field", "type", "repeated", "options", "comment"}
+	fields := md.GetFields()
 	rows := make([][]string, len(fields))
 	for i, field := range fields {
 		rows[i] = []string{
 			field.GetName(),
 			presentTypeName(field),
 			strconv.FormatBool(field.IsRepeated() && !field.IsMap()),
+			strings.Join(idlproto.CustomOptions(field), "\n"),
+			idlproto.Comment(field),
+		}
+	}
+	// The options and comment columns are shown only if one or more fields have them.
+	for col := len(header) - 1; col >= 3; col-- {
+		var nonEmpty bool
+		for _, row := range rows {
+			nonEmpty = nonEmpty || row[col] != ""
 		}
+		if nonEmpty {
+			continue
+		}
+		header = append(header[:col], header[col+1:]...)
+		for i, row := range rows {
+			rows[i] = append(row[:col], row[col+1:]...)
+		}
+	}
+	if len(header) > 3 && header[3] == "options" {
+		// Each option is shown in its own line instead of being wrapped.
+		table.SetAutoWrapText(false)
 	}
+	table.SetHeader(header)
 
 	sort.Slice(rows, func(i, j int) bool {
 		return rows[i][0] < rows[j][0]
@@ -11,11 +11,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/ktr0731/evans/config"
 	"github.com/ktr0731/evans/cui"
+	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/prompt"
+	"github.com/ktr0731/evans/report"
 	"github.com/ktr0731/evans/usecase"
 	"github.com/ktr0731/go-shellstring"
 	"github.com/mitchellh/go-homedir"
@@ -24,31 +27,100 @@ import (
 
 // REPL represents a REPL mechanism.
 type REPL struct {
-	cfg       *config.REPL
-	serverCfg *config.Server
-	prompt    prompt.Prompt
-	ui        cui.UI
+	cfg        *config.REPL
+	serverCfg  *config.Server
+	requestCfg *config.Request
+	prompt     prompt.Prompt
+	ui         cui.UI
 
 	cmds    map[string]commander
 	aliases map[string]string
+
+	// prefixTmpl is the template of the prompt prefix parsed from repl.promptFormat.
+	prefixTmpl *template.Template
+	// prefix and prefixColor is the prompt prefix. They are updated when the selected package or service is changed.
+	prefix      string
+	prefixColor prompt.Color
+
+	// reloadSpec is called before each input if it is not nil.
+	reloadSpec func() (bool, error)
+	// recordScript is called with the result of each call command run by RunScript if it is not nil.
+	recordScript func(*report.Result)
+}
+
+// Option is an optional setting for New.
+type Option func(*REPL)
+
+// WithSpecReloader makes REPL call f before each input to reload the spec.
+// f reports whether the spec is changed.
+func WithSpecReloader(f func() (bool, error)) Option {
+	return func(r *REPL) {
+		r.reloadSpec = f
+	}
+}
+
+// WithScriptRecorder makes RunScript call f with the result of each call command, and the failed command if any.
+func WithScriptRecorder(f func(*report.Result)) Option {
+	return func(r *REPL) {
+		r.recordScript = f
+	}
 }
 
-var commands = map[string]commander{
-	"call":    &callCommand{},
-	"service": &serviceCommand{},
-	"header":  &headerCommand{},
-	"package": &packageCommand{},
-	"show":    &showCommand{},
-	"exit":    &exitCommand{},
+// options is shared between commands. It is modified by set command and used as default values of other commands.
+type options struct {
+	enrich bool
+	input  string
+	output string
+	json   format.JSONOptions
+	// stats is true if call commands show the statistics of each call.
+	stats bool
+}
+
+// newCommands returns all REPL commands. Commands returned from each call don't share any options.
+// jsonOpts is the initial options for formatting responses as JSON.
+func newCommands(jsonOpts format.JSONOptions) map[string]commander {
+	opts := &options{input: "json", output: "curl", json: jsonOpts}
+	return map[string]commander{
+		"call":     &callCommand{opts: opts},
+		"service":  &serviceCommand{},
+		"header":   &headerCommand{},
+		"package":  &packageCommand{},
+		"show":     &showCommand{},
+		"profile":  &profileCommand{},
+		"env":      &envCommand{},
+		"recall":   &recallCommand{opts: opts},
+		"set":      &setCommand{opts: opts},
+		"template": &templateCommand{},
+		"session":  &sessionCommand{opts: opts},
+		"history":  &historyCommand{opts: opts},
+		"health":   &healthCommand{},
+		"channelz": &channelzCommand{},
+		"stats":    &statsCommand{},
+		"undo":     &undoCommand{},
+		"redo":     &redoCommand{},
+		"exit":     &exitCommand{},
 
-	// Depends to Protocol Buffers.
-	"desc": &descCommand{},
+		// Depends to Protocol Buffers.
+		"desc": &descCommand{},
+	}
 }
 
 // New instantiates a new REPL instance. New always calls p.SetPrefix for display the server addr.
 // New may return an error if some of passed arguments are invalid.
-func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string) (*REPL, error) {
-	cmds := commands
+func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string, opts ...Option) (*REPL, error) {
+	cmds := newCommands(format.JSONOptions{
+		Compact:       cfg.Output.Compact,
+		Indent:        cfg.Output.Indent,
+		SortKeys:      cfg.Output.SortKeys,
+		EmitDefaults:  cfg.Output.EmitDefaults,
+		ProtoNames:    cfg.Output.ProtoNames,
+		Int64AsNumber: cfg.Output.Int64AsNumber,
+		BytesEncoding: cfg.Output.BytesEncoding,
+	})
+	// history command shows the history of the prompt.
+	cmds["history"].(*historyCommand).history = p.GetCommandHistory
+	// call command selects the RPC by the prompt if no RPC names are passed.
+	cmds["call"].(*callCommand).fuzzySelect = p.FuzzySelect
 	// Each value must be a key of cmds.
 	aliases := map[string]string{
 		"quit": "exit",
@@ -71,14 +143,33 @@ func New(cfg *config.Config, p prompt.Prompt, ui cui.UI, pkgName, svcName string
 		return nil, errors.Wrap(result, "failed to instantiate a new REPL")
 	}
 	r := &REPL{
-		cfg:       cfg.REPL,
-		serverCfg: cfg.Server,
-		prompt:    p,
-		ui:        ui,
-		cmds:      cmds,
-		aliases:   aliases,
+		cfg:        cfg.REPL,
+		serverCfg:  cfg.Server,
+		requestCfg: cfg.Request,
+		prompt:     p,
+		ui:         ui,
+		cmds:       cmds,
+		aliases:    aliases,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
 
+	format := cfg.REPL.PromptFormat
+	if format == "" || format == legacyPromptFormat {
+		format = config.DefaultPromptFormat
+	}
+	// color is replaced on each rendering by makePrefix.
+	tmpl, err := template.New("prompt").Funcs(template.FuncMap{"color": func(string) string { return "" }}).Parse(format)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid repl.promptFormat config")
+	}
+	r.prefixTmpl = tmpl
+	r.prefix, r.prefixColor = r.makePrefix()
+	updatePrefix := func(string) { r.prefix, r.prefixColor = r.makePrefix() }
+	usecase.OnPackageChange(updatePrefix)
+	usecase.OnServiceChange(updatePrefix)
+
 	return r, nil
 }
 
@@ -90,7 +181,16 @@ func (r *REPL) Run(ctx context.Context) error {
 	}
 
 	for {
-		r.prompt.SetPrefix(r.makePrefix())
+		if r.reloadSpec != nil {
+			if changed, err := r.reloadSpec(); err != nil {
+				r.ui.Error(fmt.Sprintf("failed to reload the spec: %s\n", err))
+			} else if changed {
+				r.ui.Info("the spec is reloaded\n")
+			}
+		}
+
+		r.prompt.SetPrefix(r.prefix)
+		r.prompt.SetPrefixColor(r.prefixColor)
 
 		in, err := r.prompt.Input()
 		if errors.Is(err, io.EOF) {
@@ -153,11 +253,14 @@ func (r *REPL) runCommand(cmdName string, args []string) error {
 	if err := cmd.Validate(args); err != nil {
 		return err
 	}
-	if err := cmd.Run(r.ui.Writer(), args); err != nil {
-		return err
+	switch cmd.(type) {
+	case *undoCommand, *redoCommand:
+		return cmd.Run(r.ui.Writer(), args)
 	}
-
-	return nil
+	// Changes of the state by other commands are recorded to be undone.
+	return usecase.RecordChange(func() error {
+		return cmd.Run(r.ui.Writer(), args)
+	})
 }
 
 func (r *REPL) printSplash(p string) {
@@ -188,13 +291,91 @@ func (r *REPL) printSplash(p string) {
 	}
 }
 
-func (r *REPL) makePrefix() string {
-	p := fmt.Sprintf("%s:%s> ", r.serverCfg.Host, r.serverCfg.Port)
-	dsn := usecase.GetDomainSourceName()
-	if dsn != "" {
-		p = fmt.Sprintf("%s@%s", dsn, p)
+// legacyPromptFormat is the default value of repl.promptFormat before it became a template.
+// Config files written by old versions have it, so it is replaced with the default template.
+const legacyPromptFormat = "{package}.{service}@{addr}:{port}"
+
+// promptData is the data of the prompt prefix template.
+type promptData struct {
+	Host    string
+	Port    string
+	Package string
+	Service string
+	// DSN is the selected package and service joined by a dot such that "api.Example".
+	DSN     string
+	Profile string
+	// Transport is one of "grpc", "grpc-web", "twirp" or "http".
+	Transport string
+	TLS       bool
+	// Connection describes the transport, whether TLS is enabled and the profile such that "grpc+tls, prod".
+	Connection string
+	// ShowConnection is the value of repl.promptConnection config.
+	ShowConnection bool
+}
+
+// makePrefix renders the prompt prefix and its color. If rendering failed, it falls back to the server address.
+func (r *REPL) makePrefix() (string, prompt.Color) {
+	data := &promptData{
+		Host:           r.serverCfg.Host,
+		Port:           r.serverCfg.Port,
+		Package:        usecase.GetSelectedPackage(),
+		Service:        usecase.GetSelectedService(),
+		DSN:            usecase.GetDomainSourceName(),
+		Profile:        usecase.GetCurrentProfile(),
+		Transport:      r.transport(),
+		TLS:            r.serverCfg.TLS,
+		Connection:     r.connection(),
+		ShowConnection: r.cfg.PromptConnection,
+	}
+	// The prompt colors the whole prefix with one color, so color in the template selects it instead of
+	// writing escape sequences. The last one wins.
+	color := prompt.ColorBlue
+	tmpl := template.Must(r.prefixTmpl.Clone()).Funcs(template.FuncMap{
+		"color": func(name string) (string, error) {
+			c, ok := prompt.ColorByName(name)
+			if !ok {
+				return "", errors.Errorf("unknown color '%s'", name)
+			}
+			color = c
+			return "", nil
+		},
+	})
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		r.ui.Error(fmt.Sprintf("failed to render repl.promptFormat: %s\n", err))
+		return fmt.Sprintf("%s:%s> ", r.serverCfg.Host, r.serverCfg.Port), prompt.ColorBlue
+	}
+	return buf.String(), color
+}
+
+// transport returns the name of the transport used to send requests.
+func (r *REPL) transport() string {
+	if r.requestCfg == nil {
+		return "grpc"
+	}
+	switch {
+	case r.requestCfg.Web:
+		return "grpc-web"
+	case r.requestCfg.Twirp:
+		return "twirp"
+	case r.requestCfg.Transcoding:
+		return "http"
+	default:
+		return "grpc"
+	}
+}
+
+// connection describes the transport, whether TLS is enabled and the current profile such that "grpc-web+tls, prod".
+// Both of the server and request config are replaced in place when the profile is switched.
+func (r *REPL) connection() string {
+	c := r.transport()
+	if r.serverCfg.TLS {
+		c += "+tls"
+	}
+	if profile := usecase.GetCurrentProfile(); profile != "" {
+		c = fmt.Sprintf("%s, %s", c, profile)
 	}
-	return p
+	return c
 }
 
 func (r *REPL) helpText() string {
@@ -18,7 +18,7 @@ import (
 )
 
 func TestREPL_helpText(t *testing.T) {
-	dummyCfg := &config.Config{REPL: &config.REPL{}, Server: &config.Server{Host: "127.0.0.1", Port: "50051"}}
+	dummyCfg := &config.Config{REPL: &config.REPL{}, Server: &config.Server{Host: "127.0.0.1", Port: "50051"}, Output: &config.Output{}}
 
 	usecase.Clear()
 
@@ -34,7 +34,7 @@ func TestREPL_helpText(t *testing.T) {
 }
 
 func TestREPL_printSplash(t *testing.T) {
-	dummyCfg := &config.Config{REPL: &config.REPL{}, Server: &config.Server{Host: "127.0.0.1", Port: "50051"}}
+	dummyCfg := &config.Config{REPL: &config.REPL{}, Server: &config.Server{Host: "127.0.0.1", Port: "50051"}, Output: &config.Output{}}
 
 	usecase.Clear()
 
@@ -86,9 +86,13 @@ func TestREPL_printSplash(t *testing.T) {
 
 func TestREPL_makePrefix(t *testing.T) {
 	cases := map[string]struct {
-		pkgName string
-		svcName string
-		RPCsErr error
+		pkgName    string
+		svcName    string
+		RPCsErr    error
+		connection bool
+		format     string
+		// red is true if the prefix must be red instead of the default color.
+		red bool
 
 		hasErr   bool
 		expected string
@@ -100,13 +104,34 @@ func TestREPL_makePrefix(t *testing.T) {
 			svcName:  "Example",
 			expected: "api.Example@127.0.0.1:50051> ",
 		},
+		"connection shown": {
+			pkgName:    "api",
+			connection: true,
+			expected:   "api@127.0.0.1:50051 (grpc-web+tls)> ",
+		},
+		"legacy format": {
+			pkgName:  "api",
+			format:   "{package}.{service}@{addr}:{port}",
+			expected: "api@127.0.0.1:50051> ",
+		},
+		"custom format": {
+			pkgName:    "api",
+			svcName:    "Example",
+			connection: true,
+			format:     `{{color "red"}}{{.Service}} {{.Transport}}{{if .TLS}}s{{end}}://{{.Host}}$ `,
+			expected:   "Example grpc-webs://127.0.0.1$ ",
+			red:        true,
+		},
+		"invalid format": {format: "{{.Host", hasErr: true},
 	}
 
 	for name, c := range cases {
 		c := c
 		dummyCfg := &config.Config{
-			REPL:   &config.REPL{},
-			Server: &config.Server{Host: "127.0.0.1", Port: "50051"},
+			REPL:    &config.REPL{PromptConnection: c.connection, PromptFormat: c.format},
+			Server:  &config.Server{Host: "127.0.0.1", Port: "50051", TLS: c.connection},
+			Request: &config.Request{Web: c.connection},
+			Output:  &config.Output{},
 		}
 		dummySpec := &SpecMock{
 			ServiceNamesFunc: func() []string {
@@ -131,23 +156,77 @@ func TestREPL_makePrefix(t *testing.T) {
 				t.Fatalf("New must not return an erorr, but got '%s'", err)
 			}
 
-			actual := r.makePrefix()
+			actual, color := r.makePrefix()
 			if c.expected != actual {
 				t.Errorf("expected prefix '%s', but got '%s'", c.expected, actual)
 			}
+			expectedColor := prompt.ColorBlue
+			if c.red {
+				expectedColor, _ = prompt.ColorByName("red")
+			}
+			if expectedColor != color {
+				t.Errorf("expected color %d, but got %d", expectedColor, color)
+			}
 		})
 	}
 }
 
+func TestREPL_prefixUpdated(t *testing.T) {
+	defer usecase.Clear()
+	dummyCfg := &config.Config{
+		REPL:   &config.REPL{},
+		Server: &config.Server{Host: "127.0.0.1", Port: "50051"},
+		Output: &config.Output{},
+	}
+	dummySpec := &SpecMock{
+		ServiceNamesFunc: func() []string {
+			return []string{"api.Example"}
+		},
+		RPCsFunc: func(svcName string) ([]*grpc.RPC, error) {
+			return nil, nil
+		},
+	}
+	usecase.Inject(usecase.Dependencies{Spec: dummySpec})
+	r, err := New(dummyCfg, prompt.New(), cui.New(cui.Writer(ioutil.Discard)), "", "")
+	if err != nil {
+		t.Fatalf("New must not return an erorr, but got '%s'", err)
+	}
+
+	if err := r.runCommand("package", []string{"api"}); err != nil {
+		t.Fatalf("package command must not return an error, but got '%s'", err)
+	}
+	if expected := "api@127.0.0.1:50051> "; r.prefix != expected {
+		t.Errorf("expected prefix '%s', but got '%s'", expected, r.prefix)
+	}
+	if err := r.runCommand("undo", nil); err != nil {
+		t.Fatalf("undo command must not return an error, but got '%s'", err)
+	}
+	if expected := "127.0.0.1:50051> "; r.prefix != expected {
+		t.Errorf("expected prefix '%s', but got '%s'", expected, r.prefix)
+	}
+}
+
 var expectedHelpText = `
 Available commands:
-  call       call a RPC
-  desc       describe the structure of selected message
-  exit       exit current REPL
-  header     set/unset headers to each request. if header value is empty, the header is removed.
-  package    set a package as the currently selected package
-  service    set the service as the current selected service
-  show       show package, service or RPC names
+  call        call a RPC
+  channelz    show channels, servers or sockets of the server by channelz
+  desc        describe the structure of a message, enum, service or method
+  env         show variables defined by set command
+  exit        exit current REPL
+  header      set/append/unset headers to each request. if header value is empty, the header is removed.
+  health      check the serving status of the server or services
+  history     show the command history or the request history
+  package     set a package as the currently selected package
+  profile     list profiles or switch the current connection to a profile
+  recall      call the last called RPC again with the same request
+  redo        redo the change undone by the last undo
+  service     set the service as the current selected service
+  session     save, load or list sessions
+  set         set an option such that the timeout for each RPC call
+  show        show package, service or RPC names
+  stats       show the statistics of calls in the session
+  template    save, list or delete request templates
+  undo        undo the last change of headers, variables or the selection
 
 Show more details:
   <command> --help`
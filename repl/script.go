@@ -0,0 +1,112 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/report"
+	"github.com/ktr0731/evans/usecase"
+	"github.com/ktr0731/go-shellstring"
+	"github.com/pkg/errors"
+)
+
+// scriptCommand is a REPL command read from a script.
+type scriptCommand struct {
+	// line is the line number of the command in the script. It starts from 1.
+	line int
+	args []string
+	// body is the request body for call command. It is empty if the command doesn't have the body.
+	body string
+}
+
+// parseScript parses a script that consists of REPL commands. Each line is a command.
+// Lines starting with '#' and empty lines are ignored.
+// The request body of call command is JSON values which follow the command until an empty line.
+// Streaming RPCs can receive two or more JSON values.
+//
+//   package api
+//   service Example
+//   call Unary
+//   {"name": "oumae"}
+//
+func parseScript(in io.Reader) ([]*scriptCommand, error) {
+	var (
+		cmds   []*scriptCommand
+		inBody bool
+	)
+	s := bufio.NewScanner(in)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			inBody = false
+			continue
+		}
+		if inBody {
+			cmd := cmds[len(cmds)-1]
+			cmd.body += line + "\n"
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args, err := shellstring.Parse(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: failed to parse command", n)
+		}
+		cmds = append(cmds, &scriptCommand{line: n, args: args})
+		inBody = args[0] == "call"
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read the script")
+	}
+	return cmds, nil
+}
+
+// scriptFiller is a fill.InteractiveFiller that fills values from the request body of the script.
+type scriptFiller struct {
+	filler fill.Filler
+}
+
+func (f *scriptFiller) Fill(v interface{}, _ bool) error {
+	return f.filler.Fill(v)
+}
+
+// RunScript executes REPL commands read from in non-interactively. See parseScript for the script format.
+// RunScript stops the execution when a command returns an error. See WithScriptRecorder to record results of commands.
+func (r *REPL) RunScript(in io.Reader) error {
+	cmds, err := parseScript(in)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		// Never read from the prompt even if the body is empty.
+		usecase.InjectPartially(usecase.Dependencies{
+			InteractiveFiller: &scriptFiller{filler: fill.NewSilentFiller(strings.NewReader(cmd.body))},
+		})
+
+		start := time.Now()
+		err := r.runCommand(cmd.args[0], cmd.args[1:])
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "line %d: command %s", cmd.line, cmd.args[0])
+		}
+		if r.recordScript != nil && (cmd.args[0] == "call" || err != nil) {
+			r.recordScript(&report.Result{
+				Name:     fmt.Sprintf("line %d: %s", cmd.line, strings.Join(cmd.args, " ")),
+				Duration: time.Since(start),
+				Err:      err,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
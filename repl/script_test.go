@@ -0,0 +1,34 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `# comment
+package api
+service Example
+
+call --enrich Unary
+{"name": "oumae"}
+{"name": "kumiko"}
+
+header foo=bar
+`
+	cmds, err := parseScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("parseScript must not return an error, but got '%s'", err)
+	}
+	expected := []*scriptCommand{
+		{line: 2, args: []string{"package", "api"}},
+		{line: 3, args: []string{"service", "Example"}},
+		{line: 5, args: []string{"call", "--enrich", "Unary"}, body: "{\"name\": \"oumae\"}\n{\"name\": \"kumiko\"}\n"},
+		{line: 9, args: []string{"header", "foo=bar"}},
+	}
+	if diff := cmp.Diff(expected, cmds, cmp.AllowUnexported(scriptCommand{})); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
@@ -0,0 +1,192 @@
+// Package report writes results of test runs such that test suites and scripts to files which CI systems can read.
+// JUnit XML and JSON are supported.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the result of a test case such that a case of a test suite or an RPC call of a script.
+type Result struct {
+	Name string
+	// Method is the fully-qualified method name which the case calls. It may be empty.
+	Method   string
+	Duration time.Duration
+	// Err is the reason why the case failed. It is nil if the case passed.
+	Err error
+}
+
+// Report is a set of results.
+type Report struct {
+	// Name is the name of the test run such that the name of the suite file.
+	Name    string
+	Results []*Result
+}
+
+func (r *Report) failures() int {
+	var n int
+	for _, res := range r.Results {
+		if res.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) duration() time.Duration {
+	var d time.Duration
+	for _, res := range r.Results {
+		d += res.Duration
+	}
+	return d
+}
+
+// ValidateFileName returns an error if the format of the report file cannot be determined by its extension.
+// ".xml" means JUnit XML, and ".json" means JSON.
+func ValidateFileName(fname string) error {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".xml", ".json":
+		return nil
+	default:
+		return errors.Errorf("unknown report format of '%s'. the extension must be .xml (JUnit XML) or .json", fname)
+	}
+}
+
+// WriteFile writes r to fname. The format is determined by the extension of fname. See ValidateFileName.
+func WriteFile(fname string, r *Report) error {
+	if err := ValidateFileName(fname); err != nil {
+		return err
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the report file")
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(fname)) == ".xml" {
+		return WriteJUnit(f, r)
+	}
+	return WriteJSON(f, r)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes r as JUnit XML. The class name of each case is its method name, or the name of r
+// if the method name is empty.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suite := junitTestSuite{
+		Name:     r.Name,
+		Tests:    len(r.Results),
+		Failures: r.failures(),
+		Time:     seconds(r.duration()),
+	}
+	for _, res := range r.Results {
+		c := junitTestCase{Name: res.Name, ClassName: res.Method, Time: seconds(res.Duration)}
+		if c.ClassName == "" {
+			c.ClassName = r.Name
+		}
+		if res.Err != nil {
+			c.Failure = &junitFailure{Message: res.Err.Error(), Type: "failure", Text: res.Err.Error()}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suites := junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Time: suite.Time, Suites: []junitTestSuite{suite}}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "failed to write the report")
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return errors.Wrap(err, "failed to encode the report as JUnit XML")
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return errors.Wrap(err, "failed to write the report")
+	}
+	return nil
+}
+
+type jsonReport struct {
+	Name   string `json:"name"`
+	Tests  int    `json:"tests"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+	// Time is the total duration in seconds.
+	Time    float64      `json:"time"`
+	Results []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	Name   string  `json:"name"`
+	Method string  `json:"method,omitempty"`
+	Passed bool    `json:"passed"`
+	Time   float64 `json:"time"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// WriteJSON writes r as JSON. Durations are represented in seconds.
+func WriteJSON(w io.Writer, r *Report) error {
+	failures := r.failures()
+	jr := jsonReport{
+		Name:    r.Name,
+		Tests:   len(r.Results),
+		Passed:  len(r.Results) - failures,
+		Failed:  failures,
+		Time:    r.duration().Seconds(),
+		Results: make([]jsonResult, 0, len(r.Results)),
+	}
+	for _, res := range r.Results {
+		v := jsonResult{Name: res.Name, Method: res.Method, Passed: res.Err == nil, Time: res.Duration.Seconds()}
+		if res.Err != nil {
+			v.Error = res.Err.Error()
+		}
+		jr.Results = append(jr.Results, v)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(jr); err != nil {
+		return errors.Wrap(err, "failed to encode the report as JSON")
+	}
+	return nil
+}
+
+// seconds formats d in seconds with millisecond precision as JUnit XML does.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
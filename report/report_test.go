@@ -0,0 +1,143 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/report"
+	"github.com/pkg/errors"
+)
+
+var rep = &report.Report{
+	Name: "suite",
+	Results: []*report.Result{
+		{Name: "say hello", Method: "api.Example.Unary", Duration: 1500 * time.Millisecond},
+		{Name: "line 5: call Unary", Duration: 500 * time.Millisecond, Err: errors.New(`expected "OK", but got <NotFound>`)},
+	},
+}
+
+func TestValidateFileName(t *testing.T) {
+	cases := map[string]bool{
+		"junit.xml":         false,
+		"out/results.JSON":  false,
+		"results.yaml":      true,
+		"results":           true,
+		"results.json.orig": true,
+	}
+	for fname, hasErr := range cases {
+		err := report.ValidateFileName(fname)
+		if hasErr && err == nil {
+			t.Errorf("%s: ValidateFileName must return an error", fname)
+		}
+		if !hasErr && err != nil {
+			t.Errorf("%s: ValidateFileName must not return an error, but got '%s'", fname, err)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf, rep); err != nil {
+		t.Fatalf("WriteJUnit must not return an error, but got '%s'", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("the report must start with the XML header, but got:\n%s", buf.String())
+	}
+
+	type testCase struct {
+		Name      string `xml:"name,attr"`
+		ClassName string `xml:"classname,attr"`
+		Time      string `xml:"time,attr"`
+		Failure   *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"failure"`
+	}
+	var actual struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string     `xml:"name,attr"`
+			Time  string     `xml:"time,attr"`
+			Cases []testCase `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("the report must be a valid XML, but got '%s'", err)
+	}
+	if actual.Tests != 2 || actual.Failures != 1 {
+		t.Errorf("expected 2 tests and 1 failure, but got %d tests and %d failures", actual.Tests, actual.Failures)
+	}
+	if n := len(actual.Suites); n != 1 {
+		t.Fatalf("expected 1 testsuite, but got %d", n)
+	}
+	if s := actual.Suites[0]; s.Name != "suite" || s.Time != "2.000" {
+		t.Errorf("unexpected testsuite: name = %s, time = %s", s.Name, s.Time)
+	}
+	cases := actual.Suites[0].Cases
+	if n := len(cases); n != 2 {
+		t.Fatalf("expected 2 testcases, but got %d", n)
+	}
+	if c := cases[0]; c.Name != "say hello" || c.ClassName != "api.Example.Unary" || c.Time != "1.500" || c.Failure != nil {
+		t.Errorf("unexpected passed testcase: %+v", c)
+	}
+	c := cases[1]
+	if c.ClassName != "suite" {
+		t.Errorf("the class name must fall back to the report name, but got '%s'", c.ClassName)
+	}
+	if c.Failure == nil || c.Failure.Message != `expected "OK", but got <NotFound>` {
+		t.Errorf("unexpected failure: %+v", c.Failure)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, rep); err != nil {
+		t.Fatalf("WriteJSON must not return an error, but got '%s'", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("the report must be a valid JSON, but got '%s'", err)
+	}
+	expected := map[string]interface{}{
+		"name":   "suite",
+		"tests":  2.0,
+		"passed": 1.0,
+		"failed": 1.0,
+		"time":   2.0,
+		"results": []interface{}{
+			map[string]interface{}{"name": "say hello", "method": "api.Example.Unary", "passed": true, "time": 1.5},
+			map[string]interface{}{"name": "line 5: call Unary", "passed": false, "time": 0.5, "error": `expected "OK", but got <NotFound>`},
+		},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, fname := range []string{"junit.xml", "results.json"} {
+		path := filepath.Join(dir, fname)
+		if err := report.WriteFile(path, rep); err != nil {
+			t.Fatalf("%s: WriteFile must not return an error, but got '%s'", fname, err)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: failed to read the report: %s", fname, err)
+		}
+		isXML := strings.HasPrefix(string(b), "<?xml")
+		if isXML != (filepath.Ext(fname) == ".xml") {
+			t.Errorf("%s: the format must be determined by the extension, but got:\n%s", fname, b)
+		}
+	}
+	if err := report.WriteFile(filepath.Join(dir, "results.txt"), rep); err == nil {
+		t.Errorf("WriteFile must return an error for an unknown format")
+	}
+}
@@ -0,0 +1,42 @@
+// Package session provides a file-based store of named REPL sessions.
+// Each session is stored as a JSON file such that
+//
+//   <dir>/work.json
+//
+package session
+
+import (
+	"path/filepath"
+
+	"github.com/ktr0731/evans/filestore"
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+var (
+	ErrNotFound    = errors.New("session not found")
+	ErrInvalidName = errors.New("invalid session name")
+)
+
+// DefaultDir returns the default directory of sessions. It is under the config directory.
+func DefaultDir() string {
+	return filepath.Join(xdgbasedir.ConfigHome(), meta.AppName, "sessions")
+}
+
+// Store stores sessions under a directory. Load and Delete return ErrNotFound if the session is missing.
+// Sessions are readable only by the user because their headers usually have credentials.
+type Store struct {
+	*filestore.Store
+}
+
+// NewStore returns a new store which stores sessions under dir.
+func NewStore(dir string) *Store {
+	return &Store{filestore.New(dir, filestore.Options{
+		Kind:           "session",
+		ErrNotFound:    ErrNotFound,
+		ErrInvalidName: ErrInvalidName,
+		DirMode:        0700,
+		FileMode:       0600,
+	})}
+}
@@ -0,0 +1,74 @@
+package session_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/session"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := session.NewStore(dir)
+
+	names, err := s.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no sessions, but got %v", names)
+	}
+
+	for _, name := range []string{"kumiko", "reina"} {
+		if err := s.Save(name, []byte(`{"service": "`+name+`"}`)); err != nil {
+			t.Fatalf("Save must not return an error, but got '%s'", err)
+		}
+	}
+	names, err = s.Names()
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"kumiko", "reina"}, names); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	b, err := s.Load("kumiko")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if string(b) != `{"service": "kumiko"}` {
+		t.Errorf("unexpected session: %s", b)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(dir, "kumiko.json"))
+		if err != nil {
+			t.Fatalf("failed to stat the session: %s", err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("sessions must be readable only by the user, but the permission is %o", perm)
+		}
+	}
+
+	if err := s.Delete("kumiko"); err != nil {
+		t.Fatalf("Delete must not return an error, but got '%s'", err)
+	}
+	if _, err := s.Load("kumiko"); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Load must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Delete("kumiko"); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Delete must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Save("../kumiko", nil); !errors.Is(err, session.ErrInvalidName) {
+		t.Errorf("Save must return ErrInvalidName, but got '%v'", err)
+	}
+}
@@ -0,0 +1,261 @@
+package suite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	idlproto "github.com/ktr0731/evans/idl/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v2"
+)
+
+// Runner runs test cases by calling methods of the spec with the client.
+type Runner struct {
+	spec    idl.Spec
+	client  grpc.Client
+	headers map[string][]string
+	scoped  grpc.ScopedHeaders
+	timeout time.Duration
+}
+
+// NewRunner returns a new runner. headers are sent with all requests in addition to headers of each case,
+// and scoped headers are sent with requests to methods in the scope.
+// If timeout is positive, each call is canceled after timeout.
+func NewRunner(spec idl.Spec, client grpc.Client, headers map[string][]string, scoped grpc.ScopedHeaders, timeout time.Duration) *Runner {
+	return &Runner{spec: spec, client: client, headers: headers, scoped: scoped, timeout: timeout}
+}
+
+// Result is the result of a case.
+type Result struct {
+	Case *Case
+	// Err is the reason why the case failed. It is nil if the case passed.
+	Err      error
+	Duration time.Duration
+}
+
+// Run runs all cases of s. At most parallel cases are run concurrently, so that cases are run sequentially
+// if parallel is 1 or less. Results are in the same order as the cases.
+// Run returns an error only if the default headers are invalid.
+func (r *Runner) Run(ctx context.Context, s *Suite, parallel int) ([]*Result, error) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	// Default headers are expanded only once to not run commands for every case.
+	headers, err := grpc.ExpandHeaders(ctx, r.headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := r.scoped.Expand(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, len(s.Cases))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, c := range s.Cases {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, c *Case) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			err := r.run(ctx, c, scoped.For(c.Method, headers))
+			results[i] = &Result{Case: c, Err: err, Duration: time.Since(start)}
+		}(i, c)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// run runs c with headers in addition to headers of c.
+func (r *Runner) run(ctx context.Context, c *Case, headers grpc.Headers) error {
+	rpc, err := idlproto.LookupRPC(r.spec, c.Method)
+	if err != nil {
+		return err
+	}
+	code := codes.OK
+	if c.Expect.Code != "" {
+		code, err = grpc.ParseCode(c.Expect.Code)
+		if err != nil {
+			return err
+		}
+	}
+	reqs, err := r.requests(rpc, c.Request)
+	if err != nil {
+		return err
+	}
+
+	res, stat, err := grpc.CallWithOptions(ctx, r.client, rpc, reqs, grpc.CallOptions{
+		Headers:      headers,
+		ExtraHeaders: c.Headers,
+		Timeout:      r.timeout,
+	})
+	if err != nil {
+		return err
+	}
+	if stat.Code() != code {
+		return errors.Errorf("expected status code %s, but got %s (message: %q)", code, stat.Code(), stat.Message())
+	}
+	if stat.Code() != codes.OK {
+		return nil
+	}
+
+	expected := c.Expect.Responses
+	if c.Expect.Response != nil {
+		expected = []interface{}{c.Expect.Response}
+	}
+	if expected == nil {
+		return nil
+	}
+	if len(expected) != len(res) {
+		return errors.Errorf("expected %d responses, but got %d", len(expected), len(res))
+	}
+	for i := range expected {
+		if err := compare(rpc, expected[i], res[i]); err != nil {
+			return errors.Wrapf(err, "response #%d doesn't match", i+1)
+		}
+	}
+	return nil
+}
+
+// requests decodes v as request bodies of the RPC. If v is nil, an empty request is returned.
+func (r *Runner) requests(rpc *grpc.RPC, v interface{}) ([]interface{}, error) {
+	if v == nil {
+		req, err := rpc.RequestType.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to instantiate a request")
+		}
+		return []interface{}{req}, nil
+	}
+	msgs, err := decode(rpc.RequestType, v)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid request")
+	}
+	return msgs, nil
+}
+
+// decode decodes v as messages of typ. If v is a sequence, each element is decoded as a message.
+func decode(typ *grpc.Type, v interface{}) ([]interface{}, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	filler := fill.NewSilentYAMLFiller(bytes.NewReader(b))
+	var msgs []interface{}
+	for {
+		msg, err := typ.New()
+		if err != nil {
+			return nil, err
+		}
+		err = filler.Fill(msg)
+		if errors.Is(err, io.EOF) {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// compare reports an error unless fields set in the expected response have the same values as the actual response.
+func compare(rpc *grpc.RPC, expected, actual interface{}) error {
+	msgs, err := decode(rpc.ResponseType, expected)
+	if err != nil {
+		return errors.Wrap(err, "invalid expected response")
+	}
+	if len(msgs) != 1 {
+		return errors.Errorf("the expected response must be a message, but got %d messages", len(msgs))
+	}
+	e, err := toJSONValue(msgs[0])
+	if err != nil {
+		return err
+	}
+	a, err := toJSONValue(actual)
+	if err != nil {
+		return err
+	}
+	if !contains(a, e) {
+		eb, _ := json.Marshal(e)
+		ab, _ := json.Marshal(a)
+		return errors.Errorf("expected %s, but got %s", eb, ab)
+	}
+	return nil
+}
+
+func toJSONValue(msg interface{}) (interface{}, error) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("%T is not a Protocol Buffers message", msg)
+	}
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, m); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the message")
+	}
+	var v interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the message")
+	}
+	return v, nil
+}
+
+// contains reports whether actual contains expected. Objects of expected can lack keys of actual,
+// and arrays must have the same length.
+func contains(actual, expected interface{}) bool {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range e {
+			if !contains(a[k], v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !contains(a[i], e[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, expected)
+	}
+}
+
+// Report writes results and the summary to w. It returns the number of failed cases.
+func Report(w io.Writer, results []*Result) int {
+	var failed int
+	for _, r := range results {
+		d := r.Duration.Round(time.Millisecond)
+		if r.Err == nil {
+			fmt.Fprintf(w, "PASS  %s (%s)\n", r.Case.Name, d)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL  %s (%s)\n      %s\n", r.Case.Name, d, r.Err)
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
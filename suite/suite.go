@@ -0,0 +1,70 @@
+// Package suite provides a runner of test suites defined in YAML files.
+// Each test case calls a method with request bodies and headers, and asserts the status code and responses.
+package suite
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Suite is a set of test cases.
+type Suite struct {
+	Cases []*Case `yaml:"cases"`
+}
+
+// Case is a test case which calls a method and asserts the result.
+type Case struct {
+	// Name is the name of the case. If it is empty, Method is used.
+	Name string `yaml:"name"`
+	// Method is the fully-qualified method name such that "api.Example.Unary".
+	Method string `yaml:"method"`
+	// Headers are sent with the request in addition to the default headers.
+	// References in the values are not expanded unlike the default headers.
+	Headers map[string]string `yaml:"headers"`
+	// Request is a request body, or a sequence of request bodies for client streaming and bidi streaming RPCs.
+	// If it is nil, an empty request is sent.
+	Request interface{} `yaml:"request"`
+	// Expect is the expected result.
+	Expect Expect `yaml:"expect"`
+}
+
+// Expect is the expected result of a case.
+type Expect struct {
+	// Code is the expected status code such that "OK", "NotFound", "NOT_FOUND" or "5". The default is "OK".
+	Code string `yaml:"code"`
+	// Response is the expected response. Only fields which are set in it are compared, so that it can be a part
+	// of the actual response. For streaming RPCs, it is the same as Responses which has only Response.
+	Response interface{} `yaml:"response"`
+	// Responses are the expected responses of server streaming and bidi streaming RPCs in order.
+	// The number of responses must be the same as the actual one.
+	Responses []interface{} `yaml:"responses"`
+}
+
+// Load loads a test suite from the YAML file.
+func Load(fname string) (*Suite, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the suite file")
+	}
+	var s Suite
+	if err := yaml.UnmarshalStrict(b, &s); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the suite file as YAML")
+	}
+	if len(s.Cases) == 0 {
+		return nil, errors.New("the suite has no cases")
+	}
+	for i, c := range s.Cases {
+		if c.Method == "" {
+			return nil, errors.Errorf("method of the case #%d is required", i+1)
+		}
+		if c.Name == "" {
+			c.Name = c.Method
+		}
+		if c.Expect.Response != nil && c.Expect.Responses != nil {
+			return nil, errors.Errorf("both of response and responses are specified in the case '%s'", c.Name)
+		}
+	}
+	return &s, nil
+}
@@ -0,0 +1,126 @@
+package suite_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl/proto"
+	"github.com/ktr0731/evans/mock"
+	"github.com/ktr0731/evans/suite"
+)
+
+func TestLoad(t *testing.T) {
+	cases := map[string]struct {
+		in     string
+		hasErr bool
+	}{
+		"valid":       {in: "cases:\n  - method: api.Example.Unary\n"},
+		"no cases":    {in: "cases: []\n", hasErr: true},
+		"no method":   {in: "cases:\n  - name: foo\n", hasErr: true},
+		"unknown key": {in: "cases:\n  - method: api.Example.Unary\n    foo: bar\n", hasErr: true},
+		"both of response and responses": {
+			in:     "cases:\n  - method: api.Example.Unary\n    expect:\n      response: {}\n      responses: [{}]\n",
+			hasErr: true,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			fname := filepath.Join(t.TempDir(), "suite.yaml")
+			if err := ioutil.WriteFile(fname, []byte(c.in), 0600); err != nil {
+				t.Fatalf("failed to write the suite file: %s", err)
+			}
+			s, err := suite.Load(fname)
+			if c.hasErr {
+				if err == nil {
+					t.Errorf("Load must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load must not return an error, but got '%s'", err)
+			}
+			if name := s.Cases[0].Name; name != "api.Example.Unary" {
+				t.Errorf("the name must be the method name by default, but got '%s'", name)
+			}
+		})
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	responses, err := mock.LoadResponses("../mock/testdata/responses.yaml")
+	if err != nil {
+		t.Fatalf("LoadResponses must not return an error, but got '%s'", err)
+	}
+	serverSpec, err := proto.LoadFiles([]string{"../mock/testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	srv, err := mock.NewServer(serverSpec, responses)
+	if err != nil {
+		t.Fatalf("NewServer must not return an error, but got '%s'", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go srv.Serve(l) //nolint:errcheck
+	defer srv.Stop()
+
+	client, err := grpc.NewClient(l.Addr().String(), "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("NewClient must not return an error, but got '%s'", err)
+	}
+	defer client.Close(context.Background()) //nolint:errcheck
+	spec, err := proto.LoadFiles([]string{"testdata"}, []string{"api.proto"})
+	if err != nil {
+		t.Fatalf("LoadFiles must not return an error, but got '%s'", err)
+	}
+	s, err := suite.Load("testdata/suite.yaml")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+
+	results, err := suite.NewRunner(spec, client, map[string][]string{"user-agent": {"evans"}}, nil, 0).Run(context.Background(), s, 3)
+	if err != nil {
+		t.Fatalf("Run must not return an error, but got '%s'", err)
+	}
+
+	expected := map[string]bool{
+		"unary":                             true,
+		"unary with an unexpected response": false,
+		"unary with multiple requests":      false,
+		"client streaming":                  true,
+		"server streaming":                  true,
+		"server streaming with the wrong number of responses": false,
+		"api.Example.BidiStreaming":                           true,
+		"the expected status code":                            true,
+		"an unexpected status code":                           false,
+		"an unknown method":                                   false,
+	}
+	if len(results) != len(s.Cases) {
+		t.Fatalf("expected %d results, but got %d", len(s.Cases), len(results))
+	}
+	for i, r := range results {
+		if r.Case != s.Cases[i] {
+			t.Errorf("results must be in the same order as cases")
+		}
+		if passed := r.Err == nil; passed != expected[r.Case.Name] {
+			t.Errorf("%s: expected passed = %t, but got %t (err: %v)", r.Case.Name, expected[r.Case.Name], passed, r.Err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if failed := suite.Report(&buf, results); failed != 5 {
+		t.Errorf("expected 5 failed cases, but got %d", failed)
+	}
+	if out := buf.String(); !strings.HasSuffix(out, "\n5 passed, 5 failed\n") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+}
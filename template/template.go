@@ -0,0 +1,63 @@
+// Package template provides a file-based store of named request templates.
+// Templates are stored in a directory per fully-qualified RPC name such that
+//
+//   <dir>/api.Example.CreateUser/create-user.json
+//
+package template
+
+import (
+	"path/filepath"
+
+	"github.com/ktr0731/evans/filestore"
+	"github.com/ktr0731/evans/meta"
+	"github.com/pkg/errors"
+	"github.com/zchee/go-xdgbasedir"
+)
+
+var (
+	ErrNotFound    = errors.New("template not found")
+	ErrInvalidName = errors.New("invalid template name")
+)
+
+// DefaultDir returns the default directory of templates. It is under the config directory.
+func DefaultDir() string {
+	return filepath.Join(xdgbasedir.ConfigHome(), meta.AppName, "templates")
+}
+
+// Store stores templates under a directory.
+type Store struct {
+	files *filestore.Store
+}
+
+// NewStore returns a new store which stores templates under dir.
+func NewStore(dir string) *Store {
+	return &Store{files: filestore.New(dir, filestore.Options{
+		Kind:           "template",
+		ErrNotFound:    ErrNotFound,
+		ErrInvalidName: ErrInvalidName,
+		DirMode:        0755,
+		FileMode:       0644,
+	})}
+}
+
+// Names returns all template names of the RPC in ascending order.
+func (s *Store) Names(fqrn string) ([]string, error) {
+	return s.files.Sub(fqrn).Names()
+}
+
+// Load loads the template named name of the RPC.
+// Load returns ErrNotFound if the template is missing.
+func (s *Store) Load(fqrn, name string) ([]byte, error) {
+	return s.files.Sub(fqrn).Load(name)
+}
+
+// Save saves body as the template named name of the RPC. If the template already exists, it is overwritten.
+func (s *Store) Save(fqrn, name string, body []byte) error {
+	return s.files.Sub(fqrn).Save(name, body)
+}
+
+// Delete deletes the template named name of the RPC.
+// Delete returns ErrNotFound if the template is missing.
+func (s *Store) Delete(fqrn, name string) error {
+	return s.files.Sub(fqrn).Delete(name)
+}
@@ -0,0 +1,64 @@
+package template_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/template"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const fqrn = "api.Example.Unary"
+	s := template.NewStore(dir)
+
+	names, err := s.Names(fqrn)
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no templates, but got %v", names)
+	}
+
+	for _, name := range []string{"kumiko", "reina"} {
+		if err := s.Save(fqrn, name, []byte(`{"name": "`+name+`"}`)); err != nil {
+			t.Fatalf("Save must not return an error, but got '%s'", err)
+		}
+	}
+	names, err = s.Names(fqrn)
+	if err != nil {
+		t.Fatalf("Names must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff([]string{"kumiko", "reina"}, names); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	b, err := s.Load(fqrn, "kumiko")
+	if err != nil {
+		t.Fatalf("Load must not return an error, but got '%s'", err)
+	}
+	if string(b) != `{"name": "kumiko"}` {
+		t.Errorf("unexpected template: %s", b)
+	}
+
+	if err := s.Delete(fqrn, "kumiko"); err != nil {
+		t.Fatalf("Delete must not return an error, but got '%s'", err)
+	}
+	if _, err := s.Load(fqrn, "kumiko"); !errors.Is(err, template.ErrNotFound) {
+		t.Errorf("Load must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Delete(fqrn, "kumiko"); !errors.Is(err, template.ErrNotFound) {
+		t.Errorf("Delete must return ErrNotFound, but got '%v'", err)
+	}
+	if err := s.Save(fqrn, "../kumiko", nil); !errors.Is(err, template.ErrInvalidName) {
+		t.Errorf("Save must return ErrInvalidName, but got '%v'", err)
+	}
+}
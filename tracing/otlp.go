@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Exporter exports spans to an OpenTelemetry collector by OTLP/HTTP with JSON encoding.
+type Exporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+}
+
+// NewExporter returns a new exporter which sends spans to endpoint such that "http://localhost:4318".
+// If endpoint has no path, the default path "/v1/traces" is used. serviceName is the service.name of the resource.
+func NewExporter(endpoint, serviceName string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid OTLP endpoint '%s'", endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf(`invalid OTLP endpoint '%s'. the scheme must be "http" or "https"`, endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return &Exporter{
+		endpoint:    u.String(),
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Export sends spans to the collector.
+func (e *Exporter) Export(ctx context.Context, spans []*Span) error {
+	b, err := json.Marshal(e.newRequest(spans))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode spans")
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "failed to create a request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to send spans")
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return errors.Errorf("the collector returned %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// The following types are the JSON encoding of OTLP ExportTraceServiceRequest.
+// IDs are encoded as hex strings, and 64-bit integers are encoded as strings.
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*otlpKeyValue `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	spanKindClient  = 3
+	statusCodeError = 2
+)
+
+func stringKeyValue(k, v string) *otlpKeyValue {
+	return &otlpKeyValue{Key: k, Value: otlpValue{StringValue: &v}}
+}
+
+func (e *Exporter) newRequest(spans []*Span) *otlpRequest {
+	ss := make([]*otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := &otlpSpan{
+			TraceID:           hex.EncodeToString(s.Context.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.Context.SpanID[:]),
+			Name:              s.Name,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.ParentSpanID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.ParentSpanID[:])
+		}
+		keys := make([]string, 0, len(s.Attributes))
+		for k := range s.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			span.Attributes = append(span.Attributes, stringKeyValue(k, s.Attributes[k]))
+		}
+		code := strconv.Itoa(int(s.Code))
+		span.Attributes = append(span.Attributes, &otlpKeyValue{Key: "rpc.grpc.status_code", Value: otlpValue{IntValue: &code}})
+		// Client spans are unset if the RPC succeeded, and error otherwise.
+		if s.Code != codes.OK {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.Message}
+		}
+		ss = append(ss, span)
+	}
+	return &otlpRequest{
+		ResourceSpans: []*otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []*otlpKeyValue{stringKeyValue("service.name", e.serviceName)}},
+			ScopeSpans: []*otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/ktr0731/evans"},
+				Spans: ss,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,151 @@
+// Package tracing provides W3C Trace Context propagation and exporting client spans to OpenTelemetry collectors
+// by OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// TraceparentKey is the metadata key of W3C Trace Context.
+const TraceparentKey = "traceparent"
+
+// SpanContext is the part of a span which is propagated to servers.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Sampled is true if the trace is recorded.
+	Sampled bool
+}
+
+// ParseTraceparent parses s as the traceparent header such that
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	sp := strings.Split(strings.TrimSpace(s), "-")
+	if len(sp) < 4 {
+		return sc, errors.Errorf("invalid traceparent '%s'. it must be formatted as <version>-<trace-id>-<parent-id>-<flags>", s)
+	}
+	if len(sp[0]) != 2 || sp[0] == "ff" {
+		return sc, errors.Errorf("invalid traceparent version '%s'", sp[0])
+	}
+	// Version 00 has exactly 4 fields. Future versions may have additional fields.
+	if sp[0] == "00" && len(sp) != 4 {
+		return sc, errors.Errorf("invalid traceparent '%s'. it must have 4 fields", s)
+	}
+	if err := decodeHex(sc.TraceID[:], sp[1]); err != nil {
+		return sc, errors.Wrapf(err, "invalid trace ID '%s'", sp[1])
+	}
+	if err := decodeHex(sc.SpanID[:], sp[2]); err != nil {
+		return sc, errors.Wrapf(err, "invalid parent ID '%s'", sp[2])
+	}
+	var flags [1]byte
+	if err := decodeHex(flags[:], sp[3]); err != nil {
+		return sc, errors.Wrapf(err, "invalid trace flags '%s'", sp[3])
+	}
+	if sc.TraceID == [16]byte{} || sc.SpanID == [8]byte{} {
+		return sc, errors.Errorf("invalid traceparent '%s'. IDs must not be all zeros", s)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+func decodeHex(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return errors.Errorf("it must be %d lowercase hex digits", hex.EncodedLen(len(dst)))
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// Traceparent returns sc as the traceparent header.
+func (sc SpanContext) Traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// Span is a client span of an RPC.
+type Span struct {
+	// Name is the RPC name such that "api.Example/Unary".
+	Name    string
+	Context SpanContext
+	// ParentSpanID is the ID of the parent span. It is all zeros if the span is a root span.
+	ParentSpanID [8]byte
+	Start, End   time.Time
+	// Attributes are string attributes of the span such that "rpc.service".
+	Attributes map[string]string
+	// Code is the status code of the RPC.
+	Code    codes.Code
+	Message string
+}
+
+// Tracer starts spans and exports finished spans.
+type Tracer struct {
+	parent   *SpanContext
+	exporter *Exporter
+
+	wg sync.WaitGroup
+}
+
+// NewTracer returns a new tracer. If parent is not nil, all spans are children of parent. Otherwise, each span starts
+// a new trace. If exporter is not nil, sampled spans are exported by it.
+func NewTracer(parent *SpanContext, exporter *Exporter) *Tracer {
+	return &Tracer{parent: parent, exporter: exporter}
+}
+
+// Start starts a new span named name.
+func (t *Tracer) Start(name string) *Span {
+	s := &Span{Name: name, Start: time.Now(), Attributes: map[string]string{}}
+	if t.parent != nil {
+		s.Context.TraceID = t.parent.TraceID
+		s.Context.Sampled = t.parent.Sampled
+		s.ParentSpanID = t.parent.SpanID
+	} else {
+		rand.Read(s.Context.TraceID[:]) //nolint:errcheck
+		s.Context.Sampled = true
+	}
+	rand.Read(s.Context.SpanID[:]) //nolint:errcheck
+	return s
+}
+
+// Finish ends s with the status code. The span is exported in background. Errors of exporting are only logged.
+func (t *Tracer) Finish(s *Span, code codes.Code, msg string) {
+	s.End, s.Code, s.Message = time.Now(), code, msg
+	if t.exporter == nil || !s.Context.Sampled {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		if err := t.exporter.Export(context.Background(), []*Span{s}); err != nil {
+			logger.Printf("failed to export the span of '%s': %s", s.Name, err)
+		}
+	}()
+}
+
+// Close waits for spans being exported until ctx is done.
+func (t *Tracer) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed to wait for exporting spans")
+	}
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := map[string]struct {
+		in          string
+		expectedErr bool
+	}{
+		"sampled":              {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"not sampled":          {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		"future version":       {in: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo"},
+		"empty":                {in: "", expectedErr: true},
+		"invalid version":      {in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectedErr: true},
+		"too many fields":      {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo", expectedErr: true},
+		"short trace ID":       {in: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", expectedErr: true},
+		"uppercase span ID":    {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01", expectedErr: true},
+		"all zeros trace ID":   {in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expectedErr: true},
+		"invalid flags":        {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", expectedErr: true},
+		"non-hex trace ID":     {in: "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", expectedErr: true},
+		"all zeros parent ID":  {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", expectedErr: true},
+		"missing flags":        {in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", expectedErr: true},
+		"surrounded by spaces": {in: " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 "},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			sc, err := ParseTraceparent(c.in)
+			if c.expectedErr {
+				if err == nil {
+					t.Fatal("ParseTraceparent must return an error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTraceparent must not return an error, but got '%s'", err)
+			}
+			if sc.Traceparent()[3:52] != "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7" {
+				t.Errorf("unexpected traceparent: %s", sc.Traceparent())
+			}
+		})
+	}
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("new trace", func(t *testing.T) {
+		tr := NewTracer(nil, nil)
+		s1, s2 := tr.Start("a"), tr.Start("b")
+		if s1.Context.TraceID == s2.Context.TraceID {
+			t.Errorf("each span must start a new trace, but both have %x", s1.Context.TraceID)
+		}
+		if !s1.Context.Sampled {
+			t.Error("new traces must be sampled")
+		}
+		if s1.ParentSpanID != [8]byte{} {
+			t.Errorf("root spans must not have a parent, but got %x", s1.ParentSpanID)
+		}
+	})
+
+	t.Run("child of the parent", func(t *testing.T) {
+		parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		if err != nil {
+			t.Fatalf("ParseTraceparent must not return an error, but got '%s'", err)
+		}
+		s := NewTracer(&parent, nil).Start("a")
+		if s.Context.TraceID != parent.TraceID {
+			t.Errorf("expected trace ID %x, but got %x", parent.TraceID, s.Context.TraceID)
+		}
+		if s.ParentSpanID != parent.SpanID {
+			t.Errorf("expected parent span ID %x, but got %x", parent.SpanID, s.ParentSpanID)
+		}
+		if s.Context.SpanID == parent.SpanID {
+			t.Error("the span must have a new span ID")
+		}
+		if s.Context.Sampled {
+			t.Error("the sampled flag must be inherited from the parent")
+		}
+	})
+}
+
+func TestExporter(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("expected path /v1/traces, but got %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("failed to read the body: %s", err)
+		}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("failed to decode the body: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(srv.URL, "evans")
+	if err != nil {
+		t.Fatalf("NewExporter must not return an error, but got '%s'", err)
+	}
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceparent must not return an error, but got '%s'", err)
+	}
+	tr := NewTracer(&parent, e)
+	s := tr.Start("api.Example/Unary")
+	s.Context.SpanID = [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	s.Attributes["rpc.system"] = "grpc"
+	tr.Finish(s, codes.NotFound, "not found")
+	if err := tr.Close(context.Background()); err != nil {
+		t.Fatalf("Close must not return an error, but got '%s'", err)
+	}
+
+	span := got["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	delete(span, "startTimeUnixNano")
+	delete(span, "endTimeUnixNano")
+	expected := map[string]interface{}{
+		"traceId":      "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanId":       "0102030405060708",
+		"parentSpanId": "00f067aa0ba902b7",
+		"name":         "api.Example/Unary",
+		"kind":         float64(3),
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "rpc.system", "value": map[string]interface{}{"stringValue": "grpc"}},
+			map[string]interface{}{"key": "rpc.grpc.status_code", "value": map[string]interface{}{"intValue": "5"}},
+		},
+		"status": map[string]interface{}{"code": float64(2), "message": "not found"},
+	}
+	if diff := cmp.Diff(expected, span); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", "://"} {
+		if _, err := NewExporter(endpoint, "evans"); err == nil {
+			t.Errorf("NewExporter must return an error for '%s', but got nil", endpoint)
+		}
+	}
+}
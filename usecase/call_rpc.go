@@ -2,11 +2,16 @@ package usecase
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
 	"github.com/ktr0731/evans/fill"
-	"github.com/ktr0731/evans/idl/proto"
+	"github.com/ktr0731/evans/grpc"
 	"github.com/ktr0731/evans/logger"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
@@ -26,6 +31,8 @@ func (e ErrorCode) String() string {
 
 type gRPCError struct {
 	*status.Status
+
+	details []map[string]interface{}
 }
 
 func (e *gRPCError) Unwrap() error {
@@ -33,7 +40,15 @@ func (e *gRPCError) Unwrap() error {
 }
 
 func (e *gRPCError) Error() string {
-	return e.Status.Err().Error()
+	if len(e.details) == 0 {
+		return e.Status.Err().Error()
+	}
+	return fmt.Sprintf("%s\n%s", e.Status.Err().Error(), FormatStatusDetails(e.details))
+}
+
+// Details returns the decoded error details. Each detail is a map which has "@type" key.
+func (e *gRPCError) Details() []map[string]interface{} {
+	return e.details
 }
 
 func (e *gRPCError) Code() ErrorCode {
@@ -47,16 +62,59 @@ func CallRPC(ctx context.Context, w io.Writer, rpcName string) error {
 	return dm.CallRPC(ctx, w, rpcName, dm.filler)
 }
 func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName string, filler fill.Filler) error {
-	fqsn := proto.FullyQualifiedServiceName(m.state.selectedPackage, m.state.selectedService)
-	rpc, err := m.spec.RPC(fqsn, rpcName)
+	e := m.env()
+	rpc, err := getRPC(e, rpcName)
 	if err != nil {
-		return errors.Wrap(err, "failed to get the RPC descriptor")
+		return err
+	}
+	return m.callRPC(ctx, e, w, rpc, filler)
+}
+
+// callRPC calls rpc with e.client. The selected package and service in e are used to check deprecation.
+func (m *dependencyManager) callRPC(ctx context.Context, e env, w io.Writer, rpc *grpc.RPC, filler fill.Filler) (err error) {
+	stats := &CallStats{Method: rpc.FullyQualifiedName}
+	defer func() {
+		m.recordStats(stats, err)
+	}()
+
+	// sentRequests is recorded as the last requests of the RPC.
+	// receivedResponse is the last received response. It is recorded after the call to avoid data races
+	// between the receiver and the filler of bidi streaming RPCs.
+	var (
+		sentRequests     []interface{}
+		receivedResponse interface{}
+	)
+	if err := m.checkDeprecatedRPC(e, rpc); err != nil {
+		return err
 	}
+	warnedFields := map[string]bool{}
+	defer func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if len(sentRequests) != 0 {
+			m.recordRequests(rpc.FullyQualifiedName, sentRequests)
+			m.recordRequestHistory(rpc, sentRequests)
+		}
+		if receivedResponse != nil {
+			m.state.lastResponse = receivedResponse
+		}
+	}()
 	newRequest := func() (interface{}, error) {
 		req, err := rpc.RequestType.New()
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to instantiate an instance of the request type '%s'", rpc.RequestType.FullyQualifiedName)
 		}
+		if rf, ok := filler.(fill.RawFiller); ok {
+			// Raw requests are sent as they are even if they are malformed, so they are recorded only if they are valid.
+			b, err := rf.FillRaw(rpc.IsClientStreaming)
+			if err != nil {
+				return nil, err
+			}
+			if u, ok := req.(proto.Unmarshaler); ok && u.Unmarshal(b) == nil {
+				sentRequests = append(sentRequests, req)
+			}
+			return &rawRequest{b: b}, nil
+		}
 		err = filler.Fill(req)
 		if errors.Is(err, io.EOF) {
 			return nil, io.EOF
@@ -64,6 +122,10 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		if err != nil {
 			return nil, err
 		}
+		if err := m.checkDeprecatedFields(req, warnedFields); err != nil {
+			return nil, err
+		}
+		sentRequests = append(sentRequests, req)
 		return req, nil
 	}
 	newResponse := func() (interface{}, error) {
@@ -74,17 +136,32 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		return res, nil
 	}
 	flushHeader := func(header metadata.MD) {
+		m.propagateHeaders(header)
 		m.responseFormatter.FormatHeader(header)
 	}
 	flushResponse := func(res interface{}) error {
+		if res != nil {
+			receivedResponse = res
+			stats.addResponse(res)
+		}
 		return m.responseFormatter.FormatMessage(res)
 	}
 	flushTrailer := func(status *status.Status, trailer metadata.MD) error {
-		return m.responseFormatter.FormatTrailer(status, trailer)
+		m.propagateHeaders(trailer)
+		return m.responseFormatter.FormatTrailer(newStatus(e.spec, status), trailer)
 	}
 	flushDone := func() error {
 		return m.responseFormatter.Done()
 	}
+	// flushCanceled writes the Canceled status as the final status of a stream interrupted by the user (e.g. CTRL+C).
+	flushCanceled := func(trailer metadata.MD) {
+		if err := flushTrailer(status.New(codes.Canceled, context.Canceled.Error()), trailer); err != nil {
+			logger.Printf("failed to format the trailer: %s", err)
+		}
+		if err := flushDone(); err != nil {
+			logger.Printf("failed to call Done: %s", err)
+		}
+	}
 	flushAll := func(status *status.Status, header, trailer metadata.MD, res interface{}) error {
 		flushHeader(header)
 		if err := flushResponse(res); err != nil {
@@ -96,11 +173,19 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		return flushDone()
 	}
 
-	md := metadata.New(nil)
-	for k, v := range m.ListHeaders() {
-		md.Append(k, v...)
+	ctx, err = m.withHeaders(ctx, rpc.FullyQualifiedName)
+	if err != nil {
+		return err
+	}
+
+	// Unary RPCs apply the timeout just before invoking the RPC to exclude the time for inputting.
+	// Streaming RPCs apply it to the whole stream.
+	streamCtx := ctx
+	if rpc.IsClientStreaming || rpc.IsServerStreaming {
+		var cancel context.CancelFunc
+		streamCtx, cancel = m.withTimeout(ctx)
+		defer cancel()
 	}
-	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	streamDesc := &gogrpc.StreamDesc{
 		StreamName:    rpc.Name,
@@ -109,7 +194,12 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 	}
 	switch {
 	case rpc.IsClientStreaming && rpc.IsServerStreaming:
-		stream, err := m.gRPCClient.NewBidiStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		// cancelStream is used to abort receiving responses when sending requests failed.
+		// Otherwise, the receiver waits for responses which the server will never send.
+		streamCtx, cancelStream := context.WithCancel(streamCtx)
+		defer cancelStream()
+		stats.start = time.Now()
+		stream, err := e.client.NewBidiStream(streamCtx, streamDesc, rpc.FullyQualifiedName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create a bidi stream for RPC '%s'", streamDesc.StreamName)
 		}
@@ -124,9 +214,13 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 				if err != nil {
 					return err
 				}
-				stat, err := handleGRPCResponseError(stream.Receive(res))
+				stat, err := m.handleGRPCResponseError(streamCtx, stream.Receive(m.wrapResponse(res, true)))
 				if err != nil {
 					if errors.Is(err, context.Canceled) {
+						// streamCtx is also canceled when sending requests failed. In that case, the error is reported by the sender.
+						if ctx.Err() != nil {
+							writeTrailerOnce.Do(func() { flushCanceled(stream.Trailer()) })
+						}
 						return nil
 					}
 					if errors.Is(err, io.EOF) {
@@ -170,7 +264,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 				}
 
 				if stat.Code() != codes.OK {
-					return &gRPCError{stat}
+					return &gRPCError{Status: stat, details: decodeStatusDetails(e.spec, stat)}
 				}
 
 				if err := flushResponse(res); err != nil {
@@ -180,25 +274,33 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		})
 
 		eg.Go(func() error {
-			for {
-				req, err := newRequest()
-				if errors.Is(err, io.EOF) {
-					if err := stream.CloseSend(); err != nil {
-						return errors.Wrapf(err, "failed to close the stream of RPC '%s'", streamDesc.StreamName)
+			err := func() error {
+				for {
+					req, err := newRequest()
+					if errors.Is(err, io.EOF) {
+						// Half-close the stream. The server can still send responses.
+						if err := stream.CloseSend(); err != nil {
+							return errors.Wrapf(err, "failed to close the stream of RPC '%s'", streamDesc.StreamName)
+						}
+						return nil
 					}
-					return nil
-				}
-				if err != nil {
-					return err
-				}
-				err = stream.Send(req)
-				if errors.Is(err, io.EOF) {
-					return nil
-				}
-				if err != nil {
-					return errors.Wrapf(err, "failed to send a RPC to the client stream '%s'", streamDesc.StreamName)
+					if err != nil {
+						return err
+					}
+					err = stream.Send(req)
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					if err != nil {
+						return errors.Wrapf(err, "failed to send a RPC to the client stream '%s'", streamDesc.StreamName)
+					}
+					stats.addRequest(req)
 				}
+			}()
+			if err != nil {
+				cancelStream()
 			}
+			return err
 		})
 
 		if err := eg.Wait(); err != nil {
@@ -217,7 +319,8 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 	//   6. Format the response and output it.
 	//
 	case rpc.IsClientStreaming:
-		stream, err := m.gRPCClient.NewClientStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		stats.start = time.Now()
+		stream, err := e.client.NewClientStream(streamCtx, streamDesc, rpc.FullyQualifiedName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create a new client stream for RPC '%s'", streamDesc.StreamName)
 		}
@@ -229,7 +332,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 				if err != nil {
 					return err
 				}
-				stat, err := handleGRPCResponseError(stream.CloseAndReceive(res))
+				stat, err := m.handleGRPCResponseError(streamCtx, stream.CloseAndReceive(m.wrapResponse(res, false)))
 				if err != nil {
 					return errors.Wrapf(err, "failed to close the stream of RPC '%s'", streamDesc.StreamName)
 				}
@@ -251,7 +354,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 				}
 
 				if stat.Code() != codes.OK {
-					return &gRPCError{stat}
+					return &gRPCError{Status: stat, details: decodeStatusDetails(e.spec, stat)}
 				}
 				return nil
 			}
@@ -261,6 +364,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			if err := stream.Send(req); err != nil {
 				return errors.Wrapf(err, "failed to send a RPC to the client stream '%s'", streamDesc.StreamName)
 			}
+			stats.addRequest(req)
 		}
 
 	// Server streaming RPCs are RPC that a client sends once and server responds several times.
@@ -273,7 +377,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 	//   5. If io.EOF received, finish the RPC connection.
 	//
 	case rpc.IsServerStreaming:
-		stream, err := m.gRPCClient.NewServerStream(ctx, streamDesc, rpc.FullyQualifiedName)
+		stream, err := e.client.NewServerStream(streamCtx, streamDesc, rpc.FullyQualifiedName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create a new server stream for RPC '%s'", streamDesc.StreamName)
 		}
@@ -281,9 +385,11 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		if err != nil {
 			return err
 		}
+		stats.start = time.Now()
 		if err := stream.Send(req); err != nil {
 			return errors.Wrapf(err, "failed to send a RPC to the server stream '%s'", streamDesc.StreamName)
 		}
+		stats.addRequest(req)
 
 		var writeHeaderOnce, writeTrailerOnce sync.Once
 
@@ -292,9 +398,10 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			if err != nil {
 				return err
 			}
-			stat, err := handleGRPCResponseError(stream.Receive(res))
+			stat, err := m.handleGRPCResponseError(streamCtx, stream.Receive(m.wrapResponse(res, true)))
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
+					writeTrailerOnce.Do(func() { flushCanceled(stream.Trailer()) })
 					return nil
 				}
 				if errors.Is(err, io.EOF) {
@@ -328,7 +435,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 			}
 
 			if stat.Code() != codes.OK {
-				return &gRPCError{stat}
+				return &gRPCError{Status: stat, details: decodeStatusDetails(e.spec, stat)}
 			}
 
 			if err := flushResponse(res); err != nil {
@@ -353,11 +460,15 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		if err != nil {
 			return err
 		}
-		header, trailer, err := m.gRPCClient.Invoke(ctx, rpc.FullyQualifiedName, req, res)
-		stat, err := handleGRPCResponseError(err)
+		ctx, cancel := m.withTimeout(ctx)
+		defer cancel()
+		stats.start = time.Now()
+		header, trailer, err := e.client.Invoke(ctx, rpc.FullyQualifiedName, req, m.wrapResponse(res, false))
+		stat, err := m.handleGRPCResponseError(ctx, err)
 		if err != nil {
 			return errors.Wrap(err, "failed to send a request")
 		}
+		stats.addRequest(req)
 
 		if stat.Code() != codes.OK {
 			res = nil
@@ -369,7 +480,7 @@ func (m *dependencyManager) CallRPC(ctx context.Context, w io.Writer, rpcName st
 		}
 
 		if stat.Code() != codes.OK {
-			return &gRPCError{stat}
+			return &gRPCError{Status: stat, details: decodeStatusDetails(e.spec, stat)}
 		}
 		return nil
 	}
@@ -395,10 +506,87 @@ func (m *dependencyManager) CallRPCInteractively(ctx context.Context, w io.Write
 	})
 }
 
-func handleGRPCResponseError(err error) (*status.Status, error) {
+// RecallRPC calls the last called RPC again with the same requests.
+// modify is called with each request before sending it, so that it can override field values.
+func RecallRPC(ctx context.Context, w io.Writer, modify func(req interface{}) error) error {
+	return dm.RecallRPC(ctx, w, modify)
+}
+
+func (m *dependencyManager) RecallRPC(ctx context.Context, w io.Writer, modify func(req interface{}) error) error {
+	e := m.env()
+	rpc, err := m.getLastRPC(e.spec)
+	if err != nil {
+		return err
+	}
+	reqs, protoNames := m.lastRequestsOf(rpc.FullyQualifiedName)
+	body, err := formatRequests(rpc, reqs, protoNames)
+	if err != nil {
+		return err
+	}
+	filler := fill.NewSilentFiller(strings.NewReader(body))
+	return m.callRPC(ctx, e, w, rpc, &interactiveFiller{
+		fillFunc: func(v interface{}) error {
+			if err := filler.Fill(v); err != nil {
+				return err
+			}
+			return modify(v)
+		},
+	})
+}
+
+func (m *dependencyManager) handleGRPCResponseError(ctx context.Context, err error) (*status.Status, error) {
 	stat, ok := status.FromError(errors.Cause(err))
 	if !ok {
 		return nil, err
 	}
+	// Make it clear that the RPC is canceled by the timeout Evans set, not by the server.
+	if timeout := m.GetTimeout(); stat.Code() == codes.DeadlineExceeded && ctx.Err() == context.DeadlineExceeded && timeout > 0 {
+		stat = status.Newf(codes.DeadlineExceeded, "%s (timeout: %s)", stat.Message(), timeout)
+	}
 	return stat, nil
 }
+
+// withHeaders returns a new context that has the headers sent with an RPC fqrn as the outgoing metadata.
+// They are the headers the client has and scoped headers of fqrn.
+// Variables, environment variables and commands in header values are expanded, and binary header values are decoded.
+// Commands run without m.mu held, and they are killed if ctx is canceled.
+func (m *dependencyManager) withHeaders(ctx context.Context, fqrn string) (context.Context, error) {
+	m.mu.RLock()
+	headers := cloneHeaders(m.state.scopedHeaders.For(fqrn, m.headers()))
+	m.mu.RUnlock()
+	lookup := func(name string) (string, bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.lookupHeaderVariable(name)
+	}
+
+	md := metadata.New(nil)
+	for k, v := range headers {
+		for _, vv := range v {
+			ev, err := grpc.ResolveHeader(ctx, k, vv, lookup)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid header '%s'", k)
+			}
+			md.Append(k, ev)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// lookupHeaderVariable looks up a variable referenced from header values. Variables take precedence over
+// environment variables.
+func (m *dependencyManager) lookupHeaderVariable(name string) (string, bool) {
+	if v, ok := m.lookupVariable(name); ok {
+		return v, true
+	}
+	return os.LookupEnv(name)
+}
+
+// withTimeout returns a new context that has the deadline if the timeout is set.
+func (m *dependencyManager) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := m.GetTimeout()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
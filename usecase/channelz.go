@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	channelzGetTopChannelsRPC   = "grpc.channelz.v1.Channelz.GetTopChannels"
+	channelzGetServersRPC       = "grpc.channelz.v1.Channelz.GetServers"
+	channelzGetServerSocketsRPC = "grpc.channelz.v1.Channelz.GetServerSockets"
+	channelzGetSocketRPC        = "grpc.channelz.v1.Channelz.GetSocket"
+)
+
+// FormatChannelzChannels formats the summary of top channels the server has by grpc.channelz.v1.Channelz.
+func FormatChannelzChannels(ctx context.Context) (string, error) {
+	return dm.FormatChannelzChannels(ctx)
+}
+func (m *dependencyManager) FormatChannelzChannels(ctx context.Context) (string, error) {
+	type channel struct {
+		ID             int64  `json:"id" table:"id"`
+		Target         string `json:"target" table:"target"`
+		State          string `json:"state" table:"state"`
+		Subchannels    int    `json:"subchannels" table:"subchannels"`
+		CallsStarted   int64  `json:"callsStarted" table:"calls started"`
+		CallsSucceeded int64  `json:"callsSucceeded" table:"calls succeeded"`
+		CallsFailed    int64  `json:"callsFailed" table:"calls failed"`
+		LastCall       string `json:"lastCall" table:"last call"`
+	}
+	var v struct {
+		Channels []channel `json:"channels"`
+	}
+	v.Channels = []channel{} // Show an empty list instead of null in JSON.
+	ctx, err := m.withHeaders(ctx, channelzGetTopChannelsRPC)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client := m.env().client
+	var start int64
+	for {
+		var res channelzpb.GetTopChannelsResponse
+		req := &channelzpb.GetTopChannelsRequest{StartChannelId: start}
+		if _, _, err := client.Invoke(ctx, channelzGetTopChannelsRPC, req, &res); err != nil {
+			return "", errors.Wrap(err, "failed to get top channels")
+		}
+		for _, c := range res.GetChannel() {
+			d := c.GetData()
+			v.Channels = append(v.Channels, channel{
+				ID:             c.GetRef().GetChannelId(),
+				Target:         d.GetTarget(),
+				State:          d.GetState().GetState().String(),
+				Subchannels:    len(c.GetSubchannelRef()),
+				CallsStarted:   d.GetCallsStarted(),
+				CallsSucceeded: d.GetCallsSucceeded(),
+				CallsFailed:    d.GetCallsFailed(),
+				LastCall:       formatChannelzTimestamp(d.GetLastCallStartedTimestamp()),
+			})
+			start = c.GetRef().GetChannelId() + 1
+		}
+		if res.GetEnd() || len(res.GetChannel()) == 0 {
+			break
+		}
+	}
+	out, err := m.resourcePresenter.Format(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format channels by presenter")
+	}
+	return out, nil
+}
+
+// FormatChannelzServers formats the summary of servers by grpc.channelz.v1.Channelz.
+func FormatChannelzServers(ctx context.Context) (string, error) {
+	return dm.FormatChannelzServers(ctx)
+}
+func (m *dependencyManager) FormatChannelzServers(ctx context.Context) (string, error) {
+	type server struct {
+		ID             int64  `json:"id" table:"id"`
+		ListenSockets  string `json:"listenSockets" table:"listen sockets"`
+		CallsStarted   int64  `json:"callsStarted" table:"calls started"`
+		CallsSucceeded int64  `json:"callsSucceeded" table:"calls succeeded"`
+		CallsFailed    int64  `json:"callsFailed" table:"calls failed"`
+		LastCall       string `json:"lastCall" table:"last call"`
+	}
+	var v struct {
+		Servers []server `json:"servers"`
+	}
+	v.Servers = []server{}
+	ctx, err := m.withHeaders(ctx, channelzGetServersRPC)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client := m.env().client
+	var start int64
+	for {
+		var res channelzpb.GetServersResponse
+		req := &channelzpb.GetServersRequest{StartServerId: start}
+		if _, _, err := client.Invoke(ctx, channelzGetServersRPC, req, &res); err != nil {
+			return "", errors.Wrap(err, "failed to get servers")
+		}
+		for _, s := range res.GetServer() {
+			d := s.GetData()
+			listenSockets := make([]string, 0, len(s.GetListenSocket()))
+			for _, ref := range s.GetListenSocket() {
+				listenSockets = append(listenSockets, ref.GetName())
+			}
+			v.Servers = append(v.Servers, server{
+				ID:             s.GetRef().GetServerId(),
+				ListenSockets:  strings.Join(listenSockets, ", "),
+				CallsStarted:   d.GetCallsStarted(),
+				CallsSucceeded: d.GetCallsSucceeded(),
+				CallsFailed:    d.GetCallsFailed(),
+				LastCall:       formatChannelzTimestamp(d.GetLastCallStartedTimestamp()),
+			})
+			start = s.GetRef().GetServerId() + 1
+		}
+		if res.GetEnd() || len(res.GetServer()) == 0 {
+			break
+		}
+	}
+	out, err := m.resourcePresenter.Format(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format servers by presenter")
+	}
+	return out, nil
+}
+
+// FormatChannelzSockets formats the summary of sockets the server accepted by grpc.channelz.v1.Channelz.
+// serverID is an ID of servers listed by FormatChannelzServers.
+func FormatChannelzSockets(ctx context.Context, serverID int64) (string, error) {
+	return dm.FormatChannelzSockets(ctx, serverID)
+}
+func (m *dependencyManager) FormatChannelzSockets(ctx context.Context, serverID int64) (string, error) {
+	type socket struct {
+		ID               int64  `json:"id" table:"id"`
+		Local            string `json:"local" table:"local"`
+		Remote           string `json:"remote" table:"remote"`
+		StreamsStarted   int64  `json:"streamsStarted" table:"streams started"`
+		StreamsSucceeded int64  `json:"streamsSucceeded" table:"streams succeeded"`
+		StreamsFailed    int64  `json:"streamsFailed" table:"streams failed"`
+		MessagesSent     int64  `json:"messagesSent" table:"messages sent"`
+		MessagesReceived int64  `json:"messagesReceived" table:"messages received"`
+		LastMessage      string `json:"lastMessage" table:"last message"`
+	}
+	var v struct {
+		Sockets []socket `json:"sockets"`
+	}
+	v.Sockets = []socket{}
+	ctx, err := m.withHeaders(ctx, channelzGetServerSocketsRPC)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client := m.env().client
+	var (
+		start int64
+		refs  []*channelzpb.SocketRef
+	)
+	for {
+		var res channelzpb.GetServerSocketsResponse
+		req := &channelzpb.GetServerSocketsRequest{ServerId: serverID, StartSocketId: start}
+		if _, _, err := client.Invoke(ctx, channelzGetServerSocketsRPC, req, &res); err != nil {
+			return "", errors.Wrapf(err, "failed to get sockets of server %d", serverID)
+		}
+		refs = append(refs, res.GetSocketRef()...)
+		if n := len(res.GetSocketRef()); n != 0 {
+			start = res.GetSocketRef()[n-1].GetSocketId() + 1
+		}
+		if res.GetEnd() || len(res.GetSocketRef()) == 0 {
+			break
+		}
+	}
+	for _, ref := range refs {
+		var res channelzpb.GetSocketResponse
+		req := &channelzpb.GetSocketRequest{SocketId: ref.GetSocketId()}
+		_, _, err := client.Invoke(ctx, channelzGetSocketRPC, req, &res)
+		if status.Code(errors.Cause(err)) == codes.NotFound {
+			// The socket has been closed after listing.
+			continue
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get socket %d", ref.GetSocketId())
+		}
+		s, d := res.GetSocket(), res.GetSocket().GetData()
+		lastMessage := d.GetLastMessageReceivedTimestamp()
+		if t := d.GetLastMessageSentTimestamp(); lastMessage == nil || (t != nil && timestampAfter(t, lastMessage)) {
+			lastMessage = t
+		}
+		v.Sockets = append(v.Sockets, socket{
+			ID:               ref.GetSocketId(),
+			Local:            formatChannelzAddress(s.GetLocal()),
+			Remote:           formatChannelzAddress(s.GetRemote()),
+			StreamsStarted:   d.GetStreamsStarted(),
+			StreamsSucceeded: d.GetStreamsSucceeded(),
+			StreamsFailed:    d.GetStreamsFailed(),
+			MessagesSent:     d.GetMessagesSent(),
+			MessagesReceived: d.GetMessagesReceived(),
+			LastMessage:      formatChannelzTimestamp(lastMessage),
+		})
+	}
+	out, err := m.resourcePresenter.Format(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format sockets by presenter")
+	}
+	return out, nil
+}
+
+// formatChannelzAddress formats a as host:port, a file name of the Unix domain socket or the name of other addresses.
+func formatChannelzAddress(a *channelzpb.Address) string {
+	switch {
+	case a.GetTcpipAddress() != nil:
+		ip := net.IP(a.GetTcpipAddress().GetIpAddress())
+		return net.JoinHostPort(ip.String(), strconv.Itoa(int(a.GetTcpipAddress().GetPort())))
+	case a.GetUdsAddress() != nil:
+		return a.GetUdsAddress().GetFilename()
+	case a.GetOtherAddress() != nil:
+		return a.GetOtherAddress().GetName()
+	default:
+		return "-"
+	}
+}
+
+// formatChannelzTimestamp formats ts as RFC 3339. It returns "-" if ts is nil, which means the event never occurred.
+func formatChannelzTimestamp(ts *timestamp.Timestamp) string {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func timestampAfter(a, b *timestamp.Timestamp) bool {
+	return a.GetSeconds() > b.GetSeconds() || (a.GetSeconds() == b.GetSeconds() && a.GetNanos() > b.GetNanos())
+}
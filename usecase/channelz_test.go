@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/ktr0731/evans/grpc"
+	presentjson "github.com/ktr0731/evans/present/json"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/channelz/service"
+)
+
+func TestFormatChannelz(t *testing.T) {
+	defer Clear()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	srv := gogrpc.NewServer()
+	service.RegisterChannelzServiceToServer(srv)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	addr := l.Addr().String()
+	client, err := grpc.NewClient(addr, "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	defer client.Close(context.Background())
+	Inject(Dependencies{GRPCClient: client, ResourcePresenter: presentjson.NewPresenter("")})
+
+	ctx := context.Background()
+	out, err := FormatChannelzChannels(ctx)
+	if err != nil {
+		t.Fatalf("FormatChannelzChannels must not return an error, but got '%s'", err)
+	}
+	var channels struct {
+		Channels []struct {
+			Target       string `json:"target"`
+			CallsStarted int64  `json:"callsStarted"`
+		} `json:"channels"`
+	}
+	if err := json.Unmarshal([]byte(out), &channels); err != nil {
+		t.Fatalf("failed to unmarshal channels: %s", err)
+	}
+	var found bool
+	for _, c := range channels.Channels {
+		if c.Target == addr && c.CallsStarted > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the channel of the client must be listed, but got '%s'", out)
+	}
+
+	out, err = FormatChannelzServers(ctx)
+	if err != nil {
+		t.Fatalf("FormatChannelzServers must not return an error, but got '%s'", err)
+	}
+	var servers struct {
+		Servers []struct {
+			ID            int64  `json:"id"`
+			ListenSockets string `json:"listenSockets"`
+		} `json:"servers"`
+	}
+	if err := json.Unmarshal([]byte(out), &servers); err != nil {
+		t.Fatalf("failed to unmarshal servers: %s", err)
+	}
+	var serverID int64
+	for _, s := range servers.Servers {
+		if s.ListenSockets == addr {
+			serverID = s.ID
+		}
+	}
+	if serverID == 0 {
+		t.Fatalf("the server listening %s must be listed, but got '%s'", addr, out)
+	}
+
+	out, err = FormatChannelzSockets(ctx, serverID)
+	if err != nil {
+		t.Fatalf("FormatChannelzSockets must not return an error, but got '%s'", err)
+	}
+	var sockets struct {
+		Sockets []struct {
+			Local          string `json:"local"`
+			StreamsStarted int64  `json:"streamsStarted"`
+		} `json:"sockets"`
+	}
+	if err := json.Unmarshal([]byte(out), &sockets); err != nil {
+		t.Fatalf("failed to unmarshal sockets: %s", err)
+	}
+	if len(sockets.Sockets) != 1 || sockets.Sockets[0].Local != addr || sockets.Sockets[0].StreamsStarted == 0 {
+		t.Errorf("the socket accepted from the client must be listed, but got '%s'", out)
+	}
+}
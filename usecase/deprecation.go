@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl/proto"
+)
+
+// OnDeprecatedUsage registers f which is called with a warning when a deprecated service is selected, a deprecated RPC
+// is called or a request which sets deprecated fields is sent. RPCs are warned before their requests are inputted.
+// If f returns an error, the selection or the RPC is aborted with the error.
+func OnDeprecatedUsage(f func(warning string) error) {
+	dm.OnDeprecatedUsage(f)
+}
+func (m *dependencyManager) OnDeprecatedUsage(f func(warning string) error) {
+	m.observers.deprecated = append(m.observers.deprecated, f)
+}
+
+// notifyDeprecatedUsage calls observers of deprecated usages with warning.
+func (m *dependencyManager) notifyDeprecatedUsage(warning string) error {
+	for _, f := range m.observers.deprecated {
+		if err := f(warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDeprecatedService notifies observers if the selected service fqsn is deprecated.
+func (m *dependencyManager) checkDeprecatedService(fqsn string) error {
+	var deprecated bool
+	m.mu.Lock()
+	if len(m.observers.deprecated) != 0 {
+		d, err := m.spec.ResolveSymbol(fqsn)
+		deprecated = err == nil && proto.IsDeprecatedService(d)
+	}
+	m.state.warnedService = ""
+	if deprecated {
+		m.state.warnedService = fqsn
+	}
+	m.mu.Unlock()
+	if !deprecated {
+		return nil
+	}
+	return m.notifyDeprecatedUsage(fmt.Sprintf("service '%s' is deprecated", fqsn))
+}
+
+// checkDeprecatedRPC notifies observers if rpc or its service is deprecated. If only the service is deprecated and it
+// is the service selected in e which is already warned at the selection, it is not warned again.
+func (m *dependencyManager) checkDeprecatedRPC(e env, rpc *grpc.RPC) error {
+	if rpc.Deprecated {
+		return m.notifyDeprecatedUsage(fmt.Sprintf("RPC '%s' is deprecated", rpc.FullyQualifiedName))
+	}
+	if !rpc.ServiceDeprecated {
+		return nil
+	}
+	fqsn := strings.TrimSuffix(rpc.FullyQualifiedName, "."+rpc.Name)
+	selected := proto.FullyQualifiedServiceName(e.pkg, e.svc)
+	m.mu.RLock()
+	warned := m.state.warnedService
+	m.mu.RUnlock()
+	if fqsn == warned && fqsn == selected {
+		return nil
+	}
+	return m.notifyDeprecatedUsage(fmt.Sprintf("service '%s' is deprecated", fqsn))
+}
+
+// checkDeprecatedFields notifies observers of deprecated fields set in req. Fields in warned are skipped so that
+// each field is warned only once for the requests of a stream. Warned fields are added to warned.
+func (m *dependencyManager) checkDeprecatedFields(req interface{}, warned map[string]bool) error {
+	for _, name := range proto.DeprecatedFields(req) {
+		if warned[name] {
+			continue
+		}
+		warned[name] = true
+		if err := m.notifyDeprecatedUsage(fmt.Sprintf("field '%s' is deprecated", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"github.com/pkg/errors"
+)
+
+func TestDeprecatedUsage(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto3";
+package api;
+message Request {
+  string name = 1 [deprecated = true];
+  string nickname = 2;
+  repeated Request children = 3;
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+	md := fds[0].FindMessage("api.Request")
+
+	m := &dependencyManager{}
+	var warnings []string
+	m.OnDeprecatedUsage(func(warning string) error {
+		warnings = append(warnings, warning)
+		return nil
+	})
+
+	if err := m.checkDeprecatedRPC(m.env(), &grpc.RPC{FullyQualifiedName: "api.Example.Unary"}); err != nil {
+		t.Fatalf("checkDeprecatedRPC must not return an error, but got '%s'", err)
+	}
+	if err := m.checkDeprecatedRPC(m.env(), &grpc.RPC{FullyQualifiedName: "api.Example.Old", Deprecated: true}); err != nil {
+		t.Fatalf("checkDeprecatedRPC must not return an error, but got '%s'", err)
+	}
+
+	child := dynamic.NewMessage(md)
+	child.SetFieldByName("name", "kumiko")
+	req := dynamic.NewMessage(md)
+	req.SetFieldByName("nickname", "kumiko")
+	req.SetFieldByName("children", []*dynamic.Message{child})
+	warned := map[string]bool{}
+	// Each field is warned only once for the requests of the same call.
+	for i := 0; i < 2; i++ {
+		if err := m.checkDeprecatedFields(req, warned); err != nil {
+			t.Fatalf("checkDeprecatedFields must not return an error, but got '%s'", err)
+		}
+	}
+
+	expected := []string{
+		"RPC 'api.Example.Old' is deprecated",
+		"field 'api.Request.name' is deprecated",
+	}
+	if diff := cmp.Diff(expected, warnings); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	denied := errors.New("denied")
+	m.OnDeprecatedUsage(func(string) error { return denied })
+	if err := m.checkDeprecatedFields(child, map[string]bool{}); !errors.Is(err, denied) {
+		t.Errorf("checkDeprecatedFields must return the error of the observer, but got '%v'", err)
+	}
+}
+
+// symbolSpec resolves symbols from fd in addition to rpcSpec.
+type symbolSpec struct {
+	*rpcSpec
+	fd *desc.FileDescriptor
+}
+
+func (s *symbolSpec) ResolveSymbol(symbol string) (interface{}, error) {
+	d := s.fd.FindSymbol(symbol)
+	if d == nil {
+		return nil, idl.ErrUnknownSymbol
+	}
+	return d, nil
+}
+
+func TestDeprecatedService(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"api.proto": `
+syntax = "proto3";
+package api;
+message Empty {}
+service Old {
+  option deprecated = true;
+  rpc Get(Empty) returns (Empty);
+}
+service Example {
+  rpc Get(Empty) returns (Empty);
+}`,
+		}),
+	}
+	fds, err := p.ParseFiles("api.proto")
+	if err != nil {
+		t.Fatalf("ParseFiles must not return an error, but got '%s'", err)
+	}
+
+	m := &dependencyManager{
+		spec: &symbolSpec{
+			rpcSpec: &rpcSpec{rpcs: map[string][]string{"api.Old": {"Get"}, "api.Example": {"Get"}}},
+			fd:      fds[0],
+		},
+		state: defaultState,
+	}
+	var warnings []string
+	m.OnDeprecatedUsage(func(warning string) error {
+		warnings = append(warnings, warning)
+		return nil
+	})
+
+	oldRPC := &grpc.RPC{Name: "Get", FullyQualifiedName: "api.Old.Get", ServiceDeprecated: true}
+	if err := m.UseService("api.Old"); err != nil {
+		t.Fatalf("UseService must not return an error, but got '%s'", err)
+	}
+	// The service is already warned at the selection.
+	if err := m.checkDeprecatedRPC(m.env(), oldRPC); err != nil {
+		t.Fatalf("checkDeprecatedRPC must not return an error, but got '%s'", err)
+	}
+	if err := m.UseService("api.Example"); err != nil {
+		t.Fatalf("UseService must not return an error, but got '%s'", err)
+	}
+	if err := m.checkDeprecatedRPC(m.env(), oldRPC); err != nil {
+		t.Fatalf("checkDeprecatedRPC must not return an error, but got '%s'", err)
+	}
+
+	expected := []string{
+		"service 'api.Old' is deprecated",
+		"service 'api.Old' is deprecated",
+	}
+	if diff := cmp.Diff(expected, warnings); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of names suggested by unknownNameError.
+const maxSuggestions = 3
+
+// unknownNameError is returned when a package, service, RPC or type named name is not found.
+// It has names similar to name as suggestions. err is one of the errors defined in idl package
+// such that idl.ErrUnknownServiceName, so errors.Is and errors.Cause work the same as err.
+type unknownNameError struct {
+	err         error
+	name        string
+	suggestions []string
+}
+
+func newUnknownNameError(err error, name string, candidates []string) error {
+	return &unknownNameError{err: err, name: name, suggestions: suggestNames(name, candidates)}
+}
+
+func (e *unknownNameError) Error() string {
+	msg := fmt.Sprintf("%s '%s'", e.err, e.name)
+	if len(e.suggestions) == 0 {
+		return msg
+	}
+	quoted := make([]string, 0, len(e.suggestions))
+	for _, s := range e.suggestions {
+		quoted = append(quoted, fmt.Sprintf("'%s'", s))
+	}
+	return fmt.Sprintf("%s, did you mean %s?", msg, strings.Join(quoted, " or "))
+}
+
+func (e *unknownNameError) Cause() error  { return e.err }
+func (e *unknownNameError) Unwrap() error { return e.err }
+
+// suggestNames returns the candidates most similar to name in ascending order.
+// Names are compared case-insensitively, and candidates which need too many edits are ignored.
+func suggestNames(name string, candidates []string) []string {
+	type suggestion struct {
+		name string
+		dist int
+	}
+	lname := strings.ToLower(name)
+	// Allow about one typo per three characters.
+	threshold := len(lname)/3 + 1
+	var s []suggestion
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := editDistance(lname, strings.ToLower(c)); d <= threshold {
+			s = append(s, suggestion{name: c, dist: d})
+		}
+	}
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].dist != s[j].dist {
+			return s[i].dist < s[j].dist
+		}
+		return s[i].name < s[j].name
+	})
+	names := make([]string, 0, len(s))
+	for _, v := range s {
+		if v.dist != s[0].dist || len(names) == maxSuggestions {
+			break
+		}
+		names = append(names, v.name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
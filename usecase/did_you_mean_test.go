@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/idl"
+	"github.com/pkg/errors"
+)
+
+func TestSuggestNames(t *testing.T) {
+	candidates := []string{"Unary", "ClientStreaming", "ServerStreaming", "BidiStreaming", "UnaryEnum", "UnaryMessage"}
+	cases := map[string]struct {
+		name       string
+		candidates []string
+		expected   []string
+	}{
+		"typo":            {name: "Unray", expected: []string{"Unary"}},
+		"case difference": {name: "serverstreaming", expected: []string{"ServerStreaming"}},
+		"closest only":    {name: "ClientStreamin", expected: []string{"ClientStreaming"}},
+		"ties": {
+			name:       "api",
+			candidates: []string{"apix", "apic", "apiz", "apia"},
+			expected:   []string{"apia", "apic", "apix"},
+		},
+		"too different": {name: "Health", expected: []string{}},
+		"empty":         {name: "", expected: []string{}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			cs := candidates
+			if c.candidates != nil {
+				cs = c.candidates
+			}
+			if diff := cmp.Diff(c.expected, suggestNames(c.name, cs)); diff != "" {
+				t.Errorf("(-want, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnknownNameError(t *testing.T) {
+	err := errors.Wrap(newUnknownNameError(idl.ErrUnknownServiceName, "Exmaple", []string{"Example", "Other"}), "failed")
+	if expected := "failed: unknown service name 'Exmaple', did you mean 'Example'?"; err.Error() != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, err.Error())
+	}
+	if !errors.Is(err, idl.ErrUnknownServiceName) {
+		t.Errorf("errors.Is must report the error is idl.ErrUnknownServiceName")
+	}
+	if errors.Cause(err) != idl.ErrUnknownServiceName {
+		t.Errorf("errors.Cause must return idl.ErrUnknownServiceName, but got '%s'", errors.Cause(err))
+	}
+
+	err = newUnknownNameError(idl.ErrUnknownPackageName, "foo", []string{"api"})
+	if expected := "unknown package name 'foo'"; err.Error() != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, err.Error())
+	}
+}
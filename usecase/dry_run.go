@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/ktr0731/evans/fill"
+	"github.com/pkg/errors"
+)
+
+// DryRunRPC constructs requests of the RPC with the filler the same as CallRPC, but it never sends them.
+// Instead, each request is written to w as JSON followed by its size in the wire format.
+// Streaming RPCs which accept two or more requests are constructed until the filler returns io.EOF.
+func DryRunRPC(w io.Writer, rpcName string) error {
+	return dm.DryRunRPC(w, rpcName, dm.filler)
+}
+func (m *dependencyManager) DryRunRPC(w io.Writer, rpcName string, filler fill.Filler) error {
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return err
+	}
+	for n := 0; n == 0 || rpc.IsClientStreaming; n++ {
+		req, err := rpc.RequestType.New()
+		if err != nil {
+			return errors.Wrapf(err, "failed to instantiate an instance of the request type '%s'", rpc.RequestType.FullyQualifiedName)
+		}
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return errors.Errorf("the request type '%s' is not a Protocol Buffers message", rpc.RequestType.FullyQualifiedName)
+		}
+		if rf, ok := filler.(fill.RawFiller); ok {
+			var b []byte
+			b, err = rf.FillRaw(rpc.IsClientStreaming)
+			if err == nil {
+				err = errors.Wrap(proto.Unmarshal(b, msg), "failed to unmarshal the serialized request")
+			}
+		} else {
+			err = filler.Fill(req)
+		}
+		if errors.Is(err, io.EOF) {
+			if n == 0 {
+				return errors.New("no request found")
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid request '%s'", rpc.RequestType.FullyQualifiedName)
+		}
+
+		out, err := formatRequests(rpc, []interface{}{req}, m.state.protoNames)
+		if err != nil {
+			return err
+		}
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal the request")
+		}
+		fmt.Fprintf(w, "%swire size: %d bytes\n", out, len(b))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import "reflect"
+
+// observers is callbacks called when the state is changed.
+type observers struct {
+	pkg    []func(pkg string)
+	svc    []func(svc string)
+	header []func()
+	// deprecated is called when deprecated RPCs or fields are used. It is not a change of the state, but it is
+	// registered in the same way.
+	deprecated []func(warning string) error
+}
+
+// OnPackageChange registers f which is called with the new package name when the selected package is changed.
+// f is also called when the profile is switched because it replaces the connection.
+// Changes are detected by RecordChange, Undo and Redo.
+func OnPackageChange(f func(pkg string)) {
+	dm.OnPackageChange(f)
+}
+func (m *dependencyManager) OnPackageChange(f func(pkg string)) {
+	m.observers.pkg = append(m.observers.pkg, f)
+}
+
+// OnServiceChange registers f which is called with the new service name when the selected service is changed.
+// f is also called when the profile is switched because it replaces the connection.
+// Changes are detected by RecordChange, Undo and Redo.
+func OnServiceChange(f func(svc string)) {
+	dm.OnServiceChange(f)
+}
+func (m *dependencyManager) OnServiceChange(f func(svc string)) {
+	m.observers.svc = append(m.observers.svc, f)
+}
+
+// OnHeaderChange registers f which is called when headers or scoped headers are changed.
+// Changes are detected by RecordChange, Undo and Redo.
+func OnHeaderChange(f func()) {
+	dm.OnHeaderChange(f)
+}
+func (m *dependencyManager) OnHeaderChange(f func()) {
+	m.observers.header = append(m.observers.header, f)
+}
+
+// notifyChanges calls observers of the parts which differ between before and after.
+func (m *dependencyManager) notifyChanges(before, after *snapshot) {
+	switched := before.profile != after.profile
+	if switched || before.pkg != after.pkg {
+		for _, f := range m.observers.pkg {
+			f(after.pkg)
+		}
+	}
+	if switched || before.svc != after.svc {
+		for _, f := range m.observers.svc {
+			f(after.svc)
+		}
+	}
+	if !reflect.DeepEqual(before.header, after.header) || !reflect.DeepEqual(before.scopedHeaders, after.scopedHeaders) {
+		for _, f := range m.observers.header {
+			f()
+		}
+	}
+}
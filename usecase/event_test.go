@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/ktr0731/evans/grpc"
+)
+
+func TestObservers(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{GRPCClient: client})
+
+	var (
+		pkgs, svcs []string
+		headers    int
+	)
+	OnPackageChange(func(pkg string) { pkgs = append(pkgs, pkg) })
+	OnServiceChange(func(svc string) { svcs = append(svcs, svc) })
+	OnHeaderChange(func() { headers++ })
+
+	_ = RecordChange(func() error {
+		dm.state.selectedPackage, dm.state.selectedService = "api", "Example"
+		return nil
+	})
+	_ = RecordChange(func() error { AddScopedHeader("api.Example", "x-route", "example"); return nil })
+	_ = RecordChange(func() error { return SetVariable("name", "kumiko") })
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo must not return an error, but got '%s'", err)
+	}
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo must not return an error, but got '%s'", err)
+	}
+
+	if len(pkgs) != 1 || pkgs[0] != "api" {
+		t.Errorf("observers of packages must be called once with 'api', but got %v", pkgs)
+	}
+	if len(svcs) != 1 || svcs[0] != "Example" {
+		t.Errorf("observers of services must be called once with 'Example', but got %v", svcs)
+	}
+	if headers != 2 {
+		t.Errorf("observers of headers must be called twice, but called %d times", headers)
+	}
+}
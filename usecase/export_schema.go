@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ExportFiles returns IDL files which define the passed services and their dependencies.
+// The key is a file name and the value is the file content. If svcNames is empty, all loaded files are returned.
+func ExportFiles(svcNames []string) (map[string]string, error) {
+	return dm.ExportFiles(svcNames)
+}
+func (m *dependencyManager) ExportFiles(svcNames []string) (map[string]string, error) {
+	files, err := m.env().spec.ExportFiles(svcNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export files")
+	}
+	return files, nil
+}
+
+// ExportDescriptorSet returns the serialized descriptor set which contains the same files as ExportFiles.
+func ExportDescriptorSet(svcNames []string) ([]byte, error) {
+	return dm.ExportDescriptorSet(svcNames)
+}
+func (m *dependencyManager) ExportDescriptorSet(svcNames []string) ([]byte, error) {
+	b, err := m.env().spec.ExportDescriptorSet(svcNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export descriptor set")
+	}
+	return b, nil
+}
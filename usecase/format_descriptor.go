@@ -1,8 +1,13 @@
 package usecase
 
 import (
+	"bytes"
+	gojson "encoding/json"
 	"fmt"
 
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/ktr0731/evans/idl"
 	"github.com/pkg/errors"
 )
 
@@ -11,13 +16,48 @@ func FormatDescriptor(symbol string) (string, error) {
 	return dm.FormatDescriptor(symbol)
 }
 func (m *dependencyManager) FormatDescriptor(symbol string) (string, error) {
-	v, err := m.spec.ResolveSymbol(symbol)
+	spec := m.env().spec
+	v, err := spec.ResolveSymbol(symbol)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to resolve symbol '%s'", symbol)
 	}
-	out, err := m.spec.FormatDescriptor(v)
+	out, err := spec.FormatDescriptor(v)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to format the descriptor of symbol '%s'", symbol)
 	}
 	return fmt.Sprintf("%s:\n%s", symbol, out), nil
 }
+
+// FormatDescriptorJSON formats the descriptor of the passed symbol as JSON. The output is the JSON representation
+// of the descriptor proto such that google.protobuf.DescriptorProto, so that it contains all information of the symbol.
+func FormatDescriptorJSON(symbol string) (string, error) {
+	return dm.FormatDescriptorJSON(symbol)
+}
+func (m *dependencyManager) FormatDescriptorJSON(symbol string) (string, error) {
+	b, err := descriptorJSON(m.env().spec, symbol)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := gojson.Indent(&out, b, "", "  "); err != nil {
+		return "", errors.Wrap(err, "failed to indent JSON")
+	}
+	return out.String(), nil
+}
+
+func descriptorJSON(spec idl.Spec, symbol string) ([]byte, error) {
+	v, err := spec.ResolveSymbol(symbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve symbol '%s'", symbol)
+	}
+	d, ok := v.(interface{ AsProto() proto.Message })
+	if !ok {
+		return nil, errors.Errorf("the descriptor of symbol '%s' cannot be formatted as JSON", symbol)
+	}
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	var b bytes.Buffer
+	if err := marshaler.Marshal(&b, d.AsProto()); err != nil {
+		return nil, errors.Wrapf(err, "failed to format the descriptor of symbol '%s' as JSON", symbol)
+	}
+	return b.Bytes(), nil
+}
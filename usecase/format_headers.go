@@ -6,7 +6,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-// FormatHeaders formats all package names.
+// FormatHeaders formats all headers including scoped ones.
 func FormatHeaders() (string, error) {
 	return dm.FormatHeaders()
 }
@@ -15,18 +15,50 @@ func (m *dependencyManager) FormatHeaders() (string, error) {
 		Key string `json:"key"`
 		Val string `json:"val"`
 	}
-	var s struct {
-		Headers []header `json:"headers"`
+	type scopedHeader struct {
+		Scope string `json:"scope"`
+		Key   string `json:"key"`
+		Val   string `json:"val"`
 	}
-	headers := m.ListHeaders()
-	for k, v := range headers {
+	m.mu.RLock()
+	var headers []scopedHeader
+	for k, v := range m.headers() {
 		for _, vv := range v {
-			s.Headers = append(s.Headers, header{k, vv})
+			headers = append(headers, scopedHeader{Key: k, Val: vv})
 		}
 	}
-	sort.Slice(s.Headers, func(i, j int) bool {
-		return s.Headers[i].Key < s.Headers[j].Key
+	var scoped bool
+	for scope, h := range m.state.scopedHeaders {
+		for k, v := range h {
+			for _, vv := range v {
+				headers = append(headers, scopedHeader{Scope: scope, Key: k, Val: vv})
+				scoped = true
+			}
+		}
+	}
+	m.mu.RUnlock()
+	sort.SliceStable(headers, func(i, j int) bool {
+		if headers[i].Scope != headers[j].Scope {
+			return headers[i].Scope < headers[j].Scope
+		}
+		return headers[i].Key < headers[j].Key
 	})
+
+	// The scope column is shown only if there are scoped headers.
+	var s interface{}
+	if scoped {
+		s = struct {
+			Headers []scopedHeader `json:"headers"`
+		}{headers}
+	} else {
+		plain := struct {
+			Headers []header `json:"headers"`
+		}{}
+		for _, h := range headers {
+			plain.Headers = append(plain.Headers, header{h.Key, h.Val})
+		}
+		s = plain
+	}
 	out, err := m.resourcePresenter.Format(s)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to format header names by presenter")
@@ -3,6 +3,7 @@ package usecase
 import (
 	"sort"
 
+	"github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
 
@@ -11,7 +12,8 @@ func FormatMessages() (string, error) {
 	return dm.FormatMessages()
 }
 func (m *dependencyManager) FormatMessages() (string, error) {
-	svcs := m.ListServicesOld()
+	e := m.env()
+	svcs := m.listServicesOld(e.spec, e.pkg)
 	type message struct {
 		Message string `json:"message"`
 	}
@@ -20,7 +22,7 @@ func (m *dependencyManager) FormatMessages() (string, error) {
 	}
 	encountered := make(map[string]struct{})
 	for _, svc := range svcs {
-		rpcs, err := m.ListRPCs(svc)
+		rpcs, err := listRPCs(e.spec, proto.FullyQualifiedServiceName(e.pkg, svc))
 		if err != nil {
 			return "", errors.Wrap(err, "failed to list RPCs")
 		}
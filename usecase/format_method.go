@@ -9,11 +9,13 @@ func FormatMethod(fqmn string) (string, error) {
 	return dm.FormatMethod(fqmn)
 }
 func (m *dependencyManager) FormatMethod(fqmn string) (string, error) {
-	fqsn, _, err := ParseFullyQualifiedMethodName(fqmn)
+	spec := m.env().spec
+	fqsn, mtd, err := parseFullyQualifiedMethodName(spec, fqmn)
 	if err != nil {
 		return "", err
 	}
-	v, err := m.methodsToFormatStructs(fqsn)
+	fqmn = fqsn + "." + mtd
+	v, err := methodsToFormatStructs(spec, fqsn)
 	if err != nil {
 		return "", err
 	}
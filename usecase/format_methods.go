@@ -3,6 +3,7 @@ package usecase
 import (
 	"sort"
 
+	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
@@ -12,8 +13,9 @@ func FormatMethods() (string, error) {
 	return dm.FormatMethods()
 }
 func (m *dependencyManager) FormatMethods() (string, error) {
-	fqsn := proto.FullyQualifiedServiceName(m.state.selectedPackage, m.state.selectedService)
-	v, err := m.methodsToFormatStructs(fqsn)
+	e := m.env()
+	fqsn := proto.FullyQualifiedServiceName(e.pkg, e.svc)
+	v, err := methodsToFormatStructs(e.spec, fqsn)
 	if err != nil {
 		return "", err
 	}
@@ -24,7 +26,7 @@ func (m *dependencyManager) FormatMethods() (string, error) {
 	return out, nil
 }
 
-func (m *dependencyManager) methodsToFormatStructs(fqsn string) (v struct {
+func methodsToFormatStructs(spec idl.Spec, fqsn string) (v struct {
 	Methods []struct {
 		Name               string `json:"name" table:"name"`
 		FullyQualifiedName string `json:"fully_qualified_name" name:"target" table:"fully-qualified name"`
@@ -32,7 +34,7 @@ func (m *dependencyManager) methodsToFormatStructs(fqsn string) (v struct {
 		ResponseType       string `json:"response_type" table:"response type"`
 	} `json:"methods" name:"target"`
 }, _ error) {
-	methods, err := m.listRPCs(fqsn)
+	methods, err := listRPCs(spec, fqsn)
 	if err != nil {
 		return v, errors.Wrapf(err, "failed to list methods associated with '%s'", fqsn)
 	}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/ktr0731/evans/fill"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	idlproto "github.com/ktr0731/evans/idl/proto"
+	"github.com/pkg/errors"
+)
+
+// FormatRequest formats the requests of the last call to the RPC as JSON.
+// If the RPC has never been called, FormatRequest formats a request which has default values.
+// Two or more requests of streaming RPCs are formatted as a JSON array.
+func FormatRequest(rpcName string) (string, error) {
+	return dm.FormatRequest(rpcName)
+}
+func (m *dependencyManager) FormatRequest(rpcName string) (string, error) {
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return "", err
+	}
+	reqs, protoNames := m.lastRequestsOf(rpc.FullyQualifiedName)
+	if len(reqs) == 0 {
+		req, err := rpc.RequestType.New()
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to instantiate an instance of the request type '%s'", rpc.RequestType.FullyQualifiedName)
+		}
+		reqs = []interface{}{req}
+	}
+	return formatRequests(rpc, reqs, protoNames)
+}
+
+// SetProtoNames sets whether formatted requests use original field names in the proto file instead of
+// lowerCamelCase JSON names.
+func SetProtoNames(b bool) {
+	dm.SetProtoNames(b)
+}
+func (m *dependencyManager) SetProtoNames(b bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.protoNames = b
+}
+
+func formatRequests(rpc *grpc.RPC, reqs []interface{}, protoNames bool) (string, error) {
+	marshaler := &jsonpb.Marshaler{EmitDefaults: true, Indent: "  ", OrigName: protoNames}
+	s := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return "", errors.Errorf("the request type '%s' is not a Protocol Buffers message", rpc.RequestType.FullyQualifiedName)
+		}
+		var buf bytes.Buffer
+		if err := marshaler.Marshal(&buf, msg); err != nil {
+			return "", errors.Wrap(err, "failed to marshal the request")
+		}
+		s = append(s, buf.String())
+	}
+	if len(s) == 1 {
+		return s[0] + "\n", nil
+	}
+	return "[\n" + strings.Join(s, ",\n") + "\n]\n", nil
+}
+
+// ValidateRequest validates whether in is valid requests of the RPC without sending them.
+// The format of in is the same as fill.SilentFiller.
+func ValidateRequest(rpcName string, in io.Reader) error {
+	return dm.ValidateRequest(rpcName, in)
+}
+func (m *dependencyManager) ValidateRequest(rpcName string, in io.Reader) error {
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return err
+	}
+	filler := fill.NewSilentFiller(in)
+	for n := 0; ; n++ {
+		req, err := rpc.RequestType.New()
+		if err != nil {
+			return errors.Wrapf(err, "failed to instantiate an instance of the request type '%s'", rpc.RequestType.FullyQualifiedName)
+		}
+		err = filler.Fill(req)
+		if errors.Is(err, io.EOF) {
+			if n == 0 {
+				return errors.New("no request found")
+			}
+			return nil
+		}
+		if errors.Is(err, fill.ErrCodecMismatch) {
+			return errors.New("the request must be formatted as JSON")
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid request '%s'", rpc.RequestType.FullyQualifiedName)
+		}
+		if n > 0 && !rpc.IsClientStreaming {
+			return errors.Errorf("RPC '%s' accepts only one request", rpc.Name)
+		}
+	}
+}
+
+// getRPC returns the RPC which belongs to the service selected in e.
+func getRPC(e env, rpcName string) (*grpc.RPC, error) {
+	fqsn := idlproto.FullyQualifiedServiceName(e.pkg, e.svc)
+	name := normalizeMethodName(rpcName)
+	if i := strings.LastIndex(name, "."); i != -1 {
+		fqsn, name = resolveServiceName(e.spec, name[:i]), name[i+1:]
+	}
+	rpc, err := e.spec.RPC(fqsn, resolveRPCName(e.spec, fqsn, name))
+	if errors.Is(err, idl.ErrUnknownServiceName) {
+		err = newUnknownNameError(idl.ErrUnknownServiceName, fqsn, e.spec.ServiceNames())
+	}
+	if errors.Is(err, idl.ErrUnknownRPCName) {
+		err = newUnknownNameError(idl.ErrUnknownRPCName, rpcName, rpcNamesLike(e.spec, fqsn, rpcName))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the RPC descriptor")
+	}
+	return rpc, nil
+}
+
+// rpcNamesLike returns names of RPCs in spec which belong to the service fqsn. If rpcName is fully-qualified,
+// it returns fully-qualified names of all RPCs instead.
+func rpcNamesLike(spec idl.Spec, fqsn, rpcName string) []string {
+	var names []string
+	if !strings.Contains(rpcName, ".") {
+		rpcs, _ := spec.RPCs(fqsn)
+		for _, rpc := range rpcs {
+			names = append(names, rpc.Name)
+		}
+		return names
+	}
+	for _, svc := range spec.ServiceNames() {
+		rpcs, _ := spec.RPCs(svc)
+		for _, rpc := range rpcs {
+			names = append(names, rpc.FullyQualifiedName)
+		}
+	}
+	return names
+}
+
+// getLastRPC returns the last called RPC from spec.
+func (m *dependencyManager) getLastRPC(spec idl.Spec) (*grpc.RPC, error) {
+	m.mu.RLock()
+	lastRPC := m.state.lastRPC
+	m.mu.RUnlock()
+	if lastRPC == "" {
+		return nil, errors.New("no RPCs have been called yet")
+	}
+	fqsn, mtd, err := parseFullyQualifiedMethodName(spec, lastRPC)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the last called RPC name")
+	}
+	rpc, err := spec.RPC(fqsn, mtd)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the RPC descriptor")
+	}
+	return rpc, nil
+}
+
+// lastRequestsOf returns the requests of the last call to fqrn, and whether they are formatted with original field names.
+func (m *dependencyManager) lastRequestsOf(fqrn string) ([]interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.lastRequests[fqrn], m.state.protoNames
+}
+
+// recordRequests records reqs as the last requests of fqrn. m.mu must be held.
+func (m *dependencyManager) recordRequests(fqrn string, reqs []interface{}) {
+	if m.state.lastRequests == nil {
+		m.state.lastRequests = make(map[string][]interface{})
+	}
+	m.state.lastRequests[fqrn] = reqs
+	m.state.lastRPC = fqrn
+}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	gojson "encoding/json"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -22,3 +23,26 @@ func (m *dependencyManager) FormatServiceDescriptors() (string, error) {
 	}
 	return strings.Join(out, "\n\n"), nil
 }
+
+// FormatServiceDescriptorsJSON formats all service descriptors the spec loaded as a JSON array.
+// Each element is the same as the output of FormatDescriptorJSON.
+func FormatServiceDescriptorsJSON() (string, error) {
+	return dm.FormatServiceDescriptorsJSON()
+}
+func (m *dependencyManager) FormatServiceDescriptorsJSON() (string, error) {
+	spec := m.env().spec
+	svcs := spec.ServiceNames()
+	descs := make([]gojson.RawMessage, 0, len(svcs))
+	for _, s := range svcs {
+		b, err := descriptorJSON(spec, s)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to format one service descriptor")
+		}
+		descs = append(descs, b)
+	}
+	b, err := gojson.MarshalIndent(descs, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format service descriptors as JSON")
+	}
+	return string(b), nil
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"sort"
 
+	"github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
 
@@ -12,7 +13,8 @@ func FormatServicesOld() (string, error) {
 	return dm.FormatServicesOld()
 }
 func (m *dependencyManager) FormatServicesOld() (string, error) {
-	svcs := m.ListServicesOld()
+	e := m.env()
+	svcs := m.listServicesOld(e.spec, e.pkg)
 	type service struct {
 		Service      string `json:"service"`
 		RPC          string `json:"rpc"`
@@ -23,7 +25,7 @@ func (m *dependencyManager) FormatServicesOld() (string, error) {
 		Services []service `json:"services"`
 	}
 	for _, svc := range svcs {
-		rpcs, err := m.ListRPCs(svc)
+		rpcs, err := listRPCs(e.spec, proto.FullyQualifiedServiceName(e.pkg, svc))
 		if err != nil {
 			return "", errors.Wrapf(err, "failed to list RPCs associated with '%s'", svc)
 		}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl/proto"
+	"github.com/pkg/errors"
+)
+
+// FormatTree formats all packages, services and RPCs as an indented tree. Each RPC has its request and response types.
+// If pattern is not empty, it is a glob pattern such that "api.*" which is matched with fully-qualified names.
+// Only matched packages, services and RPCs are shown with their parents. Children of a matched package or service
+// are also shown.
+func FormatTree(pattern string) (string, error) {
+	return dm.FormatTree(pattern)
+}
+func (m *dependencyManager) FormatTree(pattern string) (string, error) {
+	pkgs, err := m.schema(pattern)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, pkg := range pkgs {
+		var svcNodes []*treeNode
+		for _, svc := range pkg.Services {
+			rpcNodes := make([]*treeNode, 0, len(svc.RPCs))
+			for _, rpc := range svc.RPCs {
+				rpcNodes = append(rpcNodes, &treeNode{name: rpc.signature()})
+			}
+			svcNodes = append(svcNodes, &treeNode{name: svc.Name, children: rpcNodes})
+		}
+		if pkg.Name == "" {
+			// Services which don't belong to any packages are shown at the top level.
+			for _, n := range svcNodes {
+				b.WriteString(n.name + "\n")
+				writeTree(&b, n.children, "")
+			}
+			continue
+		}
+		b.WriteString(pkg.Name + "\n")
+		writeTree(&b, svcNodes, "")
+	}
+	return b.String(), nil
+}
+
+// FormatSchema formats the same packages, services and RPCs as FormatTree by the presenter.
+// It is intended for structured formats such that JSON.
+func FormatSchema(pattern string) (string, error) {
+	return dm.FormatSchema(pattern)
+}
+func (m *dependencyManager) FormatSchema(pattern string) (string, error) {
+	pkgs, err := m.schema(pattern)
+	if err != nil {
+		return "", err
+	}
+	v := struct {
+		Packages []*schemaPackage `json:"packages"`
+	}{Packages: pkgs}
+	out, err := m.resourcePresenter.Format(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to format the schema by presenter")
+	}
+	return out, nil
+}
+
+type schemaPackage struct {
+	Name     string           `json:"name"`
+	Services []*schemaService `json:"services"`
+}
+
+type schemaService struct {
+	Name               string       `json:"name"`
+	FullyQualifiedName string       `json:"fully_qualified_name"`
+	RPCs               []*schemaRPC `json:"rpcs"`
+}
+
+type schemaRPC struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fully_qualified_name"`
+	RequestType        string `json:"request_type"`
+	ResponseType       string `json:"response_type"`
+	IsClientStreaming  bool   `json:"client_streaming"`
+	IsServerStreaming  bool   `json:"server_streaming"`
+}
+
+// signature formats rpc such that the RPC definition of proto files.
+func (rpc *schemaRPC) signature() string {
+	typeName := func(name string, stream bool) string {
+		if stream {
+			return "stream " + name
+		}
+		return name
+	}
+	return fmt.Sprintf("%s(%s) returns (%s)", rpc.Name, typeName(rpc.RequestType, rpc.IsClientStreaming), typeName(rpc.ResponseType, rpc.IsServerStreaming))
+}
+
+// schema returns packages, services and RPCs filtered by pattern in ascending order of names.
+// RPCs are in the order of definitions. See FormatTree for details of pattern.
+func (m *dependencyManager) schema(pattern string) ([]*schemaPackage, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern '%s'", pattern)
+	}
+	match := func(name string) bool {
+		if pattern == "" {
+			return true
+		}
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+
+	spec := m.env().spec
+	svcs := map[string][]string{}
+	for _, fqsn := range spec.ServiceNames() {
+		pkg, _ := proto.ParseFullyQualifiedServiceName(fqsn)
+		svcs[pkg] = append(svcs[pkg], fqsn)
+	}
+	pkgNames := make([]string, 0, len(svcs))
+	for pkg := range svcs {
+		pkgNames = append(pkgNames, pkg)
+	}
+	sort.Strings(pkgNames)
+
+	var pkgs []*schemaPackage
+	for _, pkgName := range pkgNames {
+		pkgMatched := match(pkgName)
+		sort.Strings(svcs[pkgName])
+		pkg := &schemaPackage{Name: pkgName, Services: []*schemaService{}}
+		for _, fqsn := range svcs[pkgName] {
+			svcMatched := pkgMatched || match(fqsn)
+			rpcs, err := listRPCs(spec, fqsn)
+			if err != nil {
+				return nil, err
+			}
+			_, svcName := proto.ParseFullyQualifiedServiceName(fqsn)
+			svc := &schemaService{Name: svcName, FullyQualifiedName: fqsn, RPCs: []*schemaRPC{}}
+			for _, rpc := range rpcs {
+				if svcMatched || match(rpc.FullyQualifiedName) {
+					svc.RPCs = append(svc.RPCs, newSchemaRPC(rpc))
+				}
+			}
+			if svcMatched || len(svc.RPCs) != 0 {
+				pkg.Services = append(pkg.Services, svc)
+			}
+		}
+		if pkgMatched || len(pkg.Services) != 0 {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	if len(pkgs) == 0 {
+		if pattern == "" {
+			return nil, errors.New("no services are loaded")
+		}
+		return nil, errors.Errorf("no packages, services or RPCs match '%s'", pattern)
+	}
+	return pkgs, nil
+}
+
+func newSchemaRPC(rpc *grpc.RPC) *schemaRPC {
+	return &schemaRPC{
+		Name:               rpc.Name,
+		FullyQualifiedName: rpc.FullyQualifiedName,
+		RequestType:        rpc.RequestType.FullyQualifiedName,
+		ResponseType:       rpc.ResponseType.FullyQualifiedName,
+		IsClientStreaming:  rpc.IsClientStreaming,
+		IsServerStreaming:  rpc.IsServerStreaming,
+	}
+}
+
+type treeNode struct {
+	name     string
+	children []*treeNode
+}
+
+// writeTree writes nodes and their children to b. Each line is indented by indent and tree branches.
+func writeTree(b *strings.Builder, nodes []*treeNode, indent string) {
+	for i, n := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		b.WriteString(indent + branch + n.name + "\n")
+		writeTree(b, n.children, indent+next)
+	}
+}
@@ -2,10 +2,13 @@ package usecase
 
 import "strings"
 
+// GetDomainSourceName returns the selected package and service joined by a dot such that "api.Example".
 func GetDomainSourceName() string {
 	return dm.GetDomainSourceName()
 }
 func (m *dependencyManager) GetDomainSourceName() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var s []string
 	if pkg := m.state.selectedPackage; pkg != "" {
 		s = append(s, pkg)
@@ -15,3 +18,23 @@ func (m *dependencyManager) GetDomainSourceName() string {
 	}
 	return strings.Join(s, ".")
 }
+
+// GetSelectedPackage returns the selected package name. It returns an empty string if no packages are selected.
+func GetSelectedPackage() string {
+	return dm.GetSelectedPackage()
+}
+func (m *dependencyManager) GetSelectedPackage() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.selectedPackage
+}
+
+// GetSelectedService returns the selected service name. It returns an empty string if no services are selected.
+func GetSelectedService() string {
+	return dm.GetSelectedService()
+}
+func (m *dependencyManager) GetSelectedService() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.selectedService
+}
@@ -1,23 +1,49 @@
 package usecase
 
 import (
+	"strings"
+
+	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
 
 // GetTypeDescriptor gets the descriptor of a type which belongs to the currently selected package.
+// If typeName is not found in the package, GetTypeDescriptor treats typeName as a fully-qualified symbol name
+// such that messages, enums, services and methods.
 func GetTypeDescriptor(typeName string) (interface{}, error) {
 	return dm.GetTypeDescriptor(typeName)
 }
 func (m *dependencyManager) GetTypeDescriptor(typeName string) (interface{}, error) {
-	pkgName := m.state.selectedPackage
+	e := m.env()
+	pkgName := e.pkg
 	if pkgName == "" {
 		pkgName = "''"
 	}
 	fqmn := proto.FullyQualifiedMessageName(pkgName, typeName)
-	d, err := m.spec.ResolveSymbol(fqmn)
+	d, err := e.spec.ResolveSymbol(fqmn)
+	if errors.Is(err, idl.ErrUnknownSymbol) {
+		d, err = e.spec.ResolveSymbol(typeName)
+	}
+	if errors.Is(err, idl.ErrUnknownSymbol) {
+		err = newUnknownNameError(idl.ErrUnknownSymbol, typeName, messageNamesFrom(e.spec, e.pkg))
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get the type descriptor of '%s'", typeName)
 	}
 	return d, nil
 }
+
+// messageNamesFrom returns message names in spec which are relative to pkg if they belong to pkg,
+// otherwise fully-qualified.
+func messageNamesFrom(spec idl.Spec, pkg string) []string {
+	names := messageNames(spec)
+	if pkg == "" {
+		return names
+	}
+	relNames := make([]string, 0, len(names))
+	for _, name := range names {
+		relNames = append(relNames, strings.TrimPrefix(name, pkg+"."))
+	}
+	return relNames
+}
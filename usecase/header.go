@@ -1,35 +1,215 @@
 package usecase
 
 import (
+	"encoding/base64"
 	"strings"
 
 	"github.com/ktr0731/evans/grpc"
 	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
 )
 
 func AddHeader(k, v string) {
 	dm.AddHeader(k, v)
 }
 func (m *dependencyManager) AddHeader(k, v string) {
-	if strings.ToLower(k) == "user-agent" {
-		logger.Println(`warning: cannot add a header named "user-agent"`)
-		return
-	}
-	if err := m.gRPCClient.Header().Add(k, v); err != nil {
-		logger.Printf("failed to add a header %s=%s: %s", k, v, err)
-	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	addHeader(m.headers(), k, v)
+}
+
+// AppendHeader appends values vs to a key k. Unlike AddHeader, it returns an error if k or vs are invalid.
+func AppendHeader(k string, vs ...string) error {
+	return dm.AppendHeader(k, vs...)
+}
+func (m *dependencyManager) AppendHeader(k string, vs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return appendHeader(m.headers(), k, vs)
+}
+
+// SetHeader replaces values of a key k with vs.
+func SetHeader(k string, vs ...string) error {
+	return dm.SetHeader(k, vs...)
+}
+func (m *dependencyManager) SetHeader(k string, vs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return errors.Wrapf(m.headers().Set(k, vs...), "failed to set a header '%s'", k)
 }
 
 func RemoveHeader(k string) {
 	dm.RemoveHeader(k)
 }
 func (m *dependencyManager) RemoveHeader(k string) {
-	m.gRPCClient.Header().Remove(k)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.headers().Remove(k)
 }
 
+// ListHeaders returns a copy of the headers. Use AddHeader, SetHeader and RemoveHeader to change them.
 func ListHeaders() grpc.Headers {
 	return dm.ListHeaders()
 }
 func (m *dependencyManager) ListHeaders() grpc.Headers {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneHeaders(m.headers())
+}
+
+// headers returns the headers the client has. m.mu must be held.
+func (m *dependencyManager) headers() grpc.Headers {
 	return m.gRPCClient.Header()
 }
+
+// replaceHeaders replaces all headers the client has with h. m.mu must be held.
+func (m *dependencyManager) replaceHeaders(h map[string][]string) {
+	for k := range m.headers() {
+		m.headers().Remove(k)
+	}
+	for k, v := range h {
+		for _, vv := range v {
+			addHeader(m.headers(), k, vv)
+		}
+	}
+}
+
+// AddScopedHeader appends a value v to a key k of headers sent only with RPCs in scope.
+// scope is a fully-qualified package, service or RPC name.
+func AddScopedHeader(scope, k, v string) {
+	dm.AddScopedHeader(scope, k, v)
+}
+func (m *dependencyManager) AddScopedHeader(scope, k, v string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	addHeader(m.scopedHeaders(scope), k, v)
+}
+
+// AppendScopedHeader is the same as AppendHeader, but appends values to headers sent only with RPCs in scope.
+func AppendScopedHeader(scope, k string, vs ...string) error {
+	return dm.AppendScopedHeader(scope, k, vs...)
+}
+func (m *dependencyManager) AppendScopedHeader(scope, k string, vs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return appendHeader(m.scopedHeaders(scope), k, vs)
+}
+
+// SetScopedHeader is the same as SetHeader, but replaces values of headers sent only with RPCs in scope.
+func SetScopedHeader(scope, k string, vs ...string) error {
+	return dm.SetScopedHeader(scope, k, vs...)
+}
+func (m *dependencyManager) SetScopedHeader(scope, k string, vs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return errors.Wrapf(m.scopedHeaders(scope).Set(k, vs...), "failed to set a header '%s' to %s", k, scope)
+}
+
+// RemoveScopedHeader removes a key k from headers sent only with RPCs in scope.
+func RemoveScopedHeader(scope, k string) {
+	dm.RemoveScopedHeader(scope, k)
+}
+func (m *dependencyManager) RemoveScopedHeader(scope, k string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.scopedHeaders[scope].Remove(k)
+}
+
+// ListScopedHeaders returns a copy of headers sent only with RPCs in scope.
+func ListScopedHeaders(scope string) grpc.Headers {
+	return dm.ListScopedHeaders(scope)
+}
+func (m *dependencyManager) ListScopedHeaders(scope string) grpc.Headers {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneHeaders(m.state.scopedHeaders[scope])
+}
+
+// scopedHeaders returns headers of scope. If scope has no headers yet, they are created. m.mu must be held.
+func (m *dependencyManager) scopedHeaders(scope string) grpc.Headers {
+	if m.state.scopedHeaders == nil {
+		m.state.scopedHeaders = grpc.ScopedHeaders{}
+	}
+	h, ok := m.state.scopedHeaders[scope]
+	if !ok {
+		h = grpc.Headers{}
+		m.state.scopedHeaders[scope] = h
+	}
+	return h
+}
+
+// addHeader appends a value v to a key k of h. Errors are only logged. "user-agent" can't be added because
+// the client sends its own one.
+func addHeader(h grpc.Headers, k, v string) {
+	if strings.ToLower(k) == "user-agent" {
+		logger.Println(`warning: cannot add a header named "user-agent"`)
+		return
+	}
+	if err := h.Add(k, v); err != nil {
+		logger.Printf("failed to add a header %s=%s: %s", k, v, err)
+	}
+}
+
+func appendHeader(h grpc.Headers, k string, vs []string) error {
+	for _, v := range vs {
+		if err := h.Add(k, v); err != nil {
+			return errors.Wrapf(err, "failed to add a header '%s=%s'", k, v)
+		}
+	}
+	return nil
+}
+
+// cloneHeaders returns a deep copy of h. Unlike copyHeaders, it returns empty headers instead of nil.
+func cloneHeaders(h grpc.Headers) grpc.Headers {
+	c := make(grpc.Headers, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// PropagateHeader adds a rule which copies values of a response header or trailer from into a request header to
+// after each RPC, so that they are sent with subsequent RPCs. If to is empty, from is used.
+func PropagateHeader(from, to string) {
+	dm.PropagateHeader(from, to)
+}
+func (m *dependencyManager) PropagateHeader(from, to string) {
+	if to == "" {
+		to = from
+	}
+	if strings.ToLower(to) == "user-agent" {
+		logger.Println(`warning: cannot propagate a header to "user-agent"`)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.propagatedHeaders == nil {
+		m.state.propagatedHeaders = map[string]string{}
+	}
+	m.state.propagatedHeaders[strings.ToLower(from)] = strings.ToLower(to)
+}
+
+// propagateHeaders replaces request headers with values of response headers or trailers in md
+// according to the rules added by PropagateHeader.
+func (m *dependencyManager) propagateHeaders(md metadata.MD) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for from, to := range m.state.propagatedHeaders {
+		vs := md.Get(from)
+		if len(vs) == 0 {
+			continue
+		}
+		hvs := make([]string, 0, len(vs))
+		for _, v := range vs {
+			if grpc.IsBinaryHeader(to) {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			// Header values are expanded at call time, but received values must be sent as they are.
+			hvs = append(hvs, strings.ReplaceAll(v, "$", "$$"))
+		}
+		if err := m.headers().Set(to, hvs...); err != nil {
+			logger.Printf("failed to propagate a header %s to %s: %s", from, to, err)
+		}
+	}
+}
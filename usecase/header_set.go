@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"encoding/json"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/pkg/errors"
+)
+
+// HeaderSetStore stores named header sets.
+type HeaderSetStore interface {
+	// Names returns all header set names in ascending order.
+	Names() ([]string, error)
+	// Load loads the header set named name.
+	Load(name string) ([]byte, error)
+	// Save saves b as the header set named name.
+	Save(name string, b []byte) error
+}
+
+// headerSet is the snapshot of headers which is saved by SaveHeaderSet.
+type headerSet struct {
+	Header        grpc.Headers       `json:"header,omitempty"`
+	ScopedHeaders grpc.ScopedHeaders `json:"scopedHeaders,omitempty"`
+}
+
+// SaveHeaderSet saves all headers including scoped ones as the header set named name.
+func SaveHeaderSet(name string) error {
+	return dm.SaveHeaderSet(name)
+}
+func (m *dependencyManager) SaveHeaderSet(name string) error {
+	if m.headerSetStore == nil {
+		return errors.New("header sets are not available")
+	}
+	m.mu.RLock()
+	hs := &headerSet{Header: m.headers(), ScopedHeaders: grpc.ScopedHeaders{}}
+	for scope, h := range m.state.scopedHeaders {
+		if len(h) != 0 {
+			hs.ScopedHeaders[scope] = h
+		}
+	}
+	b, err := json.MarshalIndent(hs, "", "  ")
+	m.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the header set")
+	}
+	return m.headerSetStore.Save(name, append(b, '\n'))
+}
+
+// UseHeaderSet replaces all headers including scoped ones with the header set named name.
+func UseHeaderSet(name string) error {
+	return dm.UseHeaderSet(name)
+}
+func (m *dependencyManager) UseHeaderSet(name string) error {
+	if m.headerSetStore == nil {
+		return errors.New("header sets are not available")
+	}
+	b, err := m.headerSetStore.Load(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the header set '%s'", name)
+	}
+	var hs headerSet
+	if err := json.Unmarshal(b, &hs); err != nil {
+		return errors.Wrapf(err, "failed to decode the header set '%s'", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replaceHeaders(hs.Header)
+	m.state.scopedHeaders = nil
+	for scope, h := range hs.ScopedHeaders {
+		for k, v := range h {
+			for _, vv := range v {
+				addHeader(m.scopedHeaders(scope), k, vv)
+			}
+		}
+	}
+	return nil
+}
+
+// ListHeaderSets lists all header set names.
+func ListHeaderSets() ([]string, error) {
+	return dm.ListHeaderSets()
+}
+func (m *dependencyManager) ListHeaderSets() ([]string, error) {
+	if m.headerSetStore == nil {
+		return nil, errors.New("header sets are not available")
+	}
+	return m.headerSetStore.Names()
+}
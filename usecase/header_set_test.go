@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+)
+
+func TestSaveAndUseHeaderSet(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	// Header sets are stored in the same way as sessions.
+	Inject(Dependencies{GRPCClient: client, HeaderSetStore: sessionStore{}})
+
+	AddHeader("authorization", "Bearer admin")
+	AddHeader("x-tenant", "a")
+	AddHeader("x-tenant", "b")
+	AddScopedHeader("api.Example", "x-route", "example")
+	if err := SaveHeaderSet("admin"); err != nil {
+		t.Fatalf("SaveHeaderSet must not return an error, but got '%s'", err)
+	}
+
+	RemoveHeader("x-tenant")
+	RemoveHeader("authorization")
+	AddHeader("authorization", "Bearer guest")
+	RemoveScopedHeader("api.Example", "x-route")
+	AddScopedHeader("api.Foo", "x-route", "foo")
+	if err := SaveHeaderSet("guest"); err != nil {
+		t.Fatalf("SaveHeaderSet must not return an error, but got '%s'", err)
+	}
+
+	if err := UseHeaderSet("admin"); err != nil {
+		t.Fatalf("UseHeaderSet must not return an error, but got '%s'", err)
+	}
+	expected := grpc.Headers{"authorization": {"Bearer admin"}, "x-tenant": {"a", "b"}}
+	if diff := cmp.Diff(expected, ListHeaders()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+	if diff := cmp.Diff(grpc.ScopedHeaders{"api.Example": {"x-route": {"example"}}}, dm.state.scopedHeaders); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	if err := UseHeaderSet("guest"); err != nil {
+		t.Fatalf("UseHeaderSet must not return an error, but got '%s'", err)
+	}
+	expected = grpc.Headers{"authorization": {"Bearer guest"}}
+	if diff := cmp.Diff(expected, ListHeaders()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+	if diff := cmp.Diff(grpc.ScopedHeaders{"api.Foo": {"x-route": {"foo"}}}, dm.state.scopedHeaders); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+}
@@ -1,15 +1,19 @@
 package usecase
 
 import (
+	"context"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ktr0731/evans/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestHeader(t *testing.T) {
 	defer Clear()
-	client, err := grpc.NewClient("", "", false, false, "", "", "")
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
 	if err != nil {
 		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
 	}
@@ -34,3 +38,136 @@ func TestHeader(t *testing.T) {
 		t.Errorf("unexpected header:\n%s", diff)
 	}
 }
+
+func TestWithHeaders(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{GRPCClient: client})
+	os.Setenv("EVANS_TEST_TOKEN", "env")
+	defer os.Unsetenv("EVANS_TEST_TOKEN")
+	os.Setenv("EVANS_TEST_ENV", "env")
+	defer os.Unsetenv("EVANS_TEST_ENV")
+	if err := dm.SetVariable("EVANS_TEST_TOKEN", "variable"); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+
+	AddHeader("x-variable", "${EVANS_TEST_TOKEN}")
+	AddHeader("x-env", "$EVANS_TEST_ENV")
+	AddHeader("x-command", "$(echo command)")
+
+	ctx, err := dm.withHeaders(context.Background(), "api.Example.Unary")
+	if err != nil {
+		t.Fatalf("withHeaders must not return an error, but got '%s'", err)
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	expected := metadata.MD{"x-variable": {"variable"}, "x-env": {"env"}, "x-command": {"command"}}
+	if diff := cmp.Diff(expected, md); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	AddHeader("x-command", "$(false)")
+	if _, err := dm.withHeaders(context.Background(), "api.Example.Unary"); err == nil {
+		t.Error("withHeaders must return an error if the command fails")
+	}
+
+	// A hanging command doesn't block state changes, and it is killed by canceling the context.
+	if err := SetHeader("x-command", "$(sleep 10)"); err != nil {
+		t.Fatalf("SetHeader must not return an error, but got '%s'", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := dm.withHeaders(ctx, "api.Example.Unary")
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	AddHeader("x-added", "while the command is running")
+	cancel()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("withHeaders must return an error if the context is canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("withHeaders must return soon after the context is canceled")
+	}
+}
+
+func TestWithHeaders_scoped(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{GRPCClient: client})
+
+	AddHeader("x-route", "default")
+	AddScopedHeader("api.Example", "X-Route", "example")
+	AddScopedHeader("api.Example.Unary", "x-route", "unary")
+	AddScopedHeader("api.Example", "user-agent", "evans")
+
+	cases := map[string]string{
+		"api.Example.Unary": "unary",
+		"api.Example.Echo":  "example",
+		"api.Other.Unary":   "default",
+	}
+	for fqrn, expected := range cases {
+		ctx, err := dm.withHeaders(context.Background(), fqrn)
+		if err != nil {
+			t.Fatalf("withHeaders must not return an error, but got '%s'", err)
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if diff := cmp.Diff(metadata.MD{"x-route": {expected}}, md); diff != "" {
+			t.Errorf("%s: (-want, +got)\n%s", fqrn, diff)
+		}
+	}
+
+	RemoveScopedHeader("api.Example.Unary", "x-route")
+	ctx, err := dm.withHeaders(context.Background(), "api.Example.Unary")
+	if err != nil {
+		t.Fatalf("withHeaders must not return an error, but got '%s'", err)
+	}
+	if md, _ := metadata.FromOutgoingContext(ctx); md.Get("x-route")[0] != "example" {
+		t.Errorf("the removed scoped header must not be sent, but got %v", md)
+	}
+}
+
+func TestPropagateHeaders(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{GRPCClient: client})
+
+	AddHeader("x-session-id", "old")
+	PropagateHeader("X-Session-ID", "")
+	PropagateHeader("set-token", "authorization")
+	PropagateHeader("trace-bin", "")
+	PropagateHeader("x-agent", "user-agent")
+
+	dm.propagateHeaders(metadata.Pairs("x-session-id", "new", "x-other", "other", "x-agent", "agent"))
+	dm.propagateHeaders(metadata.Pairs("set-token", "$token", "trace-bin", "\x00\x01"))
+
+	expected := grpc.Headers{
+		"x-session-id":  {"new"},
+		"authorization": {"$$token"},
+		"trace-bin":     {"AAE="},
+	}
+	if diff := cmp.Diff(expected, dm.ListHeaders()); diff != "" {
+		t.Errorf("unexpected headers (-want +got):\n%s", diff)
+	}
+
+	ctx, err := dm.withHeaders(context.Background(), "api.Example.Unary")
+	if err != nil {
+		t.Fatalf("withHeaders must not return an error, but got '%s'", err)
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if got := md.Get("authorization"); len(got) != 1 || got[0] != "$token" {
+		t.Errorf("propagated values must be sent as they are, but got %v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	healthCheckRPC = "grpc.health.v1.Health.Check"
+	healthWatchRPC = "grpc.health.v1.Health.Watch"
+)
+
+// ErrNotServing is returned from CheckHealth if one or more services are not serving.
+var ErrNotServing = errors.New("one or more services are not serving")
+
+// CheckHealth checks the serving status of each service by grpc.health.v1.Health/Check, then writes it to w.
+// If services is empty, the overall health of the server is checked.
+// CheckHealth returns ErrNotServing if one or more services are not SERVING.
+func CheckHealth(ctx context.Context, w io.Writer, services []string) error {
+	return dm.CheckHealth(ctx, w, services)
+}
+func (m *dependencyManager) CheckHealth(ctx context.Context, w io.Writer, services []string) error {
+	if len(services) == 0 {
+		services = []string{""}
+	}
+	ctx, err := m.withHeaders(ctx, healthCheckRPC)
+	if err != nil {
+		return err
+	}
+	client := m.env().client
+	var notServing bool
+	for _, svc := range services {
+		s, err := m.checkHealth(ctx, client, svc)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check the health of %s", healthTarget(svc))
+		}
+		if s != grpc_health_v1.HealthCheckResponse_SERVING {
+			notServing = true
+		}
+		fmt.Fprintln(w, formatHealth(svc, s))
+	}
+	if notServing {
+		return ErrNotServing
+	}
+	return nil
+}
+
+func (m *dependencyManager) checkHealth(ctx context.Context, client grpc.Client, service string) (grpc_health_v1.HealthCheckResponse_ServingStatus, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	req, res := &grpc_health_v1.HealthCheckRequest{Service: service}, &grpc_health_v1.HealthCheckResponse{}
+	_, _, err := client.Invoke(ctx, healthCheckRPC, req, res)
+	// The server returns NotFound if the service is unknown.
+	if status.Code(errors.Cause(err)) == codes.NotFound {
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN, nil
+	}
+	if err != nil {
+		return grpc_health_v1.HealthCheckResponse_UNKNOWN, err
+	}
+	return res.GetStatus(), nil
+}
+
+// WatchHealth watches the serving status of each service by grpc.health.v1.Health/Watch, then writes each
+// change to w until ctx is canceled or any of streams is finished.
+// If services is empty, the overall health of the server is watched.
+func WatchHealth(ctx context.Context, w io.Writer, services []string) error {
+	return dm.WatchHealth(ctx, w, services)
+}
+func (m *dependencyManager) WatchHealth(ctx context.Context, w io.Writer, services []string) error {
+	if len(services) == 0 {
+		services = []string{""}
+	}
+	ctx, err := m.withHeaders(ctx, healthWatchRPC)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	client := m.env().client
+	var mu sync.Mutex
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, svc := range services {
+		svc := svc
+		eg.Go(func() error {
+			err := m.watchHealth(ctx, client, svc, func(s grpc_health_v1.HealthCheckResponse_ServingStatus) {
+				mu.Lock()
+				defer mu.Unlock()
+				fmt.Fprintln(w, formatHealth(svc, s))
+			})
+			return errors.Wrapf(err, "failed to watch the health of %s", healthTarget(svc))
+		})
+	}
+	err = eg.Wait()
+	if status.Code(errors.Cause(err)) == codes.Canceled {
+		return nil
+	}
+	return err
+}
+
+func (m *dependencyManager) watchHealth(ctx context.Context, client grpc.Client, service string, f func(grpc_health_v1.HealthCheckResponse_ServingStatus)) error {
+	streamDesc := &gogrpc.StreamDesc{StreamName: "Watch", ServerStreams: true}
+	stream, err := client.NewServerStream(ctx, streamDesc, healthWatchRPC)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&grpc_health_v1.HealthCheckRequest{Service: service}); err != nil {
+		return err
+	}
+	for {
+		var res grpc_health_v1.HealthCheckResponse
+		err := stream.Receive(&res)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f(res.GetStatus())
+	}
+}
+
+// healthTarget returns the description of the service for error messages.
+func healthTarget(service string) string {
+	if service == "" {
+		return "the server"
+	}
+	return fmt.Sprintf("service '%s'", service)
+}
+
+// formatHealth formats the serving status of the service. The overall health of the server is shown as the status only.
+func formatHealth(service string, s grpc_health_v1.HealthCheckResponse_ServingStatus) string {
+	if service == "" {
+		return s.String()
+	}
+	return fmt.Sprintf("%s: %s", service, s)
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/pkg/errors"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func startHealthServer(t *testing.T) (*health.Server, string, func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	srv := gogrpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("api.Example", grpc_health_v1.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("api.Foo", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go srv.Serve(l)
+	return hs, l.Addr().String(), srv.Stop
+}
+
+func TestCheckHealth(t *testing.T) {
+	_, addr, stop := startHealthServer(t)
+	defer stop()
+
+	cases := map[string]struct {
+		services []string
+		expected string
+		err      error
+	}{
+		"server":          {expected: "SERVING\n"},
+		"serving service": {services: []string{"api.Example"}, expected: "api.Example: SERVING\n"},
+		"not serving service": {
+			services: []string{"api.Example", "api.Foo"},
+			expected: "api.Example: SERVING\napi.Foo: NOT_SERVING\n",
+			err:      ErrNotServing,
+		},
+		"unknown service": {
+			services: []string{"api.Bar"},
+			expected: "api.Bar: SERVICE_UNKNOWN\n",
+			err:      ErrNotServing,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			defer Clear()
+			client, err := grpc.NewClient(addr, "", false, false, "", "", "", "", grpc.ConnOptions{})
+			if err != nil {
+				t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+			}
+			defer client.Close(context.Background())
+			Inject(Dependencies{GRPCClient: client})
+
+			var buf bytes.Buffer
+			err = CheckHealth(context.Background(), &buf, c.services)
+			if !errors.Is(err, c.err) {
+				t.Errorf("expected error '%v', but got '%v'", c.err, err)
+			}
+			if actual := buf.String(); actual != c.expected {
+				t.Errorf("expected '%s', but got '%s'", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	defer Clear()
+	hs, addr, stop := startHealthServer(t)
+	defer stop()
+
+	client, err := grpc.NewClient(addr, "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	defer client.Close(context.Background())
+	Inject(Dependencies{GRPCClient: client})
+
+	var buf syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- WatchHealth(ctx, &buf, []string{"api.Foo"})
+	}()
+
+	waitFor := func(s string) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if strings.Contains(buf.String(), s) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("'%s' is not written, got '%s'", s, buf.String())
+	}
+	waitFor("api.Foo: NOT_SERVING\n")
+	hs.SetServingStatus("api.Foo", grpc_health_v1.HealthCheckResponse_SERVING)
+	waitFor("api.Foo: SERVING\n")
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("WatchHealth must not return an error if the context is canceled, but got '%s'", err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer which can be read while it is written by another goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"sort"
+
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/idl/proto"
+)
+
+// index holds lookup tables built from the loaded spec for avoiding linear scans against large schemas.
+// The index is built lazily at the first lookup, and rebuilt when a spec which differs from the last one is looked up.
+type index struct {
+	// pkgNames is the sorted package names.
+	pkgNames []string
+	// key: package name, val: service names (not fully-qualified) belong to the package.
+	// Service names are ordered by the same order as idl.Spec.ServiceNames.
+	svcNames map[string][]string
+	// key: package name, val: a set of service names (not fully-qualified) belong to the package.
+	svcs map[string]map[string]struct{}
+}
+
+func newIndex(fqsns []string) *index {
+	idx := &index{
+		svcNames: make(map[string][]string),
+		svcs:     make(map[string]map[string]struct{}),
+	}
+	for _, fqsn := range fqsns {
+		pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
+		if _, ok := idx.svcs[pkg]; !ok {
+			idx.pkgNames = append(idx.pkgNames, pkg)
+			idx.svcs[pkg] = make(map[string]struct{})
+		}
+		if _, ok := idx.svcs[pkg][svc]; ok {
+			continue
+		}
+		idx.svcs[pkg][svc] = struct{}{}
+		idx.svcNames[pkg] = append(idx.svcNames[pkg], svc)
+	}
+	sort.Strings(idx.pkgNames)
+	return idx
+}
+
+func (i *index) hasPackage(pkg string) bool {
+	_, ok := i.svcs[pkg]
+	return ok
+}
+
+func (i *index) hasService(pkg, svc string) bool {
+	_, ok := i.svcs[pkg][svc]
+	return ok
+}
+
+// index returns the index of spec. If it is not built from spec yet, index builds it.
+func (m *dependencyManager) index(spec idl.Spec) *index {
+	m.idxMu.Lock()
+	defer m.idxMu.Unlock()
+	if m.idx == nil || m.idxSpec != spec {
+		m.idx = newIndex(spec.ServiceNames())
+		m.idxSpec = spec
+	}
+	return m.idx
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIndex(t *testing.T) {
+	idx := newIndex([]string{"api.Example", "api.Foo", "Bar", "other.api.Example", "api.Example"})
+
+	if diff := cmp.Diff([]string{"", "api", "other.api"}, idx.pkgNames); diff != "" {
+		t.Errorf("unexpected package names:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Example", "Foo"}, idx.svcNames["api"]); diff != "" {
+		t.Errorf("unexpected service names:\n%s", diff)
+	}
+
+	cases := map[string]struct {
+		pkg, svc   string
+		hasPackage bool
+		hasService bool
+	}{
+		"found":           {pkg: "api", svc: "Foo", hasPackage: true, hasService: true},
+		"empty package":   {pkg: "", svc: "Bar", hasPackage: true, hasService: true},
+		"unknown service": {pkg: "other.api", svc: "Foo", hasPackage: true},
+		"unknown package": {pkg: "foo", svc: "Example"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if actual := idx.hasPackage(c.pkg); actual != c.hasPackage {
+				t.Errorf("expected hasPackage returns %t, but got %t", c.hasPackage, actual)
+			}
+			if actual := idx.hasService(c.pkg, c.svc); actual != c.hasService {
+				t.Errorf("expected hasService returns %t, but got %t", c.hasService, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import "github.com/ktr0731/evans/idl"
+
+// ListMessages returns the loaded fully-qualified message names.
+func ListMessages() []string {
+	return dm.ListMessages()
+}
+func (m *dependencyManager) ListMessages() []string {
+	return messageNames(m.env().spec)
+}
+
+func messageNames(spec idl.Spec) []string {
+	if spec == nil {
+		return nil
+	}
+	return spec.MessageNames()
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
 	"github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
@@ -13,17 +14,35 @@ func ListRPCs(svcName string) ([]*grpc.RPC, error) {
 	return dm.ListRPCs(svcName)
 }
 func (m *dependencyManager) ListRPCs(svcName string) ([]*grpc.RPC, error) {
+	e := m.env()
 	if svcName == "" {
-		svcName = m.state.selectedService
+		svcName = e.svc
 	}
-	fqsn := proto.FullyQualifiedServiceName(m.state.selectedPackage, svcName)
-	return m.listRPCs(fqsn)
+	fqsn := proto.FullyQualifiedServiceName(e.pkg, svcName)
+	return listRPCs(e.spec, fqsn)
 }
 
-func (m *dependencyManager) listRPCs(fqsn string) ([]*grpc.RPC, error) {
-	rpcs, err := m.spec.RPCs(fqsn)
+func listRPCs(spec idl.Spec, fqsn string) ([]*grpc.RPC, error) {
+	rpcs, err := spec.RPCs(fqsn)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list RPCs")
 	}
 	return rpcs, nil
 }
+
+// ListAllRPCs lists RPCs of all loaded services regardless of the selected package and service.
+func ListAllRPCs() ([]*grpc.RPC, error) {
+	return dm.ListAllRPCs()
+}
+func (m *dependencyManager) ListAllRPCs() ([]*grpc.RPC, error) {
+	spec := m.env().spec
+	var rpcs []*grpc.RPC
+	for _, fqsn := range spec.ServiceNames() {
+		r, err := listRPCs(spec, fqsn)
+		if err != nil {
+			return nil, err
+		}
+		rpcs = append(rpcs, r...)
+	}
+	return rpcs, nil
+}
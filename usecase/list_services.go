@@ -5,9 +5,5 @@ func ListServices() []string {
 	return dm.ListServices()
 }
 func (m *dependencyManager) ListServices() []string {
-	return m.listServices()
-}
-
-func (m *dependencyManager) listServices() []string {
-	return m.spec.ServiceNames()
+	return m.env().spec.ServiceNames()
 }
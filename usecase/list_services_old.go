@@ -1,8 +1,6 @@
 package usecase
 
-import (
-	"github.com/ktr0731/evans/idl/proto"
-)
+import "github.com/ktr0731/evans/idl"
 
 // ListServicesOld returns the services belong to the selected package.
 // The returned service names are NOT fully-qualified.
@@ -10,17 +8,16 @@ func ListServicesOld() []string {
 	return dm.ListServicesOld()
 }
 func (m *dependencyManager) ListServicesOld() []string {
-	return m.listServicesOld(m.state.selectedPackage)
+	e := m.env()
+	return m.listServicesOld(e.spec, e.pkg)
 }
 
-func (m *dependencyManager) listServicesOld(pkgName string) []string {
-	var svcs []string
-	svcNames := m.spec.ServiceNames()
-	for i := range svcNames {
-		pkg, svc := proto.ParseFullyQualifiedServiceName(svcNames[i])
-		if pkg == pkgName {
-			svcs = append(svcs, svc)
-		}
+func (m *dependencyManager) listServicesOld(spec idl.Spec, pkgName string) []string {
+	svcNames := m.index(spec).svcNames[pkgName]
+	if len(svcNames) == 0 {
+		return nil
 	}
+	svcs := make([]string, len(svcNames))
+	copy(svcs, svcNames)
 	return svcs
 }
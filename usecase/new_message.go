@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"github.com/pkg/errors"
+)
+
+// NewMessage instantiates a message of the passed fully-qualified message name.
+// The message recognizes extensions defined in all loaded files.
+func NewMessage(fqmn string) (interface{}, error) {
+	return dm.NewMessage(fqmn)
+}
+func (m *dependencyManager) NewMessage(fqmn string) (interface{}, error) {
+	typ, err := m.env().spec.MessageType(fqmn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the message type of '%s'", fqmn)
+	}
+	v, err := typ.New()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to instantiate message '%s'", fqmn)
+	}
+	return v, nil
+}
@@ -3,6 +3,8 @@ package usecase
 import (
 	"errors"
 	"strings"
+
+	"github.com/ktr0731/evans/idl"
 )
 
 // ParseFullyQualifiedMethodName parses the passed fully-qualified method as fully-qualified service name and method name.
@@ -11,15 +13,23 @@ import (
 //   - An error described in idl.Spec.RPC method returns.
 //   - An error if fqmn is not a valid fully-qualified method name form.
 //
+// fqmn may be in the gRPC path form such that "api.Example/Unary". Returned names are resolved to the loaded ones
+// case-insensitively if fqmn doesn't exactly match any methods.
 func ParseFullyQualifiedMethodName(fqmn string) (fqsn, method string, err error) {
 	return dm.ParseFullyQualifiedMethodName(fqmn)
 }
 func (m *dependencyManager) ParseFullyQualifiedMethodName(fqmn string) (string, string, error) {
+	return parseFullyQualifiedMethodName(m.env().spec, fqmn)
+}
+
+func parseFullyQualifiedMethodName(spec idl.Spec, fqmn string) (string, string, error) {
+	fqmn = normalizeMethodName(fqmn)
 	i := strings.LastIndex(fqmn, ".")
 	if i == -1 {
 		return "", "", errors.New("invalid fully-qualified method name")
 	}
-	svc, mtd := fqmn[:i], fqmn[i+1:]
-	_, err := m.spec.RPC(svc, mtd)
+	svc := resolveServiceName(spec, fqmn[:i])
+	mtd := resolveRPCName(spec, svc, fqmn[i+1:])
+	_, err := spec.RPC(svc, mtd)
 	return svc, mtd, err
 }
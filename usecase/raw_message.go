@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/pkg/errors"
+)
+
+// SetRawResponseWriter sets w as the destination of serialized responses. Each received response is written to w
+// as the wire bytes. Responses of server streaming and bidi streaming RPCs are prefixed by their lengths encoded as varints.
+// If w is nil, responses are not written.
+func SetRawResponseWriter(w io.Writer) {
+	dm.SetRawResponseWriter(w)
+}
+func (m *dependencyManager) SetRawResponseWriter(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.rawResponseWriter = w
+}
+
+// rawRequest is a request which is sent as the serialized bytes as it is.
+// The gRPC codec uses Marshal instead of marshaling the message.
+type rawRequest struct {
+	b []byte
+}
+
+func (r *rawRequest) Reset()         { r.b = nil }
+func (r *rawRequest) String() string { return fmt.Sprintf("%x", r.b) }
+func (r *rawRequest) ProtoMessage()  {}
+
+func (r *rawRequest) Marshal() ([]byte, error) {
+	return r.b, nil
+}
+
+// rawResponse writes the serialized response to w before decoding it into the embedded message.
+type rawResponse struct {
+	proto.Message
+
+	w         io.Writer
+	delimited bool
+}
+
+func (r *rawResponse) Unmarshal(b []byte) error {
+	if r.delimited {
+		var buf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(buf[:], uint64(len(b)))
+		if _, err := r.w.Write(buf[:n]); err != nil {
+			return errors.Wrap(err, "failed to write the length of the raw response")
+		}
+	}
+	if _, err := r.w.Write(b); err != nil {
+		return errors.Wrap(err, "failed to write the raw response")
+	}
+	if u, ok := r.Message.(proto.Unmarshaler); ok {
+		return u.Unmarshal(b)
+	}
+	return proto.Unmarshal(b, r.Message)
+}
+
+func (r *rawResponse) Marshal() ([]byte, error) {
+	return proto.Marshal(r.Message)
+}
+
+// wrapResponse wraps res to write the serialized response if the raw response writer is set.
+func (m *dependencyManager) wrapResponse(res interface{}, delimited bool) interface{} {
+	m.mu.RLock()
+	w := m.state.rawResponseWriter
+	m.mu.RUnlock()
+	msg, ok := res.(proto.Message)
+	if w == nil || !ok {
+		return res
+	}
+	return &rawResponse{Message: msg, w: w, delimited: delimited}
+}
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestWrapResponse(t *testing.T) {
+	in := []byte("\x0a\x06kumiko")
+	cases := map[string]struct {
+		delimited bool
+		expected  []byte
+	}{
+		"not delimited": {expected: in},
+		"delimited":     {delimited: true, expected: append([]byte{byte(len(in))}, in...)},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			m := &dependencyManager{}
+			m.SetRawResponseWriter(&buf)
+
+			var res errdetails.ErrorInfo
+			wrapped := m.wrapResponse(&res, c.delimited)
+			u, ok := wrapped.(proto.Unmarshaler)
+			if !ok {
+				t.Fatalf("the wrapped response must implement proto.Unmarshaler, but got %T", wrapped)
+			}
+			if err := u.Unmarshal(in); err != nil {
+				t.Fatalf("Unmarshal must not return an error, but got '%s'", err)
+			}
+			if res.Reason != "kumiko" {
+				t.Errorf("expected reason 'kumiko', but got '%s'", res.Reason)
+			}
+			if !bytes.Equal(c.expected, buf.Bytes()) {
+				t.Errorf("expected %q, but got %q", c.expected, buf.Bytes())
+			}
+		})
+	}
+
+	t.Run("no writer", func(t *testing.T) {
+		var res errdetails.ErrorInfo
+		if wrapped := (&dependencyManager{}).wrapResponse(&res, false); wrapped != &res {
+			t.Errorf("the response must not be wrapped if the writer is not set")
+		}
+	})
+}
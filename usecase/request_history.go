@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+)
+
+// RequestHistoryStore stores sent request bodies for each RPC.
+type RequestHistoryStore interface {
+	// Load loads request bodies of the RPC in ascending order.
+	Load(fqrn string) ([]string, error)
+	// Append appends body to the request history of the RPC.
+	Append(fqrn, body string) error
+}
+
+// ListRequestHistory lists sent request bodies of the RPC which belongs to the currently selected service.
+// Bodies are sorted in ascending order, so that the last one is the newest.
+func ListRequestHistory(rpcName string) ([]string, error) {
+	return dm.ListRequestHistory(rpcName)
+}
+func (m *dependencyManager) ListRequestHistory(rpcName string) ([]string, error) {
+	if m.requestHistoryStore == nil {
+		return nil, errors.New("request history is not available")
+	}
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return nil, err
+	}
+	return m.requestHistoryStore.Load(rpc.FullyQualifiedName)
+}
+
+// LoadRequestHistory loads the n-th request body of the request history listed by ListRequestHistory.
+// n starts from 1.
+func LoadRequestHistory(rpcName string, n int) (string, error) {
+	return dm.LoadRequestHistory(rpcName, n)
+}
+func (m *dependencyManager) LoadRequestHistory(rpcName string, n int) (string, error) {
+	h, err := m.ListRequestHistory(rpcName)
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || n > len(h) {
+		return "", errors.Errorf("request history of '%s' has no entry %d", rpcName, n)
+	}
+	return h[n-1], nil
+}
+
+// recordRequestHistory appends sent requests to the request history if it is available.
+// Failures are only logged because they must not fail the RPC call. m.mu must be held.
+func (m *dependencyManager) recordRequestHistory(rpc *grpc.RPC, reqs []interface{}) {
+	if m.requestHistoryStore == nil {
+		return
+	}
+	body, err := formatRequests(rpc, reqs, m.state.protoNames)
+	if err != nil {
+		logger.Printf("failed to format requests for the request history: %s", err)
+		return
+	}
+	if err := m.requestHistoryStore.Append(rpc.FullyQualifiedName, body); err != nil {
+		logger.Printf("failed to write the request history: %s", err)
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"github.com/pkg/errors"
+)
+
+// TemplateStore stores named request templates for each RPC.
+type TemplateStore interface {
+	// Names returns all template names of the RPC in ascending order.
+	Names(fqrn string) ([]string, error)
+	// Load loads the template named name of the RPC.
+	Load(fqrn, name string) ([]byte, error)
+	// Save saves body as the template named name of the RPC.
+	Save(fqrn, name string, body []byte) error
+	// Delete deletes the template named name of the RPC.
+	Delete(fqrn, name string) error
+}
+
+// SaveRequestTemplate saves the requests of the last call as the template named name.
+// The template belongs to the last called RPC.
+func SaveRequestTemplate(name string) error {
+	return dm.SaveRequestTemplate(name)
+}
+func (m *dependencyManager) SaveRequestTemplate(name string) error {
+	if m.templateStore == nil {
+		return errors.New("templates are not available")
+	}
+	rpc, err := m.getLastRPC(m.env().spec)
+	if err != nil {
+		return err
+	}
+	reqs, protoNames := m.lastRequestsOf(rpc.FullyQualifiedName)
+	body, err := formatRequests(rpc, reqs, protoNames)
+	if err != nil {
+		return err
+	}
+	return m.templateStore.Save(rpc.FullyQualifiedName, name, []byte(body))
+}
+
+// LoadRequestTemplate loads the template named name of the RPC which belongs to the currently selected service.
+func LoadRequestTemplate(rpcName, name string) (string, error) {
+	return dm.LoadRequestTemplate(rpcName, name)
+}
+func (m *dependencyManager) LoadRequestTemplate(rpcName, name string) (string, error) {
+	if m.templateStore == nil {
+		return "", errors.New("templates are not available")
+	}
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return "", err
+	}
+	b, err := m.templateStore.Load(rpc.FullyQualifiedName, name)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load the template '%s'", name)
+	}
+	return string(b), nil
+}
+
+// ListRequestTemplates lists template names of the RPC which belongs to the currently selected service.
+func ListRequestTemplates(rpcName string) ([]string, error) {
+	return dm.ListRequestTemplates(rpcName)
+}
+func (m *dependencyManager) ListRequestTemplates(rpcName string) ([]string, error) {
+	if m.templateStore == nil {
+		return nil, errors.New("templates are not available")
+	}
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return nil, err
+	}
+	return m.templateStore.Names(rpc.FullyQualifiedName)
+}
+
+// DeleteRequestTemplate deletes the template named name of the RPC which belongs to the currently selected service.
+func DeleteRequestTemplate(rpcName, name string) error {
+	return dm.DeleteRequestTemplate(rpcName, name)
+}
+func (m *dependencyManager) DeleteRequestTemplate(rpcName, name string) error {
+	if m.templateStore == nil {
+		return errors.New("templates are not available")
+	}
+	rpc, err := getRPC(m.env(), rpcName)
+	if err != nil {
+		return err
+	}
+	if err := m.templateStore.Delete(rpc.FullyQualifiedName, name); err != nil {
+		return errors.Wrapf(err, "failed to delete the template '%s'", name)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"strings"
+
+	"github.com/ktr0731/evans/idl"
+)
+
+// matchName returns the name in names which equals name. If there is no such name, it returns the only name
+// which equals name case-insensitively. ok is false if no names match or the match is ambiguous.
+func matchName(name string, names []string) (_ string, ok bool) {
+	var matched []string
+	for _, n := range names {
+		if n == name {
+			return n, true
+		}
+		if strings.EqualFold(n, name) {
+			matched = append(matched, n)
+		}
+	}
+	if len(matched) != 1 {
+		return "", false
+	}
+	return matched[0], true
+}
+
+// normalizeMethodName converts a method name in the gRPC path form such that "/api.Example/Unary" or
+// "api.Example/Unary" to the dot-separated form such that "api.Example.Unary".
+func normalizeMethodName(name string) string {
+	return strings.Replace(strings.TrimPrefix(name, "/"), "/", ".", 1)
+}
+
+// resolveServiceName returns the fully-qualified service name in spec which matches fqsn by matchName.
+// It returns fqsn as it is if no services match.
+func resolveServiceName(spec idl.Spec, fqsn string) string {
+	if n, ok := matchName(fqsn, spec.ServiceNames()); ok {
+		return n
+	}
+	return fqsn
+}
+
+// resolveRPCName returns the name of the RPC in spec which belongs to fqsn and matches rpcName by matchName.
+// It returns rpcName as it is if no RPCs match.
+func resolveRPCName(spec idl.Spec, fqsn, rpcName string) string {
+	rpcs, err := spec.RPCs(fqsn)
+	if err != nil {
+		return rpcName
+	}
+	names := make([]string, 0, len(rpcs))
+	for _, rpc := range rpcs {
+		names = append(names, rpc.Name)
+	}
+	if n, ok := matchName(rpcName, names); ok {
+		return n
+	}
+	return rpcName
+}
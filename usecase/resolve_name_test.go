@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"github.com/pkg/errors"
+)
+
+type rpcSpec struct {
+	idl.Spec
+	rpcs map[string][]string
+}
+
+func (s *rpcSpec) ServiceNames() []string {
+	var names []string
+	for n := range s.rpcs {
+		names = append(names, n)
+	}
+	return names
+}
+
+func (s *rpcSpec) RPCs(svcName string) ([]*grpc.RPC, error) {
+	names, ok := s.rpcs[svcName]
+	if !ok {
+		return nil, idl.ErrUnknownServiceName
+	}
+	rpcs := make([]*grpc.RPC, 0, len(names))
+	for _, n := range names {
+		rpcs = append(rpcs, &grpc.RPC{Name: n, FullyQualifiedName: svcName + "." + n})
+	}
+	return rpcs, nil
+}
+
+func (s *rpcSpec) RPC(svcName, rpcName string) (*grpc.RPC, error) {
+	rpcs, err := s.RPCs(svcName)
+	if err != nil {
+		return nil, err
+	}
+	for _, rpc := range rpcs {
+		if rpc.Name == rpcName {
+			return rpc, nil
+		}
+	}
+	return nil, idl.ErrUnknownRPCName
+}
+
+func TestResolveNames(t *testing.T) {
+	newDM := func() *dependencyManager {
+		return &dependencyManager{
+			spec: &rpcSpec{rpcs: map[string][]string{
+				"api.Greeter":  {"SayHello", "Sayhello2"},
+				"api.Ambig":    {"Foo", "FOO"},
+				"other.Health": {"Check"},
+			}},
+			state: defaultState,
+		}
+	}
+
+	t.Run("UsePackage", func(t *testing.T) {
+		m := newDM()
+		if err := m.UsePackage("API"); err != nil {
+			t.Fatalf("UsePackage must not return an error, but got '%s'", err)
+		}
+		if m.state.selectedPackage != "api" {
+			t.Errorf("expected 'api', but got '%s'", m.state.selectedPackage)
+		}
+	})
+
+	t.Run("UseService", func(t *testing.T) {
+		cases := map[string]struct {
+			pkg, svc              string
+			expectedPkg, expected string
+			hasErr                bool
+		}{
+			"exact":                   {pkg: "api", svc: "Greeter", expectedPkg: "api", expected: "Greeter"},
+			"case-insensitive":        {pkg: "api", svc: "greeter", expectedPkg: "api", expected: "Greeter"},
+			"fully-qualified":         {svc: "other.Health", expectedPkg: "other", expected: "Health"},
+			"fully-qualified lower":   {pkg: "api", svc: "Other.health", expectedPkg: "other", expected: "Health"},
+			"unknown":                 {pkg: "api", svc: "Greter", hasErr: true},
+			"unknown fully-qualified": {pkg: "api", svc: "api.Health", hasErr: true},
+		}
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				m := newDM()
+				m.state.selectedPackage = c.pkg
+				err := m.UseService(c.svc)
+				if c.hasErr {
+					if !errors.Is(err, idl.ErrUnknownServiceName) {
+						t.Errorf("expected ErrUnknownServiceName, but got '%v'", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("UseService must not return an error, but got '%s'", err)
+				}
+				if m.state.selectedPackage != c.expectedPkg || m.state.selectedService != c.expected {
+					t.Errorf("expected %s.%s, but got %s.%s", c.expectedPkg, c.expected, m.state.selectedPackage, m.state.selectedService)
+				}
+			})
+		}
+	})
+
+	t.Run("getRPC", func(t *testing.T) {
+		cases := map[string]struct {
+			rpcName  string
+			expected string
+			err      error
+		}{
+			"exact":                     {rpcName: "SayHello", expected: "api.Greeter.SayHello"},
+			"case-insensitive":          {rpcName: "sayhello", expected: "api.Greeter.SayHello"},
+			"exact wins":                {rpcName: "Sayhello2", expected: "api.Greeter.Sayhello2"},
+			"fully-qualified":           {rpcName: "other.Health.Check", expected: "other.Health.Check"},
+			"path form":                 {rpcName: "other.Health/check", expected: "other.Health.Check"},
+			"path form with slash":      {rpcName: "/OTHER.health/Check", expected: "other.Health.Check"},
+			"ambiguous":                 {rpcName: "api.Ambig.foo", err: idl.ErrUnknownRPCName},
+			"unknown":                   {rpcName: "SayBye", err: idl.ErrUnknownRPCName},
+			"unknown service":           {rpcName: "api.Health.Check", err: idl.ErrUnknownServiceName},
+			"unknown method in service": {rpcName: "other.Health/Watch", err: idl.ErrUnknownRPCName},
+		}
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				m := newDM()
+				m.state.selectedPackage = "api"
+				m.state.selectedService = "Greeter"
+				rpc, err := getRPC(m.env(), c.rpcName)
+				if c.err != nil {
+					if !errors.Is(err, c.err) {
+						t.Errorf("expected '%s', but got '%v'", c.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("getRPC must not return an error, but got '%s'", err)
+				}
+				if rpc.FullyQualifiedName != c.expected {
+					t.Errorf("expected '%s', but got '%s'", c.expected, rpc.FullyQualifiedName)
+				}
+			})
+		}
+	})
+
+	t.Run("ParseFullyQualifiedMethodName", func(t *testing.T) {
+		svc, mtd, err := newDM().ParseFullyQualifiedMethodName("API.greeter/sayhello")
+		if err != nil {
+			t.Fatalf("ParseFullyQualifiedMethodName must not return an error, but got '%s'", err)
+		}
+		if svc != "api.Greeter" || mtd != "SayHello" {
+			t.Errorf("expected api.Greeter and SayHello, but got %s and %s", svc, mtd)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SessionStore stores named sessions.
+type SessionStore interface {
+	// Names returns all session names in ascending order.
+	Names() ([]string, error)
+	// Load loads the session named name.
+	Load(name string) ([]byte, error)
+	// Save saves b as the session named name.
+	Save(name string, b []byte) error
+}
+
+// session is the state which is saved by SaveSession.
+type session struct {
+	Profile   string              `json:"profile,omitempty"`
+	Package   string              `json:"package,omitempty"`
+	Service   string              `json:"service,omitempty"`
+	Header    map[string][]string `json:"header,omitempty"`
+	Variables map[string]string   `json:"variables,omitempty"`
+	Timeout   string              `json:"timeout,omitempty"`
+	// Options is options of the caller such that the output format. It is opaque for this package.
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// SaveSession saves the selected profile, package and service, headers, variables and the timeout as the session
+// named name. opts is saved together, it is encoded as JSON.
+func SaveSession(name string, opts interface{}) error {
+	return dm.SaveSession(name, opts)
+}
+func (m *dependencyManager) SaveSession(name string, opts interface{}) error {
+	if m.sessionStore == nil {
+		return errors.New("sessions are not available")
+	}
+	m.mu.RLock()
+	s := &session{
+		Profile:   m.state.selectedProfile,
+		Package:   m.state.selectedPackage,
+		Service:   m.state.selectedService,
+		Header:    cloneHeaders(m.headers()),
+		Variables: make(map[string]string, len(m.state.variables)),
+	}
+	for k, v := range m.state.variables {
+		s.Variables[k] = v
+	}
+	if m.state.timeout > 0 {
+		s.Timeout = m.state.timeout.String()
+	}
+	m.mu.RUnlock()
+	if opts != nil {
+		b, err := json.Marshal(opts)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode options")
+		}
+		s.Options = b
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the session")
+	}
+	return m.sessionStore.Save(name, append(b, '\n'))
+}
+
+// LoadSession restores the state saved as the session named name. Headers and variables are replaced with saved ones.
+// Saved options are decoded into opts if opts is not nil.
+func LoadSession(name string, opts interface{}) error {
+	return dm.LoadSession(name, opts)
+}
+func (m *dependencyManager) LoadSession(name string, opts interface{}) error {
+	if m.sessionStore == nil {
+		return errors.New("sessions are not available")
+	}
+	b, err := m.sessionStore.Load(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the session '%s'", name)
+	}
+	var s session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Wrapf(err, "failed to decode the session '%s'", name)
+	}
+	var timeout time.Duration
+	if s.Timeout != "" {
+		timeout, err = time.ParseDuration(s.Timeout)
+		if err != nil {
+			return errors.Wrapf(err, "invalid timeout '%s'", s.Timeout)
+		}
+	}
+	if opts != nil && len(s.Options) != 0 {
+		if err := json.Unmarshal(s.Options, opts); err != nil {
+			return errors.Wrap(err, "failed to decode options")
+		}
+	}
+
+	// The profile must be restored first because it replaces the connection and the selected package and service.
+	if s.Profile != "" && s.Profile != m.GetCurrentProfile() {
+		if err := m.UseProfile(s.Profile); err != nil {
+			return err
+		}
+	}
+	m.mu.Lock()
+	m.replaceHeaders(s.Header)
+	m.state.variables = s.Variables
+	m.state.timeout = timeout
+	m.state.selectedPackage, m.state.selectedService = "", ""
+	m.mu.Unlock()
+
+	if s.Package != "" {
+		if err := m.UsePackage(s.Package); err != nil {
+			return errors.Wrapf(err, "failed to select the package '%s'", s.Package)
+		}
+	}
+	if s.Service != "" {
+		if err := m.UseService(s.Service); err != nil {
+			return errors.Wrapf(err, "failed to select the service '%s'", s.Service)
+		}
+	}
+	return nil
+}
+
+// ListSessions lists all session names.
+func ListSessions() ([]string, error) {
+	return dm.ListSessions()
+}
+func (m *dependencyManager) ListSessions() ([]string, error) {
+	if m.sessionStore == nil {
+		return nil, errors.New("sessions are not available")
+	}
+	return m.sessionStore.Names()
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+)
+
+type sessionStore map[string][]byte
+
+func (s sessionStore) Names() ([]string, error) { return nil, nil }
+
+func (s sessionStore) Load(name string) ([]byte, error) { return s[name], nil }
+
+func (s sessionStore) Save(name string, b []byte) error {
+	s[name] = b
+	return nil
+}
+
+func TestSaveAndLoadSession(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{
+		Spec:         &spec{svcNames: []string{"api.Example", "api.Foo"}},
+		GRPCClient:   client,
+		SessionStore: sessionStore{},
+	})
+
+	if err := UsePackage("api"); err != nil {
+		t.Fatalf("UsePackage must not return an error, but got '%s'", err)
+	}
+	if err := UseService("Example"); err != nil {
+		t.Fatalf("UseService must not return an error, but got '%s'", err)
+	}
+	AddHeader("kumiko", "oumae")
+	if err := SetVariable("token", "abc123"); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+	SetTimeout(5 * time.Second)
+	type options struct{ Output string }
+	if err := SaveSession("work", &options{Output: "json"}); err != nil {
+		t.Fatalf("SaveSession must not return an error, but got '%s'", err)
+	}
+
+	if err := UseService("Foo"); err != nil {
+		t.Fatalf("UseService must not return an error, but got '%s'", err)
+	}
+	RemoveHeader("kumiko")
+	AddHeader("reina", "kousaka")
+	if err := SetVariable("token", ""); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+	SetTimeout(0)
+
+	var opts options
+	if err := LoadSession("work", &opts); err != nil {
+		t.Fatalf("LoadSession must not return an error, but got '%s'", err)
+	}
+	if dsn := GetDomainSourceName(); dsn != "api.Example" {
+		t.Errorf("expected DSN is api.Example, but got %s", dsn)
+	}
+	if diff := cmp.Diff(grpc.Headers{"kumiko": {"oumae"}}, ListHeaders()); diff != "" {
+		t.Errorf("headers must be replaced with saved ones (-want, +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]*Variable{{Name: "token", Value: "abc123"}}, ListVariables()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+	if timeout := GetTimeout(); timeout != 5*time.Second {
+		t.Errorf("expected timeout is 5s, but got %s", timeout)
+	}
+	if opts.Output != "json" {
+		t.Errorf("expected saved options are restored, but got %+v", opts)
+	}
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CallStats is the statistics of a call.
+type CallStats struct {
+	// Method is the fully-qualified method name.
+	Method string
+	// Elapsed is the time from starting the RPC to finishing it. The time for inputting the request of unary and
+	// server streaming RPCs is excluded, but the time for inputting requests of client streams is included.
+	Elapsed time.Duration
+	// Code is the status code of the call. It is codes.Unknown if the call failed without receiving the status.
+	Code codes.Code
+
+	RequestMessages, ResponseMessages int
+	// RequestBytes and ResponseBytes are the total size of serialized messages.
+	RequestBytes, ResponseBytes int
+
+	start time.Time
+}
+
+// String returns the one-line summary of the call.
+func (s *CallStats) String() string {
+	return fmt.Sprintf("elapsed: %s, sent: %s, received: %s, status: %s",
+		s.Elapsed.Round(time.Microsecond),
+		formatMessages(s.RequestMessages, s.RequestBytes),
+		formatMessages(s.ResponseMessages, s.ResponseBytes),
+		s.Code)
+}
+
+func formatMessages(n, size int) string {
+	if n == 1 {
+		return fmt.Sprintf("1 message (%d bytes)", size)
+	}
+	return fmt.Sprintf("%d messages (%d bytes)", n, size)
+}
+
+func (s *CallStats) addRequest(req interface{}) {
+	s.RequestMessages++
+	s.RequestBytes += messageSize(req)
+}
+
+func (s *CallStats) addResponse(res interface{}) {
+	s.ResponseMessages++
+	s.ResponseBytes += messageSize(res)
+}
+
+func messageSize(v interface{}) int {
+	switch v := v.(type) {
+	case *rawRequest:
+		return len(v.b)
+	case proto.Message:
+		return proto.Size(v)
+	default:
+		return 0
+	}
+}
+
+// MethodStats is the statistics of calls of a method in the session.
+type MethodStats struct {
+	Method string
+	Calls  int
+	// Errors is the number of calls which failed or returned non-OK status codes.
+	Errors int
+	// Total is the total elapsed time of all calls.
+	Total time.Duration
+}
+
+// Mean returns the mean latency of the calls.
+func (s *MethodStats) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// LastCallStats returns the statistics of the last call. It returns nil if no RPCs have been called yet.
+func LastCallStats() *CallStats {
+	return dm.LastCallStats()
+}
+func (m *dependencyManager) LastCallStats() *CallStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.lastStats
+}
+
+// ListMethodStats returns the statistics of each called method in the session. They are sorted by the method name.
+func ListMethodStats() []*MethodStats {
+	return dm.ListMethodStats()
+}
+func (m *dependencyManager) ListMethodStats() []*MethodStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make([]*MethodStats, 0, len(m.state.methodStats))
+	for _, s := range m.state.methodStats {
+		s := *s
+		stats = append(stats, &s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+// ClearStats clears the statistics of the session.
+func ClearStats() {
+	dm.ClearStats()
+}
+func (m *dependencyManager) ClearStats() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.lastStats = nil
+	m.state.methodStats = nil
+}
+
+// recordStats records s as the statistics of the last call. err is the error returned from the call.
+// Calls which didn't start, such that inputting the request failed, are not recorded.
+func (m *dependencyManager) recordStats(s *CallStats, err error) {
+	if s.start.IsZero() {
+		return
+	}
+	s.Elapsed = time.Since(s.start)
+	s.Code = codeOf(err)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.lastStats = s
+
+	if m.state.methodStats == nil {
+		m.state.methodStats = make(map[string]*MethodStats)
+	}
+	ms, ok := m.state.methodStats[s.Method]
+	if !ok {
+		ms = &MethodStats{Method: s.Method}
+		m.state.methodStats[s.Method] = ms
+	}
+	ms.Calls++
+	ms.Total += s.Elapsed
+	if s.Code != codes.OK {
+		ms.Errors++
+	}
+}
+
+func codeOf(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	var gerr *gRPCError
+	if errors.As(err, &gerr) {
+		return gerr.Status.Code()
+	}
+	if stat, ok := status.FromError(errors.Cause(err)); ok {
+		return stat.Code()
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecordStats(t *testing.T) {
+	m := &dependencyManager{}
+
+	m.recordStats(&CallStats{Method: "api.Example.Unary"}, errors.New("inputting canceled"))
+	if s := m.LastCallStats(); s != nil {
+		t.Fatalf("calls which didn't start must not be recorded, but got '%s'", s)
+	}
+
+	start := time.Now().Add(-time.Second)
+	calls := []struct {
+		method string
+		err    error
+	}{
+		{"api.Example.Unary", nil},
+		{"api.Example.Unary", &gRPCError{Status: status.New(codes.NotFound, "not found")}},
+		{"api.Example.ClientStreaming", errors.Wrap(status.Error(codes.Unavailable, "unavailable"), "failed to send a request")},
+		{"api.Example.Unary", errors.New("failed to send a request")},
+	}
+	for _, c := range calls {
+		m.recordStats(&CallStats{Method: c.method, start: start}, c.err)
+	}
+
+	last := m.LastCallStats()
+	if last.Method != "api.Example.Unary" || last.Code != codes.Unknown {
+		t.Errorf("unexpected last call stats: %s %s", last.Method, last.Code)
+	}
+	if last.Elapsed < time.Second {
+		t.Errorf("elapsed time must be the time since the start, but got %s", last.Elapsed)
+	}
+
+	expected := []*MethodStats{
+		{Method: "api.Example.ClientStreaming", Calls: 1, Errors: 1},
+		{Method: "api.Example.Unary", Calls: 3, Errors: 2},
+	}
+	if diff := cmp.Diff(expected, m.ListMethodStats(), cmpopts.IgnoreFields(MethodStats{}, "Total")); diff != "" {
+		t.Errorf("-want, +got\n%s", diff)
+	}
+
+	m.ClearStats()
+	if s := m.LastCallStats(); s != nil {
+		t.Errorf("stats must be cleared, but got '%s'", s)
+	}
+	if s := m.ListMethodStats(); len(s) != 0 {
+		t.Errorf("stats must be cleared, but got %d methods", len(s))
+	}
+}
+
+func TestCallStats_String(t *testing.T) {
+	s := &CallStats{
+		Elapsed:          1500 * time.Microsecond,
+		Code:             codes.OK,
+		RequestMessages:  2,
+		ResponseMessages: 1,
+		RequestBytes:     24,
+		ResponseBytes:    12,
+	}
+	expected := "elapsed: 1.5ms, sent: 2 messages (24 bytes), received: 1 message (12 bytes), status: OK"
+	if actual := s.String(); actual != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, actual)
+	}
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/ktr0731/evans/format"
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // For calling RegisterType.
+	"google.golang.org/grpc/status"
+)
+
+// newStatus converts s to *format.Status with error details decoded by decodeStatusDetails.
+func newStatus(spec idl.Spec, s *status.Status) *format.Status {
+	return &format.Status{
+		Code:    s.Code(),
+		Message: s.Message(),
+		Details: decodeStatusDetails(spec, s),
+	}
+}
+
+// FormatStatusDetails formats decoded error details as human-readable text.
+func FormatStatusDetails(details []map[string]interface{}) string {
+	s := make([]string, 0, len(details))
+	for _, d := range details {
+		b, err := json.MarshalIndent(d, "  ", "  ")
+		if err != nil {
+			s = append(s, fmt.Sprintf("  %v", d))
+			continue
+		}
+		s = append(s, "  "+string(b))
+	}
+	return fmt.Sprintf("details:\n%s", strings.Join(s, "\n"))
+}
+
+// decodeStatusDetails decodes each error detail of s into a JSON-compatible map which has "@type" key.
+// The message type of a detail is resolved from registered types such that google.rpc.BadRequest first,
+// and then from spec. If both of them failed, the detail is represented by the raw bytes.
+func decodeStatusDetails(spec idl.Spec, s *status.Status) []map[string]interface{} {
+	anys := s.Proto().GetDetails()
+	if len(anys) == 0 {
+		return nil
+	}
+	details := make([]map[string]interface{}, 0, len(anys))
+	for _, a := range anys {
+		d, err := decodeStatusDetail(spec, a)
+		if err != nil {
+			logger.Printf("failed to decode the error detail '%s': %s", a.GetTypeUrl(), err)
+			d = map[string]interface{}{"@type": a.GetTypeUrl(), "value": a.GetValue()}
+		}
+		details = append(details, d)
+	}
+	return details
+}
+
+func decodeStatusDetail(spec idl.Spec, a *any.Any) (map[string]interface{}, error) {
+	msg, err := newStatusDetailMessage(spec, a)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, msg); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the detail")
+	}
+	var d map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the detail")
+	}
+	d["@type"] = a.GetTypeUrl()
+	return d, nil
+}
+
+func newStatusDetailMessage(spec idl.Spec, a *any.Any) (proto.Message, error) {
+	var dany ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(a, &dany); err == nil {
+		return dany.Message, nil
+	}
+	if spec == nil {
+		return nil, errors.New("unknown message type")
+	}
+
+	typeURL := a.GetTypeUrl()
+	typ, err := spec.MessageType(typeURL[strings.LastIndex(typeURL, "/")+1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve the message type")
+	}
+	v, err := typ.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to instantiate the message")
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("the message type '%s' is not a Protocol Buffers message", typ.FullyQualifiedName)
+	}
+	if err := proto.Unmarshal(a.GetValue(), msg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the value")
+	}
+	return msg, nil
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type detailSpec struct {
+	idl.Spec
+}
+
+func (s *detailSpec) MessageType(fqmn string) (*grpc.Type, error) {
+	if fqmn != "api.ErrorInfo" {
+		return nil, idl.ErrUnknownSymbol
+	}
+	return &grpc.Type{
+		Name:               "ErrorInfo",
+		FullyQualifiedName: fqmn,
+		New:                func() (interface{}, error) { return &errdetails.ErrorInfo{}, nil },
+	}, nil
+}
+
+func TestDecodeStatusDetails(t *testing.T) {
+	newAny := func(t *testing.T, typeURL string, m proto.Message) *any.Any {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatalf("proto.Marshal must not return an error, but got '%s'", err)
+		}
+		return &any.Any{TypeUrl: typeURL, Value: b}
+	}
+
+	spec := &detailSpec{}
+	stat := status.FromProto(&spb.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: "invalid argument",
+		Details: []*any.Any{
+			newAny(t, "type.googleapis.com/google.rpc.BadRequest", &errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "name", Description: "required"}},
+			}),
+			newAny(t, "type.googleapis.com/api.ErrorInfo", &errdetails.ErrorInfo{Reason: "NOT_FOUND"}),
+			{TypeUrl: "type.googleapis.com/api.Unknown", Value: []byte("kumiko")},
+		},
+	})
+
+	expected := []map[string]interface{}{
+		{
+			"@type": "type.googleapis.com/google.rpc.BadRequest",
+			"fieldViolations": []interface{}{
+				map[string]interface{}{"field": "name", "description": "required"},
+			},
+		},
+		{"@type": "type.googleapis.com/api.ErrorInfo", "reason": "NOT_FOUND"},
+		{"@type": "type.googleapis.com/api.Unknown", "value": []byte("kumiko")},
+	}
+	if diff := cmp.Diff(expected, decodeStatusDetails(spec, stat)); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	if details := decodeStatusDetails(spec, status.New(codes.Internal, "internal error")); details != nil {
+		t.Errorf("decodeStatusDetails must return nil if the status has no details, but got %v", details)
+	}
+}
@@ -0,0 +1,23 @@
+package usecase
+
+import "time"
+
+// SetTimeout sets d as the timeout for each RPC call. If d is zero, RPC calls have no deadline.
+func SetTimeout(d time.Duration) {
+	dm.SetTimeout(d)
+}
+func (m *dependencyManager) SetTimeout(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.timeout = d
+}
+
+// GetTimeout returns the current timeout for each RPC call.
+func GetTimeout() time.Duration {
+	return dm.GetTimeout()
+}
+func (m *dependencyManager) GetTimeout() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.timeout
+}
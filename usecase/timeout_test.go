@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTimeout(t *testing.T) {
+	m := &dependencyManager{}
+
+	ctx, cancel := m.withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("the context must not have the deadline if the timeout is zero")
+	}
+
+	m.SetTimeout(time.Nanosecond)
+	ctx, cancel = m.withTimeout(context.Background())
+	defer cancel()
+	<-ctx.Done()
+
+	stat, err := m.handleGRPCResponseError(ctx, status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error()))
+	if err != nil {
+		t.Fatalf("handleGRPCResponseError must not return an error, but got '%s'", err)
+	}
+	if expected := "context deadline exceeded (timeout: 1ns)"; stat.Message() != expected {
+		t.Errorf("expected '%s', but got '%s'", expected, stat.Message())
+	}
+}
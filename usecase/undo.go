@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+)
+
+// maxUndo is the maximum number of changes which can be undone.
+const maxUndo = 100
+
+// snapshot is the state which is restored by Undo and Redo. profile is only used to detect switching profiles.
+type snapshot struct {
+	profile       string
+	pkg           string
+	svc           string
+	header        grpc.Headers
+	scopedHeaders grpc.ScopedHeaders
+	variables     map[string]string
+	timeout       time.Duration
+}
+
+// RecordChange calls f, which may change the selected package and service, headers, variables or the timeout,
+// and records the change so that it can be undone by Undo. The change is recorded even if f returns an error
+// because f may change the state partially. Nothing is recorded if f doesn't change the state.
+// Switching profiles can't be undone because it replaces the connection, so it clears recorded changes.
+func RecordChange(f func() error) error {
+	return dm.RecordChange(f)
+}
+func (m *dependencyManager) RecordChange(f func() error) error {
+	// f changes the state via methods which hold m.mu, so m.mu can't be held while calling f.
+	before := m.lockedSnapshot()
+	err := f()
+	after := m.lockedSnapshot()
+	if reflect.DeepEqual(before, after) {
+		return err
+	}
+	m.notifyChanges(before, after)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if before.profile != after.profile {
+		m.state.undoStack, m.state.redoStack = nil, nil
+		return err
+	}
+	m.state.undoStack = append(m.state.undoStack, before)
+	if len(m.state.undoStack) > maxUndo {
+		m.state.undoStack = m.state.undoStack[1:]
+	}
+	m.state.redoStack = nil
+	return err
+}
+
+// Undo restores the state before the last change recorded by RecordChange.
+func Undo() error {
+	return dm.Undo()
+}
+func (m *dependencyManager) Undo() error {
+	m.mu.Lock()
+	n := len(m.state.undoStack)
+	if n == 0 {
+		m.mu.Unlock()
+		return errors.New("nothing to undo")
+	}
+	current, prev := m.snapshot(), m.state.undoStack[n-1]
+	m.restore(prev)
+	m.state.undoStack = m.state.undoStack[:n-1]
+	m.state.redoStack = append(m.state.redoStack, current)
+	m.mu.Unlock()
+	m.notifyChanges(current, prev)
+	return nil
+}
+
+// Redo restores the state undone by the last Undo.
+func Redo() error {
+	return dm.Redo()
+}
+func (m *dependencyManager) Redo() error {
+	m.mu.Lock()
+	n := len(m.state.redoStack)
+	if n == 0 {
+		m.mu.Unlock()
+		return errors.New("nothing to redo")
+	}
+	current, next := m.snapshot(), m.state.redoStack[n-1]
+	m.restore(next)
+	m.state.redoStack = m.state.redoStack[:n-1]
+	m.state.undoStack = append(m.state.undoStack, current)
+	m.mu.Unlock()
+	m.notifyChanges(current, next)
+	return nil
+}
+
+// lockedSnapshot is the same as snapshot, but holds m.mu while copying the state.
+func (m *dependencyManager) lockedSnapshot() *snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshot()
+}
+
+// snapshot copies the current state. Empty headers, scopes and variables are normalized to nil to compare snapshots.
+// m.mu must be held.
+func (m *dependencyManager) snapshot() *snapshot {
+	s := &snapshot{
+		profile: m.state.selectedProfile,
+		pkg:     m.state.selectedPackage,
+		svc:     m.state.selectedService,
+		timeout: m.state.timeout,
+	}
+	if m.gRPCClient != nil {
+		s.header = copyHeaders(m.headers())
+	}
+	for scope, h := range m.state.scopedHeaders {
+		if len(h) == 0 {
+			continue
+		}
+		if s.scopedHeaders == nil {
+			s.scopedHeaders = grpc.ScopedHeaders{}
+		}
+		s.scopedHeaders[scope] = copyHeaders(h)
+	}
+	for k, v := range m.state.variables {
+		if s.variables == nil {
+			s.variables = map[string]string{}
+		}
+		s.variables[k] = v
+	}
+	return s
+}
+
+// restore replaces the current state with s. m.mu must be held.
+func (m *dependencyManager) restore(s *snapshot) {
+	if m.gRPCClient != nil {
+		for k := range m.headers() {
+			m.headers().Remove(k)
+		}
+		for k, v := range s.header {
+			if err := m.headers().Set(k, v...); err != nil {
+				logger.Printf("failed to restore a header %s: %s", k, err)
+			}
+		}
+	}
+	m.state.scopedHeaders = nil
+	for scope, h := range s.scopedHeaders {
+		if m.state.scopedHeaders == nil {
+			m.state.scopedHeaders = grpc.ScopedHeaders{}
+		}
+		m.state.scopedHeaders[scope] = copyHeaders(h)
+	}
+	m.state.variables = nil
+	for k, v := range s.variables {
+		if m.state.variables == nil {
+			m.state.variables = map[string]string{}
+		}
+		m.state.variables[k] = v
+	}
+	m.state.selectedPackage, m.state.selectedService = s.pkg, s.svc
+	m.state.timeout = s.timeout
+}
+
+// copyHeaders returns a deep copy of h, or nil if h is empty.
+func copyHeaders(h grpc.Headers) grpc.Headers {
+	if len(h) == 0 {
+		return nil
+	}
+	c := make(grpc.Headers, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+)
+
+func TestUndoAndRedo(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{GRPCClient: client})
+	AddHeader("authorization", "Bearer token")
+
+	if err := Undo(); err == nil {
+		t.Errorf("Undo must return an error because no changes are recorded")
+	}
+
+	err = RecordChange(func() error {
+		RemoveHeader("authorization")
+		AddScopedHeader("api.Example", "x-route", "example")
+		return dm.SetVariable("name", "kumiko")
+	})
+	if err != nil {
+		t.Fatalf("RecordChange must not return an error, but got '%s'", err)
+	}
+	// Changes which don't change the state are not recorded.
+	if err := RecordChange(func() error { return nil }); err != nil {
+		t.Fatalf("RecordChange must not return an error, but got '%s'", err)
+	}
+
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(grpc.Headers{"authorization": {"Bearer token"}}, ListHeaders()); diff != "" {
+		t.Errorf("headers must be restored (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(grpc.Headers{}, ListScopedHeaders("api.Example")); diff != "" {
+		t.Errorf("scoped headers must be restored (-want +got):\n%s", diff)
+	}
+	if len(ListVariables()) != 0 {
+		t.Errorf("variables must be restored, but got %v", ListVariables())
+	}
+	if err := Undo(); err == nil {
+		t.Errorf("Undo must return an error because all changes are undone")
+	}
+
+	if err := Redo(); err != nil {
+		t.Fatalf("Redo must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(grpc.Headers{}, ListHeaders()); diff != "" {
+		t.Errorf("headers must be changed again (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(grpc.Headers{"x-route": {"example"}}, ListScopedHeaders("api.Example")); diff != "" {
+		t.Errorf("scoped headers must be changed again (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]*Variable{{Name: "name", Value: "kumiko"}}, ListVariables()); diff != "" {
+		t.Errorf("variables must be changed again (-want +got):\n%s", diff)
+	}
+	if err := Redo(); err == nil {
+		t.Errorf("Redo must return an error because all changes are redone")
+	}
+
+	// A new change clears undone changes.
+	if err := Undo(); err != nil {
+		t.Fatalf("Undo must not return an error, but got '%s'", err)
+	}
+	if err := RecordChange(func() error { AddHeader("x-new", "new"); return nil }); err != nil {
+		t.Fatalf("RecordChange must not return an error, but got '%s'", err)
+	}
+	if err := Redo(); err == nil {
+		t.Errorf("Redo must return an error because a new change is recorded after Undo")
+	}
+}
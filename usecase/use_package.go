@@ -7,16 +7,20 @@ import "github.com/ktr0731/evans/idl"
 //
 //   - idl.ErrUnknownPackageName: pkgName is not in loaded packages.
 //
+// If pkgName doesn't exactly match any packages, it is compared with loaded packages case-insensitively.
 func UsePackage(pkgName string) error {
 	return dm.UsePackage(pkgName)
 }
 func (m *dependencyManager) UsePackage(pkgName string) error {
-	for _, pkg := range ListPackages() {
-		if pkg == pkgName {
-			m.state.selectedPackage = pkgName
-			m.state.selectedService = ""
-			return nil
-		}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The package is looked up under the lock, so that it is always selected from the current spec.
+	pkgNames := m.index(m.spec).pkgNames
+	n, ok := matchName(pkgName, pkgNames)
+	if !ok {
+		return newUnknownNameError(idl.ErrUnknownPackageName, pkgName, pkgNames)
 	}
-	return idl.ErrUnknownPackageName
+	m.state.selectedPackage = n
+	m.state.selectedService = ""
+	return nil
 }
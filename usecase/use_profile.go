@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"github.com/ktr0731/evans/logger"
+	"github.com/pkg/errors"
+)
+
+// Profile is a connection environment which is switched by UseProfile.
+type Profile struct {
+	Spec       idl.Spec
+	GRPCClient grpc.Client
+	// Header is default headers of the profile. It replaces default headers of the previous profile.
+	Header map[string][]string
+	// Package and Service are selected after switching if they are not empty.
+	Package string
+	Service string
+	// Timeout is the timeout for each RPC call.
+	Timeout time.Duration
+}
+
+// ProfileLoader loads named connection profiles.
+type ProfileLoader interface {
+	// Names returns all profile names in ascending order.
+	Names() []string
+	// Load establishes a new connection with the profile named name.
+	Load(name string) (*Profile, error)
+}
+
+// ListProfiles lists all profile names.
+func ListProfiles() []string {
+	return dm.ListProfiles()
+}
+func (m *dependencyManager) ListProfiles() []string {
+	if m.profileLoader == nil {
+		return nil
+	}
+	return m.profileLoader.Names()
+}
+
+// GetCurrentProfile returns the currently used profile name. It returns an empty string if no profiles are used.
+func GetCurrentProfile() string {
+	return dm.GetCurrentProfile()
+}
+func (m *dependencyManager) GetCurrentProfile() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.selectedProfile
+}
+
+// UseProfile switches the current connection to the profile named name.
+// The selected package and service are cleared, then the defaults of the profile are selected.
+// Headers, scoped headers and propagation rules added by the user are kept, but default headers of the previous profile are replaced
+// with the new ones.
+func UseProfile(name string) error {
+	return dm.UseProfile(name)
+}
+func (m *dependencyManager) UseProfile(name string) error {
+	if m.profileLoader == nil {
+		return errors.New("no profiles are defined")
+	}
+	p, err := m.profileLoader.Load(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load profile '%s'", name)
+	}
+
+	m.mu.Lock()
+	var oldHeader grpc.Headers
+	if m.gRPCClient != nil {
+		oldHeader = m.gRPCClient.Header()
+	}
+	newHeader := p.GRPCClient.Header()
+	for k, v := range oldHeader {
+		if _, ok := m.state.profileHeader[k]; ok {
+			continue
+		}
+		if _, ok := p.Header[k]; ok {
+			continue
+		}
+		for _, vv := range v {
+			_ = newHeader.Add(k, vv)
+		}
+	}
+	for k, v := range p.Header {
+		for _, vv := range v {
+			if err := newHeader.Add(k, vv); err != nil {
+				logger.Printf("failed to add a header %s=%s: %s", k, vv, err)
+			}
+		}
+	}
+
+	if m.gRPCClient != nil {
+		if err := m.gRPCClient.Close(context.Background()); err != nil {
+			logger.Printf("failed to close the previous gRPC client: %s", err)
+		}
+	}
+	m.spec = p.Spec
+	m.gRPCClient = p.GRPCClient
+	// Variables, scoped headers and propagation rules are defined by the user, so they are kept the same as headers.
+	// The field name style is not a connection setting, so it is also kept.
+	// Statistics summarize the whole session, so they are kept across profiles.
+	variables, protoNames := m.state.variables, m.state.protoNames
+	scopedHeaders, propagatedHeaders := m.state.scopedHeaders, m.state.propagatedHeaders
+	lastStats, methodStats := m.state.lastStats, m.state.methodStats
+	m.state = defaultState
+	m.state.variables = variables
+	m.state.scopedHeaders, m.state.propagatedHeaders = scopedHeaders, propagatedHeaders
+	m.state.protoNames = protoNames
+	m.state.lastStats, m.state.methodStats = lastStats, methodStats
+	m.state.selectedProfile = name
+	m.state.profileHeader = p.Header
+	m.state.timeout = p.Timeout
+	m.mu.Unlock()
+
+	// Select the empty package if the spec has it, the same as the initial selection.
+	if p.Package != "" || m.index(p.Spec).hasPackage("") {
+		if err := m.UsePackage(p.Package); err != nil {
+			return errors.Wrapf(err, "failed to set '%s' as the default package", p.Package)
+		}
+	}
+	if p.Service != "" {
+		if err := m.UseService(p.Service); err != nil {
+			return errors.Wrapf(err, "failed to set '%s' as the default service", p.Service)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/evans/grpc"
+	"github.com/ktr0731/evans/idl"
+	"google.golang.org/grpc/metadata"
+)
+
+type spec struct {
+	idl.Spec
+	svcNames []string
+}
+
+func (s *spec) ServiceNames() []string { return s.svcNames }
+
+type profileLoader struct {
+	t *testing.T
+}
+
+func (l *profileLoader) Names() []string { return []string{"dev", "prod"} }
+
+func (l *profileLoader) Load(name string) (*Profile, error) {
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		l.t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	return &Profile{
+		Spec:       &spec{svcNames: []string{"api.Example", "api.Foo"}},
+		GRPCClient: client,
+		Header:     map[string][]string{"env": {name}},
+		Package:    "api",
+		Service:    "Example",
+	}, nil
+}
+
+func TestUseProfile(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{
+		Spec:          &spec{svcNames: []string{"other.Example"}},
+		GRPCClient:    client,
+		ProfileLoader: &profileLoader{t: t},
+	})
+	AddHeader("kumiko", "oumae")
+	AddScopedHeader("api.Example", "x-route", "example")
+	PropagateHeader("set-session", "session")
+
+	for _, name := range []string{"dev", "prod"} {
+		if err := UseProfile(name); err != nil {
+			t.Fatalf("UseProfile must not return an error, but got '%s'", err)
+		}
+		if actual := GetCurrentProfile(); actual != name {
+			t.Errorf("expected current profile is %s, but got %s", name, actual)
+		}
+		if dsn := GetDomainSourceName(); dsn != "api.Example" {
+			t.Errorf("expected DSN is api.Example, but got %s", dsn)
+		}
+		// The header added by the user is kept, but the default header of the previous profile is replaced.
+		expected := grpc.Headers{"kumiko": {"oumae"}, "env": {name}}
+		if diff := cmp.Diff(expected, ListHeaders()); diff != "" {
+			t.Errorf("unexpected header:\n%s", diff)
+		}
+		// Scoped headers are also added by the user, so they are still sent with RPCs in the scope.
+		ctx, err := dm.withHeaders(context.Background(), "api.Example.Unary")
+		if err != nil {
+			t.Fatalf("withHeaders must not return an error, but got '%s'", err)
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if diff := cmp.Diff([]string{"example"}, md.Get("x-route")); diff != "" {
+			t.Errorf("unexpected scoped header:\n%s", diff)
+		}
+	}
+
+	// Propagation rules are kept, so response headers are still copied into request headers.
+	dm.propagateHeaders(metadata.Pairs("set-session", "token"))
+	if diff := cmp.Diff([]string{"token"}, ListHeaders()["session"]); diff != "" {
+		t.Errorf("unexpected propagated header:\n%s", diff)
+	}
+}
@@ -1,8 +1,10 @@
 package usecase
 
 import (
+	"strings"
+
 	"github.com/ktr0731/evans/idl"
-	"github.com/ktr0731/evans/idl/proto"
+	idlproto "github.com/ktr0731/evans/idl/proto"
 	"github.com/pkg/errors"
 )
 
@@ -12,6 +14,8 @@ import (
 //   - ErrPackageUnselected: REPL never call UsePackage.
 //   - ErrUnknownServiceName: svcName is not in loaded services.
 //
+// svcName is compared case-insensitively if it doesn't exactly match any services.
+// If svcName is fully-qualified such that "api.Example", the package is also selected.
 func UseService(svcName string) error {
 	return dm.UseService(svcName)
 }
@@ -19,20 +23,40 @@ func (m *dependencyManager) UseService(svcName string) error {
 	if svcName == "" {
 		return errors.Errorf("invalid service name '%s'", svcName)
 	}
-	var hasPackage bool
-	for _, fqsn := range m.spec.ServiceNames() {
-		pkg, svc := proto.ParseFullyQualifiedServiceName(fqsn)
-		if m.state.selectedPackage == pkg {
-			hasPackage = true
-			if svcName == svc {
-				m.state.selectedService = svcName
-				return nil
-			}
+	fqsn, err := m.selectService(svcName)
+	if err != nil {
+		return err
+	}
+	return m.checkDeprecatedService(fqsn)
+}
+
+// selectService selects svcName and returns the fully-qualified name of the selected service.
+// The service is looked up under the lock, so that it is always selected from the current spec.
+func (m *dependencyManager) selectService(svcName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.index(m.spec)
+	if strings.Contains(svcName, ".") {
+		fqsn := resolveServiceName(m.spec, svcName)
+		pkg, svc := idlproto.ParseFullyQualifiedServiceName(fqsn)
+		if !idx.hasService(pkg, svc) {
+			return "", newUnknownNameError(idl.ErrUnknownServiceName, svcName, m.spec.ServiceNames())
 		}
+		m.state.selectedPackage = pkg
+		m.state.selectedService = svc
+		return fqsn, nil
+	}
+	pkg := m.state.selectedPackage
+	if n, ok := matchName(svcName, idx.svcNames[pkg]); ok {
+		svcName = n
+	}
+	if idx.hasService(pkg, svcName) {
+		m.state.selectedService = svcName
+		return idlproto.FullyQualifiedServiceName(pkg, svcName), nil
 	}
-	if hasPackage {
-		return idl.ErrUnknownServiceName
+	if idx.hasPackage(pkg) {
+		return "", newUnknownNameError(idl.ErrUnknownServiceName, svcName, idx.svcNames[pkg])
 	}
 	// In the case of empty package.
-	return idl.ErrPackageUnselected
+	return "", idl.ErrPackageUnselected
 }
@@ -3,6 +3,10 @@
 package usecase
 
 import (
+	"io"
+	"sync"
+	"time"
+
 	"github.com/ktr0731/evans/fill"
 	"github.com/ktr0731/evans/format"
 	"github.com/ktr0731/evans/grpc"
@@ -16,29 +20,112 @@ var (
 )
 
 type dependencyManager struct {
-	spec              idl.Spec
-	filler            fill.Filler
-	interactiveFiller fill.InteractiveFiller
-	gRPCClient        grpc.Client
-	responseFormatter *format.ResponseFormatter
-	resourcePresenter present.Presenter
+	spec                idl.Spec
+	filler              fill.Filler
+	interactiveFiller   fill.InteractiveFiller
+	gRPCClient          grpc.Client
+	responseFormatter   *format.ResponseFormatter
+	resourcePresenter   present.Presenter
+	profileLoader       ProfileLoader
+	templateStore       TemplateStore
+	sessionStore        SessionStore
+	headerSetStore      HeaderSetStore
+	requestHistoryStore RequestHistoryStore
+
+	// idx is built from idxSpec lazily. It is rebuilt when another spec is looked up.
+	// idxMu guards idx and idxSpec. It is separated from mu because lookups are done while mu is held.
+	idxMu   sync.Mutex
+	idx     *index
+	idxSpec idl.Spec
+
+	// observers is not a part of state, so it is kept across profiles.
+	observers observers
 
+	// mu guards spec, gRPCClient, headers and state, so that RPCs can be called concurrently and streams can receive
+	// responses in the background. The state must be changed only via methods which hold mu.
+	// Usecases read spec, gRPCClient and the selected package and service via env instead of holding mu.
+	mu    sync.RWMutex
 	state state
 }
 
+// env is a snapshot of the loaded spec, the client and the selected package and service. Each usecase takes it
+// at first and passes it down, so that switching profiles concurrently doesn't mix two environments in a usecase.
+type env struct {
+	spec   idl.Spec
+	client grpc.Client
+	pkg    string
+	svc    string
+}
+
+// env takes a snapshot of the current environment. m.mu must not be held.
+func (m *dependencyManager) env() env {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return env{
+		spec:   m.spec,
+		client: m.gRPCClient,
+		pkg:    m.state.selectedPackage,
+		svc:    m.state.selectedService,
+	}
+}
+
 // state has the domain state modified by each usecase logic. The default value is used as the initial value.
 type state struct {
 	selectedPackage string // TODO: remove in v1.0.0.
 	selectedService string
+	// warnedService is the fully-qualified name of the service which is warned as deprecated at the selection.
+	// Calling RPCs of the service doesn't warn it again while it is selected.
+	warnedService string
+
+	// timeout is the timeout for each RPC call. Zero means no timeout.
+	timeout time.Duration
+	// rawResponseWriter is the destination of serialized responses. If it is nil, they are not written.
+	rawResponseWriter io.Writer
+	// protoNames is true if formatted requests use original field names instead of JSON names.
+	protoNames bool
+
+	// lastRequests is the requests of the last call for each RPC. The key is a fully-qualified RPC name.
+	lastRequests map[string][]interface{}
+	// lastRPC is the fully-qualified name of the last called RPC.
+	lastRPC string
+	// lastResponse is the last received response of the last call. It is referenced by $resp.
+	lastResponse interface{}
+	// lastStats is the statistics of the last call.
+	lastStats *CallStats
+	// methodStats is the statistics of calls in the session. The key is a fully-qualified RPC name.
+	methodStats map[string]*MethodStats
+
+	// variables is user-defined variables which are referenced by $name.
+	variables map[string]string
+
+	// scopedHeaders is default headers of packages, services and RPCs.
+	scopedHeaders grpc.ScopedHeaders
+	// propagatedHeaders is rules to copy response headers into request headers. The key is a key of response
+	// headers or trailers, and the value is a key of request headers.
+	propagatedHeaders map[string]string
+
+	// undoStack and redoStack is snapshots of the state before changes recorded by RecordChange. The last one is
+	// the latest.
+	undoStack []*snapshot
+	redoStack []*snapshot
+
+	selectedProfile string
+	// profileHeader is default headers of the selected profile.
+	profileHeader map[string][]string
 }
 
 type Dependencies struct {
-	Spec              idl.Spec
-	Filler            fill.Filler
-	InteractiveFiller fill.InteractiveFiller
-	GRPCClient        grpc.Client
-	ResponseFormatter *format.ResponseFormatter
-	ResourcePresenter present.Presenter
+	Spec                idl.Spec
+	Filler              fill.Filler
+	InteractiveFiller   fill.InteractiveFiller
+	GRPCClient          grpc.Client
+	ResponseFormatter   *format.ResponseFormatter
+	ResourcePresenter   present.Presenter
+	ProfileLoader       ProfileLoader
+	TemplateStore       TemplateStore
+	SessionStore        SessionStore
+	HeaderSetStore      HeaderSetStore
+	RequestHistoryStore RequestHistoryStore
 }
 
 // Inject corresponds an implementation to an interface type. Inject clears the previous states if it exists.
@@ -48,12 +135,17 @@ func Inject(deps Dependencies) {
 
 func (m *dependencyManager) Inject(d Dependencies) {
 	dm = &dependencyManager{
-		spec:              d.Spec,
-		filler:            d.Filler,
-		interactiveFiller: d.InteractiveFiller,
-		gRPCClient:        d.GRPCClient,
-		responseFormatter: d.ResponseFormatter,
-		resourcePresenter: d.ResourcePresenter,
+		spec:                d.Spec,
+		filler:              d.Filler,
+		interactiveFiller:   d.InteractiveFiller,
+		gRPCClient:          d.GRPCClient,
+		responseFormatter:   d.ResponseFormatter,
+		resourcePresenter:   d.ResourcePresenter,
+		profileLoader:       d.ProfileLoader,
+		templateStore:       d.TemplateStore,
+		sessionStore:        d.SessionStore,
+		headerSetStore:      d.HeaderSetStore,
+		requestHistoryStore: d.RequestHistoryStore,
 
 		state: defaultState,
 	}
@@ -65,6 +157,8 @@ func InjectPartially(deps Dependencies) {
 }
 
 func (m *dependencyManager) InjectPartially(d Dependencies) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if d.Spec != nil {
 		m.spec = d.Spec
 	}
@@ -83,6 +177,21 @@ func (m *dependencyManager) InjectPartially(d Dependencies) {
 	if d.ResourcePresenter != nil {
 		m.resourcePresenter = d.ResourcePresenter
 	}
+	if d.ProfileLoader != nil {
+		m.profileLoader = d.ProfileLoader
+	}
+	if d.TemplateStore != nil {
+		m.templateStore = d.TemplateStore
+	}
+	if d.SessionStore != nil {
+		m.sessionStore = d.SessionStore
+	}
+	if d.HeaderSetStore != nil {
+		m.headerSetStore = d.HeaderSetStore
+	}
+	if d.RequestHistoryStore != nil {
+		m.requestHistoryStore = d.RequestHistoryStore
+	}
 }
 
 // Clear clears all dependencies and states. Usually, it is used for unit testing.
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/evans/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TestConcurrentAccess must be run with the race detector to check that RPCs can be called concurrently.
+func TestConcurrentAccess(t *testing.T) {
+	defer Clear()
+	client, err := grpc.NewClient("", "", false, false, "", "", "", "", grpc.ConnOptions{})
+	if err != nil {
+		t.Fatalf("grpc.NewClient must not return an error, but got '%s'", err)
+	}
+	Inject(Dependencies{
+		GRPCClient:    client,
+		Spec:          &spec{svcNames: []string{"api.Example", "api.Foo"}},
+		ProfileLoader: &profileLoader{t: t},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := dm.withHeaders(context.Background(), "api.Example.Unary"); err != nil {
+				t.Errorf("withHeaders must not return an error, but got '%s'", err)
+			}
+			dm.propagateHeaders(metadata.Pairs("x-session-id", "abc"))
+			AddHeader("x-request", "1")
+			AddScopedHeader("api.Example", "x-route", "example")
+			if err := SetVariable("name", "kumiko"); err != nil {
+				t.Errorf("SetVariable must not return an error, but got '%s'", err)
+			}
+			ExpandVariables("$name")
+			dm.recordStats(&CallStats{Method: "api.Example.Unary", start: time.Now()}, nil)
+			ListMethodStats()
+
+			PropagateHeader("x-session-id", "")
+			if err := SetScopedHeader("api.Example", "x-tenant", "a", "b"); err != nil {
+				t.Errorf("SetScopedHeader must not return an error, but got '%s'", err)
+			}
+			ListScopedHeaders("api.Example")
+			RemoveScopedHeader("api.Example", "x-tenant")
+			if err := AppendHeader("x-request", "2"); err != nil {
+				t.Errorf("AppendHeader must not return an error, but got '%s'", err)
+			}
+			ListHeaders()
+
+			if err := RecordChange(func() error {
+				SetTimeout(time.Second)
+				return UseService("api.Example")
+			}); err != nil {
+				t.Errorf("RecordChange must not return an error, but got '%s'", err)
+			}
+			Undo() //nolint:errcheck
+			Redo() //nolint:errcheck
+			_, cancel := dm.withTimeout(context.Background())
+			cancel()
+			GetTimeout()
+			GetDomainSourceName()
+			if err := UsePackage("api"); err != nil {
+				t.Errorf("UsePackage must not return an error, but got '%s'", err)
+			}
+
+			// Switching profiles replaces the spec and the client while others read them.
+			if err := UseProfile("dev"); err != nil {
+				t.Errorf("UseProfile must not return an error, but got '%s'", err)
+			}
+			ListPackages()
+			ListServices()
+			ListServicesOld()
+			GetSelectedService()
+		}()
+	}
+	wg.Wait()
+
+	if n := ListMethodStats()[0].Calls; n != 10 {
+		t.Errorf("all calls must be recorded, but got %d", n)
+	}
+}
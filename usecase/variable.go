@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:staticcheck
+	"github.com/golang/protobuf/proto"  //nolint:staticcheck
+	"github.com/pkg/errors"
+)
+
+// responseVariableName is the name of the builtin variable which references the last response.
+const responseVariableName = "resp"
+
+var (
+	variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// variableRefPattern matches $$, ${name} and $name.
+	variableRefPattern = regexp.MustCompile(`\$\$|\$\{([^}]+)\}|\$([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_]+)*)`)
+)
+
+// Variable is a pair of a name and a value.
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// SetVariable sets value to the variable named name. If value is empty, the variable is removed.
+// The name must consist of alphanumerics and underscores, and must not start with a digit.
+func SetVariable(name, value string) error {
+	return dm.SetVariable(name, value)
+}
+func (m *dependencyManager) SetVariable(name, value string) error {
+	if !variableNamePattern.MatchString(name) {
+		return errors.Errorf("invalid variable name '%s'", name)
+	}
+	if name == responseVariableName {
+		return errors.Errorf("'%s' is a builtin variable", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if value == "" {
+		delete(m.state.variables, name)
+		return nil
+	}
+	if m.state.variables == nil {
+		m.state.variables = make(map[string]string)
+	}
+	m.state.variables[name] = value
+	return nil
+}
+
+// ListVariables returns all variables ordered by the name.
+func ListVariables() []*Variable {
+	return dm.ListVariables()
+}
+func (m *dependencyManager) ListVariables() []*Variable {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	vars := make([]*Variable, 0, len(m.state.variables))
+	for k, v := range m.state.variables {
+		vars = append(vars, &Variable{Name: k, Value: v})
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name < vars[j].Name
+	})
+	return vars
+}
+
+// ExpandVariables replaces references to variables in s with their values.
+// Both of $name and ${name} are references, and $$ is replaced with $.
+// $resp is a builtin variable which references the last response such that $resp.id.
+// References to undefined variables are left as it is.
+func ExpandVariables(s string) string {
+	return dm.ExpandVariables(s)
+}
+func (m *dependencyManager) ExpandVariables(s string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	return variableRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(ref, "$"), "{"), "}")
+		if v, ok := m.lookupVariable(name); ok {
+			return v
+		}
+		return ref
+	})
+}
+
+func (m *dependencyManager) lookupVariable(name string) (string, bool) {
+	if name == responseVariableName || strings.HasPrefix(name, responseVariableName+".") {
+		return m.lookupResponse(strings.TrimPrefix(strings.TrimPrefix(name, responseVariableName), "."))
+	}
+	v, ok := m.state.variables[name]
+	return v, ok
+}
+
+// lookupResponse returns the value of the last response specified by path.
+// path is dot-separated field names, and elements of repeated fields are specified by indices such that items.0.id.
+// If path is empty, lookupResponse returns the whole response as JSON.
+func (m *dependencyManager) lookupResponse(path string) (string, bool) {
+	msg, ok := m.state.lastResponse.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	var buf bytes.Buffer
+	marshaler := &jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+	if err := marshaler.Marshal(&buf, msg); err != nil {
+		return "", false
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch vv := v.(type) {
+			case map[string]interface{}:
+				v, ok = vv[key]
+				if !ok {
+					return "", false
+				}
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(vv) {
+					return "", false
+				}
+				v = vv[i]
+			default:
+				return "", false
+			}
+		}
+	}
+
+	switch vv := v.(type) {
+	case string:
+		return vv, true
+	case json.Number:
+		return vv.String(), true
+	case bool:
+		return strconv.FormatBool(vv), true
+	case nil:
+		return "null", true
+	default:
+		b, err := json.Marshal(vv)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestVariables(t *testing.T) {
+	m := &dependencyManager{}
+	if err := m.SetVariable("token", "abc123"); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+	if err := m.SetVariable("name", "kumiko"); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+	if err := m.SetVariable("1st", "kumiko"); err == nil {
+		t.Errorf("SetVariable must return an error because the name is invalid")
+	}
+
+	expected := []*Variable{{Name: "name", Value: "kumiko"}, {Name: "token", Value: "abc123"}}
+	if diff := cmp.Diff(expected, m.ListVariables()); diff != "" {
+		t.Errorf("(-want, +got)\n%s", diff)
+	}
+
+	cases := map[string]string{
+		"$token":              "abc123",
+		"Bearer ${token}":     "Bearer abc123",
+		"$name-$token":        "kumiko-abc123",
+		"$undefined":          "$undefined",
+		"$$token":             "$token",
+		"no variables":        "no variables",
+		"${name}san costs $5": "kumikosan costs $5",
+	}
+	for in, expected := range cases {
+		if actual := m.ExpandVariables(in); actual != expected {
+			t.Errorf("ExpandVariables(%q): expected '%s', but got '%s'", in, expected, actual)
+		}
+	}
+
+	if err := m.SetVariable("token", ""); err != nil {
+		t.Fatalf("SetVariable must not return an error, but got '%s'", err)
+	}
+	if actual := m.ExpandVariables("$token"); actual != "$token" {
+		t.Errorf("the removed variable must not be expanded, but got '%s'", actual)
+	}
+}
+
+func TestExpandVariables_response(t *testing.T) {
+	m := &dependencyManager{}
+	if actual := m.ExpandVariables("$resp.field_violations"); actual != "$resp.field_violations" {
+		t.Errorf("$resp must not be expanded before calling any RPCs, but got '%s'", actual)
+	}
+
+	m.state.lastResponse = &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "required"},
+			{Field: "age"},
+		},
+	}
+	cases := map[string]string{
+		"$resp.field_violations.0.field":   "name",
+		"$resp.field_violations.1.field":   "age",
+		"${resp.field_violations.0}":       `{"description":"required","field":"name"}`,
+		"$resp.field_violations.2.field":   "$resp.field_violations.2.field",
+		"$resp.field_violations.foo.field": "$resp.field_violations.foo.field",
+		"$resp.unknown":                    "$resp.unknown",
+	}
+	for in, expected := range cases {
+		if actual := m.ExpandVariables(in); actual != expected {
+			t.Errorf("ExpandVariables(%q): expected '%s', but got '%s'", in, expected, actual)
+		}
+	}
+
+	if err := m.SetVariable("resp", "kumiko"); err == nil {
+		t.Errorf("SetVariable must return an error because resp is a builtin variable")
+	}
+}